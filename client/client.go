@@ -0,0 +1,228 @@
+// Package client is a Go client of the services of the indexer. Requests and
+// responses are the types of the route handlers (see types.go), so that they
+// stay in sync with the server. Failed requests of read only routes are retried
+// on connection errors and on responses indicating a temporary failure, see
+// WithRetries. Requests changing data (webhook subscriptions) are not retried.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flare-indexer/services/api"
+	"flare-indexer/utils/errcode"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultRetryDelay = 500 * time.Millisecond
+	// Retry delays are doubled after each attempt up to maxRetryDelay
+	maxRetryDelay = 10 * time.Second
+	// Size of the body of a response which is not a JSON response of the
+	// services included in the error
+	maxErrorBodySize = 512
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	adminToken string
+	maxRetries int
+	retryDelay time.Duration
+}
+
+type Option func(*Client)
+
+// The http client used for the requests, it should have a timeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// API key sent in the X-API-Key header, see the key_limits of the services
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// Admin token of the services sent as bearer token, needed by the watchlist
+// and webhook routes
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// Number of retries of a failed read only request (0 disables retries) and the
+// delay before the first retry
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+// Client of the services at baseURL, including the base_path of the services,
+// e.g. "http://localhost:8000/api"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		retryDelay: defaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error response of the services, or an unexpected response. Code is empty if
+// the response is not a JSON response of the services.
+type Error struct {
+	HTTPStatus int
+	Status     api.ApiResStatusEnum
+	Code       errcode.Code
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if len(e.Code) == 0 {
+		return fmt.Sprintf("indexer responded with HTTP status %d: %s", e.HTTPStatus, e.Message)
+	}
+	return fmt.Sprintf("indexer responded with %s (HTTP status %d): %s", e.Code, e.HTTPStatus, e.Message)
+}
+
+// Returns true if err is an error response with the code, e.g. errcode.NotFound
+func IsCode(err error, code errcode.Code) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
+
+// Temporary failures: the data is not indexed yet (errcode.ChainLag), rate
+// limits and failures of a proxy in front of the services
+func (e *Error) retryable() bool {
+	switch e.HTTPStatus {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Sends the request of a read only route and decodes the data of the response
+// into T. The body is encoded as JSON if it is not nil. Read only requests may
+// be retried, see WithRetries.
+func do[T any](ctx context.Context, c *Client, method string, path string, query url.Values, body interface{}) (T, error) {
+	return doWithRetries[T](ctx, c, c.maxRetries, method, path, query, body)
+}
+
+// Sends the request of a route changing data without retrying it, as it is not
+// known whether a failed request was applied by the services
+func doOnce[T any](ctx context.Context, c *Client, method string, path string, query url.Values, body interface{}) (T, error) {
+	return doWithRetries[T](ctx, c, 0, method, path, query, body)
+}
+
+func doWithRetries[T any](ctx context.Context, c *Client, maxRetries int, method string, path string, query url.Values, body interface{}) (T, error) {
+	var result T
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			return result, err
+		}
+	}
+	requestURL := c.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		data, err := c.send(ctx, method, requestURL, bodyBytes)
+		if err == nil {
+			if err := json.Unmarshal(data, &result); err != nil {
+				return result, fmt.Errorf("cannot decode the response of %s: %w", path, err)
+			}
+			return result, nil
+		}
+		var errResponse *Error
+		if errors.As(err, &errResponse) && !errResponse.retryable() {
+			return result, err
+		}
+		if ctx.Err() != nil || attempt >= maxRetries {
+			return result, err
+		}
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// Returns the data of the response, or an *Error for error responses
+func (c *Client) send(ctx context.Context, method string, requestURL string, body []byte) (json.RawMessage, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if len(c.apiKey) > 0 {
+		request.Header.Set("X-API-Key", c.apiKey)
+	}
+	if len(c.adminToken) > 0 {
+		request.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapper api.ApiResponseWrapper[json.RawMessage]
+	if err := json.Unmarshal(responseBody, &wrapper); err != nil || len(wrapper.Status) == 0 {
+		if len(responseBody) > maxErrorBodySize {
+			responseBody = responseBody[:maxErrorBodySize]
+		}
+		return nil, &Error{HTTPStatus: response.StatusCode, Message: strings.TrimSpace(string(responseBody))}
+	}
+	// Some error responses are sent with the HTTP status OK
+	if response.StatusCode != http.StatusOK || wrapper.Status != api.ApiResStatusOk {
+		message := wrapper.ErrorMessage
+		if len(wrapper.ErrorDetails) > 0 {
+			message += ": " + wrapper.ErrorDetails
+		}
+		return nil, &Error{
+			HTTPStatus: response.StatusCode,
+			Status:     wrapper.Status,
+			Code:       wrapper.ErrorCode,
+			Message:    message,
+		}
+	}
+	return wrapper.Data, nil
+}
@@ -0,0 +1,148 @@
+//go:build !integration
+// +build !integration
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/services/api"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/errcode"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "key", r.Header.Get("X-API-Key"))
+		switch r.URL.Path {
+		case "/api/validators/transactions":
+			require.Equal(t, http.MethodPost, r.Method)
+			var request GetStakerTxRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			require.Equal(t, "NodeID-1", request.NodeID)
+			require.Equal(t, 10, request.Limit)
+			utils.WriteApiResponseOk(w, TxIDsResponse{TxIDs: []string{"tx1", "tx2"}})
+		case "/api/addresses/P-localflare1abc/utxos":
+			require.Equal(t, http.MethodGet, r.Method)
+			require.Equal(t, "5", r.URL.Query().Get("height"))
+			require.Equal(t, "20", r.URL.Query().Get("limit"))
+			require.False(t, r.URL.Query().Has("offset"))
+			utils.WriteApiResponseOk(w, GetAddressUTXOsResponse{Address: "localflare1abc", Height: 5})
+		case "/api/transactions/get/missing":
+			utils.WriteErrorResponse(w, errcode.NotFound, "transaction not found")
+		case "/api/watchlist/list":
+			require.Equal(t, "Bearer admin", r.Header.Get("Authorization"))
+			utils.WriteApiResponseOk(w, []WatchlistEntryResponse{{ID: 1}})
+		case "/api/blocks/list":
+			utils.WriteApiResponseError(w, api.ApiResStatusQueryLimitExceeded, "limit exceeded", "")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := New(server.URL+"/api/", WithAPIKey("key"), WithAdminToken("admin"), WithRetries(0, 0))
+
+	txIDs, err := c.ValidatorTransactions(ctx, GetStakerTxRequest{
+		PaginatedRequest: PaginatedRequest{Limit: 10},
+		NodeID:           "NodeID-1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx1", "tx2"}, txIDs)
+
+	height := uint64(5)
+	utxos, err := c.AddressUTXOs(ctx, "P-localflare1abc", UTXOsQuery{PageQuery: PageQuery{Limit: 20}, Height: &height})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), utxos.Height)
+
+	entries, err := c.WatchlistEntries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	_, err = c.Transaction(ctx, "missing")
+	require.True(t, IsCode(err, errcode.NotFound))
+	require.ErrorContains(t, err, "transaction not found")
+
+	// Error responses sent with the HTTP status OK
+	_, err = c.Blocks(ctx, GetBlocksRequest{})
+	require.True(t, IsCode(err, errcode.Validation))
+
+	// Responses which are not responses of the services
+	_, err = c.Labels(ctx)
+	var errResponse *Error
+	require.ErrorAs(t, err, &errResponse)
+	require.Equal(t, http.StatusNotFound, errResponse.HTTPStatus)
+	require.Empty(t, errResponse.Code)
+}
+
+func TestClientRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/mirroring/epochs/3" && calls == 1:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		case r.URL.Path == "/mirroring/epochs/3" && calls == 2:
+			utils.WriteErrorResponse(w, errcode.ChainLag, "epoch is not indexed yet")
+		case r.URL.Path == "/mirroring/epochs/3":
+			utils.WriteApiResponseOk(w, MirroringEpochResponse{Epoch: 3, TxCount: 7})
+		default:
+			utils.WriteErrorResponse(w, errcode.ChainLag, "not indexed yet")
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := New(server.URL, WithRetries(2, time.Millisecond))
+	epoch, err := c.MirroringEpoch(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, 7, epoch.TxCount)
+	require.Equal(t, 3, calls)
+
+	// Retries are exhausted
+	calls = 0
+	_, err = c.MirroringEpoch(ctx, 4)
+	require.True(t, IsCode(err, errcode.ChainLag))
+	require.Equal(t, 3, calls)
+
+	// The context ends the retries
+	calls = 0
+	c = New(server.URL, WithRetries(5, time.Hour))
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = c.MirroringEpoch(timeoutCtx, 4)
+	require.True(t, IsCode(err, errcode.ChainLag))
+	require.Equal(t, 1, calls)
+}
+
+func TestClientDoesNotRetryChanges(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := New(server.URL, WithAdminToken("admin"), WithRetries(2, time.Millisecond))
+	_, err := c.SetWebhookSubscription(ctx, SetWebhookSubscriptionRequest{Name: "hook"})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	err = c.RemoveWebhookSubscription(ctx, "hook")
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	_, err = c.WebhookSubscriptions(ctx)
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+}
@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Offset and limit of the routes paginated with query parameters, the default
+// limit of the route is used if Limit is 0
+type PageQuery struct {
+	Offset int
+	Limit  int
+}
+
+func (q PageQuery) values() url.Values {
+	values := url.Values{}
+	if q.Offset > 0 {
+		values.Set("offset", strconv.Itoa(q.Offset))
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return values
+}
+
+// Time (AsOf) or block height of the unspent set, at most one of them, the
+// current unspent set if neither is set
+type UTXOsQuery struct {
+	PageQuery
+	AsOf   *time.Time
+	Height *uint64
+}
+
+// Range of epochs, unbounded if First or Last is not set
+type MerkleRootsQuery struct {
+	PageQuery
+	First *int64
+	Last  *int64
+}
+
+type TopValidatorsQuery struct {
+	// weight (default), delegators or uptime
+	By string
+	// The last recorded epoch if not set
+	Epoch *int64
+	Limit int
+}
+
+type ChangesQuery struct {
+	// Id of the last change already read, GetChangesResponse.Next of the
+	// previous response
+	After uint64
+	Limit int
+	// Table of the changed rows, all tables if empty
+	Entity string
+}
+
+//
+// Staking
+//
+
+func (c *Client) ValidatorTransactions(ctx context.Context, request GetStakerTxRequest) ([]string, error) {
+	return c.txIDs(ctx, "/validators/transactions", request)
+}
+
+func (c *Client) DelegatorTransactions(ctx context.Context, request GetStakerTxRequest) ([]string, error) {
+	return c.txIDs(ctx, "/delegators/transactions", request)
+}
+
+func (c *Client) Validators(ctx context.Context, request GetStakerRequest) ([]GetStakerResponse, error) {
+	return do[[]GetStakerResponse](ctx, c, http.MethodPost, "/validators/list", nil, request)
+}
+
+func (c *Client) Delegators(ctx context.Context, request GetStakerRequest) ([]GetStakerResponse, error) {
+	return do[[]GetStakerResponse](ctx, c, http.MethodPost, "/delegators/list", nil, request)
+}
+
+func (c *Client) ValidatorCapacities(ctx context.Context, request GetValidatorCapacityRequest) ([]ValidatorCapacityResponse, error) {
+	return do[[]ValidatorCapacityResponse](ctx, c, http.MethodPost, "/validators/capacity", nil, request)
+}
+
+// Validators and delegators active at the time
+func (c *Client) StakersAt(ctx context.Context, t time.Time) (GetStakersAtResponse, error) {
+	query := url.Values{"time": {t.UTC().Format(time.RFC3339Nano)}}
+	return do[GetStakersAtResponse](ctx, c, http.MethodGet, "/validators/at", query, nil)
+}
+
+// Validators and delegators active at the start of the reward epoch
+func (c *Client) StakersAtEpoch(ctx context.Context, epoch int64) (GetStakersAtResponse, error) {
+	query := url.Values{"epoch": {strconv.FormatInt(epoch, 10)}}
+	return do[GetStakersAtResponse](ctx, c, http.MethodGet, "/validators/at", query, nil)
+}
+
+func (c *Client) ValidatorWeights(ctx context.Context, epoch int64) (GetValidatorWeightsResponse, error) {
+	query := url.Values{"epoch": {strconv.FormatInt(epoch, 10)}}
+	return do[GetValidatorWeightsResponse](ctx, c, http.MethodGet, "/validators/weights", query, nil)
+}
+
+func (c *Client) TopValidators(ctx context.Context, q TopValidatorsQuery) (GetTopValidatorsResponse, error) {
+	query := url.Values{}
+	if len(q.By) > 0 {
+		query.Set("by", q.By)
+	}
+	if q.Epoch != nil {
+		query.Set("epoch", strconv.FormatInt(*q.Epoch, 10))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return do[GetTopValidatorsResponse](ctx, c, http.MethodGet, "/validators/top", query, nil)
+}
+
+func (c *Client) ValidatorBLSKeys(ctx context.Context, nodeID string) ([]ValidatorBLSKeyResponse, error) {
+	return do[[]ValidatorBLSKeyResponse](ctx, c, http.MethodGet, "/validators/bls/"+url.PathEscape(nodeID), nil, nil)
+}
+
+func (c *Client) Labels(ctx context.Context) ([]ValidatorLabelResponse, error) {
+	return do[[]ValidatorLabelResponse](ctx, c, http.MethodGet, "/labels/list", nil, nil)
+}
+
+//
+// Transfers
+//
+
+func (c *Client) ImportTransactions(ctx context.Context, request GetTransferRequest) ([]string, error) {
+	return c.txIDs(ctx, "/imports/transactions", request)
+}
+
+func (c *Client) ExportTransactions(ctx context.Context, request GetTransferRequest) ([]string, error) {
+	return c.txIDs(ctx, "/exports/transactions", request)
+}
+
+//
+// Transactions
+//
+
+func (c *Client) Transaction(ctx context.Context, txID string) (*PChainTx, error) {
+	return do[*PChainTx](ctx, c, http.MethodGet, "/transactions/get/"+url.PathEscape(txID), nil, nil)
+}
+
+// Decoded transaction with its credentials, source is "db", "node" or empty,
+// see routes.RawTxSourceDB
+func (c *Client) RawTransaction(ctx context.Context, txID string, source string) (RawTransactionResponse, error) {
+	query := url.Values{}
+	if len(source) > 0 {
+		query.Set("source", source)
+	}
+	return do[RawTransactionResponse](ctx, c, http.MethodGet, "/transactions/raw/"+url.PathEscape(txID), query, nil)
+}
+
+func (c *Client) TransactionsBatch(ctx context.Context, txIDs []string) (TransactionsBatchResponse, error) {
+	return do[TransactionsBatchResponse](ctx, c, http.MethodPost, "/transactions/batch", nil,
+		GetTransactionsBatchRequest{TxIDs: txIDs})
+}
+
+func (c *Client) TransactionsByMemo(ctx context.Context, request GetTransactionsByMemoRequest) ([]string, error) {
+	return c.txIDs(ctx, "/transactions/memo", request)
+}
+
+//
+// Addresses
+//
+
+func (c *Client) AddressActivity(ctx context.Context, address string, page PageQuery) (GetAddressActivityResponse, error) {
+	return do[GetAddressActivityResponse](ctx, c, http.MethodGet,
+		"/addresses/"+url.PathEscape(address)+"/activity", page.values(), nil)
+}
+
+func (c *Client) AddressUTXOs(ctx context.Context, address string, q UTXOsQuery) (GetAddressUTXOsResponse, error) {
+	query := q.values()
+	if q.AsOf != nil {
+		query.Set("asof", q.AsOf.UTC().Format(time.RFC3339Nano))
+	}
+	if q.Height != nil {
+		query.Set("height", strconv.FormatUint(*q.Height, 10))
+	}
+	return do[GetAddressUTXOsResponse](ctx, c, http.MethodGet,
+		"/addresses/"+url.PathEscape(address)+"/utxos", query, nil)
+}
+
+func (c *Client) AddressSignedStakes(ctx context.Context, address string, page PageQuery) (GetAddressSignedStakesResponse, error) {
+	return do[GetAddressSignedStakesResponse](ctx, c, http.MethodGet,
+		"/addresses/"+url.PathEscape(address)+"/signed_stakes", page.values(), nil)
+}
+
+//
+// Blocks
+//
+
+// Last block accepted at or before the time
+func (c *Client) BlockAtTime(ctx context.Context, t time.Time) (BlockResponse, error) {
+	return do[BlockResponse](ctx, c, http.MethodPost, "/blocks/at-time", nil, GetBlockAtTimeRequest{Time: t})
+}
+
+func (c *Client) Block(ctx context.Context, height uint64) (*PChainBlockResponse, error) {
+	return do[*PChainBlockResponse](ctx, c, http.MethodGet, "/blocks/get/"+strconv.FormatUint(height, 10), nil, nil)
+}
+
+func (c *Client) BlockByContainerIndex(ctx context.Context, index uint64) (*PChainBlockResponse, error) {
+	return do[*PChainBlockResponse](ctx, c, http.MethodGet, "/blocks/container/"+strconv.FormatUint(index, 10), nil, nil)
+}
+
+// Blocks, the newest first
+func (c *Client) Blocks(ctx context.Context, request GetBlocksRequest) ([]PChainBlockResponse, error) {
+	return do[[]PChainBlockResponse](ctx, c, http.MethodPost, "/blocks/list", nil, request)
+}
+
+//
+// Mirroring
+//
+
+// Stake data and merkle proofs of the transaction for the mirroring contract
+func (c *Client) MirroringData(ctx context.Context, txID string) ([]MirroringResponse, error) {
+	return do[[]MirroringResponse](ctx, c, http.MethodGet, "/mirroring/tx_data/"+url.PathEscape(txID), nil, nil)
+}
+
+func (c *Client) MirroringAttempts(ctx context.Context, txID string) ([]MirroringAttemptResponse, error) {
+	return do[[]MirroringAttemptResponse](ctx, c, http.MethodGet, "/mirroring/attempts/"+url.PathEscape(txID), nil, nil)
+}
+
+func (c *Client) MirroringEpoch(ctx context.Context, epoch int64) (MirroringEpochResponse, error) {
+	return do[MirroringEpochResponse](ctx, c, http.MethodGet, "/mirroring/epochs/"+strconv.FormatInt(epoch, 10), nil, nil)
+}
+
+func (c *Client) MerkleRoots(ctx context.Context, q MerkleRootsQuery) ([]MerkleRootResponse, error) {
+	query := q.values()
+	if q.First != nil {
+		query.Set("first", strconv.FormatInt(*q.First, 10))
+	}
+	if q.Last != nil {
+		query.Set("last", strconv.FormatInt(*q.Last, 10))
+	}
+	return do[[]MerkleRootResponse](ctx, c, http.MethodGet, "/merkle_roots", query, nil)
+}
+
+//
+// Fees
+//
+
+func (c *Client) DailyBurnStats(ctx context.Context, request GetDailyBurnStatsRequest) ([]BurnStatsResponse, error) {
+	return do[[]BurnStatsResponse](ctx, c, http.MethodPost, "/fees/daily", nil, request)
+}
+
+func (c *Client) EpochBurnStats(ctx context.Context, request GetEpochBurnStatsRequest) ([]BurnStatsResponse, error) {
+	return do[[]BurnStatsResponse](ctx, c, http.MethodPost, "/fees/epochs", nil, request)
+}
+
+//
+// Changes and watchlist
+//
+
+func (c *Client) Changes(ctx context.Context, q ChangesQuery) (GetChangesResponse, error) {
+	query := url.Values{}
+	if q.After > 0 {
+		query.Set("after", strconv.FormatUint(q.After, 10))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if len(q.Entity) > 0 {
+		query.Set("entity", q.Entity)
+	}
+	return do[GetChangesResponse](ctx, c, http.MethodGet, "/changes", query, nil)
+}
+
+// Needs the admin token, see WithAdminToken
+func (c *Client) WatchlistEntries(ctx context.Context) ([]WatchlistEntryResponse, error) {
+	return do[[]WatchlistEntryResponse](ctx, c, http.MethodGet, "/watchlist/list", nil, nil)
+}
+
+// Needs the admin token, see WithAdminToken
+func (c *Client) WatchlistMatches(ctx context.Context, request GetWatchlistMatchesRequest) ([]WatchlistMatchResponse, error) {
+	return do[[]WatchlistMatchResponse](ctx, c, http.MethodPost, "/watchlist/matches", nil, request)
+}
+
+// Needs the admin token, see WithAdminToken
+func (c *Client) WebhookSubscriptions(ctx context.Context) ([]WebhookSubscriptionResponse, error) {
+	return do[[]WebhookSubscriptionResponse](ctx, c, http.MethodGet, "/webhooks/list", nil, nil)
+}
+
+// Needs the admin token, see WithAdminToken
+func (c *Client) SetWebhookSubscription(ctx context.Context, request SetWebhookSubscriptionRequest) (WebhookSubscriptionResponse, error) {
+	return doOnce[WebhookSubscriptionResponse](ctx, c, http.MethodPost, "/webhooks/set", nil, request)
+}
+
+// Needs the admin token, see WithAdminToken
+func (c *Client) RemoveWebhookSubscription(ctx context.Context, name string) error {
+	_, err := doOnce[bool](ctx, c, http.MethodPost, "/webhooks/remove", nil, RemoveWebhookSubscriptionRequest{Name: name})
+	return err
+}
+
+func (c *Client) txIDs(ctx context.Context, path string, request interface{}) ([]string, error) {
+	response, err := do[TxIDsResponse](ctx, c, http.MethodPost, path, nil, request)
+	return response.TxIDs, err
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"flare-indexer/services/api"
+	"flare-indexer/services/routes"
+)
+
+// Requests and responses of the routes, aliases of the types of the route
+// handlers so that changes of the server are changes of the client
+
+type (
+	PaginatedRequest = routes.PaginatedRequest
+	TxIDsResponse    = routes.TxIDsResponse
+
+	GetStakerTxRequest          = routes.GetStakerTxRequest
+	GetStakerRequest            = routes.GetStakerRequest
+	GetStakerResponse           = routes.GetStakerResponse
+	GetValidatorCapacityRequest = routes.GetValidatorCapacityRequest
+	ValidatorCapacityResponse   = routes.ValidatorCapacityResponse
+	StakingViolationResponse    = routes.StakingViolationResponse
+	GetStakersAtResponse        = routes.GetStakersAtResponse
+	ValidatorBLSKeyResponse     = routes.ValidatorBLSKeyResponse
+	ValidatorWeightResponse     = routes.ValidatorWeightResponse
+	GetValidatorWeightsResponse = routes.GetValidatorWeightsResponse
+	TopValidatorResponse        = routes.TopValidatorResponse
+	GetTopValidatorsResponse    = routes.GetTopValidatorsResponse
+	ValidatorLabelResponse      = routes.ValidatorLabelResponse
+
+	GetTransferRequest = routes.GetTransferRequest
+
+	PChainTx                     = api.ApiPChainTx
+	PChainTxInput                = api.ApiPChainTxInput
+	PChainTxOutput               = api.ApiPChainTxOutput
+	GetTransactionsBatchRequest  = routes.GetTransactionsBatchRequest
+	TransactionsBatchResponse    = routes.TransactionsBatchResponse
+	GetTransactionsByMemoRequest = routes.GetTransactionsByMemoRequest
+	RawTransactionResponse       = routes.RawTransactionResponse
+
+	AddressActivityResponse        = routes.AddressActivityResponse
+	GetAddressActivityResponse     = routes.GetAddressActivityResponse
+	UTXOResponse                   = routes.UTXOResponse
+	GetAddressUTXOsResponse        = routes.GetAddressUTXOsResponse
+	GetAddressSignedStakesResponse = routes.GetAddressSignedStakesResponse
+
+	GetBlockAtTimeRequest = routes.GetBlockAtTimeRequest
+	BlockResponse         = routes.BlockResponse
+	GetBlocksRequest      = routes.GetBlocksRequest
+	PChainBlockResponse   = routes.PChainBlockResponse
+
+	MirroringStakeData       = routes.MirroringStakeData
+	MirroringResponse        = routes.MirroringResponse
+	MirroringAttemptResponse = routes.MirroringAttemptResponse
+	MirroringEpochStatus     = routes.MirroringEpochStatus
+	MirroringEpochResponse   = routes.MirroringEpochResponse
+
+	GetDailyBurnStatsRequest = routes.GetDailyBurnStatsRequest
+	GetEpochBurnStatsRequest = routes.GetEpochBurnStatsRequest
+	BurnStatsResponse        = routes.BurnStatsResponse
+
+	MerkleRootResponse = routes.MerkleRootResponse
+
+	ChangeResponse     = routes.ChangeResponse
+	GetChangesResponse = routes.GetChangesResponse
+
+	WatchlistEntryResponse     = routes.WatchlistEntryResponse
+	GetWatchlistMatchesRequest = routes.GetWatchlistMatchesRequest
+	WatchlistMatchResponse     = routes.WatchlistMatchResponse
+
+	WebhookSubscriptionResponse      = routes.WebhookSubscriptionResponse
+	SetWebhookSubscriptionRequest    = routes.SetWebhookSubscriptionRequest
+	RemoveWebhookSubscriptionRequest = routes.RemoveWebhookSubscriptionRequest
+)
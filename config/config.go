@@ -1,13 +1,17 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -40,6 +44,31 @@ type DBConfig struct {
 	Username   string `toml:"username" envconfig:"DB_USERNAME"`
 	Password   string `toml:"password" envconfig:"DB_PASSWORD"`
 	LogQueries bool   `toml:"log_queries"`
+	// Prefix of all table names, allows several indexers to share one database
+	TablePrefix string `toml:"table_prefix" envconfig:"DB_TABLE_PREFIX"`
+	// DSNs of read replicas (e.g. "user:password@tcp(replica:3306)/flare_indexer"), used for
+	// queries of the services, indexers and cronjobs always use the primary database
+	ReadReplicas []string `toml:"read_replicas" envconfig:"DB_READ_REPLICAS"`
+	// Maximal duration of a single statement, no limit if zero
+	QueryTimeout time.Duration `toml:"query_timeout" envconfig:"DB_QUERY_TIMEOUT"`
+	// Number of fractional second digits (0-6) of the datetime columns,
+	// DefaultTimestampPrecision if not set. Timestamps are stored in UTC.
+	TimestampPrecision *int `toml:"timestamp_precision" envconfig:"DB_TIMESTAMP_PRECISION"`
+}
+
+// Precision of the datetime columns of databases created by earlier versions
+const DefaultTimestampPrecision = 3
+
+// Number of fractional second digits of the datetime columns
+func (c DBConfig) DatetimePrecision() (int, error) {
+	if c.TimestampPrecision == nil {
+		return DefaultTimestampPrecision, nil
+	}
+	precision := *c.TimestampPrecision
+	if precision < 0 || precision > 6 {
+		return 0, fmt.Errorf("db: timestamp_precision must be between 0 and 6")
+	}
+	return precision, nil
 }
 
 type ChainConfig struct {
@@ -47,14 +76,91 @@ type ChainConfig struct {
 	ChainAddressHRP string `toml:"address_hrp" envconfig:"CHAIN_ADDRESS_HRP"`
 	ChainID         int    `toml:"chain_id" envconfig:"CHAIN_ID"`
 	EthRPCURL       string `toml:"eth_rpc_url" envconfig:"ETH_RPC_URL"`
-	ApiKey          string `toml:"api_key" envconfig:"API_KEY"`
+	// Period of checking the connection to the eth RPC, 0 to disable the check
+	EthRPCHealthCheckPeriod time.Duration `toml:"eth_rpc_health_check_period" envconfig:"ETH_RPC_HEALTH_CHECK_PERIOD"`
+	ApiKey                  string        `toml:"api_key" envconfig:"API_KEY"`
 	// setting the private key in config file is deprecated, except in development and testing
 	// use private_key_file instead
 	PrivateKey     string `toml:"private_key" envconfig:"PRIVATE_KEY"`
 	PrivateKeyFile string `toml:"private_key_file" envconfig:"PRIVATE_KEY_FILE"`
+	// encrypted geth keystore (JSON) file, takes precedence over the private key settings,
+	// the passphrase is read from keystore_passphrase_file or keystore_passphrase
+	KeystoreFile           string `toml:"keystore_file" envconfig:"KEYSTORE_FILE"`
+	KeystorePassphrase     string `toml:"keystore_passphrase" envconfig:"KEYSTORE_PASSPHRASE"`
+	KeystorePassphraseFile string `toml:"keystore_passphrase_file" envconfig:"KEYSTORE_PASSPHRASE_FILE"`
+	// do not stop the indexer if the node version is not supported by the container parsers
+	SkipNodeVersionCheck bool `toml:"skip_node_version_check" envconfig:"SKIP_NODE_VERSION_CHECK"`
+	// Network ID of the node, if zero the network ID of the Flare network with
+	// chain_id (see FlareNetworkIDs). The two IDs differ for some Flare networks
+	// and for the Avalanche networks, e.g. network ID 1 and chain ID 43114 for the
+	// Avalanche mainnet, whose network_id must be set.
+	NetworkID uint32 `toml:"network_id" envconfig:"CHAIN_NETWORK_ID"`
+	// ID (cb58) of the asset paying the fees, the burned amounts of other assets
+	// are not counted in the fees of X-chain transactions. All assets are counted
+	// if empty, the X-chain of Flare networks has no other assets.
+	AssetID string `toml:"asset_id" envconfig:"CHAIN_ASSET_ID"`
+	// Maximal durations of a single call of the index API (index.*), of the
+	// platform API (platform.*) and of the eth RPC, defaults if zero. Each
+	// dependency has its own limit, so a slow one cannot use up the time of a
+	// whole batch or cronjob run. DB statements are limited by query_timeout.
+	IndexerTimeout  time.Duration `toml:"indexer_timeout" envconfig:"CHAIN_INDEXER_TIMEOUT"`
+	PlatformTimeout time.Duration `toml:"platform_timeout" envconfig:"CHAIN_PLATFORM_TIMEOUT"`
+	EthRPCTimeout   time.Duration `toml:"eth_rpc_timeout" envconfig:"ETH_RPC_TIMEOUT"`
 }
 
+// Default limits of the calls of the node APIs and of the eth RPC
+const (
+	DefaultIndexerTimeout  = 3 * time.Minute
+	DefaultPlatformTimeout = time.Minute
+	DefaultEthRPCTimeout   = 30 * time.Second
+)
+
+func (cfg ChainConfig) GetIndexerTimeout() time.Duration {
+	return timeoutOrDefault(cfg.IndexerTimeout, DefaultIndexerTimeout)
+}
+
+func (cfg ChainConfig) GetPlatformTimeout() time.Duration {
+	return timeoutOrDefault(cfg.PlatformTimeout, DefaultPlatformTimeout)
+}
+
+func (cfg ChainConfig) GetEthRPCTimeout() time.Duration {
+	return timeoutOrDefault(cfg.EthRPCTimeout, DefaultEthRPCTimeout)
+}
+
+func timeoutOrDefault(timeout time.Duration, defaultTimeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// Network IDs of the nodes of the Flare networks by the chain ID of their
+// C-chain, e.g. songbird has network ID 5 and chain ID 19
+var FlareNetworkIDs = map[int]uint32{
+	14:  14,  // flare
+	19:  5,   // songbird
+	16:  7,   // coston
+	114: 114, // coston2
+	162: 162, // localflare
+}
+
+// Network ID of the node, see NetworkID
+func (cfg ChainConfig) GetNetworkID() (uint32, error) {
+	if cfg.NetworkID != 0 {
+		return cfg.NetworkID, nil
+	}
+	if networkID, ok := FlareNetworkIDs[cfg.ChainID]; ok {
+		return networkID, nil
+	}
+	return 0, fmt.Errorf("chain: network_id must be set for chain_id %d, which is not a Flare network", cfg.ChainID)
+}
+
+// Returns the hex encoded private key from the keystore file, the private key
+// file or the private key setting
 func (cfg ChainConfig) GetPrivateKey() (string, error) {
+	if cfg.KeystoreFile != "" {
+		return cfg.keystorePrivateKey()
+	}
 	if cfg.PrivateKeyFile == "" {
 		log.Print("WARNING: using private_key is deprecated, use private_key_file instead")
 		return cfg.PrivateKey, nil
@@ -67,29 +173,87 @@ func (cfg ChainConfig) GetPrivateKey() (string, error) {
 	}
 }
 
+func (cfg ChainConfig) keystorePrivateKey() (string, error) {
+	keyJSON, err := os.ReadFile(cfg.KeystoreFile)
+	if err != nil {
+		return "", fmt.Errorf("error opening keystore file: %w", err)
+	}
+	passphrase := cfg.KeystorePassphrase
+	if cfg.KeystorePassphraseFile != "" {
+		content, err := os.ReadFile(cfg.KeystorePassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("error opening keystore passphrase file: %w", err)
+		}
+		// Only the line break added by editors is removed, the passphrase may
+		// contain other whitespace
+		passphrase = strings.TrimRight(string(content), "\r\n")
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting keystore file: %w", err)
+	}
+	return hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)), nil
+}
+
 type EpochConfig struct {
 	First int64 `toml:"first" envconfig:"EPOCH_FIRST"`
 }
 
+// Node IDs and input addresses to exclude from (or exclusively include in) the
+// set of staking transactions that is voted on and mirrored. Voting, mirroring and
+// the mirroring API must use the same lists, otherwise merkle roots and proofs differ.
+// Empty include lists and zero limits do not restrict the set. The minimal
+// durations are staking rules, see StakingRulesConfig.
+type StakingFilterConfig struct {
+	IncludeNodeIDs   []string `toml:"include_node_ids" envconfig:"STAKING_FILTER_INCLUDE_NODE_IDS"`
+	ExcludeNodeIDs   []string `toml:"exclude_node_ids" envconfig:"STAKING_FILTER_EXCLUDE_NODE_IDS"`
+	IncludeAddresses []string `toml:"include_addresses" envconfig:"STAKING_FILTER_INCLUDE_ADDRESSES"`
+	ExcludeAddresses []string `toml:"exclude_addresses" envconfig:"STAKING_FILTER_EXCLUDE_ADDRESSES"`
+
+	MinWeight        uint64        `toml:"min_weight" envconfig:"STAKING_FILTER_MIN_WEIGHT"`                 // In nanoFLR
+	MaxDuration      time.Duration `toml:"max_duration" envconfig:"STAKING_FILTER_MAX_DURATION"`             // Maximal end time - start time
+	MaxFeePercentage uint32        `toml:"max_fee_percentage" envconfig:"STAKING_FILTER_MAX_FEE_PERCENTAGE"` // Validators (permissionless included) only, in units of 1/10000 %
+}
+
+// Staking rules of the network (on Flare: max 200M FLR per validator including
+// delegations, max 15 times the validator's own stake, validators staking for at
+// least 60 days and delegators for at least 14 days). Zero values are not checked.
+type StakingRulesConfig struct {
+	MaxValidatorStake    uint64        `toml:"max_validator_stake" envconfig:"STAKING_RULES_MAX_VALIDATOR_STAKE"`       // Max total stake of a validator in nanoFLR
+	MaxDelegationFactor  uint64        `toml:"max_delegation_factor" envconfig:"STAKING_RULES_MAX_DELEGATION_FACTOR"`   // Max total stake / own stake of a validator
+	MinValidatorDuration time.Duration `toml:"min_validator_duration" envconfig:"STAKING_RULES_MIN_VALIDATOR_DURATION"` // Minimal end time - start time
+	MinDelegatorDuration time.Duration `toml:"min_delegator_duration" envconfig:"STAKING_RULES_MIN_DELEGATOR_DURATION"` // Minimal end time - start time
+	// Exclude stakes violating the rules from the stakes voted on and mirrored,
+	// only the rules not depending on other stakes are applied
+	Filter bool `toml:"filter" envconfig:"STAKING_RULES_FILTER"`
+}
+
 type ContractAddresses struct {
 	Voting common.Address `toml:"voting" envconfig:"VOTING_CONTRACT_ADDRESS"`
 }
 
 func ParseConfigFile(cfg interface{}, fileName string, allowMissing bool) error {
+	_, err := DecodeConfigFile(cfg, fileName, allowMissing)
+	return err
+}
+
+// Same as ParseConfigFile, also returns the metadata needed to decode
+// toml.Primitive values of cfg
+func DecodeConfigFile(cfg interface{}, fileName string, allowMissing bool) (toml.MetaData, error) {
 	content, err := os.ReadFile(fileName)
 	if err != nil {
 		if allowMissing {
-			return nil
+			return toml.MetaData{}, nil
 		} else {
-			return fmt.Errorf("error opening config file: %w", err)
+			return toml.MetaData{}, fmt.Errorf("error opening config file: %w", err)
 		}
 	}
 
-	_, err = toml.Decode(string(content), cfg)
+	md, err := toml.Decode(string(content), cfg)
 	if err != nil {
-		return fmt.Errorf("error parsing config file: %w", err)
+		return md, fmt.Errorf("error parsing config file: %w", err)
 	}
-	return nil
+	return md, nil
 }
 
 func ReadEnv(cfg interface{}) error {
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPrivateKeyFromKeystore(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	account, err := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP).
+		ImportECDSA(privateKey, "secret passphrase")
+	require.NoError(t, err)
+	passphraseFile := path.Join(dir, "passphrase.txt")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("secret passphrase\n"), 0600))
+
+	cfg := ChainConfig{
+		PrivateKey:         "0x1234",
+		KeystoreFile:       account.URL.Path,
+		KeystorePassphrase: "secret passphrase",
+	}
+	key, err := cfg.GetPrivateKey()
+	require.NoError(t, err)
+	decoded, err := crypto.HexToECDSA(key)
+	require.NoError(t, err)
+	require.Equal(t, privateKey.D, decoded.D)
+
+	// The passphrase file takes precedence over the passphrase
+	cfg.KeystorePassphrase = "wrong"
+	cfg.KeystorePassphraseFile = passphraseFile
+	key2, err := cfg.GetPrivateKey()
+	require.NoError(t, err)
+	require.Equal(t, key, key2)
+
+	cfg.KeystorePassphraseFile = ""
+	_, err = cfg.GetPrivateKey()
+	require.ErrorIs(t, err, keystore.ErrDecrypt)
+}
+
+func TestDatetimePrecision(t *testing.T) {
+	precision, err := DBConfig{}.DatetimePrecision()
+	require.NoError(t, err)
+	require.Equal(t, DefaultTimestampPrecision, precision)
+
+	for _, p := range []int{0, 6} {
+		p := p
+		precision, err = DBConfig{TimestampPrecision: &p}.DatetimePrecision()
+		require.NoError(t, err)
+		require.Equal(t, p, precision)
+	}
+	for _, p := range []int{-1, 7} {
+		p := p
+		_, err = DBConfig{TimestampPrecision: &p}.DatetimePrecision()
+		require.Error(t, err)
+	}
+}
+
+func TestChainConfigTimeouts(t *testing.T) {
+	cfg := ChainConfig{}
+	require.Equal(t, DefaultIndexerTimeout, cfg.GetIndexerTimeout())
+	require.Equal(t, DefaultPlatformTimeout, cfg.GetPlatformTimeout())
+	require.Equal(t, DefaultEthRPCTimeout, cfg.GetEthRPCTimeout())
+
+	cfg = ChainConfig{IndexerTimeout: time.Minute, PlatformTimeout: 10 * time.Second, EthRPCTimeout: 5 * time.Second}
+	require.Equal(t, time.Minute, cfg.GetIndexerTimeout())
+	require.Equal(t, 10*time.Second, cfg.GetPlatformTimeout())
+	require.Equal(t, 5*time.Second, cfg.GetEthRPCTimeout())
+}
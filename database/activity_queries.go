@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AddressActivityType string
+
+const (
+	AddressActivityReceived     AddressActivityType = "RECEIVED"      // Output to the address
+	AddressActivitySpent        AddressActivityType = "SPENT"         // Input of the address
+	AddressActivityStakeCreated AddressActivityType = "STAKE_CREATED" // Staking transaction funded by the address
+	AddressActivityStakeStarted AddressActivityType = "STAKE_STARTED" // Start of the stake
+	AddressActivityStakeEnded   AddressActivityType = "STAKE_ENDED"   // End of the stake, the stake is returned
+	AddressActivityReward       AddressActivityType = "REWARD"        // Reward output to the address at the end of a stake
+)
+
+// Event of the activity timeline of an address
+type AddressActivity struct {
+	Type  AddressActivityType
+	Chain string // "P" or "X"
+	TxID  string
+	// Type of the P-chain or X-chain transaction
+	TxType string
+	// Amount of the output or input, weight of the stake
+	Amount uint64
+	// Node ID of a stake
+	NodeID string
+	// Time of the block (of the vertex for X-chain transactions), start or end
+	// time of a stake
+	Timestamp time.Time
+}
+
+var stakingTxTypes = []PChainTxType{
+	PChainAddValidatorTx, PChainAddDelegatorTx,
+	PChainAddPermissionlessValidatorTx, PChainAddPermissionlessDelegatorTx,
+}
+
+// Returns the activity of the address on the P-chain and the X-chain up to now,
+// the newest first. Request is paginated (offset, limit).
+func FetchAddressActivity(ctx context.Context, db *gorm.DB, address string, now time.Time, offset int, limit int) ([]AddressActivity, error) {
+	pChainTxs := pChainTxTable(db)
+	pChainOutputs := tableName(db, "PChainTxOutput") + " as outputs"
+	pChainInputs := tableName(db, "PChainTxInput") + " as inputs"
+	accepted := "p_chain_txes.status = ?"
+
+	received := db.Table(pChainOutputs).
+		Joins("join "+pChainTxs+" on p_chain_txes.tx_id = outputs.tx_id").
+		Where("outputs.address = ?", address).
+		Where("outputs.type NOT IN ?", []PChainOutputType{PChainStakeOutput, PChainRewardOutput}).
+		Where(accepted, PChainTxAccepted).
+		Select("? as type, 'P' as chain, p_chain_txes.tx_id, p_chain_txes.type as tx_type, outputs.amount, p_chain_txes.node_id, p_chain_txes.timestamp",
+			AddressActivityReceived)
+	// Reward outputs reference the staking transaction and are paid at its end
+	rewards := db.Table(pChainOutputs).
+		Joins("join "+pChainTxs+" on p_chain_txes.tx_id = outputs.tx_id").
+		Where("outputs.address = ?", address).
+		Where("outputs.type = ?", PChainRewardOutput).
+		Where(accepted, PChainTxAccepted).
+		Where("p_chain_txes.end_time <= ?", now).
+		Select("? as type, 'P' as chain, p_chain_txes.tx_id, p_chain_txes.type as tx_type, outputs.amount, p_chain_txes.node_id, p_chain_txes.end_time as timestamp",
+			AddressActivityReward)
+	spent := db.Table(pChainInputs).
+		Joins("join "+pChainTxs+" on p_chain_txes.tx_id = inputs.tx_id").
+		Where("inputs.address = ?", address).
+		Where(accepted, PChainTxAccepted).
+		Select("? as type, 'P' as chain, p_chain_txes.tx_id, p_chain_txes.type as tx_type, inputs.amount, p_chain_txes.node_id, p_chain_txes.timestamp",
+			AddressActivitySpent)
+
+	stakes := func(activity AddressActivityType, timeColumn string) *gorm.DB {
+		query := db.Table(pChainTxs).
+			Where("p_chain_txes.type IN ?", stakingTxTypes).
+			Where(accepted, PChainTxAccepted).
+			Where("p_chain_txes.tx_id IN (?)", db.Table(tableName(db, "PChainTxInput")).Select("tx_id").Where("address = ?", address)).
+			Select("? as type, 'P' as chain, p_chain_txes.tx_id, p_chain_txes.type as tx_type, p_chain_txes.weight as amount, p_chain_txes.node_id, p_chain_txes."+timeColumn+" as timestamp",
+				activity)
+		if timeColumn != "timestamp" {
+			query = query.Where("p_chain_txes."+timeColumn+" <= ?", now)
+		}
+		return query
+	}
+
+	xChainTxs := tableName(db, "XChainTx") + " as x_chain_txes"
+	xReceived := db.Table(tableName(db, "XChainTxOutput")+" as outputs").
+		Joins("join "+xChainTxs+" on x_chain_txes.tx_id = outputs.tx_id").
+		Where("outputs.address = ?", address).
+		Select("? as type, 'X' as chain, x_chain_txes.tx_id, x_chain_txes.type as tx_type, outputs.amount, '' as node_id, x_chain_txes.timestamp",
+			AddressActivityReceived)
+	xSpent := db.Table(tableName(db, "XChainTxInput")+" as inputs").
+		Joins("join "+xChainTxs+" on x_chain_txes.tx_id = inputs.tx_id").
+		Where("inputs.address = ?", address).
+		Select("? as type, 'X' as chain, x_chain_txes.tx_id, x_chain_txes.type as tx_type, inputs.amount, '' as node_id, x_chain_txes.timestamp",
+			AddressActivitySpent)
+
+	var activity []AddressActivity
+	err := db.WithContext(ctx).Raw(
+		"SELECT * FROM (? UNION ALL ? UNION ALL ? UNION ALL ? UNION ALL ? UNION ALL ? UNION ALL ? UNION ALL ?) AS activity "+
+			"ORDER BY timestamp DESC, chain, tx_id, type LIMIT ? OFFSET ?",
+		received, rewards, spent,
+		stakes(AddressActivityStakeCreated, "timestamp"),
+		stakes(AddressActivityStakeStarted, "start_time"),
+		stakes(AddressActivityStakeEnded, "end_time"),
+		xReceived, xSpent,
+		limit, offset,
+	).Scan(&activity).Error
+	return activity, err
+}
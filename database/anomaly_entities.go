@@ -0,0 +1,16 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with the anomalies found in the voting and mirroring data by the
+// anomalies cronjob. An anomaly is recorded once per type, epoch and transaction.
+type Anomaly struct {
+	BaseEntity
+	Type      AnomalyType `gorm:"type:varchar(20);uniqueIndex:idx_anomaly"`
+	Epoch     int64       `gorm:"uniqueIndex:idx_anomaly"`
+	TxID      string      `gorm:"type:varchar(50);uniqueIndex:idx_anomaly"` // P-chain staking transaction ID
+	Details   string      `gorm:"type:varchar(256)"`
+	Timestamp time.Time   `gorm:"index"` // Time when found
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Creates the anomalies which are not recorded yet and returns the number of
+// new anomalies
+func CreateAnomalies(ctx context.Context, db *gorm.DB, anomalies []*Anomaly) (int64, error) {
+	if len(anomalies) == 0 {
+		return 0, nil
+	}
+	result := db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(anomalies)
+	return result.RowsAffected, result.Error
+}
+
+func FetchAnomalies(ctx context.Context, db *gorm.DB, epoch int64) ([]Anomaly, error) {
+	var anomalies []Anomaly
+	err := db.WithContext(ctx).Where(&Anomaly{Epoch: epoch}).Order("id").Find(&anomalies).Error
+	return anomalies, err
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+)
+
+// Non-negative integer of any size, stored as DECIMAL(65,0). Sums of amounts
+// (in nanoFLR) use it, since they may overflow uint64, e.g. the total stake of
+// a network with more than 18.4 billion FLR staked. The value is immutable,
+// the zero value is 0.
+type BigInt struct {
+	value *big.Int
+}
+
+func NewBigInt(x uint64) BigInt {
+	return BigInt{value: new(big.Int).SetUint64(x)}
+}
+
+// Copy of the value
+func (b BigInt) Big() *big.Int {
+	if b.value == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(b.value)
+}
+
+func (b BigInt) Add(x uint64) BigInt {
+	return BigInt{value: b.Big().Add(b.Big(), new(big.Int).SetUint64(x))}
+}
+
+func (b BigInt) Cmp(other BigInt) int {
+	return b.Big().Cmp(other.Big())
+}
+
+func (b BigInt) String() string {
+	return b.Big().String()
+}
+
+func (BigInt) GormDataType() string {
+	return "decimal(65,0)"
+}
+
+func (b BigInt) Value() (driver.Value, error) {
+	return b.String(), nil
+}
+
+// Scans DECIMAL columns and aggregates, which the driver returns as strings,
+// and integer columns
+func (b *BigInt) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*b = BigInt{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("negative value %d", v)
+		}
+		*b = NewBigInt(uint64(v))
+		return nil
+	case uint64:
+		*b = NewBigInt(v)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into BigInt", src)
+	}
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid non-negative integer '%s'", s)
+	}
+	*b = BigInt{value: value}
+	return nil
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigIntSum(t *testing.T) {
+	sum := NewBigInt(math.MaxUint64).Add(math.MaxUint64)
+	require.Equal(t, "36893488147419103230", sum.String())
+	require.Equal(t, 1, sum.Cmp(NewBigInt(math.MaxUint64)))
+	require.Equal(t, "0", BigInt{}.String())
+
+	value, err := sum.Value()
+	require.NoError(t, err)
+	require.Equal(t, "36893488147419103230", value)
+}
+
+func TestBigIntScan(t *testing.T) {
+	var b BigInt
+	require.NoError(t, b.Scan([]byte("36893488147419103230")))
+	require.Equal(t, "36893488147419103230", b.String())
+	require.NoError(t, b.Scan(int64(7)))
+	require.Equal(t, "7", b.String())
+	require.NoError(t, b.Scan(nil))
+	require.Equal(t, "0", b.String())
+
+	require.Error(t, b.Scan("-1"))
+	require.Error(t, b.Scan("1.5"))
+	require.Error(t, b.Scan(1.5))
+}
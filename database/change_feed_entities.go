@@ -0,0 +1,26 @@
+package database
+
+import (
+	"time"
+)
+
+type ChangeOp string
+
+const (
+	ChangeCreated ChangeOp = "CREATE"
+	ChangeUpdated ChangeOp = "UPDATE"
+	ChangeDeleted ChangeOp = "DELETE"
+)
+
+// Table with an entry for each created, updated or deleted row of the indexed
+// chain data (P-chain blocks, transactions, inputs and outputs, X-chain
+// vertices, transactions, inputs and outputs). Downstream jobs tail the table
+// by id to replicate the data incrementally.
+type ChangeFeedEntry struct {
+	BaseEntity
+	Entity    string    `gorm:"type:varchar(64);not null;index"` // Table of the changed row, including the table prefix
+	EntityID  uint64    // ID of the changed row
+	Op        ChangeOp  `gorm:"type:varchar(10);not null"`
+	Height    uint64    `gorm:"index"` // Height of the block (vertex) of the changed row
+	Timestamp time.Time `gorm:"index"` // Time of the change
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Number of change feed entries inserted with a single query
+const changeFeedBatchSize = 1000
+
+// Row of the indexed chain data recorded in the change feed
+type changedRow struct {
+	id     uint64
+	height uint64
+}
+
+// Records the creation of the rows of the entity
+func recordCreated(db *gorm.DB, entityName string, rows []changedRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	entity := tableName(db, entityName)
+	now := db.NowFunc()
+	entries := make([]*ChangeFeedEntry, len(rows))
+	for i, r := range rows {
+		entries[i] = &ChangeFeedEntry{Entity: entity, EntityID: r.id, Op: ChangeCreated, Height: r.height, Timestamp: now}
+	}
+	return db.CreateInBatches(entries, changeFeedBatchSize).Error
+}
+
+// Records the change of the rows of the entity selected by the query, which
+// must select the id and the height of each row
+func recordChanges(db *gorm.DB, entityName string, op ChangeOp, rows *gorm.DB) error {
+	now := db.NowFunc()
+	changes := db.Table("(?) AS changes", rows).
+		Select("?, ?, ?, changes.id, ?, changes.height, ?", now, now, tableName(db, entityName), op, now)
+	return db.Exec("INSERT INTO "+tableName(db, "ChangeFeedEntry")+
+		" (created_at, updated_at, entity, entity_id, op, height, timestamp) ?", changes).Error
+}
+
+// Returns the query selecting the id and the height of the transaction of the
+// inputs or outputs (ioEntityName) of the transactions (txEntityName) with
+// heightColumn >= minHeight
+func txIORows(db *gorm.DB, ioEntityName string, txEntityName string, heightColumn string, minHeight uint64) *gorm.DB {
+	return db.Table(tableName(db, ioEntityName)+" AS ios").
+		Joins("JOIN "+tableName(db, txEntityName)+" AS txs ON txs.tx_id = ios.tx_id").
+		Where("txs."+heightColumn+" >= ?", minHeight).
+		Select("ios.id, txs." + heightColumn + " AS height")
+}
+
+// Updates the rows of the model selected by where and records the update of
+// each row in the change feed, with the height from heightColumn
+func updateWithChangeFeed(
+	db *gorm.DB, model interface{}, entityName string, heightColumn string,
+	values map[string]interface{}, where string, args ...interface{},
+) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Recorded before the update, which may change the selected rows
+		rows := tx.Model(model).Select("id, "+heightColumn+" AS height").Where(where, args...)
+		if err := recordChanges(tx, entityName, ChangeUpdated, rows); err != nil {
+			return err
+		}
+		return tx.Model(model).Where(where, args...).Updates(values).Error
+	})
+}
+
+// Returns at most limit change feed entries with id > afterID, ordered by id.
+// Only the entries of the table are returned if it is not empty.
+func FetchChangeFeed(ctx context.Context, db *gorm.DB, entity string, afterID uint64, limit int) ([]ChangeFeedEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := db.WithContext(ctx).Where("id > ?", afterID)
+	if len(entity) > 0 {
+		query = query.Where("entity = ?", entity)
+	}
+	var entries []ChangeFeedEntry
+	err := query.Order("id").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// Deletes the change feed entries created before the given time
+func DeleteChangeFeedBefore(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	result := db.WithContext(ctx).Where("timestamp < ?", before).Delete(&ChangeFeedEntry{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"time"
+)
+
+// Location of an event log of the mirroring or voting contract on the C-chain
+type ContractEvent struct {
+	BlockNumber uint64    `gorm:"uniqueIndex:idx_contract_event"`
+	LogIndex    uint      `gorm:"uniqueIndex:idx_contract_event"`
+	EthTxHash   string    `gorm:"type:varchar(66)"` // Hash of the C-chain transaction emitting the event
+	Timestamp   time.Time `gorm:"index"`            // Time of the block
+}
+
+// Table with the StakeConfirmed events of the mirroring contract, i.e., the
+// stakes verified and mirrored by the contract
+type MirroringStakeConfirmedEvent struct {
+	BaseEntity
+	ContractEvent
+	Contract  string `gorm:"type:varchar(42)"`       // Address of the mirroring contract
+	TxID      string `gorm:"type:varchar(50);index"` // P-chain staking transaction ID
+	Owner     string `gorm:"type:varchar(42)"`       // C-chain address of the staker
+	NodeID    string `gorm:"type:varchar(50);index"`
+	StakeHash string `gorm:"type:varchar(66)"` // Hash of the mirrored stake data
+	Amount    uint64 // Amount of the stake in nanoFLR (gwei), same as the weight of the P-chain stake
+}
+
+// Table with the PChainStakeMirrorVoteSubmitted events of the voting contract
+type VotingVoteSubmittedEvent struct {
+	BaseEntity
+	ContractEvent
+	Epoch      int64  `gorm:"index"`
+	Voter      string `gorm:"type:varchar(42)"`
+	MerkleRoot string `gorm:"type:varchar(66)"`
+}
+
+// Table with the PChainStakeMirrorVotingFinalized events of the voting
+// contract, i.e., the finalized merkle root of each epoch
+type VotingFinalizedEvent struct {
+	BaseEntity
+	ContractEvent
+	Epoch      int64  `gorm:"index"`
+	MerkleRoot string `gorm:"type:varchar(66)"`
+}
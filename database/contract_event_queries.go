@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Events of the mirroring and voting contracts in a range of C-chain blocks
+type ContractEvents struct {
+	StakesConfirmed []*MirroringStakeConfirmedEvent
+	VotesSubmitted  []*VotingVoteSubmittedEvent
+	Finalizations   []*VotingFinalizedEvent
+}
+
+func (e *ContractEvents) Len() int {
+	return len(e.StakesConfirmed) + len(e.VotesSubmitted) + len(e.Finalizations)
+}
+
+// Creates the events which are not stored yet, so that a range of blocks can be
+// indexed again
+func CreateContractEvents(ctx context.Context, db *gorm.DB, events *ContractEvents) error {
+	db = db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true})
+	if len(events.StakesConfirmed) > 0 {
+		if err := db.Create(events.StakesConfirmed).Error; err != nil {
+			return err
+		}
+	}
+	if len(events.VotesSubmitted) > 0 {
+		if err := db.Create(events.VotesSubmitted).Error; err != nil {
+			return err
+		}
+	}
+	if len(events.Finalizations) > 0 {
+		return db.Create(events.Finalizations).Error
+	}
+	return nil
+}
@@ -38,3 +38,23 @@ type UptimeAggregation struct {
 	// Length of the staking interval(s) intersecting with the epoch interval
 	StakingDuration int64
 }
+
+type CronjobRunStatus string
+
+const (
+	CronjobRunSucceeded CronjobRunStatus = "SUCCEEDED"
+	CronjobRunFailed    CronjobRunStatus = "FAILED"
+)
+
+// Table with an entry for each call of a cronjob
+type CronjobRun struct {
+	BaseEntity
+	Name       string           `gorm:"type:varchar(50);not null;index:idx_cronjob_run_name_started"`
+	Started    time.Time        `gorm:"index:idx_cronjob_run_name_started"`
+	Duration   int64            // Duration of the call in milliseconds
+	Status     CronjobRunStatus `gorm:"type:varchar(20)"`
+	Error      string           `gorm:"type:varchar(256)"`
+	Items      int              // Number of items (e.g. transactions or votes) processed by the call
+	FirstEpoch *int64           `gorm:"index"` // First epoch processed by the call, nil if none
+	LastEpoch  *int64           `gorm:"index"` // Last epoch processed by the call, nil if none
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func CreateCronjobRun(ctx context.Context, db *gorm.DB, run *CronjobRun) error {
+	return db.WithContext(ctx).Create(run).Error
+}
+
+// Deletes the runs of the cronjob started before the given time
+func DeleteCronjobRunsBefore(ctx context.Context, db *gorm.DB, name string, before time.Time) error {
+	return db.WithContext(ctx).Where("name = ? AND started < ?", name, before).Delete(&CronjobRun{}).Error
+}
+
+// Returns the runs of the cronjob (of all cronjobs if name is empty) which
+// processed the epoch (if not nil), the newest first
+func FetchCronjobRuns(ctx context.Context, db *gorm.DB, name string, epoch *int64, offset int, limit int) ([]CronjobRun, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := db.WithContext(ctx)
+	if len(name) > 0 {
+		query = query.Where("name = ?", name)
+	}
+	if epoch != nil {
+		query = query.Where("first_epoch <= ? AND last_epoch >= ?", *epoch, *epoch)
+	}
+	var runs []CronjobRun
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// Returns the last limit failed runs of all cronjobs, the newest first
+func FetchFailedCronjobRuns(ctx context.Context, db *gorm.DB, limit int) ([]CronjobRun, error) {
+	var runs []CronjobRun
+	err := db.WithContext(ctx).Where("status = ?", CronjobRunFailed).
+		Order("id desc").Limit(limit).Find(&runs).Error
+	return runs, err
+}
@@ -4,9 +4,13 @@ import (
 	"time"
 )
 
-// Abstact entity, all other entities should be derived from it
+// Abstact entity, all other entities should be derived from it. The creation
+// and update times of the rows are set by gorm (zero for rows created before
+// the columns were added).
 type BaseEntity struct {
-	ID uint64 `gorm:"primaryKey"`
+	ID        uint64 `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type Migration struct {
@@ -26,6 +30,15 @@ type State struct {
 	Updated        time.Time
 }
 
+// Runtime setting shared by all indexer instances using the database, e.g.,
+// "mirror.enabled" = "false" (see indexer/featureflags)
+type FeatureFlag struct {
+	BaseEntity
+	Name    string `gorm:"type:varchar(100);unique;not null"`
+	Value   string `gorm:"type:varchar(256)"`
+	Updated time.Time
+}
+
 // Abstact entity, common columns for X-chain and P-chain transaction inputs
 type TxInput struct {
 	BaseEntity
@@ -33,8 +46,8 @@ type TxInput struct {
 	TxID    string `gorm:"type:varchar(50);not null;index"` // Transaction ID
 	Amount  uint64
 	Address string `gorm:"type:varchar(60);index"`
-	OutTxID string `gorm:"type:varchar(50)"` // Transaction ID with output
-	OutIdx  uint32 // Index of the output
+	OutTxID string `gorm:"type:varchar(50);index:,composite:spent_output,priority:1"` // Transaction ID with output
+	OutIdx  uint32 `gorm:"index:,composite:spent_output,priority:2"`                  // Index of the output
 }
 
 // Abstact entity, common columns for X-chain and P-chain transaction inputs
@@ -42,6 +55,6 @@ type TxOutput struct {
 	BaseEntity
 	TxID    string `gorm:"type:varchar(50);not null;index"` // Transaction ID
 	Amount  uint64
-	Idx     uint32
+	Idx     uint32 // Index of the output
 	Address string `gorm:"type:varchar(60);index"`
 }
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with an entry for each transaction sent to a contract by a cronjob,
+// with the gas used and the fee paid
+type ContractSubmission struct {
+	BaseEntity
+	Cronjob   string    `gorm:"type:varchar(50);index:idx_contract_submissions_cronjob_epoch"` // Name of the cronjob
+	Epoch     *int64    `gorm:"index:idx_contract_submissions_cronjob_epoch"`                  // Epoch of the submission (nil if not bound to an epoch)
+	EthTxHash string    `gorm:"type:varchar(66);uniqueIndex"`                                  // Hash of the C-chain transaction
+	Sender    string    `gorm:"type:varchar(42)"`                                              // Address of the key that signed the transaction
+	Contract  string    `gorm:"type:varchar(42)"`                                              // Address of the called contract
+	GasUsed   uint64    // Gas used by the transaction
+	GasPrice  uint64    // Effective gas price in wei
+	Fee       uint64    // Fee paid (gas used times effective gas price) in wei
+	Reverted  bool      // Reverted transactions pay the fee as well
+	Timestamp time.Time `gorm:"index"` // Time the receipt was recorded
+}
+
+// Aggregated gas spend of the submissions of a cronjob in an epoch, or of all
+// epochs if Epoch is nil
+type GasSpend struct {
+	Cronjob     string
+	Epoch       *int64
+	Submissions int64
+	Reverted    int64
+	GasUsed     uint64
+	Fee         float64 // In FLR
+}
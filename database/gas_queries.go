@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateContractSubmission(ctx context.Context, db *gorm.DB, submission *ContractSubmission) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(submission).Error
+}
+
+// Epochs (inclusive) and cronjob of the aggregated submissions, unset fields
+// do not restrict them
+type GasSpendFilter struct {
+	Cronjob    string
+	FirstEpoch *int64
+	LastEpoch  *int64
+}
+
+func (f *GasSpendFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.Cronjob) > 0 {
+		query = query.Where("cronjob = ?", f.Cronjob)
+	}
+	if f.FirstEpoch != nil {
+		query = query.Where("epoch >= ?", *f.FirstEpoch)
+	}
+	if f.LastEpoch != nil {
+		query = query.Where("epoch <= ?", *f.LastEpoch)
+	}
+	return query
+}
+
+const gasSpendColumns = "cronjob, COUNT(*) AS submissions, SUM(reverted) AS reverted, " +
+	"SUM(gas_used) AS gas_used, SUM(fee) / 1e18 AS fee"
+
+// Gas spend of the submissions matching the filter per cronjob and epoch,
+// ordered by epoch and cronjob. Submissions not bound to an epoch are returned
+// first, with a nil epoch.
+func FetchGasSpendByEpoch(ctx context.Context, db *gorm.DB, filter *GasSpendFilter) ([]GasSpend, error) {
+	var spend []GasSpend
+	query := filter.apply(db.WithContext(ctx).Model(&ContractSubmission{}))
+	err := query.Select(gasSpendColumns + ", epoch").
+		Group("cronjob, epoch").
+		Order("epoch, cronjob").
+		Scan(&spend).Error
+	return spend, err
+}
+
+// Total gas spend of the submissions matching the filter per cronjob
+func FetchGasSpendTotals(ctx context.Context, db *gorm.DB, filter *GasSpendFilter) ([]GasSpend, error) {
+	var spend []GasSpend
+	query := filter.apply(db.WithContext(ctx).Model(&ContractSubmission{}))
+	err := query.Select(gasSpendColumns).
+		Group("cronjob").
+		Order("cronjob").
+		Scan(&spend).Error
+	return spend, err
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Secondary index which is not declared by the tags of the entity, so that it
+// is not created by the auto migration on startup. Creating an index on a large
+// table takes a while, the migration creating it records the duration.
+type Index struct {
+	Name    string
+	Columns []string
+	// Rows with the same values of the columns of a unique index are deleted
+	// before it is created, except the one with the lowest id
+	Unique bool
+}
+
+// Indexes of the queries of the stakers of a node (node_id and start_time), of
+// the burned fees in a time range (timestamp) and of the stakers active at a
+// time (type, start_time and end_time), which scan the table otherwise
+var PChainTxIndexes = []Index{
+	{Name: "idx_p_chain_tx_node_id_start_time", Columns: []string{"node_id", "start_time"}},
+	{Name: "idx_p_chain_tx_timestamp", Columns: []string{"timestamp"}},
+	{Name: "idx_p_chain_tx_staking_interval", Columns: []string{"type", "start_time", "end_time"}},
+}
+
+// Index of the query of the unmirrored transactions of an epoch. The
+// transactions have no mirrored flag, whether a transaction is mirrored is the
+// EXISTS subquery on the succeeded mirroring attempts of PChainTxFilter.Mirrored,
+// so instead of a (mirrored, timestamp) index of the transactions the subquery
+// is served by this index and the time range by idx_p_chain_tx_timestamp.
+var MirroringAttemptIndexes = []Index{
+	{Name: "idx_mirroring_attempts_tx_status", Columns: []string{"tx_id", "status"}},
+}
+
+// Unique indexes of the inputs and outputs of indexed transactions and of the
+// watchlist matches, which are skipped when they are already stored (see
+// ingest_queries.go). Databases indexed before may contain duplicates of them,
+// which is why they are not declared by the tags of the entities.
+var (
+	PChainTxInputIndexes  = []Index{{Name: "idx_p_chain_tx_inputs_tx_input", Columns: []string{"tx_id", "in_idx"}, Unique: true}}
+	PChainTxOutputIndexes = []Index{{Name: "idx_p_chain_tx_outputs_tx_output", Columns: []string{"tx_id", "idx"}, Unique: true}}
+	XChainTxInputIndexes  = []Index{{Name: "idx_x_chain_tx_inputs_tx_input", Columns: []string{"tx_id", "in_idx"}, Unique: true}}
+	XChainTxOutputIndexes = []Index{{Name: "idx_x_chain_tx_outputs_tx_output", Columns: []string{"tx_id", "idx"}, Unique: true}}
+	WatchlistMatchIndexes = []Index{{Name: "idx_watchlist_match", Columns: []string{"entry_id", "tx_id"}, Unique: true}}
+)
+
+// Creates the indexes of the table of the entity with the given struct name
+// which do not exist yet
+func CreateIndexes(ctx context.Context, db *gorm.DB, entityName string, indexes []Index) error {
+	db = db.WithContext(ctx)
+	table := tableName(db, entityName)
+	for _, index := range indexes {
+		if db.Migrator().HasIndex(table, index.Name) {
+			continue
+		}
+		if index.Unique {
+			if err := deleteDuplicateRows(db, table, index.Columns).Error; err != nil {
+				return err
+			}
+		}
+		if err := createIndex(db, table, index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createIndex(db *gorm.DB, table string, index Index) *gorm.DB {
+	sql := "CREATE INDEX ? ON ??"
+	if index.Unique {
+		sql = "CREATE UNIQUE INDEX ? ON ??"
+	}
+	return db.Exec(sql, clause.Column{Name: index.Name}, clause.Table{Name: table}, columnList(index.Columns))
+}
+
+// Deletes the rows of the table with the same values of the columns as a row
+// with a lower id
+func deleteDuplicateRows(db *gorm.DB, table string, columns []string) *gorm.DB {
+	conditions := make([]clause.Expression, 0, len(columns)+1)
+	for _, c := range columns {
+		conditions = append(conditions, clause.Eq{
+			Column: clause.Column{Table: "t1", Name: c},
+			Value:  clause.Column{Table: "t2", Name: c},
+		})
+	}
+	conditions = append(conditions, clause.Gt{
+		Column: clause.Column{Table: "t1", Name: "id"},
+		Value:  clause.Column{Table: "t2", Name: "id"},
+	})
+	return db.Exec("DELETE t1 FROM ? AS t1 JOIN ? AS t2 ON ?",
+		clause.Table{Name: table}, clause.Table{Name: table}, clause.And(conditions...))
+}
+
+func columnList(columns []string) []interface{} {
+	list := make([]interface{}, len(columns))
+	for i, c := range columns {
+		list[i] = clause.Column{Name: c}
+	}
+	return list
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteDuplicateRows(t *testing.T) {
+	db := dryRunDB(t)
+	stmt := deleteDuplicateRows(db, tableName(db, "PChainTxInput"), []string{"tx_id", "in_idx"}).Statement
+	require.Equal(t, "DELETE t1 FROM `x_p_chain_tx_inputs` AS t1 JOIN `x_p_chain_tx_inputs` AS t2 "+
+		"ON (`t1`.`tx_id` = `t2`.`tx_id` AND `t1`.`in_idx` = `t2`.`in_idx` AND `t1`.`id` > `t2`.`id`)",
+		stmt.SQL.String())
+	require.Empty(t, stmt.Vars)
+}
+
+func TestCreateUniqueIndex(t *testing.T) {
+	db := dryRunDB(t)
+	for _, unique := range []bool{false, true} {
+		index := Index{Name: "idx_watchlist_match", Columns: []string{"entry_id", "tx_id"}, Unique: unique}
+		stmt := createIndex(db, tableName(db, "WatchlistMatch"), index).Statement
+		create := "CREATE INDEX"
+		if unique {
+			create = "CREATE UNIQUE INDEX"
+		}
+		require.Equal(t, create+" `idx_watchlist_match` ON `x_watchlist_matches`(`entry_id`,`tx_id`)", stmt.SQL.String())
+	}
+}
+
+func TestCreateMirroringAttemptIndex(t *testing.T) {
+	db := dryRunDB(t)
+	stmt := createIndex(db, tableName(db, "MirroringAttempt"), MirroringAttemptIndexes[0]).Statement
+	require.Equal(t, "CREATE INDEX `idx_mirroring_attempts_tx_status` ON `x_mirroring_attempts`(`tx_id`,`status`)", stmt.SQL.String())
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// Rows of indexed containers are created only once: the rows of a batch which
+// are already stored, e.g. by a run of the same batch before a crash or during
+// a reindex, are skipped, so persisting a batch again does not fail and does
+// not duplicate rows. The unique indexes of the tables enforce the same.
+
+// Returns the rows which are not stored in the table of T and are not repeated
+// in rows. Stored keys are read from the rows selected by where, only the
+// columns needed by key are fetched.
+func newRows[T any, K comparable](
+	db *gorm.DB, rows []*T, columns string, key func(*T) K, where string, args ...interface{},
+) ([]*T, error) {
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	var stored []T
+	if err := db.Model(new(T)).Select(columns).Where(where, args...).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[K]bool, len(stored)+len(rows))
+	for i := range stored {
+		seen[key(&stored[i])] = true
+	}
+	result := make([]*T, 0, len(rows))
+	for _, row := range rows {
+		k := key(row)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// Key of an input, the transaction and the index of the input
+type inputKey struct {
+	txID  string
+	inIdx uint32
+}
+
+// Key of an output, the transaction and the index of the output
+type outputKey struct {
+	txID string
+	idx  uint32
+}
+
+func newInputs[T any](db *gorm.DB, ins []*T, input func(*T) *TxInput) ([]*T, error) {
+	txIDs := make([]string, len(ins))
+	for i, in := range ins {
+		txIDs[i] = input(in).TxID
+	}
+	return newRows(db, ins, "tx_id, in_idx", func(in *T) inputKey {
+		return inputKey{txID: input(in).TxID, inIdx: input(in).InIdx}
+	}, "tx_id IN ?", txIDs)
+}
+
+func newOutputs[T any](db *gorm.DB, outs []*T, output func(*T) *TxOutput) ([]*T, error) {
+	txIDs := make([]string, len(outs))
+	for i, out := range outs {
+		txIDs[i] = output(out).TxID
+	}
+	return newRows(db, outs, "tx_id, idx", func(out *T) outputKey {
+		return outputKey{txID: output(out).TxID, idx: output(out).Idx}
+	}, "tx_id IN ?", txIDs)
+}
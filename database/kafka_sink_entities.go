@@ -0,0 +1,18 @@
+package database
+
+import (
+	"time"
+)
+
+// Position of a Kafka sink: the database id of the next transaction to stream
+// and the end offset of partition 0 of the topic after the messages of the
+// streamed transactions. Both are updated in one statement after each written
+// batch, so messages written after the last update are found by comparing the
+// offsets (see indexer/sink).
+type KafkaSinkCheckpoint struct {
+	BaseEntity
+	Name    string `gorm:"type:varchar(50);uniqueIndex"`
+	NextID  uint64
+	Offset  int64 // Negative if not known yet
+	Updated time.Time
+}
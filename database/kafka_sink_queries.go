@@ -0,0 +1,25 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Checkpoint of the sink, nil if it has none yet
+func FetchKafkaSinkCheckpoint(ctx context.Context, db *gorm.DB, name string) (*KafkaSinkCheckpoint, error) {
+	var checkpoints []KafkaSinkCheckpoint
+	err := db.WithContext(ctx).Where("name = ?", name).Limit(1).Find(&checkpoints).Error
+	if err != nil || len(checkpoints) == 0 {
+		return nil, err
+	}
+	return &checkpoints[0], nil
+}
+
+func SaveKafkaSinkCheckpoint(ctx context.Context, db *gorm.DB, checkpoint *KafkaSinkCheckpoint) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"next_id", "offset", "updated"}),
+	}).Create(checkpoint).Error
+}
@@ -0,0 +1,16 @@
+package database
+
+import (
+	"time"
+)
+
+// Operator of a validator node, loaded from the labels file of the indexer or
+// set with the admin API of the services
+type ValidatorLabel struct {
+	BaseEntity
+	NodeID       string `gorm:"type:varchar(50);uniqueIndex"`
+	Name         string `gorm:"type:varchar(100)"`
+	Website      string `gorm:"type:varchar(200)"`
+	FtsoProvider string `gorm:"type:varchar(42)"` // C-chain address of the FTSO data provider
+	Updated      time.Time
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"flare-indexer/utils/errcode"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Creates the labels or updates the labels of the same node IDs
+func UpsertValidatorLabels(ctx context.Context, db *gorm.DB, labels []*ValidatorLabel) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "node_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "website", "ftso_provider", "updated"}),
+	}).Create(labels).Error
+}
+
+func DeleteValidatorLabel(ctx context.Context, db *gorm.DB, nodeID string) error {
+	result := db.WithContext(ctx).Where("node_id = ?", nodeID).Delete(&ValidatorLabel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errcode.Wrap(gorm.ErrRecordNotFound, errcode.NotFound, "label not found")
+	}
+	return nil
+}
+
+// Returns the labels of the node IDs, all labels if nodeIDs is nil
+func FetchValidatorLabels(ctx context.Context, db *gorm.DB, nodeIDs []string) ([]ValidatorLabel, error) {
+	query := db.WithContext(ctx)
+	if nodeIDs != nil {
+		if len(nodeIDs) == 0 {
+			return nil, nil
+		}
+		query = query.Where("node_id IN ?", nodeIDs)
+	}
+	var labels []ValidatorLabel
+	err := query.Order("node_id").Find(&labels).Error
+	return labels, err
+}
+
+// Returns the number of labels and the last update of a label, which change
+// whenever a label is set or removed
+func FetchValidatorLabelsVersion(ctx context.Context, db *gorm.DB) (int64, time.Time, error) {
+	var version struct {
+		Count   int64
+		Updated *time.Time
+	}
+	err := db.WithContext(ctx).Model(&ValidatorLabel{}).
+		Select("COUNT(*) AS count, MAX(updated) AS updated").
+		Scan(&version).Error
+	if err != nil || version.Updated == nil {
+		return version.Count, time.Time{}, err
+	}
+	return version.Count, *version.Updated, nil
+}
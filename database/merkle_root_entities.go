@@ -0,0 +1,20 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with the history of the merkle roots of the epochs computed by the
+// voting cronjob and the vote command. A root is recorded once per epoch, a
+// different root of the same epoch (e.g. after reindexing) is recorded as well.
+type EpochMerkleRoot struct {
+	BaseEntity
+	Epoch      int64  `gorm:"uniqueIndex:idx_epoch_merkle_root"`
+	MerkleRoot string `gorm:"type:varchar(66);uniqueIndex:idx_epoch_merkle_root"` // Hex, "0x" prefix
+	TxCount    int    // Number of staking transactions in the tree
+	// Address of the key which signed the root and the signature (hex, "0x"
+	// prefix), empty if the roots are not signed
+	Signer    string    `gorm:"type:varchar(42)"`
+	Signature string    `gorm:"type:varchar(132)"`
+	Timestamp time.Time `gorm:"index"` // Time when first computed
+}
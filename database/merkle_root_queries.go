@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Creates the merkle root if the same root of the epoch is not recorded yet
+func CreateEpochMerkleRoot(ctx context.Context, db *gorm.DB, root *EpochMerkleRoot) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(root).Error
+}
+
+// Fetch the merkle roots of the epochs in [firstEpoch, lastEpoch] (no bound if
+// nil), the newest epoch first and the roots of an epoch in the order of their
+// computation
+func FetchEpochMerkleRoots(ctx context.Context, db *gorm.DB, firstEpoch *int64, lastEpoch *int64, offset int, limit int) ([]EpochMerkleRoot, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := db.WithContext(ctx)
+	if firstEpoch != nil {
+		query = query.Where("epoch >= ?", *firstEpoch)
+	}
+	if lastEpoch != nil {
+		query = query.Where("epoch <= ?", *lastEpoch)
+	}
+	var roots []EpochMerkleRoot
+	err := query.Order("epoch DESC").Order("id").Offset(offset).Limit(limit).Find(&roots).Error
+	return roots, err
+}
@@ -16,6 +16,10 @@ func (out TxOutput) Addr() string {
 	return out.Address
 }
 
+func (out TxOutput) Amt() uint64 {
+	return out.Amount
+}
+
 func (out TxOutput) Tx() string {
 	return out.TxID
 }
@@ -28,6 +32,10 @@ func (in TxInput) Addr() string {
 	return in.Address
 }
 
+func (in TxInput) Amt() uint64 {
+	return in.Amount
+}
+
 func (in TxInput) OutTx() string {
 	return in.OutTxID
 }
@@ -39,3 +47,7 @@ func (in TxInput) OutIndex() uint32 {
 func (in *TxInput) UpdateAddr(addr string) {
 	in.Address = addr
 }
+
+func (in *TxInput) UpdateAmount(amount uint64) {
+	in.Amount = amount
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with an entry for each attempted submission of a staking transaction
+// to the mirroring contract
+type MirroringAttempt struct {
+	BaseEntity
+	TxID         string                 `gorm:"type:varchar(50);not null;index"` // P-chain staking transaction ID
+	InputAddress string                 `gorm:"type:varchar(60)"`                // Input address of the mirrored stake
+	Epoch        int64                  // Epoch of the mirrored stake
+	EthTxHash    string                 `gorm:"type:varchar(66)"` // Hash of the C-chain transaction (empty if not sent)
+	Sender       string                 `gorm:"type:varchar(42)"` // Address of the key that signed the C-chain transaction
+	Contract     string                 `gorm:"type:varchar(42)"` // Address of the mirroring contract (empty if unknown)
+	GasUsed      uint64                 // Gas used by the C-chain transaction (if mined)
+	Status       MirroringAttemptStatus `gorm:"type:varchar(20)"`
+	RevertReason string                 `gorm:"type:varchar(256)"` // Error returned by the node or contract
+	Timestamp    time.Time              `gorm:"index"`             // Time of the attempt
+	// Set if the attempt was created or corrected by the reconciliation with the
+	// state of the mirroring contract
+	Reconciled bool
+}
+
+// Table with an entry for each epoch processed by the mirroring cronjob (or
+// the mirror command), also if it had no stakes to mirror, so that epochs
+// without stakes can be told apart from epochs which were not processed
+type MirroringEpoch struct {
+	BaseEntity
+	Epoch     int64     `gorm:"uniqueIndex"`
+	TxCount   int       // Number of staking transactions of the epoch eligible for mirroring
+	Timestamp time.Time // Time of the last processing
+}
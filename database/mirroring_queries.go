@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateMirroringAttempt(ctx context.Context, db *gorm.DB, attempt *MirroringAttempt) error {
+	return db.WithContext(ctx).Create(attempt).Error
+}
+
+func UpdateMirroringAttempt(ctx context.Context, db *gorm.DB, attempt *MirroringAttempt) error {
+	return db.WithContext(ctx).Save(attempt).Error
+}
+
+func FetchMirroringAttempts(ctx context.Context, db *gorm.DB, txID string) ([]MirroringAttempt, error) {
+	var attempts []MirroringAttempt
+	err := db.WithContext(ctx).Where(&MirroringAttempt{TxID: txID}).Order("timestamp asc").Find(&attempts).Error
+	return attempts, err
+}
+
+// Successful mirroring attempts of the transactions, in any epoch
+func FetchSucceededMirroringAttempts(ctx context.Context, db *gorm.DB, txIDs []string) ([]MirroringAttempt, error) {
+	var attempts []MirroringAttempt
+	if len(txIDs) == 0 {
+		return attempts, nil
+	}
+	err := db.WithContext(ctx).
+		Where("tx_id IN ?", txIDs).
+		Where(&MirroringAttempt{Status: MirroringAttemptSucceeded}).
+		Order("id").Find(&attempts).Error
+	return attempts, err
+}
+
+// Attempts of the transactions which mirrored the stakes, successfully or by
+// another provider, in any epoch
+func FetchMirroredAttempts(ctx context.Context, db *gorm.DB, txIDs []string) ([]MirroringAttempt, error) {
+	var attempts []MirroringAttempt
+	if len(txIDs) == 0 {
+		return attempts, nil
+	}
+	err := db.WithContext(ctx).
+		Where("tx_id IN ?", txIDs).
+		Where("status IN ?", []MirroringAttemptStatus{MirroringAttemptSucceeded, MirroringAttemptAlreadyMirrored}).
+		Order("id").Find(&attempts).Error
+	return attempts, err
+}
+
+// Attempts of the epoch whose transaction was sent but not confirmed yet
+func FetchPendingMirroringAttempts(ctx context.Context, db *gorm.DB, epoch int64) ([]MirroringAttempt, error) {
+	var attempts []MirroringAttempt
+	err := db.WithContext(ctx).
+		Where("epoch = ?", epoch).
+		Where(&MirroringAttempt{Status: MirroringAttemptPending}).
+		Order("id").Find(&attempts).Error
+	return attempts, err
+}
+
+// Records the processing of the epoch, a later processing replaces the entry
+func UpsertMirroringEpoch(ctx context.Context, db *gorm.DB, epoch *MirroringEpoch) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "epoch"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tx_count", "timestamp"}),
+	}).Create(epoch).Error
+}
+
+// Processing of the epoch, nil if it was not processed
+func FetchMirroringEpoch(ctx context.Context, db *gorm.DB, epoch int64) (*MirroringEpoch, error) {
+	var entries []MirroringEpoch
+	err := db.WithContext(ctx).Where("epoch = ?", epoch).Limit(1).Find(&entries).Error
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/utils/errcode"
+
+	"gorm.io/gorm"
+)
+
+// Creates the blocks which are not stored yet and records them in the change
+// feed
+func CreatePChainBlocks(ctx context.Context, db *gorm.DB, blocks []*PChainBlock) error {
+	db = db.WithContext(ctx)
+	heights := make([]uint64, len(blocks))
+	for i, b := range blocks {
+		heights[i] = b.Height
+	}
+	blocks, err := newRows(db, blocks, "height", func(b *PChainBlock) uint64 { return b.Height }, "height IN ?", heights)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 { // attempt to create from an empty slice returns error
+		return nil
+	}
+	if err := db.Create(blocks).Error; err != nil {
+		return err
+	}
+	rows := make([]changedRow, len(blocks))
+	for i, b := range blocks {
+		rows[i] = changedRow{id: b.ID, height: b.Height}
+	}
+	return recordCreated(db, "PChainBlock", rows)
+}
+
+// Fetch the block with the given height, returns gorm.ErrRecordNotFound (with
+// the code errcode.ChainLag if the height is above the last indexed block) if
+// the block is not indexed
+func FetchPChainBlock(ctx context.Context, db *gorm.DB, height uint64) (*PChainBlock, error) {
+	db = db.WithContext(ctx)
+	var block PChainBlock
+	err := db.Where("height = ?", height).Take(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, pChainBlockNotFound(db, height, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Error of the block with the given height which is not stored, the chain lag
+// error if the height is above the last stored block
+func pChainBlockNotFound(db *gorm.DB, height uint64, notFound error) error {
+	var maxHeight *uint64
+	if err := db.Model(&PChainBlock{}).Select("MAX(height)").Scan(&maxHeight).Error; err != nil {
+		return err
+	}
+	if maxHeight == nil || height > *maxHeight {
+		return errcode.Wrap(notFound, errcode.ChainLag, "block %d is not indexed yet", height)
+	}
+	return errcode.Wrap(notFound, errcode.NotFound, "block %d not found", height)
+}
+
+// Fetch the block stored with the given container index of the avalanchego
+// indexer, returns gorm.ErrRecordNotFound if there is no such block
+func FetchPChainBlockByContainerIndex(ctx context.Context, db *gorm.DB, index uint64) (*PChainBlock, error) {
+	var block PChainBlock
+	err := db.WithContext(ctx).Where("container_index = ?", index).Take(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errcode.Wrap(err, errcode.NotFound, "no block with container index %d", index)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Set the container index of blocks without one to the index of the block in the
+// indexer of a node indexing the chain from the start, i.e. the height minus one
+// (the genesis block is not indexed)
+func UpdatePChainBlockContainerIndexes(ctx context.Context, db *gorm.DB) error {
+	return updateWithChangeFeed(db.WithContext(ctx), &PChainBlock{}, "PChainBlock", "height",
+		map[string]interface{}{"container_index": gorm.Expr("height - 1")},
+		"container_index IS NULL AND height > 0")
+}
+
+// Fetch blocks ordered by height, the newest first
+func FetchPChainBlocks(ctx context.Context, db *gorm.DB, offset int, limit int) ([]PChainBlock, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var blocks []PChainBlock
+	err := db.WithContext(ctx).Order("height DESC").Offset(offset).Limit(limit).Find(&blocks).Error
+	return blocks, err
+}
+
+// Fetch ids of the transactions in the block with the given height, in the order
+// of execution
+func FetchPChainBlockTxIDs(ctx context.Context, db *gorm.DB, height uint64) ([]string, error) {
+	var txIDs []string
+	err := db.WithContext(ctx).Model(&PChainTx{}).
+		Where("block_height = ? AND tx_id IS NOT NULL", height).
+		Order("block_tx_index, id").
+		Pluck("tx_id", &txIDs).Error
+	return txIDs, err
+}
+
+// Deletes the blocks with a container index >= fromIndex, the transactions of
+// these and later blocks and their inputs, outputs and signers, so the containers
+// can be indexed again. The deleted rows are kept as tombstones with the given
+// reason. Returns the number of deleted blocks.
+func DeletePChainEntitiesFromIndex(ctx context.Context, db *gorm.DB, fromIndex uint64, reason string) (int64, error) {
+	db = db.WithContext(ctx)
+	var minHeight *uint64
+	err := db.Model(&PChainBlock{}).Where("container_index >= ?", fromIndex).
+		Select("MIN(height)").Scan(&minHeight).Error
+	if err != nil || minHeight == nil {
+		return 0, err
+	}
+	now := db.NowFunc()
+	txIDs := db.Model(&PChainTx{}).Select("tx_id").Where("block_height >= ?", *minHeight)
+
+	// Signers are recovered from the transactions again, they are not kept as tombstones
+	if err := db.Where("tx_id IN (?)", txIDs).Delete(&PChainTxSigner{}).Error; err != nil {
+		return 0, err
+	}
+
+	inputs := db.Where("tx_id IN (?)", txIDs)
+	err = recordChanges(db, "PChainTxInput", ChangeDeleted, txIORows(db, "PChainTxInput", "PChainTx", "block_height", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[PChainTxInput](db, inputs, "PChainTxInput", reason, now); err != nil {
+		return 0, err
+	}
+	if err := inputs.Delete(&PChainTxInput{}).Error; err != nil {
+		return 0, err
+	}
+	outputs := db.Where("tx_id IN (?)", txIDs)
+	err = recordChanges(db, "PChainTxOutput", ChangeDeleted, txIORows(db, "PChainTxOutput", "PChainTx", "block_height", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[PChainTxOutput](db, outputs, "PChainTxOutput", reason, now); err != nil {
+		return 0, err
+	}
+	if err := outputs.Delete(&PChainTxOutput{}).Error; err != nil {
+		return 0, err
+	}
+	txs := db.Where("block_height >= ?", *minHeight)
+	err = recordChanges(db, "PChainTx", ChangeDeleted,
+		db.Model(&PChainTx{}).Select("id, block_height AS height").Where("block_height >= ?", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[PChainTx](db, txs, "PChainTx", reason, now); err != nil {
+		return 0, err
+	}
+	if err := txs.Delete(&PChainTx{}).Error; err != nil {
+		return 0, err
+	}
+	blocks := db.Where("height >= ?", *minHeight)
+	err = recordChanges(db, "PChainBlock", ChangeDeleted,
+		db.Model(&PChainBlock{}).Select("id, height").Where("height >= ?", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[PChainBlock](db, blocks, "PChainBlock", reason, now); err != nil {
+		return 0, err
+	}
+	result := blocks.Delete(&PChainBlock{})
+	return result.RowsAffected, result.Error
+}
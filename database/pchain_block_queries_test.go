@@ -0,0 +1,65 @@
+//go:build integration
+// +build integration
+
+package database
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestFetchPChainBlockAtTimeWithGaps(t *testing.T) {
+	db, err := ConnectAndInitializeTestDB(&config.DBConfig{
+		Username: MysqlTestUser,
+		Password: MysqlTestPassword,
+		Host:     MysqlTestHost,
+		Port:     MysqlTestPort,
+		Database: "flare_indexer_indexer",
+	}, false)
+	require.NoError(t, err)
+
+	// The blocks are rolled back after the test
+	tx := db.Begin()
+	defer tx.Rollback()
+	require.NoError(t, tx.Where("1 = 1").Delete(&PChainBlock{}).Error)
+
+	ctx := context.Background()
+	start := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	_, err = FetchPChainBlockAtTime(ctx, tx, start)
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	// Heights 12-14 and 16-19 are not indexed, e.g. blocks without transactions
+	// of the indexed types
+	for i, height := range []uint64{10, 11, 15, 20} {
+		require.NoError(t, tx.Create(&PChainBlock{
+			Height:    height,
+			BlockID:   "block" + string(rune('a'+i)),
+			Timestamp: start.Add(time.Duration(height) * time.Minute),
+		}).Error)
+	}
+
+	for _, test := range []struct {
+		minutes int
+		height  uint64
+	}{
+		{10, 10},
+		{11, 11},
+		{14, 11},
+		{15, 15},
+		{19, 15},
+		{25, 20},
+	} {
+		block, err := FetchPChainBlockAtTime(ctx, tx, start.Add(time.Duration(test.minutes)*time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, test.height, block.BlockHeight, "minute %d", test.minutes)
+	}
+
+	_, err = FetchPChainBlockAtTime(ctx, tx, start.Add(5*time.Minute))
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
@@ -7,7 +7,7 @@ import (
 // Table with indexed data for a P-chain transaction
 type PChainTx struct {
 	BaseEntity
-	Type          PChainTxType    `gorm:"type:varchar(20);index"`    // Transaction type
+	Type          PChainTxType    `gorm:"type:varchar(40);index"`    // Transaction type
 	TxID          *string         `gorm:"type:varchar(50);unique"`   // Transaction ID
 	BlockID       string          `gorm:"type:varchar(50);not null"` // Block ID
 	BlockType     PChainBlockType `gorm:"type:varchar(20)"`          // Block type (proposal, accepted, rejected, etc.)
@@ -20,10 +20,34 @@ type PChainTx struct {
 	EndTime       *time.Time      `gorm:"index"`            // End time of validator or delegator (when NodeID is not null)
 	Time          *time.Time      // Chain time (in case of advance time transaction)
 	Weight        uint64          // Weight (stake amount) (when NodeID is not null)
-	RewardsOwner  string          `gorm:"type:varchar(60)"` // Rewards owner address (in case of add delegator or validator transaction)
-	Memo          string          `gorm:"type:varchar(256)"`
+	RewardsOwner  string          `gorm:"type:varchar(60)"`        // Rewards owner address (in case of add delegator or validator transaction)
+	Memo          string          `gorm:"type:varchar(514);index"` // Memo (hex, "0x" prefix), empty if the transaction has no memo
 	Bytes         []byte          `gorm:"type:mediumblob"`
 	FeePercentage uint32          // Fee percentage (in case of add validator transaction)
+	Fee           uint64          // Burned amount (inputs minus outputs)
+	// BLS public key and proof of possession (hex, "0x" prefix) of a permissionless
+	// validator of the primary network, empty if the transaction has no BLS key
+	BlsPublicKey         string `gorm:"type:varchar(98)"`
+	BlsProofOfPossession string `gorm:"type:varchar(194)"`
+	// Position of the transaction in its block, in the order of execution
+	// (transactions of a Banff proposal block before the proposal transaction)
+	BlockTxIndex uint32
+	// Only accepted transactions take effect, e.g. are voted on and mirrored
+	Status PChainTxStatus `gorm:"type:varchar(20);default:'ACCEPTED'"`
+}
+
+// Table with indexed P-chain blocks, including blocks without transactions
+type PChainBlock struct {
+	BaseEntity
+	Height    uint64    `gorm:"unique"`                           // Block height
+	BlockID   string    `gorm:"type:varchar(50);unique;not null"` // Block (container) ID
+	ParentID  string    `gorm:"type:varchar(50)"`                 // ID of the parent block
+	Timestamp time.Time `gorm:"index"`                            // Time when accepted by the node
+	Proposer  string    `gorm:"type:varchar(50)"`                 // Node ID of the proposer, empty if built without a proposer
+	TxCount   int       // Number of transactions in the block
+	// Index of the block in the avalanchego indexer of the node, derived from
+	// the height for blocks indexed before it was stored
+	ContainerIndex *uint64 `gorm:"unique"`
 }
 
 type PChainTxInput struct {
@@ -34,3 +58,13 @@ type PChainTxOutput struct {
 	TxOutput
 	Type PChainOutputType `gorm:"type:varchar(20)"` // Transaction output type (default or "stake" output)
 }
+
+// Table with the addresses of the keys which signed the inputs of P-chain staking
+// transactions, recovered from the signatures of their credentials
+type PChainTxSigner struct {
+	BaseEntity
+	TxID    string `gorm:"type:varchar(50);uniqueIndex:idx_p_chain_tx_signer,priority:1"` // Transaction ID
+	CredIdx uint32 `gorm:"uniqueIndex:idx_p_chain_tx_signer,priority:2"`                  // Index of the credential
+	SigIdx  uint32 `gorm:"uniqueIndex:idx_p_chain_tx_signer,priority:3"`                  // Index of the signature in the credential
+	Address string `gorm:"type:varchar(60);index"`                                        // Address of the signing key
+}
@@ -1,7 +1,10 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"flare-indexer/utils"
+	"flare-indexer/utils/errcode"
 	"fmt"
 	"time"
 
@@ -12,13 +15,96 @@ var (
 	errInvalidTransactionType = fmt.Errorf("invalid transaction type")
 )
 
-func FetchPChainTxOutputs(db *gorm.DB, ids []string) ([]PChainTxOutput, error) {
+func FetchPChainTxOutputs(ctx context.Context, db *gorm.DB, ids []string) ([]PChainTxOutput, error) {
 	var txs []PChainTxOutput
-	err := db.Where("tx_id IN ?", ids).Find(&txs).Error
+	err := db.WithContext(ctx).Where("tx_id IN ?", ids).Find(&txs).Error
 	return txs, err
 }
 
-func CreatePChainEntities(db *gorm.DB, txs []*PChainTx, ins []*PChainTxInput, outs []*PChainTxOutput) error {
+func FetchPChainTxInputs(ctx context.Context, db *gorm.DB, ids []string) ([]PChainTxInput, error) {
+	var ins []PChainTxInput
+	err := db.WithContext(ctx).Where("tx_id IN ?", ids).Order("in_idx").Find(&ins).Error
+	return ins, err
+}
+
+// Returns at most limit inputs with database id >= fromID whose address or
+// amount was not resolved when they were indexed, ordered by id
+func FetchUnresolvedPChainTxInputs(ctx context.Context, db *gorm.DB, fromID uint64, limit int) ([]PChainTxInput, error) {
+	var ins []PChainTxInput
+	err := db.WithContext(ctx).
+		Where("id >= ? AND (address IS NULL OR address = '' OR amount IS NULL OR amount = 0)", fromID).
+		Order("id").Limit(limit).Find(&ins).Error
+	return ins, err
+}
+
+// Stores the address and the amount of the input and records the update in the
+// change feed, with the height of the transaction of the input
+func UpdatePChainTxInput(ctx context.Context, db *gorm.DB, in *PChainTxInput) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rows := txIORows(tx, "PChainTxInput", "PChainTx", "block_height", 0).Where("ios.id = ?", in.ID)
+		if err := recordChanges(tx, "PChainTxInput", ChangeUpdated, rows); err != nil {
+			return err
+		}
+		return tx.Model(&PChainTxInput{}).Where("id = ?", in.ID).
+			Updates(map[string]interface{}{"address": in.Address, "amount": in.Amount}).Error
+	})
+}
+
+// Returns at most limit transactions (and blocks without transactions) with
+// database id >= fromID, ordered by id
+func FetchPChainTxsFromID(ctx context.Context, db *gorm.DB, fromID uint64, limit int) ([]PChainTx, error) {
+	var txs []PChainTx
+	err := db.WithContext(ctx).Where("id >= ?", fromID).Order("id").Limit(limit).Find(&txs).Error
+	return txs, err
+}
+
+func UpdatePChainTxFee(ctx context.Context, db *gorm.DB, id uint64, fee uint64) error {
+	return updatePChainTxs(db.WithContext(ctx), map[string]interface{}{"fee": fee}, "id = ?", id)
+}
+
+func UpdatePChainTxBLSKey(ctx context.Context, db *gorm.DB, id uint64, publicKey string, proofOfPossession string) error {
+	return updatePChainTxs(db.WithContext(ctx),
+		map[string]interface{}{"bls_public_key": publicKey, "bls_proof_of_possession": proofOfPossession},
+		"id = ?", id)
+}
+
+func UpdatePChainTxMemo(ctx context.Context, db *gorm.DB, id uint64, memo string) error {
+	return updatePChainTxs(db.WithContext(ctx), map[string]interface{}{"memo": memo}, "id = ?", id)
+}
+
+func UpdatePChainTxNodeID(ctx context.Context, db *gorm.DB, id uint64, nodeID string) error {
+	return updatePChainTxs(db.WithContext(ctx), map[string]interface{}{"node_id": nodeID}, "id = ?", id)
+}
+
+func UpdatePChainTxStatus(ctx context.Context, db *gorm.DB, id uint64, status PChainTxStatus) error {
+	return updatePChainTxs(db.WithContext(ctx), map[string]interface{}{"status": status}, "id = ?", id)
+}
+
+// Set the status of the proposed transaction of the proposal block with the given
+// height, decided by the option block following it
+func UpdatePChainProposalTxStatus(ctx context.Context, db *gorm.DB, height uint64, status PChainTxStatus) error {
+	return updatePChainTxs(db.WithContext(ctx), map[string]interface{}{"status": status},
+		"block_height = ? AND status = ?", height, PChainTxProposed)
+}
+
+func UpdatePChainTxBlockTxIndex(ctx context.Context, db *gorm.DB, id uint64, txIndex uint32) error {
+	return updatePChainTxs(db.WithContext(ctx), map[string]interface{}{"block_tx_index": txIndex}, "id = ?", id)
+}
+
+// Updates the transactions selected by where and records the updates in the
+// change feed
+func updatePChainTxs(db *gorm.DB, values map[string]interface{}, where string, args ...interface{}) error {
+	return updateWithChangeFeed(db, &PChainTx{}, "PChainTx", "block_height", values, where, args...)
+}
+
+// Creates the transactions and their inputs and outputs which are not stored yet
+// and records them in the change feed
+func CreatePChainEntities(ctx context.Context, db *gorm.DB, txs []*PChainTx, ins []*PChainTxInput, outs []*PChainTxOutput) error {
+	db = db.WithContext(ctx)
+	txs, ins, outs, err := newPChainEntities(db, txs, ins, outs)
+	if err != nil {
+		return err
+	}
 	if len(txs) > 0 { // attempt to create from an empty slice returns error
 		err := db.Create(txs).Error
 		if err != nil {
@@ -32,16 +118,82 @@ func CreatePChainEntities(db *gorm.DB, txs []*PChainTx, ins []*PChainTxInput, ou
 		}
 	}
 	if len(outs) > 0 {
-		return db.Create(outs).Error
+		err := db.Create(outs).Error
+		if err != nil {
+			return err
+		}
 	}
-	return nil
+
+	heights := make(map[string]uint64, len(txs))
+	txRows := make([]changedRow, len(txs))
+	for i, tx := range txs {
+		if tx.TxID != nil {
+			heights[*tx.TxID] = tx.BlockHeight
+		}
+		txRows[i] = changedRow{id: tx.ID, height: tx.BlockHeight}
+	}
+	inRows := make([]changedRow, len(ins))
+	for i, in := range ins {
+		inRows[i] = changedRow{id: in.ID, height: heights[in.TxID]}
+	}
+	outRows := make([]changedRow, len(outs))
+	for i, out := range outs {
+		outRows[i] = changedRow{id: out.ID, height: heights[out.TxID]}
+	}
+	if err := recordCreated(db, "PChainTx", txRows); err != nil {
+		return err
+	}
+	if err := recordCreated(db, "PChainTxInput", inRows); err != nil {
+		return err
+	}
+	return recordCreated(db, "PChainTxOutput", outRows)
+}
+
+// Key of a P-chain transaction, the transaction ID or the block ID of the empty
+// transaction of a block without transactions
+type pChainTxKey struct {
+	txID    string
+	blockID string
+}
+
+func newPChainEntities(db *gorm.DB, txs []*PChainTx, ins []*PChainTxInput, outs []*PChainTxOutput) (
+	[]*PChainTx, []*PChainTxInput, []*PChainTxOutput, error,
+) {
+	var txIDs, blockIDs []string
+	for _, tx := range txs {
+		if tx.TxID != nil {
+			txIDs = append(txIDs, *tx.TxID)
+		} else {
+			blockIDs = append(blockIDs, tx.BlockID)
+		}
+	}
+	txs, err := newRows(db, txs, "tx_id, block_id", func(tx *PChainTx) pChainTxKey {
+		if tx.TxID != nil {
+			return pChainTxKey{txID: *tx.TxID}
+		}
+		return pChainTxKey{blockID: tx.BlockID}
+	}, "tx_id IN ? OR (tx_id IS NULL AND block_id IN ?)", txIDs, blockIDs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ins, err = newInputs(db, ins, func(in *PChainTxInput) *TxInput { return &in.TxInput })
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	outs, err = newOutputs(db, outs, func(out *PChainTxOutput) *TxOutput { return &out.TxOutput })
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return txs, ins, outs, nil
 }
 
 // Returns a list of transaction ids initiating a create validator transaction or a create delegation transaction
+// (txType is PChainAddValidatorTx or PChainAddDelegatorTx, permissionless ones included)
 // - if address is not empty, only returns transactions where the given address is the sender of the transaction
 // - if time is not zero, only returns transactions where the validatot time or delegation time contains the given time
 // - if nodeID is not empty, only returns transactions where the given node ID is the validator node ID
 func FetchPChainStakingTransactions(
+	ctx context.Context,
 	db *gorm.DB,
 	txType PChainTxType,
 	nodeID string,
@@ -52,8 +204,9 @@ func FetchPChainStakingTransactions(
 ) ([]string, error) {
 	var validatorTxs []PChainTx
 
-	if txType != PChainAddValidatorTx && txType != PChainAddDelegatorTx {
-		return nil, errInvalidTransactionType
+	types, err := pChainStakerTxTypes(txType)
+	if err != nil {
+		return nil, err
 	}
 	if limit <= 0 {
 		limit = 100
@@ -62,18 +215,15 @@ func FetchPChainStakingTransactions(
 		offset = 0
 	}
 
-	query := db.Where(&PChainTx{Type: txType})
-	if len(nodeID) > 0 {
-		query = query.Where("node_id = ?", nodeID)
-	}
-	if !time.IsZero() {
-		query = query.Where("start_time <= ?", time).Where("end_time >= ?", time)
+	filter := PChainTxFilter{
+		Types:        types,
+		Status:       PChainTxAccepted,
+		NodeID:       nodeID,
+		InputAddress: address,
+		ActiveAt:     time,
 	}
-	if len(address) > 0 {
-		query = query.Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
-			Where("inputs.address = ?", address)
-	}
-	err := query.Offset(offset).Limit(limit).Order("p_chain_txes.tx_id").
+	query := filter.apply(db.WithContext(ctx).Table(pChainTxTable(db)))
+	err = query.Offset(offset).Limit(limit).Order("p_chain_txes.tx_id").
 		Distinct().Select("p_chain_txes.tx_id").Find(&validatorTxs).Error
 	if err != nil {
 		return nil, err
@@ -83,8 +233,9 @@ func FetchPChainStakingTransactions(
 }
 
 // Returns a list of staking data for stakers active at specific time which include input addresses.
-// Request is paginated (offset, limit).
+// Validators or delegators as in FetchPChainStakingTransactions. Request is paginated (offset, limit).
 func FetchPChainStakingData(
+	ctx context.Context,
 	db *gorm.DB,
 	time time.Time,
 	txType PChainTxType,
@@ -93,6 +244,10 @@ func FetchPChainStakingData(
 ) ([]PChainTxData, error) {
 	var validatorTxs []PChainTxData
 
+	types, err := pChainStakerTxTypes(txType)
+	if err != nil {
+		return nil, err
+	}
 	if limit <= 0 {
 		limit = 100
 	}
@@ -100,11 +255,13 @@ func FetchPChainStakingData(
 		offset = 0
 	}
 
-	query := db.
-		Table("p_chain_txes").
-		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
-		Where("start_time <= ?", time).Where("? <= end_time", time).
-		Where("type = ?", txType).
+	filter := PChainTxFilter{
+		Types:    types,
+		Status:   PChainTxAccepted,
+		ActiveAt: time,
+	}
+	query := filter.apply(db.WithContext(ctx).Table(pChainTxTable(db))).
+		Joins(pChainInputsJoin(db)).
 		Group("p_chain_txes.id").
 		Order("p_chain_txes.id").Offset(offset).Limit(limit).
 		Select("p_chain_txes.*, group_concat(distinct(inputs.address)) as input_address").
@@ -112,8 +269,46 @@ func FetchPChainStakingData(
 	return validatorTxs, query.Error
 }
 
+// Returns all validators and delegators active at the given time, i.e., the
+// staking transactions with start_time <= time <= end_time, including their
+// input addresses, ordered by type and id
+func FetchPChainStakersAt(ctx context.Context, db *gorm.DB, time time.Time) ([]PChainTxData, error) {
+	var stakerTxs []PChainTxData
+	filter := PChainTxFilter{
+		Types:    pChainStakingTxTypes,
+		Status:   PChainTxAccepted,
+		ActiveAt: time,
+	}
+	query := filter.apply(db.WithContext(ctx).Table(pChainTxTable(db))).
+		Joins(pChainInputsJoin(db)).
+		Group("p_chain_txes.id").
+		Order("p_chain_txes.type").Order("p_chain_txes.id").
+		Select("p_chain_txes.*, group_concat(distinct(inputs.address)) as input_address").
+		Scan(&stakerTxs)
+	return stakerTxs, query.Error
+}
+
+// Returns ids of the transactions whose memo (hex encoded, "0x" prefix) starts with
+// the given prefix, in the order of indexing. The prefix must be hex encoded as
+// well, so that it contains no wildcards. Request is paginated (offset, limit).
+func FetchPChainTxIDsByMemoPrefix(ctx context.Context, db *gorm.DB, prefix string, offset int, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var txIDs []string
+	err := db.WithContext(ctx).Model(&PChainTx{}).
+		Where("memo LIKE ?", prefix+"%").
+		Order("id").Offset(offset).Limit(limit).
+		Pluck("tx_id", &txIDs).Error
+	return txIDs, err
+}
+
 // Returns a list of transaction ids initiating transfers between chains (import/export transactions)
 func FetchPChainTransferTransactions(
+	ctx context.Context,
 	db *gorm.DB,
 	txType PChainTxType,
 	address string,
@@ -130,16 +325,13 @@ func FetchPChainTransferTransactions(
 	if offset < 0 {
 		offset = 0
 	}
-	query := db.Where(&PChainTx{Type: txType})
-	if len(address) > 0 {
-		if txType == PChainImportTx {
-			query = query.Joins("left join p_chain_tx_outputs as outputs on outputs.tx_id = p_chain_txes.tx_id").
-				Where("outputs.address = ?", address)
-		} else {
-			query = query.Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
-				Where("inputs.address = ?", address)
-		}
+	filter := PChainTxFilter{Types: []PChainTxType{txType}}
+	if txType == PChainImportTx {
+		filter.OutputAddress = address
+	} else {
+		filter.InputAddress = address
 	}
+	query := filter.apply(db.WithContext(ctx).Table(pChainTxTable(db)))
 	err := query.Offset(offset).Limit(limit).Order("p_chain_txes.tx_id").
 		Distinct().Select("p_chain_txes.tx_id").Find(&txs).Error
 	if err != nil {
@@ -149,7 +341,8 @@ func FetchPChainTransferTransactions(
 	return utils.Map(txs, func(t PChainTx) string { return *t.TxID }), nil
 }
 
-func FetchPChainTxFull(db *gorm.DB, txID string) (*PChainTx, []PChainTxInput, []PChainTxOutput, error) {
+func FetchPChainTxFull(ctx context.Context, db *gorm.DB, txID string) (*PChainTx, []PChainTxInput, []PChainTxOutput, error) {
+	db = db.WithContext(ctx)
 	var tx PChainTx
 	err := db.Where(&PChainTx{TxID: &txID}).First(&tx).Error
 	if err != nil {
@@ -171,21 +364,59 @@ func FetchPChainTxFull(db *gorm.DB, txID string) (*PChainTx, []PChainTxInput, []
 	return &tx, inputs, outputs, nil
 }
 
-func FetchPChainTx(db *gorm.DB, txID string) (*PChainTx, error) {
+// Fetch the bytes of the block containing the transaction with the given id, the
+// error has the code errcode.NotFound if the transaction is not indexed. The bytes
+// are empty for transactions indexed without them.
+func FetchPChainTxBlockBytes(ctx context.Context, db *gorm.DB, txID string) ([]byte, error) {
 	var tx PChainTx
-	err := db.Where(&PChainTx{TxID: &txID}).First(&tx).Error
+	err := db.WithContext(ctx).Where(&PChainTx{TxID: &txID}).Select("bytes").Take(&tx).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errcode.Wrap(err, errcode.NotFound, "tx %s not found", txID)
+	}
+	return tx.Bytes, err
+}
+
+// Fetch the transactions with the given ids and their inputs and outputs with three
+// queries. Transactions which are not indexed are skipped. Inputs and outputs are
+// ordered by index.
+func FetchPChainTxsFull(ctx context.Context, db *gorm.DB, txIDs []string) ([]PChainTx, []PChainTxInput, []PChainTxOutput, error) {
+	db = db.WithContext(ctx)
+	var txs []PChainTx
+	err := db.Where("tx_id IN ?", txIDs).Find(&txs).Error
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	inputs, err := FetchPChainTxInputs(ctx, db, txIDs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var outputs []PChainTxOutput
+	err = db.Where("tx_id IN ?", txIDs).Order("idx").Find(&outputs).Error
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return txs, inputs, outputs, nil
+}
+
+func FetchPChainTx(ctx context.Context, db *gorm.DB, txID string) (*PChainTx, error) {
+	var tx PChainTx
+	err := db.WithContext(ctx).Where(&PChainTx{TxID: &txID}).First(&tx).Error
 	if err != nil {
 		return nil, err
 	}
 	return &tx, nil
 }
 
-func FetchPChainTxData(db *gorm.DB, txID string, address string) (*PChainTxData, error) {
+func FetchPChainTxData(ctx context.Context, db *gorm.DB, txID string, address string) (*PChainTxData, error) {
 	var tx PChainTxData
-	err := db.Table("p_chain_txes").
-		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
+	err := db.WithContext(ctx).Table(pChainTxTable(db)).
+		Joins(pChainInputsJoin(db)).
 		Where("p_chain_txes.tx_id = ?", txID).
 		Where("inputs.address = ?", address).
+		Where("p_chain_txes.status = ?", PChainTxAccepted).
 		Group("p_chain_txes.id").
 		// any_value is used to avoid only_full_group_by error
 		Select("p_chain_txes.*, any_value(inputs.address) as input_address, min(inputs.in_idx) as input_index").
@@ -208,14 +439,14 @@ type PChainTxData struct {
 // Find P-chain transaction in given block height
 // Returns transaction and true if found, nil and true if block was found,
 // nil and false if block height does not exist.
-func FindPChainTxInBlockHeight(db *gorm.DB,
+func FindPChainTxInBlockHeight(ctx context.Context, db *gorm.DB,
 	txID string,
 	height uint32,
 ) (*PChainTxData, bool, error) {
 	var txs []PChainTxData
 	// err := db.Where(&PChainTx{BlockHeight: height}).Find(&txs).Error
-	err := db.Table("p_chain_txes").
-		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
+	err := db.WithContext(ctx).Table(pChainTxTable(db)).
+		Joins(pChainInputsJoin(db)).
 		Where("p_chain_txes.block_height = ?", height).
 		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index").
 		Scan(&txs).Error
@@ -232,39 +463,57 @@ func FindPChainTxInBlockHeight(db *gorm.DB,
 	return &txs[0], true, nil
 }
 
-func FetchPChainVotingData(db *gorm.DB, from time.Time, to time.Time) ([]PChainTxData, error) {
+func FetchPChainVotingData(ctx context.Context, db *gorm.DB, from time.Time, to time.Time) ([]PChainTxData, error) {
 	var data []PChainTxData
 
-	query := db.
-		Table("p_chain_txes").
-		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
-		Where("type = ? OR type = ?", PChainAddValidatorTx, PChainAddDelegatorTx).
-		Where("start_time >= ?", from).Where("start_time < ?", to).
+	filter := PChainTxFilter{
+		Types:     pChainStakingTxTypes,
+		Status:    PChainTxAccepted,
+		StartFrom: from,
+		StartTo:   to,
+	}
+	query := filter.apply(db.WithContext(ctx).Table(pChainTxTable(db))).
+		Joins(pChainInputsJoin(db)).
 		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index").
 		Scan(&data)
 	return data, query.Error
 }
 
+// Voting data (as in FetchPChainVotingData) of the first input of each
+// transaction, at most limit rows with ids greater than afterID ordered by id, so
+// the voting data of an epoch can be read page by page
+func FetchPChainVotingDataPage(ctx context.Context, db *gorm.DB, from time.Time, to time.Time, afterID uint64, limit int) ([]PChainTxData, error) {
+	var data []PChainTxData
+	err := pChainVotingDataPageQuery(db.WithContext(ctx), from, to, afterID, limit).Scan(&data).Error
+	return data, err
+}
+
+func pChainVotingDataPageQuery(db *gorm.DB, from time.Time, to time.Time, afterID uint64, limit int) *gorm.DB {
+	filter := PChainTxFilter{
+		Types:     pChainStakingTxTypes,
+		Status:    PChainTxAccepted,
+		StartFrom: from,
+		StartTo:   to,
+	}
+	return filter.apply(db.Table(pChainTxTable(db))).
+		Joins(pChainInputsJoin(db)).
+		Where("inputs.in_idx = ?", 0).
+		Where("p_chain_txes.id > ?", afterID).
+		Order("p_chain_txes.id").Limit(limit).
+		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index")
+}
+
 type GetPChainTxsForEpochInput struct {
 	DB             *gorm.DB
 	StartTimestamp time.Time
 	EndTimestamp   time.Time
 }
 
-func GetPChainTxsForEpoch(in *GetPChainTxsForEpochInput) ([]PChainTxData, error) {
+// Staking transactions of all types starting in the epoch, with one row per input
+// address
+func GetPChainTxsForEpoch(ctx context.Context, in *GetPChainTxsForEpochInput) ([]PChainTxData, error) {
 	var txs []PChainTxData
-	err := in.DB.
-		Table("p_chain_txes").
-		Joins("left join p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id").
-		Where("p_chain_txes.start_time >= ?", in.StartTimestamp).
-		Where("p_chain_txes.start_time < ?", in.EndTimestamp).
-		Where(
-			in.DB.Where("p_chain_txes.type = ?", PChainAddDelegatorTx).
-				Or("p_chain_txes.type = ?", PChainAddValidatorTx),
-		).
-		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index").
-		Find(&txs).
-		Error
+	err := pChainTxsForEpochQuery(in.DB.WithContext(ctx), in.StartTimestamp, in.EndTimestamp).Find(&txs).Error
 	if err != nil {
 		return nil, err
 	}
@@ -272,16 +521,213 @@ func GetPChainTxsForEpoch(in *GetPChainTxsForEpochInput) ([]PChainTxData, error)
 	return txs, nil
 }
 
-// Fetches all P-chain staking transactions of type txType intersecting the given time interval
-func FetchNodeStakingIntervals(db *gorm.DB, txType PChainTxType, startTime time.Time, endTime time.Time) ([]PChainTx, error) {
-	if txType != PChainAddValidatorTx && txType != PChainAddDelegatorTx {
-		return nil, errInvalidTransactionType
+func pChainTxsForEpochQuery(db *gorm.DB, start time.Time, end time.Time) *gorm.DB {
+	filter := PChainTxFilter{
+		Types:     pChainStakingTxTypes,
+		Status:    PChainTxAccepted,
+		StartFrom: start,
+		StartTo:   end,
+	}
+	return filter.apply(db.Table(pChainTxTable(db))).
+		Joins(pChainInputsJoin(db)).
+		Select("p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index")
+}
+
+// Fetches all P-chain validator or delegator transactions (as in FetchPChainStakingTransactions)
+// intersecting the given time interval
+func FetchNodeStakingIntervals(ctx context.Context, db *gorm.DB, txType PChainTxType, startTime time.Time, endTime time.Time) ([]PChainTx, error) {
+	types, err := pChainStakerTxTypes(txType)
+	if err != nil {
+		return nil, err
 	}
 
 	var txs []PChainTx
-	err := db.Where(&PChainTx{Type: txType}).
+	err = db.WithContext(ctx).Where("type IN ?", types).Where(&PChainTx{Status: PChainTxAccepted}).
 		Where("start_time <= ?", endTime).
 		Where("end_time >= ?", startTime).
 		Find(&txs).Error
 	return txs, err
 }
+
+// Staking transactions of the blocks at the heights
+func FetchPChainStakingTxsAtHeights(ctx context.Context, db *gorm.DB, heights []uint64) ([]PChainTx, error) {
+	var txs []PChainTx
+	if len(heights) == 0 {
+		return txs, nil
+	}
+	err := db.WithContext(ctx).
+		Where("block_height IN ?", heights).
+		Where("type IN ?", pChainStakingTxTypes).
+		Find(&txs).Error
+	return txs, err
+}
+
+// Fetches the permissionless validator transactions of the node with a BLS key,
+// the latest (by start time) first
+func FetchNodeBLSKeys(ctx context.Context, db *gorm.DB, nodeID string) ([]PChainTx, error) {
+	var txs []PChainTx
+	err := db.WithContext(ctx).
+		Where(&PChainTx{Type: PChainAddPermissionlessValidatorTx, NodeID: nodeID}).
+		Where("bls_public_key <> ''").
+		Order("start_time desc").Order("id desc").
+		Find(&txs).Error
+	return txs, err
+}
+
+// P-chain transactions table, aliased to p_chain_txes also if the table name
+// is prefixed
+func pChainTxTable(db *gorm.DB) string {
+	return tableName(db, "PChainTx") + " as p_chain_txes"
+}
+
+func pChainInputsJoin(db *gorm.DB) string {
+	return "left join " + tableName(db, "PChainTxInput") + " as inputs on inputs.tx_id = p_chain_txes.tx_id"
+}
+
+func pChainOutputsJoin(db *gorm.DB) string {
+	return "left join " + tableName(db, "PChainTxOutput") + " as outputs on outputs.tx_id = p_chain_txes.tx_id"
+}
+
+// Burned amount and number of transactions in a time bucket
+type BurnStats struct {
+	Bucket  int64
+	Burned  BigInt
+	TxCount uint64
+}
+
+// Aggregate fees of P-chain transactions with timestamps in [from, to) into
+// consecutive buckets of the given length, the bucket with index 0 starts at start.
+// Buckets without transactions are omitted.
+func FetchPChainBurnStats(ctx context.Context, db *gorm.DB, start time.Time, bucket time.Duration, from time.Time, to time.Time) ([]BurnStats, error) {
+	var stats []BurnStats
+	err := burnStatsQuery(db.WithContext(ctx).Model(&PChainTx{}).Where("tx_id IS NOT NULL"), start, bucket, from, to).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// Query of the burn statistics of the transactions of the model of db
+func burnStatsQuery(db *gorm.DB, start time.Time, bucket time.Duration, from time.Time, to time.Time) *gorm.DB {
+	return db.
+		Select("FLOOR(TIMESTAMPDIFF(SECOND, ?, timestamp) / ?) AS bucket, SUM(fee) AS burned, COUNT(*) AS tx_count",
+			start, int64(bucket/time.Second)).
+		Where("timestamp >= ? AND timestamp < ?", from, to).
+		Group("bucket").
+		Order("bucket")
+}
+
+// Block of indexed P-chain transactions
+type PChainBlockInfo struct {
+	BlockHeight uint64
+	BlockID     string
+	Timestamp   time.Time
+}
+
+// Fetch the block with the given height, returns gorm.ErrRecordNotFound if the
+// block is not indexed
+func FetchPChainBlockInfo(ctx context.Context, db *gorm.DB, height uint64) (*PChainBlockInfo, error) {
+	var block PChainBlockInfo
+	err := db.WithContext(ctx).Model(&PChainTx{}).
+		Select("block_height, block_id, timestamp").
+		Where("block_height = ?", height).
+		Take(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errcode.Wrap(err, errcode.NotFound, "block %d not found", height)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Fetch the lowest and the highest indexed block height, returns gorm.ErrRecordNotFound
+// (with the code errcode.ChainLag) if no block is indexed
+func FetchPChainBlockHeightRange(ctx context.Context, db *gorm.DB) (uint64, uint64, error) {
+	var heights struct {
+		Min *uint64
+		Max *uint64
+	}
+	err := db.WithContext(ctx).Model(&PChainTx{}).
+		Select("MIN(block_height) AS min, MAX(block_height) AS max").
+		Scan(&heights).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	if heights.Min == nil || heights.Max == nil {
+		return 0, 0, errcode.Wrap(gorm.ErrRecordNotFound, errcode.ChainLag, "no block is indexed yet")
+	}
+	return *heights.Min, *heights.Max, nil
+}
+
+// Fetch the last block accepted at or before the given time, returns
+// gorm.ErrRecordNotFound if there is no such block. The blocks table has all
+// indexed blocks, also blocks without (indexed) transactions, and the index of
+// its timestamp column.
+func FetchPChainBlockAtTime(ctx context.Context, db *gorm.DB, t time.Time) (*PChainBlockInfo, error) {
+	var blocks []PChainBlockInfo
+	if err := pChainBlockAtTimeQuery(db.WithContext(ctx), t).Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 {
+		return &blocks[0], nil
+	}
+	var count int64
+	if err := db.WithContext(ctx).Model(&PChainBlock{}).Limit(1).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errcode.Wrap(gorm.ErrRecordNotFound, errcode.ChainLag, "no block is indexed yet")
+	}
+	return nil, errcode.Wrap(gorm.ErrRecordNotFound, errcode.NotFound, "no block indexed before the given time")
+}
+
+func pChainBlockAtTimeQuery(db *gorm.DB, t time.Time) *gorm.DB {
+	return db.Model(&PChainBlock{}).
+		Select("height AS block_height, block_id, timestamp").
+		Where("timestamp <= ?", t).
+		Order("height DESC").
+		Limit(1)
+}
+
+// Calls fn for each validator or delegator transaction (as in
+// FetchPChainStakingTransactions) starting in the interval [from, to), in the order of ids, with the comma separated input
+// addresses. Rows are read one at a time, so the result set is never held in
+// memory; the iteration stops at the first error returned by fn.
+func StreamPChainStakingData(
+	ctx context.Context,
+	db *gorm.DB,
+	txType PChainTxType,
+	from time.Time,
+	to time.Time,
+	fn func(tx *PChainTxData) error,
+) error {
+	types, err := pChainStakerTxTypes(txType)
+	if err != nil {
+		return err
+	}
+	rows, err := db.WithContext(ctx).
+		Table(pChainTxTable(db)).
+		Joins(pChainInputsJoin(db)).
+		Where("p_chain_txes.start_time >= ?", from).
+		Where("p_chain_txes.start_time < ?", to).
+		Where("p_chain_txes.type IN ?", types).
+		Where("p_chain_txes.status = ?", PChainTxAccepted).
+		Group("p_chain_txes.id").
+		Order("p_chain_txes.id").
+		Select("p_chain_txes.*, group_concat(distinct(inputs.address)) as input_address").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tx PChainTxData
+		if err := db.ScanRows(rows, &tx); err != nil {
+			return err
+		}
+		if err := fn(&tx); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Creates the signers, existing signers (of the same transaction, credential and
+// signature) are kept
+func CreatePChainTxSigners(ctx context.Context, db *gorm.DB, signers []*PChainTxSigner) error {
+	if len(signers) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(signers).Error
+}
+
+// Fetch the signers of the transactions with the given ids, ordered by transaction,
+// credential and signature
+func FetchPChainTxSigners(ctx context.Context, db *gorm.DB, txIDs []string) ([]PChainTxSigner, error) {
+	var signers []PChainTxSigner
+	if len(txIDs) == 0 {
+		return signers, nil
+	}
+	err := db.WithContext(ctx).Where("tx_id IN ?", txIDs).
+		Order("tx_id, cred_idx, sig_idx").
+		Find(&signers).Error
+	return signers, err
+}
+
+// Fetch the ids of the staking transactions signed by the key of the address,
+// ordered by block height. Request is paginated (offset, limit).
+func FetchPChainStakingTxIDsBySigner(ctx context.Context, db *gorm.DB, address string, offset int, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	db = db.WithContext(ctx)
+	signed := db.Table(tableName(db, "PChainTxSigner")+" as signers").
+		Where("signers.tx_id = p_chain_txes.tx_id AND signers.address = ?", address).
+		Select("1")
+	var txIDs []string
+	err := db.Table(pChainTxTable(db)).
+		Where("p_chain_txes.type IN ?", stakingTxTypes).
+		Where("EXISTS (?)", signed).
+		Order("p_chain_txes.block_height, p_chain_txes.tx_id").
+		Offset(offset).Limit(limit).
+		Pluck("p_chain_txes.tx_id", &txIDs).Error
+	return txIDs, err
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Filter of P-chain transactions shared by the queries of the API and of the
+// cronjobs. Empty fields do not restrict the selected transactions. The filter
+// is applied to queries of the P-chain transactions table aliased to
+// p_chain_txes (see pChainTxTable).
+type PChainTxFilter struct {
+	Types         []PChainTxType // Transaction types
+	Status        PChainTxStatus // Status of the transaction
+	NodeID        string         // Validator node ID
+	InputAddress  string         // Address of one of the inputs (joins inputs)
+	OutputAddress string         // Address of one of the outputs (joins outputs)
+	// Staking interval (start_time <= ActiveAt <= end_time) contains the time
+	ActiveAt time.Time
+	// Start of the staking interval in [StartFrom, StartTo)
+	StartFrom time.Time
+	StartTo   time.Time
+	// Time of the block of the transaction in [From, To)
+	From time.Time
+	To   time.Time
+	// Weight (stake amount) in [MinWeight, MaxWeight], MaxWeight 0 is unbounded
+	MinWeight uint64
+	MaxWeight uint64
+	// Whether the transaction was (not) mirrored successfully to the mirroring
+	// contract by this indexer, nil selects both
+	Mirrored *bool
+}
+
+// Adds the conditions (and the joins) of the filter to the query
+func (f *PChainTxFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.Types) == 1 {
+		query = query.Where("p_chain_txes.type = ?", f.Types[0])
+	} else if len(f.Types) > 1 {
+		query = query.Where("p_chain_txes.type IN ?", f.Types)
+	}
+	if len(f.Status) > 0 {
+		query = query.Where("p_chain_txes.status = ?", f.Status)
+	}
+	if len(f.NodeID) > 0 {
+		query = query.Where("p_chain_txes.node_id = ?", f.NodeID)
+	}
+	if len(f.InputAddress) > 0 {
+		query = query.Joins(pChainInputsJoin(query)).Where("inputs.address = ?", f.InputAddress)
+	}
+	if len(f.OutputAddress) > 0 {
+		query = query.Joins(pChainOutputsJoin(query)).Where("outputs.address = ?", f.OutputAddress)
+	}
+	if !f.ActiveAt.IsZero() {
+		query = query.Where("p_chain_txes.start_time <= ?", f.ActiveAt).Where("p_chain_txes.end_time >= ?", f.ActiveAt)
+	}
+	if !f.StartFrom.IsZero() {
+		query = query.Where("p_chain_txes.start_time >= ?", f.StartFrom)
+	}
+	if !f.StartTo.IsZero() {
+		query = query.Where("p_chain_txes.start_time < ?", f.StartTo)
+	}
+	if !f.From.IsZero() {
+		query = query.Where("p_chain_txes.timestamp >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		query = query.Where("p_chain_txes.timestamp < ?", f.To)
+	}
+	if f.MinWeight > 0 {
+		query = query.Where("p_chain_txes.weight >= ?", f.MinWeight)
+	}
+	if f.MaxWeight > 0 {
+		query = query.Where("p_chain_txes.weight <= ?", f.MaxWeight)
+	}
+	if f.Mirrored != nil {
+		mirrored := query.Session(&gorm.Session{NewDB: true}).
+			Table(tableName(query, "MirroringAttempt")).
+			Select("1").
+			Where("tx_id = p_chain_txes.tx_id").
+			Where("status = ?", MirroringAttemptSucceeded)
+		if *f.Mirrored {
+			query = query.Where("EXISTS (?)", mirrored)
+		} else {
+			query = query.Where("NOT EXISTS (?)", mirrored)
+		}
+	}
+	return query
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Database which only generates the SQL of the queries, without a connection
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+		DSN:                       "user:pass@tcp(localhost:3306)/db",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		NamingStrategy:       schema.NamingStrategy{TablePrefix: "x_"},
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func filterSQL(t *testing.T, filter PChainTxFilter) (string, []interface{}) {
+	db := dryRunDB(t)
+	var txs []PChainTx
+	stmt := filter.apply(db.Table(pChainTxTable(db))).Select("p_chain_txes.tx_id").Find(&txs).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestPChainTxFilterEmpty(t *testing.T) {
+	sql, vars := filterSQL(t, PChainTxFilter{})
+	require.Equal(t, "SELECT p_chain_txes.tx_id FROM x_p_chain_txes as p_chain_txes", sql)
+	require.Empty(t, vars)
+}
+
+func TestPChainTxFilterStaking(t *testing.T) {
+	at := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	sql, vars := filterSQL(t, PChainTxFilter{
+		Types:        []PChainTxType{PChainAddValidatorTx, PChainAddDelegatorTx},
+		Status:       PChainTxAccepted,
+		NodeID:       "NodeID-1",
+		InputAddress: "costwo1",
+		ActiveAt:     at,
+		MinWeight:    10,
+		MaxWeight:    20,
+	})
+	require.Equal(t, "SELECT p_chain_txes.tx_id FROM x_p_chain_txes as p_chain_txes "+
+		"left join x_p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id "+
+		"WHERE p_chain_txes.type IN (?,?) AND p_chain_txes.status = ? AND p_chain_txes.node_id = ? "+
+		"AND inputs.address = ? AND p_chain_txes.start_time <= ? AND p_chain_txes.end_time >= ? "+
+		"AND p_chain_txes.weight >= ? AND p_chain_txes.weight <= ?", sql)
+	require.Equal(t, []interface{}{PChainAddValidatorTx, PChainAddDelegatorTx, PChainTxAccepted,
+		"NodeID-1", "costwo1", at, at, uint64(10), uint64(20)}, vars)
+}
+
+func TestPChainTxFilterTimeRanges(t *testing.T) {
+	from := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	sql, vars := filterSQL(t, PChainTxFilter{
+		Types:         []PChainTxType{PChainImportTx},
+		OutputAddress: "costwo1",
+		StartFrom:     from,
+		StartTo:       to,
+		From:          from,
+		To:            to,
+	})
+	require.Equal(t, "SELECT p_chain_txes.tx_id FROM x_p_chain_txes as p_chain_txes "+
+		"left join x_p_chain_tx_outputs as outputs on outputs.tx_id = p_chain_txes.tx_id "+
+		"WHERE p_chain_txes.type = ? AND outputs.address = ? "+
+		"AND p_chain_txes.start_time >= ? AND p_chain_txes.start_time < ? "+
+		"AND p_chain_txes.timestamp >= ? AND p_chain_txes.timestamp < ?", sql)
+	require.Equal(t, []interface{}{PChainImportTx, "costwo1", from, to, from, to}, vars)
+}
+
+func TestPChainTxFilterMirrored(t *testing.T) {
+	for _, mirrored := range []bool{true, false} {
+		sql, vars := filterSQL(t, PChainTxFilter{Mirrored: &mirrored})
+		exists := "EXISTS"
+		if !mirrored {
+			exists = "NOT EXISTS"
+		}
+		require.Equal(t, "SELECT p_chain_txes.tx_id FROM x_p_chain_txes as p_chain_txes WHERE "+exists+
+			" (SELECT 1 FROM `x_mirroring_attempts` WHERE tx_id = p_chain_txes.tx_id AND status = ?)", sql)
+		require.Equal(t, []interface{}{MirroringAttemptSucceeded}, vars)
+	}
+}
+
+func TestPChainBlockAtTimeQuery(t *testing.T) {
+	db := dryRunDB(t)
+	at := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	var blocks []PChainBlockInfo
+	stmt := pChainBlockAtTimeQuery(db, at).Find(&blocks).Statement
+	require.Equal(t, "SELECT height AS block_height, block_id, timestamp FROM `x_p_chain_blocks` "+
+		"WHERE timestamp <= ? ORDER BY height DESC LIMIT ?", stmt.SQL.String())
+	require.Equal(t, []interface{}{at, 1}, stmt.Vars)
+}
+
+func TestPChainTxsForEpochQuery(t *testing.T) {
+	db := dryRunDB(t)
+	start := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	var txs []PChainTxData
+	stmt := pChainTxsForEpochQuery(db, start, end).Find(&txs).Statement
+	require.Equal(t, "SELECT p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index "+
+		"FROM x_p_chain_txes as p_chain_txes "+
+		"left join x_p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id "+
+		"WHERE p_chain_txes.type IN (?,?,?,?) AND p_chain_txes.status = ? "+
+		"AND p_chain_txes.start_time >= ? AND p_chain_txes.start_time < ?", stmt.SQL.String())
+	require.Equal(t, []interface{}{PChainAddValidatorTx, PChainAddDelegatorTx,
+		PChainAddPermissionlessValidatorTx, PChainAddPermissionlessDelegatorTx, PChainTxAccepted, start, end}, stmt.Vars)
+}
+
+func TestPChainVotingDataPageQuery(t *testing.T) {
+	db := dryRunDB(t)
+	start := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	var txs []PChainTxData
+	stmt := pChainVotingDataPageQuery(db, start, end, 42, 100).Find(&txs).Statement
+	require.Equal(t, "SELECT p_chain_txes.*, inputs.address as input_address, inputs.in_idx as input_index "+
+		"FROM x_p_chain_txes as p_chain_txes "+
+		"left join x_p_chain_tx_inputs as inputs on inputs.tx_id = p_chain_txes.tx_id "+
+		"WHERE p_chain_txes.type IN (?,?,?,?) AND p_chain_txes.status = ? "+
+		"AND p_chain_txes.start_time >= ? AND p_chain_txes.start_time < ? "+
+		"AND inputs.in_idx = ? AND p_chain_txes.id > ? ORDER BY p_chain_txes.id LIMIT ?", stmt.SQL.String())
+	require.Equal(t, []interface{}{PChainAddValidatorTx, PChainAddDelegatorTx,
+		PChainAddPermissionlessValidatorTx, PChainAddPermissionlessDelegatorTx, PChainTxAccepted, start, end,
+		0, uint64(42), 100}, stmt.Vars)
+}
+
+func TestXChainBurnStatsQuery(t *testing.T) {
+	db := dryRunDB(t)
+	start := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	from := start.Add(24 * time.Hour)
+	to := from.Add(24 * time.Hour)
+	var stats []BurnStats
+	stmt := burnStatsQuery(db.Model(&XChainTx{}), start, 24*time.Hour, from, to).Scan(&stats).Statement
+	require.Equal(t, "SELECT FLOOR(TIMESTAMPDIFF(SECOND, ?, timestamp) / ?) AS bucket, SUM(fee) AS burned, COUNT(*) AS tx_count "+
+		"FROM `x_x_chain_txes` WHERE timestamp >= ? AND timestamp < ? GROUP BY `bucket` ORDER BY bucket", stmt.SQL.String())
+	require.Equal(t, []interface{}{start, int64(86400), from, to}, stmt.Vars)
+}
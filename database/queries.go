@@ -1,49 +1,60 @@
 package database
 
 import (
+	"context"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-func FetchState(db *gorm.DB, name string) (State, error) {
+// Name of the state of the P-chain indexer, Updated is the time of its last run
+const PChainIndexerStateName = "p_chain_block"
+
+func FetchState(ctx context.Context, db *gorm.DB, name string) (State, error) {
 	var currentState State
-	err := db.Where(&State{Name: name}).First(&currentState).Error
+	err := db.WithContext(ctx).Where(&State{Name: name}).First(&currentState).Error
 	return currentState, err
 }
 
-func FetchMigrations(db *gorm.DB) ([]Migration, error) {
+// Returns the states of all indexers and cronjobs, ordered by name
+func FetchStates(ctx context.Context, db *gorm.DB) ([]State, error) {
+	var states []State
+	err := db.WithContext(ctx).Order("name").Find(&states).Error
+	return states, err
+}
+
+func FetchMigrations(ctx context.Context, db *gorm.DB) ([]Migration, error) {
 	var migrations []Migration
-	err := db.Order("version asc").Find(&migrations).Error
+	err := db.WithContext(ctx).Order("version asc").Find(&migrations).Error
 	return migrations, err
 }
 
-func CreateMigration(db *gorm.DB, m *Migration) error {
-	return db.Create(m).Error
+func CreateMigration(ctx context.Context, db *gorm.DB, m *Migration) error {
+	return db.WithContext(ctx).Create(m).Error
 }
 
-func UpdateMigration(db *gorm.DB, m *Migration) error {
-	return db.Save(m).Error
+func UpdateMigration(ctx context.Context, db *gorm.DB, m *Migration) error {
+	return db.WithContext(ctx).Save(m).Error
 }
 
-func CreateState(db *gorm.DB, s *State) error {
-	return db.Create(s).Error
+func CreateState(ctx context.Context, db *gorm.DB, s *State) error {
+	return db.WithContext(ctx).Create(s).Error
 }
 
-func UpdateState(db *gorm.DB, s *State) error {
-	return db.Save(s).Error
+func UpdateState(ctx context.Context, db *gorm.DB, s *State) error {
+	return db.WithContext(ctx).Save(s).Error
 }
 
-func CreateUptimeCronjobEntry(db *gorm.DB, entities []*UptimeCronjob) error {
+func CreateUptimeCronjobEntry(ctx context.Context, db *gorm.DB, entities []*UptimeCronjob) error {
 	if len(entities) > 0 {
-		return db.Create(entities).Error
+		return db.WithContext(ctx).Create(entities).Error
 	}
 	return nil
 }
 
-func FetchLastUptimeAggregation(db *gorm.DB) (*UptimeAggregation, error) {
+func FetchLastUptimeAggregation(ctx context.Context, db *gorm.DB) (*UptimeAggregation, error) {
 	var lastAggregation UptimeAggregation
-	err := db.Order("epoch desc").First(&lastAggregation).Error
+	err := db.WithContext(ctx).Order("epoch desc").First(&lastAggregation).Error
 	if err == nil {
 		return &lastAggregation, nil
 	} else if err == gorm.ErrRecordNotFound {
@@ -53,19 +64,44 @@ func FetchLastUptimeAggregation(db *gorm.DB) (*UptimeAggregation, error) {
 	}
 }
 
-func FetchNodeUptimes(db *gorm.DB, nodeID string, startTime time.Time, endTime time.Time) ([]UptimeCronjob, error) {
+// The limit uptime aggregations of the epoch with the highest ratio of uptime to
+// staking duration, ties are ordered by node ID
+func FetchTopUptimeAggregations(ctx context.Context, db *gorm.DB, epoch int, limit int) ([]UptimeAggregation, error) {
+	var aggregations []UptimeAggregation
+	err := db.WithContext(ctx).Where("epoch = ?", epoch).Where("staking_duration > 0").
+		Order("value / staking_duration desc").Order("node_id").
+		Limit(limit).
+		Find(&aggregations).Error
+	return aggregations, err
+}
+
+func FetchNodeUptimes(ctx context.Context, db *gorm.DB, nodeID string, startTime time.Time, endTime time.Time) ([]UptimeCronjob, error) {
 	var uptimes []UptimeCronjob
-	err := db.Where("node_id = ? AND timestamp >= ? AND timestamp < ?", nodeID, startTime, endTime).Order("timestamp asc").Find(&uptimes).Error
+	err := db.WithContext(ctx).Where("node_id = ? AND timestamp >= ? AND timestamp < ?", nodeID, startTime, endTime).Order("timestamp asc").Find(&uptimes).Error
 	return uptimes, err
 }
 
-func PersistUptimeAggregations(db *gorm.DB, aggregations []*UptimeAggregation) error {
+func PersistUptimeAggregations(ctx context.Context, db *gorm.DB, aggregations []*UptimeAggregation) error {
 	if len(aggregations) == 0 {
 		return nil
 	}
-	return db.Create(aggregations).Error
+	return db.WithContext(ctx).Create(aggregations).Error
+}
+
+func DeleteUptimesBefore(ctx context.Context, db *gorm.DB, timestamp time.Time) error {
+	return db.WithContext(ctx).Where("timestamp < ?", timestamp).Delete(&UptimeCronjob{}).Error
+}
+
+func FetchFeatureFlags(ctx context.Context, db *gorm.DB) ([]FeatureFlag, error) {
+	var flags []FeatureFlag
+	err := db.WithContext(ctx).Find(&flags).Error
+	return flags, err
 }
 
-func DeleteUptimesBefore(db *gorm.DB, timestamp time.Time) error {
-	return db.Where("timestamp < ?", timestamp).Delete(&UptimeCronjob{}).Error
+// Create or update the flag with the given name
+func SetFeatureFlag(ctx context.Context, db *gorm.DB, name string, value string) error {
+	flag := FeatureFlag{Name: name}
+	return db.WithContext(ctx).Where(&flag).
+		Assign(FeatureFlag{Value: value, Updated: db.NowFunc()}).
+		FirstOrCreate(&flag).Error
 }
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	queryLogStartKey = "flare:query_log_start"
+
+	// Maximal number of statements and statement length kept in a query log
+	maxLoggedQueries   = 50
+	maxLoggedQueryLen  = 2000
+	truncatedQueryMark = "..."
+)
+
+type queryLogContextKey struct{}
+
+// Statement executed while a query log is attached to the context
+type LoggedQuery struct {
+	SQL      string // With the values of the parameters
+	Duration time.Duration
+	Rows     int64
+}
+
+// Statements executed with a context, e.g., to log the queries of a slow API
+// request. Only the first maxLoggedQueries statements are kept.
+type QueryLog struct {
+	mu      sync.Mutex
+	queries []LoggedQuery
+	dropped int
+}
+
+// Returns a context which collects the statements executed with it (or a
+// derived context) into the returned log. Statements are collected only if
+// the query log plugin is registered, see UseQueryLog.
+func WithQueryLog(ctx context.Context) (context.Context, *QueryLog) {
+	log := &QueryLog{}
+	return context.WithValue(ctx, queryLogContextKey{}, log), log
+}
+
+// Collected statements and the number of statements which were not kept
+func (l *QueryLog) Queries() ([]LoggedQuery, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queries := make([]LoggedQuery, len(l.queries))
+	copy(queries, l.queries)
+	return queries, l.dropped
+}
+
+func (l *QueryLog) add(query LoggedQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queries) >= maxLoggedQueries {
+		l.dropped++
+		return
+	}
+	l.queries = append(l.queries, query)
+}
+
+// Registers the plugin collecting statements into the query logs of their contexts
+func UseQueryLog(db *gorm.DB) error {
+	return db.Use(queryLogPlugin{})
+}
+
+type queryLogPlugin struct{}
+
+func (p queryLogPlugin) Name() string {
+	return "flare:query_log"
+}
+
+func (p queryLogPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("*").Register("flare:query_log_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("*").Register("flare:query_log_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("*").Register("flare:query_log_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("*").Register("flare:query_log_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("*").Register("flare:query_log_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("*").Register("flare:query_log_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("*").Register("flare:query_log_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("*").Register("flare:query_log_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("*").Register("flare:query_log_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("*").Register("flare:query_log_end", p.end); err != nil {
+		return err
+	}
+	// The duration of Rows (and Scan) does not include reading the result
+	if err := callbacks.Row().Before("*").Register("flare:query_log_start", p.start); err != nil {
+		return err
+	}
+	return callbacks.Row().After("*").Register("flare:query_log_end", p.end)
+}
+
+func (p queryLogPlugin) start(db *gorm.DB) {
+	if queryLogOf(db) != nil {
+		db.InstanceSet(queryLogStartKey, time.Now())
+	}
+}
+
+func (p queryLogPlugin) end(db *gorm.DB) {
+	log := queryLogOf(db)
+	if log == nil {
+		return
+	}
+	var duration time.Duration
+	if start, ok := db.InstanceGet(queryLogStartKey); ok {
+		duration = time.Since(start.(time.Time))
+	}
+	sql := db.Dialector.Explain(db.Statement.SQL.String(), db.Statement.Vars...)
+	if len(sql) > maxLoggedQueryLen {
+		sql = sql[:maxLoggedQueryLen] + truncatedQueryMark
+	}
+	log.add(LoggedQuery{SQL: sql, Duration: duration, Rows: db.RowsAffected})
+}
+
+func queryLogOf(db *gorm.DB) *QueryLog {
+	if db.Statement == nil || db.Statement.Context == nil {
+		return nil
+	}
+	log, _ := db.Statement.Context.Value(queryLogContextKey{}).(*QueryLog)
+	return log
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"flare-indexer/logger"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Registers the plugin prefixing the SQL statements executed with the context
+// of a request (see logger.WithRequestID) with a comment with the request ID,
+// e.g. "/* request_id=abc */ SELECT ...", so that statements in the process
+// list or the slow query log of the database can be traced to the request. IDs
+// containing "*/", which would end the comment, are not added; the services
+// only accept IDs matching requestIDPattern (services/utils), which have no "*"
+// or "/".
+func UseRequestIDTags(db *gorm.DB) error {
+	return db.Use(requestTagsPlugin{})
+}
+
+type requestTagsPlugin struct{}
+
+func (p requestTagsPlugin) Name() string {
+	return "flare:request_tags"
+}
+
+func (p requestTagsPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("flare:request_tag", p.tag("INSERT")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("flare:request_tag", p.tag("SELECT")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("flare:request_tag", p.tag("UPDATE")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("flare:request_tag", p.tag("DELETE")); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("flare:request_tag", p.tag("")); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("flare:request_tag", p.tag("SELECT"))
+}
+
+// Callback adding the comment to the SQL of raw statements or to the first
+// clause (clauseName) of the statement built by the gorm callback. It runs
+// after the read replica is chosen, which depends on the raw SQL.
+func (p requestTagsPlugin) tag(clauseName string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		id := logger.RequestID(db.Statement.Context)
+		if len(id) == 0 || strings.Contains(id, "*/") {
+			return
+		}
+		comment := "/* request_id=" + id + " */"
+		if db.Statement.SQL.Len() > 0 {
+			sql := db.Statement.SQL.String()
+			db.Statement.SQL.Reset()
+			db.Statement.SQL.WriteString(comment + " " + sql)
+			return
+		}
+		if len(clauseName) == 0 {
+			return
+		}
+		c := db.Statement.Clauses[clauseName]
+		c.BeforeExpression = clause.Expr{SQL: comment}
+		db.Statement.Clauses[clauseName] = c
+	}
+}
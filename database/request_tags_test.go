@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+	"flare-indexer/logger"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDTags(t *testing.T) {
+	db := dryRunDB(t)
+	require.NoError(t, UseRequestIDTags(db))
+
+	ctx := logger.WithRequestID(context.Background(), "abc-1")
+	var states []State
+	stmt := db.WithContext(ctx).Find(&states).Statement
+	require.Equal(t, "/* request_id=abc-1 */ SELECT * FROM `x_states`", stmt.SQL.String())
+
+	// An ID which would end the comment is not added
+	ctx = logger.WithRequestID(context.Background(), "a*/ DROP TABLE x /*")
+	stmt = db.WithContext(ctx).Find(&states).Statement
+	require.Equal(t, "SELECT * FROM `x_states`", stmt.SQL.String())
+}
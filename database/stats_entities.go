@@ -0,0 +1,26 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with daily aggregates of the indexed P-chain transactions, maintained
+// by the stats cronjob so that the landing pages of explorers do not query the
+// transaction tables
+type DailyStats struct {
+	BaseEntity
+	Day             time.Time `gorm:"uniqueIndex"` // Start of the day (UTC)
+	TxCount         uint64    // Number of accepted transactions of the day
+	TotalStaked     BigInt    // Weight of the validators and delegators active at the end of the day
+	UniqueAddresses uint64    // Number of distinct input addresses of the transactions of the day
+	NewValidators   uint64    // Number of nodes which added their first validator transaction
+}
+
+// Table with the number of accepted transactions of each type per day, the
+// counts of the types of the day add up to DailyStats.TxCount
+type DailyTxCount struct {
+	BaseEntity
+	Day     time.Time    `gorm:"uniqueIndex:idx_daily_tx_counts_day_type,priority:1"` // Start of the day (UTC)
+	Type    PChainTxType `gorm:"type:varchar(40);uniqueIndex:idx_daily_tx_counts_day_type,priority:2"`
+	TxCount uint64
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Aggregates the accepted transactions of the day starting at day (UTC), the
+// counts per type are ordered by type
+func AggregateDailyStats(ctx context.Context, db *gorm.DB, day time.Time) (*DailyStats, []DailyTxCount, error) {
+	db = db.WithContext(ctx)
+	end := day.Add(24 * time.Hour)
+	dayFilter := PChainTxFilter{Status: PChainTxAccepted, From: day, To: end}
+
+	// Blocks without transactions are stored as transactions without id
+	var counts []DailyTxCount
+	err := dayFilter.apply(db.Table(pChainTxTable(db))).
+		Where("p_chain_txes.tx_id IS NOT NULL").
+		Select("p_chain_txes.type AS type, COUNT(*) AS tx_count").
+		Group("p_chain_txes.type").
+		Order("p_chain_txes.type").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	stats := &DailyStats{Day: day}
+	for i := range counts {
+		counts[i].Day = day
+		stats.TxCount += counts[i].TxCount
+	}
+
+	stakers := PChainTxFilter{Types: pChainStakingTxTypes, Status: PChainTxAccepted, ActiveAt: end}
+	err = stakers.apply(db.Table(pChainTxTable(db))).
+		Select("COALESCE(SUM(p_chain_txes.weight), 0)").
+		Scan(&stats.TotalStaked).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = dayFilter.apply(db.Table(pChainTxTable(db))).
+		Joins(pChainInputsJoin(db)).
+		Select("COUNT(DISTINCT inputs.address)").
+		Scan(&stats.UniqueAddresses).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	earlier := db.Session(&gorm.Session{NewDB: true}).
+		Table(tableName(db, "PChainTx")+" as earlier").
+		Select("1").
+		Where("earlier.node_id = p_chain_txes.node_id").
+		Where("earlier.type IN ?", pChainValidatorTxTypes).
+		Where("earlier.status = ?", PChainTxAccepted).
+		Where("earlier.timestamp < ?", day)
+	validators := PChainTxFilter{Types: pChainValidatorTxTypes, Status: PChainTxAccepted, From: day, To: end}
+	err = validators.apply(db.Table(pChainTxTable(db))).
+		Where("NOT EXISTS (?)", earlier).
+		Select("COUNT(DISTINCT p_chain_txes.node_id)").
+		Scan(&stats.NewValidators).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return stats, counts, nil
+}
+
+// Stores the stats of the day, replacing the stats of an earlier aggregation
+func UpsertDailyStats(ctx context.Context, db *gorm.DB, stats *DailyStats, counts []DailyTxCount) error {
+	return DoInTransaction(ctx, db,
+		func(tx *gorm.DB) error {
+			return tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "day"}},
+				DoUpdates: clause.AssignmentColumns([]string{"tx_count", "total_staked", "unique_addresses", "new_validators", "updated_at"}),
+			}).Create(stats).Error
+		},
+		func(tx *gorm.DB) error {
+			return tx.Where("day = ?", stats.Day).Delete(&DailyTxCount{}).Error
+		},
+		func(tx *gorm.DB) error {
+			if len(counts) == 0 {
+				return nil
+			}
+			return tx.Create(counts).Error
+		},
+	)
+}
+
+// Stats of the aggregated days in [from, to) and their counts per type, both
+// ordered by day
+func FetchDailyStats(ctx context.Context, db *gorm.DB, from time.Time, to time.Time) ([]DailyStats, []DailyTxCount, error) {
+	db = db.WithContext(ctx)
+	var stats []DailyStats
+	err := db.Where("day >= ? AND day < ?", from, to).Order("day").Find(&stats).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var counts []DailyTxCount
+	err = db.Where("day >= ? AND day < ?", from, to).Order("day").Order("type").Find(&counts).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return stats, counts, nil
+}
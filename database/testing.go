@@ -6,9 +6,7 @@ import (
 	"time"
 
 	"github.com/go-sql-driver/mysql"
-	gormMysql "gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 const (
@@ -29,16 +27,11 @@ func ConnectTestDB(cfg *config.DBConfig) (*gorm.DB, error) {
 		ParseTime:            true,
 	}
 
-	var gormLogLevel logger.LogLevel
-	if cfg.LogQueries {
-		gormLogLevel = logger.Info
-	} else {
-		gormLogLevel = logger.Silent
-	}
-	gormConfig := gorm.Config{
-		Logger: logger.Default.LogMode(gormLogLevel),
+	precision, err := cfg.DatetimePrecision()
+	if err != nil {
+		return nil, err
 	}
-	return gorm.Open(gormMysql.Open(dbConfig.FormatDSN()), &gormConfig)
+	return gorm.Open(newDialector(&dbConfig, precision), newGormConfig(cfg, precision))
 }
 
 func ConnectAndInitializeTestDB(cfg *config.DBConfig, dropTables bool) (*gorm.DB, error) {
@@ -74,7 +67,7 @@ func FetchTransactionsByBlockHeights(db *gorm.DB, heights []uint64) ([]*PChainTx
 
 func FetchUptimes(db *gorm.DB, nodeIDs []string, start time.Time, end time.Time) ([]*UptimeCronjob, error) {
 	var uptimes []*UptimeCronjob
-	query := db.Table("uptime_cronjobs").
+	query := db.Table(tableName(db, "UptimeCronjob")).
 		Where("timestamp >= ?", start).
 		Where("timestamp < ?", end)
 	if len(nodeIDs) > 0 {
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	statementTimeoutCancelKey = "flare:statement_timeout_cancel"
+	noStatementTimeoutKey     = "flare:no_statement_timeout"
+)
+
+// Plugin limiting the duration of each statement. The timeout is added to the
+// context of the statement, so it applies on top of the deadline of the
+// context passed to the query functions.
+type statementTimeout struct {
+	timeout time.Duration
+}
+
+func (p statementTimeout) Name() string {
+	return "flare:statement_timeout"
+}
+
+func (p statementTimeout) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("*").Register("flare:timeout_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("*").Register("flare:timeout_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("*").Register("flare:timeout_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("*").Register("flare:timeout_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("*").Register("flare:timeout_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("*").Register("flare:timeout_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("*").Register("flare:timeout_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("*").Register("flare:timeout_end", p.end); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("*").Register("flare:timeout_start", p.start); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("*").Register("flare:timeout_end", p.end); err != nil {
+		return err
+	}
+	// Rows (and Scan) read the result after the callbacks have finished, so
+	// the context can not be cancelled early, it is released at the deadline
+	return callbacks.Row().Before("*").Register("flare:timeout_start", p.start)
+}
+
+func (p statementTimeout) start(db *gorm.DB) {
+	if _, ok := db.Get(noStatementTimeoutKey); ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(db.Statement.Context, p.timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(statementTimeoutCancelKey, cancel)
+}
+
+func (p statementTimeout) end(db *gorm.DB) {
+	if cancel, ok := db.InstanceGet(statementTimeoutCancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// Session of db without the statement timeout of the config, e.g., for
+// queries streaming large results. Such queries must be limited by the
+// deadline of their context instead.
+func WithoutStatementTimeout(db *gorm.DB) *gorm.DB {
+	return db.Set(noStatementTimeoutKey, true)
+}
@@ -0,0 +1,16 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with copies of the rows deleted when indexed data is deleted to index
+// it again, kept for investigations until the retention period of the indexer
+// config expires
+type Tombstone struct {
+	BaseEntity
+	SourceTable string    `gorm:"type:varchar(64);not null;index"` // Table of the deleted row, including the table prefix
+	Reason      string    `gorm:"type:varchar(256)"`
+	Data        string    `gorm:"type:text"` // Deleted row as JSON
+	Timestamp   time.Time `gorm:"index"`     // Time of the deletion
+}
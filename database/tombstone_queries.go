@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Number of rows copied to tombstones with a single query
+const tombstoneBatchSize = 1000
+
+// Copies the rows of the entity selected by the query to tombstones, before
+// they are deleted with the same query
+func createTombstones[T any](db *gorm.DB, query *gorm.DB, entityName string, reason string, now time.Time) error {
+	table := tableName(db, entityName)
+	var rows []T
+	return query.Session(&gorm.Session{}).FindInBatches(&rows, tombstoneBatchSize, func(_ *gorm.DB, _ int) error {
+		tombstones := make([]*Tombstone, len(rows))
+		for i := range rows {
+			data, err := json.Marshal(&rows[i])
+			if err != nil {
+				return err
+			}
+			tombstones[i] = &Tombstone{
+				SourceTable: table,
+				Reason:      reason,
+				Data:        string(data),
+				Timestamp:   now,
+			}
+		}
+		return db.Create(tombstones).Error
+	}).Error
+}
+
+// Deletes the tombstones created before the given time
+func DeleteTombstonesBefore(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	result := db.WithContext(ctx).Where("timestamp < ?", before).Delete(&Tombstone{})
+	return result.RowsAffected, result.Error
+}
+
+// Returns the tombstones of the table (of all tables if empty), the newest first
+func FetchTombstones(ctx context.Context, db *gorm.DB, sourceTable string, offset int, limit int) ([]Tombstone, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := db.WithContext(ctx)
+	if len(sourceTable) > 0 {
+		query = query.Where("source_table = ?", sourceTable)
+	}
+	var tombstones []Tombstone
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&tombstones).Error
+	return tombstones, err
+}
@@ -9,6 +9,9 @@ const (
 	XChainImportTx XChainTxType = "IMPORT_TX"
 )
 
+// Indexed X-chain transaction types
+var XChainTxTypes = []XChainTxType{XChainBaseTx, XChainImportTx}
+
 // P-chain types
 
 type PChainTxType string
@@ -24,8 +27,55 @@ const (
 	PChainCreateSubnetTx       PChainTxType = "CREATE_SUBNET_TX"
 	PChainAddSubnetValidatorTx PChainTxType = "ADD_SUBNET_VALIDATOR_TX"
 	PChainUnknownTx            PChainTxType = "UNKNOWN_TX"
+
+	// Transaction types introduced with Banff
+	PChainAddPermissionlessValidatorTx PChainTxType = "ADD_PERMISSIONLESS_VALIDATOR_TX"
+	PChainAddPermissionlessDelegatorTx PChainTxType = "ADD_PERMISSIONLESS_DELEGATOR_TX"
+	PChainRemoveSubnetValidatorTx      PChainTxType = "REMOVE_SUBNET_VALIDATOR_TX"
+	PChainTransformSubnetTx            PChainTxType = "TRANSFORM_SUBNET_TX"
+)
+
+// Indexed P-chain transaction types
+var PChainTxTypes = []PChainTxType{
+	PChainRewardValidatorTx, PChainAddDelegatorTx, PChainAddValidatorTx, PChainImportTx,
+	PChainExportTx, PChainAdvanceTimeTx, PChainCreateChainTx, PChainCreateSubnetTx,
+	PChainAddSubnetValidatorTx, PChainAddPermissionlessValidatorTx, PChainAddPermissionlessDelegatorTx,
+	PChainRemoveSubnetValidatorTx, PChainTransformSubnetTx,
+}
+
+// Validators and delegators of the primary network are added by the Apricot
+// transactions and, since Banff, by the permissionless ones
+var (
+	pChainValidatorTxTypes = []PChainTxType{PChainAddValidatorTx, PChainAddPermissionlessValidatorTx}
+	pChainDelegatorTxTypes = []PChainTxType{PChainAddDelegatorTx, PChainAddPermissionlessDelegatorTx}
+
+	// Types of the staking transactions whose weight is staked
+	pChainStakingTxTypes = []PChainTxType{
+		PChainAddValidatorTx, PChainAddDelegatorTx, PChainAddPermissionlessValidatorTx, PChainAddPermissionlessDelegatorTx,
+	}
 )
 
+func (t PChainTxType) IsValidatorTx() bool {
+	return t == PChainAddValidatorTx || t == PChainAddPermissionlessValidatorTx
+}
+
+func (t PChainTxType) IsDelegatorTx() bool {
+	return t == PChainAddDelegatorTx || t == PChainAddPermissionlessDelegatorTx
+}
+
+// Types of the transactions adding validators (txType is PChainAddValidatorTx)
+// or delegators (txType is PChainAddDelegatorTx), permissionless ones included
+func pChainStakerTxTypes(txType PChainTxType) ([]PChainTxType, error) {
+	switch txType {
+	case PChainAddValidatorTx:
+		return pChainValidatorTxTypes, nil
+	case PChainAddDelegatorTx:
+		return pChainDelegatorTxTypes, nil
+	default:
+		return nil, errInvalidTransactionType
+	}
+}
+
 type PChainBlockType string
 
 const (
@@ -35,6 +85,19 @@ const (
 	PChainStandardBlock PChainBlockType = "STANDARD_BLOCK"
 )
 
+// Status of a P-chain transaction. Transactions of a proposal block take effect
+// only if the block is followed by a commit block, they are aborted otherwise.
+type PChainTxStatus string
+
+const (
+	// Transaction of a standard or option block, or of a committed proposal block
+	PChainTxAccepted PChainTxStatus = "ACCEPTED"
+	// Transaction of a proposal block whose option block is not indexed yet
+	PChainTxProposed PChainTxStatus = "PROPOSED"
+	// Transaction of a proposal block followed by an abort block
+	PChainTxAborted PChainTxStatus = "ABORTED"
+)
+
 type PChainOutputType string
 
 const (
@@ -52,3 +115,39 @@ const (
 	MigrationCompleted MigrationStatus = "COMPLETED"
 	MigrationFailed    MigrationStatus = "FAILED"
 )
+
+type MirroringAttemptStatus string
+
+const (
+	MirroringAttemptSucceeded MirroringAttemptStatus = "SUCCEEDED" // Transaction mined successfully
+	MirroringAttemptReverted  MirroringAttemptStatus = "REVERTED"  // Transaction mined but reverted
+	MirroringAttemptRejected  MirroringAttemptStatus = "REJECTED"  // Transaction was not sent (e.g. gas estimation failed)
+	MirroringAttemptPending   MirroringAttemptStatus = "PENDING"   // Transaction was sent but its receipt was not seen yet
+	// Transaction was not sent, the stake was mirrored by another provider
+	MirroringAttemptAlreadyMirrored MirroringAttemptStatus = "ALREADY_MIRRORED"
+)
+
+type AnomalyType string
+
+const (
+	AnomalyDuplicateTx     AnomalyType = "DUPLICATE_TX"      // Transaction with several rows in the voting data of an epoch
+	AnomalyMultipleEpochs  AnomalyType = "MULTIPLE_EPOCHS"   // Stake mirrored in an epoch other than the one it starts in
+	AnomalyDoubleMirrored  AnomalyType = "DOUBLE_MIRRORED"   // Stake mirrored successfully more than once
+	AnomalyWeightMismatch  AnomalyType = "WEIGHT_MISMATCH"   // Indexed weight differs from the weight reported by the node
+	AnomalySourceNotSigner AnomalyType = "SOURCE_NOT_SIGNER" // Input address of the mirrored stake did not sign its first input
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"   // Not delivered yet, retried at NextAttempt
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED" // Receiver responded with a 2xx status
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED"    // All attempts failed
+)
+
+type WatchlistKind string
+
+const (
+	WatchlistAddress WatchlistKind = "ADDRESS" // P-chain address (without "P-" prefix)
+	WatchlistNodeID  WatchlistKind = "NODE_ID"
+)
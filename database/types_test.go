@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPChainStakerTxTypes(t *testing.T) {
+	types, err := pChainStakerTxTypes(PChainAddValidatorTx)
+	require.NoError(t, err)
+	require.Equal(t, []PChainTxType{PChainAddValidatorTx, PChainAddPermissionlessValidatorTx}, types)
+	for _, txType := range types {
+		require.True(t, txType.IsValidatorTx())
+		require.False(t, txType.IsDelegatorTx())
+	}
+
+	types, err = pChainStakerTxTypes(PChainAddDelegatorTx)
+	require.NoError(t, err)
+	require.Equal(t, []PChainTxType{PChainAddDelegatorTx, PChainAddPermissionlessDelegatorTx}, types)
+	for _, txType := range types {
+		require.True(t, txType.IsDelegatorTx())
+		require.False(t, txType.IsValidatorTx())
+	}
+
+	_, err = pChainStakerTxTypes(PChainAddPermissionlessValidatorTx)
+	require.ErrorIs(t, err, errInvalidTransactionType)
+	require.False(t, PChainAddSubnetValidatorTx.IsValidatorTx())
+}
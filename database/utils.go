@@ -1,13 +1,18 @@
 package database
 
 import (
+	"context"
 	"flare-indexer/config"
+	"flare-indexer/utils"
 	"fmt"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	gormMysql "gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
 )
 
 var (
@@ -22,8 +27,31 @@ var (
 		PChainTx{},
 		PChainTxInput{},
 		PChainTxOutput{},
+		PChainBlock{},
+		PChainTxSigner{},
 		UptimeCronjob{},
 		UptimeAggregation{},
+		MirroringAttempt{},
+		MirroringEpoch{},
+		ValidatorWeight{},
+		Anomaly{},
+		MirroringStakeConfirmedEvent{},
+		VotingVoteSubmittedEvent{},
+		VotingFinalizedEvent{},
+		FeatureFlag{},
+		WebhookDelivery{},
+		WebhookSubscription{},
+		WatchlistEntry{},
+		WatchlistMatch{},
+		CronjobRun{},
+		ValidatorLabel{},
+		ContractSubmission{},
+		Tombstone{},
+		ChangeFeedEntry{},
+		EpochMerkleRoot{},
+		DailyStats{},
+		DailyTxCount{},
+		KafkaSinkCheckpoint{},
 	}
 )
 
@@ -38,17 +66,94 @@ func Connect(cfg *config.DBConfig) (*gorm.DB, error) {
 		AllowNativePasswords: true,
 		ParseTime:            true,
 	}
+	precision, err := cfg.DatetimePrecision()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(newDialector(&dbConfig, precision), newGormConfig(cfg, precision))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.QueryTimeout > 0 {
+		if err := db.Use(statementTimeout{timeout: cfg.QueryTimeout}); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Connect to the database and send queries to the read replicas configured
+// in cfg. Other statements and transactions use the primary database.
+func ConnectWithReadReplicas(cfg *config.DBConfig) (*gorm.DB, error) {
+	db, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.ReadReplicas) == 0 {
+		return db, nil
+	}
 
+	precision, err := cfg.DatetimePrecision()
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]gorm.Dialector, len(cfg.ReadReplicas))
+	for i, dsn := range cfg.ReadReplicas {
+		replicaConfig, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			// Do not print the DSN, it contains the password
+			return nil, fmt.Errorf("invalid DSN of read replica %d", i+1)
+		}
+		replicaConfig.AllowNativePasswords = true
+		replicaConfig.ParseTime = true
+		replicas[i] = newDialector(replicaConfig, precision)
+	}
+	err = db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Times are written and read in UTC and the session time zone is UTC, so the
+// stored timestamps do not depend on the time zone of the server or the
+// database. Datetime columns have the given number of fractional second digits.
+func newDialector(dsn *mysql.Config, precision int) gorm.Dialector {
+	dsn.Loc = time.UTC
+	if dsn.Params == nil {
+		dsn.Params = make(map[string]string)
+	}
+	dsn.Params["time_zone"] = "'+00:00'"
+	return gormMysql.New(gormMysql.Config{
+		DSN:                      dsn.FormatDSN(),
+		DefaultDatetimePrecision: &precision,
+	})
+}
+
+func newGormConfig(cfg *config.DBConfig, precision int) *gorm.Config {
 	var gormLogLevel logger.LogLevel
 	if cfg.LogQueries {
 		gormLogLevel = logger.Info
 	} else {
 		gormLogLevel = logger.Silent
 	}
-	gormConfig := gorm.Config{
-		Logger: logger.Default.LogMode(gormLogLevel),
+	return &gorm.Config{
+		Logger:         logger.Default.LogMode(gormLogLevel),
+		NamingStrategy: schema.NamingStrategy{TablePrefix: cfg.TablePrefix},
+		// Creation and update times of the entities
+		NowFunc: func() time.Time { return utils.TruncateUTC(time.Now(), precision) },
 	}
-	return gorm.Open(gormMysql.Open(dbConfig.FormatDSN()), &gormConfig)
+}
+
+// Table name of the entity with the given struct name, including the table
+// prefix. Queries referring to table names must use it instead of the
+// table name literal.
+func tableName(db *gorm.DB, entityName string) string {
+	return db.NamingStrategy.TableName(entityName)
 }
 
 func ConnectAndInitialize(cfg *config.DBConfig) (*gorm.DB, error) {
@@ -66,8 +171,10 @@ func ConnectAndInitialize(cfg *config.DBConfig) (*gorm.DB, error) {
 	return db, nil
 }
 
-func DoInTransaction(db *gorm.DB, operations ...func(db *gorm.DB) error) error {
-	tx := db.Begin()
+// Runs the operations in a transaction bound to ctx, the transaction is rolled
+// back if ctx is done before it is committed
+func DoInTransaction(ctx context.Context, db *gorm.DB, operations ...func(db *gorm.DB) error) error {
+	tx := db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
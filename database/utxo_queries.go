@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Unspent P-chain output of an address
+type PChainUTXO struct {
+	TxID   string
+	Idx    uint32
+	Amount uint64
+	Type   PChainOutputType
+	// Block height of the transaction of the output (of the staking transaction
+	// for stake and reward outputs)
+	BlockHeight uint64
+	// Stake output of a stake which has not ended yet
+	Locked bool
+}
+
+// Total amounts of the unspent outputs of an address
+type PChainUTXOTotals struct {
+	Count    int64
+	Unlocked BigInt
+	Locked   BigInt
+}
+
+// Returns the query of the unspent P-chain outputs of the address after the
+// block with the given height, with the stakes which ended at or before t
+// returned and their rewards paid. An output is unspent if no input of an
+// accepted transaction up to the height consumes it.
+func pChainUTXOsQuery(db *gorm.DB, address string, height uint64, t time.Time) *gorm.DB {
+	spent := db.Table(tableName(db, "PChainTxInput")+" as inputs").
+		Joins("join "+tableName(db, "PChainTx")+" as spending on spending.tx_id = inputs.tx_id").
+		Where("inputs.out_tx_id = outputs.tx_id AND inputs.out_idx = outputs.idx").
+		Where("spending.status = ? AND spending.block_height <= ?", PChainTxAccepted, height).
+		Select("1")
+	return db.Table(tableName(db, "PChainTxOutput")+" as outputs").
+		Joins("join "+pChainTxTable(db)+" on p_chain_txes.tx_id = outputs.tx_id").
+		Where("outputs.address = ?", address).
+		Where("p_chain_txes.status = ? AND p_chain_txes.block_height <= ?", PChainTxAccepted, height).
+		// Rewards are paid at the end of the stake
+		Where("outputs.type <> ? OR p_chain_txes.end_time <= ?", PChainRewardOutput, t).
+		Where("NOT EXISTS (?)", spent)
+}
+
+// True for stake outputs of stakes ending after t
+func pChainUTXOLocked(t time.Time) clause.Expr {
+	return gorm.Expr("(outputs.type = ? AND p_chain_txes.end_time > ?)", PChainStakeOutput, t)
+}
+
+// Fetch the unspent P-chain outputs of the address after the block with the
+// given height, ordered by height and output. Stakes which ended at or before t
+// are returned to their owners and their rewards are paid; stake outputs of
+// stakes ending after t are locked. Request is paginated (offset, limit).
+func FetchPChainUTXOs(ctx context.Context, db *gorm.DB, address string, height uint64, t time.Time, offset int, limit int) ([]PChainUTXO, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	db = db.WithContext(ctx)
+	var utxos []PChainUTXO
+	err := pChainUTXOsQuery(db, address, height, t).
+		Select("outputs.tx_id, outputs.idx, outputs.amount, outputs.type, p_chain_txes.block_height, ? AS locked",
+			pChainUTXOLocked(t)).
+		Order("p_chain_txes.block_height, outputs.tx_id, outputs.idx").
+		Offset(offset).Limit(limit).
+		Scan(&utxos).Error
+	return utxos, err
+}
+
+// Returns the number of unspent P-chain outputs of the address and their
+// unlocked and locked amounts, see FetchPChainUTXOs
+func FetchPChainUTXOTotals(ctx context.Context, db *gorm.DB, address string, height uint64, t time.Time) (PChainUTXOTotals, error) {
+	db = db.WithContext(ctx)
+	var totals PChainUTXOTotals
+	err := pChainUTXOsQuery(db, address, height, t).
+		Select("COUNT(*) AS count, "+
+			"COALESCE(SUM(CASE WHEN ? THEN 0 ELSE outputs.amount END), 0) AS unlocked, "+
+			"COALESCE(SUM(CASE WHEN ? THEN outputs.amount ELSE 0 END), 0) AS locked",
+			pChainUTXOLocked(t), pChainUTXOLocked(t)).
+		Scan(&totals).Error
+	return totals, err
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"time"
+)
+
+// Address or node ID of interest registered by an operator. Transactions with
+// inputs or outputs of a watched address, or staking to a watched node ID, are
+// tagged when indexed.
+type WatchlistEntry struct {
+	BaseEntity
+	Kind    WatchlistKind `gorm:"type:varchar(20);uniqueIndex:idx_watchlist_entry"`
+	Value   string        `gorm:"type:varchar(60);uniqueIndex:idx_watchlist_entry"`
+	Label   string        `gorm:"type:varchar(100)"` // Description set by the operator
+	Created time.Time
+}
+
+// Tag of a P-chain transaction matching a watchlist entry
+type WatchlistMatch struct {
+	BaseEntity
+	EntryID   uint64        `gorm:"index"` // Id of the watchlist entry (entries can be deleted later)
+	Kind      WatchlistKind `gorm:"type:varchar(20)"`
+	Value     string        `gorm:"type:varchar(60);index"`
+	TxID      string        `gorm:"type:varchar(50);not null;index"` // Transaction ID
+	TxType    PChainTxType  `gorm:"type:varchar(40)"`
+	Timestamp time.Time     // Time when indexed
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"flare-indexer/utils/errcode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func FetchWatchlist(ctx context.Context, db *gorm.DB) ([]WatchlistEntry, error) {
+	var entries []WatchlistEntry
+	err := db.WithContext(ctx).Order("id").Find(&entries).Error
+	return entries, err
+}
+
+func CreateWatchlistEntry(ctx context.Context, db *gorm.DB, entry *WatchlistEntry) error {
+	return db.WithContext(ctx).Create(entry).Error
+}
+
+// Deletes the entry, the matches of the entry are kept
+func DeleteWatchlistEntry(ctx context.Context, db *gorm.DB, id uint64) error {
+	result := db.WithContext(ctx).Delete(&WatchlistEntry{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errcode.Wrap(gorm.ErrRecordNotFound, errcode.NotFound, "entry not found")
+	}
+	return nil
+}
+
+// Creates the matches, existing matches (of the same entry and transaction) are
+// kept
+func CreateWatchlistMatches(ctx context.Context, db *gorm.DB, matches []*WatchlistMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(matches).Error
+}
+
+// Returns the matches of the watched value (all matches if value is empty),
+// the newest first
+func FetchWatchlistMatches(ctx context.Context, db *gorm.DB, value string, offset int, limit int) ([]WatchlistMatch, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := db.WithContext(ctx)
+	if len(value) > 0 {
+		query = query.Where(&WatchlistMatch{Value: value})
+	}
+	var matches []WatchlistMatch
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&matches).Error
+	return matches, err
+}
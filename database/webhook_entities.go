@@ -0,0 +1,35 @@
+package database
+
+import (
+	"time"
+)
+
+// Table with an entry for each webhook call, pending entries are the delivery
+// queue and the others the delivery log
+type WebhookDelivery struct {
+	BaseEntity
+	Webhook        string                `gorm:"type:varchar(100);not null;index"` // Name of the webhook in the config
+	Event          string                `gorm:"type:varchar(40)"`                 // Event type
+	TxID           string                `gorm:"type:varchar(50);index"`           // P-chain transaction of the event
+	Payload        string                `gorm:"type:text"`                        // JSON body
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);index"`
+	Attempts       int                   // Number of failed or successful calls
+	NextAttempt    time.Time             `gorm:"index"` // Time of the next call of a pending delivery
+	ResponseStatus int                   // HTTP status of the last call, 0 if there was no response
+	LastError      string                `gorm:"type:varchar(256)"`
+	Created        time.Time
+	Updated        time.Time
+}
+
+// Webhook registered with the admin routes of the services, called like the
+// webhooks of the indexer config
+type WebhookSubscription struct {
+	BaseEntity
+	Name      string `gorm:"type:varchar(100);not null;uniqueIndex"` // Unique name, also among the webhooks of the config
+	URL       string `gorm:"type:varchar(500)"`
+	Secret    string `gorm:"type:varchar(200)"` // Key of the HMAC-SHA256 signature of the payloads
+	Event     string `gorm:"type:varchar(40)"`  // "address_activity" or "delegation"
+	Addresses string `gorm:"type:text"`         // Comma separated addresses of address_activity webhooks
+	NodeIDs   string `gorm:"type:text"`         // Comma separated node IDs of delegation webhooks
+	Updated   time.Time
+}
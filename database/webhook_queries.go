@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"flare-indexer/utils/errcode"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateWebhookDeliveries(ctx context.Context, db *gorm.DB, deliveries []*WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Create(deliveries).Error
+}
+
+// Returns at most limit pending deliveries with next attempt before now, the
+// oldest first
+func FetchDueWebhookDeliveries(ctx context.Context, db *gorm.DB, now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.WithContext(ctx).Where("status = ? AND next_attempt <= ?", WebhookDeliveryPending, now).
+		Order("id").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func UpdateWebhookDelivery(ctx context.Context, db *gorm.DB, delivery *WebhookDelivery) error {
+	return db.WithContext(ctx).Save(delivery).Error
+}
+
+func FetchWebhookSubscriptions(ctx context.Context, db *gorm.DB) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	err := db.WithContext(ctx).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Creates the subscription or updates the subscription of the same name
+func UpsertWebhookSubscription(ctx context.Context, db *gorm.DB, subscription *WebhookSubscription) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url", "secret", "event", "addresses", "node_ids", "updated"}),
+	}).Create(subscription).Error
+}
+
+// Deletes the subscription, its deliveries are kept
+func DeleteWebhookSubscription(ctx context.Context, db *gorm.DB, name string) error {
+	result := db.WithContext(ctx).Where("name = ?", name).Delete(&WebhookSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errcode.Wrap(gorm.ErrRecordNotFound, errcode.NotFound, "webhook not found")
+	}
+	return nil
+}
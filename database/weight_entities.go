@@ -0,0 +1,13 @@
+package database
+
+// Table with the weights of the validators at the start of each epoch, recorded
+// by the P-chain indexer once the indexed chain passes the start of the epoch
+type ValidatorWeight struct {
+	BaseEntity
+	Epoch           int64  `gorm:"uniqueIndex:idx_validator_weights_epoch_node,priority:1"`
+	NodeID          string `gorm:"type:varchar(50);uniqueIndex:idx_validator_weights_epoch_node,priority:2;index"`
+	OwnWeight       BigInt // Stake of the validator transactions of the node
+	DelegatedWeight BigInt // Stake of the delegator transactions of the node
+	Weight          BigInt // Own plus delegated stake
+	Delegators      int    // Number of delegator transactions
+}
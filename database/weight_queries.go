@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Name of the state with the next epoch of the validator weights (NextDBIndex)
+const ValidatorWeightsStateName = "validator_weights"
+
+func CreateValidatorWeights(ctx context.Context, db *gorm.DB, weights []*ValidatorWeight) error {
+	if len(weights) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Create(weights).Error
+}
+
+// Replaces the recorded weights of the epoch
+func ReplaceValidatorWeights(ctx context.Context, db *gorm.DB, epoch int64, weights []*ValidatorWeight) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("epoch = ?", epoch).Delete(&ValidatorWeight{}).Error; err != nil {
+			return err
+		}
+		return CreateValidatorWeights(ctx, tx, weights)
+	})
+}
+
+// Weights of the validators in the epoch, the highest weight first
+func FetchValidatorWeights(ctx context.Context, db *gorm.DB, epoch int64) ([]ValidatorWeight, error) {
+	var weights []ValidatorWeight
+	err := db.WithContext(ctx).Where("epoch = ?", epoch).
+		Order("weight desc").Order("node_id").
+		Find(&weights).Error
+	return weights, err
+}
+
+// Column by which validator weights are ranked
+type ValidatorWeightOrder string
+
+const (
+	ValidatorWeightOrderWeight     ValidatorWeightOrder = "weight"
+	ValidatorWeightOrderDelegators ValidatorWeightOrder = "delegators"
+)
+
+// The limit validators of the epoch with the highest weight or the most
+// delegators, ties are ordered by node ID
+func FetchTopValidatorWeights(ctx context.Context, db *gorm.DB, epoch int64, order ValidatorWeightOrder, limit int) ([]ValidatorWeight, error) {
+	if order != ValidatorWeightOrderWeight && order != ValidatorWeightOrderDelegators {
+		return nil, fmt.Errorf("invalid validator weight order %q", order)
+	}
+	var weights []ValidatorWeight
+	err := db.WithContext(ctx).Where("epoch = ?", epoch).
+		Order(string(order) + " desc").Order("node_id").
+		Limit(limit).
+		Find(&weights).Error
+	return weights, err
+}
+
+// Weights of the node in the epochs [firstEpoch, lastEpoch], the epochs in which
+// the node was not validating are missing
+func FetchNodeValidatorWeights(ctx context.Context, db *gorm.DB, nodeID string, firstEpoch int64, lastEpoch int64) ([]ValidatorWeight, error) {
+	var weights []ValidatorWeight
+	err := db.WithContext(ctx).Where("node_id = ?", nodeID).
+		Where("epoch >= ?", firstEpoch).Where("epoch <= ?", lastEpoch).
+		Order("epoch").
+		Find(&weights).Error
+	return weights, err
+}
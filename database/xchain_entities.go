@@ -7,11 +7,16 @@ import (
 // Table with indexed data for an X-chain transaction
 type XChainTx struct {
 	BaseEntity
-	Type      XChainTxType `gorm:"type:varchar(20)"`                 // Transaction type
-	TxID      string       `gorm:"type:varchar(50);unique;not null"` // Transaction ID
-	VtxHeight uint64
-	Memo      string `gorm:"type:varchar(256)"`
-	Bytes     []byte `gorm:"type:mediumblob"`
+	Type       XChainTxType `gorm:"type:varchar(20)"`                 // Transaction type
+	TxID       string       `gorm:"type:varchar(50);unique;not null"` // Transaction ID
+	VtxHeight  uint64
+	VtxID      string    `gorm:"type:varchar(50);index"` // Vertex (container) ID
+	VtxIndex   uint64    `gorm:"index"`                  // Vertex index - from indexer
+	VtxTxIndex uint32    // Position of the transaction in the vertex
+	Timestamp  time.Time `gorm:"index"` // Time when the vertex was indexed by the node
+	Fee        uint64    // Burned amount (inputs minus outputs)
+	Memo       string    `gorm:"type:varchar(514)"` // Memo (hex, "0x" prefix), empty if the transaction has no memo
+	Bytes      []byte    `gorm:"type:mediumblob"`
 }
 
 type XChainTxInput struct {
@@ -1,16 +1,85 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
-func FetchXChainTxOutputs(db *gorm.DB, ids []string) ([]XChainTxOutput, error) {
+func FetchXChainTxOutputs(ctx context.Context, db *gorm.DB, ids []string) ([]XChainTxOutput, error) {
 	var txs []XChainTxOutput
-	err := db.Where("tx_id IN ?", ids).Find(&txs).Error
+	err := db.WithContext(ctx).Where("tx_id IN ?", ids).Find(&txs).Error
 	return txs, err
 }
 
-func CreateXChainEntities(db *gorm.DB, vertices []*XChainVtx, txs []*XChainTx, ins []*XChainTxInput, outs []*XChainTxOutput) error {
+func FetchXChainTxInputs(ctx context.Context, db *gorm.DB, ids []string) ([]XChainTxInput, error) {
+	var ins []XChainTxInput
+	err := db.WithContext(ctx).Where("tx_id IN ?", ids).Order("in_idx").Find(&ins).Error
+	return ins, err
+}
+
+// Returns at most limit transactions with database id >= fromID, ordered by id
+func FetchXChainTxsFromID(ctx context.Context, db *gorm.DB, fromID uint64, limit int) ([]XChainTx, error) {
+	var txs []XChainTx
+	err := db.WithContext(ctx).Where("id >= ?", fromID).Order("id").Limit(limit).Find(&txs).Error
+	return txs, err
+}
+
+func UpdateXChainTxFee(ctx context.Context, db *gorm.DB, id uint64, fee uint64) error {
+	return updateWithChangeFeed(db.WithContext(ctx), &XChainTx{}, "XChainTx", "vtx_height",
+		map[string]interface{}{"fee": fee}, "id = ?", id)
+}
+
+func UpdateXChainTxMemo(ctx context.Context, db *gorm.DB, id uint64, memo string) error {
+	return updateWithChangeFeed(db.WithContext(ctx), &XChainTx{}, "XChainTx", "vtx_height",
+		map[string]interface{}{"memo": memo}, "id = ?", id)
+}
+
+// Aggregate fees of X-chain transactions as in FetchPChainBurnStats
+func FetchXChainBurnStats(ctx context.Context, db *gorm.DB, start time.Time, bucket time.Duration, from time.Time, to time.Time) ([]BurnStats, error) {
+	var stats []BurnStats
+	err := burnStatsQuery(db.WithContext(ctx).Model(&XChainTx{}), start, bucket, from, to).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// Returns the vertices with the given heights by height. Vertices with the same
+// height may be indexed at different times, the one with the lowest index is
+// returned.
+func FetchXChainVerticesByHeight(ctx context.Context, db *gorm.DB, heights []uint64) (map[uint64]XChainVtx, error) {
+	var vertices []XChainVtx
+	err := db.WithContext(ctx).Where("height IN ?", heights).Order("vtx_index DESC").Find(&vertices).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uint64]XChainVtx, len(vertices))
+	for _, vtx := range vertices {
+		result[vtx.Height] = vtx
+	}
+	return result, nil
+}
+
+// Sets the vertex of a transaction indexed before the vertices of transactions
+// were stored
+func UpdateXChainTxVertex(ctx context.Context, db *gorm.DB, id uint64, vtx *XChainVtx, vtxTxIndex uint32) error {
+	return updateWithChangeFeed(db.WithContext(ctx), &XChainTx{}, "XChainTx", "vtx_height",
+		map[string]interface{}{
+			"vtx_id":       vtx.VtxID,
+			"vtx_index":    vtx.VtxIndex,
+			"vtx_tx_index": vtxTxIndex,
+			"timestamp":    vtx.Timestamp,
+		}, "id = ?", id)
+}
+
+// Creates the vertices, transactions and their inputs and outputs which are not
+// stored yet and records them in the change feed
+func CreateXChainEntities(ctx context.Context, db *gorm.DB, vertices []*XChainVtx, txs []*XChainTx, ins []*XChainTxInput, outs []*XChainTxOutput) error {
+	db = db.WithContext(ctx)
+	vertices, txs, ins, outs, err := newXChainEntities(db, vertices, txs, ins, outs)
+	if err != nil {
+		return err
+	}
 	if len(vertices) > 0 { // attempt to create from an empty slice returns error
 		err := db.Create(vertices).Error
 		if err != nil {
@@ -30,7 +99,131 @@ func CreateXChainEntities(db *gorm.DB, vertices []*XChainVtx, txs []*XChainTx, i
 		}
 	}
 	if len(outs) > 0 {
-		return db.Create(outs).Error
+		err := db.Create(outs).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	vtxRows := make([]changedRow, len(vertices))
+	for i, vtx := range vertices {
+		vtxRows[i] = changedRow{id: vtx.ID, height: vtx.Height}
+	}
+	heights := make(map[string]uint64, len(txs))
+	txRows := make([]changedRow, len(txs))
+	for i, tx := range txs {
+		heights[tx.TxID] = tx.VtxHeight
+		txRows[i] = changedRow{id: tx.ID, height: tx.VtxHeight}
+	}
+	inRows := make([]changedRow, len(ins))
+	for i, in := range ins {
+		inRows[i] = changedRow{id: in.ID, height: heights[in.TxID]}
+	}
+	outRows := make([]changedRow, len(outs))
+	for i, out := range outs {
+		outRows[i] = changedRow{id: out.ID, height: heights[out.TxID]}
+	}
+	if err := recordCreated(db, "XChainVtx", vtxRows); err != nil {
+		return err
+	}
+	if err := recordCreated(db, "XChainTx", txRows); err != nil {
+		return err
+	}
+	if err := recordCreated(db, "XChainTxInput", inRows); err != nil {
+		return err
+	}
+	return recordCreated(db, "XChainTxOutput", outRows)
+}
+
+func newXChainEntities(db *gorm.DB, vertices []*XChainVtx, txs []*XChainTx, ins []*XChainTxInput, outs []*XChainTxOutput) (
+	[]*XChainVtx, []*XChainTx, []*XChainTxInput, []*XChainTxOutput, error,
+) {
+	vtxIDs := make([]string, len(vertices))
+	for i, vtx := range vertices {
+		vtxIDs[i] = vtx.VtxID
+	}
+	vertices, err := newRows(db, vertices, "vtx_id", func(vtx *XChainVtx) string { return vtx.VtxID },
+		"vtx_id IN ?", vtxIDs)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	txIDs := make([]string, len(txs))
+	for i, tx := range txs {
+		txIDs[i] = tx.TxID
+	}
+	txs, err = newRows(db, txs, "tx_id", func(tx *XChainTx) string { return tx.TxID }, "tx_id IN ?", txIDs)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	ins, err = newInputs(db, ins, func(in *XChainTxInput) *TxInput { return &in.TxInput })
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	outs, err = newOutputs(db, outs, func(out *XChainTxOutput) *TxOutput { return &out.TxOutput })
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return vertices, txs, ins, outs, nil
+}
+
+// Deletes the vertices with an index >= fromIndex, the transactions of these
+// and later vertices and their inputs and outputs, so the containers can be
+// indexed again. The deleted rows are kept as tombstones with the given reason.
+// Returns the number of deleted vertices.
+func DeleteXChainEntitiesFromIndex(ctx context.Context, db *gorm.DB, fromIndex uint64, reason string) (int64, error) {
+	db = db.WithContext(ctx)
+	var minHeight *uint64
+	err := db.Model(&XChainVtx{}).Where("vtx_index >= ?", fromIndex).
+		Select("MIN(height)").Scan(&minHeight).Error
+	if err != nil || minHeight == nil {
+		return 0, err
+	}
+	now := db.NowFunc()
+	txIDs := db.Model(&XChainTx{}).Select("tx_id").Where("vtx_height >= ?", *minHeight)
+
+	inputs := db.Where("tx_id IN (?)", txIDs)
+	err = recordChanges(db, "XChainTxInput", ChangeDeleted, txIORows(db, "XChainTxInput", "XChainTx", "vtx_height", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[XChainTxInput](db, inputs, "XChainTxInput", reason, now); err != nil {
+		return 0, err
+	}
+	if err := inputs.Delete(&XChainTxInput{}).Error; err != nil {
+		return 0, err
+	}
+	outputs := db.Where("tx_id IN (?)", txIDs)
+	err = recordChanges(db, "XChainTxOutput", ChangeDeleted, txIORows(db, "XChainTxOutput", "XChainTx", "vtx_height", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[XChainTxOutput](db, outputs, "XChainTxOutput", reason, now); err != nil {
+		return 0, err
+	}
+	if err := outputs.Delete(&XChainTxOutput{}).Error; err != nil {
+		return 0, err
+	}
+	txs := db.Where("vtx_height >= ?", *minHeight)
+	err = recordChanges(db, "XChainTx", ChangeDeleted,
+		db.Model(&XChainTx{}).Select("id, vtx_height AS height").Where("vtx_height >= ?", *minHeight))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[XChainTx](db, txs, "XChainTx", reason, now); err != nil {
+		return 0, err
+	}
+	if err := txs.Delete(&XChainTx{}).Error; err != nil {
+		return 0, err
+	}
+	vertices := db.Where("vtx_index >= ?", fromIndex)
+	err = recordChanges(db, "XChainVtx", ChangeDeleted,
+		db.Model(&XChainVtx{}).Select("id, height").Where("vtx_index >= ?", fromIndex))
+	if err != nil {
+		return 0, err
+	}
+	if err := createTombstones[XChainVtx](db, vertices, "XChainVtx", reason, now); err != nil {
+		return 0, err
 	}
-	return nil
+	result := vertices.Delete(&XChainVtx{})
+	return result.RowsAffected, result.Error
 }
@@ -3,33 +3,177 @@ package config
 import (
 	"flare-indexer/config"
 	"flare-indexer/utils"
+	"fmt"
+	"regexp"
+	"sort"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/ethereum/go-ethereum/common"
 )
 
+var networkNameRegex = regexp.MustCompile("^[a-z][a-z0-9_]*$")
+
 type Config struct {
-	DB                config.DBConfig     `toml:"db"`
-	Logger            config.LoggerConfig `toml:"logger"`
-	Chain             config.ChainConfig  `toml:"chain"`
-	Metrics           MetricsConfig       `toml:"metrics"`
-	XChainIndexer     IndexerConfig       `toml:"x_chain_indexer"`
-	PChainIndexer     IndexerConfig       `toml:"p_chain_indexer"`
-	UptimeCronjob     UptimeConfig        `toml:"uptime_cronjob"`
-	Mirror            MirrorConfig        `toml:"mirroring_cronjob"`
-	VotingCronjob     VotingConfig        `toml:"voting_cronjob"`
-	ContractAddresses ContractAddresses   `toml:"contract_addresses"`
+	DB                config.DBConfig            `toml:"db"`
+	Logger            config.LoggerConfig        `toml:"logger"`
+	Chain             config.ChainConfig         `toml:"chain"`
+	Metrics           MetricsConfig              `toml:"metrics"`
+	XChainIndexer     IndexerConfig              `toml:"x_chain_indexer"`
+	PChainIndexer     IndexerConfig              `toml:"p_chain_indexer"`
+	ValidatorWeights  ValidatorWeightsConfig     `toml:"validator_weights"`
+	UptimeCronjob     UptimeConfig               `toml:"uptime_cronjob"`
+	Mirror            MirrorConfig               `toml:"mirroring_cronjob"`
+	VotingCronjob     VotingConfig               `toml:"voting_cronjob"`
+	Anomalies         AnomaliesConfig            `toml:"anomalies_cronjob"`
+	Reconciliation    ReconciliationConfig       `toml:"reconciliation_cronjob"`
+	ContractEvents    ContractEventsConfig       `toml:"contract_events_cronjob"`
+	Balance           BalanceConfig              `toml:"balance_cronjob"`
+	ClockDrift        ClockDriftConfig           `toml:"clock_drift_cronjob"`
+	ContractAddresses ContractAddresses          `toml:"contract_addresses"`
+	StakingFilter     config.StakingFilterConfig `toml:"staking_filter"`
+	StakingRules      config.StakingRulesConfig  `toml:"staking_rules"`
+	FeatureFlags      FeatureFlagsConfig         `toml:"feature_flags"`
+	Events            EventsConfig               `toml:"events"`
+	KafkaSink         KafkaSinkConfig            `toml:"kafka_sink"`
+	Webhooks          WebhooksConfig             `toml:"webhooks"`
+	CronjobRuns       CronjobRunsConfig          `toml:"cronjob_runs"`
+	Tombstones        TombstonesConfig           `toml:"tombstones"`
+	ChangeFeed        ChangeFeedConfig           `toml:"change_feed"`
+	Pruning           PruningConfig              `toml:"pruning_cronjob"`
+	Stats             StatsConfig                `toml:"stats_cronjob"`
+	ValidatorLabels   ValidatorLabelsConfig      `toml:"validator_labels"`
+
+	// Networks indexed by this process, each [networks.<name>] section overrides
+	// the settings above for one network
+	Networks map[string]toml.Primitive `toml:"networks" ignored:"true"`
+
+	// Name of the network, empty if Networks is not set
+	Network string `toml:"-" ignored:"true"`
 }
 
 type MetricsConfig struct {
 	PrometheusAddress string `toml:"prometheus_address" envconfig:"PROMETHEUS_ADDRESS"`
 }
 
+type CronjobRunsConfig struct {
+	// Runs older than this are deleted from the cronjob_runs table, 0 to keep all
+	Retention time.Duration `toml:"retention" envconfig:"CRONJOB_RUNS_RETENTION"`
+}
+
+type TombstonesConfig struct {
+	// Copies of the rows deleted by reindex are kept in the tombstones table for
+	// this long, 0 to keep them forever
+	Retention time.Duration `toml:"retention" envconfig:"TOMBSTONES_RETENTION"`
+}
+
+type ChangeFeedConfig struct {
+	// Entries of the change feed are kept for this long, 0 to keep them forever
+	Retention time.Duration `toml:"retention" envconfig:"CHANGE_FEED_RETENTION"`
+}
+
+// Deletion of the tombstones and change feed entries older than their retention
+type PruningConfig struct {
+	CronjobConfig
+}
+
+// Aggregation of the indexed transactions of each completed day in the
+// daily_stats table
+type StatsConfig struct {
+	CronjobConfig
+}
+
+type ValidatorLabelsConfig struct {
+	// TOML or JSON file with validator labels, loaded on startup
+	File string `toml:"file" envconfig:"VALIDATOR_LABELS_FILE"`
+}
+
+type FeatureFlagsConfig struct {
+	// Period of reading the feature_flags table, 0 to read it only on startup
+	RefreshPeriod time.Duration `toml:"refresh_period" envconfig:"FEATURE_FLAGS_REFRESH_PERIOD"`
+}
+
+type EventsConfig struct {
+	Enabled bool `toml:"enabled" envconfig:"EVENTS_ENABLED"`
+	// Message broker, "redis" (pub/sub) or "nats"
+	Backend string `toml:"backend" envconfig:"EVENTS_BACKEND"`
+	// e.g. "redis://localhost:6379/0" or "nats://localhost:4222"
+	URL string `toml:"url" envconfig:"EVENTS_URL"`
+	// "json" or "protobuf" (google.protobuf.Struct)
+	Serialization string `toml:"serialization" envconfig:"EVENTS_SERIALIZATION"`
+	// Events are published to topic <topic_prefix><event type> unless set in Topics
+	TopicPrefix string            `toml:"topic_prefix" envconfig:"EVENTS_TOPIC_PREFIX"`
+	Topics      map[string]string `toml:"topics"`
+}
+
+type KafkaSinkConfig struct {
+	Enabled bool     `toml:"enabled" envconfig:"KAFKA_SINK_ENABLED"`
+	Brokers []string `toml:"brokers" envconfig:"KAFKA_SINK_BROKERS"`
+	// Topics of P-chain and X-chain transactions, chains without a topic are not streamed
+	PChainTopic string        `toml:"p_chain_topic" envconfig:"KAFKA_SINK_P_CHAIN_TOPIC"`
+	XChainTopic string        `toml:"x_chain_topic" envconfig:"KAFKA_SINK_X_CHAIN_TOPIC"`
+	Timeout     time.Duration `toml:"timeout"`
+	BatchSize   int           `toml:"batch_size"`
+}
+
+type WebhooksConfig struct {
+	Enabled bool `toml:"enabled" envconfig:"WEBHOOKS_ENABLED"`
+	// Period of queueing the events of new transactions and calling the webhooks
+	Timeout   time.Duration `toml:"timeout"`
+	BatchSize int           `toml:"batch_size"`
+	// Number of calls before a delivery fails
+	MaxAttempts int `toml:"max_attempts"`
+	// Delay before the first retry, doubled after each failed call up to MaxRetryDelay
+	RetryDelay     time.Duration   `toml:"retry_delay"`
+	MaxRetryDelay  time.Duration   `toml:"max_retry_delay"`
+	RequestTimeout time.Duration   `toml:"request_timeout"`
+	Hooks          []WebhookConfig `toml:"hooks" ignored:"true"`
+}
+
+type WebhookConfig struct {
+	// Unique name, deliveries of the webhook are logged by name
+	Name string `toml:"name"`
+	URL  string `toml:"url"`
+	// Key of the HMAC-SHA256 signature of the payloads
+	Secret string `toml:"secret"`
+	// "address_activity" or "delegation"
+	Event string `toml:"event"`
+	// Addresses of address_activity webhooks
+	Addresses []string `toml:"addresses"`
+	// Node IDs of delegation webhooks
+	NodeIDs []string `toml:"node_ids"`
+}
+
 type IndexerConfig struct {
 	Enabled    bool          `toml:"enabled"`
 	Timeout    time.Duration `toml:"timeout"`
 	BatchSize  int           `toml:"batch_size"`
 	StartIndex uint64        `toml:"start_index"`
+	// Number of batches fetched from the node and persisted in one transaction,
+	// 1 if not set
+	WriteBuffer int `toml:"write_buffer"`
+	// No more containers are added to a transaction once its rows reach this
+	// value, no limit if zero. A single container is not split.
+	MaxRowsPerTransaction int `toml:"max_rows_per_transaction"`
+	// If the container at the next index is not available on the node, continue
+	// at the first available index instead of failing
+	AdjustStartIndex bool `toml:"adjust_start_index"`
+	// Indexed transaction types, e.g. "ADD_VALIDATOR_TX", all types if empty
+	IncludeTypes []string `toml:"include_types"`
+	// Transaction types which are not indexed, applied after IncludeTypes
+	ExcludeTypes []string `toml:"exclude_types"`
+	// Source of the P-chain blocks, "indexer" (the index API of the node, the
+	// default) or "rpc" (platform.getHeight and platform.getBlockByHeight) for
+	// nodes without the index API. Not used by the X-chain indexer.
+	Source string `toml:"source"`
+}
+
+// Weights of the validators at the start of each reward epoch of the voting
+// contract, recorded by the P-chain indexer
+type ValidatorWeightsConfig struct {
+	Enabled bool `toml:"enabled" envconfig:"VALIDATOR_WEIGHTS_ENABLED"`
+	// First recorded epoch
+	First int64 `toml:"first" envconfig:"VALIDATOR_WEIGHTS_EPOCH_FIRST"`
 }
 
 type CronjobConfig struct {
@@ -37,17 +181,67 @@ type CronjobConfig struct {
 	Timeout   time.Duration `toml:"timeout"`
 	BatchSize int64         `toml:"batch_size"`
 	Delay     time.Duration `toml:"delay"`
+	// "@every <duration>", "@epoch[+<duration>]" or a cron expression, every
+	// Timeout if empty
+	Schedule string `toml:"schedule"`
+	// Maximal random delay added to each call
+	Jitter time.Duration `toml:"jitter"`
 }
 
 type MirrorConfig struct {
 	CronjobConfig
 	config.EpochConfig
+	// Time after the end of an epoch before its stakes are mirrored, for the
+	// finalization of the votes and the indexing lag, one epoch period if zero
+	Buffer time.Duration `toml:"buffer" envconfig:"MIRROR_BUFFER"`
 }
 
 type VotingConfig struct {
 	CronjobConfig
 	config.EpochConfig
 	GasLimit uint64 `toml:"gas_limit" envconfig:"VOTING_GAS_LIMIT"`
+	// Sign the computed merkle roots with the private key, see
+	// staking.SignMerkleRoot
+	SignMerkleRoots bool `toml:"sign_merkle_roots" envconfig:"VOTING_SIGN_MERKLE_ROOTS"`
+}
+
+// Verification of the voting and mirroring data of the epochs mirrored by the
+// mirroring cronjob (of this or another indexer instance sharing the database)
+type AnomaliesConfig struct {
+	CronjobConfig
+	config.EpochConfig
+}
+
+// Reconciliation of the mirroring attempts with the stakes mirrored on the
+// mirroring contract
+type ReconciliationConfig struct {
+	CronjobConfig
+	config.EpochConfig
+}
+
+// Indexing of the event logs of the mirroring and voting contracts. BatchSize is
+// the number of C-chain blocks queried by a single request.
+type ContractEventsConfig struct {
+	CronjobConfig
+	// First indexed C-chain block, e.g. the block of the deployment of the contracts
+	StartBlock uint64 `toml:"start_block" envconfig:"CONTRACT_EVENTS_START_BLOCK"`
+}
+
+// Monitoring of the native token balance of the address of the configured
+// private key, which pays for the voting and mirroring transactions
+type BalanceConfig struct {
+	CronjobConfig
+	// A warning is logged if the balance is below this value (in FLR), no warning if zero
+	WarningThreshold float64 `toml:"warning_threshold" envconfig:"BALANCE_WARNING_THRESHOLD"`
+}
+
+// Monitoring of the difference between the local clock and the clock of the
+// node, epochs of the cronjobs are selected by the local clock
+type ClockDriftConfig struct {
+	CronjobConfig
+	// A warning is logged if the local clock differs from the clock of the node
+	// by more than this value, no warning if zero
+	WarningThreshold time.Duration `toml:"warning_threshold" envconfig:"CLOCK_DRIFT_WARNING_THRESHOLD"`
 }
 
 type UptimeConfig struct {
@@ -58,11 +252,49 @@ type UptimeConfig struct {
 	EnableVoting                   bool            `toml:"enable_voting"`
 	UptimeThreshold                float64         `toml:"uptime_threshold"`
 	DeleteOldUptimesEpochThreshold int64           `toml:"delete_old_uptimes_epoch_threshold"`
+	// Schedule of the uptime voting, Schedule applies to the uptime cronjob
+	VotingSchedule string `toml:"voting_schedule"`
 }
 
 type ContractAddresses struct {
 	config.ContractAddresses
 	Mirroring common.Address `toml:"mirroring" envconfig:"MIRRORING_CONTRACT_ADDRESS"`
+	// Mirroring contracts with their activation epochs, replaces Mirroring when
+	// several contract versions are live, e.g. during an upgrade
+	MirroringContracts []MirroringContract `toml:"mirroring_contracts" ignored:"true"`
+}
+
+type MirroringContract struct {
+	Address common.Address `toml:"address"`
+	// First epoch whose stakes are mirrored to the contract
+	FirstEpoch int64 `toml:"first_epoch"`
+}
+
+// The mirroring contracts sorted by activation epoch, Mirroring activated at
+// epoch 0 if MirroringContracts is empty
+func (c ContractAddresses) MirroringContractsByEpoch() []MirroringContract {
+	if len(c.MirroringContracts) == 0 {
+		return []MirroringContract{{Address: c.Mirroring}}
+	}
+	contracts := make([]MirroringContract, len(c.MirroringContracts))
+	copy(contracts, c.MirroringContracts)
+	sort.SliceStable(contracts, func(i, j int) bool { return contracts[i].FirstEpoch < contracts[j].FirstEpoch })
+	return contracts
+}
+
+// Address of the mirroring contract of the stakes of the epoch, i.e., the
+// contract with the latest activation epoch not after it, and false if no
+// contract is active in the epoch
+func (c ContractAddresses) MirroringContractFor(epoch int64) (common.Address, bool) {
+	var address common.Address
+	found := false
+	for _, contract := range c.MirroringContractsByEpoch() {
+		if contract.FirstEpoch > epoch {
+			break
+		}
+		address, found = contract.Address, true
+	}
+	return address, found
 }
 
 func newConfig() *Config {
@@ -79,6 +311,44 @@ func newConfig() *Config {
 			BatchSize:  10,
 			StartIndex: 0,
 		},
+		VotingCronjob: VotingConfig{
+			CronjobConfig: CronjobConfig{
+				Jitter: 10 * time.Second,
+			},
+		},
+		Mirror: MirrorConfig{
+			CronjobConfig: CronjobConfig{
+				Schedule: "@epoch",
+			},
+		},
+		Anomalies: AnomaliesConfig{
+			CronjobConfig: CronjobConfig{
+				Schedule: "@epoch+10m",
+			},
+		},
+		Reconciliation: ReconciliationConfig{
+			CronjobConfig: CronjobConfig{
+				Schedule: "@epoch+20m",
+			},
+		},
+		ContractEvents: ContractEventsConfig{
+			CronjobConfig: CronjobConfig{
+				Timeout:   30 * time.Second,
+				BatchSize: 1000,
+			},
+		},
+		Balance: BalanceConfig{
+			CronjobConfig: CronjobConfig{
+				Timeout: 5 * time.Minute,
+			},
+		},
+		ClockDrift: ClockDriftConfig{
+			CronjobConfig: CronjobConfig{
+				Enabled: true,
+				Timeout: 10 * time.Minute,
+			},
+			WarningThreshold: 5 * time.Second,
+		},
 		UptimeCronjob: UptimeConfig{
 			CronjobConfig: CronjobConfig{
 				Enabled: false,
@@ -86,7 +356,44 @@ func newConfig() *Config {
 			},
 		},
 		Chain: config.ChainConfig{
-			NodeURL: "http://localhost:9650/",
+			NodeURL:                 "http://localhost:9650/",
+			EthRPCHealthCheckPeriod: 30 * time.Second,
+		},
+		FeatureFlags: FeatureFlagsConfig{
+			RefreshPeriod: 30 * time.Second,
+		},
+		CronjobRuns: CronjobRunsConfig{
+			Retention: 30 * 24 * time.Hour,
+		},
+		Tombstones: TombstonesConfig{
+			Retention: 90 * 24 * time.Hour,
+		},
+		ChangeFeed: ChangeFeedConfig{
+			Retention: 30 * 24 * time.Hour,
+		},
+		Pruning: PruningConfig{
+			CronjobConfig: CronjobConfig{
+				Enabled: true,
+				Timeout: time.Hour,
+			},
+		},
+		Stats: StatsConfig{
+			CronjobConfig: CronjobConfig{
+				Timeout:   time.Hour,
+				BatchSize: 30,
+			},
+		},
+		KafkaSink: KafkaSinkConfig{
+			Timeout:   10 * time.Second,
+			BatchSize: 100,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:        10 * time.Second,
+			BatchSize:      100,
+			MaxAttempts:    10,
+			RetryDelay:     30 * time.Second,
+			MaxRetryDelay:  time.Hour,
+			RequestTimeout: 10 * time.Second,
 		},
 	}
 }
@@ -99,9 +406,13 @@ func (c Config) ChainConfig() config.ChainConfig {
 	return c.Chain
 }
 
-func BuildConfig(cfgFileName string) (*Config, error) {
+// Returns the config of each network configured in [networks.<name>] sections,
+// sorted by network name, or the config itself if there are no such sections.
+// Environment variables override the shared settings, network sections
+// override both.
+func BuildNetworkConfigs(cfgFileName string) ([]*Config, error) {
 	cfg := newConfig()
-	err := config.ParseConfigFile(cfg, cfgFileName, false)
+	md, err := config.DecodeConfigFile(cfg, cfgFileName, false)
 	if err != nil {
 		return nil, err
 	}
@@ -109,5 +420,41 @@ func BuildConfig(cfgFileName string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return cfg, nil
+	if len(cfg.Networks) == 0 {
+		return []*Config{cfg}, nil
+	}
+
+	names := make([]string, 0, len(cfg.Networks))
+	for name := range cfg.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cfgs := make([]*Config, len(names))
+	databases := make(map[string]string)
+	for i, name := range names {
+		if !networkNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid network name %q, use lowercase letters, digits and underscores", name)
+		}
+		networkCfg := *cfg
+		networkCfg.Networks = nil
+		networkCfg.Network = name
+		if err := md.PrimitiveDecode(cfg.Networks[name], &networkCfg); err != nil {
+			return nil, fmt.Errorf("error parsing config of network %s: %w", name, err)
+		}
+		if len(networkCfg.Chain.ChainAddressHRP) == 0 {
+			return nil, fmt.Errorf("address_hrp of network %s must be specified", name)
+		}
+		// The logger is shared by all networks of the process
+		if networkCfg.Logger != cfg.Logger {
+			return nil, fmt.Errorf("logger of network %s cannot be set, use the top-level logger settings", name)
+		}
+		db := fmt.Sprintf("%s:%d/%s/%s", networkCfg.DB.Host, networkCfg.DB.Port, networkCfg.DB.Database, networkCfg.DB.TablePrefix)
+		if other, ok := databases[db]; ok {
+			return nil, fmt.Errorf("networks %s and %s use the same database and table prefix", other, name)
+		}
+		databases[db] = name
+		cfgs[i] = &networkCfg
+	}
+	return cfgs, nil
 }
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Replaces the secrets in the output of the config show command
+const RedactedValue = "REDACTED"
+
+// Returns a copy of the config with the passwords, keys and secrets replaced by
+// RedactedValue, also in the DSNs of the read replicas and in URLs. Empty
+// secrets are kept empty, so that unset secrets are visible.
+func (c Config) Redacted() *Config {
+	c.Networks = nil
+
+	c.DB.Password = redact(c.DB.Password)
+	replicas := make([]string, len(c.DB.ReadReplicas))
+	for i, dsn := range c.DB.ReadReplicas {
+		replicas[i] = redactDSN(dsn)
+	}
+	c.DB.ReadReplicas = replicas
+
+	c.Chain.ApiKey = redact(c.Chain.ApiKey)
+	c.Chain.PrivateKey = redact(c.Chain.PrivateKey)
+	c.Chain.KeystorePassphrase = redact(c.Chain.KeystorePassphrase)
+	c.Chain.NodeURL = redactURL(c.Chain.NodeURL)
+	c.Chain.EthRPCURL = redactURL(c.Chain.EthRPCURL)
+
+	c.Events.URL = redactURL(c.Events.URL)
+
+	hooks := make([]WebhookConfig, len(c.Webhooks.Hooks))
+	for i, hook := range c.Webhooks.Hooks {
+		hook.URL = redactURL(hook.URL)
+		hook.Secret = redact(hook.Secret)
+		hooks[i] = hook
+	}
+	c.Webhooks.Hooks = hooks
+	return &c
+}
+
+func redact(secret string) string {
+	if len(secret) == 0 {
+		return ""
+	}
+	return RedactedValue
+}
+
+// Redacts the password of a MySQL DSN, e.g. "user:password@tcp(host:3306)/db"
+func redactDSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.Index(dsn[:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + RedactedValue + dsn[at:]
+}
+
+// Redacts the password in the user info of the URL, URLs which cannot be
+// parsed are redacted completely since they may contain anything
+func redactURL(rawURL string) string {
+	if len(rawURL) == 0 {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return RedactedValue
+	}
+	if _, ok := u.User.Password(); !ok {
+		return rawURL
+	}
+	u.User = url.UserPassword(u.User.Username(), RedactedValue)
+	return u.String()
+}
+
+// Setting of the config overridden by an environment variable
+type EnvOverride struct {
+	// TOML path of the setting, e.g. "db.host"
+	Setting string
+	// Name of the environment variable
+	Variable string
+}
+
+// Returns the settings of the config which are read from the environment
+// variables set in the process environment, in the order of the config fields.
+// Variables are looked up like envconfig does in ReadEnv, i.e., by the
+// prefixed field name first and then by the envconfig tag.
+func EnvOverrides() []EnvOverride {
+	var overrides []EnvOverride
+	collectEnvOverrides(reflect.TypeOf(Config{}), "", "", &overrides)
+	return overrides
+}
+
+func collectEnvOverrides(t reflect.Type, settingPrefix string, envPrefix string, overrides *[]EnvOverride) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("ignored") == "true" {
+			continue
+		}
+		setting := settingPrefix
+		if !field.Anonymous {
+			setting += strings.Split(field.Tag.Get("toml"), ",")[0]
+		}
+		alt := field.Tag.Get("envconfig")
+		key := field.Name
+		if len(alt) > 0 {
+			key = alt
+		}
+		if len(envPrefix) > 0 {
+			key = envPrefix + "_" + key
+		}
+		key = strings.ToUpper(key)
+
+		if field.Type.Kind() == reflect.Struct && !isTextValue(field.Type) {
+			innerSetting, innerEnv := setting+".", key
+			if field.Anonymous {
+				innerSetting, innerEnv = settingPrefix, envPrefix
+			}
+			collectEnvOverrides(field.Type, innerSetting, innerEnv, overrides)
+			continue
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			*overrides = append(*overrides, EnvOverride{Setting: setting, Variable: key})
+		} else if _, ok := os.LookupEnv(alt); ok && len(alt) > 0 {
+			*overrides = append(*overrides, EnvOverride{Setting: setting, Variable: alt})
+		}
+	}
+}
+
+// Struct types decoded from a single value, e.g. timestamps
+func isTextValue(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(reflect.TypeOf((*interface{ UnmarshalText([]byte) error })(nil)).Elem())
+}
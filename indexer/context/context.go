@@ -1,10 +1,14 @@
 package context
 
 import (
-	"flag"
+	sysContext "context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/events"
+	"flare-indexer/indexer/featureflags"
+	"flare-indexer/utils/chain"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,9 +17,18 @@ type IndexerContext interface {
 	Config() *config.Config
 	DB() *gorm.DB
 	Flags() *IndexerFlags
+	FeatureFlags() *featureflags.Flags
+	Events() *events.Publisher
+	// Eth RPC client shared by the cronjobs of the network
+	EthClient() *chain.ManagedEthClient
+	// Container parser for the node version of the network
+	Parser() *chain.Parser
 }
 
 type IndexerFlags struct {
+	// Subcommand of the binary, one of the Command constants
+	Command string
+
 	ConfigFileName string
 
 	// Set start epoch for voting cronjob to this value, overrides config and database value,
@@ -25,31 +38,101 @@ type IndexerFlags struct {
 	// Set start epoch for mirroring cronjob to this value, overrides config and database value,
 	// valid value is > 0
 	ResetMirrorCronjob int64
+
+	// Epoch of the mirror, vote, verify, export and compare commands
+	Epoch int64
+
+	// The mirror command either previews the stakes of the epoch or mirrors them
+	MirrorPreview bool
+	MirrorExecute bool
+
+	// Sign the transactions of the mirror command on a Ledger with the key at
+	// this derivation path instead of the configured private key
+	Ledger     bool
+	LedgerPath string
+
+	// Chain of the backfill and reindex commands, ChainP or ChainX
+	Chain string
+
+	// First container index indexed again by the reindex command and the reason
+	// recorded with the tombstones of the deleted rows
+	FromIndex     uint64
+	ReindexReason string
+
+	// Stakers exported by the export command, ExportValidators or
+	// ExportDelegators, and the output format, ExportCSV or ExportJSONL
+	ExportType   string
+	ExportFormat string
+
+	// Base URL of the services of the indexer compared by the compare command
+	// (including the base path) and the API key sent to it, if not empty
+	CompareURL    string
+	CompareAPIKey string
+
+	// Generated data of the devdata command, the epochs start at DevDataEpochStart
+	// (if zero, the last epoch ends at the current hour)
+	DevDataEpochs      int
+	DevDataEpochStart  time.Time
+	DevDataEpochPeriod time.Duration
+	DevDataValidators  int
+	DevDataDelegators  int
+	DevDataSeed        int64
+
+	// Action of the config command, ConfigShow or ConfigCheck
+	ConfigAction string
 }
 
 type indexerContext struct {
-	config *config.Config
-	db     *gorm.DB
-	flags  *IndexerFlags
+	config       *config.Config
+	db           *gorm.DB
+	flags        *IndexerFlags
+	featureFlags *featureflags.Flags
+	events       *events.Publisher
+	ethClient    *chain.ManagedEthClient
+	parser       *chain.Parser
 }
 
-func BuildContext() (IndexerContext, error) {
-	flags := parseIndexerFlags()
-	cfg, err := config.BuildConfig(flags.ConfigFileName)
+// Builds the context of each network indexed by this process, flags are
+// parsed by ParseIndexerFlags
+func BuildContexts(flags *IndexerFlags) ([]IndexerContext, error) {
+	cfgs, err := config.BuildNetworkConfigs(flags.ConfigFileName)
 	if err != nil {
 		return nil, err
 	}
-	globalConfig.GlobalConfigCallback.Call(cfg)
 
+	ctxs := make([]IndexerContext, len(cfgs))
+	for i, cfg := range cfgs {
+		globalConfig.GlobalConfigCallback.Call(cfg)
+		ctxs[i], err = buildContext(cfg, flags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ctxs, nil
+}
+
+func buildContext(cfg *config.Config, flags *IndexerFlags) (IndexerContext, error) {
 	db, err := database.ConnectAndInitialize(&cfg.DB)
 	if err != nil {
 		return nil, err
 	}
 
+	featureFlags := featureflags.New(db)
+	featureFlags.Start(cfg.FeatureFlags.RefreshPeriod)
+
+	publisher, err := events.New(&cfg.Events, cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+
 	return &indexerContext{
-		config: cfg,
-		db:     db,
-		flags:  flags,
+		config:       cfg,
+		db:           db,
+		flags:        flags,
+		featureFlags: featureFlags,
+		events:       publisher,
+		ethClient:    newEthClient(&cfg.Chain),
+		parser:       chain.NewParser(),
 	}, nil
 }
 
@@ -59,15 +142,18 @@ func (c *indexerContext) DB() *gorm.DB { return c.db }
 
 func (c *indexerContext) Flags() *IndexerFlags { return c.flags }
 
-func parseIndexerFlags() *IndexerFlags {
-	cfgFlag := flag.String("config", globalConfig.CONFIG_FILE, "Configuration file (toml format)")
-	resetVotingFlag := flag.Int64("reset-voting", 0, "Set start epoch for voting cronjob to this value, overrides config and database value, valid values are > 0")
-	resetMirrorFlag := flag.Int64("reset-mirroring", 0, "Set start epoch for mirroring cronjob to this value, overrides config and database value, valid values are > 0")
-	flag.Parse()
+func (c *indexerContext) FeatureFlags() *featureflags.Flags { return c.featureFlags }
 
-	return &IndexerFlags{
-		ConfigFileName:     *cfgFlag,
-		ResetVotingCronjob: *resetVotingFlag,
-		ResetMirrorCronjob: *resetMirrorFlag,
-	}
+func (c *indexerContext) Events() *events.Publisher { return c.events }
+
+func (c *indexerContext) EthClient() *chain.ManagedEthClient { return c.ethClient }
+
+func (c *indexerContext) Parser() *chain.Parser { return c.parser }
+
+// The client connects on the first call, the health check only checks an
+// existing connection and runs for the lifetime of the process
+func newEthClient(cfg *globalConfig.ChainConfig) *chain.ManagedEthClient {
+	client := chain.NewManagedEthClient(cfg.EthRPCURL, cfg.GetEthRPCTimeout())
+	client.StartHealthCheck(sysContext.Background(), cfg.EthRPCHealthCheckPeriod)
+	return client
 }
@@ -0,0 +1,215 @@
+package context
+
+import (
+	"flag"
+	globalConfig "flare-indexer/config"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Subcommands of the indexer binary, all of them load the config given by the
+// --config flag
+const (
+	// Runs the indexers and cronjobs, the default command
+	CommandRun = "run"
+	// Migrates the database and exits
+	CommandMigrate = "migrate"
+	// Indexes a chain up to the last accepted container and exits
+	CommandBackfill = "backfill"
+	// Deletes the indexed data of a chain from a container index on, so it is
+	// indexed again by the next run or backfill
+	CommandReindex = "reindex"
+	// Mirrors the stakes of an epoch once
+	CommandMirror = "mirror"
+	// Submits the vote of an epoch once
+	CommandVote = "vote"
+	// Checks the voting and mirroring data of an epoch for anomalies
+	CommandVerify = "verify"
+	// Writes the stakers of an epoch to stdout
+	CommandExport = "export"
+	// Compares the stakers of an epoch with the services of another indexer
+	CommandCompare = "compare"
+	// Resolves the missing addresses and amounts of indexed P-chain inputs
+	CommandRepairInputs = "repair-inputs"
+	// Populates an empty database with synthetic data for local development
+	CommandDevData = "devdata"
+	// Prints or checks the resolved config, followed by ConfigShow or ConfigCheck
+	CommandConfig = "config"
+)
+
+const (
+	ChainP = "p"
+	ChainX = "x"
+
+	ExportValidators = "validators"
+	ExportDelegators = "delegators"
+
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+
+	ConfigShow  = "show"
+	ConfigCheck = "check"
+)
+
+var commandDescriptions = []struct {
+	name        string
+	description string
+}{
+	{CommandRun, "run the indexers and cronjobs (default)"},
+	{CommandMigrate, "migrate the database and exit"},
+	{CommandBackfill, "index a chain up to the last accepted container and exit"},
+	{CommandReindex, "delete the indexed data of a chain from a container index on to index it again"},
+	{CommandMirror, "preview or mirror the stakes of an epoch once"},
+	{CommandVote, "submit the vote of an epoch once"},
+	{CommandVerify, "check the voting and mirroring data of an epoch for anomalies"},
+	{CommandExport, "write the validators or delegators of an epoch to stdout"},
+	{CommandCompare, "compare the stakers of an epoch with another indexer and print the differences"},
+	{CommandRepairInputs, "resolve the missing addresses and amounts of indexed P-chain inputs"},
+	{CommandDevData, "populate an empty database with synthetic data for local development"},
+	{CommandConfig + " " + ConfigShow, "print the resolved config with the secrets redacted"},
+	{CommandConfig + " " + ConfigCheck, "check the config without connecting to the node or the database"},
+}
+
+// Parses the command line arguments (without the program name). The first
+// argument selects the command, arguments starting with flags run the indexer
+// as before the commands were introduced, e.g. "indexer --config config.toml".
+func ParseIndexerFlags(args []string) (*IndexerFlags, error) {
+	return parseIndexerFlags(args, os.Stderr)
+}
+
+func parseIndexerFlags(args []string, output io.Writer) (*IndexerFlags, error) {
+	flags := &IndexerFlags{Command: CommandRun}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		flags.Command = args[0]
+		args = args[1:]
+	}
+	if flags.Command == CommandConfig && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		flags.ConfigAction = args[0]
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet(flags.Command, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() { printUsage(fs, output) }
+	fs.StringVar(&flags.ConfigFileName, "config", globalConfig.CONFIG_FILE, "Configuration file (toml format)")
+
+	switch flags.Command {
+	case CommandRun:
+		fs.Int64Var(&flags.ResetVotingCronjob, "reset-voting", 0, "Set start epoch for voting cronjob to this value, overrides config and database value, valid values are > 0")
+		fs.Int64Var(&flags.ResetMirrorCronjob, "reset-mirroring", 0, "Set start epoch for mirroring cronjob to this value, overrides config and database value, valid values are > 0")
+	case CommandMigrate:
+	case CommandBackfill:
+		fs.StringVar(&flags.Chain, "chain", ChainP, "Chain to index, p or x")
+	case CommandReindex:
+		fs.StringVar(&flags.Chain, "chain", ChainP, "Chain to index again, p or x")
+		fs.Uint64Var(&flags.FromIndex, "from", 0, "First container index to index again")
+		fs.StringVar(&flags.ReindexReason, "reason", "", "Reason recorded with the tombstones of the deleted data")
+	case CommandMirror:
+		fs.Int64Var(&flags.Epoch, "epoch", 0, "Epoch to mirror, valid values are > 0")
+		fs.BoolVar(&flags.MirrorPreview, "preview", false, "Print the stakes with their merkle proofs and the estimated gas without sending anything")
+		fs.BoolVar(&flags.MirrorExecute, "execute", false, "Mirror the stakes")
+		fs.BoolVar(&flags.Ledger, "ledger", false, "Sign the transactions on a Ledger")
+		fs.StringVar(&flags.LedgerPath, "ledger-path", "m/44'/60'/0'/0/0", "Derivation path of the Ledger key")
+	case CommandVote:
+		fs.Int64Var(&flags.Epoch, "epoch", 0, "Epoch to vote for, valid values are > 0")
+	case CommandVerify:
+		fs.Int64Var(&flags.Epoch, "epoch", 0, "Epoch to verify, valid values are > 0")
+	case CommandExport:
+		fs.Int64Var(&flags.Epoch, "epoch", 0, "Epoch of the exported stakers, valid values are > 0")
+		fs.StringVar(&flags.ExportType, "type", ExportValidators, "Exported stakers, validators or delegators")
+		fs.StringVar(&flags.ExportFormat, "format", ExportJSONL, "Output format, jsonl or csv")
+	case CommandCompare:
+		fs.Int64Var(&flags.Epoch, "epoch", 0, "Epoch of the compared stakers, valid values are > 0")
+		fs.StringVar(&flags.CompareURL, "url", "", "Base URL of the services of the other indexer, e.g. https://indexer.example.com/api")
+		fs.StringVar(&flags.CompareAPIKey, "api-key", "", "API key sent to the other indexer in the X-API-Key header")
+	case CommandRepairInputs:
+	case CommandDevData:
+		fs.IntVar(&flags.DevDataEpochs, "epochs", 10, "Number of epochs with starting stakes")
+		fs.Func("epoch-start", "Start of the first epoch (RFC 3339), by default the last epoch ends at the current hour", func(value string) (err error) {
+			flags.DevDataEpochStart, err = time.Parse(time.RFC3339, value)
+			return err
+		})
+		fs.DurationVar(&flags.DevDataEpochPeriod, "epoch-period", 24*time.Hour, "Duration of an epoch")
+		fs.IntVar(&flags.DevDataValidators, "validators", 20, "Number of validators")
+		fs.IntVar(&flags.DevDataDelegators, "delegators", 5, "Number of delegations of each validator")
+		fs.Int64Var(&flags.DevDataSeed, "seed", 1, "Seed of the random data, equal seeds generate equal data")
+	case CommandConfig:
+	default:
+		printUsage(nil, output)
+		return nil, fmt.Errorf("unknown command %q", flags.Command)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments for command %s: %s", flags.Command, strings.Join(fs.Args(), " "))
+	}
+	if err := flags.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", flags.Command, err)
+	}
+	return flags, nil
+}
+
+func (f *IndexerFlags) check() error {
+	switch f.Command {
+	case CommandBackfill, CommandReindex:
+		if f.Chain != ChainP && f.Chain != ChainX {
+			return fmt.Errorf("--chain must be p or x, got %q", f.Chain)
+		}
+	case CommandMirror:
+		if f.Epoch <= 0 {
+			return fmt.Errorf("--epoch must be positive")
+		}
+		if f.MirrorPreview == f.MirrorExecute {
+			return fmt.Errorf("either --preview or --execute must be set")
+		}
+	case CommandVote, CommandVerify:
+		if f.Epoch <= 0 {
+			return fmt.Errorf("--epoch must be positive")
+		}
+	case CommandExport:
+		if f.Epoch <= 0 {
+			return fmt.Errorf("--epoch must be positive")
+		}
+		if f.ExportType != ExportValidators && f.ExportType != ExportDelegators {
+			return fmt.Errorf("--type must be validators or delegators, got %q", f.ExportType)
+		}
+		if f.ExportFormat != ExportCSV && f.ExportFormat != ExportJSONL {
+			return fmt.Errorf("--format must be jsonl or csv, got %q", f.ExportFormat)
+		}
+	case CommandCompare:
+		if f.Epoch <= 0 {
+			return fmt.Errorf("--epoch must be positive")
+		}
+		u, err := url.Parse(f.CompareURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("--url must be an http or https URL, got %q", f.CompareURL)
+		}
+	case CommandDevData:
+		if f.DevDataEpochs <= 0 || f.DevDataEpochPeriod <= 0 || f.DevDataValidators <= 0 || f.DevDataDelegators < 0 {
+			return fmt.Errorf("--epochs, --epoch-period and --validators must be positive, --delegators must not be negative")
+		}
+	case CommandConfig:
+		if f.ConfigAction != ConfigShow && f.ConfigAction != ConfigCheck {
+			return fmt.Errorf("expected %s or %s, got %q", ConfigShow, ConfigCheck, f.ConfigAction)
+		}
+	}
+	return nil
+}
+
+// Prints the commands and the flags of fs, if not nil
+func printUsage(fs *flag.FlagSet, output io.Writer) {
+	fmt.Fprintf(output, "Usage: indexer [command] [flags]\n\nCommands:\n")
+	for _, c := range commandDescriptions {
+		fmt.Fprintf(output, "  %-14s %s\n", c.name, c.description)
+	}
+	if fs != nil {
+		fmt.Fprintf(output, "\nFlags of %s:\n", fs.Name())
+		fs.PrintDefaults()
+	}
+}
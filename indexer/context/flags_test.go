@@ -0,0 +1,108 @@
+package context
+
+import (
+	"flag"
+	globalConfig "flare-indexer/config"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIndexerFlags(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		expected IndexerFlags
+	}{
+		{
+			args:     nil,
+			expected: IndexerFlags{Command: CommandRun, ConfigFileName: globalConfig.CONFIG_FILE},
+		},
+		{
+			// Flags without a command run the indexer
+			args:     []string{"--config", "local.toml", "--reset-voting", "5"},
+			expected: IndexerFlags{Command: CommandRun, ConfigFileName: "local.toml", ResetVotingCronjob: 5},
+		},
+		{
+			args:     []string{"migrate", "--config", "local.toml"},
+			expected: IndexerFlags{Command: CommandMigrate, ConfigFileName: "local.toml"},
+		},
+		{
+			args: []string{"reindex", "--chain", "x", "--from", "100", "--reason", "missing outputs"},
+			expected: IndexerFlags{
+				Command: CommandReindex, ConfigFileName: globalConfig.CONFIG_FILE,
+				Chain: ChainX, FromIndex: 100, ReindexReason: "missing outputs",
+			},
+		},
+		{
+			args: []string{"mirror", "--epoch", "12", "--execute", "--ledger"},
+			expected: IndexerFlags{
+				Command: CommandMirror, ConfigFileName: globalConfig.CONFIG_FILE,
+				Epoch: 12, MirrorExecute: true, Ledger: true, LedgerPath: "m/44'/60'/0'/0/0",
+			},
+		},
+		{
+			args: []string{"export", "--epoch", "12", "--format", "csv"},
+			expected: IndexerFlags{
+				Command: CommandExport, ConfigFileName: globalConfig.CONFIG_FILE,
+				Epoch: 12, ExportType: ExportValidators, ExportFormat: ExportCSV,
+			},
+		},
+		{
+			args: []string{"compare", "--epoch", "12", "--url", "https://indexer.example.com/api", "--api-key", "secret"},
+			expected: IndexerFlags{
+				Command: CommandCompare, ConfigFileName: globalConfig.CONFIG_FILE,
+				Epoch: 12, CompareURL: "https://indexer.example.com/api", CompareAPIKey: "secret",
+			},
+		},
+		{
+			args: []string{"devdata", "--epochs", "3", "--epoch-start", "2023-01-01T00:00:00Z", "--epoch-period", "90m"},
+			expected: IndexerFlags{
+				Command: CommandDevData, ConfigFileName: globalConfig.CONFIG_FILE,
+				DevDataEpochs: 3, DevDataEpochStart: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				DevDataEpochPeriod: 90 * time.Minute, DevDataValidators: 20, DevDataDelegators: 5, DevDataSeed: 1,
+			},
+		},
+		{
+			args:     []string{"repair-inputs"},
+			expected: IndexerFlags{Command: CommandRepairInputs, ConfigFileName: globalConfig.CONFIG_FILE},
+		},
+		{
+			args:     []string{"config", "show", "--config", "flare.toml"},
+			expected: IndexerFlags{Command: CommandConfig, ConfigFileName: "flare.toml", ConfigAction: ConfigShow},
+		},
+	} {
+		flags, err := parseIndexerFlags(test.args, io.Discard)
+		require.NoError(t, err, test.args)
+		require.Equal(t, test.expected, *flags, test.args)
+	}
+}
+
+func TestParseIndexerFlagsErrors(t *testing.T) {
+	for _, test := range []struct {
+		args  []string
+		error string
+	}{
+		{[]string{"start"}, `unknown command "start"`},
+		{[]string{"vote"}, "vote: --epoch must be positive"},
+		{[]string{"mirror", "--epoch", "1"}, "mirror: either --preview or --execute must be set"},
+		{[]string{"mirror", "--epoch", "1", "--preview", "--execute"}, "mirror: either --preview or --execute must be set"},
+		{[]string{"backfill", "--chain", "c"}, `backfill: --chain must be p or x, got "c"`},
+		{[]string{"export", "--epoch", "1", "--type", "stakers"}, `export: --type must be validators or delegators, got "stakers"`},
+		{[]string{"compare", "--url", "https://indexer.example.com"}, "compare: --epoch must be positive"},
+		{[]string{"compare", "--epoch", "1"}, `compare: --url must be an http or https URL, got ""`},
+		{[]string{"compare", "--epoch", "1", "--url", "indexer.example.com"}, `compare: --url must be an http or https URL, got "indexer.example.com"`},
+		// Flags of other commands are rejected
+		{[]string{"migrate", "--epoch", "1"}, "flag provided but not defined: -epoch"},
+		{[]string{"verify", "--epoch", "1", "2"}, "unexpected arguments for command verify: 2"},
+		{[]string{"config"}, `config: expected show or check, got ""`},
+		{[]string{"config", "print"}, `config: expected show or check, got "print"`},
+	} {
+		_, err := parseIndexerFlags(test.args, io.Discard)
+		require.EqualError(t, err, test.error, test.args)
+	}
+
+	_, err := parseIndexerFlags([]string{"mirror", "-h"}, io.Discard)
+	require.ErrorIs(t, err, flag.ErrHelp)
+}
@@ -1,10 +1,13 @@
 package context
 
 import (
+	sysContext "context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/featureflags"
 	"flare-indexer/indexer/migrations"
+	"flare-indexer/utils/chain"
 )
 
 func BuildTestContext(cfg *config.Config) (IndexerContext, error) {
@@ -12,6 +15,7 @@ func BuildTestContext(cfg *config.Config) (IndexerContext, error) {
 	var err error
 
 	ctx.config = cfg
+	ctx.parser = chain.NewParser()
 	globalConfig.GlobalConfigCallback.Call(cfg)
 
 	ctx.db, err = database.ConnectAndInitializeTestDB(&cfg.DB, true)
@@ -19,10 +23,12 @@ func BuildTestContext(cfg *config.Config) (IndexerContext, error) {
 		return nil, err
 	}
 
-	err = migrations.Container.ExecuteAll(ctx.db)
+	err = migrations.Container.ExecuteAll(chain.ContextWithParser(sysContext.Background(), ctx.parser), ctx.db)
 	if err != nil {
 		return nil, err
 	}
+	ctx.featureFlags = featureflags.New(ctx.db)
+	ctx.ethClient = chain.NewManagedEthClient(cfg.Chain.EthRPCURL, cfg.Chain.GetEthRPCTimeout())
 
 	return &ctx, nil
 }
@@ -1,12 +1,13 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/logger"
-	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
 	"flare-indexer/utils/staking"
+	"fmt"
 	"time"
 
 	"github.com/ava-labs/avalanchego/utils/crypto"
@@ -14,37 +15,55 @@ import (
 	"github.com/pkg/errors"
 )
 
-const addressBinderStateName = "address_binder_cronjob"
+const (
+	addressBinderStateName   = "address_binder_cronjob"
+	addressBinderCronjobName = "address_binder"
+)
 
 type addressBinderCronJob struct {
 	epochCronjob
 	db        addressBinderDB
 	contracts addressBinderContracts
-	time      utils.ShiftedTime
+	parser    *chain.Parser
 }
 
 type addressBinderDB interface {
-	FetchState(name string) (database.State, error)
-	UpdateJobState(epoch int64, force bool) error
-	GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error)
-	GetPChainTx(txID string, address string) (*database.PChainTxData, error)
+	FetchState(ctx context.Context, name string) (database.State, error)
+	UpdateJobState(ctx context.Context, epoch int64, force bool) error
+	GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error)
+	GetPChainTx(ctx context.Context, txID string, address string) (*database.PChainTxData, error)
 }
 
 type addressBinderContracts interface {
-	GetMerkleRoot(epoch int64) ([32]byte, error)
-	IsAddressRegistered(address string) (bool, error)
+	GetMerkleRoot(ctx context.Context, epoch int64) ([32]byte, error)
+	IsAddressRegistered(ctx context.Context, address string) (bool, error)
 	RegisterPublicKey(publicKey crypto.PublicKey) error
 	EpochConfig() (time.Time, time.Duration, error)
 }
 
+// The cronjob is created when it is started, see lazyCronjob
 func NewAddressBinderCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
 	cfg := ctx.Config()
 
 	if !cfg.Mirror.Enabled {
 		return &addressBinderCronJob{}, nil
 	}
+	if err := checkContractAddresses(cfg, true); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", addressBinderCronjobName, err)
+	}
+	return newLazyCronjob(addressBinderCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createAddressBinderCronjob(startCtx, ctx)
+	}), nil
+}
+
+// Creates the cronjob, needs the eth RPC
+func createAddressBinderCronjob(startCtx context.Context, ctx indexerctx.IndexerContext) (*addressBinderCronJob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), true); err != nil {
+		return nil, err
+	}
 
-	contracts, err := initAddressBinderJobContracts(cfg)
+	contracts, err := initAddressBinderJobContracts(startCtx, cfg, ctx.EthClient(), newGasAccounting(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -56,27 +75,39 @@ func NewAddressBinderCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
 
 	epochs := staking.NewEpochInfo(&cfg.Mirror.EpochConfig, start, period)
 
+	epochCronjob, err := newEpochCronjob(addressBinderCronjobName, &cfg.Mirror.CronjobConfig, epochs)
+	if err != nil {
+		return nil, err
+	}
+
 	mc := &addressBinderCronJob{
-		epochCronjob: newEpochCronjob(&cfg.Mirror.CronjobConfig, epochs),
+		epochCronjob: epochCronjob,
 		db:           NewAddressBinderDBGorm(ctx.DB()),
 		contracts:    contracts,
+		parser:       ctx.Parser(),
 	}
+	mc.flags = ctx.FeatureFlags()
 
-	err = mc.reset(ctx.Flags().ResetMirrorCronjob)
-
-	return mc, err
+	err = mc.reset(startCtx, ctx.Flags().ResetMirrorCronjob)
+	if err != nil {
+		return nil, err
+	}
+	return mc, nil
 }
 
 func (c *addressBinderCronJob) Name() string {
-	return "address_binder"
+	return addressBinderCronjobName
 }
 
-func (c *addressBinderCronJob) OnStart() error {
+func (c *addressBinderCronJob) OnStart(ctx context.Context) error {
 	return nil
 }
 
-func (c *addressBinderCronJob) Call() error {
-	epochRange, err := c.getEpochRange()
+func (c *addressBinderCronJob) Call(ctx context.Context) error {
+	ctx, cancel := c.withEpochDeadline(ctx, c.now())
+	defer cancel()
+
+	epochRange, err := c.getEpochRange(ctx)
 	if err != nil {
 		if errors.Is(err, errNoEpochsToRegisterAddresses) {
 			logger.Debug("no epochs to register addresses")
@@ -90,14 +121,15 @@ func (c *addressBinderCronJob) Call() error {
 	registeredAddresses := mapset.NewSet[string]()
 	for epoch := epochRange.start; epoch <= epochRange.end; epoch++ {
 		logger.Debug("registering addresses for epoch %d", epoch)
-		if err := c.registerEpoch(registeredAddresses, epoch); err != nil {
+		if err := c.registerEpoch(ctx, registeredAddresses, epoch); err != nil {
 			return err
 		}
+		ReportEpoch(ctx, epoch)
 	}
 
 	logger.Debug("successfully registered addresses for epochs %d-%d", epochRange.start, epochRange.end)
 
-	if err := c.db.UpdateJobState(epochRange.end+1, false); err != nil {
+	if err := c.db.UpdateJobState(ctx, epochRange.end+1, false); err != nil {
 		return err
 	}
 
@@ -106,15 +138,15 @@ func (c *addressBinderCronJob) Call() error {
 
 var errNoEpochsToRegisterAddresses = errors.New("no epochs to register addresses")
 
-func (c *addressBinderCronJob) getEpochRange() (*epochRange, error) {
-	startEpoch, err := c.getStartEpoch()
+func (c *addressBinderCronJob) getEpochRange(ctx context.Context) (*epochRange, error) {
+	startEpoch, err := c.getStartEpoch(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	logger.Debug("start epoch: %d", startEpoch)
 
-	endEpoch, err := c.getEndEpoch(startEpoch)
+	endEpoch, err := c.getEndEpoch(ctx, startEpoch)
 	if err != nil {
 		return nil, err
 	}
@@ -122,8 +154,8 @@ func (c *addressBinderCronJob) getEpochRange() (*epochRange, error) {
 	return c.getTrimmedEpochRange(startEpoch, endEpoch), nil
 }
 
-func (c *addressBinderCronJob) getStartEpoch() (int64, error) {
-	jobState, err := c.db.FetchState(addressBinderStateName)
+func (c *addressBinderCronJob) getStartEpoch(ctx context.Context) (int64, error) {
+	jobState, err := c.db.FetchState(ctx, addressBinderStateName)
 	if err != nil {
 		return 0, err
 	}
@@ -131,12 +163,12 @@ func (c *addressBinderCronJob) getStartEpoch() (int64, error) {
 	return int64(jobState.NextDBIndex), nil
 }
 
-func (c *addressBinderCronJob) getEndEpoch(startEpoch int64) (int64, error) {
-	currEpoch := c.epochs.GetEpochIndex(c.time.Now())
+func (c *addressBinderCronJob) getEndEpoch(ctx context.Context, startEpoch int64) (int64, error) {
+	currEpoch := c.epochs.GetEpochIndex(c.now())
 	logger.Debug("current epoch: %d", currEpoch)
 
 	for epoch := currEpoch; epoch > startEpoch; epoch-- {
-		confirmed, err := c.isEpochConfirmed(epoch)
+		confirmed, err := c.isEpochConfirmed(ctx, epoch)
 		if err != nil {
 			return 0, err
 		}
@@ -149,8 +181,8 @@ func (c *addressBinderCronJob) getEndEpoch(startEpoch int64) (int64, error) {
 	return 0, errNoEpochsToRegisterAddresses
 }
 
-func (c *addressBinderCronJob) isEpochConfirmed(epoch int64) (bool, error) {
-	merkleRoot, err := c.contracts.GetMerkleRoot(epoch)
+func (c *addressBinderCronJob) isEpochConfirmed(ctx context.Context, epoch int64) (bool, error) {
+	merkleRoot, err := c.contracts.GetMerkleRoot(ctx, epoch)
 	if err != nil {
 		return false, errors.Wrap(err, "votingContract.GetMerkleRoot")
 	}
@@ -158,8 +190,8 @@ func (c *addressBinderCronJob) isEpochConfirmed(epoch int64) (bool, error) {
 	return merkleRoot != [32]byte{}, nil
 }
 
-func (c *addressBinderCronJob) registerEpoch(registeredAddresses mapset.Set[string], epoch int64) error {
-	txs, err := c.getEpochTxs(epoch)
+func (c *addressBinderCronJob) registerEpoch(ctx context.Context, registeredAddresses mapset.Set[string], epoch int64) error {
+	txs, err := c.getEpochTxs(ctx, epoch)
 	if err != nil {
 		return err
 	}
@@ -170,17 +202,17 @@ func (c *addressBinderCronJob) registerEpoch(registeredAddresses mapset.Set[stri
 	}
 
 	logger.Info("registering %d txs", len(txs))
-	if err := c.registerTxs(registeredAddresses, txs, epoch); err != nil {
+	if err := c.registerTxs(ctx, registeredAddresses, txs, epoch); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (c *addressBinderCronJob) getEpochTxs(epoch int64) ([]database.PChainTxData, error) {
+func (c *addressBinderCronJob) getEpochTxs(ctx context.Context, epoch int64) ([]database.PChainTxData, error) {
 	startTimestamp, endTimestamp := c.epochs.GetTimeRange(epoch)
 
-	txs, err := c.db.GetPChainTxsForEpoch(startTimestamp, endTimestamp)
+	txs, err := c.db.GetPChainTxsForEpoch(ctx, startTimestamp, endTimestamp)
 	if err != nil {
 		return nil, err
 	}
@@ -188,12 +220,12 @@ func (c *addressBinderCronJob) getEpochTxs(epoch int64) ([]database.PChainTxData
 	return staking.DedupeTxs(txs), nil
 }
 
-func (c *addressBinderCronJob) registerTxs(registeredAddresses mapset.Set[string], txs []database.PChainTxData, epochID int64) error {
+func (c *addressBinderCronJob) registerTxs(ctx context.Context, registeredAddresses mapset.Set[string], txs []database.PChainTxData, epochID int64) error {
 	for _, tx := range txs {
 		if registeredAddresses.Contains(tx.InputAddress) {
 			continue
 		}
-		if err := c.registerAddress(*tx.TxID, tx.InputAddress); err != nil {
+		if err := c.registerAddress(ctx, *tx.TxID, tx.InputAddress); err != nil {
 			// Non-fatal error, continue registering other addresses
 			logger.Error("error registering address: %s", err.Error())
 		}
@@ -203,19 +235,19 @@ func (c *addressBinderCronJob) registerTxs(registeredAddresses mapset.Set[string
 }
 
 // Register address on AddressBinder contract if it is not already registered
-func (c *addressBinderCronJob) registerAddress(txID string, address string) error {
-	registered, err := c.contracts.IsAddressRegistered(address)
+func (c *addressBinderCronJob) registerAddress(ctx context.Context, txID string, address string) error {
+	registered, err := c.contracts.IsAddressRegistered(ctx, address)
 	if err != nil || registered {
 		return err
 	}
-	tx, err := c.db.GetPChainTx(txID, address)
+	tx, err := c.db.GetPChainTx(ctx, txID, address)
 	if err != nil {
 		return err
 	}
 	if tx == nil {
 		return errors.New("tx not found")
 	}
-	publicKeys, err := chain.PublicKeysFromPChainBlock(tx.Bytes)
+	publicKeys, err := chain.PublicKeysFromPChainBlock(c.parser, tx.Bytes)
 	if err != nil {
 		return err
 	}
@@ -230,16 +262,17 @@ func (c *addressBinderCronJob) registerAddress(txID string, address string) erro
 		}
 	}
 	logger.Info("registered address %s on address binder contract", address)
+	ReportItems(ctx, 1)
 	return nil
 }
 
-func (c *addressBinderCronJob) reset(firstEpoch int64) error {
+func (c *addressBinderCronJob) reset(ctx context.Context, firstEpoch int64) error {
 	if firstEpoch <= 0 {
 		return nil
 	}
 
 	logger.Info("Resetting address binder cronjob state to epoch %d", firstEpoch)
-	err := c.db.UpdateJobState(firstEpoch, true)
+	err := c.db.UpdateJobState(ctx, firstEpoch, true)
 	if err != nil {
 		return err
 	}
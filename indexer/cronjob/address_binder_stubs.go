@@ -3,6 +3,7 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
 	"flare-indexer/logger"
@@ -17,7 +18,6 @@ import (
 	"github.com/ava-labs/avalanchego/utils/crypto"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
@@ -30,13 +30,13 @@ func NewAddressBinderDBGorm(db *gorm.DB) addressBinderDB {
 	return addressBinderDBGorm{db: db}
 }
 
-func (m addressBinderDBGorm) FetchState(name string) (database.State, error) {
-	return database.FetchState(m.db, name)
+func (m addressBinderDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, m.db, name)
 }
 
-func (m addressBinderDBGorm) UpdateJobState(epoch int64, force bool) error {
+func (m addressBinderDBGorm) UpdateJobState(ctx context.Context, epoch int64, force bool) error {
 	return m.db.Transaction(func(tx *gorm.DB) error {
-		jobState, err := database.FetchState(tx, addressBinderStateName)
+		jobState, err := database.FetchState(ctx, tx, addressBinderStateName)
 		if err != nil {
 			return errors.Wrap(err, "database.FetchState")
 		}
@@ -47,43 +47,38 @@ func (m addressBinderDBGorm) UpdateJobState(epoch int64, force bool) error {
 		}
 
 		jobState.NextDBIndex = uint64(epoch)
-		return database.UpdateState(tx, &jobState)
+		return database.UpdateState(ctx, tx, &jobState)
 	})
 }
 
-func (m addressBinderDBGorm) GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error) {
-	return database.GetPChainTxsForEpoch(&database.GetPChainTxsForEpochInput{
+func (m addressBinderDBGorm) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+	return database.GetPChainTxsForEpoch(ctx, &database.GetPChainTxsForEpochInput{
 		DB:             m.db,
 		StartTimestamp: start,
 		EndTimestamp:   end,
 	})
 }
 
-func (m addressBinderDBGorm) GetPChainTx(txID string, address string) (*database.PChainTxData, error) {
-	return database.FetchPChainTxData(m.db, txID, address)
+func (m addressBinderDBGorm) GetPChainTx(ctx context.Context, txID string, address string) (*database.PChainTxData, error) {
+	return database.FetchPChainTxData(ctx, m.db, txID, address)
 }
 
 type addressBinderContractsCChain struct {
 	addressBinder *addresses.Binder
 	txOpts        *bind.TransactOpts
 	voting        *voting.Voting
+	gas           *gasAccounting
 }
 
-func initAddressBinderJobContracts(cfg *config.Config) (addressBinderContracts, error) {
-	if cfg.ContractAddresses.Mirroring == (common.Address{}) {
-		return nil, errors.New("mirroring contract address not set")
-	}
-
-	if cfg.ContractAddresses.Voting == (common.Address{}) {
-		return nil, errors.New("voting contract address not set")
-	}
-
-	eth, err := ethclient.Dial(cfg.Chain.EthRPCURL)
-	if err != nil {
+func initAddressBinderJobContracts(ctx context.Context, cfg *config.Config, eth chain.EthClient, gas *gasAccounting) (addressBinderContracts, error) {
+	if err := checkContractAddresses(cfg, true); err != nil {
 		return nil, err
 	}
 
-	mirroringContract, err := mirroring.NewMirroring(cfg.ContractAddresses.Mirroring, eth)
+	// Addresses are registered on the address binder of the latest mirroring
+	// contract, those of earlier epochs were registered before its activation
+	mirroringContracts := cfg.ContractAddresses.MirroringContractsByEpoch()
+	mirroringContract, err := mirroring.NewMirroring(mirroringContracts[len(mirroringContracts)-1].Address, eth)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +88,7 @@ func initAddressBinderJobContracts(cfg *config.Config) (addressBinderContracts,
 		return nil, err
 	}
 
-	addressBinderContract, err := newAddressBinderContract(eth, mirroringContract)
+	addressBinderContract, err := newAddressBinderContract(ctx, eth, mirroringContract)
 	if err != nil {
 		return nil, err
 	}
@@ -112,13 +107,14 @@ func initAddressBinderJobContracts(cfg *config.Config) (addressBinderContracts,
 		addressBinder: addressBinderContract,
 		txOpts:        txOpts,
 		voting:        votingContract,
+		gas:           gas,
 	}, nil
 }
 
 func newAddressBinderContract(
-	eth *ethclient.Client, mirroringContract *mirroring.Mirroring,
+	ctx context.Context, eth chain.EthClient, mirroringContract *mirroring.Mirroring,
 ) (*addresses.Binder, error) {
-	addressBinderAddress, err := mirroringContract.AddressBinder(new(bind.CallOpts))
+	addressBinderAddress, err := mirroringContract.AddressBinder(&bind.CallOpts{Context: ctx})
 	if err != nil {
 		return nil, err
 	}
@@ -126,16 +122,16 @@ func newAddressBinderContract(
 	return addresses.NewBinder(addressBinderAddress, eth)
 }
 
-func (m addressBinderContractsCChain) GetMerkleRoot(epoch int64) ([32]byte, error) {
-	return m.voting.GetMerkleRoot(new(bind.CallOpts), big.NewInt(epoch))
+func (m addressBinderContractsCChain) GetMerkleRoot(ctx context.Context, epoch int64) ([32]byte, error) {
+	return m.voting.GetMerkleRoot(&bind.CallOpts{Context: ctx}, big.NewInt(epoch))
 }
 
-func (m addressBinderContractsCChain) IsAddressRegistered(address string) (bool, error) {
+func (m addressBinderContractsCChain) IsAddressRegistered(ctx context.Context, address string) (bool, error) {
 	addressBytes, err := chain.ParseAddress(address)
 	if err != nil {
 		return false, err
 	}
-	boundAddress, err := m.addressBinder.PAddressToCAddress(new(bind.CallOpts), addressBytes)
+	boundAddress, err := m.addressBinder.PAddressToCAddress(&bind.CallOpts{Context: ctx}, addressBytes)
 	if err != nil {
 		return false, err
 	}
@@ -147,8 +143,13 @@ func (m addressBinderContractsCChain) RegisterPublicKey(publicKey crypto.PublicK
 	if err != nil {
 		return err
 	}
-	_, err = m.addressBinder.RegisterAddresses(m.txOpts, publicKey.Bytes(), publicKey.Address(), ethAddress)
-	return err
+	tx, err := m.addressBinder.RegisterAddresses(m.txOpts, publicKey.Bytes(), publicKey.Address(), ethAddress)
+	if err != nil {
+		return err
+	}
+	// Registrations are not bound to an epoch
+	m.gas.waitAndRecord(addressBinderCronjobName, nil, tx, m.txOpts.From.Hex())
+	return nil
 }
 
 func (m addressBinderContractsCChain) EpochConfig() (start time.Time, period time.Duration, err error) {
@@ -0,0 +1,274 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	anomaliesStateName   = "anomalies_cronjob"
+	anomaliesCronjobName = "anomalies"
+)
+
+// Verifies the voting and mirroring data of each mirrored epoch. Anomalies are
+// stored in the anomalies table, logged, counted by the anomalies_total metric
+// and published as AnomalyDetected events.
+type anomaliesCronjob struct {
+	epochCronjob
+	db      anomaliesDB
+	stakers chain.StakersClient
+	filter  *staking.TxFilter
+	network string
+}
+
+type anomaliesDB interface {
+	FetchState(ctx context.Context, name string) (database.State, error)
+	UpdateState(ctx context.Context, state *database.State) error
+	GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error)
+	FetchSucceededMirroringAttempts(ctx context.Context, txIDs []string) ([]database.MirroringAttempt, error)
+	FetchPChainTxSigners(ctx context.Context, txIDs []string) ([]database.PChainTxSigner, error)
+	CreateAnomalies(ctx context.Context, anomalies []*database.Anomaly) (int64, error)
+}
+
+// The cronjob is created when it is started, see lazyCronjob
+func NewAnomaliesCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.Anomalies.Enabled {
+		return &anomaliesCronjob{}, nil
+	}
+	if err := checkContractAddresses(cfg, false); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", anomaliesCronjobName, err)
+	}
+	return newLazyCronjob(anomaliesCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createAnomaliesCronjob(ctx)
+	}), nil
+}
+
+// Creates the cronjob, needs the eth RPC for the epoch configuration
+func createAnomaliesCronjob(ctx indexerctx.IndexerContext) (*anomaliesCronjob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), false); err != nil {
+		return nil, err
+	}
+	start, period, err := votingEpochConfig(cfg, ctx.EthClient())
+	if err != nil {
+		return nil, err
+	}
+	endpoint := utils.JoinPaths(cfg.Chain.NodeURL, "ext/bc/P"+chain.RPCClientOptions(cfg.Chain.ApiKey))
+	c, err := newAnomaliesCronjob(cfg, anomaliesDBGorm{db: ctx.DB()}, chain.NewAvalancheStakersClient(endpoint, cfg.Chain.GetPlatformTimeout()), start, period)
+	if err != nil {
+		return nil, err
+	}
+	c.flags = ctx.FeatureFlags()
+	c.events = ctx.Events()
+	return c, nil
+}
+
+func newAnomaliesCronjob(
+	cfg *config.Config,
+	db anomaliesDB,
+	stakers chain.StakersClient,
+	start time.Time,
+	period time.Duration,
+) (*anomaliesCronjob, error) {
+	epochs := staking.NewEpochInfo(&cfg.Anomalies.EpochConfig, start, period)
+	epochCronjob, err := newEpochCronjob(anomaliesCronjobName, &cfg.Anomalies.CronjobConfig, epochs)
+	if err != nil {
+		return nil, err
+	}
+	return &anomaliesCronjob{
+		epochCronjob: epochCronjob,
+		db:           db,
+		stakers:      stakers,
+		filter:       staking.NewTxFilter(&cfg.StakingFilter, &cfg.StakingRules),
+		network:      cfg.Network,
+	}, nil
+}
+
+func (c *anomaliesCronjob) Name() string {
+	return anomaliesCronjobName
+}
+
+func (c *anomaliesCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+// Checks the epochs mirrored since the last call
+func (c *anomaliesCronjob) Call(ctx context.Context) error {
+	now := c.now()
+	ctx, cancel := c.withEpochDeadline(ctx, now)
+	defer cancel()
+
+	mirrorState, err := c.db.FetchState(ctx, mirrorStateName)
+	if err != nil {
+		return err
+	}
+	state, err := c.db.FetchState(ctx, anomaliesStateName)
+	if err != nil {
+		return err
+	}
+	lastMirrored := int64(mirrorState.NextDBIndex) - 1
+	if int64(state.NextDBIndex) > lastMirrored {
+		logger.Debug("no mirrored epochs to check")
+		return nil
+	}
+	epochRange := c.getTrimmedEpochRange(int64(state.NextDBIndex), lastMirrored)
+	logger.Debug("checking epochs %d-%d for anomalies", epochRange.start, epochRange.end)
+
+	weights, err := c.stakers.GetCurrentStakerWeights(ctx)
+	if err != nil {
+		return err
+	}
+	for epoch := epochRange.start; epoch <= epochRange.end; epoch++ {
+		anomalies, err := c.checkEpoch(ctx, epoch, weights, now)
+		if err != nil {
+			return err
+		}
+		if _, err := c.db.CreateAnomalies(ctx, anomalies); err != nil {
+			return err
+		}
+		c.alert(anomalies)
+		ReportEpoch(ctx, epoch)
+		ReportItems(ctx, len(anomalies))
+
+		state.NextDBIndex = uint64(epoch + 1)
+		if err := c.db.UpdateState(ctx, &state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Anomalies of the voting data of the epoch and of the mirroring of its stakes.
+// Weights are compared with weights of the current stakers on the node, i.e.
+// only for stakes active at time now.
+func (c *anomaliesCronjob) checkEpoch(
+	ctx context.Context, epoch int64, weights map[string]uint64, now time.Time,
+) ([]*database.Anomaly, error) {
+	start, end := c.epochs.GetTimeRange(epoch)
+	votingData, err := c.db.GetPChainTxsForEpoch(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var anomalies []*database.Anomaly
+	add := func(anomalyType database.AnomalyType, txID string, format string, args ...interface{}) {
+		anomalies = append(anomalies, &database.Anomaly{
+			Type:      anomalyType,
+			Epoch:     epoch,
+			TxID:      txID,
+			Details:   utils.TruncateString(fmt.Sprintf(format, args...), maxAnomalyDetailsLength),
+			Timestamp: now,
+		})
+	}
+
+	// Each transaction has one row with input index 0, which is the voted leaf
+	var txIDs []string
+	rows := make(map[string]int)
+	for i := range votingData {
+		tx := &votingData[i]
+		if tx.TxID == nil || tx.InputIndex != 0 {
+			continue
+		}
+		if rows[*tx.TxID] == 0 {
+			txIDs = append(txIDs, *tx.TxID)
+		}
+		rows[*tx.TxID]++
+	}
+	for _, txID := range txIDs {
+		if rows[txID] > 1 {
+			add(database.AnomalyDuplicateTx, txID, "%d rows in the voting data", rows[txID])
+		}
+	}
+
+	txs := staking.EpochTxs(votingData, c.filter)
+	stakeTxIDs := utils.Map(txs, func(tx database.PChainTxData) string {
+		return *tx.TxID
+	})
+	attempts, err := c.db.FetchSucceededMirroringAttempts(ctx, stakeTxIDs)
+	if err != nil {
+		return nil, err
+	}
+	txAttempts := make(map[string][]database.MirroringAttempt)
+	for _, a := range attempts {
+		txAttempts[a.TxID] = append(txAttempts[a.TxID], a)
+	}
+	// The first input, whose address is the source address of the mirrored
+	// stake, is signed with the first credential. Transactions indexed without
+	// signers are not checked.
+	signers, err := c.db.FetchPChainTxSigners(ctx, stakeTxIDs)
+	if err != nil {
+		return nil, err
+	}
+	txSigners := make(map[string][]string)
+	for _, s := range signers {
+		if s.CredIdx == 0 {
+			txSigners[s.TxID] = append(txSigners[s.TxID], s.Address)
+		}
+	}
+
+	for i := range txs {
+		tx := &txs[i]
+		txID := *tx.TxID
+
+		mirrored := 0
+		for _, a := range txAttempts[txID] {
+			if a.Epoch != epoch {
+				add(database.AnomalyMultipleEpochs, txID, "mirrored in epoch %d (eth tx %s)", a.Epoch, a.EthTxHash)
+				break
+			}
+			mirrored++
+		}
+		if mirrored > 1 {
+			add(database.AnomalyDoubleMirrored, txID, "mirrored %d times", mirrored)
+		}
+		if addresses, ok := txSigners[txID]; ok && !containsString(addresses, tx.InputAddress) {
+			add(database.AnomalySourceNotSigner, txID, "input address %s, signers %s",
+				tx.InputAddress, strings.Join(addresses, ", "))
+		}
+
+		if tx.StartTime == nil || tx.EndTime == nil || tx.StartTime.After(now) || !tx.EndTime.After(now) {
+			continue
+		}
+		if weight, ok := weights[txID]; !ok {
+			add(database.AnomalyWeightMismatch, txID, "weight %d, not a current staker on the node", tx.Weight)
+		} else if weight != tx.Weight {
+			add(database.AnomalyWeightMismatch, txID, "weight %d, weight on the node %d", tx.Weight, weight)
+		}
+	}
+	return anomalies, nil
+}
+
+const maxAnomalyDetailsLength = 256
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *anomaliesCronjob) alert(anomalies []*database.Anomaly) {
+	for _, a := range anomalies {
+		logger.Warn("anomaly %s in epoch %d, tx %s: %s", a.Type, a.Epoch, a.TxID, a.Details)
+		anomaliesFound.WithLabelValues(string(a.Type), c.network).Inc()
+		c.events.Publish(events.AnomalyDetected, events.AnomalyDetectedData{
+			Type:    string(a.Type),
+			Epoch:   a.Epoch,
+			TxID:    a.TxID,
+			Details: a.Details,
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+)
+
+// Returns the anomalies of the epoch, see anomaliesCronjob, independently of
+// the anomalies cronjob. The anomalies are neither stored nor published.
+func VerifyEpoch(ctx context.Context, ictx indexerctx.IndexerContext, epoch int64) ([]*database.Anomaly, error) {
+	if err := checkContractAddresses(ictx.Config(), false); err != nil {
+		return nil, err
+	}
+	c, err := createAnomaliesCronjob(ictx)
+	if err != nil {
+		return nil, err
+	}
+	return c.verifyEpoch(ctx, epoch)
+}
+
+func (c *anomaliesCronjob) verifyEpoch(ctx context.Context, epoch int64) ([]*database.Anomaly, error) {
+	weights, err := c.stakers.GetCurrentStakerWeights(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.checkEpoch(ctx, epoch, weights, c.now())
+}
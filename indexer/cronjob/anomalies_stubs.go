@@ -0,0 +1,65 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/contracts/voting"
+	"flare-indexer/utils/staking"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type anomaliesDBGorm struct {
+	db *gorm.DB
+}
+
+func (g anomaliesDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, g.db, name)
+}
+
+func (g anomaliesDBGorm) UpdateState(ctx context.Context, state *database.State) error {
+	return database.UpdateState(ctx, g.db, state)
+}
+
+func (g anomaliesDBGorm) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+	return database.GetPChainTxsForEpoch(ctx, &database.GetPChainTxsForEpochInput{
+		DB:             g.db,
+		StartTimestamp: start,
+		EndTimestamp:   end,
+	})
+}
+
+func (g anomaliesDBGorm) FetchSucceededMirroringAttempts(ctx context.Context, txIDs []string) ([]database.MirroringAttempt, error) {
+	return database.FetchSucceededMirroringAttempts(ctx, g.db, txIDs)
+}
+
+func (g anomaliesDBGorm) FetchPChainTxSigners(ctx context.Context, txIDs []string) ([]database.PChainTxSigner, error) {
+	return database.FetchPChainTxSigners(ctx, g.db, txIDs)
+}
+
+func (g anomaliesDBGorm) CreateAnomalies(ctx context.Context, anomalies []*database.Anomaly) (int64, error) {
+	return database.CreateAnomalies(ctx, g.db, anomalies)
+}
+
+// Start and period of the epochs of the voting contract
+func votingEpochConfig(cfg *config.Config, eth chain.EthClient) (time.Time, time.Duration, error) {
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, eth)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return staking.GetEpochConfig(votingContract)
+}
+
+// Epochs of the voting contract, numbered as by the voting cronjob
+func VotingEpochs(ictx indexerctx.IndexerContext) (staking.EpochInfo, error) {
+	cfg := ictx.Config()
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, ictx.EthClient())
+	if err != nil {
+		return staking.EpochInfo{}, err
+	}
+	return staking.NewVotingEpochInfo(&cfg.VotingCronjob.EpochConfig, votingContract)
+}
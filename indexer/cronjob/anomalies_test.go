@@ -0,0 +1,123 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomalies(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := time.Now().Add(24 * time.Hour)
+	newTx := func(txID string, inputIndex uint32, weight uint64) database.PChainTxData {
+		return database.PChainTxData{
+			PChainTx: database.PChainTx{
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &startTime,
+				EndTime:   &endTime,
+				TxID:      &txID,
+				Type:      database.PChainAddDelegatorTx,
+				Weight:    weight,
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+			InputIndex:   inputIndex,
+		}
+	}
+
+	db := &anomaliesTestDB{testDB: testDB{
+		epochs: epochInfo,
+		states: map[string]database.State{
+			mirrorStateName:    {NextDBIndex: 5},
+			anomaliesStateName: {NextDBIndex: 3},
+		},
+		txs: map[int64][]database.PChainTxData{
+			3: {
+				newTx("A", 0, 100), newTx("A", 0, 100), newTx("A", 1, 100),
+				newTx("B", 0, 100),
+				newTx("C", 0, 100),
+				newTx("D", 0, 100),
+			},
+		},
+		attempts: map[string][]database.MirroringAttempt{
+			"B": {
+				{TxID: "B", Epoch: 3, Status: database.MirroringAttemptSucceeded},
+				{TxID: "B", Epoch: 3, Status: database.MirroringAttemptSucceeded},
+			},
+			"C": {
+				{TxID: "C", Epoch: 3, Status: database.MirroringAttemptReverted},
+				{TxID: "C", Epoch: 3, Status: database.MirroringAttemptSucceeded},
+				{TxID: "C", Epoch: 4, Status: database.MirroringAttemptSucceeded, EthTxHash: "0x01"},
+			},
+		},
+	}}
+	// Transactions without signers are not checked
+	db.signers = []database.PChainTxSigner{
+		{TxID: "A", CredIdx: 0, Address: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u"},
+		{TxID: "B", CredIdx: 0, Address: "costwo1n5vvqn7g05sxzaes8xtvr5mx6m95q96jesrg5g"},
+		{TxID: "B", CredIdx: 1, Address: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u"},
+	}
+	stakers := testStakers{"A": 100, "B": 100, "C": 90}
+
+	j := anomaliesCronjob{
+		db:      db,
+		stakers: stakers,
+		epochCronjob: epochCronjob{
+			enabled: true,
+			epochs:  epochInfo,
+		},
+	}
+	require.NoError(t, j.Call(context.Background()))
+
+	require.Equal(t, uint64(5), db.states[anomaliesStateName].NextDBIndex)
+	require.Len(t, db.anomalies, 6)
+	for i, expected := range []database.Anomaly{
+		{Type: database.AnomalyDuplicateTx, TxID: "A", Details: "2 rows in the voting data"},
+		{Type: database.AnomalyDoubleMirrored, TxID: "B", Details: "mirrored 2 times"},
+		{Type: database.AnomalySourceNotSigner, TxID: "B", Details: "input address costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u, " +
+			"signers costwo1n5vvqn7g05sxzaes8xtvr5mx6m95q96jesrg5g"},
+		{Type: database.AnomalyMultipleEpochs, TxID: "C", Details: "mirrored in epoch 4 (eth tx 0x01)"},
+		{Type: database.AnomalyWeightMismatch, TxID: "C", Details: "weight 100, weight on the node 90"},
+		{Type: database.AnomalyWeightMismatch, TxID: "D", Details: "weight 100, not a current staker on the node"},
+	} {
+		require.Equal(t, expected.Type, db.anomalies[i].Type)
+		require.Equal(t, int64(3), db.anomalies[i].Epoch)
+		require.Equal(t, expected.TxID, db.anomalies[i].TxID)
+		require.Equal(t, expected.Details, db.anomalies[i].Details)
+	}
+
+	// Epochs which are not mirrored yet are not checked
+	require.NoError(t, j.Call(context.Background()))
+	require.Len(t, db.anomalies, 6)
+}
+
+type anomaliesTestDB struct {
+	testDB
+	signers   []database.PChainTxSigner
+	anomalies []*database.Anomaly
+}
+
+func (db *anomaliesTestDB) FetchPChainTxSigners(ctx context.Context, txIDs []string) ([]database.PChainTxSigner, error) {
+	return db.signers, nil
+}
+
+func (db *anomaliesTestDB) UpdateState(ctx context.Context, state *database.State) error {
+	db.states[anomaliesStateName] = *state
+	return nil
+}
+
+func (db *anomaliesTestDB) CreateAnomalies(ctx context.Context, anomalies []*database.Anomaly) (int64, error) {
+	db.anomalies = append(db.anomalies, anomalies...)
+	return int64(len(anomalies)), nil
+}
+
+type testStakers map[string]uint64
+
+func (s testStakers) GetCurrentStakerWeights(ctx context.Context) (map[string]uint64, error) {
+	return s, nil
+}
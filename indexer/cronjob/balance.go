@@ -0,0 +1,106 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const balanceCronjobName = "balance"
+
+// Wei per FLR
+var weiPerToken = new(big.Float).SetInt(big.NewInt(1e18))
+
+// Exports the native token balance of the address of the configured private
+// key in the signer_balance metric and logs a warning if it is below the
+// configured threshold, so that the account is funded before the voting and
+// mirroring transactions start failing
+type balanceCronjob struct {
+	enabled   bool
+	schedule  Schedule
+	address   common.Address
+	threshold float64
+
+	client balanceClient
+	gauge  prometheus.Gauge
+}
+
+// Implemented by chain.ManagedEthClient
+type balanceClient interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+func NewBalanceCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.Balance.Enabled {
+		return &balanceCronjob{}, nil
+	}
+	address, err := signerAddress(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", balanceCronjobName, err)
+	}
+	return newBalanceCronjob(&cfg.Balance, address, ctx.EthClient(), signerBalance.WithLabelValues(address.Hex(), cfg.Network))
+}
+
+func newBalanceCronjob(cfg *config.BalanceConfig, address common.Address, client balanceClient, gauge prometheus.Gauge) (*balanceCronjob, error) {
+	schedule, err := newSchedule(cfg.Schedule, &cfg.CronjobConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", balanceCronjobName, err)
+	}
+	return &balanceCronjob{
+		enabled:   cfg.Enabled,
+		schedule:  schedule,
+		address:   address,
+		threshold: cfg.WarningThreshold,
+		client:    client,
+		gauge:     gauge,
+	}, nil
+}
+
+// Address of the configured private key
+func signerAddress(cfg *config.Config) (common.Address, error) {
+	privateKey, err := cfg.Chain.GetPrivateKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	txOpts, err := TransactOptsFromPrivateKey(privateKey, cfg.Chain.ChainID)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid private key: %w", err)
+	}
+	return txOpts.From, nil
+}
+
+func (c *balanceCronjob) Name() string {
+	return balanceCronjobName
+}
+
+func (c *balanceCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *balanceCronjob) Schedule() Schedule {
+	return c.schedule
+}
+
+func (c *balanceCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func (c *balanceCronjob) Call(ctx context.Context) error {
+	wei, err := c.client.BalanceAt(ctx, c.address, nil)
+	if err != nil {
+		return fmt.Errorf("cannot fetch the balance of %s: %w", c.address.Hex(), err)
+	}
+	balance, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), weiPerToken).Float64()
+	c.gauge.Set(balance)
+	if balance < c.threshold {
+		logger.Warn("Balance of signing address %s is %g FLR, below the warning threshold %g FLR", c.address.Hex(), balance, c.threshold)
+	}
+	return nil
+}
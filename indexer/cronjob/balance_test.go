@@ -0,0 +1,47 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/indexer/config"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testBalanceClient struct {
+	balance *big.Int
+	err     error
+}
+
+func (c *testBalanceClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return c.balance, c.err
+}
+
+func TestBalanceCronjob(t *testing.T) {
+	cfg := &config.BalanceConfig{
+		CronjobConfig:    config.CronjobConfig{Enabled: true, Timeout: time.Minute},
+		WarningThreshold: 10,
+	}
+	client := &testBalanceClient{balance: new(big.Int).Mul(big.NewInt(25), big.NewInt(1e17))}
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_signer_balance"})
+	c, err := newBalanceCronjob(cfg, testContractAddress, client, gauge)
+	require.NoError(t, err)
+	require.True(t, c.Enabled())
+
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, 2.5, testutil.ToFloat64(gauge))
+
+	// The gauge keeps the last balance if it cannot be fetched
+	client.err = errors.New("connection refused")
+	require.Error(t, c.Call(context.Background()))
+	require.Equal(t, 2.5, testutil.ToFloat64(gauge))
+}
@@ -0,0 +1,24 @@
+package cronjob
+
+import (
+	"flare-indexer/utils/contracts/mirroring"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Interfaces implemented by the generated contract bindings that are mocked in
+// the tests of the stubs. Interfaces embedding staking.EpochConfigCaller are
+// defined next to the stubs (mockgen cannot handle its unnamed struct result).
+
+//go:generate mockgen -source=bindings.go -destination=mock_bindings_test.go -package=cronjob
+
+// Subset of the mirroring contract binding used by the mirror cronjob
+type mirroringBinding interface {
+	MirrorStake(
+		opts *bind.TransactOpts,
+		stakeData mirroring.IPChainStakeMirrorVerifierPChainStake,
+		merkleProof [][32]byte,
+	) (*types.Transaction, error)
+	IsActiveStakeMirrored(opts *bind.CallOpts, txId [32]byte, inputAddress [20]byte) (bool, error)
+}
@@ -0,0 +1,88 @@
+package cronjob
+
+import (
+	globalConfig "flare-indexer/config"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"math"
+)
+
+// Epochs of the schedule checks of cronjobs using the epochs of the voting
+// contract, the offsets of epoch schedules are checked against the epoch
+// period when the cronjobs are started
+var uncheckedEpochs = staking.EpochInfo{Period: math.MaxInt64}
+
+// Checks the settings of the enabled cronjobs which can be checked without
+// connecting to the node, the eth RPC or the database: contract addresses,
+// schedules and the signing key. Used by the config check command, the
+// cronjobs check the same settings when they are created or started.
+func CheckConfig(cfg *config.Config) error {
+	epochCronjobs := []struct {
+		name           string
+		cfg            *config.CronjobConfig
+		needsMirroring bool
+	}{
+		{votingCronjobName, &cfg.VotingCronjob.CronjobConfig, false},
+		{mirrorCronjobName, &cfg.Mirror.CronjobConfig, true},
+		{anomaliesCronjobName, &cfg.Anomalies.CronjobConfig, false},
+		{reconciliationCronjobName, &cfg.Reconciliation.CronjobConfig, true},
+	}
+	for _, c := range epochCronjobs {
+		if !c.cfg.Enabled {
+			continue
+		}
+		if err := checkContractAddresses(cfg, c.needsMirroring); err != nil {
+			return fmt.Errorf("%s cronjob: %w", c.name, err)
+		}
+		if _, err := newSchedule(c.cfg.Schedule, c.cfg, &uncheckedEpochs); err != nil {
+			return fmt.Errorf("%s cronjob: %w", c.name, err)
+		}
+	}
+
+	if cfg.Mirror.Enabled && cfg.Mirror.Buffer < 0 {
+		return fmt.Errorf("%s cronjob: buffer must not be negative", mirrorCronjobName)
+	}
+
+	if cfg.ContractEvents.Enabled {
+		if err := checkContractAddresses(cfg, true); err != nil {
+			return fmt.Errorf("%s cronjob: %w", contractEventsCronjobName, err)
+		}
+		if _, err := newSchedule(cfg.ContractEvents.Schedule, &cfg.ContractEvents.CronjobConfig, nil); err != nil {
+			return fmt.Errorf("%s cronjob: %w", contractEventsCronjobName, err)
+		}
+	}
+
+	uptimeCfg := &cfg.UptimeCronjob
+	if uptimeCfg.Enabled {
+		if _, err := newSchedule(uptimeCfg.Schedule, &uptimeCfg.CronjobConfig, nil); err != nil {
+			return fmt.Errorf("uptime cronjob: %w", err)
+		}
+	}
+	if uptimeCfg.Enabled && uptimeCfg.EnableVoting {
+		if err := checkContractAddresses(cfg, false); err != nil {
+			return fmt.Errorf("%s cronjob: %w", uptimeVotingCronjobName, err)
+		}
+		epochs := staking.NewEpochInfo(&globalConfig.EpochConfig{First: uptimeCfg.First}, uptimeCfg.Start.Time, uptimeCfg.Period)
+		if _, err := newSchedule(uptimeCfg.VotingSchedule, &uptimeCfg.CronjobConfig, &epochs); err != nil {
+			return fmt.Errorf("%s cronjob: %w", uptimeVotingCronjobName, err)
+		}
+	}
+
+	if cfg.Balance.Enabled {
+		if _, err := newSchedule(cfg.Balance.Schedule, &cfg.Balance.CronjobConfig, nil); err != nil {
+			return fmt.Errorf("%s cronjob: %w", balanceCronjobName, err)
+		}
+	}
+
+	if cfg.VotingCronjob.Enabled || cfg.Mirror.Enabled || cfg.Balance.Enabled || (uptimeCfg.Enabled && uptimeCfg.EnableVoting) {
+		privateKey, err := cfg.Chain.GetPrivateKey()
+		if err != nil {
+			return err
+		}
+		if _, err := TransactOptsFromPrivateKey(privateKey, cfg.Chain.ChainID); err != nil {
+			return fmt.Errorf("invalid private key: %w", err)
+		}
+	}
+	return nil
+}
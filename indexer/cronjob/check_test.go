@@ -0,0 +1,30 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"flare-indexer/indexer/config"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	cfg := &config.Config{}
+	require.NoError(t, CheckConfig(cfg))
+
+	cfg.Mirror.Enabled = true
+	cfg.Mirror.Schedule = "@epoch+10m"
+	cfg.ContractAddresses.Voting = testContractAddress
+	require.ErrorIs(t, CheckConfig(cfg), ErrContractAddressNotSet)
+
+	cfg.ContractAddresses.Mirroring = testContractAddress
+	require.EqualError(t, CheckConfig(cfg), "invalid private key: privateKey is too short")
+
+	cfg.Chain.PrivateKey = "0xd49743deccbccc5dc7baa8e69e5be03298da8688a15dd202e20f15d5e0e9a9fb"
+	require.NoError(t, CheckConfig(cfg))
+
+	cfg.Mirror.Schedule = "@epoch10m"
+	require.EqualError(t, CheckConfig(cfg), `mirror cronjob: invalid schedule "@epoch10m": expected @epoch+<duration>`)
+}
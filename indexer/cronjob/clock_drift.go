@@ -0,0 +1,89 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const clockDriftCronjobName = "clock_drift"
+
+// Exports the difference between the local clock and the clock of the node in
+// the clock_drift_seconds metric and logs a warning if it exceeds the configured
+// threshold. The epoch cronjobs select the epochs by the local clock, so a
+// drifting clock makes them vote or mirror too early or too late. The clock is
+// checked on start and then periodically.
+type clockDriftCronjob struct {
+	enabled   bool
+	schedule  Schedule
+	threshold time.Duration
+
+	measure func(ctx context.Context) (time.Duration, error)
+	gauge   prometheus.Gauge
+}
+
+func NewClockDriftCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.ClockDrift.Enabled {
+		return &clockDriftCronjob{}, nil
+	}
+	measure := func(ctx context.Context) (time.Duration, error) {
+		return chain.FetchNodeClockDrift(ctx, &cfg.Chain)
+	}
+	return newClockDriftCronjob(&cfg.ClockDrift, measure, clockDrift.WithLabelValues(cfg.Network))
+}
+
+func newClockDriftCronjob(
+	cfg *config.ClockDriftConfig, measure func(ctx context.Context) (time.Duration, error), gauge prometheus.Gauge,
+) (*clockDriftCronjob, error) {
+	schedule, err := newSchedule(cfg.Schedule, &cfg.CronjobConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", clockDriftCronjobName, err)
+	}
+	return &clockDriftCronjob{
+		enabled:   cfg.Enabled,
+		schedule:  schedule,
+		threshold: cfg.WarningThreshold,
+		measure:   measure,
+		gauge:     gauge,
+	}, nil
+}
+
+func (c *clockDriftCronjob) Name() string {
+	return clockDriftCronjobName
+}
+
+func (c *clockDriftCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *clockDriftCronjob) Schedule() Schedule {
+	return c.schedule
+}
+
+// The first scheduled call is after the interval, the clock is checked on start
+// as well. An unreachable node does not delay the start, it is logged only.
+func (c *clockDriftCronjob) OnStart(ctx context.Context) error {
+	if err := c.Call(ctx); err != nil {
+		logger.Error("%s cronjob on start: %v", clockDriftCronjobName, err)
+	}
+	return nil
+}
+
+func (c *clockDriftCronjob) Call(ctx context.Context) error {
+	drift, err := c.measure(ctx)
+	if err != nil {
+		return err
+	}
+	c.gauge.Set(drift.Seconds())
+	if c.threshold > 0 && (drift > c.threshold || drift < -c.threshold) {
+		logger.Warn("Local clock differs from the clock of the node by %v, more than the warning threshold %v", drift, c.threshold)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/indexer/config"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockDriftCronjob(t *testing.T) {
+	cfg := &config.ClockDriftConfig{
+		CronjobConfig:    config.CronjobConfig{Enabled: true, Timeout: time.Minute},
+		WarningThreshold: 5 * time.Second,
+	}
+	drift := -1500 * time.Millisecond
+	var err error
+	measure := func(ctx context.Context) (time.Duration, error) {
+		return drift, err
+	}
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_clock_drift_seconds"})
+	c, cErr := newClockDriftCronjob(cfg, measure, gauge)
+	require.NoError(t, cErr)
+	require.True(t, c.Enabled())
+
+	require.NoError(t, c.OnStart(context.Background()))
+	require.Equal(t, -1.5, testutil.ToFloat64(gauge))
+
+	drift = 7 * time.Second
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, 7.0, testutil.ToFloat64(gauge))
+
+	// The gauge keeps the last drift if the node cannot be reached, the start of
+	// the cronjob does not fail
+	err = errors.New("connection refused")
+	require.Error(t, c.Call(context.Background()))
+	require.NoError(t, c.OnStart(context.Background()))
+	require.Equal(t, 7.0, testutil.ToFloat64(gauge))
+}
@@ -0,0 +1,183 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	contractEventsStateName   = "contract_events_cronjob"
+	contractEventsCronjobName = "contract_events"
+)
+
+// Indexes the StakeConfirmed events of the mirroring contract and the
+// PChainStakeMirrorVoteSubmitted and PChainStakeMirrorVotingFinalized events of
+// the voting contract into dedicated tables. The C-chain blocks are indexed in
+// batches from the configured start block to the latest block, the next block
+// is stored in the state of the cronjob.
+type contractEventsCronjob struct {
+	schedule   Schedule
+	batchSize  uint64
+	startBlock uint64
+
+	db     contractEventsDB
+	chain  contractEventsChain
+	parser *contractEventsParser
+}
+
+type contractEventsDB interface {
+	FetchState(ctx context.Context, name string) (database.State, error)
+	// Stores the events of the blocks before nextBlock, which were not indexed yet,
+	// and updates the state
+	StoreContractEvents(ctx context.Context, events *database.ContractEvents, nextBlock uint64, lastBlock uint64) error
+}
+
+// Implemented by chain.ManagedEthClient
+type contractEventsChain interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// The cronjob is created when it is started, see lazyCronjob
+func NewContractEventsCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.ContractEvents.Enabled {
+		return &contractEventsCronjob{}, nil
+	}
+	if err := checkContractAddresses(cfg, true); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", contractEventsCronjobName, err)
+	}
+	return newLazyCronjob(contractEventsCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createContractEventsCronjob(ctx)
+	}), nil
+}
+
+// Creates the cronjob, needs the eth RPC
+func createContractEventsCronjob(ctx indexerctx.IndexerContext) (*contractEventsCronjob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), true); err != nil {
+		return nil, err
+	}
+	return newContractEventsCronjob(cfg, contractEventsDBGorm{db: ctx.DB()}, ctx.EthClient())
+}
+
+func newContractEventsCronjob(cfg *config.Config, db contractEventsDB, chain contractEventsChain) (*contractEventsCronjob, error) {
+	eventsCfg := &cfg.ContractEvents
+	if eventsCfg.BatchSize <= 0 {
+		return nil, fmt.Errorf("%s cronjob: batch size must be positive", contractEventsCronjobName)
+	}
+	schedule, err := newSchedule(eventsCfg.Schedule, &eventsCfg.CronjobConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", contractEventsCronjobName, err)
+	}
+	var mirroringAddresses []common.Address
+	for _, contract := range cfg.ContractAddresses.MirroringContractsByEpoch() {
+		mirroringAddresses = append(mirroringAddresses, contract.Address)
+	}
+	parser, err := newContractEventsParser(mirroringAddresses, cfg.ContractAddresses.Voting)
+	if err != nil {
+		return nil, err
+	}
+	return &contractEventsCronjob{
+		schedule:   schedule,
+		batchSize:  uint64(eventsCfg.BatchSize),
+		startBlock: eventsCfg.StartBlock,
+		db:         db,
+		chain:      chain,
+		parser:     parser,
+	}, nil
+}
+
+func (c *contractEventsCronjob) Name() string {
+	return contractEventsCronjobName
+}
+
+func (c *contractEventsCronjob) Enabled() bool {
+	return c.db != nil
+}
+
+func (c *contractEventsCronjob) Schedule() Schedule {
+	return c.schedule
+}
+
+func (c *contractEventsCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+// Indexes the blocks up to the latest block, until the context is done
+func (c *contractEventsCronjob) Call(ctx context.Context) error {
+	state, err := c.db.FetchState(ctx, contractEventsStateName)
+	if err != nil {
+		return err
+	}
+	latest, err := c.chain.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	lastBlock := latest.Number.Uint64()
+
+	next := state.NextDBIndex
+	if next < c.startBlock {
+		next = c.startBlock
+	}
+	for next <= lastBlock && ctx.Err() == nil {
+		end := next + c.batchSize - 1
+		if end > lastBlock {
+			end = lastBlock
+		}
+		if err := c.indexBlocks(ctx, next, end, lastBlock); err != nil {
+			return err
+		}
+		next = end + 1
+	}
+	return nil
+}
+
+// Indexes the blocks [start, end]
+func (c *contractEventsCronjob) indexBlocks(ctx context.Context, start uint64, end uint64, lastBlock uint64) error {
+	logs, err := c.chain.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+		Addresses: c.parser.addresses(),
+	})
+	if err != nil {
+		return err
+	}
+	events := &database.ContractEvents{}
+	blockTimes := make(map[uint64]time.Time)
+	for _, log := range logs {
+		if log.Removed {
+			continue
+		}
+		timestamp, ok := blockTimes[log.BlockNumber]
+		if !ok {
+			header, err := c.chain.HeaderByNumber(ctx, new(big.Int).SetUint64(log.BlockNumber))
+			if err != nil {
+				return err
+			}
+			timestamp = time.Unix(int64(header.Time), 0)
+			blockTimes[log.BlockNumber] = timestamp
+		}
+		if err := c.parser.parse(log, timestamp, events); err != nil {
+			return fmt.Errorf("block %d, log %d: %w", log.BlockNumber, log.Index, err)
+		}
+	}
+	if err := c.db.StoreContractEvents(ctx, events, end+1, lastBlock); err != nil {
+		return err
+	}
+	if n := events.Len(); n > 0 {
+		logger.Info("indexed %d contract events in blocks %d-%d", n, start, end)
+		ReportItems(ctx, n)
+	}
+	return nil
+}
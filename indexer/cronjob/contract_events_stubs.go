@@ -0,0 +1,150 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/contracts/voting"
+	"math/big"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type contractEventsDBGorm struct {
+	db *gorm.DB
+}
+
+func (g contractEventsDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, g.db, name)
+}
+
+func (g contractEventsDBGorm) StoreContractEvents(ctx context.Context, events *database.ContractEvents, nextBlock uint64, lastBlock uint64) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := database.CreateContractEvents(ctx, tx, events); err != nil {
+			return errors.Wrap(err, "database.CreateContractEvents")
+		}
+		state, err := database.FetchState(ctx, tx, contractEventsStateName)
+		if err != nil {
+			return errors.Wrap(err, "database.FetchState")
+		}
+		state.NextDBIndex = nextBlock
+		state.LastChainIndex = lastBlock
+		state.Updated = time.Now()
+		return database.UpdateState(ctx, tx, &state)
+	})
+}
+
+// Nanoseconds of FLR, the unit of the weights of P-chain stakes, in wei
+var weiPerNanoFLR = big.NewInt(1e9)
+
+// Converts the event logs of the mirroring and voting contracts to the
+// database entities, logs of other events are ignored
+type contractEventsParser struct {
+	// Addresses of all configured mirroring contracts, see config.MirroringContract
+	mirroringAddresses []common.Address
+	votingAddress      common.Address
+	mirroring          *mirroring.MirroringFilterer
+	voting             *voting.VotingFilterer
+
+	stakeConfirmedID common.Hash
+	voteSubmittedID  common.Hash
+	finalizedID      common.Hash
+}
+
+func newContractEventsParser(mirroringAddresses []common.Address, votingAddress common.Address) (*contractEventsParser, error) {
+	// The filterers only parse logs, so the address and the backend are not used
+	mirroringFilterer, err := mirroring.NewMirroringFilterer(common.Address{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	votingFilterer, err := voting.NewVotingFilterer(common.Address{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	mirroringABI, err := mirroring.MirroringMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	votingABI, err := voting.VotingMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return &contractEventsParser{
+		mirroringAddresses: mirroringAddresses,
+		votingAddress:      votingAddress,
+		mirroring:          mirroringFilterer,
+		voting:             votingFilterer,
+		stakeConfirmedID:   mirroringABI.Events["StakeConfirmed"].ID,
+		voteSubmittedID:    votingABI.Events["PChainStakeMirrorVoteSubmitted"].ID,
+		finalizedID:        votingABI.Events["PChainStakeMirrorVotingFinalized"].ID,
+	}, nil
+}
+
+func (p *contractEventsParser) addresses() []common.Address {
+	return append([]common.Address{p.votingAddress}, p.mirroringAddresses...)
+}
+
+func (p *contractEventsParser) isMirroring(address common.Address) bool {
+	for _, a := range p.mirroringAddresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Appends the entity of log to events
+func (p *contractEventsParser) parse(log types.Log, timestamp time.Time, events *database.ContractEvents) error {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	location := database.ContractEvent{
+		BlockNumber: log.BlockNumber,
+		LogIndex:    log.Index,
+		EthTxHash:   log.TxHash.Hex(),
+		Timestamp:   timestamp,
+	}
+	switch {
+	case p.isMirroring(log.Address) && log.Topics[0] == p.stakeConfirmedID:
+		e, err := p.mirroring.ParseStakeConfirmed(log)
+		if err != nil {
+			return errors.Wrap(err, "ParseStakeConfirmed")
+		}
+		events.StakesConfirmed = append(events.StakesConfirmed, &database.MirroringStakeConfirmedEvent{
+			ContractEvent: location,
+			Contract:      log.Address.Hex(),
+			TxID:          ids.ID(e.PChainTxId).String(),
+			Owner:         e.Owner.Hex(),
+			NodeID:        ids.NodeID(e.NodeId).String(),
+			StakeHash:     common.Hash(e.TxHash).Hex(),
+			Amount:        new(big.Int).Div(e.AmountWei, weiPerNanoFLR).Uint64(),
+		})
+	case log.Address == p.votingAddress && log.Topics[0] == p.voteSubmittedID:
+		e, err := p.voting.ParsePChainStakeMirrorVoteSubmitted(log)
+		if err != nil {
+			return errors.Wrap(err, "ParsePChainStakeMirrorVoteSubmitted")
+		}
+		events.VotesSubmitted = append(events.VotesSubmitted, &database.VotingVoteSubmittedEvent{
+			ContractEvent: location,
+			Epoch:         e.EpochId.Int64(),
+			Voter:         e.Voter.Hex(),
+			MerkleRoot:    common.Hash(e.MerkleRoot).Hex(),
+		})
+	case log.Address == p.votingAddress && log.Topics[0] == p.finalizedID:
+		e, err := p.voting.ParsePChainStakeMirrorVotingFinalized(log)
+		if err != nil {
+			return errors.Wrap(err, "ParsePChainStakeMirrorVotingFinalized")
+		}
+		events.Finalizations = append(events.Finalizations, &database.VotingFinalizedEvent{
+			ContractEvent: location,
+			Epoch:         e.EpochId.Int64(),
+			MerkleRoot:    common.Hash(e.MerkleRoot).Hex(),
+		})
+	}
+	return nil
+}
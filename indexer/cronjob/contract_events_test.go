@@ -0,0 +1,172 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/contracts/voting"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testMirroringAddress = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testVotingAddress    = common.HexToAddress("0x1000000000000000000000000000000000000002")
+)
+
+type testContractEventsDB struct {
+	state  database.State
+	events database.ContractEvents
+}
+
+func (db *testContractEventsDB) FetchState(ctx context.Context, name string) (database.State, error) {
+	return db.state, nil
+}
+
+func (db *testContractEventsDB) StoreContractEvents(ctx context.Context, events *database.ContractEvents, nextBlock uint64, lastBlock uint64) error {
+	db.events.StakesConfirmed = append(db.events.StakesConfirmed, events.StakesConfirmed...)
+	db.events.VotesSubmitted = append(db.events.VotesSubmitted, events.VotesSubmitted...)
+	db.events.Finalizations = append(db.events.Finalizations, events.Finalizations...)
+	db.state.NextDBIndex = nextBlock
+	db.state.LastChainIndex = lastBlock
+	return nil
+}
+
+type testContractEventsChain struct {
+	latest  uint64
+	logs    []types.Log
+	queries [][2]uint64
+}
+
+func (c *testContractEventsChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		number = new(big.Int).SetUint64(c.latest)
+	}
+	return &types.Header{Number: number, Time: 1000 * number.Uint64()}, nil
+}
+
+func (c *testContractEventsChain) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+	c.queries = append(c.queries, [2]uint64{from, to})
+	var logs []types.Log
+	for _, log := range c.logs {
+		if log.BlockNumber >= from && log.BlockNumber <= to {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func TestContractEvents(t *testing.T) {
+	nodeID := ids.GenerateTestNodeID()
+	txID := ids.GenerateTestID()
+	owner := common.HexToAddress("0x2000000000000000000000000000000000000001")
+	root := common.HexToHash("0x3000000000000000000000000000000000000000000000000000000000000001")
+
+	stakeConfirmed := newTestLog(t, testMirroringAddress, 1500, 1, mirroring.MirroringMetaData, "StakeConfirmed",
+		[]common.Hash{common.BytesToHash(owner.Bytes()), leftAlignedHash(nodeID[:]), root},
+		new(big.Int).Mul(big.NewInt(5000), big.NewInt(1e9)), txID)
+	voteSubmitted := newTestLog(t, testVotingAddress, 1500, 2, voting.VotingMetaData, "PChainStakeMirrorVoteSubmitted",
+		nil, big.NewInt(7), owner, root)
+	finalized := newTestLog(t, testVotingAddress, 2100, 0, voting.VotingMetaData, "PChainStakeMirrorVotingFinalized",
+		[]common.Hash{common.BigToHash(big.NewInt(7))}, root)
+	// Other events and removed logs are ignored
+	other := types.Log{Address: testVotingAddress, BlockNumber: 2200, Topics: []common.Hash{root}}
+	removed := finalized
+	removed.BlockNumber = 2300
+	removed.Removed = true
+
+	cfg := &config.Config{
+		ContractEvents: config.ContractEventsConfig{
+			CronjobConfig: config.CronjobConfig{Timeout: time.Minute, BatchSize: 1000},
+			StartBlock:    1000,
+		},
+	}
+	cfg.ContractAddresses.Mirroring = testMirroringAddress
+	cfg.ContractAddresses.Voting = testVotingAddress
+	db := &testContractEventsDB{}
+	chain := &testContractEventsChain{
+		latest: 2500,
+		logs:   []types.Log{stakeConfirmed, voteSubmitted, finalized, other, removed},
+	}
+	j, err := newContractEventsCronjob(cfg, db, chain)
+	require.NoError(t, err)
+	require.True(t, j.Enabled())
+
+	require.NoError(t, j.Call(context.Background()))
+	require.Equal(t, [][2]uint64{{1000, 1999}, {2000, 2500}}, chain.queries)
+	require.Equal(t, uint64(2501), db.state.NextDBIndex)
+	require.Equal(t, uint64(2500), db.state.LastChainIndex)
+
+	require.Equal(t, []*database.MirroringStakeConfirmedEvent{{
+		ContractEvent: database.ContractEvent{
+			BlockNumber: 1500,
+			LogIndex:    1,
+			EthTxHash:   stakeConfirmed.TxHash.Hex(),
+			Timestamp:   time.Unix(1500000, 0),
+		},
+		Contract:  testMirroringAddress.Hex(),
+		TxID:      txID.String(),
+		Owner:     owner.Hex(),
+		NodeID:    nodeID.String(),
+		StakeHash: root.Hex(),
+		Amount:    5000,
+	}}, db.events.StakesConfirmed)
+	require.Equal(t, []*database.VotingVoteSubmittedEvent{{
+		ContractEvent: database.ContractEvent{
+			BlockNumber: 1500,
+			LogIndex:    2,
+			EthTxHash:   voteSubmitted.TxHash.Hex(),
+			Timestamp:   time.Unix(1500000, 0),
+		},
+		Epoch:      7,
+		Voter:      owner.Hex(),
+		MerkleRoot: root.Hex(),
+	}}, db.events.VotesSubmitted)
+	require.Len(t, db.events.Finalizations, 1)
+	require.Equal(t, int64(7), db.events.Finalizations[0].Epoch)
+	require.Equal(t, root.Hex(), db.events.Finalizations[0].MerkleRoot)
+
+	// Nothing to index until a new block is produced
+	chain.queries = nil
+	require.NoError(t, j.Call(context.Background()))
+	require.Empty(t, chain.queries)
+	chain.latest = 2600
+	require.NoError(t, j.Call(context.Background()))
+	require.Equal(t, [][2]uint64{{2501, 2600}}, chain.queries)
+}
+
+// Log of the event with the given indexed topics and non-indexed values
+func newTestLog(t *testing.T, address common.Address, block uint64, index uint, metaData *bind.MetaData, event string, topics []common.Hash, values ...interface{}) types.Log {
+	contractABI, err := metaData.GetAbi()
+	require.NoError(t, err)
+	e := contractABI.Events[event]
+	data, err := e.Inputs.NonIndexed().Pack(values...)
+	require.NoError(t, err)
+	return types.Log{
+		Address:     address,
+		Topics:      append([]common.Hash{e.ID}, topics...),
+		Data:        data,
+		BlockNumber: block,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(block*100 + uint64(index))),
+		Index:       index,
+	}
+}
+
+func leftAlignedHash(b []byte) common.Hash {
+	var h common.Hash
+	copy(h[:], b)
+	return h
+}
@@ -1,45 +1,124 @@
 package cronjob
 
 import (
+	"context"
+	"errors"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
+	"flare-indexer/indexer/featureflags"
 	"flare-indexer/logger"
 	"flare-indexer/utils"
 	"flare-indexer/utils/staking"
+	"fmt"
 	"time"
 )
 
 type Cronjob interface {
 	Name() string
 	Enabled() bool
-	Timeout() time.Duration
-	RandomTimeoutDelta() time.Duration
-	Call() error
-	OnStart() error
+	Schedule() Schedule
+	Call(ctx context.Context) error
+	OnStart(ctx context.Context) error
 }
 
-func RunCronjob(c Cronjob) {
+// Runs the cronjob if enabled in the config at the times of its schedule.
+// Calls never overlap, if a call takes longer than the time to the next
+// scheduled call, the missed calls are replaced by a single call right after
+// it. Each call can be skipped by setting the "<name>.enabled" feature flag to
+// false. Calls are recorded in the cronjob_runs table.
+//
+// Panics of the cronjob are recovered and the cronjob is restarted after a
+// delay, doubled after each consecutive panic. A failed start (e.g. the
+// C-chain node is unavailable) is retried in the same way.
+func RunCronjob(c Cronjob, ctx indexerctx.IndexerContext) {
 	if !c.Enabled() {
 		logger.Debug("%s cronjob disabled", c.Name())
 		return
 	}
 
-	err := c.OnStart()
-	if err != nil {
-		logger.Error("%s cronjob on start error %v", c.Name(), err)
-		return
+	errorsCounter := cronjobErrors.WithLabelValues(c.Name(), ctx.Config().Network)
+	panicsCounter := cronjobPanics.WithLabelValues(c.Name(), ctx.Config().Network)
+	backoffGauge := cronjobBackoff.WithLabelValues(c.Name(), ctx.Config().Network)
+	backoff := &restartBackoff{}
+
+	// Returns the delay before the next call if err is a panic
+	handlePanic := func(err error) (time.Duration, bool) {
+		var p *panicError
+		if !errors.As(err, &p) {
+			return 0, false
+		}
+		panicsCounter.Inc()
+		delay := backoff.next()
+		backoffGauge.Set(delay.Seconds())
+		logger.Error("%s cronjob %v, restarting in %v\n%s", c.Name(), err, delay, p.stack)
+		return delay, true
 	}
 
+	for {
+		err := recoverCall(func() error { return c.OnStart(context.Background()) })
+		if err == nil {
+			break
+		}
+		errorsCounter.Inc()
+		delay, panicked := handlePanic(err)
+		if !panicked {
+			delay = backoff.next()
+			backoffGauge.Set(delay.Seconds())
+			logger.Error("%s cronjob on start error %v, retrying in %v", c.Name(), err, delay)
+		}
+		time.Sleep(delay)
+	}
+	backoff.reset()
+	backoffGauge.Set(0)
+
 	logger.Debug("starting %s cronjob", c.Name())
 
-	ticker := utils.NewRandomizedTicker(c.Timeout(), c.RandomTimeoutDelta())
+	flags := ctx.FeatureFlags()
+	runs := newRunLog(c.Name(), runsDBGorm{db: ctx.DB()}, &ctx.Config().CronjobRuns)
+	schedule := c.Schedule()
+	next := schedule.Next(time.Now())
 	for {
-		<-ticker
-		err := c.Call()
-		if err != nil {
-			logger.Error("%s cronjob error %s", c.Name(), err.Error())
+		time.Sleep(time.Until(next))
+		start := time.Now()
+		var restartDelay time.Duration
+		if flags.Enabled(c.Name()) {
+			err := runs.call(context.Background(), c)
+			if err != nil {
+				errorsCounter.Inc()
+				var panicked bool
+				if restartDelay, panicked = handlePanic(err); !panicked {
+					logger.Error("%s cronjob error %s", c.Name(), err.Error())
+				}
+			}
+		} else {
+			logger.Debug("%s cronjob paused by feature flag", c.Name())
+		}
+		if restartDelay == 0 {
+			backoff.reset()
+			backoffGauge.Set(0)
+		}
+
+		var missed bool
+		next, missed = nextCall(schedule, start, time.Now())
+		if missed {
+			logger.Debug("%s cronjob call took %v, missed scheduled calls", c.Name(), time.Since(start))
 		}
+		if restart := time.Now().Add(restartDelay); next.Before(restart) {
+			next = restart
+		}
+	}
+}
+
+// Time of the call after the one started at start, which ended at end, and
+// whether scheduled calls were missed
+func nextCall(schedule Schedule, start time.Time, end time.Time) (time.Time, bool) {
+	next := schedule.Next(start)
+	if next.Before(end) {
+		return end, true
 	}
+	return next, false
 }
 
 const (
@@ -47,11 +126,16 @@ const (
 )
 
 type epochCronjob struct {
+	name      string
+	flags     *featureflags.Flags
+	events    *events.Publisher
 	enabled   bool
-	timeout   time.Duration // call cronjob every "timeout"
+	schedule  Schedule
 	epochs    staking.EpochInfo
 	delay     time.Duration // voting delay
 	batchSize int64
+	// Clock compared with the epoch boundaries, the system clock if nil
+	clock utils.Clock
 }
 
 type epochRange struct {
@@ -59,26 +143,34 @@ type epochRange struct {
 	end   int64
 }
 
-func newEpochCronjob(cronjobCfg *config.CronjobConfig, epochs staking.EpochInfo) epochCronjob {
+func newEpochCronjob(name string, cronjobCfg *config.CronjobConfig, epochs staking.EpochInfo) (epochCronjob, error) {
+	schedule, err := newSchedule(cronjobCfg.Schedule, cronjobCfg, &epochs)
+	if err != nil && cronjobCfg.Enabled {
+		return epochCronjob{}, fmt.Errorf("%s cronjob: %w", name, err)
+	}
 	return epochCronjob{
+		name:      name,
 		enabled:   cronjobCfg.Enabled,
-		timeout:   cronjobCfg.Timeout,
+		schedule:  schedule,
 		epochs:    epochs,
 		batchSize: cronjobCfg.BatchSize,
 		delay:     cronjobCfg.Delay,
-	}
+	}, nil
 }
 
 func (c *epochCronjob) Enabled() bool {
 	return c.enabled
 }
 
-func (c *epochCronjob) Timeout() time.Duration {
-	return c.timeout
+func (c *epochCronjob) Schedule() Schedule {
+	return c.schedule
 }
 
-func (c *epochCronjob) RandomTimeoutDelta() time.Duration {
-	return 0
+func (c *epochCronjob) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
 }
 
 // Get processing range (closed interval)
@@ -89,7 +181,7 @@ func (c *epochCronjob) getEpochRange(start int64, now time.Time) *epochRange {
 // Get trimmed processing range (closed interval)
 func (c *epochCronjob) getTrimmedEpochRange(start, end int64) *epochRange {
 	start = utils.Max(start, c.epochs.First)
-	batchSize := c.batchSize
+	batchSize := c.flags.BatchSize(c.name, c.batchSize)
 	if batchSize == 0 {
 		batchSize = defaultEpochBatchSize
 	} else if batchSize < 0 {
@@ -101,6 +193,20 @@ func (c *epochCronjob) getTrimmedEpochRange(start, end int64) *epochRange {
 	return &epochRange{start, end}
 }
 
+// Context of a call at time now, which is done at the end of the current
+// epoch. The next call processes the epoch that has just ended, so a call
+// must not hold up the cronjob beyond that. The timeout is the time left in
+// the epoch by the clock of the cronjob, which may differ from the system
+// clock.
+func (c *epochCronjob) withEpochDeadline(ctx context.Context, now time.Time) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.epochs.GetEndTime(c.epochs.GetEpochIndex(now)).Sub(now))
+}
+
+// If true, the cronjob should not send transactions or update its state
+func (c *epochCronjob) dryRun() bool {
+	return c.flags.DryRun(c.name)
+}
+
 func (c *epochCronjob) indexerBehind(idxState *database.State, epoch int64) bool {
 	epochEnd := c.epochs.GetEndTime(epoch)
 	return epochEnd.After(idxState.Updated.Add(-c.delay)) || idxState.NextDBIndex <= idxState.LastChainIndex
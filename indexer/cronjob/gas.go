@@ -0,0 +1,125 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// Maximal time to wait for a submitted transaction to be mined before its gas
+// is recorded
+const submissionReceiptTimeout = 60 * time.Second
+
+// Records the gas used and the fee paid by the contract transactions of the
+// cronjobs in the contract_submissions table and in the metrics. Recording
+// errors are logged and do not fail the submissions. A nil gasAccounting
+// records nothing.
+type gasAccounting struct {
+	db      gasDB
+	eth     gasChain
+	network string
+	now     func() time.Time
+}
+
+type gasDB interface {
+	CreateContractSubmission(ctx context.Context, submission *database.ContractSubmission) error
+}
+
+// Implemented by chain.ManagedEthClient
+type gasChain interface {
+	bind.DeployBackend
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+func newGasAccounting(ctx indexerctx.IndexerContext) *gasAccounting {
+	return &gasAccounting{
+		db:      gasDBGorm{db: ctx.DB()},
+		eth:     ctx.EthClient(),
+		network: ctx.Config().Network,
+		now:     time.Now,
+	}
+}
+
+// Waits until the transaction is mined and records it, epoch is nil for
+// submissions not bound to an epoch
+func (g *gasAccounting) waitAndRecord(cronjob string, epoch *int64, tx *types.Transaction, sender string) {
+	if g == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), submissionReceiptTimeout)
+	defer cancel()
+
+	receipt, err := bind.WaitMined(ctx, g.eth, tx)
+	if err != nil {
+		logger.Warn("cannot record the gas of %s transaction %s: %v", cronjob, tx.Hash().Hex(), err)
+		return
+	}
+	g.record(ctx, cronjob, epoch, tx, sender, receipt)
+}
+
+// Records the mined transaction
+func (g *gasAccounting) record(ctx context.Context, cronjob string, epoch *int64, tx *types.Transaction, sender string, receipt *types.Receipt) {
+	if g == nil {
+		return
+	}
+	gasPrice, err := g.effectiveGasPrice(ctx, tx, receipt)
+	if err != nil {
+		logger.Warn("cannot record the gas of %s transaction %s: %v", cronjob, tx.Hash().Hex(), err)
+		return
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+
+	submission := &database.ContractSubmission{
+		Cronjob:   cronjob,
+		Epoch:     epoch,
+		EthTxHash: tx.Hash().Hex(),
+		Sender:    sender,
+		GasUsed:   receipt.GasUsed,
+		GasPrice:  gasPrice.Uint64(),
+		Fee:       fee.Uint64(),
+		Reverted:  receipt.Status != types.ReceiptStatusSuccessful,
+		Timestamp: g.now(),
+	}
+	if to := tx.To(); to != nil {
+		submission.Contract = to.Hex()
+	}
+	if err := g.db.CreateContractSubmission(ctx, submission); err != nil {
+		logger.Warn("cannot record the gas of %s transaction %s: %v", cronjob, tx.Hash().Hex(), err)
+	}
+
+	feeTokens, _ := new(big.Float).Quo(new(big.Float).SetInt(fee), weiPerToken).Float64()
+	contractSubmissions.WithLabelValues(cronjob, g.network).Inc()
+	contractSubmissionGas.WithLabelValues(cronjob, g.network).Add(float64(receipt.GasUsed))
+	contractSubmissionFees.WithLabelValues(cronjob, g.network).Add(feeTokens)
+}
+
+// Gas price paid by the transaction, for dynamic fee transactions the base fee
+// of the block plus the tip, capped by the fee cap
+func (g *gasAccounting) effectiveGasPrice(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) (*big.Int, error) {
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		return tx.GasPrice(), nil
+	}
+	header, err := g.eth.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header.BaseFee == nil {
+		return tx.GasPrice(), nil
+	}
+	return new(big.Int).Add(header.BaseFee, tx.EffectiveGasTipValue(header.BaseFee)), nil
+}
+
+type gasDBGorm struct {
+	db *gorm.DB
+}
+
+func (g gasDBGorm) CreateContractSubmission(ctx context.Context, submission *database.ContractSubmission) error {
+	return database.CreateContractSubmission(ctx, g.db, submission)
+}
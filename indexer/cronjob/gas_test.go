@@ -0,0 +1,90 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type testGasDB struct {
+	submissions []*database.ContractSubmission
+}
+
+func (db *testGasDB) CreateContractSubmission(ctx context.Context, submission *database.ContractSubmission) error {
+	db.submissions = append(db.submissions, submission)
+	return nil
+}
+
+// Only HeaderByNumber is implemented
+type testGasChain struct {
+	gasChain
+	baseFee *big.Int
+}
+
+func (c *testGasChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: number, BaseFee: c.baseFee}, nil
+}
+
+func TestGasAccounting(t *testing.T) {
+	db := &testGasDB{}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	gas := &gasAccounting{
+		db:      db,
+		eth:     &testGasChain{baseFee: big.NewInt(25e9)},
+		network: "test",
+		now:     func() time.Time { return now },
+	}
+	gwei := big.NewInt(1e9)
+
+	// Dynamic fee transaction paying the base fee and a tip of 2 gwei
+	dynamicTx := types.NewTx(&types.DynamicFeeTx{
+		To:        &testContractAddress,
+		GasTipCap: new(big.Int).Mul(big.NewInt(2), gwei),
+		GasFeeCap: new(big.Int).Mul(big.NewInt(100), gwei),
+	})
+	epoch := int64(12)
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: 100000, BlockNumber: big.NewInt(1)}
+	gas.record(context.Background(), mirrorCronjobName, &epoch, dynamicTx, "0x01", receipt)
+
+	// Reverted legacy transaction
+	legacyTx := types.NewTx(&types.LegacyTx{To: &testContractAddress, GasPrice: new(big.Int).Mul(big.NewInt(30), gwei)})
+	receipt = &types.Receipt{Status: types.ReceiptStatusFailed, GasUsed: 50000, BlockNumber: big.NewInt(2)}
+	gas.record(context.Background(), addressBinderCronjobName, nil, legacyTx, "0x01", receipt)
+
+	require.Equal(t, []*database.ContractSubmission{
+		{
+			Cronjob:   mirrorCronjobName,
+			Epoch:     &epoch,
+			EthTxHash: dynamicTx.Hash().Hex(),
+			Sender:    "0x01",
+			Contract:  testContractAddress.Hex(),
+			GasUsed:   100000,
+			GasPrice:  27e9,
+			Fee:       27e14,
+			Timestamp: now,
+		},
+		{
+			Cronjob:   addressBinderCronjobName,
+			EthTxHash: legacyTx.Hash().Hex(),
+			Sender:    "0x01",
+			Contract:  testContractAddress.Hex(),
+			GasUsed:   50000,
+			GasPrice:  30e9,
+			Fee:       15e14,
+			Reverted:  true,
+			Timestamp: now,
+		},
+	}, db.submissions)
+
+	// A nil gasAccounting records nothing
+	var disabled *gasAccounting
+	disabled.record(context.Background(), mirrorCronjobName, &epoch, dynamicTx, "0x01", receipt)
+}
@@ -0,0 +1,52 @@
+package cronjob
+
+import (
+	"context"
+	"fmt"
+)
+
+// Cronjob created when it is started, so that the indexer starts while the
+// dependencies of the cronjob (e.g. the C-chain node) are unavailable. A
+// failed creation fails the start, which is retried by RunCronjob.
+type lazyCronjob struct {
+	name    string
+	enabled bool
+	create  func(ctx context.Context) (Cronjob, error)
+	cronjob Cronjob
+}
+
+func newLazyCronjob(name string, enabled bool, create func(ctx context.Context) (Cronjob, error)) *lazyCronjob {
+	return &lazyCronjob{
+		name:    name,
+		enabled: enabled,
+		create:  create,
+	}
+}
+
+func (c *lazyCronjob) Name() string {
+	return c.name
+}
+
+func (c *lazyCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *lazyCronjob) OnStart(ctx context.Context) error {
+	if c.cronjob == nil {
+		cronjob, err := c.create(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot initialize: %w", err)
+		}
+		c.cronjob = cronjob
+	}
+	return c.cronjob.OnStart(ctx)
+}
+
+// Must not be called before the cronjob is started
+func (c *lazyCronjob) Schedule() Schedule {
+	return c.cronjob.Schedule()
+}
+
+func (c *lazyCronjob) Call(ctx context.Context) error {
+	return c.cronjob.Call(ctx)
+}
@@ -0,0 +1,39 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyCronjob(t *testing.T) {
+	var created, called int
+	createErr := errors.New("connection refused")
+	c := newLazyCronjob("test", true, func(ctx context.Context) (Cronjob, error) {
+		created++
+		if created == 1 {
+			return nil, createErr
+		}
+		return &runsCronjobTest{call: func(ctx context.Context) error {
+			called++
+			return nil
+		}}, nil
+	})
+	require.Equal(t, "test", c.Name())
+	require.True(t, c.Enabled())
+
+	// A failed creation is retried on the next start
+	require.ErrorIs(t, c.OnStart(context.Background()), createErr)
+	require.NoError(t, c.OnStart(context.Background()))
+	require.NoError(t, c.OnStart(context.Background()))
+	require.Equal(t, 2, created)
+
+	require.NotNil(t, c.Schedule())
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, 1, called)
+}
@@ -0,0 +1,98 @@
+package cronjob
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// Maximal time to wait for a connected Ledger
+const ledgerConnectTimeout = 30 * time.Second
+
+// Subset of accounts.Wallet used to sign transactions
+type ledgerWallet interface {
+	SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// Opens the first connected Ledger (with the Ethereum app running) and returns
+// transact options signing with the key at the derivation path, e.g.
+// "m/44'/60'/0'/0/0", and a function closing the Ledger. Each
+// transaction must be confirmed on the device, onSign is called before with
+// the transaction to let the user know what to confirm. The Ledger signs
+// legacy transactions only, so the gas price is set.
+func TransactOptsFromLedger(
+	derivationPath string,
+	chainID int,
+	gasPrice *big.Int,
+	onSign func(tx *types.Transaction),
+) (*bind.TransactOpts, func(), error) {
+	path, err := accounts.ParseDerivationPath(derivationPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "accounts.ParseDerivationPath")
+	}
+	hub, err := usbwallet.NewLedgerHub()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "usbwallet.NewLedgerHub")
+	}
+
+	wallet, err := openLedger(hub)
+	if err != nil {
+		return nil, nil, err
+	}
+	account, err := wallet.Derive(path, false)
+	if err != nil {
+		wallet.Close()
+		return nil, nil, errors.Wrap(err, "cannot derive Ledger account")
+	}
+	opts := newLedgerTransactOpts(wallet, account, big.NewInt(int64(chainID)), onSign)
+	opts.GasPrice = gasPrice
+	return opts, func() { wallet.Close() }, nil
+}
+
+// Waits for a connected Ledger and opens it
+func openLedger(hub *usbwallet.Hub) (accounts.Wallet, error) {
+	deadline := time.Now().Add(ledgerConnectTimeout)
+	for {
+		wallets := hub.Wallets()
+		if len(wallets) > 0 {
+			wallet := wallets[0]
+			if err := wallet.Open(""); err != nil {
+				return nil, errors.Wrap(err, "cannot open Ledger, is it unlocked with the Ethereum app running?")
+			}
+			return wallet, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("no Ledger connected")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func newLedgerTransactOpts(
+	wallet ledgerWallet,
+	account accounts.Account,
+	chainID *big.Int,
+	onSign func(tx *types.Transaction),
+) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: account.Address,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != account.Address {
+				return nil, bind.ErrNotAuthorized
+			}
+			if tx.Type() != types.LegacyTxType {
+				return nil, errors.New("the Ledger signs legacy transactions only, set the gas price")
+			}
+			if onSign != nil {
+				onSign(tx)
+			}
+			return wallet.SignTx(account, tx, chainID)
+		},
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// Wallet returning the transactions unchanged
+type testLedgerWallet struct {
+	signed []*types.Transaction
+}
+
+func (w *testLedgerWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.signed = append(w.signed, tx)
+	return tx, nil
+}
+
+func TestLedgerTransactOpts(t *testing.T) {
+	wallet := &testLedgerWallet{}
+	var prompted []*types.Transaction
+	opts := newLedgerTransactOpts(wallet, accounts.Account{Address: testSender}, big.NewInt(14), func(tx *types.Transaction) {
+		prompted = append(prompted, tx)
+	})
+	require.Equal(t, testSender, opts.From)
+
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 300000})
+	signed, err := opts.Signer(testSender, tx)
+	require.NoError(t, err)
+	require.Equal(t, tx, signed)
+	require.Equal(t, []*types.Transaction{tx}, prompted)
+	require.Equal(t, []*types.Transaction{tx}, wallet.signed)
+
+	_, err = opts.Signer(common.HexToAddress("0x01"), tx)
+	require.ErrorIs(t, err, bind.ErrNotAuthorized)
+
+	// Dynamic fee transactions are not signed
+	_, err = opts.Signer(testSender, types.NewTx(&types.DynamicFeeTx{Nonce: 2}))
+	require.Error(t, err)
+	require.Len(t, wallet.signed, 1)
+}
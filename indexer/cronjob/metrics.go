@@ -0,0 +1,55 @@
+package cronjob
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics of all cronjobs, labeled by the cronjob name and the network (empty
+// if the process indexes a single network)
+var (
+	cronjobErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_errors_total",
+		Help: "Number of failed calls of the cronjob, including panics",
+	}, []string{"cronjob", "network"})
+
+	cronjobPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_panics_total",
+		Help: "Number of recovered panics of the cronjob",
+	}, []string{"cronjob", "network"})
+
+	cronjobBackoff = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cronjob_restart_backoff_seconds",
+		Help: "Delay of restarting the cronjob after a panic or a failed start, 0 if it is running",
+	}, []string{"cronjob", "network"})
+
+	anomaliesFound = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "anomalies_total",
+		Help: "Number of anomalies found in the voting and mirroring data by the anomalies cronjob",
+	}, []string{"type", "network"})
+
+	signerBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "signer_balance",
+		Help: "Native token balance (in FLR) of the address signing the voting and mirroring transactions",
+	}, []string{"address", "network"})
+
+	clockDrift = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "clock_drift_seconds",
+		Help: "Difference between the local clock and the clock of the node, positive if the local clock is ahead",
+	}, []string{"network"})
+
+	contractSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "contract_submissions_total",
+		Help: "Number of mined contract transactions sent by the cronjob",
+	}, []string{"cronjob", "network"})
+
+	contractSubmissionGas = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "contract_submission_gas_used_total",
+		Help: "Gas used by the contract transactions sent by the cronjob",
+	}, []string{"cronjob", "network"})
+
+	contractSubmissionFees = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "contract_submission_fees_total",
+		Help: "Fees (in FLR) paid for the contract transactions sent by the cronjob",
+	}, []string{"cronjob", "network"})
+)
@@ -1,6 +1,7 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/migrations"
 	"time"
@@ -12,11 +13,15 @@ func init() {
 	migrations.Container.Add("2023-08-25-00-00", "Create initial state for voting cronjob", createVotingCronjobState)
 	migrations.Container.Add("2023-08-30-00-00", "Create initial state for mirror cronjob", createMirrorCronjobState)
 	migrations.Container.Add("2023-09-30-00-00", "Create initial state for address binder cronjob", createAddressBinderCronjobState)
+	migrations.Container.Add("2023-11-18-01-00", "Create initial state for anomalies cronjob", createAnomaliesCronjobState)
+	migrations.Container.Add("2023-11-18-02-00", "Create initial state for contract events cronjob", createContractEventsCronjobState)
+	migrations.Container.Add("2023-11-28-00-00", "Create initial state for stats cronjob", createStatsCronjobState)
+	migrations.Container.Add("2023-12-06-00-00", "Create index of the mirrored P-Chain transactions query", createMirroringAttemptIndexes)
 
 }
 
-func createVotingCronjobState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+func createVotingCronjobState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
 		Name:           votingStateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
@@ -24,8 +29,8 @@ func createVotingCronjobState(db *gorm.DB) error {
 	})
 }
 
-func createMirrorCronjobState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+func createMirrorCronjobState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
 		Name:           mirrorStateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
@@ -33,11 +38,42 @@ func createMirrorCronjobState(db *gorm.DB) error {
 	})
 }
 
-func createAddressBinderCronjobState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+func createAddressBinderCronjobState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
 		Name:           addressBinderStateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
 		Updated:        time.Now(),
 	})
 }
+
+func createAnomaliesCronjobState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
+		Name:           anomaliesStateName,
+		NextDBIndex:    0,
+		LastChainIndex: 0,
+		Updated:        time.Now(),
+	})
+}
+
+func createContractEventsCronjobState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
+		Name:           contractEventsStateName,
+		NextDBIndex:    0,
+		LastChainIndex: 0,
+		Updated:        time.Now(),
+	})
+}
+
+func createStatsCronjobState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
+		Name:           statsStateName,
+		NextDBIndex:    0,
+		LastChainIndex: 0,
+		Updated:        time.Now(),
+	})
+}
+
+func createMirroringAttemptIndexes(ctx context.Context, db *gorm.DB) error {
+	return database.CreateIndexes(ctx, db, "MirroringAttempt", database.MirroringAttemptIndexes)
+}
@@ -1,85 +1,180 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
+	"flare-indexer/indexer/config"
 	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
 	"flare-indexer/logger"
 	"flare-indexer/utils"
 	"flare-indexer/utils/contracts/mirroring"
 	"flare-indexer/utils/merkle"
 	"flare-indexer/utils/staking"
+	"fmt"
 	"math/big"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 )
 
-const mirrorStateName = "mirror_cronjob"
+const (
+	mirrorStateName   = "mirror_cronjob"
+	mirrorCronjobName = "mirror"
+)
 
 type mirrorCronJob struct {
 	epochCronjob
 	db        mirrorDB
 	contracts mirrorContracts
-	time      utils.ShiftedTime
+	filter    *staking.TxFilter
+	// Time after the end of an epoch before it is mirrored, see buffer()
+	bufferTime time.Duration
 }
 
 type mirrorDB interface {
-	FetchState(name string) (database.State, error)
-	UpdateJobState(epoch int64, force bool) error
-	GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error)
-	GetPChainTx(txID string, address string) (*database.PChainTxData, error)
+	FetchState(ctx context.Context, name string) (database.State, error)
+	UpdateJobState(ctx context.Context, epoch int64, force bool) error
+	GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error)
+	GetPChainTx(ctx context.Context, txID string, address string) (*database.PChainTxData, error)
+	CreateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error
+	// Attempts of the epoch whose transaction was sent but not confirmed yet
+	FetchPendingMirroringAttempts(ctx context.Context, epoch int64) ([]database.MirroringAttempt, error)
+	UpdateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error
+	// Records that the epoch was processed with txCount eligible stakes
+	RecordMirroringEpoch(ctx context.Context, epoch int64, txCount int) error
 }
 
 type mirrorContracts interface {
-	GetMerkleRoot(epoch int64) ([32]byte, error)
+	GetMerkleRoot(ctx context.Context, epoch int64) ([32]byte, error)
+	// Mirrors the stake of the epoch to the mirroring contract active in the epoch
 	MirrorStake(
+		ctx context.Context,
+		epoch int64,
+		stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+		merkleProof [][32]byte,
+	) (*mirrorStakeResult, error)
+	// Outcome of the mirror stake transaction sent earlier, nil if it is not
+	// mined yet
+	MirrorStakeReceipt(
+		ctx context.Context,
+		epoch int64,
+		txHash common.Hash,
 		stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
 		merkleProof [][32]byte,
-	) error
+	) (*mirrorStakeResult, error)
+	// Estimates the gas of the mirror stake transaction without sending it
+	EstimateMirrorStake(
+		ctx context.Context,
+		epoch int64,
+		stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+		merkleProof [][32]byte,
+	) (uint64, error)
+	// Returns for each stake whether it is already mirrored on the mirroring
+	// contract active in the epoch, e.g. by another provider
+	MirroredStakes(ctx context.Context, epoch int64, stakes []*mirroring.IPChainStakeMirrorVerifierPChainStake) ([]bool, error)
 	EpochConfig() (time.Time, time.Duration, error)
 }
 
+// Outcome of a mirror stake submission. It is returned (possibly with
+// only the sender set) also when the transaction could not be sent, or with
+// the hash set when it was sent but not mined in time.
+type mirrorStakeResult struct {
+	sender common.Address
+	// Mirroring contract the transaction was sent to
+	contract common.Address
+	txHash   common.Hash
+	gasUsed  uint64
+	// Set if the transaction was mined but its execution failed
+	reverted     bool
+	revertReason string
+}
+
+// Time after which a pending mirror stake transaction without a receipt is
+// considered dropped and the stake is submitted again
+const mirrorPendingTimeout = time.Hour
+
+// The cronjob is created when it is started, see lazyCronjob
 func NewMirrorCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
 	cfg := ctx.Config()
 
 	if !cfg.Mirror.Enabled {
 		return &mirrorCronJob{}, nil
 	}
+	if err := checkContractAddresses(cfg, true); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", mirrorCronjobName, err)
+	}
+	return newLazyCronjob(mirrorCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createMirrorCronjob(startCtx, ctx)
+	}), nil
+}
 
-	contracts, err := initMirrorJobContracts(cfg)
+// Creates the cronjob, needs the eth RPC
+func createMirrorCronjob(startCtx context.Context, ctx indexerctx.IndexerContext) (*mirrorCronJob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), true); err != nil {
+		return nil, err
+	}
+
+	contracts, err := initMirrorJobContracts(cfg, ctx.EthClient(), newGasAccounting(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	mc, err := newMirrorCronjob(cfg, NewMirrorDBGorm(ctx.DB()), contracts)
+	if err != nil {
+		return nil, err
+	}
+	mc.flags = ctx.FeatureFlags()
+	mc.events = ctx.Events()
+
+	err = mc.reset(startCtx, ctx.Flags().ResetMirrorCronjob)
 	if err != nil {
 		return nil, err
 	}
+	return mc, nil
+}
 
+func newMirrorCronjob(cfg *config.Config, db mirrorDB, contracts mirrorContracts) (*mirrorCronJob, error) {
 	start, period, err := contracts.EpochConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	epochs := staking.NewEpochInfo(&cfg.Mirror.EpochConfig, start, period)
-
-	mc := &mirrorCronJob{
-		epochCronjob: newEpochCronjob(&cfg.Mirror.CronjobConfig, epochs),
-		db:           NewMirrorDBGorm(ctx.DB()),
-		contracts:    contracts,
+	if cfg.Mirror.Buffer < 0 {
+		return nil, fmt.Errorf("%s cronjob: buffer must not be negative", mirrorCronjobName)
 	}
 
-	err = mc.reset(ctx.Flags().ResetMirrorCronjob)
+	epochCronjob, err := newEpochCronjob(mirrorCronjobName, &cfg.Mirror.CronjobConfig, epochs)
+	if err != nil {
+		return nil, err
+	}
 
-	return mc, err
+	return &mirrorCronJob{
+		epochCronjob: epochCronjob,
+		db:           db,
+		contracts:    contracts,
+		filter:       staking.NewTxFilter(&cfg.StakingFilter, &cfg.StakingRules),
+		bufferTime:   cfg.Mirror.Buffer,
+	}, nil
 }
 
 func (c *mirrorCronJob) Name() string {
-	return "mirror"
+	return mirrorCronjobName
 }
 
-func (c *mirrorCronJob) OnStart() error {
+func (c *mirrorCronJob) OnStart(ctx context.Context) error {
 	return nil
 }
 
-func (c *mirrorCronJob) Call() error {
-	epochRange, err := c.getEpochRange()
+func (c *mirrorCronJob) Call(ctx context.Context) error {
+	ctx, cancel := c.withEpochDeadline(ctx, c.now())
+	defer cancel()
+
+	epochRange, err := c.getEpochRange(ctx)
 	if err != nil {
 		if errors.Is(err, errNoEpochsToMirror) {
 			logger.Debug("no epochs to mirror")
@@ -91,16 +186,22 @@ func (c *mirrorCronJob) Call() error {
 
 	logger.Debug("mirroring epochs %d-%d", epochRange.start, epochRange.end)
 
+	dryRun := c.dryRun()
 	for epoch := epochRange.start; epoch <= epochRange.end; epoch++ {
 		logger.Debug("mirroring epoch %d", epoch)
-		if err := c.mirrorEpoch(epoch); err != nil {
+		if err := c.mirrorEpoch(ctx, epoch, dryRun); err != nil {
 			return err
 		}
+		ReportEpoch(ctx, epoch)
+	}
+
+	if dryRun {
+		return nil
 	}
 
 	logger.Debug("successfully mirrored epochs %d-%d", epochRange.start, epochRange.end)
 
-	if err := c.db.UpdateJobState(epochRange.end+1, false); err != nil {
+	if err := c.db.UpdateJobState(ctx, epochRange.end+1, false); err != nil {
 		return err
 	}
 
@@ -109,13 +210,22 @@ func (c *mirrorCronJob) Call() error {
 
 var errNoEpochsToMirror = errors.New("no epochs to mirror")
 
-func (c *mirrorCronJob) getEpochRange() (*epochRange, error) {
-	now := c.time.Now()
-	binderJobState, err := c.db.FetchState(addressBinderStateName)
+// Time after the end of an epoch before it is mirrored, one epoch period if
+// not configured
+func (c *mirrorCronJob) buffer() time.Duration {
+	if c.bufferTime > 0 {
+		return c.bufferTime
+	}
+	return c.epochs.Period
+}
+
+func (c *mirrorCronJob) getEpochRange(ctx context.Context) (*epochRange, error) {
+	now := c.now()
+	binderJobState, err := c.db.FetchState(ctx, addressBinderStateName)
 	if err != nil {
 		return nil, err
 	}
-	jobState, err := c.db.FetchState(mirrorStateName)
+	jobState, err := c.db.FetchState(ctx, mirrorStateName)
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +233,7 @@ func (c *mirrorCronJob) getEpochRange() (*epochRange, error) {
 	endEpoch := int64(binderJobState.NextDBIndex) - 1
 	for startEpoch <= endEpoch {
 		endTime := c.epochs.GetEndTime(endEpoch)
-		if endTime.After(now.Add(-c.epochs.Period)) {
+		if endTime.After(now.Add(-c.buffer())) {
 			endEpoch--
 		} else {
 			break
@@ -137,68 +247,190 @@ func (c *mirrorCronJob) getEpochRange() (*epochRange, error) {
 	return c.getTrimmedEpochRange(startEpoch, endEpoch), nil
 }
 
-func (c *mirrorCronJob) mirrorEpoch(epoch int64) error {
-	txs, err := c.getUnmirroredTxs(epoch)
+func (c *mirrorCronJob) mirrorEpoch(ctx context.Context, epoch int64, dryRun bool) error {
+	txs, err := c.getUnmirroredTxs(ctx, epoch)
 	if err != nil {
 		return err
 	}
 
 	if len(txs) == 0 {
 		logger.Debug("no unmirrored txs found")
-		return nil
+		if dryRun {
+			return nil
+		}
+		return c.db.RecordMirroringEpoch(ctx, epoch, 0)
 	}
 
 	logger.Info("mirroring %d txs", len(txs))
-	if err := c.mirrorTxs(txs, epoch); err != nil {
+	if err := c.mirrorTxs(ctx, txs, epoch, dryRun); err != nil {
 		return err
 	}
+	if !dryRun {
+		ReportItems(ctx, len(txs))
+		c.events.Publish(events.MirroringCompleted, events.MirroringCompletedData{
+			Epoch:   epoch,
+			TxCount: len(txs),
+		})
+		return c.db.RecordMirroringEpoch(ctx, epoch, len(txs))
+	}
 
 	return nil
 }
 
-func (c *mirrorCronJob) getUnmirroredTxs(epoch int64) ([]database.PChainTxData, error) {
+func (c *mirrorCronJob) getUnmirroredTxs(ctx context.Context, epoch int64) ([]database.PChainTxData, error) {
 	startTimestamp, endTimestamp := c.epochs.GetTimeRange(epoch)
 
-	txs, err := c.db.GetPChainTxsForEpoch(startTimestamp, endTimestamp)
+	txs, err := c.db.GetPChainTxsForEpoch(ctx, startTimestamp, endTimestamp)
 	if err != nil {
 		return nil, err
 	}
 
-	return staking.DedupeTxs(txs), nil
+	return staking.EpochTxs(txs, c.filter), nil
 }
 
-func (c *mirrorCronJob) mirrorTxs(txs []database.PChainTxData, epochID int64) error {
+func (c *mirrorCronJob) mirrorTxs(ctx context.Context, txs []database.PChainTxData, epochID int64, dryRun bool) error {
 	merkleTree, err := staking.BuildTree(txs)
 	if err != nil {
 		return err
 	}
 
-	if err := c.checkMerkleRoot(merkleTree, epochID); err != nil {
+	if err := c.checkMerkleRoot(ctx, merkleTree, epochID); err != nil {
+		return err
+	}
+
+	if dryRun {
+		logger.Info("Dry run: not mirroring %d txs of epoch %d", len(txs), epochID)
+		return nil
+	}
+
+	// Encoding the stakes and generating the proofs is CPU-bound, the
+	// transactions are then submitted in the order of txs
+	inputs, err := utils.ParallelMap(len(txs), 0, func(i int) (*mirrorTxInput, error) {
+		return newMirrorTxInput(epochID, merkleTree, &txs[i])
+	})
+	if err != nil {
 		return err
 	}
 
-	for i := range txs {
-		in := mirrorTxInput{
-			epochID:    big.NewInt(epochID),
-			merkleTree: merkleTree,
-			tx:         &txs[i],
+	// Stakes with a transaction sent earlier are not submitted again
+	sent, pending, err := c.resolvePendingAttempts(ctx, epochID, inputs)
+	if err != nil {
+		return err
+	}
+
+	// Stakes mirrored by another provider would only revert, wasting the gas
+	mirrored, err := c.contracts.MirroredStakes(ctx, epochID, utils.Map(inputs,
+		func(in *mirrorTxInput) *mirroring.IPChainStakeMirrorVerifierPChainStake {
+			return in.stakeData
+		}))
+	if err != nil {
+		return errors.Wrap(err, "mirroringContract.IsActiveStakeMirrored")
+	}
+
+	skipped := 0
+	for i, in := range inputs {
+		if sent[mirrorAttemptKey(in.tx.TxID, in.tx.InputAddress)] {
+			continue
+		}
+		if mirrored[i] {
+			logger.Debug("tx %s already mirrored, skipping", *in.tx.TxID)
+			if err := c.db.CreateMirroringAttempt(ctx, c.newAlreadyMirroredAttempt(in)); err != nil {
+				return errors.Wrap(err, "CreateMirroringAttempt")
+			}
+			skipped++
+			continue
 		}
 
-		if err := c.mirrorTx(&in); err != nil {
+		if err := c.mirrorTx(ctx, in); err != nil {
 			return err
 		}
 	}
 
+	if skipped > 0 {
+		logger.Info("skipped %d of %d txs of epoch %d already mirrored", skipped, len(txs), epochID)
+	}
+
+	// The epoch is processed again until the pending transactions are resolved
+	if pending > 0 {
+		return errors.Errorf("%d mirror transactions of epoch %d are still pending", pending, epochID)
+	}
+
 	return nil
 }
 
-func (c *mirrorCronJob) checkMerkleRoot(tree merkle.Tree, epoch int64) error {
+func mirrorAttemptKey(txID *string, inputAddress string) string {
+	return *txID + "/" + inputAddress
+}
+
+// Resolves the pending attempts of the epoch by the receipts of their
+// transactions. Returns the stakes whose transaction was sent, i.e. which must
+// not be submitted again, and the number of transactions still pending.
+// Attempts without a receipt after mirrorPendingTimeout are rejected, so that
+// their stakes are submitted again.
+func (c *mirrorCronJob) resolvePendingAttempts(
+	ctx context.Context, epoch int64, inputs []*mirrorTxInput,
+) (map[string]bool, int, error) {
+	attempts, err := c.db.FetchPendingMirroringAttempts(ctx, epoch)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "FetchPendingMirroringAttempts")
+	}
+	if len(attempts) == 0 {
+		return nil, 0, nil
+	}
+	byKey := make(map[string]*mirrorTxInput, len(inputs))
+	for _, in := range inputs {
+		byKey[mirrorAttemptKey(in.tx.TxID, in.tx.InputAddress)] = in
+	}
+
+	sent := make(map[string]bool)
+	pending := 0
+	for i := range attempts {
+		attempt := &attempts[i]
+		key := mirrorAttemptKey(&attempt.TxID, attempt.InputAddress)
+		in, ok := byKey[key]
+		if !ok {
+			logger.Warn("pending mirroring attempt of tx %s is not a stake of epoch %d", attempt.TxID, epoch)
+			continue
+		}
+		result, err := c.contracts.MirrorStakeReceipt(ctx, epoch, common.HexToHash(attempt.EthTxHash), in.stakeData, in.merkleProof)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "MirrorStakeReceipt")
+		}
+		switch {
+		case result != nil:
+			attempt.GasUsed = result.gasUsed
+			attempt.Status = database.MirroringAttemptSucceeded
+			if result.reverted {
+				attempt.Status = database.MirroringAttemptReverted
+				attempt.RevertReason = utils.TruncateString(result.revertReason, maxRevertReasonLength)
+			} else {
+				attempt.RevertReason = ""
+			}
+			logger.Info("pending mirroring tx %s of tx %s resolved as %s", attempt.EthTxHash, attempt.TxID, attempt.Status)
+			sent[key] = true
+		case c.now().Sub(attempt.Timestamp) > mirrorPendingTimeout:
+			attempt.Status = database.MirroringAttemptRejected
+			attempt.RevertReason = fmt.Sprintf("not mined within %s", mirrorPendingTimeout)
+			logger.Warn("pending mirroring tx %s of tx %s was dropped", attempt.EthTxHash, attempt.TxID)
+		default:
+			sent[key] = true
+			pending++
+			continue
+		}
+		if err := c.db.UpdateMirroringAttempt(ctx, attempt); err != nil {
+			return nil, 0, errors.Wrap(err, "UpdateMirroringAttempt")
+		}
+	}
+	return sent, pending, nil
+}
+
+func (c *mirrorCronJob) checkMerkleRoot(ctx context.Context, tree merkle.Tree, epoch int64) error {
 	root, err := tree.Root()
 	if err != nil {
 		return err
 	}
 
-	contractRoot, err := c.contracts.GetMerkleRoot(epoch)
+	contractRoot, err := c.contracts.GetMerkleRoot(ctx, epoch)
 	if err != nil {
 		return errors.Wrap(err, "votingContract.GetMerkleRoot")
 	}
@@ -211,24 +443,39 @@ func (c *mirrorCronJob) checkMerkleRoot(tree merkle.Tree, epoch int64) error {
 }
 
 type mirrorTxInput struct {
-	epochID    *big.Int
-	merkleTree merkle.Tree
-	tx         *database.PChainTxData
+	epochID     *big.Int
+	tx          *database.PChainTxData
+	stakeData   *mirroring.IPChainStakeMirrorVerifierPChainStake
+	merkleProof [][32]byte
 }
 
-func (c *mirrorCronJob) mirrorTx(in *mirrorTxInput) error {
-	stakeData, err := staking.ToStakeData(in.tx)
+// Stake data and merkle proof of the transaction, safe for concurrent use
+// since the tree is only read
+func newMirrorTxInput(epochID int64, merkleTree merkle.Tree, tx *database.PChainTxData) (*mirrorTxInput, error) {
+	stakeData, err := staking.ToStakeData(tx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	merkleProof, err := staking.GetMerkleProof(in.merkleTree, in.tx)
+	merkleProof, err := staking.GetMerkleProof(merkleTree, tx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return &mirrorTxInput{
+		epochID:     big.NewInt(epochID),
+		tx:          tx,
+		stakeData:   stakeData,
+		merkleProof: merkleProof,
+	}, nil
+}
+
+func (c *mirrorCronJob) mirrorTx(ctx context.Context, in *mirrorTxInput) error {
 	logger.Debug("mirroring tx %s", *in.tx.TxID)
-	err = c.contracts.MirrorStake(stakeData, merkleProof)
+	result, err := c.contracts.MirrorStake(ctx, in.epochID.Int64(), in.stakeData, in.merkleProof)
+	if dbErr := c.db.CreateMirroringAttempt(ctx, c.newMirroringAttempt(in, result, err)); dbErr != nil {
+		return errors.Wrap(dbErr, "CreateMirroringAttempt")
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "transaction already mirrored") {
 			logger.Info("tx %s already mirrored", *in.tx.TxID)
@@ -258,16 +505,71 @@ func (c *mirrorCronJob) mirrorTx(in *mirrorTxInput) error {
 		return errors.Wrap(err, "mirroringContract.MirrorStake")
 	}
 
+	if result != nil && result.reverted {
+		logger.Warn("mirroring tx %s reverted (eth tx %s)", *in.tx.TxID, result.txHash.Hex())
+	}
+
 	return nil
 }
 
-func (c *mirrorCronJob) reset(firstEpoch int64) error {
+// Stake which was not submitted, since another provider mirrored it. It is not
+// counted as mirrored by this indexer.
+func (c *mirrorCronJob) newAlreadyMirroredAttempt(in *mirrorTxInput) *database.MirroringAttempt {
+	return &database.MirroringAttempt{
+		TxID:         *in.tx.TxID,
+		InputAddress: in.tx.InputAddress,
+		Epoch:        in.epochID.Int64(),
+		Status:       database.MirroringAttemptAlreadyMirrored,
+		Timestamp:    c.now(),
+	}
+}
+
+// Attempt of the mirror stake submission
+func (c *mirrorCronJob) newMirroringAttempt(
+	in *mirrorTxInput, result *mirrorStakeResult, err error,
+) *database.MirroringAttempt {
+	attempt := &database.MirroringAttempt{
+		TxID:         *in.tx.TxID,
+		InputAddress: in.tx.InputAddress,
+		Epoch:        in.epochID.Int64(),
+		Status:       database.MirroringAttemptSucceeded,
+		Timestamp:    c.now(),
+	}
+	if result != nil {
+		attempt.Sender = result.sender.Hex()
+		if result.contract != (common.Address{}) {
+			attempt.Contract = result.contract.Hex()
+		}
+		if result.txHash != (common.Hash{}) {
+			attempt.EthTxHash = result.txHash.Hex()
+		}
+		attempt.GasUsed = result.gasUsed
+		if result.reverted {
+			attempt.Status = database.MirroringAttemptReverted
+			attempt.RevertReason = utils.TruncateString(result.revertReason, maxRevertReasonLength)
+		}
+	}
+	if err != nil {
+		// A transaction which was sent but not mined in time may still be mined
+		if len(attempt.EthTxHash) > 0 {
+			attempt.Status = database.MirroringAttemptPending
+		} else {
+			attempt.Status = database.MirroringAttemptRejected
+		}
+		attempt.RevertReason = utils.TruncateString(err.Error(), maxRevertReasonLength)
+	}
+	return attempt
+}
+
+const maxRevertReasonLength = 256
+
+func (c *mirrorCronJob) reset(ctx context.Context, firstEpoch int64) error {
 	if firstEpoch <= 0 {
 		return nil
 	}
 
 	logger.Info("Resetting mirroring cronjob state to epoch %d", firstEpoch)
-	err := c.db.UpdateJobState(firstEpoch, true)
+	err := c.db.UpdateJobState(ctx, firstEpoch, true)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,235 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"flare-indexer/utils/merkle"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var ErrMirrorRunAborted = errors.New("mirror run aborted")
+
+// Stakes of an epoch to be mirrored by RunMirrorEpoch
+type MirrorRunSummary struct {
+	Epoch   int64
+	TxCount int
+	// Mirroring contract active in the epoch
+	Contract common.Address
+	// Address signing the transactions
+	Sender common.Address
+}
+
+func (s MirrorRunSummary) String() string {
+	return fmt.Sprintf("epoch %d: %d staking transactions to mirroring contract %s, signed by %s",
+		s.Epoch, s.TxCount, s.Contract.Hex(), s.Sender.Hex())
+}
+
+// Mirrors the stakes of the epoch once with the given transact options, e.g.
+// signing on a Ledger (see TransactOptsFromLedger), independently of the
+// mirroring cronjob, whose state is not changed. confirm is called with the
+// summary of the stakes before anything is sent and the run is aborted with
+// ErrMirrorRunAborted if it returns false. The attempts are recorded as for
+// the mirroring cronjob.
+func RunMirrorEpoch(
+	ctx context.Context,
+	ictx indexerctx.IndexerContext,
+	epoch int64,
+	txOpts *bind.TransactOpts,
+	confirm func(summary MirrorRunSummary) bool,
+) error {
+	c, summary, err := newMirrorRun(ictx, epoch, txOpts)
+	if err != nil {
+		return err
+	}
+	return c.runEpoch(ctx, summary, confirm)
+}
+
+// Stake of the preview of a mirror run
+type MirrorPreviewStake struct {
+	TxID         string
+	Type         database.PChainTxType
+	NodeID       string
+	InputAddress string
+	StartTime    time.Time
+	EndTime      time.Time
+	Weight       uint64
+	// Leaf of the stake in the merkle tree of the epoch and its proof
+	Hash  common.Hash
+	Proof []common.Hash
+	// Estimated gas of the mirror transaction, zero if the estimation failed,
+	// e.g. because the stake is already mirrored
+	Gas           uint64
+	EstimateError string
+}
+
+// Stakes which would be mirrored by RunMirrorEpoch
+type MirrorPreview struct {
+	MirrorRunSummary
+	// Root of the stakes and root voted for the epoch, the stakes can only be
+	// mirrored if they are equal
+	MerkleRoot  common.Hash
+	VotedRoot   common.Hash
+	Stakes      []MirrorPreviewStake
+	TotalGas    uint64
+	GasEstimate bool
+}
+
+// Returns the stakes of the epoch with their merkle proofs and the gas of the
+// mirror transactions estimated for the sender of txOpts, nothing is sent
+func PreviewMirrorEpoch(
+	ctx context.Context,
+	ictx indexerctx.IndexerContext,
+	epoch int64,
+	txOpts *bind.TransactOpts,
+) (*MirrorPreview, error) {
+	c, summary, err := newMirrorRun(ictx, epoch, txOpts)
+	if err != nil {
+		return nil, err
+	}
+	return c.previewEpoch(ctx, summary)
+}
+
+func newMirrorRun(
+	ictx indexerctx.IndexerContext,
+	epoch int64,
+	txOpts *bind.TransactOpts,
+) (*mirrorCronJob, MirrorRunSummary, error) {
+	cfg := ictx.Config()
+	if err := checkContractAddresses(cfg, true); err != nil {
+		return nil, MirrorRunSummary{}, err
+	}
+	if err := probeContracts(cfg, ictx.EthClient(), true); err != nil {
+		return nil, MirrorRunSummary{}, err
+	}
+	contracts, err := initMirrorJobContractsWithOpts(cfg, ictx.EthClient(), txOpts, newGasAccounting(ictx))
+	if err != nil {
+		return nil, MirrorRunSummary{}, err
+	}
+	c, err := newMirrorCronjob(cfg, NewMirrorDBGorm(ictx.DB()), contracts)
+	if err != nil {
+		return nil, MirrorRunSummary{}, err
+	}
+	c.events = ictx.Events()
+
+	contract, ok := cfg.ContractAddresses.MirroringContractFor(epoch)
+	if !ok {
+		return nil, MirrorRunSummary{}, fmt.Errorf("no mirroring contract active in epoch %d", epoch)
+	}
+	return c, MirrorRunSummary{Epoch: epoch, Contract: contract, Sender: txOpts.From}, nil
+}
+
+func (c *mirrorCronJob) runEpoch(ctx context.Context, summary MirrorRunSummary, confirm func(summary MirrorRunSummary) bool) error {
+	txs, err := c.getUnmirroredTxs(ctx, summary.Epoch)
+	if err != nil {
+		return err
+	}
+	summary.TxCount = len(txs)
+	if !confirm(summary) {
+		return ErrMirrorRunAborted
+	}
+	if len(txs) == 0 {
+		return c.db.RecordMirroringEpoch(ctx, summary.Epoch, 0)
+	}
+
+	logger.Info("mirroring %d txs of epoch %d", len(txs), summary.Epoch)
+	if err := c.mirrorTxs(ctx, txs, summary.Epoch, false); err != nil {
+		return err
+	}
+	c.events.Publish(events.MirroringCompleted, events.MirroringCompletedData{
+		Epoch:   summary.Epoch,
+		TxCount: len(txs),
+	})
+	return c.db.RecordMirroringEpoch(ctx, summary.Epoch, len(txs))
+}
+
+func (c *mirrorCronJob) previewEpoch(ctx context.Context, summary MirrorRunSummary) (*MirrorPreview, error) {
+	txs, err := c.getUnmirroredTxs(ctx, summary.Epoch)
+	if err != nil {
+		return nil, err
+	}
+	summary.TxCount = len(txs)
+	preview := &MirrorPreview{MirrorRunSummary: summary, GasEstimate: true}
+
+	votedRoot, err := c.contracts.GetMerkleRoot(ctx, summary.Epoch)
+	if err != nil {
+		return nil, errors.Wrap(err, "votingContract.GetMerkleRoot")
+	}
+	preview.VotedRoot = votedRoot
+	if len(txs) == 0 {
+		return preview, nil
+	}
+
+	merkleTree, err := staking.BuildTree(txs)
+	if err != nil {
+		return nil, err
+	}
+	if preview.MerkleRoot, err = merkleTree.Root(); err != nil {
+		return nil, err
+	}
+
+	// The estimation fails anyway if the stakes do not match the voted root
+	estimate := preview.MerkleRoot == preview.VotedRoot
+	for i := range txs {
+		tx := &txs[i]
+		stake, err := c.previewStake(ctx, summary.Epoch, merkleTree, tx, estimate)
+		if err != nil {
+			return nil, fmt.Errorf("tx %s: %w", *tx.TxID, err)
+		}
+		preview.Stakes = append(preview.Stakes, *stake)
+		preview.TotalGas += stake.Gas
+		if len(stake.EstimateError) > 0 {
+			preview.GasEstimate = false
+		}
+	}
+	return preview, nil
+}
+
+func (c *mirrorCronJob) previewStake(
+	ctx context.Context, epoch int64, merkleTree merkle.Tree, tx *database.PChainTxData, estimate bool,
+) (*MirrorPreviewStake, error) {
+	hash, err := staking.HashTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	stakeData, err := staking.ToStakeData(tx)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := staking.GetMerkleProof(merkleTree, tx)
+	if err != nil {
+		return nil, err
+	}
+	stake := &MirrorPreviewStake{
+		TxID:         *tx.TxID,
+		Type:         tx.Type,
+		NodeID:       tx.NodeID,
+		InputAddress: tx.InputAddress,
+		Weight:       tx.Weight,
+		Hash:         hash,
+		Proof:        utils.Map(proof, func(p [32]byte) common.Hash { return p }),
+	}
+	if tx.StartTime != nil {
+		stake.StartTime = *tx.StartTime
+	}
+	if tx.EndTime != nil {
+		stake.EndTime = *tx.EndTime
+	}
+	if !estimate {
+		stake.EstimateError = "merkle root mismatch"
+		return stake, nil
+	}
+	if stake.Gas, err = c.contracts.EstimateMirrorStake(ctx, epoch, stakeData, proof); err != nil {
+		stake.EstimateError = err.Error()
+	}
+	return stake, nil
+}
@@ -0,0 +1,148 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/staking"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorRun(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := epochInfo.GetEndTime(999)
+	txID := "5uZETr5SUKqGJLzFP5BeGxbXU5CFcCBQYPu288eX9R1QDQMjn"
+	tx := database.PChainTxData{
+		PChainTx: database.PChainTx{
+			ChainID:   "costwo",
+			NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+			StartTime: &startTime,
+			EndTime:   &endTime,
+			TxID:      &txID,
+			Type:      database.PChainAddDelegatorTx,
+		},
+		InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+	}
+	txHash, err := staking.HashTransaction(&tx)
+	require.NoError(t, err)
+
+	db := testDB{
+		epochs:   epochInfo,
+		states:   map[string]database.State{mirrorStateName: {NextDBIndex: 1}},
+		txs:      map[int64][]database.PChainTxData{3: {tx}},
+		attempts: make(map[string][]database.MirroringAttempt),
+	}
+	contracts := &testContracts{merkleRoots: map[int64][32]byte{3: txHash}}
+	j, err := newMirrorCronjob(&config.Config{}, db, contracts)
+	require.NoError(t, err)
+	j.epochs = epochInfo
+
+	var summaries []MirrorRunSummary
+	confirm := func(answer bool) func(MirrorRunSummary) bool {
+		return func(s MirrorRunSummary) bool {
+			summaries = append(summaries, s)
+			return answer
+		}
+	}
+	summary := MirrorRunSummary{Epoch: 3, Contract: testContractAddress, Sender: testSender}
+
+	err = j.runEpoch(context.Background(), summary, confirm(false))
+	require.ErrorIs(t, err, ErrMirrorRunAborted)
+	require.Empty(t, contracts.mirroredStakes)
+
+	require.NoError(t, j.runEpoch(context.Background(), summary, confirm(true)))
+	require.Len(t, contracts.mirroredStakes, 1)
+	require.Len(t, db.attempts[txID], 1)
+
+	summary.TxCount = 1
+	require.Equal(t, []MirrorRunSummary{summary, summary}, summaries)
+	// The state of the mirroring cronjob is not changed
+	require.Equal(t, uint64(1), db.states[mirrorStateName].NextDBIndex)
+}
+
+func TestMirrorPreview(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := epochInfo.GetEndTime(999)
+	newTx := func(txID string) database.PChainTxData {
+		return database.PChainTxData{
+			PChainTx: database.PChainTx{
+				ChainID:   "costwo",
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &startTime,
+				EndTime:   &endTime,
+				TxID:      &txID,
+				Type:      database.PChainAddDelegatorTx,
+				Weight:    1000,
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+	}
+	txs := []database.PChainTxData{
+		newTx("5uZETr5SUKqGJLzFP5BeGxbXU5CFcCBQYPu288eX9R1QDQMjn"),
+		newTx("XnfV79XVMyuXbTw8iNreQ9FrUgy9csYBJp1xRscay3oDzhyq8"),
+	}
+	root, err := staking.GetMerkleRoot(txs)
+	require.NoError(t, err)
+
+	db := testDB{
+		epochs:   epochInfo,
+		states:   map[string]database.State{mirrorStateName: {NextDBIndex: 1}},
+		txs:      map[int64][]database.PChainTxData{3: txs},
+		attempts: make(map[string][]database.MirroringAttempt),
+	}
+	alreadyMirrored := errors.New("execution reverted: transaction already mirrored")
+	contracts := &testContracts{
+		merkleRoots: map[int64][32]byte{3: root},
+		mirrorErrors: map[[32]byte]error{
+			parseTxID(t, *txs[1].TxID): alreadyMirrored,
+		},
+	}
+	j, err := newMirrorCronjob(&config.Config{}, db, contracts)
+	require.NoError(t, err)
+	j.epochs = epochInfo
+
+	summary := MirrorRunSummary{Epoch: 3, Contract: testContractAddress, Sender: testSender}
+	preview, err := j.previewEpoch(context.Background(), summary)
+	require.NoError(t, err)
+
+	summary.TxCount = 2
+	require.Equal(t, summary, preview.MirrorRunSummary)
+	require.Equal(t, root, preview.MerkleRoot)
+	require.Equal(t, root, preview.VotedRoot)
+	require.Len(t, preview.Stakes, 2)
+	for i, stake := range preview.Stakes {
+		hash, err := staking.HashTransaction(&txs[i])
+		require.NoError(t, err)
+		require.Equal(t, *txs[i].TxID, stake.TxID)
+		require.Equal(t, hash, stake.Hash)
+		require.Len(t, stake.Proof, 1)
+	}
+	require.Equal(t, preview.Stakes[1].Hash, preview.Stakes[0].Proof[0])
+	require.Equal(t, uint64(250000), preview.Stakes[0].Gas)
+	require.Equal(t, alreadyMirrored.Error(), preview.Stakes[1].EstimateError)
+	require.Equal(t, uint64(250000), preview.TotalGas)
+	require.False(t, preview.GasEstimate)
+
+	// Nothing is sent or recorded
+	require.Empty(t, contracts.mirroredStakes)
+	require.Empty(t, db.attempts)
+
+	// Without a matching voted root, the gas is not estimated
+	contracts.merkleRoots[3] = [32]byte{1}
+	preview, err = j.previewEpoch(context.Background(), summary)
+	require.NoError(t, err)
+	require.Equal(t, "merkle root mismatch", preview.Stakes[0].EstimateError)
+}
+
+func parseTxID(t *testing.T, s string) [32]byte {
+	id, err := ids.FromString(s)
+	require.NoError(t, err)
+	return id
+}
@@ -3,18 +3,25 @@
 package cronjob
 
 import (
+	"context"
+	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
 	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
 	"flare-indexer/utils/contracts/mirroring"
 	"flare-indexer/utils/contracts/voting"
 	"flare-indexer/utils/staking"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
@@ -27,13 +34,13 @@ func NewMirrorDBGorm(db *gorm.DB) mirrorDB {
 	return mirrorDBGorm{db: db}
 }
 
-func (m mirrorDBGorm) FetchState(name string) (database.State, error) {
-	return database.FetchState(m.db, name)
+func (m mirrorDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, m.db, name)
 }
 
-func (m mirrorDBGorm) UpdateJobState(epoch int64, force bool) error {
+func (m mirrorDBGorm) UpdateJobState(ctx context.Context, epoch int64, force bool) error {
 	return m.db.Transaction(func(tx *gorm.DB) error {
-		jobState, err := database.FetchState(tx, mirrorStateName)
+		jobState, err := database.FetchState(ctx, tx, mirrorStateName)
 		if err != nil {
 			return errors.Wrap(err, "database.FetchState")
 		}
@@ -45,79 +52,373 @@ func (m mirrorDBGorm) UpdateJobState(epoch int64, force bool) error {
 
 		jobState.NextDBIndex = uint64(epoch)
 
-		return database.UpdateState(tx, &jobState)
+		return database.UpdateState(ctx, tx, &jobState)
 	})
 }
 
-func (m mirrorDBGorm) GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error) {
-	return database.GetPChainTxsForEpoch(&database.GetPChainTxsForEpochInput{
+func (m mirrorDBGorm) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+	return database.GetPChainTxsForEpoch(ctx, &database.GetPChainTxsForEpochInput{
 		DB:             m.db,
 		StartTimestamp: start,
 		EndTimestamp:   end,
 	})
 }
 
-func (m mirrorDBGorm) GetPChainTx(txID string, address string) (*database.PChainTxData, error) {
-	return database.FetchPChainTxData(m.db, txID, address)
+func (m mirrorDBGorm) GetPChainTx(ctx context.Context, txID string, address string) (*database.PChainTxData, error) {
+	return database.FetchPChainTxData(ctx, m.db, txID, address)
+}
+
+func (m mirrorDBGorm) CreateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error {
+	return database.CreateMirroringAttempt(ctx, m.db, attempt)
+}
+
+func (m mirrorDBGorm) FetchPendingMirroringAttempts(ctx context.Context, epoch int64) ([]database.MirroringAttempt, error) {
+	return database.FetchPendingMirroringAttempts(ctx, m.db, epoch)
+}
+
+func (m mirrorDBGorm) UpdateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error {
+	return database.UpdateMirroringAttempt(ctx, m.db, attempt)
+}
+
+func (m mirrorDBGorm) RecordMirroringEpoch(ctx context.Context, epoch int64, txCount int) error {
+	return database.UpsertMirroringEpoch(ctx, m.db, &database.MirroringEpoch{
+		Epoch:     epoch,
+		TxCount:   txCount,
+		Timestamp: m.db.NowFunc(),
+	})
+}
+
+// Subset of the voting contract binding used by the mirror cronjob
+type mirrorVotingBinding interface {
+	staking.EpochConfigCaller
+	GetMerkleRoot(opts *bind.CallOpts, epochId *big.Int) ([32]byte, error)
 }
 
 type mirrorContractsCChain struct {
-	mirroring *mirroring.Mirroring
+	eth       chain.EthClient
+	addresses config.ContractAddresses
+	// Bindings of the mirroring contracts by address
+	mirroring map[common.Address]mirroringBinding
 	txOpts    *bind.TransactOpts
-	voting    *voting.Voting
+	voting    mirrorVotingBinding
+	gas       *gasAccounting
+	// Maximal duration of a call of the C-chain node (eth_rpc_timeout), a sent
+	// transaction is waited for twice as long to be mined
+	rpcTimeout time.Duration
 }
 
-func initMirrorJobContracts(cfg *config.Config) (mirrorContracts, error) {
-	if cfg.ContractAddresses.Mirroring == (common.Address{}) {
-		return nil, errors.New("mirroring contract address not set")
-	}
-
-	if cfg.ContractAddresses.Voting == (common.Address{}) {
-		return nil, errors.New("voting contract address not set")
+// Contracts signing with the private key of the chain config
+func initMirrorJobContracts(cfg *config.Config, eth chain.EthClient, gas *gasAccounting) (mirrorContracts, error) {
+	privateKey, err := cfg.Chain.GetPrivateKey()
+	if err != nil {
+		return nil, err
 	}
 
-	eth, err := ethclient.Dial(cfg.Chain.EthRPCURL)
+	txOpts, err := TransactOptsFromPrivateKey(privateKey, cfg.Chain.ChainID)
 	if err != nil {
 		return nil, err
 	}
 
-	mirroringContract, err := mirroring.NewMirroring(cfg.ContractAddresses.Mirroring, eth)
-	if err != nil {
+	return initMirrorJobContractsWithOpts(cfg, eth, txOpts, gas)
+}
+
+func initMirrorJobContractsWithOpts(cfg *config.Config, eth chain.EthClient, txOpts *bind.TransactOpts, gas *gasAccounting) (mirrorContracts, error) {
+	if err := checkContractAddresses(cfg, true); err != nil {
 		return nil, err
 	}
 
+	mirroringContracts := make(map[common.Address]mirroringBinding)
+	for _, contract := range cfg.ContractAddresses.MirroringContractsByEpoch() {
+		mirroringContract, err := mirroring.NewMirroring(contract.Address, eth)
+		if err != nil {
+			return nil, err
+		}
+		mirroringContracts[contract.Address] = mirroringContract
+	}
+
 	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, eth)
 	if err != nil {
 		return nil, err
 	}
 
-	privateKey, err := cfg.Chain.GetPrivateKey()
+	contracts := newMirrorContractsCChain(eth, cfg.ContractAddresses, mirroringContracts, votingContract, txOpts)
+	contracts.gas = gas
+	contracts.rpcTimeout = cfg.Chain.GetEthRPCTimeout()
+	return contracts, nil
+}
+
+func newMirrorContractsCChain(
+	eth chain.EthClient,
+	addresses config.ContractAddresses,
+	mirroringContracts map[common.Address]mirroringBinding,
+	votingContract mirrorVotingBinding,
+	txOpts *bind.TransactOpts,
+) *mirrorContractsCChain {
+	return &mirrorContractsCChain{
+		eth:        eth,
+		addresses:  addresses,
+		mirroring:  mirroringContracts,
+		txOpts:     txOpts,
+		voting:     votingContract,
+		rpcTimeout: globalConfig.DefaultEthRPCTimeout,
+	}
+}
+
+func (m mirrorContractsCChain) GetMerkleRoot(ctx context.Context, epoch int64) ([32]byte, error) {
+	return m.voting.GetMerkleRoot(&bind.CallOpts{Context: ctx}, big.NewInt(epoch))
+}
+
+// Mirrors the stake to the mirroring contract active in the epoch
+func (m mirrorContractsCChain) MirrorStake(
+	ctx context.Context,
+	epoch int64,
+	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+	merkleProof [][32]byte,
+) (*mirrorStakeResult, error) {
+	result := &mirrorStakeResult{sender: m.txOpts.From}
+
+	address, ok := m.addresses.MirroringContractFor(epoch)
+	if !ok {
+		return result, errors.Errorf("no mirroring contract active in epoch %d", epoch)
+	}
+	result.contract = address
+
+	sendCtx, cancelSend := context.WithTimeout(ctx, m.rpcTimeout)
+	defer cancelSend()
+	txOpts := *m.txOpts
+	txOpts.Context = sendCtx
+	tx, err := m.mirroring[address].MirrorStake(&txOpts, *stakeData, merkleProof)
 	if err != nil {
-		return nil, err
+		return result, err
 	}
+	result.txHash = tx.Hash()
 
-	txOpts, err := TransactOptsFromPrivateKey(privateKey, cfg.Chain.ChainID)
+	ctx, cancel := context.WithTimeout(ctx, 2*m.rpcTimeout)
+	defer cancel()
+
+	// The transaction was sent, so the result is returned also if it was not
+	// mined in time and the attempt is resolved by MirrorStakeReceipt later
+	receipt, err := bind.WaitMined(ctx, m.eth, tx)
+	if err != nil {
+		return result, errors.Wrap(err, "bind.WaitMined")
+	}
+	m.setReceipt(ctx, result, tx.Data(), receipt)
+	m.gas.record(ctx, mirrorCronjobName, &epoch, tx, result.sender.Hex(), receipt)
+
+	return result, nil
+}
+
+// Outcome of the mirror stake transaction sent earlier, nil if it is not mined
+// yet. The stake and proof are needed to replay a reverted transaction.
+func (m mirrorContractsCChain) MirrorStakeReceipt(
+	ctx context.Context,
+	epoch int64,
+	txHash common.Hash,
+	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+	merkleProof [][32]byte,
+) (*mirrorStakeResult, error) {
+	result := &mirrorStakeResult{sender: m.txOpts.From, txHash: txHash}
+
+	address, ok := m.addresses.MirroringContractFor(epoch)
+	if !ok {
+		return nil, errors.Errorf("no mirroring contract active in epoch %d", epoch)
+	}
+	result.contract = address
+
+	ctx, cancel := context.WithTimeout(ctx, m.rpcTimeout)
+	defer cancel()
+
+	receipt, err := m.eth.TransactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "TransactionReceipt")
+	}
+
+	data, err := packMirrorStake(stakeData, merkleProof)
 	if err != nil {
 		return nil, err
 	}
+	m.setReceipt(ctx, result, data, receipt)
+	return result, nil
+}
 
-	return &mirrorContractsCChain{
-		mirroring: mirroringContract,
-		txOpts:    txOpts,
-		voting:    votingContract,
-	}, nil
+// Sets the outcome of the mined transaction with the given call data to the
+// result, the revert reason is obtained by replaying the call
+func (m mirrorContractsCChain) setReceipt(
+	ctx context.Context, result *mirrorStakeResult, data []byte, receipt *types.Receipt,
+) {
+	result.gasUsed = receipt.GasUsed
+	result.reverted = receipt.Status != types.ReceiptStatusSuccessful
+	if !result.reverted {
+		return
+	}
+	result.revertReason = replayRevertReason(ctx, m.eth, ethereum.CallMsg{
+		From: result.sender,
+		To:   &result.contract,
+		Data: data,
+	}, receipt.BlockNumber)
+}
+
+// Revert reason used if the replay of a reverted transaction does not fail
+const unknownRevertReason = "execution reverted"
+
+// Reason of the revert of the transaction mined in the block, obtained by
+// replaying the call on the state of the parent block. The replay may not see
+// the transactions preceding it in the block, so the reason is best-effort.
+func replayRevertReason(ctx context.Context, eth chain.EthClient, msg ethereum.CallMsg, blockNumber *big.Int) string {
+	var parent *big.Int
+	if blockNumber != nil && blockNumber.Sign() > 0 {
+		parent = new(big.Int).Sub(blockNumber, big.NewInt(1))
+	}
+	_, err := eth.CallContract(ctx, msg, parent)
+	if err == nil {
+		return unknownRevertReason
+	}
+	return decodeRevertReason(err)
 }
 
-func (m mirrorContractsCChain) GetMerkleRoot(epoch int64) ([32]byte, error) {
-	return m.voting.GetMerkleRoot(new(bind.CallOpts), big.NewInt(epoch))
+// Revert reason of the failed call, decoded from the revert data of the error
+// if the node returns it
+func decodeRevertReason(err error) string {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if reason, unpackErr := abi.UnpackRevert(common.FromHex(data)); unpackErr == nil {
+				return reason
+			}
+		}
+	}
+	return err.Error()
 }
 
-func (m mirrorContractsCChain) MirrorStake(
+func packMirrorStake(
+	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+	merkleProof [][32]byte,
+) ([]byte, error) {
+	mirroringABI, err := mirroring.MirroringMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	data, err := mirroringABI.Pack("mirrorStake", *stakeData, merkleProof)
+	if err != nil {
+		return nil, errors.Wrap(err, "abi.Pack")
+	}
+	return data, nil
+}
+
+func (m mirrorContractsCChain) EstimateMirrorStake(
+	ctx context.Context,
+	epoch int64,
 	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
 	merkleProof [][32]byte,
-) error {
-	_, err := m.mirroring.MirrorStake(m.txOpts, *stakeData, merkleProof)
-	return err
+) (uint64, error) {
+	address, ok := m.addresses.MirroringContractFor(epoch)
+	if !ok {
+		return 0, errors.Errorf("no mirroring contract active in epoch %d", epoch)
+	}
+	data, err := packMirrorStake(stakeData, merkleProof)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.rpcTimeout)
+	defer cancel()
+
+	return m.eth.EstimateGas(ctx, ethereum.CallMsg{From: m.txOpts.From, To: &address, Data: data})
+}
+
+// Number of isActiveStakeMirrored calls sent in one JSON-RPC batch
+const mirroredStakesBatchSize = 100
+
+// The calls are sent in JSON-RPC batches if the eth client supports them, each
+// batch is limited by the timeout of a single call of the client
+func (m mirrorContractsCChain) MirroredStakes(
+	ctx context.Context,
+	epoch int64,
+	stakes []*mirroring.IPChainStakeMirrorVerifierPChainStake,
+) ([]bool, error) {
+	address, ok := m.addresses.MirroringContractFor(epoch)
+	if !ok {
+		return nil, errors.Errorf("no mirroring contract active in epoch %d", epoch)
+	}
+
+	batch, ok := m.eth.(chain.BatchCaller)
+	if !ok {
+		opts := &bind.CallOpts{Context: ctx}
+		mirrored := make([]bool, len(stakes))
+		for i, stake := range stakes {
+			var err error
+			mirrored[i], err = m.mirroring[address].IsActiveStakeMirrored(opts, stake.TxId, stake.InputAddress)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return mirrored, nil
+	}
+
+	mirroringABI, err := mirroring.MirroringMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	mirrored := make([]bool, 0, len(stakes))
+	for start := 0; start < len(stakes); start += mirroredStakesBatchSize {
+		end := start + mirroredStakesBatchSize
+		if end > len(stakes) {
+			end = len(stakes)
+		}
+		results, err := batchIsActiveStakeMirrored(ctx, batch, mirroringABI, address, stakes[start:end])
+		if err != nil {
+			return nil, err
+		}
+		mirrored = append(mirrored, results...)
+	}
+	return mirrored, nil
+}
+
+func batchIsActiveStakeMirrored(
+	ctx context.Context,
+	batch chain.BatchCaller,
+	mirroringABI *abi.ABI,
+	address common.Address,
+	stakes []*mirroring.IPChainStakeMirrorVerifierPChainStake,
+) ([]bool, error) {
+	elems := make([]rpc.BatchElem, len(stakes))
+	results := make([]hexutil.Bytes, len(stakes))
+	for i, stake := range stakes {
+		data, err := mirroringABI.Pack("isActiveStakeMirrored", stake.TxId, stake.InputAddress)
+		if err != nil {
+			return nil, errors.Wrap(err, "abi.Pack")
+		}
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{map[string]interface{}{"to": address, "data": hexutil.Bytes(data)}, "latest"},
+			Result: &results[i],
+		}
+	}
+	if err := batch.BatchCallContext(ctx, elems); err != nil {
+		return nil, err
+	}
+	mirrored := make([]bool, len(stakes))
+	for i := range elems {
+		if elems[i].Error != nil {
+			return nil, elems[i].Error
+		}
+		values, err := mirroringABI.Unpack("isActiveStakeMirrored", results[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "abi.Unpack")
+		}
+		if len(values) != 1 {
+			return nil, errors.Errorf("isActiveStakeMirrored returned %d values", len(values))
+		}
+		value, ok := values[0].(bool)
+		if !ok {
+			return nil, errors.Errorf("isActiveStakeMirrored returned %T", values[0])
+		}
+		mirrored[i] = value
+	}
+	return mirrored, nil
 }
 
 func (m mirrorContractsCChain) EpochConfig() (start time.Time, period time.Duration, err error) {
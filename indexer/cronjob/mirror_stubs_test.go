@@ -0,0 +1,290 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/chain/mocks"
+	"flare-indexer/utils/contracts/mirroring"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var testSender = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+func TestMirrorStakeReceipt(t *testing.T) {
+	for _, status := range []uint64{types.ReceiptStatusSuccessful, types.ReceiptStatusFailed} {
+		ctrl := gomock.NewController(t)
+		eth := mocks.NewMockEthClient(ctrl)
+		binding := NewMockmirroringBinding(ctrl)
+
+		tx := types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 300000})
+		binding.EXPECT().MirrorStake(gomock.Any(), gomock.Any(), gomock.Any()).Return(tx, nil)
+		eth.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(&types.Receipt{
+			Status:  status,
+			GasUsed: 123456,
+			TxHash:  tx.Hash(),
+		}, nil)
+
+		if status == types.ReceiptStatusFailed {
+			eth.EXPECT().CallContract(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, testRevertError("staking already ended"))
+		}
+
+		contracts := newTestMirrorContractsCChain(eth, binding)
+		result, err := contracts.MirrorStake(context.Background(), 1, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, testSender, result.sender)
+		require.Equal(t, testContractAddress, result.contract)
+		require.Equal(t, tx.Hash(), result.txHash)
+		require.Equal(t, uint64(123456), result.gasUsed)
+		require.Equal(t, status == types.ReceiptStatusFailed, result.reverted)
+		if result.reverted {
+			require.Equal(t, "staking already ended", result.revertReason)
+		}
+	}
+}
+
+func TestMirrorStakeNotMined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	binding := NewMockmirroringBinding(ctrl)
+
+	contracts := newTestMirrorContractsCChain(eth, binding)
+	txHash := common.HexToHash("0x01")
+	eth.EXPECT().TransactionReceipt(gomock.Any(), txHash).Return(nil, ethereum.NotFound)
+	result, err := contracts.MirrorStakeReceipt(context.Background(), 1, txHash, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.NoError(t, err)
+	require.Nil(t, result)
+
+	eth.EXPECT().TransactionReceipt(gomock.Any(), txHash).Return(&types.Receipt{
+		Status:  types.ReceiptStatusSuccessful,
+		GasUsed: 123456,
+		TxHash:  txHash,
+	}, nil)
+	result, err = contracts.MirrorStakeReceipt(context.Background(), 1, txHash, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, txHash, result.txHash)
+	require.Equal(t, uint64(123456), result.gasUsed)
+	require.False(t, result.reverted)
+}
+
+func TestDecodeRevertReason(t *testing.T) {
+	require.Equal(t, "transaction already mirrored", decodeRevertReason(testRevertError("transaction already mirrored")))
+	require.Equal(t, "connection refused", decodeRevertReason(errors.New("connection refused")))
+}
+
+// Error of a reverted call with the ABI-encoded Error(string) revert data, as
+// returned by the node
+type testRevertError string
+
+func (e testRevertError) Error() string {
+	return "execution reverted: " + string(e)
+}
+
+func (e testRevertError) ErrorData() interface{} {
+	stringType, _ := abi.NewType("string", "", nil)
+	data, _ := abi.Arguments{{Type: stringType}}.Pack(string(e))
+	// Selector of Error(string)
+	return hexutil.Encode(append([]byte{0x08, 0xc3, 0x79, 0xa0}, data...))
+}
+
+func TestMirrorStakeNotSent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl) // no calls expected
+	binding := NewMockmirroringBinding(ctrl)
+
+	binding.EXPECT().MirrorStake(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("execution reverted: staking data invalid"))
+
+	contracts := newTestMirrorContractsCChain(eth, binding)
+	result, err := contracts.MirrorStake(context.Background(), 1, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.Error(t, err)
+	require.Equal(t, testSender, result.sender)
+	require.Equal(t, common.Hash{}, result.txHash)
+}
+
+func TestMirrorStakeContractVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	oldBinding := NewMockmirroringBinding(ctrl)
+	newBinding := NewMockmirroringBinding(ctrl)
+	newAddress := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	addresses := config.ContractAddresses{
+		MirroringContracts: []config.MirroringContract{
+			{Address: testContractAddress, FirstEpoch: 10},
+			{Address: newAddress, FirstEpoch: 20},
+		},
+	}
+	contracts := newMirrorContractsCChain(eth, addresses, map[common.Address]mirroringBinding{
+		testContractAddress: oldBinding,
+		newAddress:          newBinding,
+	}, nil, &bind.TransactOpts{From: testSender})
+
+	notMirrored := errors.New("execution reverted: staking data invalid")
+	oldBinding.EXPECT().MirrorStake(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, notMirrored)
+	result, err := contracts.MirrorStake(context.Background(), 19, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.ErrorIs(t, err, notMirrored)
+	require.Equal(t, testContractAddress, result.contract)
+
+	newBinding.EXPECT().MirrorStake(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, notMirrored)
+	result, err = contracts.MirrorStake(context.Background(), 20, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.ErrorIs(t, err, notMirrored)
+	require.Equal(t, newAddress, result.contract)
+
+	// Epochs before the activation of the first contract are not mirrored
+	_, err = contracts.MirrorStake(context.Background(), 9, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.Error(t, err)
+}
+
+func TestEstimateMirrorStake(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	binding := NewMockmirroringBinding(ctrl) // the estimation sends nothing
+
+	stakeData := &mirroring.IPChainStakeMirrorVerifierPChainStake{TxId: [32]byte{1}, Weight: 1000}
+	proof := [][32]byte{{2}}
+	mirroringABI, err := mirroring.MirroringMetaData.GetAbi()
+	require.NoError(t, err)
+	data, err := mirroringABI.Pack("mirrorStake", *stakeData, proof)
+	require.NoError(t, err)
+	eth.EXPECT().EstimateGas(gomock.Any(), ethereum.CallMsg{From: testSender, To: &testContractAddress, Data: data}).
+		Return(uint64(180000), nil)
+
+	contracts := newTestMirrorContractsCChain(eth, binding)
+	gas, err := contracts.EstimateMirrorStake(context.Background(), 1, stakeData, proof)
+	require.NoError(t, err)
+	require.Equal(t, uint64(180000), gas)
+}
+
+// Calls of the node are bounded by the context of the caller and by the RPC
+// timeout of the chain config
+func TestMirrorStakeContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	binding := NewMockmirroringBinding(ctrl)
+
+	contracts := newTestMirrorContractsCChain(eth, binding)
+	contracts.rpcTimeout = time.Second
+
+	eth.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			require.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+			return 0, ctx.Err()
+		}).Times(2)
+	_, err := contracts.EstimateMirrorStake(context.Background(), 1, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.NoError(t, err)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = contracts.EstimateMirrorStake(cancelled, 1, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The transaction is sent with the context of the caller
+	binding.EXPECT().MirrorStake(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(opts *bind.TransactOpts, stakeData mirroring.IPChainStakeMirrorVerifierPChainStake, merkleProof [][32]byte) (*types.Transaction, error) {
+			return nil, opts.Context.Err()
+		})
+	_, err = contracts.MirrorStake(cancelled, 1, &mirroring.IPChainStakeMirrorVerifierPChainStake{}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, contracts.txOpts.Context)
+}
+
+func TestMirroredStakes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	binding := NewMockmirroringBinding(ctrl)
+
+	stakes := []*mirroring.IPChainStakeMirrorVerifierPChainStake{
+		{TxId: [32]byte{1}, InputAddress: [20]byte{10}},
+		{TxId: [32]byte{2}, InputAddress: [20]byte{20}},
+	}
+	binding.EXPECT().IsActiveStakeMirrored(gomock.Any(), [32]byte{1}, [20]byte{10}).Return(false, nil)
+	binding.EXPECT().IsActiveStakeMirrored(gomock.Any(), [32]byte{2}, [20]byte{20}).Return(true, nil)
+
+	contracts := newTestMirrorContractsCChain(eth, binding)
+	mirrored, err := contracts.MirroredStakes(context.Background(), 1, stakes)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true}, mirrored)
+
+	binding.EXPECT().IsActiveStakeMirrored(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(false, errors.New("connection refused"))
+	_, err = contracts.MirroredStakes(context.Background(), 1, stakes)
+	require.Error(t, err)
+}
+
+func TestMirroredStakesBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := &testBatchEthClient{MockEthClient: mocks.NewMockEthClient(ctrl), mirrored: map[[32]byte]bool{{2}: true}}
+	binding := NewMockmirroringBinding(ctrl) // no calls through the binding
+
+	stakes := make([]*mirroring.IPChainStakeMirrorVerifierPChainStake, mirroredStakesBatchSize+1)
+	for i := range stakes {
+		stakes[i] = &mirroring.IPChainStakeMirrorVerifierPChainStake{TxId: [32]byte{byte(i)}}
+	}
+
+	contracts := newTestMirrorContractsCChain(eth, binding)
+	mirrored, err := contracts.MirroredStakes(context.Background(), 1, stakes)
+	require.NoError(t, err)
+	require.Len(t, mirrored, len(stakes))
+	for i := range mirrored {
+		require.Equal(t, i == 2, mirrored[i])
+	}
+	require.Equal(t, []int{mirroredStakesBatchSize, 1}, eth.batches)
+}
+
+// Eth client answering the batched isActiveStakeMirrored calls
+type testBatchEthClient struct {
+	*mocks.MockEthClient
+	mirrored map[[32]byte]bool
+	batches  []int
+}
+
+func (c *testBatchEthClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	mirroringABI, err := mirroring.MirroringMetaData.GetAbi()
+	if err != nil {
+		return err
+	}
+	c.batches = append(c.batches, len(b))
+	for _, elem := range b {
+		call := elem.Args[0].(map[string]interface{})
+		if call["to"] != testContractAddress {
+			return errors.New("unexpected contract")
+		}
+		data := call["data"].(hexutil.Bytes)
+		args, err := mirroringABI.Methods["isActiveStakeMirrored"].Inputs.Unpack(data[4:])
+		if err != nil {
+			return err
+		}
+		result, err := mirroringABI.Methods["isActiveStakeMirrored"].Outputs.Pack(c.mirrored[args[0].([32]byte)])
+		if err != nil {
+			return err
+		}
+		*elem.Result.(*hexutil.Bytes) = result
+	}
+	return nil
+}
+
+// Contracts with a single mirroring contract at testContractAddress
+func newTestMirrorContractsCChain(eth chain.EthClient, binding mirroringBinding) *mirrorContractsCChain {
+	return newMirrorContractsCChain(eth, config.ContractAddresses{Mirroring: testContractAddress},
+		map[common.Address]mirroringBinding{testContractAddress: binding}, nil, &bind.TransactOpts{From: testSender})
+}
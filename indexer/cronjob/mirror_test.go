@@ -4,10 +4,12 @@
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
 	"flare-indexer/indexer/pchain"
+	"flare-indexer/utils"
 	"flare-indexer/utils/contracts/mirroring"
 	"flare-indexer/utils/staking"
 	"testing"
@@ -77,6 +79,11 @@ func TestOneTransaction(t *testing.T) {
 	db := testMirror(t, txs, contracts)
 
 	require.Equal(t, db.states[mirrorStateName].NextDBIndex, uint64(4))
+
+	attempts := db.attempts[txid]
+	require.Len(t, attempts, 1)
+	require.Equal(t, database.MirroringAttemptSucceeded, attempts[0].Status)
+	require.NotEmpty(t, attempts[0].EthTxHash)
 }
 
 func TestMultipleTransactionsInEpoch(t *testing.T) {
@@ -122,6 +129,104 @@ func TestMultipleTransactionsInEpoch(t *testing.T) {
 	db := testMirror(t, txsMap, contracts)
 
 	require.Equal(t, db.states[mirrorStateName].NextDBIndex, uint64(4))
+	// Epochs without stakes are recorded as processed too
+	require.Equal(t, map[int64]int{0: 0, 1: 0, 2: 0, 3: 3}, db.processed)
+}
+
+func TestSkipMirroredStakes(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := epochInfo.GetEndTime(999)
+
+	txIDs := []string{
+		"XnfV79XVMyuXbTw8iNreQ9FrUgy9csYBJp1xRscay3oDzhyq8",
+		"nsPmyQbm4oo77jyykxbjf7s4Zp4urNptkyAouxVWZ2EB2kw1z",
+		"2p32tpqNrfzP3SStbP9bQGHZtJkCxjV3iHNssVnkcpUWxHMSuj",
+	}
+	txs := make([]database.PChainTxData, len(txIDs))
+	for i := range txIDs {
+		txs[i] = database.PChainTxData{
+			PChainTx: database.PChainTx{
+				ChainID:   "costwo",
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &startTime,
+				EndTime:   &endTime,
+				TxID:      &txIDs[i],
+				Type:      database.PChainAddDelegatorTx,
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+	}
+
+	mirroredID, err := ids.FromString(txIDs[1])
+	require.NoError(t, err)
+
+	contracts := testContracts{
+		merkleRoots: map[int64][32]byte{
+			3: common.HexToHash("b3ec965b802c71f9058d2ed4d80bdf5af902a3741a75221992c5eb2f879a116c"),
+		},
+		alreadyMirrored: map[[32]byte]bool{mirroredID: true},
+	}
+
+	// The snapshot has only the stakes of the other two transactions
+	db := testMirror(t, map[int64][]database.PChainTxData{3: txs}, contracts)
+
+	require.Equal(t, uint64(4), db.states[mirrorStateName].NextDBIndex)
+	require.Equal(t, 3, db.processed[3])
+	for i, txID := range txIDs {
+		attempts := db.attempts[txID]
+		require.Len(t, attempts, 1)
+		if i == 1 {
+			require.Equal(t, database.MirroringAttemptAlreadyMirrored, attempts[0].Status)
+		} else {
+			require.Equal(t, database.MirroringAttemptSucceeded, attempts[0].Status)
+		}
+	}
+	// No C-chain transaction is sent for the mirrored stake
+	require.Empty(t, db.attempts[txIDs[1]][0].EthTxHash)
+	require.NotEmpty(t, db.attempts[txIDs[0]][0].EthTxHash)
+}
+
+func TestMirrorTxsOrder(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := epochInfo.GetEndTime(999)
+
+	txs := make([]database.PChainTxData, 200)
+	for i := range txs {
+		txID := ids.GenerateTestID().String()
+		txs[i] = database.PChainTxData{
+			PChainTx: database.PChainTx{
+				ChainID:   "costwo",
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &startTime,
+				EndTime:   &endTime,
+				TxID:      &txID,
+				Type:      database.PChainAddDelegatorTx,
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+	}
+	root, err := staking.GetMerkleRoot(txs)
+	require.NoError(t, err)
+
+	db := testDB{attempts: make(map[string][]database.MirroringAttempt)}
+	contracts := &testContracts{merkleRoots: map[int64][32]byte{3: root}}
+	j := mirrorCronJob{db: db, contracts: contracts}
+	require.NoError(t, j.mirrorTxs(context.Background(), txs, 3, false))
+
+	// The stakes are submitted in the order of the transactions, each with the
+	// proof of its own leaf
+	require.Len(t, contracts.mirroredStakes, len(txs))
+	tree, err := staking.BuildTree(txs)
+	require.NoError(t, err)
+	for i, stake := range contracts.mirroredStakes {
+		txID, err := ids.FromString(*txs[i].TxID)
+		require.NoError(t, err)
+		require.Equal(t, [32]byte(txID), stake.stakeData.TxId)
+
+		proof, err := staking.GetMerkleProof(tree, &txs[i])
+		require.NoError(t, err)
+		require.Equal(t, proof, stake.merkleProof)
+	}
 }
 
 func TestMultipleTransactionsInSeparateEpochs(t *testing.T) {
@@ -170,6 +275,7 @@ func TestMultipleTransactionsInSeparateEpochs(t *testing.T) {
 	db := testMirror(t, txsMap, contracts)
 
 	require.Equal(t, db.states[mirrorStateName].NextDBIndex, uint64(4))
+	require.Equal(t, map[int64]int{0: 1, 1: 1, 2: 1, 3: 0}, db.processed)
 }
 
 func TestAlreadyMirrored(t *testing.T) {
@@ -222,6 +328,138 @@ func testMirrorErrors(t *testing.T, errorMsg string) {
 	db := testMirror(t, txs, contracts)
 
 	require.Equal(t, db.states[mirrorStateName].NextDBIndex, uint64(4))
+
+	attempts := db.attempts[txid]
+	require.Len(t, attempts, 1)
+	require.Equal(t, database.MirroringAttemptRejected, attempts[0].Status)
+	require.Equal(t, errorMsg, attempts[0].RevertReason)
+	require.Equal(t, int64(3), attempts[0].Epoch)
+}
+
+func TestPendingMirrorTx(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := epochInfo.GetEndTime(999)
+
+	txid := "5uZETr5SUKqGJLzFP5BeGxbXU5CFcCBQYPu288eX9R1QDQMjn"
+	tx := database.PChainTxData{
+		PChainTx: database.PChainTx{
+			ChainID:   "costwo",
+			NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+			StartTime: &startTime,
+			EndTime:   &endTime,
+			TxID:      &txid,
+			Type:      database.PChainAddDelegatorTx,
+		},
+		InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+	}
+	txHash, err := staking.HashTransaction(&tx)
+	require.NoError(t, err)
+	txidBytes, err := ids.FromString(txid)
+	require.NoError(t, err)
+	ethTxHash := common.BytesToHash(txidBytes[:])
+
+	db := testDB{
+		epochs: epochInfo,
+		states: map[string]database.State{
+			mirrorStateName:        {},
+			addressBinderStateName: {NextDBIndex: 4},
+		},
+		txs:      map[int64][]database.PChainTxData{3: {tx}},
+		attempts: make(map[string][]database.MirroringAttempt),
+	}
+	contracts := &testContracts{
+		merkleRoots: map[int64][32]byte{3: txHash},
+		notMined:    map[[32]byte]bool{txidBytes: true},
+		receipts:    make(map[common.Hash]*mirrorStakeResult),
+	}
+	now := epochInfo.GetEndTime(999)
+	j := mirrorCronJob{
+		db:        db,
+		contracts: contracts,
+		epochCronjob: epochCronjob{
+			enabled: true,
+			epochs:  epochInfo,
+			clock:   utils.NewFixedClock(now),
+		},
+	}
+
+	// The transaction is sent but not mined in time
+	require.Error(t, j.Call(context.Background()))
+	require.Len(t, db.attempts[txid], 1)
+	require.Equal(t, database.MirroringAttemptPending, db.attempts[txid][0].Status)
+	require.Equal(t, ethTxHash.Hex(), db.attempts[txid][0].EthTxHash)
+	require.Equal(t, uint64(0), db.states[mirrorStateName].NextDBIndex)
+
+	// Without a receipt the stake is not submitted again
+	contracts.notMined = nil
+	require.Error(t, j.Call(context.Background()))
+	require.Len(t, db.attempts[txid], 1)
+	require.Empty(t, contracts.mirroredStakes)
+
+	// The receipt resolves the attempt
+	contracts.receipts[ethTxHash] = &mirrorStakeResult{txHash: ethTxHash, gasUsed: 100000}
+	require.NoError(t, j.Call(context.Background()))
+	require.Len(t, db.attempts[txid], 1)
+	require.Equal(t, database.MirroringAttemptSucceeded, db.attempts[txid][0].Status)
+	require.Equal(t, uint64(100000), db.attempts[txid][0].GasUsed)
+	require.Empty(t, db.attempts[txid][0].RevertReason)
+	require.Empty(t, contracts.mirroredStakes)
+	require.Equal(t, uint64(4), db.states[mirrorStateName].NextDBIndex)
+}
+
+func TestDroppedMirrorTx(t *testing.T) {
+	startTime := epochInfo.GetStartTime(3)
+	endTime := epochInfo.GetEndTime(999)
+
+	txid := "5uZETr5SUKqGJLzFP5BeGxbXU5CFcCBQYPu288eX9R1QDQMjn"
+	tx := database.PChainTxData{
+		PChainTx: database.PChainTx{
+			ChainID:   "costwo",
+			NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+			StartTime: &startTime,
+			EndTime:   &endTime,
+			TxID:      &txid,
+			Type:      database.PChainAddDelegatorTx,
+		},
+		InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+	}
+	txHash, err := staking.HashTransaction(&tx)
+	require.NoError(t, err)
+
+	now := epochInfo.GetEndTime(999)
+	db := testDB{
+		epochs: epochInfo,
+		states: map[string]database.State{
+			mirrorStateName:        {},
+			addressBinderStateName: {NextDBIndex: 4},
+		},
+		txs: map[int64][]database.PChainTxData{3: {tx}},
+		attempts: map[string][]database.MirroringAttempt{txid: {{
+			TxID:         txid,
+			InputAddress: tx.InputAddress,
+			Epoch:        3,
+			EthTxHash:    common.HexToHash("0x01").Hex(),
+			Status:       database.MirroringAttemptPending,
+			Timestamp:    now.Add(-2 * mirrorPendingTimeout),
+		}}},
+	}
+	contracts := &testContracts{merkleRoots: map[int64][32]byte{3: txHash}}
+	j := mirrorCronJob{
+		db:        db,
+		contracts: contracts,
+		epochCronjob: epochCronjob{
+			enabled: true,
+			epochs:  epochInfo,
+			clock:   utils.NewFixedClock(now),
+		},
+	}
+
+	// The dropped transaction is rejected and the stake submitted again
+	require.NoError(t, j.Call(context.Background()))
+	require.Len(t, db.attempts[txid], 2)
+	require.Equal(t, database.MirroringAttemptRejected, db.attempts[txid][0].Status)
+	require.Equal(t, database.MirroringAttemptSucceeded, db.attempts[txid][1].Status)
+	require.Len(t, contracts.mirroredStakes, 1)
 }
 
 func testMirror(
@@ -243,7 +481,9 @@ func testMirror(
 				NextDBIndex: 4,
 			},
 		},
-		txs: txs,
+		txs:       txs,
+		attempts:  make(map[string][]database.MirroringAttempt),
+		processed: make(map[int64]int),
 	}
 
 	j := mirrorCronJob{
@@ -255,7 +495,7 @@ func testMirror(
 		},
 	}
 
-	err := j.Call()
+	err := j.Call(context.Background())
 	require.NoError(t, err)
 
 	cupaloy.SnapshotT(t, contracts.mirroredStakes)
@@ -264,12 +504,15 @@ func testMirror(
 }
 
 type testDB struct {
-	epochs staking.EpochInfo
-	states map[string]database.State
-	txs    map[int64][]database.PChainTxData
+	epochs   staking.EpochInfo
+	states   map[string]database.State
+	txs      map[int64][]database.PChainTxData
+	attempts map[string][]database.MirroringAttempt
+	// Eligible stakes of the processed epochs, not recorded if nil
+	processed map[int64]int
 }
 
-func (db testDB) FetchState(name string) (database.State, error) {
+func (db testDB) FetchState(ctx context.Context, name string) (database.State, error) {
 	state, ok := db.states[name]
 	if !ok {
 		return state, errors.New("not found")
@@ -278,7 +521,7 @@ func (db testDB) FetchState(name string) (database.State, error) {
 	return state, nil
 }
 
-func (db testDB) UpdateJobState(epoch int64, force bool) error {
+func (db testDB) UpdateJobState(ctx context.Context, epoch int64, force bool) error {
 	db.states[mirrorStateName] = database.State{
 		Name:        mirrorStateName,
 		NextDBIndex: uint64(epoch),
@@ -286,19 +529,93 @@ func (db testDB) UpdateJobState(epoch int64, force bool) error {
 	return nil
 }
 
-func (db testDB) GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error) {
-	epoch := db.epochs.GetEpochIndex(start)
-	return db.txs[epoch], nil
+// Transactions of the epochs in [start, end)
+func (db testDB) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+	first, last := db.epochs.GetEpochIndex(start), db.epochs.GetEpochIndex(end)-1
+	if first == last {
+		return db.txs[first], nil
+	}
+	var txs []database.PChainTxData
+	for epoch, epochTxs := range db.txs {
+		if epoch >= first && epoch <= last {
+			txs = append(txs, epochTxs...)
+		}
+	}
+	return txs, nil
 }
 
-func (db testDB) GetPChainTx(txID string, address string) (*database.PChainTxData, error) {
+func (db testDB) GetPChainTx(ctx context.Context, txID string, address string) (*database.PChainTxData, error) {
 	return nil, nil
 }
 
+func (db testDB) CreateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error {
+	db.attempts[attempt.TxID] = append(db.attempts[attempt.TxID], *attempt)
+	return nil
+}
+
+func (db testDB) RecordMirroringEpoch(ctx context.Context, epoch int64, txCount int) error {
+	if db.processed != nil {
+		db.processed[epoch] = txCount
+	}
+	return nil
+}
+
+func (db testDB) FetchPendingMirroringAttempts(ctx context.Context, epoch int64) ([]database.MirroringAttempt, error) {
+	var attempts []database.MirroringAttempt
+	for _, txAttempts := range db.attempts {
+		for _, a := range txAttempts {
+			if a.Epoch == epoch && a.Status == database.MirroringAttemptPending {
+				attempts = append(attempts, a)
+			}
+		}
+	}
+	return attempts, nil
+}
+
+func (db testDB) UpdateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error {
+	attempts := db.attempts[attempt.TxID]
+	for i := range attempts {
+		if attempts[i].ID == attempt.ID {
+			attempts[i] = *attempt
+		}
+	}
+	return nil
+}
+
+func (db testDB) FetchMirroredAttempts(ctx context.Context, txIDs []string) ([]database.MirroringAttempt, error) {
+	var attempts []database.MirroringAttempt
+	for _, txID := range txIDs {
+		for _, a := range db.attempts[txID] {
+			if a.Status == database.MirroringAttemptSucceeded || a.Status == database.MirroringAttemptAlreadyMirrored {
+				attempts = append(attempts, a)
+			}
+		}
+	}
+	return attempts, nil
+}
+
+func (db testDB) FetchSucceededMirroringAttempts(ctx context.Context, txIDs []string) ([]database.MirroringAttempt, error) {
+	var attempts []database.MirroringAttempt
+	for _, txID := range txIDs {
+		for _, a := range db.attempts[txID] {
+			if a.Status == database.MirroringAttemptSucceeded {
+				attempts = append(attempts, a)
+			}
+		}
+	}
+	return attempts, nil
+}
+
 type testContracts struct {
 	merkleRoots    map[int64][32]byte
 	mirroredStakes []mirrorStakeInput
 	mirrorErrors   map[[32]byte]error
+	// Stakes already mirrored by another provider
+	alreadyMirrored map[[32]byte]bool
+	// Stakes whose transaction is sent but not mined in time
+	notMined map[[32]byte]bool
+	// Outcomes of the transactions sent earlier
+	receipts map[common.Hash]*mirrorStakeResult
 }
 
 type mirrorStakeInput struct {
@@ -306,26 +623,66 @@ type mirrorStakeInput struct {
 	merkleProof [][32]byte
 }
 
-func (c testContracts) GetMerkleRoot(epoch int64) ([32]byte, error) {
+func (c testContracts) GetMerkleRoot(ctx context.Context, epoch int64) ([32]byte, error) {
 	return c.merkleRoots[epoch], nil
 }
 
 func (c *testContracts) MirrorStake(
+	ctx context.Context,
+	epoch int64,
 	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
 	merkleProof [][32]byte,
-) error {
+) (*mirrorStakeResult, error) {
 	if err := c.mirrorErrors[stakeData.TxId]; err != nil {
-		return err
+		return &mirrorStakeResult{}, err
+	}
+	if c.notMined[stakeData.TxId] {
+		return &mirrorStakeResult{txHash: common.BytesToHash(stakeData.TxId[:])},
+			errors.New("bind.WaitMined: context deadline exceeded")
 	}
 
 	c.mirroredStakes = append(c.mirroredStakes, mirrorStakeInput{
 		stakeData:   stakeData,
 		merkleProof: merkleProof,
 	})
-	return nil
+	return &mirrorStakeResult{txHash: common.BytesToHash(stakeData.TxId[:])}, nil
+}
+
+func (c *testContracts) MirrorStakeReceipt(
+	ctx context.Context,
+	epoch int64,
+	txHash common.Hash,
+	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+	merkleProof [][32]byte,
+) (*mirrorStakeResult, error) {
+	return c.receipts[txHash], nil
+}
+
+func (c *testContracts) EstimateMirrorStake(
+	ctx context.Context,
+	epoch int64,
+	stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake,
+	merkleProof [][32]byte,
+) (uint64, error) {
+	if err := c.mirrorErrors[stakeData.TxId]; err != nil {
+		return 0, err
+	}
+	return 250000, nil
+}
+
+func (c testContracts) MirroredStakes(
+	ctx context.Context,
+	epoch int64,
+	stakes []*mirroring.IPChainStakeMirrorVerifierPChainStake,
+) ([]bool, error) {
+	mirrored := make([]bool, len(stakes))
+	for i, stake := range stakes {
+		mirrored[i] = c.alreadyMirrored[stake.TxId]
+	}
+	return mirrored, nil
 }
 
-func (c testContracts) IsAddressRegistered(address string) (bool, error) {
+func (c testContracts) IsAddressRegistered(ctx context.Context, address string) (bool, error) {
 	return true, nil
 }
 
@@ -336,3 +693,44 @@ func (c testContracts) RegisterPublicKey(publicKey crypto.PublicKey) error {
 func (c testContracts) EpochConfig() (time.Time, time.Duration, error) {
 	return epochInfo.Start, epochInfo.Period, nil
 }
+
+func TestMirrorBuffer(t *testing.T) {
+	db := testDB{
+		states: map[string]database.State{
+			mirrorStateName:        {NextDBIndex: 3},
+			addressBinderStateName: {NextDBIndex: 10},
+		},
+	}
+	j := mirrorCronJob{
+		db:           db,
+		epochCronjob: epochCronjob{epochs: epochInfo},
+	}
+
+	// Without a buffer, an epoch is mirrored one epoch period after its end
+	clock := utils.NewFixedClock(epochInfo.GetEndTime(4).Add(epochInfo.Period))
+	j.clock = clock
+	r, err := j.getEpochRange(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &epochRange{start: 3, end: 4}, r)
+
+	j.bufferTime = 30 * time.Second
+	clock.Set(epochInfo.GetEndTime(4).Add(29 * time.Second))
+	r, err = j.getEpochRange(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &epochRange{start: 3, end: 3}, r)
+
+	// The buffer ends exactly at the time
+	clock.Set(epochInfo.GetEndTime(4).Add(30 * time.Second))
+	r, err = j.getEpochRange(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &epochRange{start: 3, end: 4}, r)
+
+	clock.Set(epochInfo.GetEndTime(4).Add(31 * time.Second))
+	r, err = j.getEpochRange(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &epochRange{start: 3, end: 4}, r)
+
+	clock.Set(epochInfo.GetEndTime(2).Add(29 * time.Second))
+	_, err = j.getEpochRange(context.Background())
+	require.ErrorIs(t, err, errNoEpochsToMirror)
+}
@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: bindings.go
+
+// Package cronjob is a generated GoMock package.
+package cronjob
+
+import (
+	mirroring "flare-indexer/utils/contracts/mirroring"
+	reflect "reflect"
+
+	bind "github.com/ethereum/go-ethereum/accounts/abi/bind"
+	types "github.com/ethereum/go-ethereum/core/types"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockmirroringBinding is a mock of mirroringBinding interface.
+type MockmirroringBinding struct {
+	ctrl     *gomock.Controller
+	recorder *MockmirroringBindingMockRecorder
+}
+
+// MockmirroringBindingMockRecorder is the mock recorder for MockmirroringBinding.
+type MockmirroringBindingMockRecorder struct {
+	mock *MockmirroringBinding
+}
+
+// NewMockmirroringBinding creates a new mock instance.
+func NewMockmirroringBinding(ctrl *gomock.Controller) *MockmirroringBinding {
+	mock := &MockmirroringBinding{ctrl: ctrl}
+	mock.recorder = &MockmirroringBindingMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmirroringBinding) EXPECT() *MockmirroringBindingMockRecorder {
+	return m.recorder
+}
+
+// IsActiveStakeMirrored mocks base method.
+func (m *MockmirroringBinding) IsActiveStakeMirrored(opts *bind.CallOpts, txId [32]byte, inputAddress [20]byte) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsActiveStakeMirrored", opts, txId, inputAddress)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsActiveStakeMirrored indicates an expected call of IsActiveStakeMirrored.
+func (mr *MockmirroringBindingMockRecorder) IsActiveStakeMirrored(opts, txId, inputAddress interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsActiveStakeMirrored", reflect.TypeOf((*MockmirroringBinding)(nil).IsActiveStakeMirrored), opts, txId, inputAddress)
+}
+
+// MirrorStake mocks base method.
+func (m *MockmirroringBinding) MirrorStake(opts *bind.TransactOpts, stakeData mirroring.IPChainStakeMirrorVerifierPChainStake, merkleProof [][32]byte) (*types.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MirrorStake", opts, stakeData, merkleProof)
+	ret0, _ := ret[0].(*types.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MirrorStake indicates an expected call of MirrorStake.
+func (mr *MockmirroringBindingMockRecorder) MirrorStake(opts, stakeData, merkleProof interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MirrorStake", reflect.TypeOf((*MockmirroringBinding)(nil).MirrorStake), opts, stakeData, merkleProof)
+}
@@ -0,0 +1,136 @@
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/indexer/config"
+	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/contracts/voting"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	contractProbeTimeout = 30 * time.Second
+)
+
+var (
+	ErrContractAddressNotSet = errors.New("contract address not set")
+	ErrNoContractCode        = errors.New("no contract code at address")
+	ErrUnexpectedContract    = errors.New("unexpected contract at address")
+)
+
+// Implemented by chain.ManagedEthClient
+type ethProbeClient interface {
+	bind.ContractCaller
+	chain.ChainIDClient
+}
+
+// Checks that the addresses of the voting contract and, if needsMirroring is
+// set, the mirroring contracts are configured. Unlike probeContracts, it does
+// not need the eth RPC and is called when the cronjobs are created.
+func checkContractAddresses(cfg *config.Config, needsMirroring bool) error {
+	if cfg.ContractAddresses.Voting == (common.Address{}) {
+		return fmt.Errorf("voting contract: %w", ErrContractAddressNotSet)
+	}
+	if !needsMirroring {
+		return nil
+	}
+	if len(cfg.ContractAddresses.MirroringContracts) > 0 && cfg.ContractAddresses.Mirroring != (common.Address{}) {
+		return errors.New("mirroring contract: set either mirroring or mirroring_contracts")
+	}
+	for _, contract := range cfg.ContractAddresses.MirroringContractsByEpoch() {
+		if contract.Address == (common.Address{}) {
+			return fmt.Errorf("mirroring contract: %w", ErrContractAddressNotSet)
+		}
+	}
+	return nil
+}
+
+// Checks that the eth RPC is connected to the configured chain and that the
+// voting contract and, if needsMirroring is set, the mirroring contracts are
+// deployed at the configured addresses, i.e., the addresses contain code and
+// the contracts respond to a view call. Called by each cronjob using the
+// contracts when it is started.
+func probeContracts(cfg *config.Config, eth ethProbeClient, needsMirroring bool) error {
+	if err := chain.CheckEthChainID(eth, cfg.Chain.ChainID); err != nil {
+		return err
+	}
+	if err := probeVotingContract(eth, cfg.ContractAddresses.Voting); err != nil {
+		return err
+	}
+	if needsMirroring {
+		for _, contract := range cfg.ContractAddresses.MirroringContractsByEpoch() {
+			if err := probeMirroringContract(eth, contract.Address); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func probeVotingContract(eth bind.ContractCaller, address common.Address) error {
+	return probeContract(eth, "voting", address, func(opts *bind.CallOpts) error {
+		caller, err := voting.NewVotingCaller(address, eth)
+		if err != nil {
+			return err
+		}
+		epochCfg, err := caller.GetEpochConfiguration(opts)
+		if err != nil {
+			return err
+		}
+		if epochCfg.EpochDurationSeconds.Sign() <= 0 {
+			return fmt.Errorf("%w: epoch duration is %s", ErrUnexpectedContract, epochCfg.EpochDurationSeconds)
+		}
+		return nil
+	})
+}
+
+func probeMirroringContract(eth bind.ContractCaller, address common.Address) error {
+	return probeContract(eth, "mirroring", address, func(opts *bind.CallOpts) error {
+		caller, err := mirroring.NewMirroringCaller(address, eth)
+		if err != nil {
+			return err
+		}
+		name, err := caller.GetContractName(opts)
+		if err != nil {
+			return err
+		}
+		if len(name) == 0 {
+			return fmt.Errorf("%w: empty contract name", ErrUnexpectedContract)
+		}
+		logger.Info("Mirroring contract %s at %s", name, address.Hex())
+		return nil
+	})
+}
+
+func probeContract(
+	eth bind.ContractCaller,
+	name string,
+	address common.Address,
+	viewCall func(opts *bind.CallOpts) error,
+) error {
+	if address == (common.Address{}) {
+		return fmt.Errorf("%s contract: %w", name, ErrContractAddressNotSet)
+	}
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), contractProbeTimeout)
+	defer cancelCtx()
+
+	code, err := eth.CodeAt(ctx, address, nil)
+	if err != nil {
+		return fmt.Errorf("%s contract at %s: cannot fetch code: %w", name, address.Hex(), err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("%s contract at %s: %w", name, address.Hex(), ErrNoContractCode)
+	}
+	if err := viewCall(&bind.CallOpts{Context: ctx}); err != nil {
+		return fmt.Errorf("%s contract at %s does not respond as expected: %w", name, address.Hex(), err)
+	}
+	return nil
+}
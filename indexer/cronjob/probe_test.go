@@ -0,0 +1,86 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/chain/mocks"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/contracts/voting"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testContractAddress = common.HexToAddress("0xf956df3800379fdFA31D0A45FDD5001D02F4109c")
+
+func TestProbeVotingContract(t *testing.T) {
+	votingABI, err := voting.VotingMetaData.GetAbi()
+	require.NoError(t, err)
+	epochCfg, err := votingABI.Methods["getEpochConfiguration"].Outputs.Pack(big.NewInt(1675346400), big.NewInt(3600))
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	eth.EXPECT().CodeAt(gomock.Any(), testContractAddress, nil).Return([]byte{0x60, 0x80}, nil).AnyTimes()
+	eth.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(epochCfg, nil)
+
+	require.NoError(t, probeVotingContract(eth, testContractAddress))
+}
+
+func TestProbeMirroringContract(t *testing.T) {
+	mirroringABI, err := mirroring.MirroringMetaData.GetAbi()
+	require.NoError(t, err)
+	name, err := mirroringABI.Methods["getContractName"].Outputs.Pack("PChainStakeMirror")
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+	eth.EXPECT().CodeAt(gomock.Any(), testContractAddress, nil).Return([]byte{0x60, 0x80}, nil).AnyTimes()
+	eth.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(name, nil)
+
+	require.NoError(t, probeMirroringContract(eth, testContractAddress))
+}
+
+func TestProbeContractErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	eth := mocks.NewMockEthClient(ctrl)
+
+	err := probeVotingContract(eth, common.Address{})
+	require.ErrorIs(t, err, ErrContractAddressNotSet)
+
+	eth.EXPECT().CodeAt(gomock.Any(), testContractAddress, nil).Return(nil, nil)
+	err = probeMirroringContract(eth, testContractAddress)
+	require.ErrorIs(t, err, ErrNoContractCode)
+	require.Contains(t, err.Error(), "mirroring contract at "+testContractAddress.Hex())
+
+	// Some other contract, the call returns no data
+	eth.EXPECT().CodeAt(gomock.Any(), testContractAddress, nil).Return([]byte{0x60, 0x80}, nil).AnyTimes()
+	eth.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return([]byte{}, nil)
+	err = probeVotingContract(eth, testContractAddress)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not respond as expected")
+}
+
+func TestCheckContractAddresses(t *testing.T) {
+	cfg := &config.Config{}
+	require.ErrorIs(t, checkContractAddresses(cfg, false), ErrContractAddressNotSet)
+
+	cfg.ContractAddresses.Voting = testContractAddress
+	require.NoError(t, checkContractAddresses(cfg, false))
+	require.ErrorIs(t, checkContractAddresses(cfg, true), ErrContractAddressNotSet)
+
+	cfg.ContractAddresses.Mirroring = testContractAddress
+	require.NoError(t, checkContractAddresses(cfg, true))
+
+	cfg.ContractAddresses.MirroringContracts = []config.MirroringContract{{Address: testContractAddress, FirstEpoch: 10}}
+	require.Error(t, checkContractAddresses(cfg, true))
+	cfg.ContractAddresses.Mirroring = common.Address{}
+	require.NoError(t, checkContractAddresses(cfg, true))
+	cfg.ContractAddresses.MirroringContracts = append(cfg.ContractAddresses.MirroringContracts, config.MirroringContract{FirstEpoch: 20})
+	require.ErrorIs(t, checkContractAddresses(cfg, true), ErrContractAddressNotSet)
+}
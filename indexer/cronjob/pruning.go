@@ -0,0 +1,129 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const pruningCronjobName = "pruning"
+
+// Deletes the tombstones and the change feed entries older than their
+// retention, so that the history tables do not grow without bounds
+type pruningCronjob struct {
+	enabled  bool
+	schedule Schedule
+	pruner   *Pruner
+}
+
+// Deletes the history rows older than the retention of the config
+type Pruner struct {
+	db                  pruningDB
+	tombstonesRetention time.Duration
+	changeFeedRetention time.Duration
+
+	now func() time.Time
+}
+
+type pruningDB interface {
+	DeleteTombstonesBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteChangeFeedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type pruningDBGorm struct {
+	db *gorm.DB
+}
+
+func (g pruningDBGorm) DeleteTombstonesBefore(ctx context.Context, before time.Time) (int64, error) {
+	return database.DeleteTombstonesBefore(ctx, g.db, before)
+}
+
+func (g pruningDBGorm) DeleteChangeFeedBefore(ctx context.Context, before time.Time) (int64, error) {
+	return database.DeleteChangeFeedBefore(ctx, g.db, before)
+}
+
+func NewPruningCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.Pruning.Enabled {
+		return &pruningCronjob{}, nil
+	}
+	schedule, err := newSchedule(cfg.Pruning.Schedule, &cfg.Pruning.CronjobConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", pruningCronjobName, err)
+	}
+	return &pruningCronjob{
+		enabled:  true,
+		schedule: schedule,
+		pruner:   NewPruner(ctx),
+	}, nil
+}
+
+func NewPruner(ctx indexerctx.IndexerContext) *Pruner {
+	return newPruner(ctx.Config(), pruningDBGorm{db: ctx.DB()})
+}
+
+func newPruner(cfg *config.Config, db pruningDB) *Pruner {
+	return &Pruner{
+		db:                  db,
+		tombstonesRetention: cfg.Tombstones.Retention,
+		changeFeedRetention: cfg.ChangeFeed.Retention,
+		now:                 time.Now,
+	}
+}
+
+// Deletes the tombstones and the change feed entries older than their
+// retention, a zero retention keeps the rows forever. Returns the number of
+// deleted rows.
+func (p *Pruner) Prune(ctx context.Context) (int64, error) {
+	now := p.now()
+	var total int64
+	if p.tombstonesRetention > 0 {
+		deleted, err := p.db.DeleteTombstonesBefore(ctx, now.Add(-p.tombstonesRetention))
+		if err != nil {
+			return total, fmt.Errorf("cannot delete tombstones: %w", err)
+		}
+		if deleted > 0 {
+			logger.Info("Deleted %d tombstones older than %v", deleted, p.tombstonesRetention)
+		}
+		total += deleted
+	}
+	if p.changeFeedRetention > 0 {
+		deleted, err := p.db.DeleteChangeFeedBefore(ctx, now.Add(-p.changeFeedRetention))
+		if err != nil {
+			return total, fmt.Errorf("cannot delete change feed entries: %w", err)
+		}
+		if deleted > 0 {
+			logger.Info("Deleted %d change feed entries older than %v", deleted, p.changeFeedRetention)
+		}
+		total += deleted
+	}
+	return total, nil
+}
+
+func (c *pruningCronjob) Name() string {
+	return pruningCronjobName
+}
+
+func (c *pruningCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *pruningCronjob) Schedule() Schedule {
+	return c.schedule
+}
+
+func (c *pruningCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func (c *pruningCronjob) Call(ctx context.Context) error {
+	deleted, err := c.pruner.Prune(ctx)
+	ReportItems(ctx, int(deleted))
+	return err
+}
@@ -0,0 +1,61 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/indexer/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPruningDB struct {
+	tombstonesBefore []time.Time
+	changeFeedBefore []time.Time
+	err              error
+}
+
+func (db *testPruningDB) DeleteTombstonesBefore(ctx context.Context, before time.Time) (int64, error) {
+	db.tombstonesBefore = append(db.tombstonesBefore, before)
+	return 2, db.err
+}
+
+func (db *testPruningDB) DeleteChangeFeedBefore(ctx context.Context, before time.Time) (int64, error) {
+	db.changeFeedBefore = append(db.changeFeedBefore, before)
+	return 5, db.err
+}
+
+func TestPruner(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		Tombstones: config.TombstonesConfig{Retention: 90 * 24 * time.Hour},
+		ChangeFeed: config.ChangeFeedConfig{Retention: 24 * time.Hour},
+	}
+	db := &testPruningDB{}
+	p := newPruner(cfg, db)
+	p.now = func() time.Time { return now }
+
+	deleted, err := p.Prune(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 7, deleted)
+	require.Equal(t, []time.Time{time.Date(2023, 12, 2, 12, 0, 0, 0, time.UTC)}, db.tombstonesBefore)
+	require.Equal(t, []time.Time{time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)}, db.changeFeedBefore)
+
+	// Zero retention keeps the change feed forever
+	cfg.ChangeFeed.Retention = 0
+	db = &testPruningDB{}
+	p = newPruner(cfg, db)
+	deleted, err = p.Prune(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 2, deleted)
+	require.Len(t, db.tombstonesBefore, 1)
+	require.Empty(t, db.changeFeedBefore)
+
+	db.err = errors.New("lock wait timeout")
+	_, err = p.Prune(context.Background())
+	require.ErrorContains(t, err, "cannot delete tombstones")
+}
@@ -0,0 +1,205 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"time"
+)
+
+const (
+	reconciliationCronjobName = "reconciliation"
+
+	// Maximal staking duration, stakes starting earlier have ended
+	maxStakingDuration = 365 * 24 * time.Hour
+	// Stakes ending within this time are not reconciled, since the mirroring
+	// contract may already have removed them
+	reconciliationEndMargin = time.Hour
+	// Revert reason of attempts of stakes not mirrored on the contract
+	notMirroredOnChain = "not mirrored on-chain"
+)
+
+// Reconciles the successful and already mirrored attempts of the active stakes
+// with the stakes mirrored on the mirroring contract. Attempts of stakes which
+// are not mirrored on the contract (e.g. the transaction was reverted by a
+// reorg) are marked as reverted, and stakes mirrored by another sender get an
+// already mirrored attempt. Corrected and created attempts are marked as reconciled.
+type reconciliationCronjob struct {
+	epochCronjob
+	db        reconciliationDB
+	contracts reconciliationContracts
+	filter    *staking.TxFilter
+}
+
+type reconciliationDB interface {
+	FetchState(ctx context.Context, name string) (database.State, error)
+	GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error)
+	FetchMirroredAttempts(ctx context.Context, txIDs []string) ([]database.MirroringAttempt, error)
+	CreateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error
+	UpdateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error
+}
+
+type reconciliationContracts interface {
+	IsActiveStakeMirrored(ctx context.Context, tx *database.PChainTxData) (bool, error)
+	EpochConfig() (time.Time, time.Duration, error)
+}
+
+// The cronjob is created when it is started, see lazyCronjob
+func NewReconciliationCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.Reconciliation.Enabled {
+		return &reconciliationCronjob{}, nil
+	}
+	if err := checkContractAddresses(cfg, true); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", reconciliationCronjobName, err)
+	}
+	return newLazyCronjob(reconciliationCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createReconciliationCronjob(ctx)
+	}), nil
+}
+
+// Creates the cronjob, needs the eth RPC
+func createReconciliationCronjob(ctx indexerctx.IndexerContext) (*reconciliationCronjob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), true); err != nil {
+		return nil, err
+	}
+	contracts, err := newReconciliationContractsCChain(cfg, ctx.EthClient())
+	if err != nil {
+		return nil, err
+	}
+	c, err := newReconciliationCronjob(cfg, reconciliationDBGorm{db: ctx.DB()}, contracts)
+	if err != nil {
+		return nil, err
+	}
+	c.flags = ctx.FeatureFlags()
+	c.events = ctx.Events()
+	return c, nil
+}
+
+func newReconciliationCronjob(cfg *config.Config, db reconciliationDB, contracts reconciliationContracts) (*reconciliationCronjob, error) {
+	start, period, err := contracts.EpochConfig()
+	if err != nil {
+		return nil, err
+	}
+	epochs := staking.NewEpochInfo(&cfg.Reconciliation.EpochConfig, start, period)
+	epochCronjob, err := newEpochCronjob(reconciliationCronjobName, &cfg.Reconciliation.CronjobConfig, epochs)
+	if err != nil {
+		return nil, err
+	}
+	return &reconciliationCronjob{
+		epochCronjob: epochCronjob,
+		db:           db,
+		contracts:    contracts,
+		filter:       staking.NewTxFilter(&cfg.StakingFilter, &cfg.StakingRules),
+	}, nil
+}
+
+func (c *reconciliationCronjob) Name() string {
+	return reconciliationCronjobName
+}
+
+func (c *reconciliationCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+// Reconciles the stakes of the mirrored epochs which are active now
+func (c *reconciliationCronjob) Call(ctx context.Context) error {
+	now := c.now()
+	ctx, cancel := c.withEpochDeadline(ctx, now)
+	defer cancel()
+
+	mirrorState, err := c.db.FetchState(ctx, mirrorStateName)
+	if err != nil {
+		return err
+	}
+	firstEpoch := utils.Max(c.epochs.GetEpochIndex(now.Add(-maxStakingDuration)), c.epochs.First)
+	lastEpoch := int64(mirrorState.NextDBIndex) - 1
+	if firstEpoch > lastEpoch {
+		logger.Debug("no mirrored epochs to reconcile")
+		return nil
+	}
+
+	votingData, err := c.db.GetPChainTxsForEpoch(ctx, c.epochs.GetStartTime(firstEpoch), c.epochs.GetEndTime(lastEpoch))
+	if err != nil {
+		return err
+	}
+	var txs []database.PChainTxData
+	for _, tx := range staking.EpochTxs(votingData, c.filter) {
+		if tx.EndTime != nil && tx.EndTime.After(now.Add(reconciliationEndMargin)) {
+			txs = append(txs, tx)
+		}
+	}
+	logger.Debug("reconciling %d active stakes of epochs %d-%d", len(txs), firstEpoch, lastEpoch)
+
+	attempts, err := c.db.FetchMirroredAttempts(ctx, utils.Map(txs, func(tx database.PChainTxData) string {
+		return *tx.TxID
+	}))
+	if err != nil {
+		return err
+	}
+	mirroredAttempts := make(map[string][]database.MirroringAttempt)
+	for _, a := range attempts {
+		key := a.TxID + "/" + a.InputAddress
+		mirroredAttempts[key] = append(mirroredAttempts[key], a)
+	}
+
+	dryRun := c.dryRun()
+	fixed := 0
+	for i := range txs {
+		tx := &txs[i]
+		mirrored, err := c.contracts.IsActiveStakeMirrored(ctx, tx)
+		if err != nil {
+			return err
+		}
+		local := mirroredAttempts[*tx.TxID+"/"+tx.InputAddress]
+		if mirrored == (len(local) > 0) {
+			continue
+		}
+		fixed++
+		if dryRun {
+			logger.Info("Dry run: not reconciling tx %s (mirrored on-chain: %t)", *tx.TxID, mirrored)
+			continue
+		}
+		if err := c.reconcile(ctx, tx, mirrored, local, now); err != nil {
+			return err
+		}
+	}
+	if fixed > 0 {
+		logger.Info("reconciled %d stakes with the mirroring contract", fixed)
+	}
+	ReportItems(ctx, fixed)
+	return nil
+}
+
+func (c *reconciliationCronjob) reconcile(
+	ctx context.Context, tx *database.PChainTxData, mirrored bool, local []database.MirroringAttempt, now time.Time,
+) error {
+	if mirrored {
+		logger.Warn("tx %s is mirrored on-chain without a local attempt", *tx.TxID)
+		return c.db.CreateMirroringAttempt(ctx, &database.MirroringAttempt{
+			TxID:         *tx.TxID,
+			InputAddress: tx.InputAddress,
+			Epoch:        c.epochs.GetEpochIndex(*tx.StartTime),
+			Status:       database.MirroringAttemptAlreadyMirrored,
+			Timestamp:    now,
+			Reconciled:   true,
+		})
+	}
+	logger.Warn("tx %s has a mirrored attempt but is not mirrored on-chain", *tx.TxID)
+	for i := range local {
+		attempt := &local[i]
+		attempt.Status = database.MirroringAttemptReverted
+		attempt.RevertReason = notMirroredOnChain
+		attempt.Reconciled = true
+		if err := c.db.UpdateMirroringAttempt(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
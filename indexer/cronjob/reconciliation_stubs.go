@@ -0,0 +1,98 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/contracts/voting"
+	"flare-indexer/utils/staking"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type reconciliationDBGorm struct {
+	db *gorm.DB
+}
+
+func (g reconciliationDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, g.db, name)
+}
+
+func (g reconciliationDBGorm) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+	return database.GetPChainTxsForEpoch(ctx, &database.GetPChainTxsForEpochInput{
+		DB:             g.db,
+		StartTimestamp: start,
+		EndTimestamp:   end,
+	})
+}
+
+func (g reconciliationDBGorm) FetchMirroredAttempts(ctx context.Context, txIDs []string) ([]database.MirroringAttempt, error) {
+	return database.FetchMirroredAttempts(ctx, g.db, txIDs)
+}
+
+func (g reconciliationDBGorm) CreateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error {
+	return database.CreateMirroringAttempt(ctx, g.db, attempt)
+}
+
+func (g reconciliationDBGorm) UpdateMirroringAttempt(ctx context.Context, attempt *database.MirroringAttempt) error {
+	return database.UpdateMirroringAttempt(ctx, g.db, attempt)
+}
+
+// Subset of the mirroring contract binding used by the reconciliation cronjob
+type reconciliationMirroringBinding interface {
+	IsActiveStakeMirrored(opts *bind.CallOpts, txId [32]byte, inputAddress [20]byte) (bool, error)
+}
+
+type reconciliationContractsCChain struct {
+	// All configured mirroring contracts, the stakes are reconciled against each
+	mirroring []reconciliationMirroringBinding
+	voting    staking.EpochConfigCaller
+}
+
+func newReconciliationContractsCChain(cfg *config.Config, eth chain.EthClient) (*reconciliationContractsCChain, error) {
+	var mirroringContracts []reconciliationMirroringBinding
+	for _, contract := range cfg.ContractAddresses.MirroringContractsByEpoch() {
+		mirroringContract, err := mirroring.NewMirroring(contract.Address, eth)
+		if err != nil {
+			return nil, err
+		}
+		mirroringContracts = append(mirroringContracts, mirroringContract)
+	}
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, eth)
+	if err != nil {
+		return nil, err
+	}
+	return &reconciliationContractsCChain{
+		mirroring: mirroringContracts,
+		voting:    votingContract,
+	}, nil
+}
+
+// True if the stake is mirrored on any of the mirroring contracts
+func (r *reconciliationContractsCChain) IsActiveStakeMirrored(ctx context.Context, tx *database.PChainTxData) (bool, error) {
+	txID, err := ids.FromString(*tx.TxID)
+	if err != nil {
+		return false, errors.Wrap(err, "ids.FromString")
+	}
+	address, err := chain.ParseAddress(tx.InputAddress)
+	if err != nil {
+		return false, errors.Wrap(err, "chain.ParseAddress")
+	}
+	for _, contract := range r.mirroring {
+		mirrored, err := contract.IsActiveStakeMirrored(&bind.CallOpts{Context: ctx}, txID, address)
+		if err != nil || mirrored {
+			return mirrored, err
+		}
+	}
+	return false, nil
+}
+
+func (r *reconciliationContractsCChain) EpochConfig() (time.Time, time.Duration, error) {
+	return staking.GetEpochConfig(r.voting)
+}
@@ -0,0 +1,137 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliation(t *testing.T) {
+	// Stakes of epochs ending more than the maximal staking duration ago are not reconciled
+	epoch := epochInfo.GetEpochIndex(time.Now()) - 2
+	startTime := epochInfo.GetStartTime(epoch)
+	endTime := time.Now().Add(24 * time.Hour)
+	newTx := func(txID string, endTime time.Time) database.PChainTxData {
+		return database.PChainTxData{
+			PChainTx: database.PChainTx{
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &startTime,
+				EndTime:   &endTime,
+				TxID:      &txID,
+				Type:      database.PChainAddDelegatorTx,
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+	}
+	succeeded := func(id uint64, txID string) database.MirroringAttempt {
+		a := database.MirroringAttempt{
+			TxID:         txID,
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+			Epoch:        epoch,
+			Status:       database.MirroringAttemptSucceeded,
+		}
+		a.ID = id
+		return a
+	}
+
+	db := testDB{
+		epochs: epochInfo,
+		states: map[string]database.State{
+			mirrorStateName: {NextDBIndex: uint64(epoch + 1)},
+		},
+		txs: map[int64][]database.PChainTxData{
+			epoch: {
+				// Mirrored locally and on-chain
+				newTx("A", endTime),
+				// Mirrored locally, reverted on-chain
+				newTx("B", endTime),
+				// Mirrored on-chain by another sender
+				newTx("C", endTime),
+				// Ending soon, not reconciled
+				newTx("D", time.Now().Add(time.Minute)),
+			},
+		},
+		attempts: map[string][]database.MirroringAttempt{
+			"A": {succeeded(1, "A")},
+			"B": {succeeded(2, "B")},
+			"D": {succeeded(3, "D")},
+		},
+	}
+	contracts := testMirroredStakes{"A": true, "C": true}
+
+	j := reconciliationCronjob{
+		db:        db,
+		contracts: contracts,
+		epochCronjob: epochCronjob{
+			enabled: true,
+			epochs:  epochInfo,
+		},
+	}
+	require.NoError(t, j.Call(context.Background()))
+
+	require.Equal(t, []database.MirroringAttempt{succeeded(1, "A")}, db.attempts["A"])
+
+	require.Len(t, db.attempts["B"], 1)
+	require.Equal(t, database.MirroringAttemptReverted, db.attempts["B"][0].Status)
+	require.Equal(t, notMirroredOnChain, db.attempts["B"][0].RevertReason)
+	require.True(t, db.attempts["B"][0].Reconciled)
+
+	require.Len(t, db.attempts["C"], 1)
+	require.Equal(t, database.MirroringAttemptAlreadyMirrored, db.attempts["C"][0].Status)
+	require.Equal(t, epoch, db.attempts["C"][0].Epoch)
+	require.Empty(t, db.attempts["C"][0].EthTxHash)
+	require.True(t, db.attempts["C"][0].Reconciled)
+
+	require.Equal(t, []database.MirroringAttempt{succeeded(3, "D")}, db.attempts["D"])
+}
+
+// Stakes mirrored on the contract by transaction ID
+type testMirroredStakes map[string]bool
+
+func (s testMirroredStakes) IsActiveStakeMirrored(ctx context.Context, tx *database.PChainTxData) (bool, error) {
+	return s[*tx.TxID], nil
+}
+
+func (s testMirroredStakes) EpochConfig() (time.Time, time.Duration, error) {
+	return epochInfo.Start, epochInfo.Period, nil
+}
+
+// Mirroring contract binding with the stakes mirrored on it by transaction ID
+type testMirroringBinding map[ids.ID]bool
+
+func (b testMirroringBinding) IsActiveStakeMirrored(opts *bind.CallOpts, txId [32]byte, inputAddress [20]byte) (bool, error) {
+	return b[txId], nil
+}
+
+func TestReconciliationContractVersions(t *testing.T) {
+	oldTxID, newTxID := ids.GenerateTestID(), ids.GenerateTestID()
+	contracts := &reconciliationContractsCChain{
+		mirroring: []reconciliationMirroringBinding{
+			testMirroringBinding{oldTxID: true},
+			testMirroringBinding{newTxID: true},
+		},
+	}
+	newTx := func(txID string) *database.PChainTxData {
+		return &database.PChainTxData{
+			PChainTx:     database.PChainTx{TxID: &txID},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+	}
+
+	for _, txID := range []ids.ID{oldTxID, newTxID} {
+		mirrored, err := contracts.IsActiveStakeMirrored(context.Background(), newTx(txID.String()))
+		require.NoError(t, err)
+		require.True(t, mirrored)
+	}
+	mirrored, err := contracts.IsActiveStakeMirrored(context.Background(), newTx(ids.GenerateTestID().String()))
+	require.NoError(t, err)
+	require.False(t, mirrored)
+}
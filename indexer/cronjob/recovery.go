@@ -0,0 +1,56 @@
+package cronjob
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	// Delay before restarting a cronjob after a panic or a failed start,
+	// doubled after each consecutive failure up to maxRestartBackoff
+	minRestartBackoff = 10 * time.Second
+	maxRestartBackoff = 10 * time.Minute
+)
+
+// Error of a call which panicked
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// Calls f and returns a panic in f as *panicError, so that a bug in one
+// cronjob does not stop the other cronjobs and the indexers
+func recoverCall(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: debug.Stack()}
+		}
+	}()
+	return f()
+}
+
+// Delay of restarting a cronjob after consecutive panics or failed starts
+type restartBackoff struct {
+	delay time.Duration
+}
+
+func (b *restartBackoff) next() time.Duration {
+	if b.delay == 0 {
+		b.delay = minRestartBackoff
+	} else if b.delay < maxRestartBackoff {
+		b.delay *= 2
+	}
+	if b.delay > maxRestartBackoff {
+		b.delay = maxRestartBackoff
+	}
+	return b.delay
+}
+
+func (b *restartBackoff) reset() {
+	b.delay = 0
+}
@@ -0,0 +1,124 @@
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Runs older than the retention are deleted at most once per period
+	runsPrunePeriod = time.Hour
+
+	maxRunErrorLength = 256
+)
+
+// Outcome of a call, filled in by the cronjob
+type runReport struct {
+	items      int
+	firstEpoch *int64
+	lastEpoch  *int64
+}
+
+type runReportKey struct{}
+
+// Adds n items (e.g. transactions or votes) processed by the current call of
+// the cronjob
+func ReportItems(ctx context.Context, n int) {
+	if r, ok := ctx.Value(runReportKey{}).(*runReport); ok {
+		r.items += n
+	}
+}
+
+// Records that the current call of the cronjob processed the epoch
+func ReportEpoch(ctx context.Context, epoch int64) {
+	r, ok := ctx.Value(runReportKey{}).(*runReport)
+	if !ok {
+		return
+	}
+	if r.firstEpoch == nil || epoch < *r.firstEpoch {
+		r.firstEpoch = &epoch
+	}
+	if r.lastEpoch == nil || epoch > *r.lastEpoch {
+		r.lastEpoch = &epoch
+	}
+}
+
+type runsDB interface {
+	CreateCronjobRun(ctx context.Context, run *database.CronjobRun) error
+	DeleteCronjobRunsBefore(ctx context.Context, name string, before time.Time) error
+}
+
+type runsDBGorm struct {
+	db *gorm.DB
+}
+
+func (g runsDBGorm) CreateCronjobRun(ctx context.Context, run *database.CronjobRun) error {
+	return database.CreateCronjobRun(ctx, g.db, run)
+}
+
+func (g runsDBGorm) DeleteCronjobRunsBefore(ctx context.Context, name string, before time.Time) error {
+	return database.DeleteCronjobRunsBefore(ctx, g.db, name, before)
+}
+
+// Persists the calls of a cronjob to the cronjob_runs table
+type runLog struct {
+	name       string
+	db         runsDB
+	retention  time.Duration
+	lastPruned time.Time
+}
+
+func newRunLog(name string, db runsDB, cfg *config.CronjobRunsConfig) *runLog {
+	return &runLog{
+		name:      name,
+		db:        db,
+		retention: cfg.Retention,
+	}
+}
+
+// Calls the cronjob and persists the outcome of the call. Failing to persist
+// it is logged and does not affect the cronjob. A panic of the call is
+// recovered and returned as *panicError.
+func (l *runLog) call(ctx context.Context, c Cronjob) error {
+	report := &runReport{}
+	start := time.Now()
+	err := recoverCall(func() error {
+		return c.Call(context.WithValue(ctx, runReportKey{}, report))
+	})
+
+	run := &database.CronjobRun{
+		Name:       l.name,
+		Started:    start,
+		Duration:   time.Since(start).Milliseconds(),
+		Status:     database.CronjobRunSucceeded,
+		Items:      report.items,
+		FirstEpoch: report.firstEpoch,
+		LastEpoch:  report.lastEpoch,
+	}
+	if err != nil {
+		run.Status = database.CronjobRunFailed
+		run.Error = utils.TruncateString(err.Error(), maxRunErrorLength)
+	}
+	if dbErr := l.db.CreateCronjobRun(ctx, run); dbErr != nil {
+		logger.Error("%s cronjob run not persisted: %v", l.name, dbErr)
+	}
+	l.prune(ctx, start)
+	return err
+}
+
+func (l *runLog) prune(ctx context.Context, now time.Time) {
+	if l.retention <= 0 || now.Sub(l.lastPruned) < runsPrunePeriod {
+		return
+	}
+	if err := l.db.DeleteCronjobRunsBefore(ctx, l.name, now.Add(-l.retention)); err != nil {
+		logger.Error("%s cronjob runs not deleted: %v", l.name, err)
+		return
+	}
+	l.lastPruned = now
+}
@@ -0,0 +1,118 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type runsDBTest struct {
+	runs   []*database.CronjobRun
+	before []time.Time
+}
+
+func (db *runsDBTest) CreateCronjobRun(ctx context.Context, run *database.CronjobRun) error {
+	db.runs = append(db.runs, run)
+	return nil
+}
+
+func (db *runsDBTest) DeleteCronjobRunsBefore(ctx context.Context, name string, before time.Time) error {
+	db.before = append(db.before, before)
+	return nil
+}
+
+type runsCronjobTest struct {
+	call func(ctx context.Context) error
+}
+
+func (c *runsCronjobTest) Name() string                   { return "test" }
+func (c *runsCronjobTest) Enabled() bool                  { return true }
+func (c *runsCronjobTest) Schedule() Schedule             { return NewIntervalSchedule(time.Minute) }
+func (c *runsCronjobTest) Call(ctx context.Context) error { return c.call(ctx) }
+func (c *runsCronjobTest) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func TestRunLog(t *testing.T) {
+	db := &runsDBTest{}
+	runs := newRunLog("test", db, &config.CronjobRunsConfig{Retention: time.Hour})
+
+	err := runs.call(context.Background(), &runsCronjobTest{call: func(ctx context.Context) error {
+		ReportEpoch(ctx, 5)
+		ReportItems(ctx, 2)
+		ReportEpoch(ctx, 3)
+		ReportItems(ctx, 1)
+		return nil
+	}})
+	require.NoError(t, err)
+	require.Len(t, db.runs, 1)
+	run := db.runs[0]
+	require.Equal(t, "test", run.Name)
+	require.Equal(t, database.CronjobRunSucceeded, run.Status)
+	require.Equal(t, 3, run.Items)
+	require.Equal(t, int64(3), *run.FirstEpoch)
+	require.Equal(t, int64(5), *run.LastEpoch)
+	require.Len(t, db.before, 1)
+
+	callErr := errors.New(strings.Repeat("x", 300))
+	err = runs.call(context.Background(), &runsCronjobTest{call: func(ctx context.Context) error {
+		return callErr
+	}})
+	require.Equal(t, callErr, err)
+	require.Len(t, db.runs, 2)
+	run = db.runs[1]
+	require.Equal(t, database.CronjobRunFailed, run.Status)
+	require.Len(t, run.Error, maxRunErrorLength)
+	require.Nil(t, run.FirstEpoch)
+	require.Zero(t, run.Items)
+
+	// Pruned at most once per period
+	require.Len(t, db.before, 1)
+}
+
+func TestReportWithoutRun(t *testing.T) {
+	// Reports outside of a run are ignored
+	ReportItems(context.Background(), 1)
+	ReportEpoch(context.Background(), 1)
+}
+
+func TestRunLogPanic(t *testing.T) {
+	db := &runsDBTest{}
+	runs := newRunLog("test", db, &config.CronjobRunsConfig{})
+
+	err := runs.call(context.Background(), &runsCronjobTest{call: func(ctx context.Context) error {
+		ReportItems(ctx, 1)
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	}})
+	var p *panicError
+	require.ErrorAs(t, err, &p)
+	require.NotEmpty(t, p.stack)
+	require.Len(t, db.runs, 1)
+	require.Equal(t, database.CronjobRunFailed, db.runs[0].Status)
+	require.True(t, strings.HasPrefix(db.runs[0].Error, "panic: "))
+	require.Equal(t, 1, db.runs[0].Items)
+}
+
+func TestRestartBackoff(t *testing.T) {
+	b := &restartBackoff{}
+	require.Equal(t, minRestartBackoff, b.next())
+	require.Equal(t, 2*minRestartBackoff, b.next())
+	for i := 0; i < 10; i++ {
+		b.next()
+	}
+	require.Equal(t, maxRestartBackoff, b.next())
+
+	b.reset()
+	require.Equal(t, minRestartBackoff, b.next())
+}
@@ -0,0 +1,255 @@
+package cronjob
+
+import (
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	everySchedulePrefix = "@every"
+	epochSchedulePrefix = "@epoch"
+
+	// Cron expressions without a run in this period are rejected
+	cronScheduleHorizon = 5 * 365 * 24 * time.Hour
+)
+
+// Times of the calls of a cronjob
+type Schedule interface {
+	// Time of the first call after t
+	Next(t time.Time) time.Time
+}
+
+// Schedule calling the cronjob every interval
+func NewIntervalSchedule(interval time.Duration) Schedule {
+	return intervalSchedule(interval)
+}
+
+// Parses the schedule of the cronjob config. Valid schedules are
+//   - empty: every cfg.Timeout
+//   - "@every <duration>", e.g. "@every 10s"
+//   - "@epoch" or "@epoch+<duration>": offset (cfg.Delay if not set) after
+//     the end of each epoch, then every cfg.Timeout until the next epoch ends
+//   - cron expression with five fields (minute, hour, day of month, month,
+//     day of week) in UTC, e.g. "*/5 * * * *"
+//
+// A random delay up to cfg.Jitter is added to each call. Epoch schedules need
+// epochs, which is nil for cronjobs not bound to epochs.
+func newSchedule(spec string, cfg *config.CronjobConfig, epochs *staking.EpochInfo) (Schedule, error) {
+	schedule, err := parseSchedule(strings.TrimSpace(spec), cfg, epochs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if cfg.Jitter > 0 {
+		return jitterSchedule{schedule: schedule, jitter: cfg.Jitter}, nil
+	}
+	return schedule, nil
+}
+
+func parseSchedule(spec string, cfg *config.CronjobConfig, epochs *staking.EpochInfo) (Schedule, error) {
+	switch {
+	case spec == "":
+		return newPositiveIntervalSchedule(cfg.Timeout)
+	case strings.HasPrefix(spec, everySchedulePrefix):
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, everySchedulePrefix)))
+		if err != nil {
+			return nil, err
+		}
+		return newPositiveIntervalSchedule(interval)
+	case strings.HasPrefix(spec, epochSchedulePrefix):
+		if epochs == nil {
+			return nil, fmt.Errorf("cronjob is not bound to epochs")
+		}
+		offset := cfg.Delay
+		if offsetSpec := strings.TrimSpace(strings.TrimPrefix(spec, epochSchedulePrefix)); offsetSpec != "" {
+			if !strings.HasPrefix(offsetSpec, "+") {
+				return nil, fmt.Errorf("expected @epoch+<duration>")
+			}
+			var err error
+			if offset, err = time.ParseDuration(strings.TrimSpace(offsetSpec[1:])); err != nil {
+				return nil, err
+			}
+		}
+		if offset < 0 || offset >= epochs.Period {
+			return nil, fmt.Errorf("offset must be between 0 and the epoch period %v", epochs.Period)
+		}
+		return epochSchedule{epochs: *epochs, offset: offset, interval: cfg.Timeout}, nil
+	default:
+		return parseCronSchedule(spec)
+	}
+}
+
+type intervalSchedule time.Duration
+
+func newPositiveIntervalSchedule(interval time.Duration) (Schedule, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	return intervalSchedule(interval), nil
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// Calls the cronjob offset after the end of each epoch and then every
+// interval (if positive) until the next epoch ends, so that the calls are
+// aligned to the epoch boundaries
+type epochSchedule struct {
+	epochs   staking.EpochInfo
+	offset   time.Duration
+	interval time.Duration
+}
+
+func (s epochSchedule) Next(t time.Time) time.Time {
+	// Start of the current period of calls, rounded down also before the
+	// start of the first epoch
+	since := t.Sub(s.epochs.Start.Add(s.offset))
+	epoch := int64(since / s.epochs.Period)
+	if since < 0 && since%s.epochs.Period != 0 {
+		epoch--
+	}
+	periodStart := s.epochs.GetStartTime(epoch).Add(s.offset)
+	next := s.epochs.GetStartTime(epoch + 1).Add(s.offset)
+	if s.interval > 0 {
+		call := periodStart.Add((t.Sub(periodStart)/s.interval + 1) * s.interval)
+		if call.Before(next) {
+			return call
+		}
+	}
+	return next
+}
+
+type jitterSchedule struct {
+	schedule Schedule
+	jitter   time.Duration
+}
+
+func (s jitterSchedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t).Add(time.Duration(rand.Int63n(int64(s.jitter))))
+}
+
+// Cron expression, each field is a bit set of the matching values
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// Day of month or day of week field starts with "*"; if both are
+	// restricted, a day matching either of them matches (as in cron)
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields of a cron expression, got %d", len(fields))
+	}
+	s := &cronSchedule{
+		anyDayOfMonth: strings.HasPrefix(fields[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// 7 is Sunday as well as 0
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("no matching time")
+	}
+	return s, nil
+}
+
+// Parses a comma separated list of values, ranges (a-b) and "*", each of them
+// optionally with a step (e.g. "*/5" or "10-20/2")
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepSpec)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangeSpec == "*":
+			start, end = min, max
+		case strings.Contains(rangeSpec, "-"):
+			startSpec, endSpec, _ := strings.Cut(rangeSpec, "-")
+			var err1, err2 error
+			start, err1 = strconv.Atoi(startSpec)
+			end, err2 = strconv.Atoi(endSpec)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rangeSpec)
+			}
+		default:
+			var err error
+			if start, err = strconv.Atoi(rangeSpec); err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangeSpec)
+			}
+			end = start
+			if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of range %d-%d", rangeSpec, min, max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Returns the zero time if there is no matching time within
+// cronScheduleHorizon
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronScheduleHorizon)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
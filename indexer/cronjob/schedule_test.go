@@ -0,0 +1,129 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/staking"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testScheduleEpochs = staking.EpochInfo{
+	Start:  time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+	Period: time.Hour,
+}
+
+func TestIntervalSchedule(t *testing.T) {
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	s, err := newSchedule("", &config.CronjobConfig{Timeout: 10 * time.Second}, nil)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(10*time.Second), s.Next(now))
+
+	s, err = newSchedule("@every 1m", &config.CronjobConfig{Timeout: 10 * time.Second}, nil)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Minute), s.Next(now))
+}
+
+func TestEpochSchedule(t *testing.T) {
+	cfg := &config.CronjobConfig{Delay: 30 * time.Second}
+	s, err := newSchedule("@epoch", cfg, &testScheduleEpochs)
+	require.NoError(t, err)
+
+	epochEnd := testScheduleEpochs.GetEndTime(5)
+	require.Equal(t, epochEnd.Add(30*time.Second), s.Next(epochEnd.Add(-time.Minute)))
+	require.Equal(t, epochEnd.Add(30*time.Second), s.Next(epochEnd))
+	require.Equal(t, epochEnd.Add(time.Hour+30*time.Second), s.Next(epochEnd.Add(30*time.Second)))
+	require.Equal(t, testScheduleEpochs.Start.Add(30*time.Second), s.Next(testScheduleEpochs.Start.Add(-10*time.Minute)))
+
+	// Retried every timeout after the epoch ends
+	cfg.Timeout = 20 * time.Minute
+	s, err = newSchedule("@epoch+1m", cfg, &testScheduleEpochs)
+	require.NoError(t, err)
+	require.Equal(t, epochEnd.Add(time.Minute), s.Next(epochEnd))
+	require.Equal(t, epochEnd.Add(21*time.Minute), s.Next(epochEnd.Add(time.Minute)))
+	require.Equal(t, epochEnd.Add(41*time.Minute), s.Next(epochEnd.Add(30*time.Minute)))
+	require.Equal(t, epochEnd.Add(61*time.Minute), s.Next(epochEnd.Add(50*time.Minute)))
+}
+
+func TestCronSchedule(t *testing.T) {
+	cfg := &config.CronjobConfig{}
+	// Thursday
+	now := time.Date(2023, 6, 1, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2023, 6, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, 6, 1, 10, 15, 0, 0, time.UTC)},
+		{"5,50 9-11 * * *", time.Date(2023, 6, 1, 10, 50, 0, 0, time.UTC)},
+		{"0 12 * * 1", time.Date(2023, 6, 5, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2023, 6, 4, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC)},
+		{"30 8 1 1 *", time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC)},
+		// Day of month or day of week
+		{"0 0 15 * 6", time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		s, err := newSchedule(test.spec, cfg, nil)
+		require.NoError(t, err, test.spec)
+		require.Equal(t, test.next, s.Next(now), test.spec)
+	}
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@every",
+		"@every -1s",
+		"@epoch",
+		"* * * *",
+		"60 * * * *",
+		"* 5-2 * * *",
+		"*/0 * * * *",
+		"0 0 30 2 *",
+	} {
+		_, err := newSchedule(spec, &config.CronjobConfig{}, nil)
+		require.Error(t, err, spec)
+	}
+
+	_, err := newSchedule("@epoch+2h", &config.CronjobConfig{}, &testScheduleEpochs)
+	require.Error(t, err)
+
+	// Schedules of disabled cronjobs are not checked
+	_, err = newEpochCronjob("test", &config.CronjobConfig{}, testScheduleEpochs)
+	require.NoError(t, err)
+	_, err = newEpochCronjob("test", &config.CronjobConfig{Enabled: true}, testScheduleEpochs)
+	require.Error(t, err)
+}
+
+func TestJitterSchedule(t *testing.T) {
+	now := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	s, err := newSchedule("@every 1m", &config.CronjobConfig{Jitter: 10 * time.Second}, nil)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		next := s.Next(now)
+		require.False(t, next.Before(now.Add(time.Minute)))
+		require.True(t, next.Before(now.Add(time.Minute+10*time.Second)))
+	}
+}
+
+func TestNextCall(t *testing.T) {
+	start := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	s := NewIntervalSchedule(time.Minute)
+
+	next, missed := nextCall(s, start, start.Add(time.Second))
+	require.Equal(t, start.Add(time.Minute), next)
+	require.False(t, missed)
+
+	// Missed calls are replaced by a call right after the long one
+	next, missed = nextCall(s, start, start.Add(3*time.Minute))
+	require.Equal(t, start.Add(3*time.Minute), next)
+	require.True(t, missed)
+}
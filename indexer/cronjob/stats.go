@@ -0,0 +1,164 @@
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/logger"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	statsCronjobName = "stats"
+	statsStateName   = "stats_cronjob"
+
+	statsDay = 24 * time.Hour
+)
+
+// Aggregates the accepted P-chain transactions of each completed UTC day in the
+// daily_stats table. A day is completed once a block of a later day is indexed.
+// The next day to aggregate (days since the Unix epoch) is kept in NextDBIndex
+// of the state, the first call starts with the day of the first indexed block.
+type statsCronjob struct {
+	enabled   bool
+	schedule  Schedule
+	batchSize int64
+
+	db statsDB
+}
+
+type statsDB interface {
+	FetchState(ctx context.Context) (database.State, error)
+	UpdateState(ctx context.Context, state *database.State) error
+	// Times of the first and the last indexed block, gorm.ErrRecordNotFound if
+	// no block is indexed
+	IndexedTimeRange(ctx context.Context) (time.Time, time.Time, error)
+	AggregateDailyStats(ctx context.Context, day time.Time) (*database.DailyStats, []database.DailyTxCount, error)
+	UpsertDailyStats(ctx context.Context, stats *database.DailyStats, counts []database.DailyTxCount) error
+}
+
+type statsDBGorm struct {
+	db *gorm.DB
+}
+
+func (g statsDBGorm) FetchState(ctx context.Context) (database.State, error) {
+	return database.FetchState(ctx, g.db, statsStateName)
+}
+
+func (g statsDBGorm) UpdateState(ctx context.Context, state *database.State) error {
+	return database.UpdateState(ctx, g.db, state)
+}
+
+func (g statsDBGorm) IndexedTimeRange(ctx context.Context) (time.Time, time.Time, error) {
+	minHeight, maxHeight, err := database.FetchPChainBlockHeightRange(ctx, g.db)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	first, err := database.FetchPChainBlockInfo(ctx, g.db, minHeight)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	last, err := database.FetchPChainBlockInfo(ctx, g.db, maxHeight)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return first.Timestamp, last.Timestamp, nil
+}
+
+func (g statsDBGorm) AggregateDailyStats(ctx context.Context, day time.Time) (*database.DailyStats, []database.DailyTxCount, error) {
+	return database.AggregateDailyStats(ctx, g.db, day)
+}
+
+func (g statsDBGorm) UpsertDailyStats(ctx context.Context, stats *database.DailyStats, counts []database.DailyTxCount) error {
+	return database.UpsertDailyStats(ctx, g.db, stats, counts)
+}
+
+func NewStatsCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config()
+	if !cfg.Stats.Enabled {
+		return &statsCronjob{}, nil
+	}
+	return newStatsCronjob(&cfg.Stats, statsDBGorm{db: ctx.DB()})
+}
+
+func newStatsCronjob(cfg *config.StatsConfig, db statsDB) (*statsCronjob, error) {
+	schedule, err := newSchedule(cfg.Schedule, &cfg.CronjobConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", statsCronjobName, err)
+	}
+	return &statsCronjob{
+		enabled:   cfg.Enabled,
+		schedule:  schedule,
+		batchSize: cfg.BatchSize,
+		db:        db,
+	}, nil
+}
+
+func (c *statsCronjob) Name() string {
+	return statsCronjobName
+}
+
+func (c *statsCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *statsCronjob) Schedule() Schedule {
+	return c.schedule
+}
+
+func (c *statsCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func (c *statsCronjob) Call(ctx context.Context) error {
+	state, err := c.db.FetchState(ctx)
+	if err != nil {
+		return err
+	}
+	first, last, err := c.db.IndexedTimeRange(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Debug("%s cronjob: no block is indexed yet", statsCronjobName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	day := int64(state.NextDBIndex)
+	if firstDay := dayIndex(first); day < firstDay {
+		day = firstDay
+	}
+	end := dayIndex(last) // the day of the last block is not completed
+	if c.batchSize > 0 && end > day+c.batchSize {
+		end = day + c.batchSize
+	}
+	aggregated := 0
+	for ; day < end; day++ {
+		start := time.Unix(day*int64(statsDay/time.Second), 0).UTC()
+		stats, counts, err := c.db.AggregateDailyStats(ctx, start)
+		if err != nil {
+			return fmt.Errorf("cannot aggregate the stats of %s: %w", start.Format("2006-01-02"), err)
+		}
+		if err := c.db.UpsertDailyStats(ctx, stats, counts); err != nil {
+			return err
+		}
+		state.NextDBIndex = uint64(day + 1)
+		state.Updated = time.Now()
+		if err := c.db.UpdateState(ctx, &state); err != nil {
+			return err
+		}
+		aggregated++
+	}
+	ReportItems(ctx, aggregated)
+	return nil
+}
+
+// Days since the Unix epoch (UTC) of the time
+func dayIndex(t time.Time) int64 {
+	return t.Unix() / int64(statsDay/time.Second)
+}
@@ -0,0 +1,85 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type testStatsDB struct {
+	state       database.State
+	first, last time.Time
+	rangeErr    error
+	aggregated  []time.Time
+	stored      []*database.DailyStats
+}
+
+func (db *testStatsDB) FetchState(ctx context.Context) (database.State, error) {
+	return db.state, nil
+}
+
+func (db *testStatsDB) UpdateState(ctx context.Context, state *database.State) error {
+	db.state = *state
+	return nil
+}
+
+func (db *testStatsDB) IndexedTimeRange(ctx context.Context) (time.Time, time.Time, error) {
+	return db.first, db.last, db.rangeErr
+}
+
+func (db *testStatsDB) AggregateDailyStats(ctx context.Context, day time.Time) (*database.DailyStats, []database.DailyTxCount, error) {
+	db.aggregated = append(db.aggregated, day)
+	counts := []database.DailyTxCount{{Day: day, Type: database.PChainAddDelegatorTx, TxCount: 2}}
+	return &database.DailyStats{Day: day, TxCount: 2}, counts, nil
+}
+
+func (db *testStatsDB) UpsertDailyStats(ctx context.Context, stats *database.DailyStats, counts []database.DailyTxCount) error {
+	db.stored = append(db.stored, stats)
+	return nil
+}
+
+func TestStatsCronjob(t *testing.T) {
+	db := &testStatsDB{
+		first: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+		last:  time.Date(2023, 10, 5, 1, 0, 0, 0, time.UTC),
+	}
+	c, err := newStatsCronjob(&config.StatsConfig{
+		CronjobConfig: config.CronjobConfig{Enabled: true, Timeout: time.Hour, BatchSize: 3},
+	}, db)
+	require.NoError(t, err)
+
+	// Starts with the day of the first block, at most batch size days
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, []time.Time{
+		time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC),
+	}, db.aggregated)
+	require.Len(t, db.stored, 3)
+	require.EqualValues(t, dayIndex(time.Date(2023, 10, 4, 0, 0, 0, 0, time.UTC)), db.state.NextDBIndex)
+
+	// The day of the last block is not completed
+	db.aggregated = nil
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, []time.Time{time.Date(2023, 10, 4, 0, 0, 0, 0, time.UTC)}, db.aggregated)
+	require.NoError(t, c.Call(context.Background()))
+	require.Len(t, db.aggregated, 1)
+
+	// Nothing to aggregate before a block is indexed
+	db = &testStatsDB{rangeErr: gorm.ErrRecordNotFound}
+	c.db = db
+	require.NoError(t, c.Call(context.Background()))
+	require.Empty(t, db.aggregated)
+
+	db.rangeErr = errors.New("connection refused")
+	require.Error(t, c.Call(context.Background()))
+}
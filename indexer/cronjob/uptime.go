@@ -1,58 +1,65 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
-	"time"
+	"fmt"
 
 	"gorm.io/gorm"
 )
 
 type uptimeCronjob struct {
-	config config.UptimeConfig
-	db     *gorm.DB
+	config   config.UptimeConfig
+	schedule Schedule
+	db       *gorm.DB
 
 	client chain.UptimeClient
 }
 
-func NewUptimeCronjob(ctx context.IndexerContext) Cronjob {
+func NewUptimeCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
+	cfg := ctx.Config().UptimeCronjob
+	var schedule Schedule
+	if cfg.Enabled {
+		var err error
+		if schedule, err = newSchedule(cfg.Schedule, &cfg.CronjobConfig, nil); err != nil {
+			return nil, fmt.Errorf("uptime cronjob: %w", err)
+		}
+	}
 	endpoint := utils.JoinPaths(ctx.Config().Chain.NodeURL, "ext/bc/P"+chain.RPCClientOptions(ctx.Config().Chain.ApiKey))
 	return &uptimeCronjob{
-		config: ctx.Config().UptimeCronjob,
-		db:     ctx.DB(),
-		client: chain.NewAvalancheUptimeClient(endpoint),
-	}
+		config:   cfg,
+		schedule: schedule,
+		db:       ctx.DB(),
+		client:   chain.NewAvalancheUptimeClient(endpoint),
+	}, nil
 }
 
 func (c *uptimeCronjob) Name() string {
 	return "uptime"
 }
 
-func (c *uptimeCronjob) Timeout() time.Duration {
-	return c.config.Timeout
+func (c *uptimeCronjob) Schedule() Schedule {
+	return c.schedule
 }
 
 func (c *uptimeCronjob) Enabled() bool {
 	return c.config.Enabled
 }
 
-func (c *uptimeCronjob) RandomTimeoutDelta() time.Duration {
-	return 0
-}
-
-func (c *uptimeCronjob) OnStart() error {
+func (c *uptimeCronjob) OnStart(ctx context.Context) error {
 	entities := []*database.UptimeCronjob{&database.UptimeCronjob{
 		NodeID:    nil,
 		Status:    database.UptimeCronjobStatusIndexerStarted,
 		Timestamp: c.client.Now(),
 	}}
-	return database.CreateUptimeCronjobEntry(c.db, entities)
+	return database.CreateUptimeCronjobEntry(ctx, c.db, entities)
 }
 
-func (c *uptimeCronjob) Call() error {
+func (c *uptimeCronjob) Call(ctx context.Context) error {
 	validators, status, err := c.client.GetValidatorStatus()
 	if err != nil {
 		return err
@@ -82,5 +89,9 @@ func (c *uptimeCronjob) Call() error {
 			}
 		}
 	}
-	return database.CreateUptimeCronjobEntry(c.db, entities)
+	if err := database.CreateUptimeCronjobEntry(ctx, c.db, entities); err != nil {
+		return err
+	}
+	ReportItems(ctx, len(validators))
+	return nil
 }
@@ -4,10 +4,11 @@
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"testing"
 	"time"
 )
@@ -38,7 +39,7 @@ func uptimeCronjobTestConfig() *config.Config {
 }
 
 func createTestUptimeCronjob() (*uptimeCronjob, error) {
-	ctx, err := context.BuildTestContext(uptimeCronjobTestConfig())
+	ctx, err := indexerctx.BuildTestContext(uptimeCronjobTestConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +60,7 @@ func TestUptime(t *testing.T) {
 	testUptimeClient.SetNow(now)
 
 	for i := 0; i < 100; i++ {
-		if err := cronjob.Call(); err != nil {
+		if err := cronjob.Call(context.Background()); err != nil {
 			t.Fatal(err)
 		}
 		testUptimeClient.Time.AdvanceNow(30 * time.Second)
@@ -1,9 +1,10 @@
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/logger"
 	"flare-indexer/utils"
 	"flare-indexer/utils/contracts/voting"
@@ -20,6 +21,8 @@ import (
 	"gorm.io/gorm"
 )
 
+const uptimeVotingCronjobName = "uptime_aggregator"
+
 var (
 	errNoEpochsToAggregate = errors.New("no epochs to aggregate")
 )
@@ -40,21 +43,34 @@ type uptimeVotingCronjob struct {
 
 	votingContract *voting.Voting
 	txOpts         *bind.TransactOpts
+	gas            *gasAccounting
 
 	db *gorm.DB
-
-	// For testing to set "now" to some past date
-	time utils.ShiftedTime
 }
 
-func NewUptimeVotingCronjob(ctx context.IndexerContext) (*uptimeVotingCronjob, error) {
+// The cronjob is created when it is started, see lazyCronjob
+func NewUptimeVotingCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
 	cfg := ctx.Config()
 
 	if !cfg.UptimeCronjob.Enabled || !cfg.UptimeCronjob.EnableVoting {
 		return &uptimeVotingCronjob{}, nil
 	}
+	if err := checkContractAddresses(cfg, false); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", uptimeVotingCronjobName, err)
+	}
+	return newLazyCronjob(uptimeVotingCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createUptimeVotingCronjob(ctx)
+	}), nil
+}
 
-	votingContract, err := newVotingContract(cfg)
+// Creates the cronjob, needs the eth RPC
+func createUptimeVotingCronjob(ctx indexerctx.IndexerContext) (*uptimeVotingCronjob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), false); err != nil {
+		return nil, err
+	}
+
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, ctx.EthClient())
 	if err != nil {
 		return nil, err
 	}
@@ -70,41 +86,46 @@ func NewUptimeVotingCronjob(ctx context.IndexerContext) (*uptimeVotingCronjob, e
 	}
 
 	config := ctx.Config().UptimeCronjob
+	epochs := staking.NewEpochInfo(&globalConfig.EpochConfig{First: config.First}, config.Start.Time, config.Period)
+	schedule, err := newSchedule(config.VotingSchedule, &config.CronjobConfig, &epochs)
+	if err != nil {
+		return nil, fmt.Errorf("uptime voting: %w", err)
+	}
 	return &uptimeVotingCronjob{
 		epochCronjob: epochCronjob{
-			enabled: config.EnableVoting,
-			timeout: config.Timeout,
-			epochs:  staking.NewEpochInfo(&globalConfig.EpochConfig{First: config.First}, config.Start.Time, config.Period),
+			enabled:  config.EnableVoting,
+			schedule: schedule,
+			epochs:   epochs,
 		},
 		lastAggregatedEpoch:            -1,
 		deleteOldUptimesEpochThreshold: config.DeleteOldUptimesEpochThreshold,
 		uptimeThreshold:                config.UptimeThreshold,
 		votingContract:                 votingContract,
 		txOpts:                         txOpts,
+		gas:                            newGasAccounting(ctx),
 		db:                             ctx.DB(),
 	}, nil
 
 }
 
 func (c *uptimeVotingCronjob) Name() string {
-	return "uptime_aggregator"
-}
-
-func (c *uptimeVotingCronjob) Timeout() time.Duration {
-	return c.timeout
+	return uptimeVotingCronjobName
 }
 
 func (c *uptimeVotingCronjob) Enabled() bool {
 	return c.enabled
 }
 
-func (c *uptimeVotingCronjob) OnStart() error {
+func (c *uptimeVotingCronjob) OnStart(ctx context.Context) error {
 	return nil
 }
 
-func (c *uptimeVotingCronjob) Call() error {
-	now := c.time.Now()
-	epochRange, err := c.aggregationRange(now)
+func (c *uptimeVotingCronjob) Call(ctx context.Context) error {
+	now := c.now()
+	ctx, cancel := c.withEpochDeadline(ctx, now)
+	defer cancel()
+
+	epochRange, err := c.aggregationRange(ctx, now)
 	if err != nil {
 		if err == errNoEpochsToAggregate {
 			return nil
@@ -117,7 +138,7 @@ func (c *uptimeVotingCronjob) Call() error {
 
 	// Aggregate missing epochs for all nodes
 	for epoch := epochRange.start; epoch <= epochRange.end; epoch++ {
-		nodeAggregations, err := c.aggregateEpoch(epoch)
+		nodeAggregations, err := c.aggregateEpoch(ctx, epoch)
 		if err != nil {
 			return err
 		}
@@ -132,19 +153,21 @@ func (c *uptimeVotingCronjob) Call() error {
 
 		aggregations = append(aggregations, nodeAggregations...)
 		lastAggregatedEpoch = epoch
+		ReportEpoch(ctx, epoch)
+		ReportItems(ctx, len(nodeAggregations))
 		logger.Info("Aggregated uptime for epoch %d", epoch)
 	}
 
 	// Persist all aggregations at once, so we have a complete set of aggregations for each epoch
 	// TODO: at the same time, remove uptimes that are not needed anymore to prevent the database
 	//       from growing too large
-	err = database.PersistUptimeAggregations(c.db, aggregations)
+	err = database.PersistUptimeAggregations(ctx, c.db, aggregations)
 	if err != nil {
 		return fmt.Errorf("failed persisting uptime aggregations %w", err)
 	}
 	c.lastAggregatedEpoch = lastAggregatedEpoch
 
-	err = c.deleteOldUptimes()
+	err = c.deleteOldUptimes(ctx)
 	if err != nil {
 		// Error is non-fatal, we only log it
 		logger.Error("Failed deleting old uptimes: %v", err)
@@ -152,7 +175,7 @@ func (c *uptimeVotingCronjob) Call() error {
 	return nil
 }
 
-func (c *uptimeVotingCronjob) aggregationRange(now time.Time) (*epochRange, error) {
+func (c *uptimeVotingCronjob) aggregationRange(ctx context.Context, now time.Time) (*epochRange, error) {
 	currentAggregationEpoch := c.epochs.GetEpochIndex(now.Add(-c.delay))
 	lastEpochToAggregate := currentAggregationEpoch - 1
 
@@ -163,7 +186,7 @@ func (c *uptimeVotingCronjob) aggregationRange(now time.Time) (*epochRange, erro
 
 	// Last aggregation epoch (epoch of the last persisted aggregation of any node since we
 	// store all epoch aggregations at once)
-	lastAggregation, dbErr := database.FetchLastUptimeAggregation(c.db)
+	lastAggregation, dbErr := database.FetchLastUptimeAggregation(ctx, c.db)
 	if dbErr != nil {
 		return nil, fmt.Errorf("failed fetching last uptime aggregation %w", dbErr)
 	}
@@ -178,11 +201,11 @@ func (c *uptimeVotingCronjob) aggregationRange(now time.Time) (*epochRange, erro
 	return c.getTrimmedEpochRange(firstEpochToAggregate, lastEpochToAggregate), nil
 }
 
-func (c *uptimeVotingCronjob) aggregateEpoch(epoch int64) ([]*database.UptimeAggregation, error) {
+func (c *uptimeVotingCronjob) aggregateEpoch(ctx context.Context, epoch int64) ([]*database.UptimeAggregation, error) {
 	epochStart, epochEnd := c.epochs.GetTimeRange(epoch)
 
 	// Get start and end times for all staking intervals that overlap with the current epoch
-	stakingIntervals, err := fetchNodeStakingIntervals(c.db, epochStart, epochEnd)
+	stakingIntervals, err := fetchNodeStakingIntervals(ctx, c.db, epochStart, epochEnd)
 	if err != nil {
 		return nil, fmt.Errorf("failed fetching node staking intervals %w", err)
 	}
@@ -195,7 +218,7 @@ func (c *uptimeVotingCronjob) aggregateEpoch(epoch int64) ([]*database.UptimeAgg
 	// Aggregate each node
 	nodeAggregations := make([]*database.UptimeAggregation, 0, epochNodes.Cardinality())
 	for nodeID := range epochNodes.Iter() {
-		nodeAggregation, err := c.aggregateNode(epoch, nodeID, stakingIntervals)
+		nodeAggregation, err := c.aggregateNode(ctx, epoch, nodeID, stakingIntervals)
 		if err != nil {
 			return nil, err
 		}
@@ -207,7 +230,7 @@ func (c *uptimeVotingCronjob) aggregateEpoch(epoch int64) ([]*database.UptimeAgg
 
 // Aggregate the uptime for a node in the given epoch, stakingIntervals are the staking intervals for
 // all nodes that overlap with the epoch (sorted by nodeID)
-func (c *uptimeVotingCronjob) aggregateNode(epoch int64, nodeID string, stakingIntervals []nodeStakingInterval) (*database.UptimeAggregation, error) {
+func (c *uptimeVotingCronjob) aggregateNode(ctx context.Context, epoch int64, nodeID string, stakingIntervals []nodeStakingInterval) (*database.UptimeAggregation, error) {
 	// Find (the first) staking interval for the node
 	idx := sort.Search(len(stakingIntervals), func(i int) bool {
 		return stakingIntervals[i].nodeID >= nodeID
@@ -221,7 +244,7 @@ func (c *uptimeVotingCronjob) aggregateNode(epoch int64, nodeID string, stakingI
 		if end <= start {
 			continue
 		}
-		ct, err := aggregateNodeUptime(c.db, nodeID, start, end)
+		ct, err := aggregateNodeUptime(ctx, c.db, nodeID, start, end)
 		if err != nil {
 			return nil, fmt.Errorf("failed aggregating node uptime %w", err)
 		}
@@ -257,11 +280,15 @@ func (c *uptimeVotingCronjob) submitVotes(epoch int64, nodeAggregations []*datab
 		}
 		nodeIDs = append(nodeIDs, nodeID)
 	}
-	_, err := c.votingContract.SubmitValidatorUptimeVote(c.txOpts, big.NewInt(epoch), nodeIDs)
-	return err
+	tx, err := c.votingContract.SubmitValidatorUptimeVote(c.txOpts, big.NewInt(epoch), nodeIDs)
+	if err != nil {
+		return err
+	}
+	c.gas.waitAndRecord(uptimeVotingCronjobName, &epoch, tx, c.txOpts.From.Hex())
+	return nil
 }
 
-func (c *uptimeVotingCronjob) deleteOldUptimes() error {
+func (c *uptimeVotingCronjob) deleteOldUptimes(ctx context.Context) error {
 	if c.deleteOldUptimesEpochThreshold <= 0 {
 		return nil
 	}
@@ -277,7 +304,7 @@ func (c *uptimeVotingCronjob) deleteOldUptimes() error {
 	}
 
 	_, epochEnd := c.epochs.GetTimeRange(lastEpochToDelete)
-	return database.DeleteUptimesBefore(c.db, epochEnd)
+	return database.DeleteUptimesBefore(ctx, c.db, epochEnd)
 }
 
 type nodeStakingInterval struct {
@@ -288,8 +315,8 @@ type nodeStakingInterval struct {
 
 // Return the staking intervals for each node, sorted by nodeID, note that it is possible
 // that a node has multiple intervals
-func fetchNodeStakingIntervals(db *gorm.DB, start time.Time, end time.Time) ([]nodeStakingInterval, error) {
-	txs, err := database.FetchNodeStakingIntervals(db, database.PChainAddValidatorTx, start, end)
+func fetchNodeStakingIntervals(ctx context.Context, db *gorm.DB, start time.Time, end time.Time) ([]nodeStakingInterval, error) {
+	txs, err := database.FetchNodeStakingIntervals(ctx, db, database.PChainAddValidatorTx, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -308,13 +335,14 @@ func fetchNodeStakingIntervals(db *gorm.DB, start time.Time, end time.Time) ([]n
 }
 
 func aggregateNodeUptime(
+	ctx context.Context,
 	db *gorm.DB,
 	nodeID string,
 	startTimestamp int64,
 	endTimestamp int64,
 ) (int64, error) {
 	// uptimes are sorted by timestamp
-	uptimes, err := database.FetchNodeUptimes(db, nodeID, time.Unix(startTimestamp, 0), time.Unix(endTimestamp, 0))
+	uptimes, err := database.FetchNodeUptimes(ctx, db, nodeID, time.Unix(startTimestamp, 0), time.Unix(endTimestamp, 0))
 	if err != nil {
 		return 0, err
 	}
@@ -4,10 +4,11 @@
 package cronjob
 
 import (
+	"context"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/pchain"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
@@ -34,16 +35,16 @@ func uptimeVotingCronjobTestConfig(epochStart time.Time) *config.Config {
 				Enabled: true,
 				Timeout: 30 * time.Second,
 			},
-			EpochConfig: globalConfig.EpochConfig{
-				Start:  utils.Timestamp{Time: epochStart},
-				Period: 90 * time.Second,
-			},
-			VotingInterval:  60 * time.Second,
+			Start:           utils.Timestamp{Time: epochStart},
+			Period:          90 * time.Second,
+			VotingSchedule:  "@every 60s",
 			EnableVoting:    true,
 			UptimeThreshold: 0.8,
 		},
-		VotingCronjob: config.VotingConfig{
-			ContractAddress: common.HexToAddress("0x7c2C195CD6D34B8F845992d380aADB2730bB9C6F"),
+		ContractAddresses: config.ContractAddresses{
+			ContractAddresses: globalConfig.ContractAddresses{
+				Voting: common.HexToAddress("0x7c2C195CD6D34B8F845992d380aADB2730bB9C6F"),
+			},
 		},
 		PChainIndexer: config.IndexerConfig{
 			Enabled:    true,
@@ -65,11 +66,11 @@ func uptimeVotingCronjobTestConfig(epochStart time.Time) *config.Config {
 }
 
 func createTestUptimeVotingCronjob(epochStart time.Time) (*uptimeVotingCronjob, *shared.ChainIndexerBase, error) {
-	ctx, err := context.BuildTestContext(uptimeVotingCronjobTestConfig(epochStart))
+	ctx, err := indexerctx.BuildTestContext(uptimeVotingCronjobTestConfig(epochStart))
 	if err != nil {
 		return nil, nil, err
 	}
-	cronjob, err := NewUptimeVotingCronjob(ctx)
+	cronjob, err := createUptimeVotingCronjob(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -99,20 +100,21 @@ func TestUptimeVoting(t *testing.T) {
 	require.NoError(t, err)
 
 	// Run indexer to allow uptime client test to fetch validator data
-	err = indexer.IndexBatch()
+	err = indexer.IndexBatch(context.Background())
 	require.NoError(t, err)
 
 	testUptimeClient.SetNow(now)
-	votingCronjob.time.SetNow(now)
+	votingClock := utils.NewFixedClock(now)
+	votingCronjob.clock = votingClock
 	for i := 0; i < 10; i++ {
-		if err := uptimeCronjob.Call(); err != nil {
+		if err := uptimeCronjob.Call(context.Background()); err != nil {
 			t.Fatal(err)
 		}
-		if err := votingCronjob.Call(); err != nil {
+		if err := votingCronjob.Call(context.Background()); err != nil {
 			t.Fatal(err)
 		}
 		testUptimeClient.Time.AdvanceNow(10 * time.Second)
-		votingCronjob.time.AdvanceNow(10 * time.Second)
+		votingClock.Advance(10 * time.Second)
 	}
 	aggr, err := database.FetchAggregations(votingCronjob.db)
 	require.NoError(t, err)
@@ -1,6 +1,7 @@
 package cronjob
 
 import (
+	"crypto/ecdsa"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -9,6 +10,23 @@ import (
 )
 
 func TransactOptsFromPrivateKey(privateKey string, chainID int) (*bind.TransactOpts, error) {
+	pk, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := bind.NewKeyedTransactorWithChainID(
+		pk, big.NewInt(int64(chainID)),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "bind.NewKeyedTransactorWithChainID")
+	}
+	// bind.N
+	return opts, nil
+}
+
+// Parses the hex private key, with or without the "0x" prefix
+func parsePrivateKey(privateKey string) (*ecdsa.PrivateKey, error) {
 	if len(privateKey) < 2 {
 		return nil, errors.New("privateKey is too short")
 	}
@@ -21,13 +39,5 @@ func TransactOptsFromPrivateKey(privateKey string, chainID int) (*bind.TransactO
 	if err != nil {
 		return nil, errors.Wrap(err, "crypto.HexToECDSA")
 	}
-
-	opts, err := bind.NewKeyedTransactorWithChainID(
-		pk, big.NewInt(int64(chainID)),
-	)
-	if err != nil {
-		return nil, errors.Wrap(err, "bind.NewKeyedTransactorWithChainID")
-	}
-	// bind.N
-	return opts, nil
+	return pk, nil
 }
@@ -1,28 +1,34 @@
 package cronjob
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"flare-indexer/database"
+	"flare-indexer/indexer/config"
 	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
 	"flare-indexer/indexer/pchain"
 	"flare-indexer/logger"
-	"flare-indexer/utils"
 	"flare-indexer/utils/staking"
+	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 )
 
 const (
-	votingStateName string = "voting_cronjob"
+	votingStateName   string = "voting_cronjob"
+	votingCronjobName string = "voting"
+
+	// Number of voting data rows read with a single query
+	votingDataPageSize = 1000
 )
 
 var (
-	zeroBytes     [32]byte    = [32]byte{}
-	zeroBytesHash common.Hash = crypto.Keccak256Hash(zeroBytes[:])
-
 	ErrEpochConfig = errors.New("epoch config mismatch")
 )
 
@@ -31,15 +37,22 @@ type votingCronjob struct {
 
 	db       votingDB
 	contract votingContract
-
-	// For testing to set "now" to some past date
-	time utils.ShiftedTime
+	filter   *staking.TxFilter
+	// Signs the recorded merkle roots, nil if they are not signed
+	rootSigner *merkleRootSigner
 }
 
 type votingDB interface {
-	FetchState(name string) (database.State, error)
-	FetchPChainVotingData(start, end time.Time) ([]database.PChainTxData, error)
-	UpdateState(state *database.State) error
+	FetchState(ctx context.Context, name string) (database.State, error)
+	FetchPChainVotingDataPage(ctx context.Context, start, end time.Time, afterID uint64, limit int) ([]database.PChainTxData, error)
+	UpdateState(ctx context.Context, state *database.State) error
+	CreateEpochMerkleRoot(ctx context.Context, root *database.EpochMerkleRoot) error
+}
+
+// Key of the data provider and the voting contract of the signed merkle roots
+type merkleRootSigner struct {
+	key    *ecdsa.PrivateKey
+	voting common.Address
 }
 
 type votingContract interface {
@@ -48,62 +61,107 @@ type votingContract interface {
 	EpochConfig() (time.Time, time.Duration, error)
 }
 
-func NewVotingCronjob(ctx indexerctx.IndexerContext) (*votingCronjob, error) {
+// The cronjob is created when it is started, see lazyCronjob
+func NewVotingCronjob(ctx indexerctx.IndexerContext) (Cronjob, error) {
 	cfg := ctx.Config()
 	if !cfg.VotingCronjob.Enabled {
 		return &votingCronjob{}, nil
 	}
+	if err := checkContractAddresses(cfg, false); err != nil {
+		return nil, fmt.Errorf("%s cronjob: %w", votingCronjobName, err)
+	}
+	return newLazyCronjob(votingCronjobName, true, func(startCtx context.Context) (Cronjob, error) {
+		return createVotingCronjob(startCtx, ctx)
+	}), nil
+}
 
-	db := &votingDBGorm{g: ctx.DB()}
-	contract, err := newVotingContractCChain(cfg)
+// Creates the cronjob, needs the eth RPC
+func createVotingCronjob(startCtx context.Context, ctx indexerctx.IndexerContext) (*votingCronjob, error) {
+	cfg := ctx.Config()
+	if err := probeContracts(cfg, ctx.EthClient(), false); err != nil {
+		return nil, err
+	}
+
+	contract, err := newVotingContractCChain(cfg, ctx.EthClient(), newGasAccounting(ctx))
 	if err != nil {
 		return nil, err
 	}
 
-	start, period, err := contract.EpochConfig()
+	vc, err := newVotingCronjob(cfg, &votingDBGorm{g: ctx.DB()}, contract)
 	if err != nil {
 		return nil, err
 	}
+	vc.flags = ctx.FeatureFlags()
+	vc.events = ctx.Events()
 
-	epochs := staking.NewEpochInfo(&cfg.VotingCronjob.EpochConfig, start, period)
+	err = vc.reset(startCtx, ctx.Flags().ResetVotingCronjob)
+	if err != nil {
+		return nil, err
+	}
+	return vc, nil
+}
 
-	vc := &votingCronjob{
-		epochCronjob: newEpochCronjob(&cfg.VotingCronjob.CronjobConfig, epochs),
-		db:           db,
-		contract:     contract,
+func newVotingCronjob(cfg *config.Config, db votingDB, contract votingContract) (*votingCronjob, error) {
+	start, period, err := contract.EpochConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	err = vc.reset(ctx.Flags().ResetVotingCronjob)
+	epochs := staking.NewEpochInfo(&cfg.VotingCronjob.EpochConfig, start, period)
+
+	epochCronjob, err := newEpochCronjob(votingCronjobName, &cfg.VotingCronjob.CronjobConfig, epochs)
 	if err != nil {
 		return nil, err
 	}
-	return vc, nil
+
+	var rootSigner *merkleRootSigner
+	if cfg.VotingCronjob.SignMerkleRoots {
+		privateKey, err := cfg.Chain.GetPrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		rootSigner = &merkleRootSigner{key: key, voting: cfg.ContractAddresses.Voting}
+	}
+
+	return &votingCronjob{
+		epochCronjob: epochCronjob,
+		db:           db,
+		contract:     contract,
+		filter:       staking.NewTxFilter(&cfg.StakingFilter, &cfg.StakingRules),
+		rootSigner:   rootSigner,
+	}, nil
 }
 
 func (c *votingCronjob) Name() string {
-	return "voting"
+	return votingCronjobName
 }
 
-func (c *votingCronjob) OnStart() error {
+func (c *votingCronjob) OnStart(ctx context.Context) error {
 	return nil
 }
 
-func (c *votingCronjob) RandomTimeoutDelta() time.Duration {
-	return 10 * time.Second
-}
+func (c *votingCronjob) Call(ctx context.Context) error {
+	// The deadline and the epochs to vote on are computed from the same time,
+	// so that a call at an epoch boundary does not vote with a passed deadline
+	now := c.now()
+	ctx, cancel := c.withEpochDeadline(ctx, now)
+	defer cancel()
 
-func (c *votingCronjob) Call() error {
-	idxState, err := c.db.FetchState(pchain.StateName)
+	idxState, err := c.db.FetchState(ctx, pchain.StateName)
 	if err != nil {
 		return err
 	}
 
-	state, err := c.db.FetchState(votingStateName)
+	state, err := c.db.FetchState(ctx, votingStateName)
 	if err != nil {
 		return err
 	}
 
-	now := c.time.Now()
+	dryRun := c.dryRun()
 
 	// Last epoch that was submitted to the contract
 	epochRange := c.getEpochRange(int64(state.NextDBIndex), now)
@@ -117,21 +175,26 @@ func (c *votingCronjob) Call() error {
 			return nil
 		}
 
-		votingData, err := c.db.FetchPChainVotingData(start, end)
-		if err != nil {
-			return err
+		if dryRun {
+			if err := c.logVote(ctx, e, start, end); err != nil {
+				return err
+			}
+			ReportEpoch(ctx, e)
+			continue
 		}
-		voted, err := c.submitVotes(e, votingData)
+		voted, err := c.submitVotes(ctx, e, start, end)
 		if err != nil {
 			return err
 		}
+		ReportEpoch(ctx, e)
 		if voted {
+			ReportItems(ctx, 1)
 			votedInBatch = true
 			logger.Info("Submitted vote for epoch %d", e)
 		} else {
 			if !votedInBatch {
 				state.NextDBIndex = uint64(e + 1)
-				if err := c.db.UpdateState(&state); err != nil {
+				if err := c.db.UpdateState(ctx, &state); err != nil {
 					return err
 				}
 			}
@@ -142,9 +205,7 @@ func (c *votingCronjob) Call() error {
 }
 
 // Return true if the vote was submitted, and false if shouldVote returned false
-func (c *votingCronjob) submitVotes(e int64, votingData []database.PChainTxData) (bool, error) {
-	votingData = staking.DedupeTxs(votingData)
-
+func (c *votingCronjob) submitVotes(ctx context.Context, e int64, start, end time.Time) (bool, error) {
 	shouldVote, err := c.contract.ShouldVote(big.NewInt(e))
 	if err != nil {
 		return false, err
@@ -153,31 +214,78 @@ func (c *votingCronjob) submitVotes(e int64, votingData []database.PChainTxData)
 		return false, nil
 	}
 
-	var merkleRoot common.Hash
-	if len(votingData) == 0 {
-		merkleRoot = zeroBytesHash
-	} else {
-		merkleRoot, err = staking.GetMerkleRoot(votingData)
+	merkleRoot, txCount, err := c.merkleRoot(ctx, start, end)
+	if err != nil {
+		return false, err
+	}
+	c.recordMerkleRoot(ctx, e, merkleRoot, txCount)
+	err = c.contract.SubmitVote(big.NewInt(e), [32]byte(merkleRoot))
+	if err != nil {
+		return true, err
+	}
+	c.events.Publish(events.EpochFinalized, events.EpochFinalizedData{
+		Epoch:      e,
+		MerkleRoot: merkleRoot.Hex(),
+		TxCount:    txCount,
+	})
+	return true, nil
+}
+
+// Logs the vote instead of submitting it, used in dry-run mode
+func (c *votingCronjob) logVote(ctx context.Context, e int64, start, end time.Time) error {
+	merkleRoot, txCount, err := c.merkleRoot(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	c.recordMerkleRoot(ctx, e, merkleRoot, txCount)
+	logger.Info("Dry run: not submitting vote %s for epoch %d (%d txs)", merkleRoot.Hex(), e, txCount)
+	return nil
+}
+
+// Merkle root and number of stakes of the deduplicated and filtered voting data
+// of the epoch, which is read page by page
+func (c *votingCronjob) merkleRoot(ctx context.Context, start, end time.Time) (common.Hash, int, error) {
+	fetch := func(afterID uint64, limit int) ([]database.PChainTxData, error) {
+		return c.db.FetchPChainVotingDataPage(ctx, start, end, afterID, limit)
+	}
+	return staking.GetMerkleRootFromPages(fetch, c.filter, votingDataPageSize)
+}
+
+// Records the merkle root of the epoch in the history of the roots, signed if
+// enabled in the config. Failures are logged and do not stop the voting.
+func (c *votingCronjob) recordMerkleRoot(ctx context.Context, epoch int64, merkleRoot common.Hash, txCount int) {
+	root := &database.EpochMerkleRoot{
+		Epoch:      epoch,
+		MerkleRoot: merkleRoot.Hex(),
+		TxCount:    txCount,
+		Timestamp:  c.now(),
+	}
+	if c.rootSigner != nil {
+		signature, err := staking.SignMerkleRoot(c.rootSigner.key, c.rootSigner.voting, epoch, merkleRoot)
 		if err != nil {
-			return false, err
+			logger.Error("Merkle root of epoch %d not signed: %v", epoch, err)
+			return
 		}
+		root.Signer = crypto.PubkeyToAddress(c.rootSigner.key.PublicKey).Hex()
+		root.Signature = hexutil.Encode(signature)
+	}
+	if err := c.db.CreateEpochMerkleRoot(ctx, root); err != nil {
+		logger.Error("Merkle root of epoch %d not persisted: %v", epoch, err)
 	}
-	err = c.contract.SubmitVote(big.NewInt(e), [32]byte(merkleRoot))
-	return true, err
 }
 
-func (c *votingCronjob) reset(firstEpoch int64) error {
+func (c *votingCronjob) reset(ctx context.Context, firstEpoch int64) error {
 	if firstEpoch <= 0 {
 		return nil
 	}
 
 	logger.Info("Resetting voting cronjob state to epoch %d", firstEpoch)
-	state, err := c.db.FetchState(votingStateName)
+	state, err := c.db.FetchState(ctx, votingStateName)
 	if err != nil {
 		return err
 	}
 	state.NextDBIndex = uint64(firstEpoch)
-	err = c.db.UpdateState(&state)
+	err = c.db.UpdateState(ctx, &state)
 	if err != nil {
 		return err
 	}
@@ -20,10 +20,11 @@ import (
 	"github.com/bradleyjkemp/cupaloy"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
-func votingCronjobTestConfig(epochStart time.Time, dbName string, privateKey string) *config.Config {
+func votingCronjobTestConfig(dbName string, privateKey string) *config.Config {
 	cfg := &config.Config{
 		Chain: globalConfig.ChainConfig{
 			ChainAddressHRP: "localflare",
@@ -36,18 +37,18 @@ func votingCronjobTestConfig(epochStart time.Time, dbName string, privateKey str
 				Enabled: true,
 				Timeout: 30 * time.Second,
 			},
-			ContractAddress: common.HexToAddress("0x7c2C195CD6D34B8F845992d380aADB2730bB9C6F"),
 		},
 		Mirror: config.MirrorConfig{
 			CronjobConfig: config.CronjobConfig{
 				Enabled: true,
 				Timeout: 30 * time.Second,
 			},
-			MirroringContract: common.HexToAddress("0x8858eeB3DfffA017D4BCE9801D340D36Cf895CCf"),
 		},
-		Epochs: config.EpochConfig{
-			Start:  utils.Timestamp{Time: epochStart},
-			Period: 90 * time.Second,
+		ContractAddresses: config.ContractAddresses{
+			ContractAddresses: globalConfig.ContractAddresses{
+				Voting: common.HexToAddress("0x7c2C195CD6D34B8F845992d380aADB2730bB9C6F"),
+			},
+			Mirroring: common.HexToAddress("0x8858eeB3DfffA017D4BCE9801D340D36Cf895CCf"),
 		},
 		PChainIndexer: config.IndexerConfig{
 			Enabled:    true,
@@ -80,24 +81,24 @@ func transformPChainTx(tx *database.PChainTx) *database.PChainTx {
 	return tx
 }
 
-func createTestVotingClients(epochStart time.Time) (*votingCronjob, *votingCronjob, *mirrorCronJob, *shared.ChainIndexerBase, *shared.ChainIndexerBase, error) {
-	ctx1, err := context.BuildTestContext(votingCronjobTestConfig(epochStart, "flare_indexer_indexer", privateKey1))
+func createTestVotingClients() (*votingCronjob, *votingCronjob, *mirrorCronJob, *shared.ChainIndexerBase, *shared.ChainIndexerBase, error) {
+	ctx1, err := context.BuildTestContext(votingCronjobTestConfig("flare_indexer_indexer", privateKey1))
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	cronjob1, err := NewVotingCronjob(ctx1)
+	cronjob1, err := createVotingCronjob(sysContext.Background(), ctx1)
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	ctx2, err := context.BuildTestContext(votingCronjobTestConfig(epochStart, "flare_indexer_indexer_2", privateKey2))
+	ctx2, err := context.BuildTestContext(votingCronjobTestConfig("flare_indexer_indexer_2", privateKey2))
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	cronjob2, err := NewVotingCronjob(ctx2)
+	cronjob2, err := createVotingCronjob(sysContext.Background(), ctx2)
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
-	mirror, err := NewMirrorCronjob(ctx1)
+	mirror, err := createMirrorCronjob(sysContext.Background(), ctx1)
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
@@ -124,10 +125,14 @@ func createTestVotingClients(epochStart time.Time) (*votingCronjob, *votingCronj
 			pchain.NewPChainDataTransformer(transformPChainTx),
 		),
 	}
-	return cronjob1, cronjob2, mirror.(*mirrorCronJob), indexer1, indexer2, nil
+	return cronjob1, cronjob2, mirror, indexer1, indexer2, nil
 }
 
-func getMerkleRootFromContract(votingContract *voting.Voting, epoch int64) ([32]byte, error) {
+func getMerkleRootFromContract(cronjob *votingCronjob, epoch int64) ([32]byte, error) {
+	votingContract, ok := cronjob.contract.(*votingContractCChain).voting.(*voting.Voting)
+	if !ok {
+		return [32]byte{}, errors.New("voting cronjob is not bound to the voting contract")
+	}
 	ctx := sysContext.Background()
 	opts := &bind.CallOpts{Context: ctx}
 	merkleRoot, err := votingContract.GetMerkleRoot(opts, big.NewInt(epoch))
@@ -139,42 +144,41 @@ func getMerkleRootFromContract(votingContract *voting.Voting, epoch int64) ([32]
 
 func TestVoting(t *testing.T) {
 	now := time.Unix(1675349340, 0) // 2023-02-02 14:49:00 UTC
-	vCronjob1, vCronjob2, mCronjob, indexer1, indexer2, err := createTestVotingClients(now)
+	vCronjob1, vCronjob2, mCronjob, indexer1, indexer2, err := createTestVotingClients()
 	require.NoError(t, err)
 
 	// Run indexer to allow voting client test to fetch validator data
 	// We need two indexers, each one for a different voting client,
 	// since the progress is stored in the DB
 	t.Run("Run indexer 1", func(t *testing.T) {
-		err := indexer1.IndexBatch()
+		err := indexer1.IndexBatch(sysContext.Background())
 		require.NoError(t, err)
 	})
 	t.Run("Run indexer 2", func(t *testing.T) {
-		err := indexer2.IndexBatch()
+		err := indexer2.IndexBatch(sysContext.Background())
 		require.NoError(t, err)
 	})
 
 	t.Run("Run voting clients 1 and 2", func(t *testing.T) {
-		vCronjob1.time.SetNow(now)
-		vCronjob2.time.SetNow(now)
+		clock := utils.NewFixedClock(now)
+		vCronjob1.clock = clock
+		vCronjob2.clock = clock
 		for i := 0; i < 10; i++ {
-			err := vCronjob1.Call()
+			err := vCronjob1.Call(sysContext.Background())
 			require.NoError(t, err)
-			err = vCronjob2.Call()
+			err = vCronjob2.Call(sysContext.Background())
 			require.NoError(t, err)
-			vCronjob1.time.AdvanceNow(30 * time.Second)
-			vCronjob2.time.AdvanceNow(30 * time.Second)
+			clock.Advance(30 * time.Second)
 		}
 	})
 	t.Run("Verify merkle root", func(t *testing.T) {
-		root, err := getMerkleRootFromContract(vCronjob1.votingContract, 0)
+		root, err := getMerkleRootFromContract(vCronjob1, 0)
 		require.NoError(t, err)
 		cupaloy.SnapshotT(t, root)
 	})
 	t.Run("Run mirroring client", func(t *testing.T) {
-		mCronjob.time.SetNow(now)
-		mCronjob.time.AdvanceNow(10 * 30 * time.Second)
-		err := mCronjob.Call()
+		mCronjob.clock = utils.NewFixedClock(now.Add(10 * 30 * time.Second))
+		err := mCronjob.Call(sysContext.Background())
 		require.NoError(t, err)
 	})
 }
@@ -0,0 +1,95 @@
+package cronjob
+
+import (
+	"context"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
+	"flare-indexer/indexer/pchain"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrVoteRunAborted = errors.New("vote run aborted")
+	ErrVoteNotNeeded  = errors.New("vote not needed")
+)
+
+// Vote of an epoch to be submitted by RunVoteEpoch
+type VoteRunSummary struct {
+	Epoch      int64
+	TxCount    int
+	MerkleRoot common.Hash
+}
+
+func (s VoteRunSummary) String() string {
+	return fmt.Sprintf("epoch %d: merkle root %s of %d staking transactions", s.Epoch, s.MerkleRoot.Hex(), s.TxCount)
+}
+
+// Submits the vote of the epoch once with the configured private key,
+// independently of the voting cronjob, whose state is not changed. The vote is
+// only submitted if the contract expects it (ErrVoteNotNeeded otherwise) and
+// confirm returns true for its summary (ErrVoteRunAborted otherwise).
+func RunVoteEpoch(
+	ctx context.Context,
+	ictx indexerctx.IndexerContext,
+	epoch int64,
+	confirm func(summary VoteRunSummary) bool,
+) error {
+	cfg := ictx.Config()
+	if err := checkContractAddresses(cfg, false); err != nil {
+		return err
+	}
+	if err := probeContracts(cfg, ictx.EthClient(), false); err != nil {
+		return err
+	}
+	contract, err := newVotingContractCChain(cfg, ictx.EthClient(), newGasAccounting(ictx))
+	if err != nil {
+		return err
+	}
+	c, err := newVotingCronjob(cfg, &votingDBGorm{g: ictx.DB()}, contract)
+	if err != nil {
+		return err
+	}
+	c.events = ictx.Events()
+	return c.runEpoch(ctx, epoch, confirm)
+}
+
+func (c *votingCronjob) runEpoch(ctx context.Context, epoch int64, confirm func(summary VoteRunSummary) bool) error {
+	idxState, err := c.db.FetchState(ctx, pchain.StateName)
+	if err != nil {
+		return err
+	}
+	if c.indexerBehind(&idxState, epoch) {
+		return fmt.Errorf("indexer is behind, epoch %d cannot be voted yet", epoch)
+	}
+	shouldVote, err := c.contract.ShouldVote(big.NewInt(epoch))
+	if err != nil {
+		return err
+	}
+	if !shouldVote {
+		return ErrVoteNotNeeded
+	}
+
+	start, end := c.epochs.GetTimeRange(epoch)
+	merkleRoot, txCount, err := c.merkleRoot(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	c.recordMerkleRoot(ctx, epoch, merkleRoot, txCount)
+	if !confirm(VoteRunSummary{Epoch: epoch, TxCount: txCount, MerkleRoot: merkleRoot}) {
+		return ErrVoteRunAborted
+	}
+
+	if err := c.contract.SubmitVote(big.NewInt(epoch), [32]byte(merkleRoot)); err != nil {
+		return err
+	}
+	c.events.Publish(events.EpochFinalized, events.EpochFinalizedData{
+		Epoch:      epoch,
+		MerkleRoot: merkleRoot.Hex(),
+		TxCount:    txCount,
+	})
+	return nil
+}
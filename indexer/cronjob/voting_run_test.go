@@ -0,0 +1,62 @@
+//go:build !integration
+// +build !integration
+
+package cronjob
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/utils/staking"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoteRun(t *testing.T) {
+	epochs := initEpochCronjob()
+	db := &votingDBTest{
+		states: map[string]database.State{
+			pchain.StateName: {Updated: time.Now(), NextDBIndex: 3, LastChainIndex: 2},
+		},
+		votingData: map[timeRange][]database.PChainTxData{
+			timeRangeForEpoch(epochs, 2): {newTxData(1), newTxData(2)},
+		},
+	}
+	contract := &votingContractTest{
+		shouldVote:     map[int64]bool{2: true},
+		submittedVotes: make(map[int64][32]byte),
+	}
+	j := votingCronjob{db: db, contract: contract, epochCronjob: epochs}
+
+	var summaries []VoteRunSummary
+	confirm := func(answer bool) func(VoteRunSummary) bool {
+		return func(s VoteRunSummary) bool {
+			summaries = append(summaries, s)
+			return answer
+		}
+	}
+
+	err := j.runEpoch(context.Background(), 2, confirm(false))
+	require.ErrorIs(t, err, ErrVoteRunAborted)
+	require.Empty(t, contract.submittedVotes)
+
+	require.NoError(t, j.runEpoch(context.Background(), 2, confirm(true)))
+	merkleRoot, err := staking.GetMerkleRoot([]database.PChainTxData{newTxData(1), newTxData(2)})
+	require.NoError(t, err)
+	require.Equal(t, [32]byte(merkleRoot), contract.submittedVotes[2])
+	summary := VoteRunSummary{Epoch: 2, TxCount: 2, MerkleRoot: merkleRoot}
+	require.Equal(t, []VoteRunSummary{summary, summary}, summaries)
+
+	// The contract does not expect another vote
+	err = j.runEpoch(context.Background(), 2, confirm(true))
+	require.ErrorIs(t, err, ErrVoteNotNeeded)
+
+	// Epochs not indexed yet cannot be voted
+	err = j.runEpoch(context.Background(), 100, confirm(true))
+	require.ErrorContains(t, err, "indexer is behind")
+	require.Len(t, summaries, 2)
+	// The state of the voting cronjob is not changed
+	require.NotContains(t, db.states, votingStateName)
+}
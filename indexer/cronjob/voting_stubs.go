@@ -1,15 +1,18 @@
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
+	"flare-indexer/utils/chain"
 	"flare-indexer/utils/contracts/voting"
 	"flare-indexer/utils/staking"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"gorm.io/gorm"
 )
 
@@ -17,26 +20,38 @@ type votingDBGorm struct {
 	g *gorm.DB
 }
 
-func (db *votingDBGorm) FetchState(name string) (database.State, error) {
-	return database.FetchState(db.g, name)
+func (db *votingDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, db.g, name)
 }
 
-func (db *votingDBGorm) FetchPChainVotingData(start, end time.Time) ([]database.PChainTxData, error) {
-	return database.FetchPChainVotingData(db.g, start, end)
+func (db *votingDBGorm) FetchPChainVotingDataPage(ctx context.Context, start, end time.Time, afterID uint64, limit int) ([]database.PChainTxData, error) {
+	return database.FetchPChainVotingDataPage(ctx, db.g, start, end, afterID, limit)
 }
 
-func (db *votingDBGorm) UpdateState(state *database.State) error {
-	return database.UpdateState(db.g, state)
+func (db *votingDBGorm) UpdateState(ctx context.Context, state *database.State) error {
+	return database.UpdateState(ctx, db.g, state)
+}
+
+func (db *votingDBGorm) CreateEpochMerkleRoot(ctx context.Context, root *database.EpochMerkleRoot) error {
+	return database.CreateEpochMerkleRoot(ctx, db.g, root)
+}
+
+// Subset of the voting contract binding used by the voting cronjob
+type votingBinding interface {
+	staking.EpochConfigCaller
+	ShouldVote(opts *bind.CallOpts, epochId *big.Int, voter common.Address) (bool, error)
+	SubmitVote(opts *bind.TransactOpts, epochId *big.Int, merkleRoot [32]byte) (*types.Transaction, error)
 }
 
 type votingContractCChain struct {
 	callOpts *bind.CallOpts
 	txOpts   *bind.TransactOpts
-	voting   *voting.Voting
+	voting   votingBinding
+	gas      *gasAccounting
 }
 
-func newVotingContractCChain(cfg *config.Config) (votingContract, error) {
-	votingContract, err := newVotingContract(cfg)
+func newVotingContractCChain(cfg *config.Config, eth chain.EthClient, gas *gasAccounting) (votingContract, error) {
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, eth)
 	if err != nil {
 		return nil, err
 	}
@@ -52,30 +67,32 @@ func newVotingContractCChain(cfg *config.Config) (votingContract, error) {
 	}
 	txOpts.GasLimit = cfg.VotingCronjob.GasLimit
 
-	callOpts := &bind.CallOpts{From: txOpts.From}
+	contract := newVotingContractCChainWithBinding(votingContract, txOpts)
+	contract.gas = gas
+	return contract, nil
+}
 
+func newVotingContractCChainWithBinding(votingContract votingBinding, txOpts *bind.TransactOpts) *votingContractCChain {
 	return &votingContractCChain{
-		callOpts: callOpts,
+		callOpts: &bind.CallOpts{From: txOpts.From},
 		txOpts:   txOpts,
 		voting:   votingContract,
-	}, nil
-}
-
-func newVotingContract(cfg *config.Config) (*voting.Voting, error) {
-	eth, err := ethclient.Dial(cfg.Chain.EthRPCURL)
-	if err != nil {
-		return nil, err
 	}
-	return voting.NewVoting(cfg.ContractAddresses.Voting, eth)
 }
 
 func (c *votingContractCChain) ShouldVote(epoch *big.Int) (bool, error) {
 	return c.voting.ShouldVote(c.callOpts, epoch, c.callOpts.From)
 }
 
+// Submits the vote and waits until it is mined to record its gas
 func (c *votingContractCChain) SubmitVote(epoch *big.Int, merkleRoot [32]byte) error {
-	_, err := c.voting.SubmitVote(c.txOpts, epoch, merkleRoot)
-	return err
+	tx, err := c.voting.SubmitVote(c.txOpts, epoch, merkleRoot)
+	if err != nil {
+		return err
+	}
+	epochID := epoch.Int64()
+	c.gas.waitAndRecord(votingCronjobName, &epochID, tx, c.txOpts.From.Hex())
+	return nil
 }
 
 func (c *votingContractCChain) EpochConfig() (start time.Time, period time.Duration, err error) {
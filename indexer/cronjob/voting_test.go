@@ -1,22 +1,32 @@
+//go:build !integration
+// +build !integration
+
 package cronjob
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/featureflags"
 	"flare-indexer/indexer/pchain"
+	"flare-indexer/utils"
 	"flare-indexer/utils/staking"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/bradleyjkemp/cupaloy"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
 type votingDBTest struct {
-	states     map[string]database.State
-	votingData map[timeRange][]database.PChainTxData
+	states      map[string]database.State
+	votingData  map[timeRange][]database.PChainTxData
+	merkleRoots []database.EpochMerkleRoot
 }
 
 type timeRange struct {
@@ -24,7 +34,7 @@ type timeRange struct {
 	end   time.Time
 }
 
-func (db *votingDBTest) FetchState(name string) (database.State, error) {
+func (db *votingDBTest) FetchState(ctx context.Context, name string) (database.State, error) {
 	state, ok := db.states[name]
 	if ok {
 		return state, nil
@@ -33,15 +43,26 @@ func (db *votingDBTest) FetchState(name string) (database.State, error) {
 	return database.State{Name: name}, nil
 }
 
-func (db *votingDBTest) FetchPChainVotingData(start, end time.Time) ([]database.PChainTxData, error) {
-	return db.votingData[timeRange{start, end}], nil
+func (db *votingDBTest) FetchPChainVotingDataPage(ctx context.Context, start, end time.Time, afterID uint64, limit int) ([]database.PChainTxData, error) {
+	var page []database.PChainTxData
+	for _, tx := range db.votingData[timeRange{start, end}] {
+		if tx.InputIndex == 0 && tx.ID > afterID && len(page) < limit {
+			page = append(page, tx)
+		}
+	}
+	return page, nil
 }
 
-func (db *votingDBTest) UpdateState(state *database.State) error {
+func (db *votingDBTest) UpdateState(ctx context.Context, state *database.State) error {
 	db.states[state.Name] = *state
 	return nil
 }
 
+func (db *votingDBTest) CreateEpochMerkleRoot(ctx context.Context, root *database.EpochMerkleRoot) error {
+	db.merkleRoots = append(db.merkleRoots, *root)
+	return nil
+}
+
 type votingContractTest struct {
 	shouldVote     map[int64]bool
 	submittedVotes map[int64][32]byte
@@ -76,7 +97,7 @@ func TestNoVotes(t *testing.T) {
 		epochCronjob: initEpochCronjob(),
 	}
 
-	err := cronjob.Call()
+	err := cronjob.Call(context.Background())
 	require.NoError(t, err)
 	require.Empty(t, contract.submittedVotes)
 }
@@ -112,22 +133,106 @@ func TestVotes(t *testing.T) {
 		epochCronjob: epochs,
 	}
 
-	err := cronjob.Call()
+	err := cronjob.Call(context.Background())
 	require.NoError(t, err)
 	require.NotEmpty(t, contract.submittedVotes)
 
 	cupaloy.SnapshotT(t, contract.submittedVotes)
 
+	// The submitted roots are recorded, unsigned
+	require.Len(t, db.merkleRoots, 2)
+	for _, root := range db.merkleRoots {
+		require.Equal(t, common.Hash(contract.submittedVotes[root.Epoch]).Hex(), root.MerkleRoot)
+		require.Empty(t, root.Signature)
+	}
+	require.Equal(t, 2, db.merkleRoots[1].TxCount)
+
 	updatedState := db.states[votingStateName]
 	require.Equal(t, updatedState.NextDBIndex, uint64(1))
 
-	err = cronjob.Call()
+	err = cronjob.Call(context.Background())
 	require.NoError(t, err)
 
 	updatedState = db.states[votingStateName]
 	require.Equal(t, updatedState.NextDBIndex, uint64(6))
 }
 
+func TestVotesDryRun(t *testing.T) {
+	epochs := initEpochCronjob()
+	epochs.flags = featureflags.NewStatic(map[string]string{"test.dry_run": "true"})
+
+	db := votingDBTest{
+		states: map[string]database.State{
+			pchain.StateName: {
+				Updated:        time.Now(),
+				NextDBIndex:    3,
+				LastChainIndex: 2,
+			},
+		},
+		votingData: map[timeRange][]database.PChainTxData{
+			timeRangeForEpoch(epochs, 1): {newTxData(0)},
+		},
+	}
+
+	contract := votingContractTest{
+		shouldVote:     map[int64]bool{1: true},
+		submittedVotes: make(map[int64][32]byte),
+	}
+
+	cronjob := votingCronjob{
+		db:           &db,
+		contract:     &contract,
+		epochCronjob: epochs,
+	}
+
+	err := cronjob.Call(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, contract.submittedVotes)
+	// The roots computed in dry-run mode are recorded as well
+	require.NotEmpty(t, db.merkleRoots)
+	require.EqualValues(t, 1, db.merkleRoots[1].Epoch)
+	require.Equal(t, 1, db.merkleRoots[1].TxCount)
+
+	_, ok := db.states[votingStateName]
+	require.False(t, ok)
+}
+
+func TestSignedMerkleRoots(t *testing.T) {
+	epochs := initEpochCronjob()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	db := votingDBTest{
+		states: map[string]database.State{
+			pchain.StateName: {Updated: time.Now(), NextDBIndex: 3, LastChainIndex: 2},
+		},
+		votingData: map[timeRange][]database.PChainTxData{
+			timeRangeForEpoch(epochs, 1): {newTxData(0)},
+		},
+	}
+	contract := votingContractTest{
+		shouldVote:     map[int64]bool{1: true},
+		submittedVotes: make(map[int64][32]byte),
+	}
+	cronjob := votingCronjob{
+		db:           &db,
+		contract:     &contract,
+		epochCronjob: epochs,
+		rootSigner:   &merkleRootSigner{key: key, voting: testContractAddress},
+	}
+	require.NoError(t, cronjob.Call(context.Background()))
+
+	require.Len(t, db.merkleRoots, 1)
+	root := db.merkleRoots[0]
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	require.Equal(t, signer.Hex(), root.Signer)
+	signature, err := hexutil.Decode(root.Signature)
+	require.NoError(t, err)
+	recovered, err := staking.RecoverMerkleRootSigner(signature, testContractAddress, root.Epoch, common.HexToHash(root.MerkleRoot))
+	require.NoError(t, err)
+	require.Equal(t, signer, recovered)
+}
+
 func timeRangeForEpoch(cj epochCronjob, epoch int64) timeRange {
 	start, end := cj.epochs.GetTimeRange(epoch)
 
@@ -146,17 +251,68 @@ func newTxData(id int) database.PChainTxData {
 
 	return database.PChainTxData{
 		PChainTx: database.PChainTx{
-			ChainID:   "costwo",
-			NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
-			StartTime: &startTime,
-			EndTime:   &endTime,
-			TxID:      &txIDs[id],
-			Type:      database.PChainAddDelegatorTx,
+			BaseEntity: database.BaseEntity{ID: uint64(id + 1)},
+			ChainID:    "costwo",
+			NodeID:     "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+			StartTime:  &startTime,
+			EndTime:    &endTime,
+			TxID:       &txIDs[id],
+			Type:       database.PChainAddDelegatorTx,
 		},
 		InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
 	}
 }
 
+func TestEpochDeadline(t *testing.T) {
+	cj := initEpochCronjob()
+	// By the clock of the cronjob, almost an hour behind the system clock
+	now := cj.epochs.Start.Add(cj.epochs.Period + time.Second)
+	left := cj.epochs.Period - time.Second
+
+	before := time.Now()
+	ctx, cancel := cj.withEpochDeadline(context.Background(), now)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinRange(t, deadline, before.Add(left), time.Now().Add(left))
+}
+
+// Calls exactly at an epoch boundary vote on the epoch which has just ended
+func TestVotesAtEpochBoundary(t *testing.T) {
+	epochs := initEpochCronjob()
+	db := votingDBTest{
+		states: map[string]database.State{
+			pchain.StateName: {Updated: time.Now(), NextDBIndex: 3, LastChainIndex: 2},
+			votingStateName:  {Name: votingStateName, NextDBIndex: 1},
+		},
+		votingData: map[timeRange][]database.PChainTxData{
+			timeRangeForEpoch(epochs, 1): {newTxData(0)},
+			timeRangeForEpoch(epochs, 2): {newTxData(1)},
+		},
+	}
+	contract := votingContractTest{
+		shouldVote:     map[int64]bool{1: true, 2: true},
+		submittedVotes: make(map[int64][32]byte),
+	}
+	clock := utils.NewFixedClock(epochs.epochs.GetEndTime(1).Add(-time.Nanosecond))
+	epochs.clock = clock
+	cronjob := votingCronjob{db: &db, contract: &contract, epochCronjob: epochs}
+
+	// Epoch 1 has not ended yet
+	require.NoError(t, cronjob.Call(context.Background()))
+	require.Empty(t, contract.submittedVotes)
+
+	clock.Advance(time.Nanosecond)
+	require.NoError(t, cronjob.Call(context.Background()))
+	require.Len(t, contract.submittedVotes, 1)
+	require.Contains(t, contract.submittedVotes, int64(1))
+
+	// The clock is stepped back into epoch 1, the epoch is not voted on again
+	clock.Advance(-time.Second)
+	require.NoError(t, cronjob.Call(context.Background()))
+	require.Len(t, contract.submittedVotes, 1)
+}
+
 func initEpochCronjob() epochCronjob {
 	cronjobCfg := config.CronjobConfig{
 		Enabled:   true,
@@ -169,5 +325,9 @@ func initEpochCronjob() epochCronjob {
 		Start:  time.Now().Add(-time.Hour),
 	}
 
-	return newEpochCronjob(&cronjobCfg, epochInfo)
+	cj, err := newEpochCronjob("test", &cronjobCfg, epochInfo)
+	if err != nil {
+		panic(err)
+	}
+	return cj
 }
@@ -0,0 +1,364 @@
+// Synthetic P-chain and X-chain data for local development, so the services
+// and the cronjobs can be developed without indexing a real network.
+package devdata
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+const (
+	nanoFLR = 1_000_000_000
+	// Burned amount of each transaction
+	txFee = 1_000_000
+	// Delegation fee shares of validators, 1% is 10000
+	minFeeShares = 20_000
+	maxFeeShares = 200_000
+)
+
+type Options struct {
+	// HRP of the generated addresses, e.g. "localflare"
+	HRP string
+	// Epochs with staking transactions starting in them, the first epoch starts
+	// at EpochStart
+	Epochs      int
+	EpochStart  time.Time
+	EpochPeriod time.Duration
+	Validators  int
+	// Number of delegations of each validator
+	DelegatorsPerValidator int
+	// Equal seeds generate equal data
+	Seed int64
+}
+
+// Generated entities, blocks and vertices are ordered by height
+type Data struct {
+	PChainBlocks  []*database.PChainBlock
+	PChainTxs     []*database.PChainTx
+	PChainInputs  []*database.PChainTxInput
+	PChainOutputs []*database.PChainTxOutput
+
+	XChainVertices []*database.XChainVtx
+	XChainTxs      []*database.XChainTx
+	XChainInputs   []*database.XChainTxInput
+	XChainOutputs  []*database.XChainTxOutput
+}
+
+// Generates the data: each staker is funded by an X-chain transfer and a P-chain
+// import to its own address, whose output is spent by the staking transaction.
+// Validators start in a random epoch and stay active for one to three times the
+// number of epochs, delegations start in a random epoch within the validation
+// period and end before the validator. Each P-chain transaction is in its own
+// standard block, each X-chain transaction in its own vertex.
+func Generate(opts *Options) (*Data, error) {
+	if opts.Epochs <= 0 || opts.EpochPeriod <= 0 || opts.Validators <= 0 || opts.DelegatorsPerValidator < 0 {
+		return nil, fmt.Errorf("epochs, epoch period and validators must be positive")
+	}
+	g := &generator{
+		opts:    opts,
+		rng:     rand.New(rand.NewSource(opts.Seed)),
+		data:    &Data{},
+		pBlocks: make(map[*database.PChainTx]time.Time),
+	}
+	g.xChain = g.id()
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+	return g.data, nil
+}
+
+type generator struct {
+	opts *Options
+	rng  *rand.Rand
+	data *Data
+
+	// ID of the X-chain, the source chain of the imports
+	xChain ids.ID
+	// Output of the X-chain faucet spent by the next transfer
+	faucet *database.XChainTxOutput
+	// Time of the blocks of the P-chain transactions
+	pBlocks map[*database.PChainTx]time.Time
+}
+
+type staker struct {
+	address   string
+	nodeID    string
+	start     time.Time
+	end       time.Time
+	weight    uint64
+	validator bool
+}
+
+func (g *generator) generate() error {
+	var stakers []*staker
+	for i := 0; i < g.opts.Validators; i++ {
+		validator, err := g.newValidator()
+		if err != nil {
+			return err
+		}
+		stakers = append(stakers, validator)
+		for j := 0; j < g.opts.DelegatorsPerValidator; j++ {
+			delegator, err := g.newDelegator(validator)
+			if err != nil {
+				return err
+			}
+			stakers = append(stakers, delegator)
+		}
+	}
+
+	// Stakers are funded a day before the first epoch, in the order of their
+	// staking transactions
+	sort.SliceStable(stakers, func(i, j int) bool { return stakers[i].start.Before(stakers[j].start) })
+	fundingTime := g.opts.EpochStart.Add(-24 * time.Hour)
+	if err := g.fundFaucet(fundingTime); err != nil {
+		return err
+	}
+	for _, s := range stakers {
+		fundingTime = fundingTime.Add(time.Minute)
+		amount := s.weight + 2*txFee + uint64(g.rng.Int63n(1000))*nanoFLR
+		transferOut := g.transfer(fundingTime, s.address, amount)
+		importOut := g.importToPChain(fundingTime.Add(10*time.Second), transferOut)
+		// Staking transactions are issued shortly before the start of the stake
+		issued := s.start.Add(-time.Duration(1+g.rng.Intn(30)) * time.Minute)
+		g.stake(issued, s, importOut)
+	}
+	g.buildPChainBlocks()
+	return nil
+}
+
+// Validators start in a random epoch and stay for 1-3 times the number of epochs
+func (g *generator) newValidator() (*staker, error) {
+	address, err := g.address()
+	if err != nil {
+		return nil, err
+	}
+	start := g.epochTime(g.rng.Intn(g.opts.Epochs))
+	periods := g.opts.Epochs * (1 + g.rng.Intn(3))
+	return &staker{
+		address:   address,
+		nodeID:    ids.NodeID(g.shortID()).String(),
+		start:     start,
+		end:       start.Add(time.Duration(periods) * g.opts.EpochPeriod),
+		weight:    uint64(1_000_000+g.rng.Intn(49_000_000)) * nanoFLR,
+		validator: true,
+	}, nil
+}
+
+// Delegations start in a random epoch of the validation period and end before
+// the validator
+func (g *generator) newDelegator(validator *staker) (*staker, error) {
+	address, err := g.address()
+	if err != nil {
+		return nil, err
+	}
+	firstEpoch := int(validator.start.Sub(g.opts.EpochStart) / g.opts.EpochPeriod)
+	start := g.epochTime(firstEpoch + g.rng.Intn(g.opts.Epochs-firstEpoch))
+	if start.Before(validator.start) {
+		start = validator.start.Add(time.Minute)
+	}
+	end := start.Add(time.Duration(1+g.rng.Intn(g.opts.Epochs)) * g.opts.EpochPeriod)
+	if end.After(validator.end) {
+		end = validator.end
+	}
+	return &staker{
+		address: address,
+		nodeID:  validator.nodeID,
+		start:   start,
+		end:     end,
+		weight:  uint64(10_000+g.rng.Intn(990_000)) * nanoFLR,
+	}, nil
+}
+
+// Random time in the first half of the epoch
+func (g *generator) epochTime(epoch int) time.Time {
+	offset := time.Duration(g.rng.Int63n(int64(g.opts.EpochPeriod / 2)))
+	return g.opts.EpochStart.Add(time.Duration(epoch)*g.opts.EpochPeriod + offset).Truncate(time.Second)
+}
+
+// Creates the genesis output of the X-chain faucet funding the stakers
+func (g *generator) fundFaucet(timestamp time.Time) error {
+	address, err := g.address()
+	if err != nil {
+		return err
+	}
+	tx := g.newXChainTx(timestamp)
+	g.faucet = &database.XChainTxOutput{TxOutput: database.TxOutput{
+		TxID:    tx.TxID,
+		Amount:  1 << 62,
+		Address: address,
+	}}
+	g.data.XChainOutputs = append(g.data.XChainOutputs, g.faucet)
+	return nil
+}
+
+// Transfers the amount from the faucet to the address on the X-chain, returns
+// the output to the address
+func (g *generator) transfer(timestamp time.Time, address string, amount uint64) *database.XChainTxOutput {
+	tx := g.newXChainTx(timestamp)
+	tx.Fee = txFee
+	g.data.XChainInputs = append(g.data.XChainInputs, &database.XChainTxInput{TxInput: database.TxInput{
+		TxID:    tx.TxID,
+		Amount:  g.faucet.Amount,
+		Address: g.faucet.Address,
+		OutTxID: g.faucet.TxID,
+		OutIdx:  g.faucet.Idx,
+	}})
+	out := &database.XChainTxOutput{TxOutput: database.TxOutput{
+		TxID:    tx.TxID,
+		Amount:  amount,
+		Idx:     0,
+		Address: address,
+	}}
+	change := &database.XChainTxOutput{TxOutput: database.TxOutput{
+		TxID:    tx.TxID,
+		Amount:  g.faucet.Amount - amount - txFee,
+		Idx:     1,
+		Address: g.faucet.Address,
+	}}
+	g.data.XChainOutputs = append(g.data.XChainOutputs, out, change)
+	g.faucet = change
+	return out
+}
+
+func (g *generator) newXChainTx(timestamp time.Time) *database.XChainTx {
+	height := uint64(len(g.data.XChainVertices))
+	parentID := ids.Empty.String()
+	if height > 0 {
+		parentID = g.data.XChainVertices[height-1].VtxID
+	}
+	vtx := &database.XChainVtx{
+		VtxID:     g.id().String(),
+		ParentID:  parentID,
+		VtxIndex:  height,
+		Height:    height,
+		Timestamp: timestamp,
+	}
+	g.data.XChainVertices = append(g.data.XChainVertices, vtx)
+	tx := &database.XChainTx{
+		Type:      database.XChainBaseTx,
+		TxID:      g.id().String(),
+		VtxHeight: height,
+		VtxID:     vtx.VtxID,
+		VtxIndex:  vtx.VtxIndex,
+		Timestamp: timestamp,
+	}
+	g.data.XChainTxs = append(g.data.XChainTxs, tx)
+	return tx
+}
+
+// Imports the X-chain output to the P-chain, the imported input is spent on
+// the X-chain and not stored with the P-chain transaction
+func (g *generator) importToPChain(timestamp time.Time, in *database.XChainTxOutput) *database.PChainTxOutput {
+	tx := g.newPChainTx(timestamp, database.PChainImportTx)
+	tx.ChainID = g.xChain.String()
+	tx.Fee = txFee
+	out := &database.PChainTxOutput{
+		TxOutput: database.TxOutput{
+			TxID:    *tx.TxID,
+			Amount:  in.Amount - txFee,
+			Address: in.Address,
+		},
+		Type: database.PChainDefaultOutput,
+	}
+	g.data.PChainOutputs = append(g.data.PChainOutputs, out)
+	return out
+}
+
+// Stakes the weight of the imported output, the rest is returned as change
+func (g *generator) stake(timestamp time.Time, s *staker, in *database.PChainTxOutput) {
+	txType := database.PChainAddDelegatorTx
+	if s.validator {
+		txType = database.PChainAddValidatorTx
+	}
+	tx := g.newPChainTx(timestamp, txType)
+	start, end := s.start, s.end
+	tx.NodeID = s.nodeID
+	tx.StartTime = &start
+	tx.EndTime = &end
+	tx.Weight = s.weight
+	tx.RewardsOwner = s.address
+	tx.Fee = txFee
+	if s.validator {
+		tx.FeePercentage = uint32(minFeeShares + g.rng.Intn(maxFeeShares-minFeeShares))
+	}
+
+	g.data.PChainInputs = append(g.data.PChainInputs, &database.PChainTxInput{TxInput: database.TxInput{
+		TxID:    *tx.TxID,
+		Amount:  in.Amount,
+		Address: in.Address,
+		OutTxID: in.TxID,
+		OutIdx:  in.Idx,
+	}})
+	g.data.PChainOutputs = append(g.data.PChainOutputs,
+		&database.PChainTxOutput{
+			TxOutput: database.TxOutput{TxID: *tx.TxID, Amount: in.Amount - s.weight - txFee, Idx: 0, Address: s.address},
+			Type:     database.PChainDefaultOutput,
+		},
+		&database.PChainTxOutput{
+			TxOutput: database.TxOutput{TxID: *tx.TxID, Amount: s.weight, Idx: 1, Address: s.address},
+			Type:     database.PChainStakeOutput,
+		},
+	)
+}
+
+func (g *generator) newPChainTx(timestamp time.Time, txType database.PChainTxType) *database.PChainTx {
+	txID := g.id().String()
+	tx := &database.PChainTx{
+		Type:      txType,
+		TxID:      &txID,
+		BlockType: database.PChainStandardBlock,
+		Timestamp: timestamp,
+		Status:    database.PChainTxAccepted,
+	}
+	g.data.PChainTxs = append(g.data.PChainTxs, tx)
+	g.pBlocks[tx] = timestamp
+	return tx
+}
+
+// Orders the P-chain transactions by the time of their blocks and creates a
+// block for each transaction. The container index of a block is its height
+// minus one, as on a node indexing the chain from the start.
+func (g *generator) buildPChainBlocks() {
+	txs := g.data.PChainTxs
+	sort.SliceStable(txs, func(i, j int) bool { return g.pBlocks[txs[i]].Before(g.pBlocks[txs[j]]) })
+	parentID := g.id().String() // genesis block, not indexed
+	for i, tx := range txs {
+		height := uint64(i + 1)
+		containerIndex := height - 1
+		block := &database.PChainBlock{
+			Height:         height,
+			BlockID:        g.id().String(),
+			ParentID:       parentID,
+			Timestamp:      g.pBlocks[tx],
+			TxCount:        1,
+			ContainerIndex: &containerIndex,
+		}
+		tx.BlockID = block.BlockID
+		tx.BlockHeight = height
+		g.data.PChainBlocks = append(g.data.PChainBlocks, block)
+		parentID = block.BlockID
+	}
+}
+
+func (g *generator) id() ids.ID {
+	var id ids.ID
+	g.rng.Read(id[:])
+	return id
+}
+
+func (g *generator) shortID() ids.ShortID {
+	var id ids.ShortID
+	g.rng.Read(id[:])
+	return id
+}
+
+func (g *generator) address() (string, error) {
+	id := g.shortID()
+	return chain.FormatAddressBytes(g.opts.HRP, id[:])
+}
@@ -0,0 +1,108 @@
+//go:build !integration
+// +build !integration
+
+package devdata
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions() *Options {
+	return &Options{
+		HRP:                    "localflare",
+		Epochs:                 5,
+		EpochStart:             time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		EpochPeriod:            24 * time.Hour,
+		Validators:             4,
+		DelegatorsPerValidator: 3,
+		Seed:                   1,
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	chain.RegisterAddressHRP("localflare")
+	opts := testOptions()
+	data, err := Generate(opts)
+	require.NoError(t, err)
+
+	stakers := opts.Validators * (1 + opts.DelegatorsPerValidator)
+	require.Len(t, data.PChainTxs, 2*stakers) // import and staking transaction
+	require.Len(t, data.PChainBlocks, len(data.PChainTxs))
+	require.Len(t, data.XChainTxs, 1+stakers) // faucet and transfers
+	require.Len(t, data.XChainVertices, len(data.XChainTxs))
+
+	// Blocks are ordered by time, each with one transaction
+	for i, block := range data.PChainBlocks {
+		require.Equal(t, uint64(i+1), block.Height)
+		require.Equal(t, uint64(i), *block.ContainerIndex)
+		require.Equal(t, block.BlockID, data.PChainTxs[i].BlockID)
+		if i > 0 {
+			require.Equal(t, data.PChainBlocks[i-1].BlockID, block.ParentID)
+			require.False(t, block.Timestamp.Before(data.PChainBlocks[i-1].Timestamp))
+		}
+	}
+
+	// Inputs spend existing outputs of the same chain
+	pOutputs := make(map[string]*database.PChainTxOutput)
+	for _, out := range data.PChainOutputs {
+		pOutputs[fmt.Sprintf("%s/%d", out.TxID, out.Idx)] = out
+	}
+	for _, in := range data.PChainInputs {
+		out := pOutputs[fmt.Sprintf("%s/%d", in.OutTxID, in.OutIdx)]
+		require.NotNil(t, out)
+		require.Equal(t, out.Amount, in.Amount)
+		require.Equal(t, out.Address, in.Address)
+	}
+	xOutputs := make(map[string]*database.XChainTxOutput)
+	for _, out := range data.XChainOutputs {
+		xOutputs[fmt.Sprintf("%s/%d", out.TxID, out.Idx)] = out
+	}
+	for _, in := range data.XChainInputs {
+		require.NotNil(t, xOutputs[fmt.Sprintf("%s/%d", in.OutTxID, in.OutIdx)])
+	}
+
+	// Delegations are within the validation period of their validator, the
+	// stakes can be voted on
+	validators := make(map[string]*database.PChainTx)
+	var votingData []database.PChainTxData
+	for _, tx := range data.PChainTxs {
+		switch tx.Type {
+		case database.PChainAddValidatorTx:
+			validators[tx.NodeID] = tx
+		case database.PChainAddDelegatorTx:
+		default:
+			continue
+		}
+		votingData = append(votingData, database.PChainTxData{PChainTx: *tx, InputAddress: tx.RewardsOwner})
+	}
+	require.Len(t, validators, opts.Validators)
+	for _, tx := range data.PChainTxs {
+		if tx.Type != database.PChainAddDelegatorTx {
+			continue
+		}
+		validator := validators[tx.NodeID]
+		require.NotNil(t, validator)
+		require.False(t, tx.StartTime.Before(*validator.StartTime))
+		require.False(t, tx.EndTime.After(*validator.EndTime))
+	}
+	_, err = staking.GetMerkleRoot(votingData)
+	require.NoError(t, err)
+
+	again, err := Generate(opts)
+	require.NoError(t, err)
+	require.Equal(t, data, again)
+}
+
+func TestGenerateInvalidOptions(t *testing.T) {
+	opts := testOptions()
+	opts.Validators = 0
+	_, err := Generate(opts)
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package devdata
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/xchain"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rows inserted by one statement
+const insertBatchSize = 500
+
+// Inserts the data into a database without indexed transactions and sets the
+// states of the chain indexers after the generated blocks and vertices, so the
+// cronjobs consider the chains indexed
+func Insert(ctx context.Context, db *gorm.DB, data *Data) error {
+	for _, entity := range []interface{}{&database.PChainTx{}, &database.XChainTx{}} {
+		var count int64
+		if err := db.WithContext(ctx).Model(entity).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("the database already contains indexed transactions")
+		}
+	}
+
+	db = db.Session(&gorm.Session{CreateBatchSize: insertBatchSize})
+	return database.DoInTransaction(ctx, db,
+		func(db *gorm.DB) error {
+			return database.CreatePChainEntities(ctx, db, data.PChainTxs, data.PChainInputs, data.PChainOutputs)
+		},
+		func(db *gorm.DB) error {
+			return database.CreatePChainBlocks(ctx, db, data.PChainBlocks)
+		},
+		func(db *gorm.DB) error {
+			return database.CreateXChainEntities(ctx, db, data.XChainVertices, data.XChainTxs, data.XChainInputs, data.XChainOutputs)
+		},
+		func(db *gorm.DB) error {
+			return updateIndexerState(ctx, db, pchain.StateName, uint64(len(data.PChainBlocks)))
+		},
+		func(db *gorm.DB) error {
+			return updateIndexerState(ctx, db, xchain.StateName, uint64(len(data.XChainVertices)))
+		},
+	)
+}
+
+func updateIndexerState(ctx context.Context, db *gorm.DB, name string, containers uint64) error {
+	state, err := database.FetchState(ctx, db, name)
+	if err != nil {
+		return err
+	}
+	state.NextDBIndex = containers
+	state.LastChainIndex = containers - 1
+	state.Updated = time.Now()
+	return database.UpdateState(ctx, db, &state)
+}
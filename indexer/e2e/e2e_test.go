@@ -0,0 +1,93 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/utils/staking"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexVoteAndMirror(t *testing.T) {
+	h := newHarness(t)
+
+	votingCronjob, err := cronjob.NewVotingCronjob(h.ctx)
+	require.NoError(t, err)
+	binderCronjob, err := cronjob.NewAddressBinderCronjob(h.ctx)
+	require.NoError(t, err)
+	mirrorCronjob, err := cronjob.NewMirrorCronjob(h.ctx)
+	require.NoError(t, err)
+	for _, c := range []cronjob.Cronjob{votingCronjob, binderCronjob, mirrorCronjob} {
+		require.NoError(t, c.OnStart(context.Background()))
+	}
+
+	start, period, err := staking.GetEpochConfig(h.voting)
+	require.NoError(t, err)
+	epochs := staking.EpochInfo{Start: start, Period: period}
+
+	t.Run("index p-chain", func(t *testing.T) {
+		h.indexAll(t)
+
+		var count int64
+		require.NoError(t, h.ctx.DB().Model(&database.PChainTx{}).Count(&count).Error)
+		require.Greater(t, count, int64(0))
+	})
+
+	var stakes []database.PChainTxData
+	t.Run("vote", func(t *testing.T) {
+		require.NoError(t, votingCronjob.Call(context.Background()))
+
+		state, err := database.FetchState(context.Background(), h.ctx.DB(), "voting_cronjob")
+		require.NoError(t, err)
+		require.Greater(t, state.NextDBIndex, uint64(0))
+
+		// The root on chain must match the root of the indexed stakes for all voted epochs
+		for epoch := int64(0); epoch < int64(state.NextDBIndex); epoch++ {
+			from, to := epochs.GetTimeRange(epoch)
+			txs, err := database.GetPChainTxsForEpoch(context.Background(), &database.GetPChainTxsForEpochInput{
+				DB:             h.ctx.DB(),
+				StartTimestamp: from,
+				EndTimestamp:   to,
+			})
+			require.NoError(t, err)
+			txs = staking.DedupeTxs(txs)
+			stakes = append(stakes, txs...)
+
+			expected, err := staking.GetMerkleRoot(txs)
+			require.NoError(t, err)
+
+			root, err := h.voting.GetMerkleRoot(new(bind.CallOpts), big.NewInt(epoch))
+			require.NoError(t, err)
+			if root != ([32]byte{}) {
+				require.Equal(t, [32]byte(expected), root, "merkle root of epoch %d", epoch)
+			}
+		}
+	})
+
+	t.Run("mirror", func(t *testing.T) {
+		require.NoError(t, binderCronjob.Call(context.Background()))
+		require.NoError(t, mirrorCronjob.Call(context.Background()))
+
+		for i := range stakes {
+			stakeData, err := staking.ToStakeData(&stakes[i])
+			require.NoError(t, err)
+
+			mirrored, err := h.mirroring.IsActiveStakeMirrored(
+				new(bind.CallOpts), stakeData.TxId, stakeData.InputAddress,
+			)
+			require.NoError(t, err)
+			require.True(t, mirrored, "stake %s not mirrored", *stakes[i].TxID)
+
+			attempts, err := database.FetchMirroringAttempts(context.Background(), h.ctx.DB(), *stakes[i].TxID)
+			require.NoError(t, err)
+			require.NotEmpty(t, attempts)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+//go:build e2e
+// +build e2e
+
+package e2e
+
+import (
+	"context"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/contracts/voting"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// First account of the default anvil mnemonic
+	defaultPrivateKey = "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	defaultEthRPCURL  = "http://127.0.0.1:8545"
+	defaultChainID    = 31337
+)
+
+// Harness wires the P-chain indexer (fed by recorded node data) and the cronjobs
+// to a test database and to contracts deployed on a local anvil node. Contract
+// addresses are read from the environment:
+//
+//	E2E_VOTING_CONTRACT     address of the voting contract (required)
+//	E2E_MIRRORING_CONTRACT  address of the mirroring contract (required)
+//	E2E_ETH_RPC_URL         RPC URL of the node (default http://127.0.0.1:8545)
+//	E2E_CHAIN_ID            chain id of the node (default 31337)
+//	E2E_PRIVATE_KEY         private key of the voter (default first anvil account)
+type harness struct {
+	ctx     indexerctx.IndexerContext
+	indexer *shared.ChainIndexerBase
+	client  *chain.RecordedIndexerClient
+
+	eth       *ethclient.Client
+	voting    *voting.Voting
+	mirroring *mirroring.Mirroring
+}
+
+func newHarness(t *testing.T) *harness {
+	cfg := harnessConfig(t)
+
+	ctx, err := indexerctx.BuildTestContext(cfg)
+	require.NoError(t, err)
+
+	client, err := chain.PChainTestClient()
+	require.NoError(t, err)
+
+	rpcClient, err := chain.PChainTestRPCClient()
+	require.NoError(t, err)
+
+	eth, err := ethclient.Dial(cfg.Chain.EthRPCURL)
+	require.NoError(t, err)
+
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, eth)
+	require.NoError(t, err)
+
+	mirroringContract, err := mirroring.NewMirroring(cfg.ContractAddresses.Mirroring, eth)
+	require.NoError(t, err)
+
+	return &harness{
+		ctx: ctx,
+		indexer: &shared.ChainIndexerBase{
+			StateName:   pchain.StateName,
+			IndexerName: "P-chain Blocks E2E",
+			Client:      client,
+			DB:          ctx.DB(),
+			Config:      cfg.PChainIndexer,
+			BatchIndexer: pchain.NewPChainBatchIndexer(
+				ctx, client, rpcClient,
+				pchain.NewPChainDataTransformer(extendStakingEndTime),
+			),
+		},
+		client:    client,
+		eth:       eth,
+		voting:    votingContract,
+		mirroring: mirroringContract,
+	}
+}
+
+func harnessConfig(t *testing.T) *config.Config {
+	votingAddress := os.Getenv("E2E_VOTING_CONTRACT")
+	mirroringAddress := os.Getenv("E2E_MIRRORING_CONTRACT")
+	if votingAddress == "" || mirroringAddress == "" {
+		t.Fatal("E2E_VOTING_CONTRACT and E2E_MIRRORING_CONTRACT must be set, see indexer/e2e/README.md")
+	}
+
+	chainID := defaultChainID
+	if s := os.Getenv("E2E_CHAIN_ID"); s != "" {
+		var err error
+		chainID, err = strconv.Atoi(s)
+		require.NoError(t, err)
+	}
+
+	cronjobConfig := config.CronjobConfig{
+		Enabled:   true,
+		Timeout:   30 * time.Second,
+		BatchSize: 100,
+	}
+
+	cfg := &config.Config{
+		Chain: globalConfig.ChainConfig{
+			ChainAddressHRP: "localflare",
+			ChainID:         chainID,
+			EthRPCURL:       envOrDefault("E2E_ETH_RPC_URL", defaultEthRPCURL),
+			PrivateKey:      envOrDefault("E2E_PRIVATE_KEY", defaultPrivateKey),
+		},
+		PChainIndexer: config.IndexerConfig{
+			Enabled:   true,
+			Timeout:   time.Second,
+			BatchSize: 200,
+		},
+		VotingCronjob: config.VotingConfig{CronjobConfig: cronjobConfig},
+		Mirror:        config.MirrorConfig{CronjobConfig: cronjobConfig},
+		DB: globalConfig.DBConfig{
+			Username: database.MysqlTestUser,
+			Password: database.MysqlTestPassword,
+			Host:     database.MysqlTestHost,
+			Port:     database.MysqlTestPort,
+			Database: "flare_indexer_e2e",
+		},
+		Logger: globalConfig.LoggerConfig{
+			Level:   "DEBUG",
+			Console: true,
+		},
+	}
+	cfg.ContractAddresses.Voting = common.HexToAddress(votingAddress)
+	cfg.ContractAddresses.Mirroring = common.HexToAddress(mirroringAddress)
+	return cfg
+}
+
+func envOrDefault(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Recorded stakes have ended long ago, the mirroring contract rejects them.
+// Extend their end time past the test run.
+func extendStakingEndTime(tx *database.PChainTx) *database.PChainTx {
+	if tx.Type == database.PChainAddValidatorTx || tx.Type == database.PChainAddDelegatorTx {
+		endTime := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+		tx.EndTime = &endTime
+	}
+	return tx
+}
+
+// Index all recorded P-chain blocks
+func (h *harness) indexAll(t *testing.T) {
+	_, lastIndex, err := h.client.GetLastAccepted(context.Background())
+	require.NoError(t, err)
+
+	for {
+		require.NoError(t, h.indexer.IndexBatch(context.Background()))
+
+		state, err := database.FetchState(context.Background(), h.ctx.DB(), pchain.StateName)
+		require.NoError(t, err)
+		if state.NextDBIndex > lastIndex {
+			return
+		}
+	}
+}
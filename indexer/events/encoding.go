@@ -0,0 +1,45 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type encoder func(event *Event) ([]byte, error)
+
+// Events are serialized as JSON objects or as google.protobuf.Struct
+// messages with the same fields, which can be decoded without generated code
+func newEncoder(serialization string) (encoder, error) {
+	switch serialization {
+	case "", "json":
+		return encodeJSON, nil
+	case "protobuf":
+		return encodeProtobuf, nil
+	default:
+		return nil, fmt.Errorf("unknown events serialization '%s', expected 'json' or 'protobuf'", serialization)
+	}
+}
+
+func encodeJSON(event *Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func encodeProtobuf(event *Event) ([]byte, error) {
+	// Round trip through JSON to use the json field names and formats
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	message, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(message)
+}
@@ -0,0 +1,189 @@
+package events
+
+import (
+	"flare-indexer/indexer/config"
+	"flare-indexer/logger"
+	"fmt"
+	"time"
+)
+
+// Type of an indexed event, also the default topic suffix
+type Type string
+
+const (
+	// A staking transaction (adding a validator or delegator) was indexed
+	StakingTx Type = "staking_tx"
+	// The voting cronjob submitted the merkle root of an epoch
+	EpochFinalized Type = "epoch_finalized"
+	// The mirroring cronjob mirrored the transactions of an epoch
+	MirroringCompleted Type = "mirroring_completed"
+	// A transaction of a watched address or node ID was indexed
+	WatchlistMatch Type = "watchlist_match"
+	// The anomalies cronjob found an anomaly in the voting or mirroring data
+	AnomalyDetected Type = "anomaly_detected"
+)
+
+const defaultTopicPrefix = "flare_indexer."
+
+// Message published for each event
+type Event struct {
+	Type      Type        `json:"type"`
+	Network   string      `json:"network,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Data of StakingTx events
+type StakingTxData struct {
+	TxID         string    `json:"txId"`
+	Type         string    `json:"txType"`
+	BlockHeight  uint64    `json:"blockHeight"`
+	NodeID       string    `json:"nodeId"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	Weight       uint64    `json:"weight"`
+	RewardsOwner string    `json:"rewardsOwner"`
+}
+
+// Data of EpochFinalized events
+type EpochFinalizedData struct {
+	Epoch      int64  `json:"epoch"`
+	MerkleRoot string `json:"merkleRoot"`
+	TxCount    int    `json:"txCount"`
+}
+
+// Data of WatchlistMatch events
+type WatchlistMatchData struct {
+	TxID      string    `json:"txId"`
+	TxType    string    `json:"txType"`
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Data of AnomalyDetected events
+type AnomalyDetectedData struct {
+	Type    string `json:"anomalyType"`
+	Epoch   int64  `json:"epoch"`
+	TxID    string `json:"txId"`
+	Details string `json:"details"`
+}
+
+// Data of MirroringCompleted events
+type MirroringCompletedData struct {
+	Epoch   int64 `json:"epoch"`
+	TxCount int   `json:"txCount"`
+}
+
+// Publishes events to a message broker so that downstream services do not
+// need to poll the database. Publishing is best effort: errors are logged and
+// do not stop indexing. All methods can be called on a nil *Publisher, which
+// drops the events.
+type Publisher struct {
+	sink    sink
+	encode  encoder
+	network string
+	prefix  string
+	topics  map[string]string
+
+	// For testing
+	now func() time.Time
+}
+
+// Message broker, topic is a Redis channel or a NATS subject
+type sink interface {
+	Publish(topic string, message []byte) error
+	Close() error
+}
+
+// Returns the publisher configured in cfg or nil if publishing is disabled
+func New(cfg *config.EventsConfig, network string) (*Publisher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	encode, err := newEncoder(cfg.Serialization)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBackend(cfg.Backend); err != nil {
+		return nil, err
+	}
+	var s sink
+	if cfg.Backend == "redis" {
+		s, err = newRedisSink(cfg.URL)
+	} else {
+		s, err = newNatsSink(cfg.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newPublisher(s, encode, cfg, network), nil
+}
+
+// Checks the backend and serialization of the config without connecting to the
+// message broker, used by the config check command
+func CheckConfig(cfg *config.EventsConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if _, err := newEncoder(cfg.Serialization); err != nil {
+		return err
+	}
+	return checkBackend(cfg.Backend)
+}
+
+func checkBackend(backend string) error {
+	if backend != "redis" && backend != "nats" {
+		return fmt.Errorf("unknown events backend '%s', expected 'redis' or 'nats'", backend)
+	}
+	return nil
+}
+
+func newPublisher(s sink, encode encoder, cfg *config.EventsConfig, network string) *Publisher {
+	prefix := cfg.TopicPrefix
+	if len(prefix) == 0 {
+		prefix = defaultTopicPrefix
+	}
+	return &Publisher{
+		sink:    s,
+		encode:  encode,
+		network: network,
+		prefix:  prefix,
+		topics:  cfg.Topics,
+		now:     time.Now,
+	}
+}
+
+// Topic of events of type t, set in the topics config or prefixed type
+func (p *Publisher) Topic(t Type) string {
+	if topic, ok := p.topics[string(t)]; ok {
+		return topic
+	}
+	return p.prefix + string(t)
+}
+
+func (p *Publisher) Publish(t Type, data interface{}) {
+	if p == nil {
+		return
+	}
+	message, err := p.encode(&Event{
+		Type:      t,
+		Network:   p.network,
+		Timestamp: p.now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		logger.Warn("Cannot encode %s event: %v", t, err)
+		return
+	}
+	if err := p.sink.Publish(p.Topic(t), message); err != nil {
+		logger.Warn("Cannot publish %s event: %v", t, err)
+	}
+}
+
+func (p *Publisher) Close() error {
+	if p == nil {
+		return nil
+	}
+	return p.sink.Close()
+}
@@ -0,0 +1,99 @@
+//go:build !integration
+// +build !integration
+
+package events
+
+import (
+	"encoding/json"
+	"flare-indexer/indexer/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type testMessage struct {
+	topic   string
+	message []byte
+}
+
+type testSink struct {
+	messages []testMessage
+}
+
+func (s *testSink) Publish(topic string, message []byte) error {
+	s.messages = append(s.messages, testMessage{topic, message})
+	return nil
+}
+
+func (s *testSink) Close() error {
+	return nil
+}
+
+var testTime = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestPublisher(t *testing.T, cfg *config.EventsConfig) (*Publisher, *testSink) {
+	encode, err := newEncoder(cfg.Serialization)
+	require.NoError(t, err)
+	s := &testSink{}
+	p := newPublisher(s, encode, cfg, "flare")
+	p.now = func() time.Time { return testTime }
+	return p, s
+}
+
+func TestPublishJSON(t *testing.T) {
+	p, s := newTestPublisher(t, &config.EventsConfig{
+		Topics: map[string]string{string(MirroringCompleted): "mirrored"},
+	})
+
+	p.Publish(EpochFinalized, EpochFinalizedData{Epoch: 5, MerkleRoot: "0x01", TxCount: 2})
+	p.Publish(MirroringCompleted, MirroringCompletedData{Epoch: 5, TxCount: 2})
+
+	require.Len(t, s.messages, 2)
+	require.Equal(t, "flare_indexer.epoch_finalized", s.messages[0].topic)
+	require.JSONEq(t, `{
+		"type": "epoch_finalized",
+		"network": "flare",
+		"timestamp": "2023-01-01T00:00:00Z",
+		"data": {"epoch": 5, "merkleRoot": "0x01", "txCount": 2}
+	}`, string(s.messages[0].message))
+	require.Equal(t, "mirrored", s.messages[1].topic)
+}
+
+func TestPublishProtobuf(t *testing.T) {
+	p, s := newTestPublisher(t, &config.EventsConfig{
+		Serialization: "protobuf",
+		TopicPrefix:   "events.",
+	})
+
+	p.Publish(MirroringCompleted, MirroringCompletedData{Epoch: 5, TxCount: 2})
+
+	require.Len(t, s.messages, 1)
+	require.Equal(t, "events.mirroring_completed", s.messages[0].topic)
+
+	var message structpb.Struct
+	require.NoError(t, proto.Unmarshal(s.messages[0].message, &message))
+	decoded, err := json.Marshal(message.AsMap())
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "mirroring_completed",
+		"network": "flare",
+		"timestamp": "2023-01-01T00:00:00Z",
+		"data": {"epoch": 5, "txCount": 2}
+	}`, string(decoded))
+}
+
+func TestNewPublisher(t *testing.T) {
+	p, err := New(&config.EventsConfig{}, "")
+	require.NoError(t, err)
+	require.Nil(t, p)
+	// Nil publisher drops events
+	p.Publish(StakingTx, StakingTxData{})
+
+	_, err = New(&config.EventsConfig{Enabled: true, Backend: "kafka"}, "")
+	require.Error(t, err)
+	_, err = New(&config.EventsConfig{Enabled: true, Backend: "redis", Serialization: "xml"}, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+)
+
+const publishTimeout = 5 * time.Second
+
+// Publishes events to Redis pub/sub channels
+type redisSink struct {
+	client *redis.Client
+}
+
+func newRedisSink(url string) (*redisSink, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid events url: %w", err)
+	}
+	return &redisSink{client: redis.NewClient(opts)}, nil
+}
+
+func (s *redisSink) Publish(topic string, message []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	return s.client.Publish(ctx, topic, message).Err()
+}
+
+func (s *redisSink) Close() error {
+	return s.client.Close()
+}
+
+// Publishes events to NATS subjects. The client reconnects automatically and
+// buffers messages while disconnected.
+type natsSink struct {
+	conn *nats.Conn
+}
+
+func newNatsSink(url string) (*natsSink, error) {
+	conn, err := nats.Connect(url, nats.Name("flare-indexer"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to NATS: %w", err)
+	}
+	return &natsSink{conn: conn}, nil
+}
+
+func (s *natsSink) Publish(topic string, message []byte) error {
+	return s.conn.Publish(topic, message)
+}
+
+func (s *natsSink) Close() error {
+	s.conn.Close()
+	return nil
+}
@@ -0,0 +1,154 @@
+package featureflags
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Feature flags are stored in the feature_flags table and polled periodically,
+// so they change the behaviour of all indexer instances using the same database
+// without a redeploy. Flag names are "<component>.<setting>", where component is
+// the name of a cronjob ("voting", "mirror", "anomalies", "reconciliation",
+// "contract_events", "address_binder", "uptime", "uptime_aggregator") or an
+// indexer ("p_chain_indexer", "x_chain_indexer"):
+//
+//	<component>.enabled     "false" pauses the cronjob or indexer
+//	<component>.dry_run     "true" makes voting and mirroring log instead of sending transactions
+//	                        and reconciliation log instead of correcting mirroring attempts
+//	<component>.batch_size  overrides batch_size from the config
+//
+// Flags cannot enable components that are disabled in the config. Missing or
+// invalid values fall back to the config. All methods can be called on a nil
+// *Flags, which always returns the defaults.
+type Flags struct {
+	db      flagsDB
+	mu      sync.RWMutex
+	values  map[string]string
+	started bool
+}
+
+const (
+	EnabledFlag   = "enabled"
+	DryRunFlag    = "dry_run"
+	BatchSizeFlag = "batch_size"
+)
+
+type flagsDB interface {
+	FetchFeatureFlags(ctx context.Context) ([]database.FeatureFlag, error)
+}
+
+type flagsDBGorm struct {
+	db *gorm.DB
+}
+
+func (g flagsDBGorm) FetchFeatureFlags(ctx context.Context) ([]database.FeatureFlag, error) {
+	return database.FetchFeatureFlags(ctx, g.db)
+}
+
+func New(db *gorm.DB) *Flags {
+	return newWithDB(flagsDBGorm{db: db})
+}
+
+func newWithDB(db flagsDB) *Flags {
+	return &Flags{
+		db:     db,
+		values: make(map[string]string),
+	}
+}
+
+// Reads the flags from the database
+func (f *Flags) Refresh(ctx context.Context) error {
+	flags, err := f.db.FetchFeatureFlags(ctx)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		values[flag.Name] = strings.TrimSpace(flag.Value)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for name, value := range values {
+		if old, ok := f.values[name]; !ok || old != value {
+			logger.Info("Feature flag %s set to '%s'", name, value)
+		}
+	}
+	f.values = values
+	return nil
+}
+
+// Refreshes the flags now and then every period in the background. A
+// non-positive period only reads the flags once.
+func (f *Flags) Start(period time.Duration) {
+	if err := f.Refresh(context.Background()); err != nil {
+		logger.Error("Cannot read feature flags: %v", err)
+	}
+	if period <= 0 || f.started {
+		return
+	}
+	f.started = true
+	go func() {
+		ticker := time.NewTicker(period)
+		for range ticker.C {
+			if err := f.Refresh(context.Background()); err != nil {
+				logger.Error("Cannot read feature flags: %v", err)
+			}
+		}
+	}()
+}
+
+func (f *Flags) value(name string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	value, ok := f.values[name]
+	return value, ok && len(value) > 0
+}
+
+func (f *Flags) Bool(name string, defaultValue bool) bool {
+	value, ok := f.value(name)
+	if !ok {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn("Invalid value '%s' of feature flag %s, using default %v", value, name, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func (f *Flags) Int(name string, defaultValue int64) int64 {
+	value, ok := f.value(name)
+	if !ok {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logger.Warn("Invalid value '%s' of feature flag %s, using default %d", value, name, defaultValue)
+		return defaultValue
+	}
+	return i
+}
+
+func (f *Flags) Enabled(component string) bool {
+	return f.Bool(component+"."+EnabledFlag, true)
+}
+
+func (f *Flags) DryRun(component string) bool {
+	return f.Bool(component+"."+DryRunFlag, false)
+}
+
+func (f *Flags) BatchSize(component string, defaultValue int64) int64 {
+	return f.Int(component+"."+BatchSizeFlag, defaultValue)
+}
@@ -0,0 +1,74 @@
+package featureflags
+
+import (
+	"context"
+	"flare-indexer/database"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type flagsDBTest struct {
+	flags []database.FeatureFlag
+	err   error
+}
+
+func (db *flagsDBTest) FetchFeatureFlags(ctx context.Context) ([]database.FeatureFlag, error) {
+	return db.flags, db.err
+}
+
+func TestFlagDefaults(t *testing.T) {
+	f := newWithDB(&flagsDBTest{})
+	require.NoError(t, f.Refresh(context.Background()))
+
+	require.True(t, f.Enabled("voting"))
+	require.False(t, f.DryRun("voting"))
+	require.Equal(t, int64(5), f.BatchSize("voting", 5))
+}
+
+func TestFlagValues(t *testing.T) {
+	db := &flagsDBTest{
+		flags: []database.FeatureFlag{
+			{Name: "voting.enabled", Value: "false"},
+			{Name: "mirror.dry_run", Value: " true "},
+			{Name: "p_chain_indexer.batch_size", Value: "50"},
+			{Name: "x_chain_indexer.batch_size", Value: "many"},
+			{Name: "uptime.enabled", Value: ""},
+		},
+	}
+	f := newWithDB(db)
+	require.NoError(t, f.Refresh(context.Background()))
+
+	require.False(t, f.Enabled("voting"))
+	require.True(t, f.DryRun("mirror"))
+	require.Equal(t, int64(50), f.BatchSize("p_chain_indexer", 10))
+	require.Equal(t, int64(10), f.BatchSize("x_chain_indexer", 10))
+	require.True(t, f.Enabled("uptime"))
+
+	// Deleted flags fall back to defaults
+	db.flags = nil
+	require.NoError(t, f.Refresh(context.Background()))
+	require.True(t, f.Enabled("voting"))
+}
+
+func TestFlagRefreshError(t *testing.T) {
+	db := &flagsDBTest{
+		flags: []database.FeatureFlag{{Name: "voting.enabled", Value: "false"}},
+	}
+	f := newWithDB(db)
+	require.NoError(t, f.Refresh(context.Background()))
+
+	// Keep the last known values if the database is not available
+	db.err = errors.New("connection refused")
+	require.Error(t, f.Refresh(context.Background()))
+	require.False(t, f.Enabled("voting"))
+}
+
+func TestNilFlags(t *testing.T) {
+	var f *Flags
+
+	require.True(t, f.Enabled("mirror"))
+	require.False(t, f.DryRun("mirror"))
+	require.Equal(t, int64(7), f.BatchSize("mirror", 7))
+}
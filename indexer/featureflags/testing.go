@@ -0,0 +1,6 @@
+package featureflags
+
+// Flags with fixed values which are never read from the database, for tests
+func NewStatic(values map[string]string) *Flags {
+	return &Flags{values: values}
+}
@@ -0,0 +1,94 @@
+package labels
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// Content of a labels file, in TOML ([[labels]] tables) or in JSON (object
+// with the "labels" array), depending on the file extension
+type File struct {
+	Labels []Label `toml:"labels" json:"labels"`
+}
+
+type Label struct {
+	NodeID       string `toml:"node_id" json:"nodeId"`
+	Name         string `toml:"name" json:"name"`
+	Website      string `toml:"website" json:"website"`
+	FtsoProvider string `toml:"ftso_provider" json:"ftsoProvider"`
+}
+
+// Stores the labels of the file in the validator_labels table, replacing the
+// labels of the same node IDs. Labels of other node IDs are kept.
+func Load(ctx context.Context, db *gorm.DB, fileName string) error {
+	file, err := ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	labels := make([]*database.ValidatorLabel, len(file.Labels))
+	for i, l := range file.Labels {
+		labels[i] = &database.ValidatorLabel{
+			NodeID:       l.NodeID,
+			Name:         l.Name,
+			Website:      l.Website,
+			FtsoProvider: l.FtsoProvider,
+			Updated:      now,
+		}
+	}
+	if err := database.UpsertValidatorLabels(ctx, db, labels); err != nil {
+		return err
+	}
+	logger.Info("Loaded %d validator labels from %s", len(labels), fileName)
+	return nil
+}
+
+func ReadFile(fileName string) (*File, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening labels file: %w", err)
+	}
+	file := &File{}
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		err = json.Unmarshal(content, file)
+	} else {
+		_, err = toml.Decode(string(content), file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing labels file: %w", err)
+	}
+	for i := range file.Labels {
+		if err := file.Labels[i].Validate(); err != nil {
+			return nil, fmt.Errorf("labels file %s, label %d: %w", fileName, i+1, err)
+		}
+	}
+	return file, nil
+}
+
+func (l *Label) Validate() error {
+	if _, err := ids.NodeIDFromString(l.NodeID); err != nil {
+		return fmt.Errorf("invalid node id %q: %w", l.NodeID, err)
+	}
+	if len(l.Name) > 100 {
+		return fmt.Errorf("name longer than 100 characters")
+	}
+	if len(l.Website) > 200 {
+		return fmt.Errorf("website longer than 200 characters")
+	}
+	if len(l.FtsoProvider) > 0 && !common.IsHexAddress(l.FtsoProvider) {
+		return fmt.Errorf("invalid ftso provider address %q", l.FtsoProvider)
+	}
+	return nil
+}
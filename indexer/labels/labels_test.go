@@ -0,0 +1,66 @@
+//go:build !integration
+// +build !integration
+
+package labels
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testNodeID = "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"
+
+func writeFile(t *testing.T, name string, content string) string {
+	fileName := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(fileName, []byte(content), 0600))
+	return fileName
+}
+
+func TestReadFile(t *testing.T) {
+	expected := Label{
+		NodeID:       testNodeID,
+		Name:         "Operator",
+		Website:      "https://example.com",
+		FtsoProvider: "0xf956df3800379fdFA31D0A45FDD5001D02F4109c",
+	}
+
+	file, err := ReadFile(writeFile(t, "labels.toml", `
+[[labels]]
+node_id = "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"
+name = "Operator"
+website = "https://example.com"
+ftso_provider = "0xf956df3800379fdFA31D0A45FDD5001D02F4109c"
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Label{expected}, file.Labels)
+
+	file, err = ReadFile(writeFile(t, "labels.json", `{"labels": [{
+		"nodeId": "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+		"name": "Operator",
+		"website": "https://example.com",
+		"ftsoProvider": "0xf956df3800379fdFA31D0A45FDD5001D02F4109c"
+	}]}`))
+	require.NoError(t, err)
+	require.Equal(t, []Label{expected}, file.Labels)
+}
+
+func TestReadInvalidFile(t *testing.T) {
+	_, err := ReadFile(writeFile(t, "labels.toml", `
+[[labels]]
+node_id = "CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"
+`))
+	require.ErrorContains(t, err, "invalid node id")
+
+	_, err = ReadFile(writeFile(t, "labels.toml", `
+[[labels]]
+node_id = "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"
+ftso_provider = "0x1234"
+`))
+	require.ErrorContains(t, err, "invalid ftso provider")
+
+	_, err = ReadFile(writeFile(t, "labels.json", `[]`))
+	require.ErrorContains(t, err, "error parsing labels file")
+}
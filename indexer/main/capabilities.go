@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
+	"fmt"
+)
+
+// Probes the APIs of the node and logs the capability report. Features using
+// an API which is not available are disabled, except the P-chain indexer, which
+// the cronjobs depend on, so the indexer does not start without its API.
+func checkNodeCapabilities(ctx context.IndexerContext) error {
+	cfg := ctx.Config()
+	caps := chain.ProbeNodeCapabilities(&cfg.Chain)
+	logger.Info("Node capabilities:%s", caps)
+	return applyNodeCapabilities(cfg, caps)
+}
+
+func applyNodeCapabilities(cfg *config.Config, caps chain.NodeCapabilities) error {
+	if cfg.PChainIndexer.Enabled {
+		if err := checkPChainSource(cfg.PChainIndexer.Source, caps); err != nil {
+			return err
+		}
+		if !caps.Platform.Available {
+			logger.Warn("Platform API is not available, outputs spent by P-chain transactions which are not indexed cannot be fetched")
+		}
+	}
+	if cfg.XChainIndexer.Enabled && !caps.XChainIndex.Available {
+		logger.Warn("X-chain index is not available, disabling the X-chain indexer (start the node with --index-enabled)")
+		cfg.XChainIndexer.Enabled = false
+	}
+	if cfg.UptimeCronjob.Enabled && !caps.Platform.Available {
+		logger.Warn("Platform API is not available, disabling the uptime cronjob and uptime voting")
+		cfg.UptimeCronjob.Enabled = false
+	}
+	return nil
+}
+
+func checkPChainSource(source string, caps chain.NodeCapabilities) error {
+	switch source {
+	case "", pchain.SourceIndexer:
+		if caps.PChainIndex.Available {
+			return nil
+		}
+		hint := "start the node with --index-enabled"
+		if caps.PlatformBlockByHeight.Available {
+			hint += ` or set source = "rpc" in the p_chain_indexer section of a new database`
+		}
+		return fmt.Errorf("p_chain_indexer: P-chain index is %s, %s", caps.PChainIndex, hint)
+	case pchain.SourceRPC:
+		if caps.PlatformBlockByHeight.Available {
+			return nil
+		}
+		return fmt.Errorf("p_chain_indexer: platform.getBlockByHeight is %s, the source %q needs a newer node",
+			caps.PlatformBlockByHeight, pchain.SourceRPC)
+	}
+	// Unknown sources are reported when the indexer is created
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	sysContext "context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timeout of a single export request to the other indexer
+const compareRequestTimeout = 5 * time.Minute
+
+// Weights of the stakes of an epoch by transaction ID
+type stakeSet map[string]uint64
+
+// Difference of a stake between the local and the remote stake set, a missing
+// stake has a nil weight
+type stakeDiff struct {
+	TxID         string
+	LocalWeight  *uint64
+	RemoteWeight *uint64
+}
+
+func (d stakeDiff) String() string {
+	switch {
+	case d.LocalWeight == nil:
+		return fmt.Sprintf("tx %s: missing locally, remote weight %d", d.TxID, *d.RemoteWeight)
+	case d.RemoteWeight == nil:
+		return fmt.Sprintf("tx %s: missing remotely, local weight %d", d.TxID, *d.LocalWeight)
+	default:
+		return fmt.Sprintf("tx %s: weight differs, local %d, remote %d", d.TxID, *d.LocalWeight, *d.RemoteWeight)
+	}
+}
+
+// Compares the validators and delegators starting in the epoch of the --epoch
+// flag with the export routes of the services of the indexer of the --url flag
+// and prints the differences
+func compareEpoch(ctx context.IndexerContext) error {
+	flags := ctx.Flags()
+	epochs, err := cronjob.VotingEpochs(ctx)
+	if err != nil {
+		return err
+	}
+
+	sigCtx, cancel := interruptContext()
+	defer cancel()
+
+	from, to := epochs.GetTimeRange(flags.Epoch)
+	db := database.WithoutStatementTimeout(ctx.DB())
+	client := &http.Client{Timeout: compareRequestTimeout}
+	differences := 0
+	for _, exportType := range []string{context.ExportValidators, context.ExportDelegators} {
+		txType := database.PChainAddValidatorTx
+		if exportType == context.ExportDelegators {
+			txType = database.PChainAddDelegatorTx
+		}
+		local := make(stakeSet)
+		err := database.StreamPChainStakingData(sigCtx, db, txType, from, to, func(tx *database.PChainTxData) error {
+			local[*tx.TxID] = tx.Weight
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		remote, err := fetchRemoteStakes(sigCtx, client, flags.CompareURL, flags.CompareAPIKey, exportType, flags.Epoch)
+		if err != nil {
+			return fmt.Errorf("cannot fetch the %s of the other indexer: %w", exportType, err)
+		}
+
+		diffs := diffStakeSets(local, remote)
+		fmt.Printf("%s of epoch %d: %d local, %d remote, %d differences\n", exportType, flags.Epoch, len(local), len(remote), len(diffs))
+		for _, d := range diffs {
+			fmt.Printf("  %s\n", d)
+		}
+		differences += len(diffs)
+	}
+	if differences > 0 {
+		return fmt.Errorf("%d differences in epoch %d", differences, flags.Epoch)
+	}
+	fmt.Printf("No differences in epoch %d\n", flags.Epoch)
+	return nil
+}
+
+// Reads the stakers of the export route (JSON Lines) of the services at baseURL
+func fetchRemoteStakes(
+	ctx sysContext.Context, client *http.Client, baseURL string, apiKey string, exportType string, epoch int64,
+) (stakeSet, error) {
+	body, err := json.Marshal(map[string]interface{}{"epoch": epoch, "format": context.ExportJSONL})
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/export/" + exportType
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(apiKey) > 0 {
+		request.Header.Set("X-API-Key", apiKey)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, fmt.Errorf("%s returned %s: %s", url, response.Status, strings.TrimSpace(string(message)))
+	}
+
+	stakes := make(stakeSet)
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var staker exportedStaker
+		if err := json.Unmarshal(line, &staker); err != nil {
+			return nil, fmt.Errorf("invalid staker %q: %w", line, err)
+		}
+		stakes[staker.TxID] = staker.Weight
+	}
+	// A truncated export fails with an unexpected EOF
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return stakes, nil
+}
+
+// Returns the stakes missing in one of the sets or with different weights,
+// ordered by transaction ID
+func diffStakeSets(local stakeSet, remote stakeSet) []stakeDiff {
+	var diffs []stakeDiff
+	for txID, localWeight := range local {
+		localWeight := localWeight
+		remoteWeight, ok := remote[txID]
+		switch {
+		case !ok:
+			diffs = append(diffs, stakeDiff{TxID: txID, LocalWeight: &localWeight})
+		case remoteWeight != localWeight:
+			diffs = append(diffs, stakeDiff{TxID: txID, LocalWeight: &localWeight, RemoteWeight: &remoteWeight})
+		}
+	}
+	for txID, remoteWeight := range remote {
+		remoteWeight := remoteWeight
+		if _, ok := local[txID]; !ok {
+			diffs = append(diffs, stakeDiff{TxID: txID, RemoteWeight: &remoteWeight})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].TxID < diffs[j].TxID })
+	return diffs
+}
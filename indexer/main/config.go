@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/indexer/events"
+	"flare-indexer/indexer/labels"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/webhooks"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+)
+
+func runConfigCommand(flags *context.IndexerFlags) error {
+	cfgs, err := config.BuildNetworkConfigs(flags.ConfigFileName)
+	if err != nil {
+		return err
+	}
+	if flags.ConfigAction == context.ConfigShow {
+		return showConfig(os.Stdout, cfgs, config.EnvOverrides())
+	}
+	for _, cfg := range cfgs {
+		if err := checkConfig(cfg); err != nil {
+			if len(cfg.Network) > 0 {
+				return fmt.Errorf("network %s: %w", cfg.Network, err)
+			}
+			return err
+		}
+	}
+	fmt.Printf("Config %s is valid\n", flags.ConfigFileName)
+	return nil
+}
+
+// Prints the resolved config of each network with the secrets redacted,
+// preceded by the settings read from environment variables
+func showConfig(w io.Writer, cfgs []*config.Config, overrides []config.EnvOverride) error {
+	for _, o := range overrides {
+		fmt.Fprintf(w, "# %s is set by environment variable %s\n", o.Setting, o.Variable)
+	}
+	if len(overrides) > 0 && len(cfgs[0].Network) > 0 {
+		fmt.Fprintln(w, "# settings in network sections take precedence over environment variables")
+	}
+	for i, cfg := range cfgs {
+		if len(overrides) > 0 || i > 0 {
+			fmt.Fprintln(w)
+		}
+		if len(cfg.Network) > 0 {
+			fmt.Fprintf(w, "# network %s\n", cfg.Network)
+		}
+		if err := toml.NewEncoder(w).Encode(cfg.Redacted()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks the settings which can be checked without connecting to the node, the
+// eth RPC or the database
+func checkConfig(cfg *config.Config) error {
+	if err := cronjob.CheckConfig(cfg); err != nil {
+		return err
+	}
+	if err := events.CheckConfig(&cfg.Events); err != nil {
+		return err
+	}
+	if err := webhooks.CheckConfig(&cfg.Webhooks); err != nil {
+		return err
+	}
+	if err := pchain.CheckValidatorWeightsConfig(cfg); err != nil {
+		return err
+	}
+	if file := cfg.ValidatorLabels.File; len(file) > 0 {
+		if _, err := labels.ReadFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
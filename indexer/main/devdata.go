@@ -0,0 +1,37 @@
+package main
+
+import (
+	sysContext "context"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/devdata"
+	"fmt"
+	"time"
+)
+
+// Populates the empty database of the network with synthetic data, see
+// devdata.Generate
+func generateDevData(ctx context.IndexerContext) error {
+	flags := ctx.Flags()
+	opts := &devdata.Options{
+		HRP:                    ctx.Config().Chain.ChainAddressHRP,
+		Epochs:                 flags.DevDataEpochs,
+		EpochStart:             flags.DevDataEpochStart,
+		EpochPeriod:            flags.DevDataEpochPeriod,
+		Validators:             flags.DevDataValidators,
+		DelegatorsPerValidator: flags.DevDataDelegators,
+		Seed:                   flags.DevDataSeed,
+	}
+	if opts.EpochStart.IsZero() {
+		opts.EpochStart = time.Now().Truncate(time.Hour).Add(-time.Duration(opts.Epochs) * opts.EpochPeriod)
+	}
+	data, err := devdata.Generate(opts)
+	if err != nil {
+		return err
+	}
+	if err := devdata.Insert(sysContext.Background(), ctx.DB(), data); err != nil {
+		return err
+	}
+	fmt.Printf("Inserted %d P-chain transactions in %d blocks and %d X-chain transactions, epochs start at %s\n",
+		len(data.PChainTxs), len(data.PChainBlocks), len(data.XChainTxs), opts.EpochStart.UTC().Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Same fields as the stakers of the export routes of the services
+type exportedStaker struct {
+	TxID           string    `json:"txID"`
+	NodeID         string    `json:"nodeID"`
+	StartTime      time.Time `json:"startTime"`
+	EndTime        time.Time `json:"endTime"`
+	Weight         uint64    `json:"weight"`
+	FeePercentage  uint32    `json:"feePercentage"`
+	InputAddresses []string  `json:"inputAddresses"`
+}
+
+var exportedStakerCSVHeader = []string{
+	"txID", "nodeID", "startTime", "endTime", "weight", "feePercentage", "inputAddresses",
+}
+
+// Input addresses are separated by spaces in the CSV export
+func (s *exportedStaker) csvRecord() []string {
+	return []string{
+		s.TxID,
+		s.NodeID,
+		s.StartTime.UTC().Format(time.RFC3339),
+		s.EndTime.UTC().Format(time.RFC3339),
+		strconv.FormatUint(s.Weight, 10),
+		strconv.FormatUint(uint64(s.FeePercentage), 10),
+		strings.Join(s.InputAddresses, " "),
+	}
+}
+
+// Writes the validators or delegators (--type flag, permissionless ones
+// included) starting in the epoch of the --epoch flag to stdout, without
+// applying the staking filter
+func exportStakers(ctx context.IndexerContext) error {
+	flags := ctx.Flags()
+	epochs, err := cronjob.VotingEpochs(ctx)
+	if err != nil {
+		return err
+	}
+	txType := database.PChainAddValidatorTx
+	if flags.ExportType == context.ExportDelegators {
+		txType = database.PChainAddDelegatorTx
+	}
+
+	sigCtx, cancel := interruptContext()
+	defer cancel()
+
+	w := newStakerWriter(os.Stdout, flags.ExportFormat)
+	from, to := epochs.GetTimeRange(flags.Epoch)
+	db := database.WithoutStatementTimeout(ctx.DB())
+	err = database.StreamPChainStakingData(sigCtx, db, txType, from, to, func(tx *database.PChainTxData) error {
+		return w.write(&exportedStaker{
+			TxID:           *tx.TxID,
+			NodeID:         tx.NodeID,
+			StartTime:      *tx.StartTime,
+			EndTime:        *tx.EndTime,
+			Weight:         tx.Weight,
+			FeePercentage:  tx.FeePercentage,
+			InputAddresses: strings.Split(tx.InputAddress, ","),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return w.close()
+}
+
+// Writes stakers as JSON Lines or as CSV starting with a header row
+type stakerWriter struct {
+	json *json.Encoder
+	csv  *csv.Writer
+}
+
+func newStakerWriter(w io.Writer, format string) *stakerWriter {
+	if format != context.ExportCSV {
+		return &stakerWriter{json: json.NewEncoder(w)}
+	}
+	sw := &stakerWriter{csv: csv.NewWriter(w)}
+	// Errors are returned by the next write or close
+	_ = sw.csv.Write(exportedStakerCSVHeader)
+	return sw
+}
+
+func (sw *stakerWriter) write(s *exportedStaker) error {
+	if sw.json != nil {
+		return sw.json.Encode(s)
+	}
+	return sw.csv.Write(s.csvRecord())
+}
+
+func (sw *stakerWriter) close() error {
+	if sw.csv == nil {
+		return nil
+	}
+	sw.csv.Flush()
+	return sw.csv.Error()
+}
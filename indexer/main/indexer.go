@@ -1,11 +1,17 @@
 package main
 
 import (
+	sysContext "context"
+	"errors"
+	"flag"
 	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/indexer/labels"
 	"flare-indexer/indexer/migrations"
 	"flare-indexer/indexer/runner"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/logger"
+	"flare-indexer/utils/chain"
 	"fmt"
 	"os"
 	"os/signal"
@@ -13,26 +19,158 @@ import (
 )
 
 func main() {
-	ctx, err := context.BuildContext()
+	flags, err := context.ParseIndexerFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		fmt.Printf("%v\n", err)
+		os.Exit(2)
+	}
+	// The config command must not connect to the database
+	if flags.Command == context.CommandConfig {
+		if err := runConfigCommand(flags); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
-	err = migrations.Container.ExecuteAll(ctx.DB())
+	ctxs, err := context.BuildContexts(flags)
 	if err != nil {
 		fmt.Printf("%v\n", err)
-		return
+		os.Exit(1)
+	}
+	if err := runCommand(flags.Command, ctxs); err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Commands other than run, migrate and backfill work on a single network
+func runCommand(command string, ctxs []context.IndexerContext) error {
+	switch command {
+	case context.CommandRun:
+		return run(ctxs)
+	case context.CommandMigrate:
+		return migrate(ctxs)
+	case context.CommandBackfill:
+		return backfill(ctxs)
+	}
+
+	if len(ctxs) > 1 {
+		return fmt.Errorf("%s needs a config with a single network", command)
+	}
+	ctx := ctxs[0]
+	if err := migrations.Container.ExecuteAll(migrationContext(ctx), ctx.DB()); err != nil {
+		return err
+	}
+	switch command {
+	case context.CommandReindex:
+		return reindex(ctx)
+	case context.CommandMirror:
+		return runMirrorEpoch(ctx)
+	case context.CommandVote:
+		return runVoteEpoch(ctx)
+	case context.CommandVerify:
+		return verifyEpoch(ctx)
+	case context.CommandExport:
+		return exportStakers(ctx)
+	case context.CommandCompare:
+		return compareEpoch(ctx)
+	case context.CommandRepairInputs:
+		return repairInputs(ctx)
+	case context.CommandDevData:
+		return generateDevData(ctx)
+	}
+	return fmt.Errorf("unknown command %q", command)
+}
+
+// Runs the indexers and cronjobs of all networks until the process is stopped
+func run(ctxs []context.IndexerContext) error {
+	for _, ctx := range ctxs {
+		if err := initNetwork(ctx); err != nil {
+			return err
+		}
 	}
 
 	cancelChan := make(chan os.Signal, 1)
 	signal.Notify(cancelChan, os.Interrupt, syscall.SIGTERM)
 
-	// Prometheus metrics
-	shared.InitMetricsServer(&ctx.Config().Metrics)
+	// Prometheus metrics, shared by all networks
+	shared.InitMetricsServer(&ctxs[0].Config().Metrics)
 
-	runner.Start(ctx)
+	for _, ctx := range ctxs {
+		runner.Start(ctx)
+	}
 
 	<-cancelChan
 	logger.Info("Stopped flare indexer")
+	return nil
+}
+
+func migrate(ctxs []context.IndexerContext) error {
+	for _, ctx := range ctxs {
+		if err := migrations.Container.ExecuteAll(migrationContext(ctx), ctx.DB()); err != nil {
+			return err
+		}
+		if network := ctx.Config().Network; len(network) > 0 {
+			fmt.Printf("Migrated the database of network %s\n", network)
+		} else {
+			fmt.Println("Migrated the database")
+		}
+	}
+	return nil
+}
+
+// Checks the node and migrates the database of the network
+func initNetwork(ctx context.IndexerContext) error {
+	if network := ctx.Config().Network; len(network) > 0 {
+		logger.Info("Starting indexer for network %s", network)
+	}
+	err := chain.CheckNetwork(&ctx.Config().Chain)
+	if err != nil {
+		return err
+	}
+	err = checkNodeVersion(ctx)
+	if err != nil {
+		return err
+	}
+	err = checkNodeCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	err = migrations.Container.ExecuteAll(migrationContext(ctx), ctx.DB())
+	if err != nil {
+		return err
+	}
+	_, err = cronjob.NewPruner(ctx).Prune(sysContext.Background())
+	if err != nil {
+		return err
+	}
+	if file := ctx.Config().ValidatorLabels.File; len(file) > 0 {
+		return labels.Load(sysContext.Background(), ctx.DB(), file)
+	}
+	return nil
+}
+
+// Containers are parsed with the parser for the node version, indexing with an
+// unsupported node version is only possible if explicitly enabled
+func checkNodeVersion(ctx context.IndexerContext) error {
+	cfg := &ctx.Config().Chain
+	err := chain.CheckNodeVersion(cfg, ctx.Parser())
+	if err != nil && cfg.SkipNodeVersionCheck {
+		logger.Warn("Node version check failed, continuing since skip_node_version_check is set: %v", err)
+		return nil
+	}
+	return err
+}
+
+// Context of the migrations of the network, with its container parser
+func migrationContext(ctx context.IndexerContext) sysContext.Context {
+	return chain.ContextWithParser(sysContext.Background(), ctx.Parser())
+}
 
+// Context cancelled when the process is interrupted
+func interruptContext() (sysContext.Context, sysContext.CancelFunc) {
+	return signal.NotifyContext(sysContext.Background(), os.Interrupt, syscall.SIGTERM)
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/indexer/xchain"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Indexes the chain of the --chain flag of each network up to the container
+// last accepted by the node when the backfill starts
+func backfill(ctxs []context.IndexerContext) error {
+	sigCtx, cancel := interruptContext()
+	defer cancel()
+
+	for _, ctx := range ctxs {
+		if err := initNetwork(ctx); err != nil {
+			return err
+		}
+		idxr, err := chainIndexer(ctx)
+		if err != nil {
+			return err
+		}
+		if err := idxr.IndexToLastAccepted(sigCtx); err != nil {
+			return err
+		}
+		state, err := database.FetchState(sigCtx, ctx.DB(), idxr.StateName)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Indexed %s up to index %d\n", idxr.IndexerName, int64(state.NextDBIndex)-1)
+	}
+	return nil
+}
+
+// Maximal length of the reason of a tombstone
+const maxTombstoneReasonLength = 256
+
+// Deletes the data indexed from the container index of the --from flag on and
+// resets the state of the indexer, so the next run or backfill indexes the
+// containers again. The deleted rows are kept as tombstones.
+func reindex(ctx context.IndexerContext) error {
+	flags := ctx.Flags()
+	idxr, err := chainIndexer(ctx)
+	if err != nil {
+		return err
+	}
+	sigCtx, cancel := interruptContext()
+	defer cancel()
+
+	state, err := database.FetchState(sigCtx, ctx.DB(), idxr.StateName)
+	if err != nil {
+		return err
+	}
+	if flags.FromIndex >= state.NextDBIndex {
+		return fmt.Errorf("%s is indexed up to index %d, nothing to index again from index %d",
+			idxr.IndexerName, int64(state.NextDBIndex)-1, flags.FromIndex)
+	}
+	question := fmt.Sprintf("Delete the data of %s indexed from index %d to %d? The indexer must not be running.",
+		idxr.IndexerName, flags.FromIndex, state.NextDBIndex-1)
+	if !askConfirmation(question) {
+		return fmt.Errorf("reindex aborted")
+	}
+
+	deleteEntities := database.DeletePChainEntitiesFromIndex
+	if flags.Chain == context.ChainX {
+		deleteEntities = database.DeleteXChainEntitiesFromIndex
+	}
+	var deleted int64
+	err = database.DoInTransaction(sigCtx, ctx.DB(),
+		func(db *gorm.DB) error {
+			deleted, err = deleteEntities(sigCtx, db, flags.FromIndex, reindexReason(flags, idxr.IndexerName))
+			return err
+		},
+		func(db *gorm.DB) error {
+			state.NextDBIndex = flags.FromIndex
+			return database.UpdateState(sigCtx, db, &state)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d containers of %s, indexing continues at index %d\n", deleted, idxr.IndexerName, flags.FromIndex)
+	_, err = cronjob.NewPruner(ctx).Prune(sigCtx)
+	return err
+}
+
+// Reason recorded with the tombstones of the rows deleted by reindex
+func reindexReason(flags *context.IndexerFlags, indexerName string) string {
+	reason := fmt.Sprintf("reindex of %s from index %d", indexerName, flags.FromIndex)
+	if len(flags.ReindexReason) > 0 {
+		reason += ": " + flags.ReindexReason
+	}
+	if runes := []rune(reason); len(runes) > maxTombstoneReasonLength {
+		reason = string(runes[:maxTombstoneReasonLength])
+	}
+	return reason
+}
+
+// Resolves the addresses and amounts missing in the indexed P-chain inputs and
+// prints how many of them were fixed
+func repairInputs(ctx context.IndexerContext) error {
+	sigCtx, cancel := interruptContext()
+	defer cancel()
+
+	result, err := pchain.RepairPChainInputs(sigCtx, ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Checked %d P-chain inputs with a missing address or amount, fixed %d, unchanged %d, unresolved %d\n",
+		result.Checked, result.Fixed, result.Unchanged, result.Unresolved)
+	return nil
+}
+
+// Indexer of the chain of the --chain flag
+func chainIndexer(ctx context.IndexerContext) (*shared.ChainIndexerBase, error) {
+	if ctx.Flags().Chain == context.ChainX {
+		idxr, err := xchain.CreateXChainTxIndexer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &idxr.ChainIndexerBase, nil
+	}
+	idxr, err := pchain.CreatePChainBlockIndexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &idxr.ChainIndexerBase, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	sysContext "context"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Previews (--preview) or mirrors (--execute) the stakes of the epoch of the
+// --epoch flag once, signing with the configured private key or on a Ledger.
+// Before anything is sent, the stakes are summarized and must be confirmed.
+func runMirrorEpoch(ctx context.IndexerContext) error {
+	flags := ctx.Flags()
+	epoch := flags.Epoch
+
+	var summary cronjob.MirrorRunSummary
+	signed := 0
+	onSign := func(tx *types.Transaction) {
+		signed++
+		fmt.Printf("Confirm transaction %d of %d of epoch %d on the Ledger (nonce %d, to %s)\n",
+			signed, summary.TxCount, epoch, tx.Nonce(), tx.To().Hex())
+	}
+	txOpts, closeSigner, err := mirrorTransactOpts(ctx, onSign)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+
+	if flags.MirrorPreview {
+		preview, err := cronjob.PreviewMirrorEpoch(sysContext.Background(), ctx, epoch, txOpts)
+		if err != nil {
+			return err
+		}
+		printMirrorPreview(preview)
+		return nil
+	}
+
+	confirm := func(s cronjob.MirrorRunSummary) bool {
+		summary = s
+		fmt.Printf("Mirroring %s\n", s)
+		if s.TxCount == 0 {
+			return true
+		}
+		return askConfirmation(fmt.Sprintf("Mirror %d transactions of epoch %d?", s.TxCount, s.Epoch))
+	}
+	if err := cronjob.RunMirrorEpoch(sysContext.Background(), ctx, epoch, txOpts, confirm); err != nil {
+		return err
+	}
+	fmt.Printf("Mirrored epoch %d\n", epoch)
+	return nil
+}
+
+// Transact options signing on a Ledger (--ledger flag) or with the configured
+// private key, and a function releasing the signer
+func mirrorTransactOpts(ctx context.IndexerContext, onSign func(tx *types.Transaction)) (*bind.TransactOpts, func(), error) {
+	flags := ctx.Flags()
+	if !flags.Ledger {
+		privateKey, err := ctx.Config().Chain.GetPrivateKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		txOpts, err := cronjob.TransactOptsFromPrivateKey(privateKey, ctx.Config().Chain.ChainID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return txOpts, func() {}, nil
+	}
+
+	gasPrice, err := ctx.EthClient().SuggestGasPrice(sysContext.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Println("Connect the Ledger, unlock it and open the Ethereum app")
+	return cronjob.TransactOptsFromLedger(flags.LedgerPath, ctx.Config().Chain.ChainID, gasPrice, onSign)
+}
+
+func printMirrorPreview(p *cronjob.MirrorPreview) {
+	fmt.Printf("Preview of %s\n", p.MirrorRunSummary)
+	fmt.Printf("Merkle root: %s\n", p.MerkleRoot.Hex())
+	if p.MerkleRoot == p.VotedRoot {
+		fmt.Println("Voted root:  matches")
+	} else {
+		fmt.Printf("Voted root:  %s, MISMATCH, the stakes cannot be mirrored\n", p.VotedRoot.Hex())
+	}
+	for i, s := range p.Stakes {
+		fmt.Printf("\n%d. %s %s\n", i+1, s.Type, s.TxID)
+		fmt.Printf("   node %s, weight %d, %s - %s\n", s.NodeID, s.Weight,
+			s.StartTime.UTC().Format(time.RFC3339), s.EndTime.UTC().Format(time.RFC3339))
+		fmt.Printf("   input address %s\n", s.InputAddress)
+		fmt.Printf("   leaf %s\n", s.Hash.Hex())
+		for _, h := range s.Proof {
+			fmt.Printf("   proof %s\n", h.Hex())
+		}
+		if len(s.EstimateError) > 0 {
+			fmt.Printf("   gas estimation failed: %s\n", s.EstimateError)
+		} else {
+			fmt.Printf("   estimated gas %d\n", s.Gas)
+		}
+	}
+	if len(p.Stakes) == 0 {
+		return
+	}
+	if p.GasEstimate {
+		fmt.Printf("\nEstimated gas of all transactions: %d\n", p.TotalGas)
+	} else {
+		fmt.Printf("\nEstimated gas of the transactions with an estimate: %d\n", p.TotalGas)
+	}
+}
+
+// Asks the question on stdout and returns true if the answer is yes
+func askConfirmation(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	sysContext "context"
+	"errors"
+	"flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"fmt"
+)
+
+// Submits the vote of the epoch of the --epoch flag once with the configured
+// private key. The vote is summarized and must be confirmed before it is sent.
+func runVoteEpoch(ctx context.IndexerContext) error {
+	epoch := ctx.Flags().Epoch
+	confirm := func(s cronjob.VoteRunSummary) bool {
+		fmt.Printf("Voting %s\n", s)
+		return askConfirmation(fmt.Sprintf("Submit the vote of epoch %d?", s.Epoch))
+	}
+	err := cronjob.RunVoteEpoch(sysContext.Background(), ctx, epoch, confirm)
+	if errors.Is(err, cronjob.ErrVoteNotNeeded) {
+		fmt.Printf("The voting contract does not expect a vote for epoch %d\n", epoch)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Submitted the vote of epoch %d\n", epoch)
+	return nil
+}
+
+// Prints the anomalies of the epoch of the --epoch flag, fails if there are any
+func verifyEpoch(ctx context.IndexerContext) error {
+	epoch := ctx.Flags().Epoch
+	anomalies, err := cronjob.VerifyEpoch(sysContext.Background(), ctx, epoch)
+	if err != nil {
+		return err
+	}
+	for _, a := range anomalies {
+		fmt.Printf("%s: tx %s: %s\n", a.Type, a.TxID, a.Details)
+	}
+	if len(anomalies) > 0 {
+		return fmt.Errorf("%d anomalies in epoch %d", len(anomalies), epoch)
+	}
+	fmt.Printf("No anomalies in epoch %d\n", epoch)
+	return nil
+}
@@ -1,6 +1,7 @@
 package migrations
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/logger"
 	"fmt"
@@ -14,31 +15,31 @@ import (
 var Container MigrationContainer = NewMigrationContainer()
 
 type MigrationContainer interface {
-	Add(version string, description string, code func(*gorm.DB) error)
-	ExecuteAll(db *gorm.DB) error
+	Add(version string, description string, code func(context.Context, *gorm.DB) error)
+	ExecuteAll(ctx context.Context, db *gorm.DB) error
 }
 
 type migration struct {
 	version     string
 	description string
-	code        func(*gorm.DB) error
+	code        func(context.Context, *gorm.DB) error
 }
 
-func executeMigration(db *gorm.DB, m migration) error {
+func executeMigration(ctx context.Context, db *gorm.DB, m migration) error {
 	dbMigration := database.Migration{
 		Version:     m.version,
 		Description: m.description,
 		Status:      database.MigrationPending,
 		ExecutedAt:  time.Now(),
 	}
-	err := database.CreateMigration(db, &dbMigration)
+	err := database.CreateMigration(ctx, db, &dbMigration)
 	if err != nil {
 		return err
 	}
 
 	// Execute migration and measure its duration
 	start := time.Now()
-	execErr := m.code(db)
+	execErr := m.code(ctx, db)
 	end := time.Now()
 
 	var status database.MigrationStatus
@@ -49,7 +50,7 @@ func executeMigration(db *gorm.DB, m migration) error {
 	}
 	dbMigration.Status = status
 	dbMigration.Duration = int((end.Sub(start)).Milliseconds())
-	err = database.UpdateMigration(db, &dbMigration)
+	err = database.UpdateMigration(ctx, db, &dbMigration)
 	if err != nil {
 		return fmt.Errorf("error updating migration %s with status %s, error is %w", m.version, status, err)
 	}
@@ -73,8 +74,8 @@ func NewMigrationContainer() MigrationContainer {
 //
 //	version - Migration version. Migrations are sorted by version and executed in that order.
 //	description - Description of the migration
-//	code - Migration code, function without parameters
-func (mc *migrationContainer) Add(version string, description string, code func(*gorm.DB) error) {
+//	code - Migration code
+func (mc *migrationContainer) Add(version string, description string, code func(context.Context, *gorm.DB) error) {
 	mc.migrations = append(mc.migrations, migration{
 		version:     version,
 		description: description,
@@ -82,8 +83,11 @@ func (mc *migrationContainer) Add(version string, description string, code func(
 	})
 }
 
-func (mc *migrationContainer) ExecuteAll(db *gorm.DB) error {
-	dbMigrations, err := database.FetchMigrations(db)
+// Migrations run on startup, before anything else, so they are not limited by
+// a deadline. The migrations parsing stored containers use the parser of the
+// context, see chain.ContextWithParser.
+func (mc *migrationContainer) ExecuteAll(ctx context.Context, db *gorm.DB) error {
+	dbMigrations, err := database.FetchMigrations(ctx, db)
 	if err != nil {
 		return err
 	}
@@ -108,7 +112,7 @@ func (mc *migrationContainer) ExecuteAll(db *gorm.DB) error {
 		if !executedVersions.Contains(m.version) {
 
 			logger.Info("Executing migration %s (%s)", m.description, m.version)
-			err := executeMigration(db, m)
+			err := executeMigration(ctx, db, m)
 			if err != nil {
 				return err
 			}
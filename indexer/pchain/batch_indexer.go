@@ -1,8 +1,11 @@
 package pchain
 
 import (
+	"context"
+	"errors"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/events"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
@@ -14,7 +17,6 @@ import (
 	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
 	"github.com/ava-labs/avalanchego/vms/platformvm/fx"
 	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
-	"github.com/ava-labs/avalanchego/vms/proposervm/block"
 	"gorm.io/gorm"
 )
 
@@ -33,10 +35,36 @@ type txBatchIndexer struct {
 	db        *gorm.DB
 	client    chain.IndexerClient
 	rpcClient chain.RPCClient
-
-	inOutIndexer    *shared.InputOutputIndexer
-	newTxs          []*database.PChainTx
+	parser    *chain.Parser
+	hrp       string
+
+	inOutIndexer *shared.InputOutputIndexer
+	newTxs       []*database.PChainTx
+	newBlocks    []*database.PChainBlock
+	// Signers of the new staking transactions
+	newSigners []*database.PChainTxSigner
+	// Decisions of option blocks, applied to the proposal transactions after the
+	// new transactions are persisted
+	decisions []proposalDecision
+	// Staking transactions of earlier batches decided by the option blocks of
+	// the last persisted batch
+	decidedTxs []database.PChainTx
+
+	// Filter of indexed transaction types, nil to index all types
+	filter          *shared.TxTypeFilter
 	dataTransformer *PChainDataTransformer
+
+	// Matches of the last persisted batch
+	watchlistMatches []*database.WatchlistMatch
+
+	// Nil if the validator weights are not recorded
+	weights *validatorWeightRecorder
+}
+
+// Status of the proposal transaction at the height preceding an option block
+type proposalDecision struct {
+	height uint64
+	status database.PChainTxStatus
 }
 
 func NewPChainDataTransformer(txTransformer func(tx *database.PChainTx) *database.PChainTx) *PChainDataTransformer {
@@ -46,18 +74,33 @@ func NewPChainDataTransformer(txTransformer func(tx *database.PChainTx) *databas
 }
 
 func NewPChainBatchIndexer(
-	ctx context.IndexerContext,
+	ctx indexerctx.IndexerContext,
 	client chain.IndexerClient,
 	rpcClient chain.RPCClient,
 	dataTransformer *PChainDataTransformer,
 ) *txBatchIndexer {
+	hrp := ctx.Config().Chain.ChainAddressHRP
 	updater := newPChainInputUpdater(ctx, rpcClient)
+	return newTxBatchIndexer(ctx.DB(), client, rpcClient, ctx.Parser(), updater, dataTransformer, hrp)
+}
+
+func newTxBatchIndexer(
+	db *gorm.DB,
+	client chain.IndexerClient,
+	rpcClient chain.RPCClient,
+	parser *chain.Parser,
+	updater shared.InputUpdater,
+	dataTransformer *PChainDataTransformer,
+	hrp string,
+) *txBatchIndexer {
 	return &txBatchIndexer{
-		db:        ctx.DB(),
+		db:        db,
 		client:    client,
 		rpcClient: rpcClient,
+		parser:    parser,
+		hrp:       hrp,
 
-		inOutIndexer:    shared.NewInputOutputIndexer(updater),
+		inOutIndexer:    shared.NewInputOutputIndexer(updater, hrp),
 		newTxs:          make([]*database.PChainTx, 0),
 		dataTransformer: dataTransformer,
 	}
@@ -65,58 +108,106 @@ func NewPChainBatchIndexer(
 
 func (xi *txBatchIndexer) Reset(containerLen int) {
 	xi.newTxs = make([]*database.PChainTx, 0, containerLen)
+	xi.newBlocks = make([]*database.PChainBlock, 0, containerLen)
+	xi.newSigners = nil
+	xi.decisions = nil
+	xi.decidedTxs = nil
 	xi.inOutIndexer.Reset(containerLen)
+	xi.watchlistMatches = nil
 }
 
-func (xi *txBatchIndexer) AddContainer(index uint64, container indexer.Container) error {
-	blk, err := block.Parse(container.Bytes)
-	if err != nil {
-		return err
-	}
-	innerBlk, err := blocks.Parse(blocks.GenesisCodec, blk.Block())
+func (xi *txBatchIndexer) Rows() int {
+	ins, outs := xi.inOutIndexer.Len()
+	return len(xi.newBlocks) + len(xi.newTxs) + len(xi.newSigners) + ins + outs
+}
+
+func (xi *txBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
+	innerBlk, err := xi.parser.ParsePChainBlock(container.Bytes)
 	if err != nil {
 		return err
 	}
 
+	// Apricot blocks are issued before Banff, Banff blocks since. Banff blocks wrap
+	// the corresponding Apricot blocks and add the block time; the later upgrades
+	// (Cortina, Durango) change the rules but keep the Banff block format.
 	switch innerBlkType := innerBlk.(type) {
 	case *blocks.ApricotProposalBlock:
 		tx := innerBlkType.Tx
-		err = xi.addTx(&container, database.PChainProposalBlock, innerBlk.Height(), tx)
-	case *blocks.ApricotCommitBlock:
+		err = xi.addTx(ctx, &container, database.PChainProposalBlock, innerBlk.Height(), 0, database.PChainTxProposed, tx)
+	case *blocks.BanffProposalBlock:
+		err = xi.addTxs(ctx, &container, database.PChainProposalBlock, innerBlk.Height(), pChainBlockTxs(innerBlkType))
+	case *blocks.ApricotCommitBlock, *blocks.BanffCommitBlock:
 		xi.addEmptyTx(&container, database.PChainCommitBlock, innerBlk.Height())
-	case *blocks.ApricotAbortBlock:
+		xi.decide(innerBlk.Height()-1, database.PChainTxAccepted)
+	case *blocks.ApricotAbortBlock, *blocks.BanffAbortBlock:
 		xi.addEmptyTx(&container, database.PChainAbortBlock, innerBlk.Height())
+		xi.decide(innerBlk.Height()-1, database.PChainTxAborted)
 	case *blocks.ApricotStandardBlock:
-		for _, tx := range innerBlkType.Txs() {
-			err = xi.addTx(&container, database.PChainStandardBlock, innerBlk.Height(), tx)
-			if err != nil {
-				break
-			}
-		}
+		err = xi.addTxs(ctx, &container, database.PChainStandardBlock, innerBlk.Height(), innerBlkType.Txs())
+	case *blocks.BanffStandardBlock:
+		err = xi.addTxs(ctx, &container, database.PChainStandardBlock, innerBlk.Height(), innerBlkType.Txs())
 	default:
 		err = fmt.Errorf("block %d has unexpected type %T", index, innerBlkType)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Transactions which are filtered out are counted as well
+	block, err := newPChainBlock(xi.parser, container.Bytes, container.ID.String(), &index, innerBlk.Height(),
+		chain.TimestampToTime(container.Timestamp), len(pChainBlockTxs(innerBlk)))
+	if err != nil {
+		return err
+	}
+	xi.newBlocks = append(xi.newBlocks, block)
+	return nil
+}
+
+func (xi *txBatchIndexer) ProcessBatch(ctx context.Context) error {
+	return xi.inOutIndexer.ProcessBatch(ctx)
 }
 
-func (xi *txBatchIndexer) ProcessBatch() error {
-	return xi.inOutIndexer.ProcessBatch()
+// Transactions of a block, in the order of execution
+func (xi *txBatchIndexer) addTxs(ctx context.Context, container *indexer.Container, blockType database.PChainBlockType, height uint64, blkTxs []*txs.Tx) error {
+	for i, tx := range blkTxs {
+		err := xi.addTx(ctx, container, blockType, height, uint32(i), newTxStatus(blockType, i, len(blkTxs)), tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (xi *txBatchIndexer) addTx(container *indexer.Container, blockType database.PChainBlockType, height uint64, tx *txs.Tx) error {
+func (xi *txBatchIndexer) addTx(
+	ctx context.Context,
+	container *indexer.Container,
+	blockType database.PChainBlockType,
+	height uint64,
+	txIndex uint32,
+	status database.PChainTxStatus,
+	tx *txs.Tx,
+) error {
 	txID := tx.ID().String()
 	dbTx := &database.PChainTx{}
 	dbTx.TxID = &txID
 	dbTx.BlockID = container.ID.String()
 	dbTx.BlockType = blockType
 	dbTx.BlockHeight = height
+	dbTx.BlockTxIndex = txIndex
+	dbTx.Status = status
 	dbTx.Timestamp = chain.TimestampToTime(container.Timestamp)
 	dbTx.Bytes = container.Bytes
+	dbTx.Fee = txFee(tx.Unsigned)
+	dbTx.Memo = chain.EncodeMemo(txMemo(tx.Unsigned))
+
+	// Entities of a transaction which is filtered out are removed after parsing
+	txsLen := len(xi.newTxs)
+	insLen, outsLen := xi.inOutIndexer.Len()
 
 	var err error = nil
 	switch unsignedTx := tx.Unsigned.(type) {
 	case *txs.RewardValidatorTx:
-		err = xi.updateRewardValidatorTx(dbTx, unsignedTx)
+		err = xi.updateRewardValidatorTx(ctx, dbTx, unsignedTx)
 	case *txs.AddValidatorTx:
 		err = xi.updateAddValidatorTx(dbTx, unsignedTx)
 	case *txs.AddDelegatorTx:
@@ -133,10 +224,39 @@ func (xi *txBatchIndexer) addTx(container *indexer.Container, blockType database
 		err = xi.updateGeneralBaseTx(dbTx, database.PChainCreateChainTx, &unsignedTx.BaseTx)
 	case *txs.CreateSubnetTx:
 		err = xi.updateGeneralBaseTx(dbTx, database.PChainCreateSubnetTx, &unsignedTx.BaseTx)
+	case *txs.AddPermissionlessValidatorTx:
+		err = xi.updateAddPermissionlessValidatorTx(dbTx, unsignedTx)
+	case *txs.AddPermissionlessDelegatorTx:
+		err = xi.updateAddPermissionlessDelegatorTx(dbTx, unsignedTx)
+	case *txs.RemoveSubnetValidatorTx:
+		err = xi.updateGeneralBaseTx(dbTx, database.PChainRemoveSubnetValidatorTx, &unsignedTx.BaseTx)
+	case *txs.TransformSubnetTx:
+		err = xi.updateGeneralBaseTx(dbTx, database.PChainTransformSubnetTx, &unsignedTx.BaseTx)
 	default:
 		err = fmt.Errorf("p-chain transaction %v with type %T in block %d is not indexed", dbTx.TxID, unsignedTx, height)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	if !xi.filter.Indexed(string(dbTx.Type)) {
+		xi.newTxs = xi.newTxs[:txsLen]
+		xi.inOutIndexer.Truncate(insLen, outsLen)
+		return nil
+	}
+	if isStakingTx(dbTx.Type) {
+		return xi.addSigners(txID, tx)
+	}
+	return nil
+}
+
+// Signers of the staking transaction, recovered from its credentials
+func (xi *txBatchIndexer) addSigners(txID string, tx *txs.Tx) error {
+	addresses, err := chain.PChainTxSignerAddresses(tx, xi.hrp)
+	if err != nil {
+		return fmt.Errorf("signers of p-chain transaction %s: %w", txID, err)
+	}
+	xi.newSigners = append(xi.newSigners, newPChainTxSigners(txID, addresses)...)
+	return nil
 }
 
 func (xi *txBatchIndexer) addEmptyTx(container *indexer.Container, blockType database.PChainBlockType, height uint64) {
@@ -147,15 +267,27 @@ func (xi *txBatchIndexer) addEmptyTx(container *indexer.Container, blockType dat
 	dbTx.Timestamp = chain.TimestampToTime(container.Timestamp)
 	dbTx.Bytes = container.Bytes
 	dbTx.TxID = nil
+	dbTx.Status = database.PChainTxAccepted
 
 	xi.newTxs = append(xi.newTxs, dbTx)
 }
 
-func (xi *txBatchIndexer) updateRewardValidatorTx(dbTx *database.PChainTx, tx *txs.RewardValidatorTx) error {
+// Decide the status of the proposal transaction at the given height. The proposal
+// block is in the same batch or it is already persisted.
+func (xi *txBatchIndexer) decide(height uint64, status database.PChainTxStatus) {
+	for _, tx := range xi.newTxs {
+		if tx.BlockHeight == height && tx.Status == database.PChainTxProposed {
+			tx.Status = status
+		}
+	}
+	xi.decisions = append(xi.decisions, proposalDecision{height: height, status: status})
+}
+
+func (xi *txBatchIndexer) updateRewardValidatorTx(ctx context.Context, dbTx *database.PChainTx, tx *txs.RewardValidatorTx) error {
 	dbTx.Type = database.PChainRewardValidatorTx
 	dbTx.RewardTxID = tx.TxID.String()
 
-	outs, err := getRewardOutputs(xi.rpcClient, dbTx.RewardTxID)
+	outs, err := getRewardOutputs(ctx, xi.rpcClient, xi.hrp, dbTx.RewardTxID)
 	if err != nil {
 		return err
 	}
@@ -175,6 +307,18 @@ func (xi *txBatchIndexer) updateAddDelegatorTx(dbTx *database.PChainTx, tx *txs.
 	return xi.updateAddStakerTx(dbTx, tx, tx.Ins, tx.DelegationRewardsOwner)
 }
 
+func (xi *txBatchIndexer) updateAddPermissionlessValidatorTx(dbTx *database.PChainTx, tx *txs.AddPermissionlessValidatorTx) error {
+	dbTx.Type = database.PChainAddPermissionlessValidatorTx
+	dbTx.FeePercentage = tx.DelegationShares
+	dbTx.BlsPublicKey, dbTx.BlsProofOfPossession = blsKey(tx.Signer)
+	return xi.updateAddStakerTx(dbTx, tx, tx.Ins, tx.ValidatorRewardsOwner)
+}
+
+func (xi *txBatchIndexer) updateAddPermissionlessDelegatorTx(dbTx *database.PChainTx, tx *txs.AddPermissionlessDelegatorTx) error {
+	dbTx.Type = database.PChainAddPermissionlessDelegatorTx
+	return xi.updateAddStakerTx(dbTx, tx, tx.Ins, tx.DelegationRewardsOwner)
+}
+
 func (xi *txBatchIndexer) updateImportTx(dbTx *database.PChainTx, tx *txs.ImportTx) error {
 	dbTx.Type = database.PChainImportTx
 	dbTx.ChainID = tx.SourceChain.String()
@@ -203,7 +347,7 @@ func (xi *txBatchIndexer) updateGeneralBaseTx(dbTx *database.PChainTx, txType da
 }
 
 // Persist all entities
-func (xi *txBatchIndexer) PersistEntities(db *gorm.DB) error {
+func (xi *txBatchIndexer) PersistEntities(ctx context.Context, db *gorm.DB) error {
 	ins, err := utils.CastArray[*database.PChainTxInput](xi.inOutIndexer.GetIns())
 	if err != nil {
 		return err
@@ -219,7 +363,130 @@ func (xi *txBatchIndexer) PersistEntities(db *gorm.DB) error {
 	} else {
 		txs = xi.newTxs
 	}
-	return database.CreatePChainEntities(db, txs, ins, outs)
+	if err := normalizeNodeIDs(txs); err != nil {
+		return err
+	}
+	if err := database.CreatePChainEntities(ctx, db, txs, ins, outs); err != nil {
+		return err
+	}
+	if err := database.CreatePChainTxSigners(ctx, db, xi.newSigners); err != nil {
+		return err
+	}
+	for _, d := range xi.decisions {
+		if err := database.UpdatePChainProposalTxStatus(ctx, db, d.height, d.status); err != nil {
+			return err
+		}
+	}
+	if err := xi.persistBlocks(ctx, db); err != nil {
+		return err
+	}
+	xi.decidedTxs, err = database.FetchPChainStakingTxsAtHeights(ctx, db, xi.earlierDecidedHeights())
+	if err != nil {
+		return err
+	}
+	if err := xi.weights.update(ctx, db, txs, xi.decidedTxs); err != nil {
+		return err
+	}
+	if len(xi.newBlocks) > 0 {
+		indexedTo := xi.newBlocks[len(xi.newBlocks)-1].Timestamp
+		if err := xi.weights.record(ctx, db, indexedTo); err != nil {
+			return err
+		}
+	}
+
+	watchlist, err := database.FetchWatchlist(ctx, db)
+	if err != nil {
+		return err
+	}
+	xi.watchlistMatches = matchWatchlist(watchlist, txs, ins, outs)
+	return database.CreateWatchlistMatches(ctx, db, xi.watchlistMatches)
+}
+
+func (xi *txBatchIndexer) persistBlocks(ctx context.Context, db *gorm.DB) error {
+	if len(xi.newBlocks) == 0 {
+		return nil
+	}
+	prev, err := database.FetchPChainBlock(ctx, db, xi.newBlocks[0].Height-1)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		prev = nil
+	} else if err != nil {
+		return err
+	}
+	if err := verifyPChainBlocks(prev, xi.newBlocks); err != nil {
+		return err
+	}
+	return database.CreatePChainBlocks(ctx, db, xi.newBlocks)
+}
+
+// Heights of the decided proposal blocks which are not in the batch
+func (xi *txBatchIndexer) earlierDecidedHeights() []uint64 {
+	newHeights := make(map[uint64]bool, len(xi.newBlocks))
+	for _, blk := range xi.newBlocks {
+		newHeights[blk.Height] = true
+	}
+	var heights []uint64
+	for _, d := range xi.decisions {
+		if !newHeights[d.height] {
+			heights = append(heights, d.height)
+		}
+	}
+	return heights
+}
+
+// Accepted staking transactions of the last persisted batch: the new ones
+// which are accepted and the ones of earlier batches accepted by its option
+// blocks. Proposed transactions are returned once they are accepted.
+func (xi *txBatchIndexer) acceptedStakingTxs() []*database.PChainTx {
+	var accepted []*database.PChainTx
+	for _, tx := range xi.newTxs {
+		if tx.TxID != nil && isStakingTx(tx.Type) && tx.Status == database.PChainTxAccepted {
+			accepted = append(accepted, tx)
+		}
+	}
+	for i := range xi.decidedTxs {
+		if xi.decidedTxs[i].Status == database.PChainTxAccepted {
+			accepted = append(accepted, &xi.decidedTxs[i])
+		}
+	}
+	return accepted
+}
+
+// Publishes a StakingTx event for each accepted staking transaction (see
+// acceptedStakingTxs) and a WatchlistMatch event for each match
+func (xi *txBatchIndexer) PublishEvents(publisher *events.Publisher) {
+	publishWatchlistMatches(publisher, xi.watchlistMatches)
+	for _, tx := range xi.acceptedStakingTxs() {
+		publisher.Publish(events.StakingTx, events.StakingTxData{
+			TxID:         *tx.TxID,
+			Type:         string(tx.Type),
+			BlockHeight:  tx.BlockHeight,
+			NodeID:       tx.NodeID,
+			StartTime:    tx.StartTime.UTC(),
+			EndTime:      tx.EndTime.UTC(),
+			Weight:       tx.Weight,
+			RewardsOwner: tx.RewardsOwner,
+		})
+	}
+}
+
+// Node IDs of staking transactions are stored in the format parsed when voting
+// and mirroring, a batch with a malformed node ID is rejected
+func normalizeNodeIDs(txs []*database.PChainTx) error {
+	for _, tx := range txs {
+		if tx.TxID == nil || !isStakingTx(tx.Type) {
+			continue
+		}
+		nodeID, err := chain.NormalizeNodeID(tx.NodeID)
+		if err != nil {
+			return fmt.Errorf("staking transaction %s: %w", *tx.TxID, err)
+		}
+		tx.NodeID = nodeID
+	}
+	return nil
+}
+
+func isStakingTx(txType database.PChainTxType) bool {
+	return txType.IsValidatorTx() || txType.IsDelegatorTx()
 }
 
 // Common code for AddDelegatorTx and AddValidatorTx
@@ -236,13 +503,13 @@ func (xi *txBatchIndexer) updateAddStakerTx(
 	dbTx.EndTime = &endTime
 	dbTx.Weight = tx.Weight()
 
-	ownerAddress, err := shared.RewardsOwnerAddress(rewardsOwner)
+	ownerAddress, err := shared.RewardsOwnerAddress(xi.hrp, rewardsOwner)
 	if err != nil {
 		return err
 	}
 	dbTx.RewardsOwner = ownerAddress
 
-	outs, err := getAddStakerTxOutputs(*dbTx.TxID, tx)
+	outs, err := getAddStakerTxOutputs(xi.hrp, *dbTx.TxID, tx)
 	if err != nil {
 		return err
 	}
@@ -253,12 +520,12 @@ func (xi *txBatchIndexer) updateAddStakerTx(
 	return nil
 }
 
-func getAddStakerTxOutputs(txID string, tx txs.PermissionlessStaker) ([]shared.Output, error) {
-	outs, err := shared.OutputsFromTxOuts(txID, tx.Outputs(), 0, PChainDefaultInputOutputCreator)
+func getAddStakerTxOutputs(hrp string, txID string, tx txs.PermissionlessStaker) ([]shared.Output, error) {
+	outs, err := shared.OutputsFromTxOuts(hrp, txID, tx.Outputs(), 0, PChainDefaultInputOutputCreator)
 	if err != nil {
 		return nil, err
 	}
-	stakeOuts, err := shared.OutputsFromTxOuts(txID, tx.Stake(), len(outs), PChainStakerInputOutputCreator)
+	stakeOuts, err := shared.OutputsFromTxOuts(hrp, txID, tx.Stake(), len(outs), PChainStakerInputOutputCreator)
 	if err != nil {
 		return nil, err
 	}
@@ -266,10 +533,10 @@ func getAddStakerTxOutputs(txID string, tx txs.PermissionlessStaker) ([]shared.O
 	return outs, nil
 }
 
-func getRewardOutputs(client chain.RPCClient, txID string) ([]shared.Output, error) {
-	utxos, err := CallPChainGetRewardUTXOsApi(client, txID)
+func getRewardOutputs(ctx context.Context, client chain.RPCClient, hrp string, txID string) ([]shared.Output, error) {
+	utxos, err := CallPChainGetRewardUTXOsApi(ctx, client, txID)
 	if err != nil {
 		return nil, err
 	}
-	return shared.OutputsFromUTXO(txID, utxos, PChainRewardOutputCreator)
+	return shared.OutputsFromUTXO(hrp, txID, utxos, PChainRewardOutputCreator)
 }
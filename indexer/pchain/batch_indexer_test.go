@@ -0,0 +1,303 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"context"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/validator"
+	"github.com/ava-labs/avalanchego/vms/proposervm/block"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+var banffTime = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestAddBanffContainers(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	nodeID := ids.NodeID{1}
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{{2}}}
+	validatorTx := banffTx(t, &txs.AddPermissionlessValidatorTx{
+		Validator: validator.Validator{
+			NodeID: nodeID,
+			Start:  uint64(banffTime.Unix()),
+			End:    uint64(banffTime.Add(14 * 24 * time.Hour).Unix()),
+			Wght:   1000,
+		},
+		Subnet:                constants.PrimaryNetworkID,
+		Signer:                &signer.Empty{},
+		StakeOuts:             []*avax.TransferableOutput{banffOut(1000, owner)},
+		ValidatorRewardsOwner: owner,
+		DelegatorRewardsOwner: owner,
+		DelegationShares:      200000,
+	})
+	removeTx := banffTx(t, &txs.RemoveSubnetValidatorTx{
+		NodeID:     nodeID,
+		Subnet:     ids.ID{3},
+		SubnetAuth: &secp256k1fx.Input{},
+	})
+
+	proposalBlk, err := blocks.NewBanffProposalBlock(banffTime, ids.Empty, 1, validatorTx)
+	require.NoError(t, err)
+	commitBlk, err := blocks.NewBanffCommitBlock(banffTime, proposalBlk.ID(), 2)
+	require.NoError(t, err)
+	standardBlk, err := blocks.NewBanffStandardBlock(banffTime, commitBlk.ID(), 3, []*txs.Tx{removeTx})
+	require.NoError(t, err)
+
+	xi := newTxBatchIndexer(nil, nil, nil, chain.NewParser(), newPChainInputUpdaterWithDB(testOutputsDB{}, nil, chain.NewParser(), "localflare"), nil, "localflare")
+	xi.Reset(3)
+	parentID := ids.Empty
+	for i, blk := range []blocks.Block{proposalBlk, commitBlk, standardBlk} {
+		container := banffContainer(t, blk, parentID)
+		require.NoError(t, xi.AddContainer(context.Background(), uint64(i), container))
+		parentID = container.ID
+	}
+
+	require.Len(t, xi.newTxs, 3)
+
+	dbValidator := xi.newTxs[0]
+	require.Equal(t, database.PChainAddPermissionlessValidatorTx, dbValidator.Type)
+	require.Equal(t, database.PChainProposalBlock, dbValidator.BlockType)
+	require.Equal(t, uint64(1), dbValidator.BlockHeight)
+	require.Equal(t, nodeID.String(), dbValidator.NodeID)
+	require.Equal(t, uint64(1000), dbValidator.Weight)
+	require.Equal(t, uint32(200000), dbValidator.FeePercentage)
+	require.NotEmpty(t, dbValidator.RewardsOwner)
+	require.Empty(t, dbValidator.BlsPublicKey)
+	require.Equal(t, database.PChainTxAccepted, dbValidator.Status)
+	require.Equal(t, []proposalDecision{{height: 1, status: database.PChainTxAccepted}}, xi.decisions)
+
+	dbCommit := xi.newTxs[1]
+	require.Equal(t, database.PChainCommitBlock, dbCommit.BlockType)
+	require.Nil(t, dbCommit.TxID)
+
+	dbRemove := xi.newTxs[2]
+	require.Equal(t, database.PChainRemoveSubnetValidatorTx, dbRemove.Type)
+	require.Equal(t, database.PChainStandardBlock, dbRemove.BlockType)
+	require.Equal(t, uint64(3), dbRemove.BlockHeight)
+
+	require.Len(t, xi.newBlocks, 3)
+	for i, txCount := range []int{1, 0, 1} {
+		require.Equal(t, uint64(i+1), xi.newBlocks[i].Height)
+		require.Equal(t, uint64(i), *xi.newBlocks[i].ContainerIndex)
+		require.Equal(t, xi.newTxs[i].BlockID, xi.newBlocks[i].BlockID)
+		require.Equal(t, txCount, xi.newBlocks[i].TxCount)
+		require.Empty(t, xi.newBlocks[i].Proposer)
+	}
+	require.NoError(t, verifyPChainBlocks(nil, xi.newBlocks))
+
+	// Node IDs of stored staking transactions are recovered from the block bytes
+	blockNodeID, err := blockTxNodeID(chain.NewParser(), dbValidator.Bytes, *dbValidator.TxID)
+	require.NoError(t, err)
+	require.Equal(t, nodeID.String(), blockNodeID)
+	_, err = blockTxNodeID(chain.NewParser(), dbRemove.Bytes, *dbRemove.TxID)
+	require.Error(t, err)
+}
+
+func TestNormalizeNodeIDs(t *testing.T) {
+	txID := "tx"
+	nodeID := ids.NodeID{1}.String()
+	validator := &database.PChainTx{TxID: &txID, Type: database.PChainAddValidatorTx, NodeID: nodeID[len(ids.NodeIDPrefix):]}
+	export := &database.PChainTx{TxID: &txID, Type: database.PChainExportTx}
+	require.NoError(t, normalizeNodeIDs([]*database.PChainTx{validator, export}))
+	require.Equal(t, nodeID, validator.NodeID)
+	require.Empty(t, export.NodeID)
+
+	delegator := &database.PChainTx{TxID: &txID, Type: database.PChainAddDelegatorTx, NodeID: nodeID[:len(nodeID)-1]}
+	require.Error(t, normalizeNodeIDs([]*database.PChainTx{delegator}))
+}
+
+func TestAddBanffContainersAborted(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{{2}}}
+	delegatorTx := banffTx(t, &txs.AddPermissionlessDelegatorTx{
+		Validator: validator.Validator{
+			NodeID: ids.NodeID{1},
+			Start:  uint64(banffTime.Unix()),
+			End:    uint64(banffTime.Add(14 * 24 * time.Hour).Unix()),
+			Wght:   100,
+		},
+		Subnet:                 constants.PrimaryNetworkID,
+		StakeOuts:              []*avax.TransferableOutput{banffOut(100, owner)},
+		DelegationRewardsOwner: owner,
+	})
+
+	proposalBlk, err := blocks.NewBanffProposalBlock(banffTime, ids.Empty, 1, delegatorTx)
+	require.NoError(t, err)
+	abortBlk, err := blocks.NewBanffAbortBlock(banffTime, proposalBlk.ID(), 2)
+	require.NoError(t, err)
+
+	xi := newTxBatchIndexer(nil, nil, nil, chain.NewParser(), newPChainInputUpdaterWithDB(testOutputsDB{}, nil, chain.NewParser(), "localflare"), nil, "localflare")
+
+	// The option block is in the batch following the proposal block
+	xi.Reset(1)
+	proposal := banffContainer(t, proposalBlk, ids.Empty)
+	require.NoError(t, xi.AddContainer(context.Background(), 0, proposal))
+	require.Len(t, xi.newTxs, 1)
+	require.Equal(t, database.PChainTxProposed, xi.newTxs[0].Status)
+	require.Empty(t, xi.decisions)
+
+	xi.Reset(1)
+	require.NoError(t, xi.AddContainer(context.Background(), 1, banffContainer(t, abortBlk, proposal.ID)))
+	require.Len(t, xi.newTxs, 1)
+	require.Equal(t, database.PChainAbortBlock, xi.newTxs[0].BlockType)
+	require.Equal(t, database.PChainTxAccepted, xi.newTxs[0].Status)
+	require.Equal(t, []proposalDecision{{height: 1, status: database.PChainTxAborted}}, xi.decisions)
+}
+
+// Staking transactions are published once they are accepted, the proposal
+// transaction of an option block in a later batch is fetched by PersistEntities
+func TestAcceptedStakingTxs(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{{2}}}
+	delegatorTx := banffTx(t, &txs.AddPermissionlessDelegatorTx{
+		Validator: validator.Validator{
+			NodeID: ids.NodeID{1},
+			Start:  uint64(banffTime.Unix()),
+			End:    uint64(banffTime.Add(14 * 24 * time.Hour).Unix()),
+			Wght:   100,
+		},
+		Subnet:                 constants.PrimaryNetworkID,
+		StakeOuts:              []*avax.TransferableOutput{banffOut(100, owner)},
+		DelegationRewardsOwner: owner,
+	})
+
+	proposalBlk, err := blocks.NewBanffProposalBlock(banffTime, ids.Empty, 1, delegatorTx)
+	require.NoError(t, err)
+	commitBlk, err := blocks.NewBanffCommitBlock(banffTime, proposalBlk.ID(), 2)
+	require.NoError(t, err)
+	abortBlk, err := blocks.NewBanffAbortBlock(banffTime, proposalBlk.ID(), 2)
+	require.NoError(t, err)
+	proposal := banffContainer(t, proposalBlk, ids.Empty)
+
+	xi := newTxBatchIndexer(nil, nil, nil, chain.NewParser(), newPChainInputUpdaterWithDB(testOutputsDB{}, nil, chain.NewParser(), "localflare"), nil, "localflare")
+
+	// Proposal and commit block in the same batch
+	xi.Reset(2)
+	require.NoError(t, xi.AddContainer(context.Background(), 0, proposal))
+	require.NoError(t, xi.AddContainer(context.Background(), 1, banffContainer(t, commitBlk, proposal.ID)))
+	require.Empty(t, xi.earlierDecidedHeights())
+	accepted := xi.acceptedStakingTxs()
+	require.Len(t, accepted, 1)
+	require.Equal(t, delegatorTx.ID().String(), *accepted[0].TxID)
+
+	// The proposal block is not published before it is decided
+	xi.Reset(1)
+	require.NoError(t, xi.AddContainer(context.Background(), 0, proposal))
+	require.Equal(t, database.PChainTxProposed, xi.newTxs[0].Status)
+	require.Empty(t, xi.acceptedStakingTxs())
+	proposed := *xi.newTxs[0]
+
+	for _, c := range []struct {
+		optionBlk blocks.Block
+		status    database.PChainTxStatus
+		published bool
+	}{
+		{commitBlk, database.PChainTxAccepted, true},
+		{abortBlk, database.PChainTxAborted, false},
+	} {
+		xi.Reset(1)
+		require.NoError(t, xi.AddContainer(context.Background(), 1, banffContainer(t, c.optionBlk, proposal.ID)))
+		require.Equal(t, []uint64{1}, xi.earlierDecidedHeights())
+
+		// Proposal transaction with the decided status, as fetched after the
+		// decisions are persisted
+		decided := proposed
+		decided.Status = c.status
+		xi.decidedTxs = []database.PChainTx{decided}
+
+		accepted := xi.acceptedStakingTxs()
+		if c.published {
+			require.Len(t, accepted, 1)
+			require.Equal(t, delegatorTx.ID().String(), *accepted[0].TxID)
+		} else {
+			require.Empty(t, accepted)
+		}
+	}
+}
+
+func TestAddBanffContainersFiltered(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{{2}}}
+	exportTx := banffTx(t, &txs.ExportTx{
+		BaseTx:           txs.BaseTx{BaseTx: avax.BaseTx{Outs: []*avax.TransferableOutput{banffOut(10, owner)}}},
+		DestinationChain: ids.ID{4},
+	})
+	removeTx := banffTx(t, &txs.RemoveSubnetValidatorTx{
+		BaseTx:     txs.BaseTx{BaseTx: avax.BaseTx{Memo: []byte("deposit")}},
+		NodeID:     ids.NodeID{1},
+		Subnet:     ids.ID{3},
+		SubnetAuth: &secp256k1fx.Input{},
+	})
+	standardBlk, err := blocks.NewBanffStandardBlock(banffTime, ids.Empty, 1, []*txs.Tx{exportTx, removeTx})
+	require.NoError(t, err)
+
+	filter, err := shared.NewTxTypeFilter(&config.IndexerConfig{
+		ExcludeTypes: []string{string(database.PChainExportTx)},
+	}, []string{string(database.PChainExportTx), string(database.PChainRemoveSubnetValidatorTx)})
+	require.NoError(t, err)
+
+	xi := newTxBatchIndexer(nil, nil, nil, chain.NewParser(), newPChainInputUpdaterWithDB(testOutputsDB{}, nil, chain.NewParser(), "localflare"), nil, "localflare")
+	xi.filter = filter
+	xi.Reset(1)
+	require.NoError(t, xi.AddContainer(context.Background(), 0, banffContainer(t, standardBlk, ids.Empty)))
+
+	require.Len(t, xi.newTxs, 1)
+	require.Equal(t, database.PChainRemoveSubnetValidatorTx, xi.newTxs[0].Type)
+	// The position in the block includes the filtered transaction
+	require.Equal(t, uint32(1), xi.newTxs[0].BlockTxIndex)
+	require.Equal(t, "0x6465706f736974", xi.newTxs[0].Memo)
+	require.Empty(t, xi.inOutIndexer.GetNewOuts())
+	require.Len(t, xi.newBlocks, 1)
+	require.Equal(t, 2, xi.newBlocks[0].TxCount)
+}
+
+func banffTx(t *testing.T, unsigned txs.UnsignedTx) *txs.Tx {
+	tx := &txs.Tx{Unsigned: unsigned}
+	require.NoError(t, tx.Initialize(txs.Codec))
+	return tx
+}
+
+func banffOut(amount uint64, owner *secp256k1fx.OutputOwners) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Out: &secp256k1fx.TransferOutput{Amt: amount, OutputOwners: *owner},
+	}
+}
+
+// Proposervm container with the given parent container
+func banffContainer(t *testing.T, blk blocks.Block, parentID ids.ID) indexer.Container {
+	proBlk, err := block.BuildUnsigned(parentID, banffTime, blk.Height(), blk.Bytes())
+	require.NoError(t, err)
+	return indexer.Container{
+		ID:        proBlk.ID(),
+		Bytes:     proBlk.Bytes(),
+		Timestamp: banffTime.UnixNano(),
+	}
+}
@@ -0,0 +1,70 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+// Create the block entity from the container bytes, containerIndex is nil if
+// the index of the container is not known
+func newPChainBlock(parser *chain.Parser, bytes []byte, blockID string, containerIndex *uint64, height uint64, timestamp time.Time, txCount int) (*database.PChainBlock, error) {
+	header, err := parser.ParsePChainBlockHeader(bytes)
+	if err != nil {
+		return nil, err
+	}
+	block := &database.PChainBlock{
+		Height:    height,
+		BlockID:   blockID,
+		ParentID:  header.ParentID.String(),
+		Timestamp: timestamp,
+		TxCount:   txCount,
+
+		ContainerIndex: containerIndex,
+	}
+	if header.Proposer != ids.EmptyNodeID {
+		block.Proposer = header.Proposer.String()
+	}
+	return block, nil
+}
+
+// Transactions of the block, in the order of execution. Banff proposal blocks may
+// carry decision transactions, they are executed before the proposal transaction,
+// which is the last one.
+func pChainBlockTxs(blk blocks.Block) []*txs.Tx {
+	if proposalBlk, ok := blk.(*blocks.BanffProposalBlock); ok {
+		blkTxs := make([]*txs.Tx, 0, len(proposalBlk.Transactions)+1)
+		blkTxs = append(blkTxs, proposalBlk.Transactions...)
+		return append(blkTxs, proposalBlk.Tx)
+	}
+	return blk.Txs()
+}
+
+// Status of a newly indexed transaction at position txIndex of a block with txCount
+// transactions. The proposal transaction of a proposal block is its last one, it is
+// accepted or aborted by the option block following it.
+func newTxStatus(blockType database.PChainBlockType, txIndex int, txCount int) database.PChainTxStatus {
+	if blockType == database.PChainProposalBlock && txIndex == txCount-1 {
+		return database.PChainTxProposed
+	}
+	return database.PChainTxAccepted
+}
+
+// Check that each block is the child of the previous one, prev is the stored block
+// preceding the first block (nil if not indexed). The P-chain is final, so a
+// mismatch means that the node or the stored data is inconsistent.
+func verifyPChainBlocks(prev *database.PChainBlock, blocks []*database.PChainBlock) error {
+	for _, block := range blocks {
+		if prev != nil && (block.Height != prev.Height+1 || block.ParentID != prev.BlockID) {
+			return fmt.Errorf("block %s at height %d with parent %s does not follow block %s at height %d",
+				block.BlockID, block.Height, block.ParentID, prev.BlockID, prev.Height)
+		}
+		prev = block
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"flare-indexer/database"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPChainBlocks(t *testing.T) {
+	prev := &database.PChainBlock{Height: 10, BlockID: "a"}
+	blocks := []*database.PChainBlock{
+		{Height: 11, BlockID: "b", ParentID: "a"},
+		{Height: 12, BlockID: "c", ParentID: "b"},
+	}
+	require.NoError(t, verifyPChainBlocks(prev, blocks))
+	require.NoError(t, verifyPChainBlocks(nil, blocks))
+
+	require.Error(t, verifyPChainBlocks(&database.PChainBlock{Height: 10, BlockID: "x"}, blocks))
+	require.Error(t, verifyPChainBlocks(&database.PChainBlock{Height: 9, BlockID: "a"}, blocks))
+
+	blocks[1].ParentID = "x"
+	require.Error(t, verifyPChainBlocks(nil, blocks))
+}
+
+func TestNewTxStatus(t *testing.T) {
+	require.Equal(t, database.PChainTxProposed, newTxStatus(database.PChainProposalBlock, 0, 1))
+	// Decision transactions of a Banff proposal block precede the proposal transaction
+	require.Equal(t, database.PChainTxAccepted, newTxStatus(database.PChainProposalBlock, 0, 2))
+	require.Equal(t, database.PChainTxProposed, newTxStatus(database.PChainProposalBlock, 1, 2))
+	require.Equal(t, database.PChainTxAccepted, newTxStatus(database.PChainStandardBlock, 0, 1))
+}
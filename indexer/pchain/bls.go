@@ -0,0 +1,76 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gorm.io/gorm"
+)
+
+// BLS public key and proof of possession of a permissionless validator, hex
+// encoded, or empty strings if the signer has no key (e.g. validators of
+// subnets)
+func blsKey(s signer.Signer) (string, string) {
+	pop, ok := s.(*signer.ProofOfPossession)
+	if !ok {
+		return "", ""
+	}
+	return hexutil.Encode(pop.PublicKey[:]), hexutil.Encode(pop.ProofOfPossession[:])
+}
+
+// Permissionless validator transactions indexed before BLS keys were stored
+// get their keys from the stored block bytes
+func storePChainBLSKeys(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			if dbTx.TxID == nil || dbTx.Type != database.PChainAddPermissionlessValidatorTx {
+				continue
+			}
+			publicKey, proofOfPossession, err := blockTxBLSKey(parser, dbTx.Bytes, *dbTx.TxID)
+			if err != nil {
+				return err
+			}
+			if publicKey == "" {
+				continue
+			}
+			if err := database.UpdatePChainTxBLSKey(ctx, db, dbTx.ID, publicKey, proofOfPossession); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// BLS key of the permissionless validator transaction with the given id contained in the block
+func blockTxBLSKey(parser *chain.Parser, blockBytes []byte, txID string) (string, string, error) {
+	blk, err := parser.ParsePChainBlock(blockBytes)
+	if err != nil {
+		return "", "", err
+	}
+	for _, tx := range pChainBlockTxs(blk) {
+		if tx.ID().String() != txID {
+			continue
+		}
+		validatorTx, ok := tx.Unsigned.(*txs.AddPermissionlessValidatorTx)
+		if !ok {
+			return "", "", fmt.Errorf("transaction %s is not a permissionless validator transaction", txID)
+		}
+		publicKey, proofOfPossession := blsKey(validatorTx.Signer)
+		return publicKey, proofOfPossession, nil
+	}
+	return "", "", fmt.Errorf("transaction %s not found in its block", txID)
+}
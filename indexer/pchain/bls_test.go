@@ -0,0 +1,61 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/validator"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockTxBLSKey(t *testing.T) {
+	sk, err := bls.NewSecretKey()
+	require.NoError(t, err)
+	pop := signer.NewProofOfPossession(sk)
+
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{{2}}}
+	validatorTx := banffTx(t, &txs.AddPermissionlessValidatorTx{
+		Validator: validator.Validator{
+			NodeID: ids.NodeID{1},
+			Start:  uint64(banffTime.Unix()),
+			End:    uint64(banffTime.Add(14 * 24 * time.Hour).Unix()),
+			Wght:   1000,
+		},
+		Subnet:                constants.PrimaryNetworkID,
+		Signer:                pop,
+		StakeOuts:             []*avax.TransferableOutput{banffOut(1000, owner)},
+		ValidatorRewardsOwner: owner,
+		DelegatorRewardsOwner: owner,
+	})
+	blk, err := blocks.NewBanffProposalBlock(banffTime, ids.Empty, 1, validatorTx)
+	require.NoError(t, err)
+
+	container := banffContainer(t, blk, ids.Empty)
+
+	publicKey, proofOfPossession, err := blockTxBLSKey(chain.NewParser(), container.Bytes, validatorTx.ID().String())
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Encode(bls.PublicKeyToBytes(bls.PublicFromSecretKey(sk))), publicKey)
+	require.Equal(t, hexutil.Encode(pop.ProofOfPossession[:]), proofOfPossession)
+	require.Len(t, publicKey, 2+2*bls.PublicKeyLen)
+	require.Len(t, proofOfPossession, 2+2*bls.SignatureLen)
+
+	_, _, err = blockTxBLSKey(chain.NewParser(), container.Bytes, ids.ID{9}.String())
+	require.Error(t, err)
+
+	publicKey, proofOfPossession = blsKey(&signer.Empty{})
+	require.Empty(t, publicKey)
+	require.Empty(t, proofOfPossession)
+}
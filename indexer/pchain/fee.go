@@ -0,0 +1,45 @@
+package pchain
+
+import (
+	"flare-indexer/indexer/shared"
+
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+type (
+	inGroups  = [][]*avax.TransferableInput
+	outGroups = [][]*avax.TransferableOutput
+)
+
+// Fee (burned amount) of a P-chain transaction. Imported inputs count as consumed,
+// exported and staked outputs as produced. Reward and advance time transactions
+// do not burn anything.
+func txFee(unsignedTx txs.UnsignedTx) uint64 {
+	switch tx := unsignedTx.(type) {
+	case *txs.ImportTx:
+		return shared.TxFee(inGroups{tx.Ins, tx.ImportedInputs}, outGroups{tx.Outs})
+	case *txs.ExportTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs, tx.ExportedOutputs})
+	case *txs.AddValidatorTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs, tx.Stake()})
+	case *txs.AddDelegatorTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs, tx.Stake()})
+	case *txs.AddPermissionlessValidatorTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs, tx.Stake()})
+	case *txs.AddPermissionlessDelegatorTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs, tx.Stake()})
+	case *txs.AddSubnetValidatorTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs})
+	case *txs.CreateChainTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs})
+	case *txs.CreateSubnetTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs})
+	case *txs.RemoveSubnetValidatorTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs})
+	case *txs.TransformSubnetTx:
+		return shared.TxFee(inGroups{tx.Ins}, outGroups{tx.Outs})
+	default:
+		return 0
+	}
+}
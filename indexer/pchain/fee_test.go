@@ -0,0 +1,45 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxFee(t *testing.T) {
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{{2}}}
+	baseTx := txs.BaseTx{BaseTx: avax.BaseTx{
+		Ins:  []*avax.TransferableInput{feeIn(700), feeIn(400)},
+		Outs: []*avax.TransferableOutput{banffOut(100, owner)},
+	}}
+
+	tests := []struct {
+		name string
+		tx   txs.UnsignedTx
+		fee  uint64
+	}{
+		{"base", &txs.CreateSubnetTx{BaseTx: baseTx, Owner: owner}, 1000},
+		{"import", &txs.ImportTx{BaseTx: baseTx, ImportedInputs: []*avax.TransferableInput{feeIn(50)}}, 1050},
+		{"export", &txs.ExportTx{BaseTx: baseTx, ExportedOutputs: []*avax.TransferableOutput{banffOut(900, owner)}}, 100},
+		{"stake", &txs.AddPermissionlessDelegatorTx{BaseTx: baseTx, StakeOuts: []*avax.TransferableOutput{banffOut(999, owner)}}, 1},
+		{"more outputs than inputs", &txs.ExportTx{BaseTx: baseTx, ExportedOutputs: []*avax.TransferableOutput{banffOut(2000, owner)}}, 0},
+		{"reward", &txs.RewardValidatorTx{TxID: ids.ID{1}}, 0},
+		{"advance time", &txs.AdvanceTimeTx{Time: 1}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.fee, txFee(test.tx))
+		})
+	}
+}
+
+func feeIn(amount uint64) *avax.TransferableInput {
+	return &avax.TransferableInput{In: &secp256k1fx.TransferInput{Amt: amount}}
+}
@@ -1,100 +1,102 @@
 package pchain
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils/chain"
 
 	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
-	mapset "github.com/deckarep/golang-set/v2"
 	"gorm.io/gorm"
 )
 
-type pChainInputUpdater struct {
-	shared.BaseInputUpdater
-
-	db     *gorm.DB
+// Outputs of P-chain transactions, from the database and from the node
+type pChainOutputFetcher struct {
+	db     pChainOutputsDB
 	client chain.RPCClient
+	parser *chain.Parser
+	hrp    string
+}
+
+type pChainOutputsDB interface {
+	FetchPChainTxOutputs(ctx context.Context, txIDs []string) ([]database.PChainTxOutput, error)
+}
+
+func newPChainInputUpdater(ctx indexerctx.IndexerContext, client chain.RPCClient) *shared.ChainInputUpdater {
+	return newPChainInputUpdaterWithDB(pChainOutputsDBGorm{db: ctx.DB()}, client, ctx.Parser(), ctx.Config().Chain.ChainAddressHRP)
 }
 
-func newPChainInputUpdater(ctx context.IndexerContext, client chain.RPCClient) *pChainInputUpdater {
-	ioUpdater := pChainInputUpdater{
-		db:     ctx.DB(),
+func newPChainInputUpdaterWithDB(db pChainOutputsDB, client chain.RPCClient, parser *chain.Parser, hrp string) *shared.ChainInputUpdater {
+	return shared.NewChainInputUpdater(&pChainOutputFetcher{
+		db:     db,
 		client: client,
-	}
-	ioUpdater.InitCache()
-	return &ioUpdater
+		parser: parser,
+		hrp:    hrp,
+	})
 }
 
-func (iu *pChainInputUpdater) UpdateInputs(inputs shared.InputList) (mapset.Set[string], error) {
-	missingTxIds := iu.UpdateInputsFromCache(inputs)
-	missingTxIds, err := iu.updateFromDB(inputs, missingTxIds)
-	if err != nil {
-		return nil, err
-	}
-	return iu.updateFromChain(inputs, missingTxIds)
+type pChainOutputsDBGorm struct {
+	db *gorm.DB
 }
 
-// notUpdated is a map from *output* id to inputs referring this output
-func (iu *pChainInputUpdater) updateFromDB(
-	inputs shared.InputList,
-	missingTxIds mapset.Set[string],
-) (mapset.Set[string], error) {
-	outs, err := database.FetchPChainTxOutputs(iu.db, missingTxIds.ToSlice())
+func (m pChainOutputsDBGorm) FetchPChainTxOutputs(ctx context.Context, txIDs []string) ([]database.PChainTxOutput, error) {
+	return database.FetchPChainTxOutputs(ctx, m.db, txIDs)
+}
+
+func (f *pChainOutputFetcher) FetchStoredOutputs(ctx context.Context, txIDs []string) ([]shared.Output, error) {
+	outs, err := f.db.FetchPChainTxOutputs(ctx, txIDs)
 	if err != nil {
 		return nil, err
 	}
-	baseOuts := shared.NewOutputMap()
-	for _, out := range outs {
-		baseOuts.Add(shared.NewIdIndexKey(out.TxID, out.Index()), &out.TxOutput)
+	storedOuts := make([]shared.Output, len(outs))
+	for i := range outs {
+		// Outputs are kept by pointers, each must refer to its own output
+		storedOuts[i] = &outs[i].TxOutput
 	}
-	return inputs.UpdateWithOutputs(baseOuts), nil
+	return storedOuts, nil
 }
 
-// notUpdated is a map from *output* id to inputs referring this output
-func (iu *pChainInputUpdater) updateFromChain(
-	inputs shared.InputList,
-	missingTxIds mapset.Set[string],
-) (mapset.Set[string], error) {
-	fetchedOuts := shared.NewOutputMap()
-	for txId := range missingTxIds.Iterator().C {
-		tx, err := CallPChainGetTxApi(iu.client, txId)
-		if err != nil {
-			return nil, err
-		}
-		if tx == nil {
-			// Genesis tx
-			fetchedOuts.Add(shared.NewIdIndexKey(txId, 0), nil)
-			continue
-		}
+func (f *pChainOutputFetcher) AddChainOutputs(ctx context.Context, txId string, fetchedOuts shared.OutputMap) error {
+	tx, err := CallPChainGetTxApi(ctx, f.client, f.parser, txId)
+	if err != nil {
+		return err
+	}
+	if tx == nil {
+		// Genesis tx
+		fetchedOuts.Add(shared.NewIdIndexKey(txId, 0), nil)
+		return nil
+	}
 
-		var outs []shared.Output
-		switch unsignedTx := tx.Unsigned.(type) {
-		case *txs.AddValidatorTx:
-			outs, err = iu.getAddStakerTxAndRewardTxOutputs(txId, unsignedTx)
-		case *txs.AddDelegatorTx:
-			outs, err = iu.getAddStakerTxAndRewardTxOutputs(txId, unsignedTx)
-		default:
-			txOuts := tx.Unsigned.Outputs()
-			outs, err = shared.OutputsFromTxOuts(txId, txOuts, 0, PChainDefaultInputOutputCreator)
-		}
-		if err != nil {
-			return nil, err
-		}
-		for _, out := range outs {
-			fetchedOuts.Add(shared.NewIdIndexKey(out.Tx(), out.Index()), out)
-		}
+	var outs []shared.Output
+	switch unsignedTx := tx.Unsigned.(type) {
+	case *txs.AddValidatorTx:
+		outs, err = f.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
+	case *txs.AddDelegatorTx:
+		outs, err = f.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
+	case *txs.AddPermissionlessValidatorTx:
+		outs, err = f.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
+	case *txs.AddPermissionlessDelegatorTx:
+		outs, err = f.getAddStakerTxAndRewardTxOutputs(ctx, txId, unsignedTx)
+	default:
+		txOuts := tx.Unsigned.Outputs()
+		outs, err = shared.OutputsFromTxOuts(f.hrp, txId, txOuts, 0, PChainDefaultInputOutputCreator)
+	}
+	if err != nil {
+		return err
+	}
+	for _, out := range outs {
+		fetchedOuts.Add(shared.NewIdIndexKeyFromOutput(out), out)
 	}
-	return inputs.UpdateWithOutputs(fetchedOuts), nil
+	return nil
 }
 
-func (iu *pChainInputUpdater) getAddStakerTxAndRewardTxOutputs(txId string, tx txs.PermissionlessStaker) ([]shared.Output, error) {
-	outs, err := getAddStakerTxOutputs(txId, tx)
+func (f *pChainOutputFetcher) getAddStakerTxAndRewardTxOutputs(ctx context.Context, txId string, tx txs.PermissionlessStaker) ([]shared.Output, error) {
+	outs, err := getAddStakerTxOutputs(f.hrp, txId, tx)
 	if err != nil {
 		return nil, err
 	}
-	rewardOuts, err := getRewardOutputs(iu.client, txId)
+	rewardOuts, err := getRewardOutputs(ctx, f.client, f.hrp, txId)
 	if err != nil {
 		return nil, err
 	}
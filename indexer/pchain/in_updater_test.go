@@ -0,0 +1,97 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"context"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/chain/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testOutTxID = "1AJu1m7G9vw9mqT81KGRYj1nBKX9e8oEgEtHp9XGNRNtpQx57"
+
+type testOutputsDB struct {
+	outputs []database.PChainTxOutput
+}
+
+func (db testOutputsDB) FetchPChainTxOutputs(ctx context.Context, txIDs []string) ([]database.PChainTxOutput, error) {
+	return db.outputs, nil
+}
+
+func TestUpdateInputsFromChain(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	recorded, err := chain.PChainTestRPCClient()
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockRPCClient(ctrl)
+	client.EXPECT().GetTx(gomock.Any(), gomock.Any()).DoAndReturn(recorded.GetTx).Times(1)
+	client.EXPECT().GetRewardUTXOs(gomock.Any(), gomock.Any()).DoAndReturn(recorded.GetRewardUTXOs).AnyTimes()
+
+	updater := newPChainInputUpdaterWithDB(testOutputsDB{}, client, chain.NewParser(), "localflare")
+
+	in := &database.PChainTxInput{
+		TxInput: database.TxInput{OutTxID: testOutTxID, OutIdx: 0},
+	}
+	missing, err := updater.UpdateInputs(context.Background(), shared.NewInputList([]shared.Input{in}))
+	require.NoError(t, err)
+	require.Equal(t, 0, missing.Cardinality())
+	require.NotEmpty(t, in.Address)
+}
+
+func TestUpdateInputsFromDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockRPCClient(ctrl) // no calls expected
+
+	db := testOutputsDB{
+		outputs: []database.PChainTxOutput{
+			{TxOutput: database.TxOutput{TxID: testOutTxID, Idx: 0, Address: "localflare1address"}},
+		},
+	}
+	updater := newPChainInputUpdaterWithDB(db, client, chain.NewParser(), "localflare")
+
+	in := &database.PChainTxInput{
+		TxInput: database.TxInput{OutTxID: testOutTxID, OutIdx: 0},
+	}
+	missing, err := updater.UpdateInputs(context.Background(), shared.NewInputList([]shared.Input{in}))
+	require.NoError(t, err)
+	require.Equal(t, 0, missing.Cardinality())
+	require.Equal(t, "localflare1address", in.Address)
+}
+
+// Regression test: the stored outputs were mapped by pointers to the loop
+// variable, so all inputs got the address of the last output
+func TestUpdateInputsFromDBOwnOutputs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockRPCClient(ctrl) // no calls expected
+
+	db := testOutputsDB{
+		outputs: []database.PChainTxOutput{
+			{TxOutput: database.TxOutput{TxID: testOutTxID, Idx: 0, Address: "localflare1a", Amount: 100}},
+			{TxOutput: database.TxOutput{TxID: testOutTxID, Idx: 1, Address: "localflare1b", Amount: 200}},
+		},
+	}
+	updater := newPChainInputUpdaterWithDB(db, client, chain.NewParser(), "localflare")
+
+	in0 := &database.PChainTxInput{TxInput: database.TxInput{OutTxID: testOutTxID, OutIdx: 0}}
+	in1 := &database.PChainTxInput{TxInput: database.TxInput{OutTxID: testOutTxID, OutIdx: 1}}
+	missing, err := updater.UpdateInputs(context.Background(), shared.NewInputList([]shared.Input{in0, in1}))
+	require.NoError(t, err)
+	require.Equal(t, 0, missing.Cardinality())
+	require.Equal(t, "localflare1a", in0.Address)
+	require.Equal(t, uint64(100), in0.Amount)
+	require.Equal(t, "localflare1b", in1.Address)
+	require.Equal(t, uint64(200), in1.Amount)
+}
@@ -2,23 +2,33 @@ package pchain
 
 import (
 	"flare-indexer/config"
+	"flare-indexer/database"
 	"flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
+	"flare-indexer/utils/contracts/voting"
+	"fmt"
 )
 
 const (
-	StateName string = "p_chain_block"
+	StateName string = database.PChainIndexerStateName
+
+	// Sources of the P-chain blocks, see IndexerConfig.Source
+	SourceIndexer = "indexer"
+	SourceRPC     = "rpc"
 )
 
 type pChainBlockIndexer struct {
 	shared.ChainIndexerBase
 }
 
-func CreatePChainBlockIndexer(ctx context.IndexerContext) *pChainBlockIndexer {
+func CreatePChainBlockIndexer(ctx context.IndexerContext) (*pChainBlockIndexer, error) {
 	config := ctx.Config().PChainIndexer
-	client := newIndexerClient(&ctx.Config().Chain)
+	client, err := newIndexerClient(&ctx.Config().Chain, ctx.Parser(), config.Source)
+	if err != nil {
+		return nil, err
+	}
 	rpcClient := newJsonRpcClient(&ctx.Config().Chain)
 
 	idxr := pChainBlockIndexer{}
@@ -27,22 +37,51 @@ func CreatePChainBlockIndexer(ctx context.IndexerContext) *pChainBlockIndexer {
 	idxr.Client = client
 	idxr.DB = ctx.DB()
 	idxr.Config = config
+	idxr.FeatureFlags = ctx.FeatureFlags()
+	idxr.FeatureFlagName = "p_chain_indexer"
+	idxr.Network = ctx.Config().Network
+	idxr.Events = ctx.Events()
 	idxr.InitMetrics(StateName)
 
-	idxr.BatchIndexer = NewPChainBatchIndexer(ctx, client, rpcClient, nil)
+	batchIndexer := NewPChainBatchIndexer(ctx, client, rpcClient, nil)
+	txTypes := utils.Map(database.PChainTxTypes, func(t database.PChainTxType) string { return string(t) })
+	filter, err := shared.NewTxTypeFilter(&config, txTypes)
+	if err != nil {
+		return nil, fmt.Errorf("p_chain_indexer: %w", err)
+	}
+	batchIndexer.filter = filter
+	votingContract, err := voting.NewVoting(ctx.Config().ContractAddresses.Voting, ctx.EthClient())
+	if err != nil {
+		return nil, err
+	}
+	batchIndexer.weights, err = newValidatorWeightRecorder(&ctx.Config().ValidatorWeights, votingContract)
+	if err != nil {
+		return nil, err
+	}
+	idxr.BatchIndexer = batchIndexer
 
-	return &idxr
+	return &idxr, nil
 }
 
 func (xi *pChainBlockIndexer) Run() {
 	xi.ChainIndexerBase.Run()
 }
 
-func newIndexerClient(cfg *config.ChainConfig) chain.IndexerClient {
-	return chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/P/block"),
-		chain.ClientOptions(cfg.ApiKey)...)
+func newIndexerClient(cfg *config.ChainConfig, parser *chain.Parser, source string) (chain.IndexerClient, error) {
+	switch source {
+	case "", SourceIndexer:
+		client := chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/P/block"),
+			chain.ClientOptions(cfg.ApiKey)...)
+		return chain.NewTimeoutIndexerClient(client, cfg.GetIndexerTimeout()), nil
+	case SourceRPC:
+		client := chain.NewPChainPlatformClient(utils.JoinPaths(cfg.NodeURL, "ext/bc/P"+chain.RPCClientOptions(cfg.ApiKey)), parser)
+		return chain.NewTimeoutIndexerClient(client, cfg.GetPlatformTimeout()), nil
+	default:
+		return nil, fmt.Errorf("p_chain_indexer: unknown source %q, use %q or %q", source, SourceIndexer, SourceRPC)
+	}
 }
 
 func newJsonRpcClient(cfg *config.ChainConfig) chain.RPCClient {
-	return chain.NewAvalancheRPCClient(utils.JoinPaths(cfg.NodeURL, "ext/bc/P"+chain.RPCClientOptions(cfg.ApiKey)))
+	return chain.NewAvalancheRPCClient(utils.JoinPaths(cfg.NodeURL, "ext/bc/P"+chain.RPCClientOptions(cfg.ApiKey)),
+		cfg.GetPlatformTimeout())
 }
@@ -4,17 +4,20 @@
 package pchain
 
 import (
+	"context"
 	"encoding/hex"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"fmt"
 	"testing"
 
 	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func createPChainTestBlockIndexer(t *testing.T, batchSize int, startIndex uint64) *pChainBlockIndexer {
-	ctx, err := context.BuildTestContext(pchainIndexerTestConfig(batchSize, startIndex))
+	ctx, err := indexerctx.BuildTestContext(pchainIndexerTestConfig(batchSize, startIndex))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,7 +46,7 @@ func TestPChainBlockIndexerAll(t *testing.T) {
 	idxr := createPChainTestBlockIndexer(t, 10, 0)
 
 	// run one batch
-	err := idxr.IndexBatch()
+	err := idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -58,7 +61,7 @@ func TestPChainBlockIndexerAll(t *testing.T) {
 	}
 
 	// run another batch
-	err = idxr.IndexBatch()
+	err = idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -80,7 +83,7 @@ func TestPChainBlockIndexerPartial(t *testing.T) {
 	idxr := createPChainTestBlockIndexer(t, 10, 20)
 
 	// run one batch
-	err := idxr.IndexBatch()
+	err := idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -95,7 +98,7 @@ func TestPChainBlockIndexerPartial(t *testing.T) {
 	}
 
 	// run another batch
-	err = idxr.IndexBatch()
+	err = idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -115,8 +118,44 @@ func TestIndexAllBlocks(t *testing.T) {
 	idxr := createPChainTestBlockIndexer(t, 200, 0)
 
 	// run batch
-	err := idxr.IndexBatch()
+	err := idxr.IndexBatch(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+// Indexing a range again, e.g. after a crash or during a reindex, keeps the
+// rows of the first run. Decisions of proposal blocks are applied again, so the
+// change feed is not compared.
+func TestPChainReindexSameRange(t *testing.T) {
+	ctx := context.Background()
+	idxr := createPChainTestBlockIndexer(t, 20, 0)
+
+	require.NoError(t, idxr.IndexBatch(ctx))
+	counts := pChainRowCounts(t, idxr.DB)
+	require.NotZero(t, counts["PChainTx"])
+
+	state, err := database.FetchState(ctx, idxr.DB, StateName)
+	require.NoError(t, err)
+	state.NextDBIndex = 0
+	require.NoError(t, database.UpdateState(ctx, idxr.DB, &state))
+
+	require.NoError(t, idxr.IndexBatch(ctx))
+	require.Equal(t, counts, pChainRowCounts(t, idxr.DB))
+}
+
+func pChainRowCounts(t *testing.T, db *gorm.DB) map[string]int64 {
+	counts := make(map[string]int64)
+	for name, model := range map[string]interface{}{
+		"PChainBlock":    &database.PChainBlock{},
+		"PChainTx":       &database.PChainTx{},
+		"PChainTxInput":  &database.PChainTxInput{},
+		"PChainTxOutput": &database.PChainTxOutput{},
+		"PChainTxSigner": &database.PChainTxSigner{},
+	} {
+		var count int64
+		require.NoError(t, db.Model(model).Count(&count).Error)
+		counts[name] = count
+	}
+	return counts
+}
@@ -0,0 +1,36 @@
+package pchain
+
+import (
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+// Memo of the base transaction of a P-chain transaction, nil for reward and
+// advance time transactions, which have no base transaction
+func txMemo(unsignedTx txs.UnsignedTx) []byte {
+	switch tx := unsignedTx.(type) {
+	case *txs.ImportTx:
+		return tx.Memo
+	case *txs.ExportTx:
+		return tx.Memo
+	case *txs.AddValidatorTx:
+		return tx.Memo
+	case *txs.AddDelegatorTx:
+		return tx.Memo
+	case *txs.AddPermissionlessValidatorTx:
+		return tx.Memo
+	case *txs.AddPermissionlessDelegatorTx:
+		return tx.Memo
+	case *txs.AddSubnetValidatorTx:
+		return tx.Memo
+	case *txs.CreateChainTx:
+		return tx.Memo
+	case *txs.CreateSubnetTx:
+		return tx.Memo
+	case *txs.RemoveSubnetValidatorTx:
+		return tx.Memo
+	case *txs.TransformSubnetTx:
+		return tx.Memo
+	default:
+		return nil
+	}
+}
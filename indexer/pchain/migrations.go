@@ -1,22 +1,349 @@
 package pchain
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/migrations"
+	"flare-indexer/utils/chain"
+	"fmt"
 	"time"
 
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
 	"gorm.io/gorm"
 )
 
+// Number of transactions processed at once when computing fees or blocks of already
+// indexed transactions
+const txMigrationBatchSize = 1000
+
 func init() {
 	migrations.Container.Add("2023-02-10-00-00", "Create initial state for P-Chain transactions", createPChainTxState)
+	migrations.Container.Add("2023-10-30-00-00", "Compute fees of indexed P-Chain transactions", computePChainTxFees)
+	migrations.Container.Add("2023-11-02-00-00", "Create blocks of indexed P-Chain transactions", createPChainBlocks)
+	migrations.Container.Add("2023-11-10-00-00", "Store BLS keys of indexed P-Chain validator transactions", storePChainBLSKeys)
+	migrations.Container.Add("2023-11-12-00-00", "Store container indexes of indexed P-Chain blocks", storePChainContainerIndexes)
+	migrations.Container.Add("2023-11-14-00-00", "Store positions of indexed P-Chain transactions in their blocks", storePChainBlockTxIndexes)
+	migrations.Container.Add("2023-11-16-00-00", "Store memos of indexed P-Chain transactions", storePChainMemos)
+	migrations.Container.Add("2023-11-18-00-00", "Store statuses of indexed P-Chain proposal transactions", storePChainProposalTxStatuses)
+	migrations.Container.Add("2023-11-20-00-00", "Create initial state for validator weights", createValidatorWeightsState)
+	migrations.Container.Add("2023-11-22-00-00", "Store signers of indexed P-Chain staking transactions", storePChainTxSigners)
+	migrations.Container.Add("2023-11-26-00-00", "Create indexes of P-Chain staker and fee queries", createPChainTxIndexes)
+	migrations.Container.Add("2023-11-30-00-00", "Normalize node IDs of indexed P-Chain staking transactions", normalizePChainNodeIDs)
+	migrations.Container.Add("2023-12-04-00-00", "Delete duplicate P-Chain inputs, outputs and watchlist matches and create unique indexes", createPChainUniqueIndexes)
+	migrations.Container.Add("2023-12-06-01-00", "Create index of P-Chain active stakers queries", createPChainTxIndexes)
 }
 
-func createPChainTxState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+func createPChainTxState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
 		Name:           StateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
 		Updated:        time.Now(),
 	})
 }
+
+func createValidatorWeightsState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
+		Name:    database.ValidatorWeightsStateName,
+		Updated: time.Now(),
+	})
+}
+
+func createPChainTxIndexes(ctx context.Context, db *gorm.DB) error {
+	return database.CreateIndexes(ctx, db, "PChainTx", database.PChainTxIndexes)
+}
+
+// Inputs, outputs and watchlist matches persisted more than once before
+// persisting was idempotent are deleted, keeping the first row of each
+func createPChainUniqueIndexes(ctx context.Context, db *gorm.DB) error {
+	if err := database.CreateIndexes(ctx, db, "PChainTxInput", database.PChainTxInputIndexes); err != nil {
+		return err
+	}
+	if err := database.CreateIndexes(ctx, db, "PChainTxOutput", database.PChainTxOutputIndexes); err != nil {
+		return err
+	}
+	return database.CreateIndexes(ctx, db, "WatchlistMatch", database.WatchlistMatchIndexes)
+}
+
+// Transactions indexed before fees were tracked have zero fee, the fee is computed
+// from the stored block bytes
+func computePChainTxFees(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			if dbTx.TxID == nil {
+				continue
+			}
+			fee, err := blockTxFee(parser, dbTx.Bytes, *dbTx.TxID)
+			if err != nil {
+				return err
+			}
+			if fee == 0 {
+				continue
+			}
+			if err := database.UpdatePChainTxFee(ctx, db, dbTx.ID, fee); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Fee of the transaction with the given id contained in the block
+func blockTxFee(parser *chain.Parser, blockBytes []byte, txID string) (uint64, error) {
+	blk, err := parser.ParsePChainBlock(blockBytes)
+	if err != nil {
+		return 0, err
+	}
+	for _, tx := range pChainBlockTxs(blk) {
+		if tx.ID().String() == txID {
+			return txFee(tx.Unsigned), nil
+		}
+	}
+	return 0, fmt.Errorf("transaction %s not found in its block", txID)
+}
+
+// Blocks of transactions indexed before blocks were stored are created from the
+// stored block bytes. Transactions of a block are stored consecutively.
+func createPChainBlocks(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	var current *database.PChainBlock
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			break
+		}
+		var completed []*database.PChainBlock
+		for _, dbTx := range dbTxs {
+			if current == nil || current.Height != dbTx.BlockHeight {
+				if current != nil {
+					completed = append(completed, current)
+				}
+				current, err = newPChainBlock(parser, dbTx.Bytes, dbTx.BlockID, nil, dbTx.BlockHeight, dbTx.Timestamp, 0)
+				if err != nil {
+					return err
+				}
+			}
+			if dbTx.TxID != nil {
+				current.TxCount++
+			}
+		}
+		if err := database.CreatePChainBlocks(ctx, db, completed); err != nil {
+			return err
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+	if current == nil {
+		return nil
+	}
+	return database.CreatePChainBlocks(ctx, db, []*database.PChainBlock{current})
+}
+
+// Blocks indexed before container indexes were stored get the index derived from
+// their height (see chain.PChainContainerIndex), which was assumed until then
+func storePChainContainerIndexes(ctx context.Context, db *gorm.DB) error {
+	return database.UpdatePChainBlockContainerIndexes(ctx, db)
+}
+
+// Transactions indexed before their positions in the blocks were stored get them
+// from the stored block bytes. Transactions at position 0 need no update.
+func storePChainBlockTxIndexes(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			if dbTx.TxID == nil {
+				continue
+			}
+			txIndex, err := blockTxIndex(parser, dbTx.Bytes, *dbTx.TxID)
+			if err != nil {
+				return err
+			}
+			if txIndex == 0 {
+				continue
+			}
+			if err := database.UpdatePChainTxBlockTxIndex(ctx, db, dbTx.ID, txIndex); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Position of the transaction with the given id in the block, in the order of execution
+func blockTxIndex(parser *chain.Parser, blockBytes []byte, txID string) (uint32, error) {
+	blk, err := parser.ParsePChainBlock(blockBytes)
+	if err != nil {
+		return 0, err
+	}
+	for i, tx := range pChainBlockTxs(blk) {
+		if tx.ID().String() == txID {
+			return uint32(i), nil
+		}
+	}
+	return 0, fmt.Errorf("transaction %s not found in its block", txID)
+}
+
+// Memos of transactions indexed before memos were stored are read from the stored
+// block bytes
+func storePChainMemos(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			if dbTx.TxID == nil {
+				continue
+			}
+			memo, err := blockTxMemo(parser, dbTx.Bytes, *dbTx.TxID)
+			if err != nil {
+				return err
+			}
+			if memo == "" {
+				continue
+			}
+			if err := database.UpdatePChainTxMemo(ctx, db, dbTx.ID, memo); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Encoded memo of the transaction with the given id contained in the block
+func blockTxMemo(parser *chain.Parser, blockBytes []byte, txID string) (string, error) {
+	blk, err := parser.ParsePChainBlock(blockBytes)
+	if err != nil {
+		return "", err
+	}
+	for _, tx := range pChainBlockTxs(blk) {
+		if tx.ID().String() == txID {
+			return chain.EncodeMemo(txMemo(tx.Unsigned)), nil
+		}
+	}
+	return "", fmt.Errorf("transaction %s not found in its block", txID)
+}
+
+// Transactions indexed before statuses were stored are accepted. Proposal
+// transactions get the status decided by the option block following them, which
+// is stored after the proposal block, or stay proposed if it is not indexed yet.
+func storePChainProposalTxStatuses(ctx context.Context, db *gorm.DB) error {
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			switch dbTx.BlockType {
+			case database.PChainProposalBlock:
+				err = storeProposalTxStatus(ctx, db, &dbTx)
+			case database.PChainCommitBlock:
+				err = database.UpdatePChainProposalTxStatus(ctx, db, dbTx.BlockHeight-1, database.PChainTxAccepted)
+			case database.PChainAbortBlock:
+				err = database.UpdatePChainProposalTxStatus(ctx, db, dbTx.BlockHeight-1, database.PChainTxAborted)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+func storeProposalTxStatus(ctx context.Context, db *gorm.DB, dbTx *database.PChainTx) error {
+	if dbTx.TxID == nil {
+		return nil
+	}
+	blk, err := chain.ParserFromContext(ctx).ParsePChainBlock(dbTx.Bytes)
+	if err != nil {
+		return err
+	}
+	status := newTxStatus(dbTx.BlockType, int(dbTx.BlockTxIndex), len(pChainBlockTxs(blk)))
+	if status == database.PChainTxAccepted {
+		return nil
+	}
+	return database.UpdatePChainTxStatus(ctx, db, dbTx.ID, status)
+}
+
+// Node IDs of staking transactions are normalized, a malformed node ID is
+// replaced by the node ID of the transaction in the stored block bytes
+func normalizePChainNodeIDs(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			if dbTx.TxID == nil || !isStakingTx(dbTx.Type) {
+				continue
+			}
+			nodeID, err := chain.NormalizeNodeID(dbTx.NodeID)
+			if err != nil {
+				nodeID, err = blockTxNodeID(parser, dbTx.Bytes, *dbTx.TxID)
+			}
+			if err != nil {
+				return err
+			}
+			if nodeID == dbTx.NodeID {
+				continue
+			}
+			if err := database.UpdatePChainTxNodeID(ctx, db, dbTx.ID, nodeID); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Node ID of the staking transaction with the given id contained in the block
+func blockTxNodeID(parser *chain.Parser, blockBytes []byte, txID string) (string, error) {
+	blk, err := parser.ParsePChainBlock(blockBytes)
+	if err != nil {
+		return "", err
+	}
+	for _, tx := range pChainBlockTxs(blk) {
+		if tx.ID().String() != txID {
+			continue
+		}
+		staker, ok := tx.Unsigned.(txs.PermissionlessStaker)
+		if !ok {
+			return "", fmt.Errorf("transaction %s is not a staking transaction", txID)
+		}
+		return staker.NodeID().String(), nil
+	}
+	return "", fmt.Errorf("transaction %s not found in its block", txID)
+}
@@ -0,0 +1,366 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/validator"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/stretchr/testify/require"
+)
+
+// Number of recorded containers parsed by the golden test. Reward UTXOs of
+// later containers are not part of the recorded RPC data.
+const goldenContainerCount = 200
+
+// The recorded containers only contain Apricot blocks. The transactions added
+// with Banff (permissionless staking, subnets and chains) are covered by the
+// Banff blocks in resources/test/p_chain_indexer_banff_blocks.json, which are
+// synthesized with the avalanchego codec until containers with these
+// transactions are recorded. Regenerate them with
+//
+//	go test ./indexer/pchain -run TestGenerateFixtures -update-fixtures
+var updateFixtures = flag.Bool("update-fixtures", false, "regenerate the P-chain Banff block fixtures")
+
+// Rows produced by the parser for a single transaction
+type goldenTxRows struct {
+	Tx      database.PChainTx
+	Inputs  []database.PChainTxInput
+	Outputs []database.PChainTxOutput
+}
+
+// Parses the recorded P-chain containers and the Banff blocks and compares the
+// rows produced for the first transaction of each (block type, tx type)
+// combination with the golden snapshots in .snapshots. Any change of the parser
+// output, e.g. after an avalanchego upgrade, shows up as a snapshot diff.
+func TestParserGolden(t *testing.T) {
+	seen := make(map[string]bool)
+	snapshotGoldenTxs(t, indexGoldenContainers(t), seen)
+	snapshotGoldenTxs(t, indexBanffContainers(t), seen)
+}
+
+func snapshotGoldenTxs(t *testing.T, xi *txBatchIndexer, seen map[string]bool) {
+	ins, err := utils.CastArray[*database.PChainTxInput](xi.inOutIndexer.GetIns())
+	require.NoError(t, err)
+	outs, err := utils.CastArray[*database.PChainTxOutput](xi.inOutIndexer.GetNewOuts())
+	require.NoError(t, err)
+
+	for _, tx := range xi.newTxs {
+		key := string(tx.BlockType)
+		if tx.Type != "" {
+			key = fmt.Sprintf("%s_%s", tx.BlockType, tx.Type)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		rows := goldenTxRows{Tx: *tx}
+		rows.Tx.Bytes = nil
+		rows.Tx.Timestamp = tx.Timestamp.UTC()
+		rows.Tx.StartTime = utcTime(tx.StartTime)
+		rows.Tx.EndTime = utcTime(tx.EndTime)
+		rows.Tx.Time = utcTime(tx.Time)
+		if tx.TxID != nil {
+			for _, in := range ins {
+				if in.TxID == *tx.TxID {
+					rows.Inputs = append(rows.Inputs, *in)
+				}
+			}
+			for _, out := range outs {
+				if out.TxID == *tx.TxID {
+					rows.Outputs = append(rows.Outputs, *out)
+				}
+			}
+		}
+		t.Run(key, func(t *testing.T) {
+			cupaloy.SnapshotT(t, rows)
+		})
+	}
+}
+
+// Fee percentages of the recorded staking transactions, in the units of the
+// DelegationShares (1/10000 %), must pass the validation of the staking rules.
+// Delegations have no fee.
+func TestRecordedStakeFees(t *testing.T) {
+	xi := indexGoldenContainers(t)
+
+	validators, delegators := 0, 0
+	for _, tx := range xi.newTxs {
+		switch tx.Type {
+		case database.PChainAddValidatorTx, database.PChainAddPermissionlessValidatorTx:
+			validators++
+		case database.PChainAddDelegatorTx, database.PChainAddPermissionlessDelegatorTx:
+			delegators++
+			require.Zero(t, tx.FeePercentage, "delegator transaction %s", *tx.TxID)
+		default:
+			continue
+		}
+		_, err := staking.FeePercentage(tx)
+		require.NoError(t, err)
+		if tx.NodeID == "NodeID-MFrZFVCXPv5iCn6M9K6XduxGTYp891xXZ" && tx.Type == database.PChainAddValidatorTx {
+			require.EqualValues(t, 100000, tx.FeePercentage) // 10%
+		}
+	}
+	require.NotZero(t, validators)
+	require.NotZero(t, delegators)
+}
+
+func indexGoldenContainers(t *testing.T) *txBatchIndexer {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	client, err := chain.PChainTestClient()
+	require.NoError(t, err)
+	rpcClient, err := chain.PChainTestRPCClient()
+	require.NoError(t, err)
+
+	updater := newPChainInputUpdaterWithDB(testOutputsDB{}, rpcClient, chain.NewParser(), "localflare")
+	xi := newTxBatchIndexer(nil, client, rpcClient, chain.NewParser(), updater, nil, "localflare")
+
+	containers, err := client.GetContainerRange(context.Background(), 0, goldenContainerCount)
+	require.NoError(t, err)
+
+	xi.Reset(len(containers))
+	for i, container := range containers {
+		require.NoError(t, xi.AddContainer(context.Background(), uint64(i), container))
+	}
+	require.NoError(t, xi.ProcessBatch(context.Background()))
+	return xi
+}
+
+// Indexes the Banff blocks in one batch, the inputs spend outputs of earlier
+// blocks of the batch
+func indexBanffContainers(t *testing.T) *txBatchIndexer {
+	client, err := chain.PChainBanffTestClient()
+	require.NoError(t, err)
+
+	updater := newPChainInputUpdaterWithDB(testOutputsDB{}, nil, chain.NewParser(), "localflare")
+	xi := newTxBatchIndexer(nil, client, nil, chain.NewParser(), updater, nil, "localflare")
+
+	_, last, err := client.GetLastAccepted(context.Background())
+	require.NoError(t, err)
+
+	xi.Reset(int(last) + 1)
+	for i := uint64(0); i <= last; i++ {
+		container, err := client.GetContainerByIndex(context.Background(), i)
+		require.NoError(t, err)
+		require.NoError(t, xi.AddContainer(context.Background(), i, container))
+	}
+	require.NoError(t, xi.ProcessBatch(context.Background()))
+	return xi
+}
+
+// Snapshots must not depend on the local time zone
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+var (
+	fixtureAssetID   = ids.ID{'F', 'L', 'R'}
+	fixtureSubnetID  = ids.ID{'S'}
+	fixtureNodeID    = ids.NodeID{'N', 1}
+	fixtureTimestamp = time.Date(2023, 10, 2, 12, 0, 0, 0, time.UTC)
+)
+
+// Generates one Banff block for each transaction type which is not part of the
+// recorded containers. The staking transactions spend the outputs of the
+// create subnet transaction and are signed, so their signers are recovered.
+func TestGenerateFixtures(t *testing.T) {
+	if !*updateFixtures {
+		t.Skip("run with -update-fixtures to regenerate the fixtures")
+	}
+
+	keyBytes := make([]byte, crypto.SECP256K1RSKLen)
+	keyBytes[31] = 1
+	key, err := (&crypto.FactorySECP256K1R{}).ToPrivateKey(keyBytes)
+	require.NoError(t, err)
+	signerKey := key.(*crypto.PrivateKeySECP256K1R)
+	address := signerKey.Address()
+
+	blsKeyBytes := make([]byte, bls.SecretKeyLen)
+	blsKeyBytes[31] = 1
+	blsKey, err := bls.SecretKeyFromBytes(blsKeyBytes)
+	require.NoError(t, err)
+
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{address}}
+	subnetAuth := &secp256k1fx.Input{SigIndices: []uint32{0}}
+	start := uint64(fixtureTimestamp.Add(time.Hour).Unix())
+	end := uint64(fixtureTimestamp.Add(30 * 24 * time.Hour).Unix())
+
+	createSubnetTx := fixtureTx(t, &txs.CreateSubnetTx{
+		BaseTx: fixtureBaseTx(nil, []*avax.TransferableOutput{
+			fixtureOut(2000, address),
+			fixtureOut(1000, address),
+		}, "create subnet"),
+		Owner: owner,
+	}, nil)
+	createChainTx := fixtureTx(t, &txs.CreateChainTx{
+		BaseTx:      fixtureBaseTx(nil, nil, ""),
+		SubnetID:    fixtureSubnetID,
+		ChainName:   "fixture",
+		VMID:        ids.ID{'V', 'M'},
+		GenesisData: []byte("{}"),
+		SubnetAuth:  subnetAuth,
+	}, nil)
+	addSubnetValidatorTx := fixtureTx(t, &txs.AddSubnetValidatorTx{
+		BaseTx: fixtureBaseTx(nil, nil, ""),
+		Validator: validator.SubnetValidator{
+			Validator: validator.Validator{NodeID: fixtureNodeID, Start: start, End: end, Wght: 1},
+			Subnet:    fixtureSubnetID,
+		},
+		SubnetAuth: subnetAuth,
+	}, nil)
+	removeSubnetValidatorTx := fixtureTx(t, &txs.RemoveSubnetValidatorTx{
+		BaseTx:     fixtureBaseTx(nil, nil, ""),
+		NodeID:     fixtureNodeID,
+		Subnet:     fixtureSubnetID,
+		SubnetAuth: subnetAuth,
+	}, nil)
+	transformSubnetTx := fixtureTx(t, &txs.TransformSubnetTx{
+		BaseTx:                   fixtureBaseTx(nil, nil, ""),
+		Subnet:                   fixtureSubnetID,
+		AssetID:                  ids.ID{'S', 'A'},
+		InitialSupply:            1000,
+		MaximumSupply:            2000,
+		MinConsumptionRate:       1,
+		MaxConsumptionRate:       2,
+		MinValidatorStake:        1,
+		MaxValidatorStake:        1000,
+		MinStakeDuration:         1,
+		MaxStakeDuration:         2,
+		MinDelegationFee:         1,
+		MinDelegatorStake:        1,
+		MaxValidatorWeightFactor: 1,
+		UptimeRequirement:        1,
+		SubnetAuth:               subnetAuth,
+	}, nil)
+	addValidatorTx := fixtureTx(t, &txs.AddPermissionlessValidatorTx{
+		BaseTx: fixtureBaseTx([]*avax.TransferableInput{
+			fixtureIn(createSubnetTx.ID(), 0, 2000),
+		}, nil, "validator"),
+		Validator:             validator.Validator{NodeID: fixtureNodeID, Start: start, End: end, Wght: 1999},
+		Subnet:                ids.Empty,
+		Signer:                signer.NewProofOfPossession(blsKey),
+		StakeOuts:             []*avax.TransferableOutput{fixtureOut(1999, address)},
+		ValidatorRewardsOwner: owner,
+		DelegatorRewardsOwner: owner,
+		DelegationShares:      200000,
+	}, signerKey)
+	addDelegatorTx := fixtureTx(t, &txs.AddPermissionlessDelegatorTx{
+		BaseTx: fixtureBaseTx([]*avax.TransferableInput{
+			fixtureIn(createSubnetTx.ID(), 1, 1000),
+		}, nil, "delegator"),
+		Validator:              validator.Validator{NodeID: fixtureNodeID, Start: start, End: end, Wght: 999},
+		Subnet:                 ids.Empty,
+		StakeOuts:              []*avax.TransferableOutput{fixtureOut(999, address)},
+		DelegationRewardsOwner: owner,
+	}, signerKey)
+
+	var recordings []chain.ContainerRecording
+	parentID := ids.ID{'P'}
+	for i, tx := range []*txs.Tx{
+		createSubnetTx,
+		createChainTx,
+		addSubnetValidatorTx,
+		removeSubnetValidatorTx,
+		transformSubnetTx,
+		addValidatorTx,
+		addDelegatorTx,
+	} {
+		timestamp := fixtureTimestamp.Add(time.Duration(i) * time.Second)
+		blk, err := blocks.NewBanffStandardBlock(timestamp, parentID, uint64(i+1), []*txs.Tx{tx})
+		require.NoError(t, err)
+		parentID = blk.ID()
+
+		encoded, err := formatting.Encode(formatting.Hex, blk.Bytes())
+		require.NoError(t, err)
+		recordings = append(recordings, chain.ContainerRecording{
+			Id:        blk.ID().String(),
+			Bytes:     encoded,
+			Timestamp: timestamp,
+			Index:     strconv.Itoa(i),
+		})
+	}
+
+	_, filename, _, _ := runtime.Caller(0)
+	dir, _ := path.Split(filename)
+	data, err := json.MarshalIndent(recordings, "", "  ")
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(dir, "../../resources/test/p_chain_indexer_banff_blocks.json"), append(data, '\n'), 0644)
+	require.NoError(t, err)
+}
+
+func fixtureTx(t *testing.T, unsigned txs.UnsignedTx, key *crypto.PrivateKeySECP256K1R) *txs.Tx {
+	tx := &txs.Tx{Unsigned: unsigned}
+	if key == nil {
+		require.NoError(t, tx.Initialize(txs.Codec))
+		return tx
+	}
+	require.NoError(t, tx.Sign(txs.Codec, [][]*crypto.PrivateKeySECP256K1R{{key}}))
+	return tx
+}
+
+func fixtureBaseTx(ins []*avax.TransferableInput, outs []*avax.TransferableOutput, memo string) txs.BaseTx {
+	return txs.BaseTx{BaseTx: avax.BaseTx{
+		BlockchainID: ids.Empty,
+		Ins:          ins,
+		Outs:         outs,
+		Memo:         []byte(memo),
+	}}
+}
+
+func fixtureOut(amount uint64, address ids.ShortID) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: fixtureAssetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: amount,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Threshold: 1,
+				Addrs:     []ids.ShortID{address},
+			},
+		},
+	}
+}
+
+func fixtureIn(txID ids.ID, index uint32, amount uint64) *avax.TransferableInput {
+	return &avax.TransferableInput{
+		UTXOID: avax.UTXOID{TxID: txID, OutputIndex: index},
+		Asset:  avax.Asset{ID: fixtureAssetID},
+		In: &secp256k1fx.TransferInput{
+			Amt:   amount,
+			Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/logger"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Number of inputs resolved at once by the input repair
+const inputRepairBatchSize = 1000
+
+// Counts of the inputs processed by RepairPChainInputs
+type InputRepairResult struct {
+	Checked    int // Inputs with a missing address or amount
+	Fixed      int // Inputs stored with the resolved address and amount
+	Unchanged  int // Inputs resolved to the stored address and amount, e.g. outputs without amount
+	Unresolved int // Inputs without a stored or fetched output
+}
+
+type inputRepairDB interface {
+	FetchUnresolvedPChainTxInputs(ctx context.Context, fromID uint64, limit int) ([]database.PChainTxInput, error)
+	UpdatePChainTxInput(ctx context.Context, in *database.PChainTxInput) error
+}
+
+type inputRepairDBGorm struct {
+	db *gorm.DB
+}
+
+func (g inputRepairDBGorm) FetchUnresolvedPChainTxInputs(ctx context.Context, fromID uint64, limit int) ([]database.PChainTxInput, error) {
+	return database.FetchUnresolvedPChainTxInputs(ctx, g.db, fromID, limit)
+}
+
+func (g inputRepairDBGorm) UpdatePChainTxInput(ctx context.Context, in *database.PChainTxInput) error {
+	return database.UpdatePChainTxInput(ctx, g.db, in)
+}
+
+// Resolves the addresses and the amounts of the indexed P-chain inputs which
+// are missing them, e.g. inputs indexed by an older version, with the input
+// updater of the indexer, i.e. from the stored outputs and from the node
+func RepairPChainInputs(ctx context.Context, ictx indexerctx.IndexerContext) (InputRepairResult, error) {
+	updater := newPChainInputUpdater(ictx, newJsonRpcClient(&ictx.Config().Chain))
+	return repairInputs(ctx, inputRepairDBGorm{db: ictx.DB()}, updater, inputRepairBatchSize)
+}
+
+func repairInputs(ctx context.Context, db inputRepairDB, updater shared.InputUpdater, batchSize int) (InputRepairResult, error) {
+	var result InputRepairResult
+	var fromID uint64
+	for {
+		ins, err := db.FetchUnresolvedPChainTxInputs(ctx, fromID, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(ins) == 0 {
+			return result, nil
+		}
+		stored := make([]database.TxInput, len(ins))
+		inputs := make([]shared.Input, len(ins))
+		for i := range ins {
+			stored[i] = ins[i].TxInput
+			inputs[i] = &ins[i]
+		}
+		inputList := shared.NewInputList(inputs)
+		if _, err := updater.UpdateInputs(ctx, inputList); err != nil {
+			return result, fmt.Errorf("cannot resolve the inputs from id %d: %w", ins[0].ID, err)
+		}
+		updater.PurgeCache()
+
+		// Inputs left in the list have no output, the others are resolved even if
+		// the output has no address or amount
+		unresolved := make(map[shared.Input]bool)
+		for _, in := range inputList.Remaining() {
+			unresolved[in] = true
+		}
+		for i := range ins {
+			in := &ins[i]
+			result.Checked++
+			if unresolved[in] {
+				result.Unresolved++
+				continue
+			}
+			if in.Address == stored[i].Address && in.Amount == stored[i].Amount {
+				result.Unchanged++
+				continue
+			}
+			if err := db.UpdatePChainTxInput(ctx, in); err != nil {
+				return result, err
+			}
+			result.Fixed++
+		}
+		logger.Info("repaired %d of %d P-chain inputs", result.Fixed, result.Checked)
+		fromID = ins[len(ins)-1].ID + 1
+	}
+}
@@ -0,0 +1,87 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"context"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/chain/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type testInputRepairDB struct {
+	inputs  []database.PChainTxInput
+	updated []database.PChainTxInput
+}
+
+func (db *testInputRepairDB) FetchUnresolvedPChainTxInputs(ctx context.Context, fromID uint64, limit int) ([]database.PChainTxInput, error) {
+	var ins []database.PChainTxInput
+	for _, in := range db.inputs {
+		if in.ID >= fromID && (len(in.Address) == 0 || in.Amount == 0) && len(ins) < limit {
+			ins = append(ins, in)
+		}
+	}
+	return ins, nil
+}
+
+func (db *testInputRepairDB) UpdatePChainTxInput(ctx context.Context, in *database.PChainTxInput) error {
+	db.updated = append(db.updated, *in)
+	return nil
+}
+
+func TestRepairInputs(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	recorded, err := chain.PChainTestRPCClient()
+	require.NoError(t, err)
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockRPCClient(ctrl)
+	client.EXPECT().GetTx(gomock.Any(), gomock.Any()).DoAndReturn(recorded.GetTx).AnyTimes()
+	client.EXPECT().GetRewardUTXOs(gomock.Any(), gomock.Any()).DoAndReturn(recorded.GetRewardUTXOs).AnyTimes()
+
+	outputs := testOutputsDB{
+		outputs: []database.PChainTxOutput{
+			{TxOutput: database.TxOutput{TxID: testOutTxID, Idx: 0, Address: "localflare1a", Amount: 100}},
+			{TxOutput: database.TxOutput{TxID: testOutTxID, Idx: 1, Address: "localflare1b", Amount: 200}},
+			{TxOutput: database.TxOutput{TxID: testOutTxID, Idx: 2, Address: "localflare1c"}},
+		},
+	}
+	newInput := func(id uint64, outIdx uint32, address string, amount uint64) database.PChainTxInput {
+		in := database.PChainTxInput{TxInput: database.TxInput{OutTxID: testOutTxID, OutIdx: outIdx, Address: address, Amount: amount}}
+		in.ID = id
+		return in
+	}
+	db := &testInputRepairDB{
+		inputs: []database.PChainTxInput{
+			newInput(1, 0, "", 100),
+			newInput(2, 0, "localflare1a", 100), // resolved
+			newInput(3, 1, "", 0),
+			newInput(4, 2, "", 0), // output without amount
+			newInput(5, 2, "localflare1c", 0),
+			newInput(6, 99, "", 0), // output neither stored nor on the chain
+		},
+	}
+
+	result, err := repairInputs(context.Background(), db, newPChainInputUpdaterWithDB(outputs, client, chain.NewParser(), "localflare"), 2)
+	require.NoError(t, err)
+	require.Equal(t, InputRepairResult{Checked: 5, Fixed: 3, Unchanged: 1, Unresolved: 1}, result)
+	require.Len(t, db.updated, 3)
+	require.Equal(t, uint64(1), db.updated[0].ID)
+	require.Equal(t, "localflare1a", db.updated[0].Address)
+	require.Equal(t, uint64(100), db.updated[0].Amount)
+	require.Equal(t, uint64(3), db.updated[1].ID)
+	require.Equal(t, "localflare1b", db.updated[1].Address)
+	require.Equal(t, uint64(200), db.updated[1].Amount)
+	require.Equal(t, uint64(4), db.updated[2].ID)
+	require.Equal(t, "localflare1c", db.updated[2].Address)
+	require.Zero(t, db.updated[2].Amount)
+}
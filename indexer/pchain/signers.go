@@ -0,0 +1,81 @@
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"gorm.io/gorm"
+)
+
+// Signers of the transaction from its signer addresses by credential and
+// signature, see chain.PChainTxSignerAddresses
+func newPChainTxSigners(txID string, addresses [][]string) []*database.PChainTxSigner {
+	var signers []*database.PChainTxSigner
+	for ci, credAddresses := range addresses {
+		for si, addr := range credAddresses {
+			signers = append(signers, &database.PChainTxSigner{
+				TxID:    txID,
+				CredIdx: uint32(ci),
+				SigIdx:  uint32(si),
+				Address: addr,
+			})
+		}
+	}
+	return signers
+}
+
+// Staking transactions indexed before signers were stored get them from the
+// stored block bytes. The migration has no configuration, the addresses are
+// formatted with the prefix of the rewards owner of the transaction.
+func storePChainTxSigners(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchPChainTxsFromID(ctx, db, fromID, txMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		var signers []*database.PChainTxSigner
+		for _, dbTx := range dbTxs {
+			if dbTx.TxID == nil || !isStakingTx(dbTx.Type) {
+				continue
+			}
+			addresses, err := blockTxSignerAddresses(parser, &dbTx)
+			if err != nil {
+				return err
+			}
+			signers = append(signers, newPChainTxSigners(*dbTx.TxID, addresses)...)
+		}
+		if err := database.CreatePChainTxSigners(ctx, db, signers); err != nil {
+			return err
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Signer addresses of the staking transaction decoded from the stored block bytes
+func blockTxSignerAddresses(parser *chain.Parser, dbTx *database.PChainTx) ([][]string, error) {
+	hrp, _, err := address.ParseBech32(dbTx.RewardsOwner)
+	if err != nil {
+		return nil, fmt.Errorf("rewards owner of transaction %s: %w", *dbTx.TxID, err)
+	}
+	txID, err := ids.FromString(*dbTx.TxID)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := chain.PChainBlockTx(parser, dbTx.Bytes, txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %s not found in its block", *dbTx.TxID)
+	}
+	return chain.PChainTxSignerAddresses(tx, hrp)
+}
@@ -0,0 +1,72 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/signer"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/validator"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPChainTxSigners(t *testing.T) {
+	factory := crypto.FactorySECP256K1R{}
+	key, err := factory.NewPrivateKey()
+	require.NoError(t, err)
+	secpKey := key.(*crypto.PrivateKeySECP256K1R)
+	address, err := chain.FormatAddressBytes("localflare", secpKey.PublicKey().Address().Bytes())
+	require.NoError(t, err)
+
+	owner := &secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{secpKey.PublicKey().Address()}}
+	validatorTx := &txs.Tx{Unsigned: &txs.AddPermissionlessValidatorTx{
+		Validator: validator.Validator{
+			NodeID: ids.NodeID{1},
+			Start:  uint64(banffTime.Unix()),
+			End:    uint64(banffTime.Add(14 * 24 * time.Hour).Unix()),
+			Wght:   1000,
+		},
+		Subnet:                constants.PrimaryNetworkID,
+		Signer:                &signer.Empty{},
+		StakeOuts:             []*avax.TransferableOutput{banffOut(1000, owner)},
+		ValidatorRewardsOwner: owner,
+		DelegatorRewardsOwner: owner,
+	}}
+	// Two credentials signed by the same key, as for a transaction with two inputs
+	require.NoError(t, validatorTx.Sign(txs.Codec, [][]*crypto.PrivateKeySECP256K1R{{secpKey}, {secpKey}}))
+	blk, err := blocks.NewBanffProposalBlock(banffTime, ids.Empty, 1, validatorTx)
+	require.NoError(t, err)
+	container := banffContainer(t, blk, ids.Empty)
+
+	txID := validatorTx.ID().String()
+	expected := []*database.PChainTxSigner{
+		{TxID: txID, CredIdx: 0, SigIdx: 0, Address: address},
+		{TxID: txID, CredIdx: 1, SigIdx: 0, Address: address},
+	}
+
+	xi := newTxBatchIndexer(nil, nil, nil, chain.NewParser(), newPChainInputUpdaterWithDB(testOutputsDB{}, nil, chain.NewParser(), "localflare"), nil, "localflare")
+	xi.Reset(1)
+	require.NoError(t, xi.AddContainer(context.Background(), 0, container))
+	require.Equal(t, expected, xi.newSigners)
+
+	// The migration formats the addresses with the prefix of the rewards owner
+	addresses, err := blockTxSignerAddresses(chain.NewParser(), &database.PChainTx{
+		TxID:         &txID,
+		Bytes:        container.Bytes,
+		RewardsOwner: address,
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, newPChainTxSigners(txID, addresses))
+}
@@ -1,17 +1,17 @@
 package pchain
 
 import (
+	"context"
 	"flare-indexer/utils/chain"
 
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/formatting"
 	"github.com/ava-labs/avalanchego/utils/json"
 	"github.com/ava-labs/avalanchego/vms/components/avax"
-	"github.com/ava-labs/avalanchego/vms/platformvm/genesis"
 	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
 )
 
-func CallPChainGetTxApi(client chain.RPCClient, txID string) (*txs.Tx, error) {
+func CallPChainGetTxApi(ctx context.Context, client chain.RPCClient, parser *chain.Parser, txID string) (*txs.Tx, error) {
 	id, err := ids.FromString(txID)
 	if err != nil {
 		return nil, err
@@ -22,22 +22,7 @@ func CallPChainGetTxApi(client chain.RPCClient, txID string) (*txs.Tx, error) {
 		return nil, nil
 	}
 
-	// Fetch from chain
-	reply, err := client.GetTx(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse from hex string
-	txData, err := formatting.Decode(formatting.Hex, reply.Tx.(string))
-	if err != nil {
-		return nil, err
-	}
-	tx, err := txs.Parse(genesis.Codec, txData)
-	if err != nil {
-		return nil, err
-	}
-	return tx, nil
+	return chain.FetchPChainTx(ctx, client, parser, id)
 }
 
 // Copy-paste from
@@ -51,14 +36,14 @@ type GetRewardUTXOsReply struct {
 	Encoding   formatting.Encoding `json:"encoding"`
 }
 
-func CallPChainGetRewardUTXOsApi(client chain.RPCClient, txID string) ([]*avax.UTXO, error) {
+func CallPChainGetRewardUTXOsApi(ctx context.Context, client chain.RPCClient, txID string) ([]*avax.UTXO, error) {
 	id, err := ids.FromString(txID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Fetch from chain
-	reply, err := client.GetRewardUTXOs(id)
+	reply, err := client.GetRewardUTXOs(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,79 @@
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/events"
+)
+
+// Returns a match for each transaction and watchlist entry of an address in
+// the inputs or outputs of the transaction or of the node ID of the
+// transaction
+func matchWatchlist(
+	entries []database.WatchlistEntry,
+	txs []*database.PChainTx,
+	ins []*database.PChainTxInput,
+	outs []*database.PChainTxOutput,
+) []*database.WatchlistMatch {
+	if len(entries) == 0 {
+		return nil
+	}
+	addresses := make(map[string]*database.WatchlistEntry)
+	nodeIDs := make(map[string]*database.WatchlistEntry)
+	for i, e := range entries {
+		switch e.Kind {
+		case database.WatchlistAddress:
+			addresses[e.Value] = &entries[i]
+		case database.WatchlistNodeID:
+			nodeIDs[e.Value] = &entries[i]
+		}
+	}
+
+	txAddresses := make(map[string][]string)
+	for _, in := range ins {
+		txAddresses[in.TxID] = append(txAddresses[in.TxID], in.Address)
+	}
+	for _, out := range outs {
+		txAddresses[out.TxID] = append(txAddresses[out.TxID], out.Address)
+	}
+
+	var matches []*database.WatchlistMatch
+	for _, tx := range txs {
+		if tx.TxID == nil {
+			continue
+		}
+		matched := make(map[uint64]bool)
+		add := func(e *database.WatchlistEntry) {
+			if e == nil || matched[e.ID] {
+				return
+			}
+			matched[e.ID] = true
+			matches = append(matches, &database.WatchlistMatch{
+				EntryID:   e.ID,
+				Kind:      e.Kind,
+				Value:     e.Value,
+				TxID:      *tx.TxID,
+				TxType:    tx.Type,
+				Timestamp: tx.Timestamp,
+			})
+		}
+		if len(tx.NodeID) > 0 {
+			add(nodeIDs[tx.NodeID])
+		}
+		for _, address := range txAddresses[*tx.TxID] {
+			add(addresses[address])
+		}
+	}
+	return matches
+}
+
+func publishWatchlistMatches(publisher *events.Publisher, matches []*database.WatchlistMatch) {
+	for _, m := range matches {
+		publisher.Publish(events.WatchlistMatch, events.WatchlistMatchData{
+			TxID:      m.TxID,
+			TxType:    string(m.TxType),
+			Kind:      string(m.Kind),
+			Value:     m.Value,
+			Timestamp: m.Timestamp.UTC(),
+		})
+	}
+}
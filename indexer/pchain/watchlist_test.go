@@ -0,0 +1,49 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"flare-indexer/database"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchWatchlist(t *testing.T) {
+	entries := []database.WatchlistEntry{
+		{BaseEntity: database.BaseEntity{ID: 1}, Kind: database.WatchlistAddress, Value: "flare1a"},
+		{BaseEntity: database.BaseEntity{ID: 2}, Kind: database.WatchlistNodeID, Value: "NodeID-1"},
+	}
+	tx1, tx2, tx3 := "tx1", "tx2", "tx3"
+	txs := []*database.PChainTx{
+		{TxID: &tx1, Type: database.PChainImportTx},
+		{TxID: &tx2, Type: database.PChainAddDelegatorTx, NodeID: "NodeID-1"},
+		{TxID: &tx3, Type: database.PChainExportTx},
+		{Type: database.PChainTxType("")},
+	}
+	ins := []*database.PChainTxInput{
+		{TxInput: database.TxInput{TxID: "tx2", Address: "flare1a"}},
+		{TxInput: database.TxInput{TxID: "tx3", Address: "flare1b"}},
+	}
+	outs := []*database.PChainTxOutput{
+		{TxOutput: database.TxOutput{TxID: "tx1", Address: "flare1a"}},
+		{TxOutput: database.TxOutput{TxID: "tx1", Address: "flare1a"}},
+		{TxOutput: database.TxOutput{TxID: "tx2", Address: "flare1a"}},
+	}
+
+	matches := matchWatchlist(entries, txs, ins, outs)
+
+	type match struct {
+		entryID uint64
+		txID    string
+	}
+	var got []match
+	for _, m := range matches {
+		got = append(got, match{m.EntryID, m.TxID})
+	}
+	require.Equal(t, []match{{1, "tx1"}, {2, "tx2"}, {1, "tx2"}}, got)
+	require.Equal(t, database.PChainAddDelegatorTx, matches[1].TxType)
+
+	require.Empty(t, matchWatchlist(nil, txs, ins, outs))
+}
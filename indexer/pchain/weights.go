@@ -0,0 +1,204 @@
+package pchain
+
+import (
+	"context"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/logger"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// Maximal number of epochs recorded with a single batch, the following batches
+// continue with the remaining epochs when catching up
+const maxWeightEpochsPerBatch = 20
+
+// Records the weights of the validators at the start of each reward epoch of
+// the voting contract once the indexed chain passes it. Staking transactions
+// are issued before their start time, so all stakes active at the start of an
+// epoch are indexed by then. Recorded epochs are updated when staking
+// transactions active at their start are indexed later on, e.g. by a repair,
+// or when their proposals are decided.
+type validatorWeightRecorder struct {
+	epochs staking.EpochInfo
+}
+
+// Returns nil if the validator weights are not enabled
+func newValidatorWeightRecorder(cfg *config.ValidatorWeightsConfig, votingContract staking.EpochConfigCaller) (*validatorWeightRecorder, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	epochs, err := staking.NewVotingEpochInfo(&globalConfig.EpochConfig{First: cfg.First}, votingContract)
+	if err != nil {
+		return nil, fmt.Errorf("validator_weights: %w", err)
+	}
+	if epochs.Period <= 0 {
+		return nil, fmt.Errorf("validator_weights: epoch period of the voting contract must be positive")
+	}
+	return &validatorWeightRecorder{epochs: epochs}, nil
+}
+
+// The epochs are read from the voting contract
+func CheckValidatorWeightsConfig(cfg *config.Config) error {
+	if cfg.ValidatorWeights.Enabled && cfg.ContractAddresses.Voting == (common.Address{}) {
+		return fmt.Errorf("validator_weights: the address of the voting contract is not set")
+	}
+	return nil
+}
+
+// Records the epochs starting before indexedTo (the time of the last indexed
+// block) which are not recorded yet
+func (r *validatorWeightRecorder) record(ctx context.Context, db *gorm.DB, indexedTo time.Time) error {
+	if r == nil {
+		return nil
+	}
+	state, err := database.FetchState(ctx, db, database.ValidatorWeightsStateName)
+	if err != nil {
+		return err
+	}
+	first, last := r.pendingEpochs(int64(state.NextDBIndex), indexedTo)
+	if first > last {
+		return nil
+	}
+	for epoch := first; epoch <= last; epoch++ {
+		weights, err := r.fetchWeights(ctx, db, epoch)
+		if err != nil {
+			return err
+		}
+		if err := database.CreateValidatorWeights(ctx, db, weights); err != nil {
+			return err
+		}
+	}
+	logger.Debug("recorded validator weights of epochs %d-%d", first, last)
+
+	state.NextDBIndex = uint64(last + 1)
+	state.UpdateTime()
+	return database.UpdateState(ctx, db, &state)
+}
+
+// Epochs [first, last] to record, first > last if there are none
+func (r *validatorWeightRecorder) pendingEpochs(next int64, indexedTo time.Time) (int64, int64) {
+	first := next
+	if first < r.epochs.First {
+		first = r.epochs.First
+	}
+	last := first - 1
+	for last+1-first < maxWeightEpochsPerBatch && !r.epochs.GetStartTime(last+1).After(indexedTo) {
+		last++
+	}
+	return first, last
+}
+
+// Recomputes the recorded epochs whose start is in the staking interval of the
+// transactions or of the decided staking transactions of earlier batches.
+// New transactions start after they are issued, so they do not change recorded
+// epochs unless the blocks are indexed again.
+func (r *validatorWeightRecorder) update(ctx context.Context, db *gorm.DB, txs []*database.PChainTx, decided []database.PChainTx) error {
+	if r == nil {
+		return nil
+	}
+	for i := range decided {
+		txs = append(txs, &decided[i])
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	state, err := database.FetchState(ctx, db, database.ValidatorWeightsStateName)
+	if err != nil {
+		return err
+	}
+	epochs := r.changedEpochs(txs, int64(state.NextDBIndex))
+	for _, epoch := range epochs {
+		weights, err := r.fetchWeights(ctx, db, epoch)
+		if err != nil {
+			return err
+		}
+		if err := database.ReplaceValidatorWeights(ctx, db, epoch, weights); err != nil {
+			return err
+		}
+	}
+	if len(epochs) > 0 {
+		logger.Debug("updated validator weights of %d recorded epochs", len(epochs))
+	}
+	return nil
+}
+
+// Recorded epochs (before next) whose start is in the staking interval of any
+// of the staking transactions, in ascending order
+func (r *validatorWeightRecorder) changedEpochs(txs []*database.PChainTx, next int64) []int64 {
+	changed := make(map[int64]bool)
+	for _, tx := range txs {
+		if !tx.Type.IsValidatorTx() && !tx.Type.IsDelegatorTx() || tx.StartTime == nil || tx.EndTime == nil {
+			continue
+		}
+		first := r.epochs.GetEpochIndex(*tx.StartTime)
+		if r.epochs.GetStartTime(first).Before(*tx.StartTime) {
+			first++
+		}
+		if first < r.epochs.First {
+			first = r.epochs.First
+		}
+		last := r.epochs.GetEpochIndex(*tx.EndTime)
+		if last >= next {
+			last = next - 1
+		}
+		for epoch := first; epoch <= last; epoch++ {
+			changed[epoch] = true
+		}
+	}
+	epochs := make([]int64, 0, len(changed))
+	for epoch := range changed {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	return epochs
+}
+
+// Weights of all validator and delegator transactions, including the
+// permissionless ones, active at the start of the epoch
+func (r *validatorWeightRecorder) fetchWeights(ctx context.Context, db *gorm.DB, epoch int64) ([]*database.ValidatorWeight, error) {
+	start := r.epochs.GetStartTime(epoch)
+	validators, err := database.FetchNodeStakingIntervals(ctx, db, database.PChainAddValidatorTx, start, start)
+	if err != nil {
+		return nil, err
+	}
+	delegators, err := database.FetchNodeStakingIntervals(ctx, db, database.PChainAddDelegatorTx, start, start)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateValidatorWeights(epoch, validators, delegators), nil
+}
+
+// Sums the stakes by node ID, in the order of the first validator transaction
+// of each node. Delegations of nodes without a validator are included as well.
+func aggregateValidatorWeights(epoch int64, validators []database.PChainTx, delegators []database.PChainTx) []*database.ValidatorWeight {
+	var weights []*database.ValidatorWeight
+	byNode := make(map[string]*database.ValidatorWeight)
+	weight := func(nodeID string) *database.ValidatorWeight {
+		w, ok := byNode[nodeID]
+		if !ok {
+			w = &database.ValidatorWeight{Epoch: epoch, NodeID: nodeID}
+			byNode[nodeID] = w
+			weights = append(weights, w)
+		}
+		return w
+	}
+	for _, tx := range validators {
+		w := weight(tx.NodeID)
+		w.OwnWeight = w.OwnWeight.Add(tx.Weight)
+		w.Weight = w.Weight.Add(tx.Weight)
+	}
+	for _, tx := range delegators {
+		w := weight(tx.NodeID)
+		w.DelegatedWeight = w.DelegatedWeight.Add(tx.Weight)
+		w.Weight = w.Weight.Add(tx.Weight)
+		w.Delegators++
+	}
+	return weights
+}
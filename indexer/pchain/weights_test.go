@@ -0,0 +1,117 @@
+//go:build !integration
+// +build !integration
+
+package pchain
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils/staking"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateValidatorWeights(t *testing.T) {
+	validators := []database.PChainTx{
+		{NodeID: "NodeID-1", Weight: 100},
+		{NodeID: "NodeID-2", Weight: 50},
+	}
+	delegators := []database.PChainTx{
+		{NodeID: "NodeID-2", Weight: 20},
+		{NodeID: "NodeID-1", Weight: 10},
+		{NodeID: "NodeID-2", Weight: 5},
+	}
+	weights := aggregateValidatorWeights(7, validators, delegators)
+	require.Equal(t, []*database.ValidatorWeight{
+		{Epoch: 7, NodeID: "NodeID-1", OwnWeight: database.NewBigInt(100), DelegatedWeight: database.NewBigInt(10),
+			Weight: database.NewBigInt(110), Delegators: 1},
+		{Epoch: 7, NodeID: "NodeID-2", OwnWeight: database.NewBigInt(50), DelegatedWeight: database.NewBigInt(25),
+			Weight: database.NewBigInt(75), Delegators: 2},
+	}, weights)
+
+	require.Empty(t, aggregateValidatorWeights(7, nil, nil))
+
+	// Sums of the stakes of a node exceeding uint64
+	validators = []database.PChainTx{{NodeID: "NodeID-1", Weight: math.MaxUint64}}
+	delegators = []database.PChainTx{{NodeID: "NodeID-1", Weight: math.MaxUint64}, {NodeID: "NodeID-1", Weight: 2}}
+	weights = aggregateValidatorWeights(7, validators, delegators)
+	require.Len(t, weights, 1)
+	require.Equal(t, "18446744073709551615", weights[0].OwnWeight.String())
+	require.Equal(t, "18446744073709551617", weights[0].DelegatedWeight.String())
+	require.Equal(t, "36893488147419103232", weights[0].Weight.String())
+}
+
+func TestPendingWeightEpochs(t *testing.T) {
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	r, err := newValidatorWeightRecorder(&config.ValidatorWeightsConfig{Enabled: true, First: 2},
+		testEpochConfigCaller{start: start, period: time.Hour})
+	require.NoError(t, err)
+
+	// Epochs before the first are not recorded
+	first, last := r.pendingEpochs(0, start.Add(90*time.Minute))
+	require.Greater(t, first, last)
+
+	// The start of epoch 4 is indexed, epoch 5 is not
+	first, last = r.pendingEpochs(0, start.Add(4*time.Hour))
+	require.Equal(t, int64(2), first)
+	require.Equal(t, int64(4), last)
+
+	first, last = r.pendingEpochs(5, start.Add(4*time.Hour+30*time.Minute))
+	require.Greater(t, first, last)
+
+	// Catching up is limited per batch
+	first, last = r.pendingEpochs(3, start.Add(1000*time.Hour))
+	require.Equal(t, int64(3), first)
+	require.Equal(t, int64(3+maxWeightEpochsPerBatch-1), last)
+
+	_, err = newValidatorWeightRecorder(&config.ValidatorWeightsConfig{Enabled: true}, testEpochConfigCaller{start: start})
+	require.Error(t, err)
+	r, err = newValidatorWeightRecorder(&config.ValidatorWeightsConfig{}, testEpochConfigCaller{})
+	require.NoError(t, err)
+	require.Nil(t, r)
+}
+
+func TestChangedWeightEpochs(t *testing.T) {
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	r := &validatorWeightRecorder{epochs: staking.EpochInfo{Start: start, Period: time.Hour, First: 2}}
+	stake := func(txType database.PChainTxType, from time.Duration, to time.Duration) *database.PChainTx {
+		startTime, endTime := start.Add(from), start.Add(to)
+		return &database.PChainTx{Type: txType, StartTime: &startTime, EndTime: &endTime}
+	}
+
+	// Stakes starting after the recorded epochs
+	require.Empty(t, r.changedEpochs([]*database.PChainTx{stake(database.PChainAddDelegatorTx, 6*time.Hour, 9*time.Hour)}, 6))
+
+	// Epochs 4 and 5 start within the first stake, epochs 2 and 3 within the
+	// second one; epoch 6 is not recorded yet
+	epochs := r.changedEpochs([]*database.PChainTx{
+		stake(database.PChainAddPermissionlessValidatorTx, 3*time.Hour+time.Minute, 9*time.Hour),
+		stake(database.PChainAddDelegatorTx, 0, 3*time.Hour),
+		stake(database.PChainImportTx, 0, 9*time.Hour),
+	}, 6)
+	require.Equal(t, []int64{2, 3, 4, 5}, epochs)
+}
+
+// Voting contract with the epoch configuration
+type testEpochConfigCaller struct {
+	start  time.Time
+	period time.Duration
+}
+
+func (c testEpochConfigCaller) GetEpochConfiguration(opts *bind.CallOpts) (struct {
+	FirstEpochStartTs    *big.Int
+	EpochDurationSeconds *big.Int
+}, error) {
+	return struct {
+		FirstEpochStartTs    *big.Int
+		EpochDurationSeconds *big.Int
+	}{
+		FirstEpochStartTs:    big.NewInt(c.start.Unix()),
+		EpochDurationSeconds: big.NewInt(int64(c.period / time.Second)),
+	}, nil
+}
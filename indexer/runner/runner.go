@@ -4,13 +4,21 @@ import (
 	"flare-indexer/indexer/context"
 	"flare-indexer/indexer/cronjob"
 	"flare-indexer/indexer/pchain"
+	"flare-indexer/indexer/sink"
+	"flare-indexer/indexer/webhooks"
 	"flare-indexer/indexer/xchain"
 	"log"
 )
 
 func Start(ctx context.IndexerContext) {
-	xIndexer := xchain.CreateXChainTxIndexer(ctx)
-	pIndexer := pchain.CreatePChainBlockIndexer(ctx)
+	xIndexer, err := xchain.CreateXChainTxIndexer(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pIndexer, err := pchain.CreatePChainBlockIndexer(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	votingCronjob, err := cronjob.NewVotingCronjob(ctx)
 	if err != nil {
@@ -24,18 +32,66 @@ func Start(ctx context.IndexerContext) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	uptimeCronjob := cronjob.NewUptimeCronjob(ctx)
+	anomaliesCronjob, err := cronjob.NewAnomaliesCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reconciliationCronjob, err := cronjob.NewReconciliationCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractEventsCronjob, err := cronjob.NewContractEventsCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	uptimeCronjob, err := cronjob.NewUptimeCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
 	uptimeVotingCronjob, err := cronjob.NewUptimeVotingCronjob(ctx)
 	if err != nil {
 		log.Fatal(err)
 	}
+	balanceCronjob, err := cronjob.NewBalanceCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clockDriftCronjob, err := cronjob.NewClockDriftCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pruningCronjob, err := cronjob.NewPruningCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	statsCronjob, err := cronjob.NewStatsCronjob(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	webhookCronjobs, err := webhooks.NewCronjobs(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	go xIndexer.Run()
 	go pIndexer.Run()
 
-	go cronjob.RunCronjob(uptimeCronjob)
-	go cronjob.RunCronjob(votingCronjob)
-	go cronjob.RunCronjob(addressBinderCronjob)
-	go cronjob.RunCronjob(mirrorCronjob)
-	go cronjob.RunCronjob(uptimeVotingCronjob)
+	go cronjob.RunCronjob(uptimeCronjob, ctx)
+	go cronjob.RunCronjob(votingCronjob, ctx)
+	go cronjob.RunCronjob(addressBinderCronjob, ctx)
+	go cronjob.RunCronjob(mirrorCronjob, ctx)
+	go cronjob.RunCronjob(anomaliesCronjob, ctx)
+	go cronjob.RunCronjob(reconciliationCronjob, ctx)
+	go cronjob.RunCronjob(contractEventsCronjob, ctx)
+	go cronjob.RunCronjob(uptimeVotingCronjob, ctx)
+	go cronjob.RunCronjob(balanceCronjob, ctx)
+	go cronjob.RunCronjob(clockDriftCronjob, ctx)
+	go cronjob.RunCronjob(pruningCronjob, ctx)
+	go cronjob.RunCronjob(statsCronjob, ctx)
+	for _, s := range sink.NewKafkaSinks(ctx) {
+		go cronjob.RunCronjob(s, ctx)
+	}
+	for _, c := range webhookCronjobs {
+		go cronjob.RunCronjob(c, ctx)
+	}
 }
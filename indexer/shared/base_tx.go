@@ -5,6 +5,7 @@ import (
 	"flare-indexer/utils/chain"
 	"fmt"
 
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/vms/components/avax"
 	"github.com/ava-labs/avalanchego/vms/components/verify"
 	"github.com/ava-labs/avalanchego/vms/platformvm/fx"
@@ -14,14 +15,14 @@ import (
 // Create database outputs from TransferableOutputs, provided their type is *secp256k1fx.TransferOutput and the
 // number of addresses for each output is 1. Error is returned if these two conditions
 // are not met.
-func OutputsFromTxOuts(txID string, outs []*avax.TransferableOutput, startIndex int, creator OutputCreator) ([]Output, error) {
+func OutputsFromTxOuts(hrp string, txID string, outs []*avax.TransferableOutput, startIndex int, creator OutputCreator) ([]Output, error) {
 	txOuts := make([]Output, len(outs))
 	for outi, cout := range outs {
 		dbOut := &database.TxOutput{
 			TxID: txID,
 			Idx:  uint32(outi + startIndex),
 		}
-		err := UpdateTransferableOutput(dbOut, cout.Out)
+		err := UpdateTransferableOutput(hrp, dbOut, cout.Out)
 		if err != nil {
 			return nil, err
 		}
@@ -33,14 +34,14 @@ func OutputsFromTxOuts(txID string, outs []*avax.TransferableOutput, startIndex
 // Create database outputs from UTXOs, provided their type is *secp256k1fx.TransferOutput and the
 // number of addresses for each output is 1. Error is returned if these two conditions
 // are not met.
-func OutputsFromUTXO(txID string, utxos []*avax.UTXO, creator OutputCreator) ([]Output, error) {
+func OutputsFromUTXO(hrp string, txID string, utxos []*avax.UTXO, creator OutputCreator) ([]Output, error) {
 	txOuts := make([]Output, len(utxos))
 	for i, utxo := range utxos {
 		dbOut := &database.TxOutput{
 			TxID: txID,
 			Idx:  utxo.OutputIndex,
 		}
-		err := UpdateTransferableOutput(dbOut, utxo.Out)
+		err := UpdateTransferableOutput(hrp, dbOut, utxo.Out)
 		if err != nil {
 			return nil, err
 		}
@@ -51,7 +52,8 @@ func OutputsFromUTXO(txID string, utxos []*avax.UTXO, creator OutputCreator) ([]
 
 // Update database output from out provided its type is *secp256k1fx.TransferOutput and the
 // number of addresses is 1. Error is returned if these two conditions are not met.
-func UpdateTransferableOutput(dbOut *database.TxOutput, out verify.State) error {
+// The address is formatted with the address prefix hrp.
+func UpdateTransferableOutput(hrp string, dbOut *database.TxOutput, out verify.State) error {
 	to, ok := out.(*secp256k1fx.TransferOutput)
 	if !ok {
 		return fmt.Errorf("TransferableOutput has unsupported type")
@@ -60,7 +62,7 @@ func UpdateTransferableOutput(dbOut *database.TxOutput, out verify.State) error
 		return fmt.Errorf("TransferableOutput has 0 or more than one address")
 	}
 
-	addr, err := chain.FormatAddressBytes(to.Addrs[0].Bytes())
+	addr, err := chain.FormatAddressBytes(hrp, to.Addrs[0].Bytes())
 	if err != nil {
 		return err
 	}
@@ -71,7 +73,7 @@ func UpdateTransferableOutput(dbOut *database.TxOutput, out verify.State) error
 
 // Return address from Owner interface provided its type is *secp256k1fx.OutputOwners and the
 // number of addresses is 1. Error is returned if these two conditions are not met.
-func RewardsOwnerAddress(owner fx.Owner) (string, error) {
+func RewardsOwnerAddress(hrp string, owner fx.Owner) (string, error) {
 	oo, ok := owner.(*secp256k1fx.OutputOwners)
 	if !ok {
 		return "", fmt.Errorf("rewards owner has unsupported type")
@@ -79,7 +81,7 @@ func RewardsOwnerAddress(owner fx.Owner) (string, error) {
 	if len(oo.Addrs) != 1 {
 		return "", fmt.Errorf("rewards owner has 0 or more than one address")
 	}
-	return chain.FormatAddressBytes(oo.Addrs[0].Bytes())
+	return chain.FormatAddressBytes(hrp, oo.Addrs[0].Bytes())
 }
 
 // Create inputs to BaseTx. Note that addresses of inputs are are not set. They should be updated from
@@ -97,3 +99,34 @@ func InputsFromTxIns(txID string, ins []*avax.TransferableInput, creator InputCr
 	}
 	return txIns
 }
+
+// Amount burned by a transaction, i.e., the sum of the amounts of the consumed inputs
+// minus the sum of the amounts of the produced outputs. Inputs and outputs are passed
+// in groups, e.g., base tx inputs and imported inputs.
+func TxFee(ins [][]*avax.TransferableInput, outs [][]*avax.TransferableOutput) uint64 {
+	return TxAssetFee(ids.Empty, ins, outs)
+}
+
+// Amount of the asset burned by a transaction, see TxFee. Inputs and outputs of
+// other assets are skipped, all assets are counted if assetID is ids.Empty.
+func TxAssetFee(assetID ids.ID, ins [][]*avax.TransferableInput, outs [][]*avax.TransferableOutput) uint64 {
+	var consumed, produced uint64
+	for _, group := range ins {
+		for _, in := range group {
+			if assetID == ids.Empty || in.AssetID() == assetID {
+				consumed += in.In.Amount()
+			}
+		}
+	}
+	for _, group := range outs {
+		for _, out := range group {
+			if assetID == ids.Empty || out.AssetID() == assetID {
+				produced += out.Out.Amount()
+			}
+		}
+	}
+	if produced >= consumed {
+		return 0
+	}
+	return consumed - produced
+}
@@ -0,0 +1,31 @@
+//go:build !integration
+// +build !integration
+
+package shared
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxAssetFee(t *testing.T) {
+	avaxID, otherID := ids.ID{1}, ids.ID{2}
+	in := func(assetID ids.ID, amount uint64) *avax.TransferableInput {
+		return &avax.TransferableInput{Asset: avax.Asset{ID: assetID}, In: &secp256k1fx.TransferInput{Amt: amount}}
+	}
+	out := func(assetID ids.ID, amount uint64) *avax.TransferableOutput {
+		return &avax.TransferableOutput{Asset: avax.Asset{ID: assetID}, Out: &secp256k1fx.TransferOutput{Amt: amount}}
+	}
+	// Transfer of another asset paying the fee with the fee asset, the amounts of
+	// the other asset do not cancel out
+	ins := [][]*avax.TransferableInput{{in(avaxID, 1000), in(otherID, 500)}}
+	outs := [][]*avax.TransferableOutput{{out(avaxID, 999), out(otherID, 50)}}
+
+	require.Equal(t, uint64(1), TxAssetFee(avaxID, ins, outs))
+	require.Equal(t, uint64(450), TxAssetFee(otherID, ins, outs))
+	require.Equal(t, uint64(451), TxFee(ins, outs))
+}
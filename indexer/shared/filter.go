@@ -0,0 +1,56 @@
+package shared
+
+import (
+	"flare-indexer/indexer/config"
+	"fmt"
+	"strings"
+)
+
+// Filter of the indexed transaction types, configured by include_types and
+// exclude_types of the indexer. A nil filter indexes all types.
+type TxTypeFilter struct {
+	include map[string]bool
+	exclude map[string]bool
+}
+
+// Returns nil if no filter is configured, error if a configured type is not one of
+// the known types of the chain
+func NewTxTypeFilter(cfg *config.IndexerConfig, knownTypes []string) (*TxTypeFilter, error) {
+	if len(cfg.IncludeTypes) == 0 && len(cfg.ExcludeTypes) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]bool, len(knownTypes))
+	for _, t := range knownTypes {
+		known[t] = true
+	}
+	toSet := func(types []string) (map[string]bool, error) {
+		set := make(map[string]bool, len(types))
+		for _, t := range types {
+			if !known[t] {
+				return nil, fmt.Errorf("unknown transaction type %q, known types are %s", t, strings.Join(knownTypes, ", "))
+			}
+			set[t] = true
+		}
+		return set, nil
+	}
+
+	var err error
+	filter := &TxTypeFilter{}
+	if filter.include, err = toSet(cfg.IncludeTypes); err != nil {
+		return nil, err
+	}
+	if filter.exclude, err = toSet(cfg.ExcludeTypes); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (f *TxTypeFilter) Indexed(txType string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !f.include[txType] {
+		return false
+	}
+	return !f.exclude[txType]
+}
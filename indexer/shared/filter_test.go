@@ -0,0 +1,41 @@
+//go:build !integration
+// +build !integration
+
+package shared
+
+import (
+	"flare-indexer/indexer/config"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxTypeFilter(t *testing.T) {
+	known := []string{"BASE_TX", "IMPORT_TX", "EXPORT_TX"}
+
+	filter, err := NewTxTypeFilter(&config.IndexerConfig{}, known)
+	require.NoError(t, err)
+	require.Nil(t, filter)
+	require.True(t, filter.Indexed("BASE_TX"))
+
+	filter, err = NewTxTypeFilter(&config.IndexerConfig{IncludeTypes: []string{"IMPORT_TX", "EXPORT_TX"}}, known)
+	require.NoError(t, err)
+	require.False(t, filter.Indexed("BASE_TX"))
+	require.True(t, filter.Indexed("IMPORT_TX"))
+
+	filter, err = NewTxTypeFilter(&config.IndexerConfig{ExcludeTypes: []string{"BASE_TX"}}, known)
+	require.NoError(t, err)
+	require.False(t, filter.Indexed("BASE_TX"))
+	require.True(t, filter.Indexed("EXPORT_TX"))
+
+	filter, err = NewTxTypeFilter(&config.IndexerConfig{
+		IncludeTypes: []string{"IMPORT_TX", "EXPORT_TX"},
+		ExcludeTypes: []string{"EXPORT_TX"},
+	}, known)
+	require.NoError(t, err)
+	require.True(t, filter.Indexed("IMPORT_TX"))
+	require.False(t, filter.Indexed("EXPORT_TX"))
+
+	_, err = NewTxTypeFilter(&config.IndexerConfig{ExcludeTypes: []string{"BASETX"}}, known)
+	require.ErrorContains(t, err, "BASETX")
+}
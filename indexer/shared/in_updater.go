@@ -2,6 +2,7 @@ package shared
 
 import (
 	"container/list"
+	"context"
 	"flare-indexer/utils"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -10,7 +11,7 @@ import (
 type InputUpdater interface {
 	// Update inputs with addresses. Updater can get outputs from cache, db, chain (indexer, api), ...
 	// Updated inputs should be removed from the list, missing output tx ids are returned
-	UpdateInputs(inputs InputList) (mapset.Set[string], error)
+	UpdateInputs(ctx context.Context, inputs InputList) (mapset.Set[string], error)
 
 	// Put outputs of a transaction to cache -- to avoid updating from chain or database
 	CacheOutputs(outs []Output)
@@ -40,6 +41,69 @@ func (iu *BaseInputUpdater) UpdateInputsFromCache(notUpdated InputList) mapset.S
 	return notUpdated.UpdateWithOutputs(iu.cache)
 }
 
+// Chain specific part of ChainInputUpdater
+type OutputFetcher interface {
+	// Stored outputs of the transactions
+	FetchStoredOutputs(ctx context.Context, txIDs []string) ([]Output, error)
+	// Adds the outputs of the transaction read from the chain to outs. A nil
+	// output marks an output of the genesis, its inputs get the transaction id
+	// as the address.
+	AddChainOutputs(ctx context.Context, txID string, outs OutputMap) error
+}
+
+// Input updater resolving the inputs from the cache, then from the stored
+// outputs and then from the chain
+type ChainInputUpdater struct {
+	BaseInputUpdater
+
+	fetcher OutputFetcher
+}
+
+func NewChainInputUpdater(fetcher OutputFetcher) *ChainInputUpdater {
+	iu := ChainInputUpdater{fetcher: fetcher}
+	iu.InitCache()
+	return &iu
+}
+
+func (iu *ChainInputUpdater) UpdateInputs(ctx context.Context, inputs InputList) (mapset.Set[string], error) {
+	missingTxIds := iu.UpdateInputsFromCache(inputs)
+	missingTxIds, err := iu.updateFromDB(ctx, inputs, missingTxIds)
+	if err != nil {
+		return nil, err
+	}
+	return iu.updateFromChain(ctx, inputs, missingTxIds)
+}
+
+func (iu *ChainInputUpdater) updateFromDB(
+	ctx context.Context,
+	inputs InputList,
+	missingTxIds mapset.Set[string],
+) (mapset.Set[string], error) {
+	outs, err := iu.fetcher.FetchStoredOutputs(ctx, missingTxIds.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+	storedOuts := NewOutputMap()
+	for _, out := range outs {
+		storedOuts.Add(NewIdIndexKeyFromOutput(out), out)
+	}
+	return inputs.UpdateWithOutputs(storedOuts), nil
+}
+
+func (iu *ChainInputUpdater) updateFromChain(
+	ctx context.Context,
+	inputs InputList,
+	missingTxIds mapset.Set[string],
+) (mapset.Set[string], error) {
+	fetchedOuts := NewOutputMap()
+	for _, txId := range missingTxIds.ToSlice() {
+		if err := iu.fetcher.AddChainOutputs(ctx, txId, fetchedOuts); err != nil {
+			return nil, err
+		}
+	}
+	return inputs.UpdateWithOutputs(fetchedOuts), nil
+}
+
 func NewInputList(inputs []Input) InputList {
 	list := InputList{list.New()}
 	for _, in := range inputs {
@@ -48,7 +112,17 @@ func NewInputList(inputs []Input) InputList {
 	return list
 }
 
+// Inputs which have not been updated
+func (il InputList) Remaining() []Input {
+	inputs := make([]Input, 0, il.inputs.Len())
+	for e := il.inputs.Front(); e != nil; e = e.Next() {
+		inputs = append(inputs, e.Value.(Input))
+	}
+	return inputs
+}
+
 // Update input address from outputs
+//   - a missing input amount is taken from the output
 //   - updated inputs will be removed from the list
 //   - return missing output tx ids
 func (il InputList) UpdateWithOutputs(outputs utils.CacheBase[IdIndexKey, Output]) mapset.Set[string] {
@@ -62,6 +136,9 @@ func (il InputList) UpdateWithOutputs(outputs utils.CacheBase[IdIndexKey, Output
 				in.UpdateAddr(in.OutTx())
 			} else {
 				in.UpdateAddr(out.Addr())
+				if in.Amt() == 0 {
+					in.UpdateAmount(out.Amt())
+				}
 			}
 			il.inputs.Remove(e)
 		} else {
@@ -0,0 +1,90 @@
+//go:build !integration
+// +build !integration
+
+package shared
+
+import (
+	"context"
+	"flare-indexer/database"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutputFetcher struct {
+	stored  []database.TxOutput
+	chain   map[string][]database.TxOutput
+	genesis string
+
+	storedCalls [][]string
+	chainCalls  []string
+}
+
+func (f *testOutputFetcher) FetchStoredOutputs(ctx context.Context, txIDs []string) ([]Output, error) {
+	f.storedCalls = append(f.storedCalls, txIDs)
+	var outs []Output
+	for i := range f.stored {
+		outs = append(outs, &f.stored[i])
+	}
+	return outs, nil
+}
+
+func (f *testOutputFetcher) AddChainOutputs(ctx context.Context, txID string, outs OutputMap) error {
+	f.chainCalls = append(f.chainCalls, txID)
+	if txID == f.genesis {
+		outs.Add(NewIdIndexKey(txID, 0), nil)
+	}
+	for i := range f.chain[txID] {
+		out := &f.chain[txID][i]
+		outs.Add(NewIdIndexKeyFromOutput(out), out)
+	}
+	return nil
+}
+
+func TestChainInputUpdater(t *testing.T) {
+	fetcher := &testOutputFetcher{
+		stored: []database.TxOutput{
+			{TxID: "stored", Idx: 0, Address: "addr0", Amount: 10},
+			{TxID: "stored", Idx: 1, Address: "addr1", Amount: 20},
+		},
+		chain: map[string][]database.TxOutput{
+			"fetched": {{TxID: "fetched", Idx: 0, Address: "addr2", Amount: 30}},
+		},
+		genesis: "genesis",
+	}
+	updater := NewChainInputUpdater(fetcher)
+	updater.CacheOutputs([]Output{&database.TxOutput{TxID: "cached", Idx: 0, Address: "addr3", Amount: 40}})
+
+	ins := []*database.TxInput{
+		{OutTxID: "stored", OutIdx: 0},
+		{OutTxID: "stored", OutIdx: 1, Amount: 5},
+		{OutTxID: "fetched", OutIdx: 0},
+		{OutTxID: "cached", OutIdx: 0},
+		{OutTxID: "genesis", OutIdx: 0},
+		{OutTxID: "unknown", OutIdx: 0},
+	}
+	inputs := make([]Input, len(ins))
+	for i, in := range ins {
+		inputs[i] = in
+	}
+	inputList := NewInputList(inputs)
+	missing, err := updater.UpdateInputs(context.Background(), inputList)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"unknown"}, missing.ToSlice())
+	require.Equal(t, []Input{ins[5]}, inputList.Remaining())
+
+	// Each input gets the address of its own output, known amounts are kept
+	require.Equal(t, "addr0", ins[0].Address)
+	require.Equal(t, uint64(10), ins[0].Amount)
+	require.Equal(t, "addr1", ins[1].Address)
+	require.Equal(t, uint64(5), ins[1].Amount)
+	require.Equal(t, "addr2", ins[2].Address)
+	require.Equal(t, "addr3", ins[3].Address)
+	require.Equal(t, "genesis", ins[4].Address)
+	require.Empty(t, ins[5].Address)
+
+	// The cached output is not fetched, stored outputs are not fetched from the chain
+	require.Len(t, fetcher.storedCalls, 1)
+	require.ElementsMatch(t, []string{"stored", "fetched", "genesis", "unknown"}, fetcher.storedCalls[0])
+	require.ElementsMatch(t, []string{"fetched", "genesis", "unknown"}, fetcher.chainCalls)
+}
@@ -1,26 +1,44 @@
 package shared
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/config"
+	"flare-indexer/indexer/events"
+	"flare-indexer/indexer/featureflags"
 	"flare-indexer/logger"
 	"flare-indexer/utils/chain"
+	"fmt"
 	"time"
 
 	"github.com/ava-labs/avalanchego/indexer"
 	"gorm.io/gorm"
 )
 
+// Rows inserted by one statement, large buffered batches would exceed the limit
+// of placeholders of a statement otherwise
+const insertBatchSize = 1000
+
 type ContainerBatchIndexer interface {
 	Reset(containerLen int)
-	AddContainer(index uint64, container indexer.Container) error
-	ProcessBatch() error
-	PersistEntities(db *gorm.DB) error
+	AddContainer(ctx context.Context, index uint64, container indexer.Container) error
+	// Number of rows of the added containers, used to limit the buffered batches
+	Rows() int
+	ProcessBatch(ctx context.Context) error
+	PersistEntities(ctx context.Context, db *gorm.DB) error
+}
+
+// Implemented by batch indexers which publish events about the persisted
+// entities
+type EventSource interface {
+	PublishEvents(publisher *events.Publisher)
 }
 
 type ChainIndexerBase struct {
 	StateName   string
 	IndexerName string
+	// Name of the indexed network, empty if the process indexes a single network
+	Network string
 
 	DB     *gorm.DB
 	Client chain.IndexerClient
@@ -28,14 +46,24 @@ type ChainIndexerBase struct {
 
 	BatchIndexer ContainerBatchIndexer
 
+	// Runtime overrides of Config, flag names are prefixed by FeatureFlagName
+	FeatureFlags    *featureflags.Flags
+	FeatureFlagName string
+
+	// Events of batch indexers implementing EventSource are published here
+	// after the batch is persisted
+	Events *events.Publisher
+
 	metrics *metrics
+	// True if the next index was checked against the indexes available on the node
+	rangeChecked bool
 }
 
-func (ci *ChainIndexerBase) IndexBatch() error {
+func (ci *ChainIndexerBase) IndexBatch(ctx context.Context) error {
 	startTime := time.Now()
 
 	// Get current state of tx indexer from db
-	currentState, err := database.FetchState(ci.DB, ci.StateName)
+	currentState, err := database.FetchState(ctx, ci.DB, ci.StateName)
 	if err != nil {
 		return err
 	}
@@ -47,8 +75,16 @@ func (ci *ChainIndexerBase) IndexBatch() error {
 		nextIndex = currentState.NextDBIndex
 	}
 
+	if !ci.rangeChecked {
+		nextIndex, err = ci.checkIndexRange(ctx, nextIndex)
+		if err != nil {
+			return err
+		}
+		ci.rangeChecked = true
+	}
+
 	// Fetch last accepted index on chain
-	_, lastIndex, err := chain.FetchLastAcceptedContainer(ci.Client)
+	_, lastIndex, err := chain.FetchLastAcceptedContainer(ctx, ci.Client)
 	if err != nil {
 		return err
 	}
@@ -63,33 +99,36 @@ func (ci *ChainIndexerBase) IndexBatch() error {
 
 		// Update time of last run (for other clients to know that the indexer is running)
 		currentState.UpdateTime()
-		return database.UpdateState(ci.DB, &currentState)
+		return database.UpdateState(ctx, ci.DB, &currentState)
 	}
 
-	// Get MaxBatch containers from the chain
-	containers, err := chain.FetchContainerRangeFromIndexer(ci.Client, nextIndex, ci.Config.BatchSize)
+	// Get MaxBatch containers from the chain, or several batches if they are buffered
+	batchSize := int(ci.FeatureFlags.BatchSize(ci.FeatureFlagName, int64(ci.Config.BatchSize)))
+	lastProcessedIndex, err := ci.bufferContainers(ctx, nextIndex, lastIndex, batchSize)
 	if err != nil {
 		return err
 	}
-
-	lastProcessedIndex, err := ci.ProcessContainers(nextIndex, containers)
-	if err != nil {
+	if err := ci.BatchIndexer.ProcessBatch(ctx); err != nil {
 		return err
 	}
 
-	err = database.DoInTransaction(ci.DB,
-		func(db *gorm.DB) error { return ci.BatchIndexer.PersistEntities(db) },
+	db := ci.DB.Session(&gorm.Session{CreateBatchSize: insertBatchSize})
+	err = database.DoInTransaction(ctx, db,
+		func(db *gorm.DB) error { return ci.BatchIndexer.PersistEntities(ctx, db) },
 		func(db *gorm.DB) error {
 			currentState.Update(lastProcessedIndex+1, lastIndex)
-			return database.UpdateState(db, &currentState)
+			return database.UpdateState(ctx, db, &currentState)
 		},
 	)
 	if err != nil {
 		return err
 	}
+	if source, ok := ci.BatchIndexer.(EventSource); ok {
+		source.PublishEvents(ci.Events)
+	}
 	duration := time.Since(startTime).Milliseconds()
 	logger.Info("Indexer '%s' processed to index %d, last accepted index is %d, duration %dms",
-		ci.IndexerName,
+		ci.name(),
 		lastProcessedIndex, lastIndex, duration)
 
 	if ci.metrics != nil {
@@ -99,25 +138,108 @@ func (ci *ChainIndexerBase) IndexBatch() error {
 	return nil
 }
 
-func (ci *ChainIndexerBase) ProcessContainers(nextIndex uint64, containers []indexer.Container) (uint64, error) {
-	ci.BatchIndexer.Reset(len(containers))
-
-	var index uint64
-	for i, container := range containers {
-		index = nextIndex + uint64(i)
-
-		err := ci.BatchIndexer.AddContainer(index, container)
+// Indexes batches until the container last accepted when it is called is
+// indexed, used to backfill the chain without running the indexer
+func (ci *ChainIndexerBase) IndexToLastAccepted(ctx context.Context) error {
+	_, lastIndex, err := chain.FetchLastAcceptedContainer(ctx, ci.Client)
+	if err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		before, err := database.FetchState(ctx, ci.DB, ci.StateName)
 		if err != nil {
-			return 0, err
+			return err
+		}
+		if err := ci.IndexBatch(ctx); err != nil {
+			return err
+		}
+		after, err := database.FetchState(ctx, ci.DB, ci.StateName)
+		if err != nil {
+			return err
+		}
+		// Nothing is indexed if the start index is beyond the last accepted index
+		if after.NextDBIndex > lastIndex || after.NextDBIndex == before.NextDBIndex {
+			return nil
 		}
 	}
+	return ctx.Err()
+}
 
-	err := ci.BatchIndexer.ProcessBatch()
+// Checks that the container at nextIndex is available on the node. If the node
+// does not retain it (e.g. the node was bootstrapped later or start_index is
+// wrong), indexing continues at the first available index if adjust_start_index
+// is set, otherwise an error with the usable range is returned.
+func (ci *ChainIndexerBase) checkIndexRange(ctx context.Context, nextIndex uint64) (uint64, error) {
+	first, last, err := chain.FetchAvailableIndexRange(ctx, ci.Client)
 	if err != nil {
 		return 0, err
 	}
+	return availableNextIndex(ci.name(), nextIndex, first, last, ci.Config.AdjustStartIndex)
+}
 
-	return index, nil
+func availableNextIndex(name string, nextIndex uint64, first uint64, last uint64, adjust bool) (uint64, error) {
+	if nextIndex > last+1 {
+		// Indexes are assigned by the node, containers at higher indexes of
+		// another node are not the ones indexed before
+		return 0, fmt.Errorf("next index %d of indexer '%s' is beyond the last accepted index of the node, "+
+			"usable range is [%d, %d]; check start_index and that the node is the one indexed before",
+			nextIndex, name, first, last)
+	}
+	if nextIndex >= first {
+		return nextIndex, nil
+	}
+	if !adjust {
+		return 0, fmt.Errorf("container at index %d of indexer '%s' is not available on the node, "+
+			"usable range is [%d, %d]; set start_index to %d or enable adjust_start_index to skip the missing containers",
+			nextIndex, name, first, last, first)
+	}
+	logger.Warn("Indexer '%s' skips containers at indexes %d to %d, which are not available on the node",
+		name, nextIndex, first-1)
+	return first, nil
+}
+
+// Fetches the containers starting at nextIndex and adds them to the batch
+// indexer. Up to write_buffer batches are fetched and persisted together in
+// one transaction; buffering stops at the last accepted container or as soon as
+// the added containers have max_rows_per_transaction rows, the remaining
+// containers of the batch are fetched again by the next call. A container is
+// not split, so a transaction exceeds the limit by the rows of its last
+// container at most. Returns the index of the last added container.
+func (ci *ChainIndexerBase) bufferContainers(ctx context.Context, nextIndex uint64, lastIndex uint64, batchSize int) (uint64, error) {
+	batches := ci.Config.WriteBuffer
+	if batches < 1 {
+		batches = 1
+	}
+	ci.BatchIndexer.Reset(batches * batchSize)
+
+	maxRows := ci.Config.MaxRowsPerTransaction
+	index := nextIndex
+	full := false
+	for i := 0; i < batches && index <= lastIndex && !full; i++ {
+		containers, err := chain.FetchContainerRangeFromIndexer(ctx, ci.Client, index, batchSize)
+		if err != nil {
+			// The node may have been replaced, check the available range in the next run
+			ci.rangeChecked = false
+			return 0, err
+		}
+		if len(containers) == 0 {
+			break
+		}
+		for _, container := range containers {
+			if err := ci.BatchIndexer.AddContainer(ctx, index, container); err != nil {
+				return 0, err
+			}
+			index++
+			if maxRows > 0 && ci.BatchIndexer.Rows() >= maxRows {
+				full = true
+				break
+			}
+		}
+	}
+	if index == nextIndex {
+		return 0, fmt.Errorf("node returned no containers from index %d", nextIndex)
+	}
+	return index - 1, nil
 }
 
 func (ci *ChainIndexerBase) Run() {
@@ -126,13 +248,28 @@ func (ci *ChainIndexerBase) Run() {
 	}
 	ticker := time.NewTicker(ci.Config.Timeout)
 	for range ticker.C {
-		err := ci.IndexBatch()
+		if !ci.FeatureFlags.Enabled(ci.FeatureFlagName) {
+			logger.Debug("%s indexer paused by feature flag", ci.name())
+			continue
+		}
+		err := ci.IndexBatch(context.Background())
 		if err != nil {
-			logger.Error("%s indexer error %v", ci.IndexerName, err)
+			logger.Error("%s indexer error %v", ci.name(), err)
 		}
 	}
 }
 
+// Metrics of indexers of different networks are prefixed by the network name
 func (ci *ChainIndexerBase) InitMetrics(namespace string) {
+	if len(ci.Network) > 0 {
+		namespace = ci.Network + "_" + namespace
+	}
 	ci.metrics = newMetrics(namespace)
 }
+
+func (ci *ChainIndexerBase) name() string {
+	if len(ci.Network) > 0 {
+		return ci.IndexerName + " (" + ci.Network + ")"
+	}
+	return ci.IndexerName
+}
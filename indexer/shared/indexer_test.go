@@ -0,0 +1,134 @@
+//go:build !integration
+// +build !integration
+
+package shared
+
+import (
+	"context"
+	"flare-indexer/indexer/config"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAvailableNextIndex(t *testing.T) {
+	next, err := availableNextIndex("test", 150, 100, 200, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), next)
+
+	// Waiting for the next accepted container
+	next, err = availableNextIndex("test", 201, 100, 200, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(201), next)
+
+	_, err = availableNextIndex("test", 50, 100, 200, false)
+	require.EqualError(t, err, "container at index 50 of indexer 'test' is not available on the node, "+
+		"usable range is [100, 200]; set start_index to 100 or enable adjust_start_index to skip the missing containers")
+
+	next, err = availableNextIndex("test", 50, 100, 200, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), next)
+
+	// Not adjusted, the indexed containers are not on the node
+	_, err = availableNextIndex("test", 202, 100, 200, true)
+	require.ErrorContains(t, err, "usable range is [100, 200]")
+}
+
+// Node with containers at indexes 0 to last
+type testIndexerClient struct {
+	last    uint64
+	fetches int
+}
+
+func (c *testIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	c.fetches++
+	var containers []indexer.Container
+	for i := from; i <= c.last && len(containers) < numToFetch; i++ {
+		containers = append(containers, indexer.Container{Bytes: []byte{byte(i)}})
+	}
+	return containers, nil
+}
+
+func (c *testIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	return indexer.Container{}, c.last, nil
+}
+
+func (c *testIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	return indexer.Container{}, nil
+}
+
+func (c *testIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	return 0, nil
+}
+
+// Batch indexer with rowsPerContainer rows per container
+type testBatchIndexer struct {
+	rowsPerContainer int
+	indexes          []uint64
+}
+
+func (b *testBatchIndexer) Reset(containerLen int) {
+	b.indexes = nil
+}
+
+func (b *testBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
+	b.indexes = append(b.indexes, index)
+	return nil
+}
+
+func (b *testBatchIndexer) Rows() int {
+	return len(b.indexes) * b.rowsPerContainer
+}
+
+func (b *testBatchIndexer) ProcessBatch(ctx context.Context) error {
+	return nil
+}
+
+func (b *testBatchIndexer) PersistEntities(ctx context.Context, db *gorm.DB) error {
+	return nil
+}
+
+func TestBufferContainers(t *testing.T) {
+	client := &testIndexerClient{last: 99}
+	batchIndexer := &testBatchIndexer{rowsPerContainer: 3}
+	ci := &ChainIndexerBase{Client: client, BatchIndexer: batchIndexer}
+
+	// Not buffered
+	last, err := ci.bufferContainers(context.Background(), 10, 99, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(19), last)
+	require.Equal(t, 1, client.fetches)
+
+	// Buffering stops at the last accepted container
+	ci.Config = config.IndexerConfig{WriteBuffer: 4}
+	client.fetches = 0
+	last, err = ci.bufferContainers(context.Background(), 75, 99, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(99), last)
+	require.Equal(t, 3, client.fetches)
+	require.Len(t, batchIndexer.indexes, 25)
+	require.Equal(t, uint64(75), batchIndexer.indexes[0])
+
+	// Buffering stops once the buffered rows reach the limit, 51 rows after
+	// 17 containers of the second batch
+	ci.Config = config.IndexerConfig{WriteBuffer: 4, MaxRowsPerTransaction: 50}
+	client.fetches = 0
+	last, err = ci.bufferContainers(context.Background(), 0, 99, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(16), last)
+	require.Equal(t, 2, client.fetches)
+	require.Equal(t, 51, batchIndexer.Rows())
+
+	// The limit applies within the first batch as well, a container is not split
+	ci.Config = config.IndexerConfig{MaxRowsPerTransaction: 5}
+	last, err = ci.bufferContainers(context.Background(), 0, 99, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), last)
+	require.Equal(t, 6, batchIndexer.Rows())
+
+	_, err = ci.bufferContainers(context.Background(), 100, 99, 10)
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ava-labs/avalanchego/vms/components/avax"
@@ -10,6 +11,9 @@ import (
 type InputOutputIndexer struct {
 	inUpdater InputUpdater
 
+	// Address prefix of the indexed network
+	hrp string
+
 	// Outputs of new transactions in a batch or additional outputs (reward transactions),
 	// outputs should be chain-specific database objects
 	outs []Output
@@ -18,10 +22,11 @@ type InputOutputIndexer struct {
 	ins []Input
 }
 
-// Return new input output indexer
-func NewInputOutputIndexer(inUpdater InputUpdater) *InputOutputIndexer {
+// Return new input output indexer, addresses are formatted with the address prefix hrp
+func NewInputOutputIndexer(inUpdater InputUpdater, hrp string) *InputOutputIndexer {
 	indexer := InputOutputIndexer{
 		inUpdater: inUpdater,
+		hrp:       hrp,
 	}
 	indexer.Reset(0)
 	return &indexer
@@ -39,7 +44,7 @@ func (iox *InputOutputIndexer) AddNewFromBaseTx(
 	tx *avax.BaseTx,
 	creator InputOutputCreator,
 ) error {
-	outs, err := OutputsFromTxOuts(txID, tx.Outs, 0, creator)
+	outs, err := OutputsFromTxOuts(iox.hrp, txID, tx.Outs, 0, creator)
 	if err != nil {
 		return err
 	}
@@ -53,9 +58,9 @@ func (iox *InputOutputIndexer) Add(outs []Output, ins []Input) {
 	iox.ins = append(iox.ins, ins...)
 }
 
-func (iox *InputOutputIndexer) UpdateInputs(inputs []Input) error {
+func (iox *InputOutputIndexer) UpdateInputs(ctx context.Context, inputs []Input) error {
 	list := NewInputList(inputs)
-	notUpdated, err := iox.inUpdater.UpdateInputs(list)
+	notUpdated, err := iox.inUpdater.UpdateInputs(ctx, list)
 	if err != nil {
 		return err
 	}
@@ -65,9 +70,9 @@ func (iox *InputOutputIndexer) UpdateInputs(inputs []Input) error {
 	return nil
 }
 
-func (iox *InputOutputIndexer) ProcessBatch() error {
+func (iox *InputOutputIndexer) ProcessBatch(ctx context.Context) error {
 	iox.inUpdater.CacheOutputs(iox.outs)
-	return iox.UpdateInputs(iox.ins)
+	return iox.UpdateInputs(ctx, iox.ins)
 }
 
 func (iox *InputOutputIndexer) GetIns() []Input {
@@ -77,3 +82,15 @@ func (iox *InputOutputIndexer) GetIns() []Input {
 func (iox *InputOutputIndexer) GetNewOuts() []Output {
 	return iox.outs
 }
+
+// Number of inputs and outputs added since the last reset
+func (iox *InputOutputIndexer) Len() (int, int) {
+	return len(iox.ins), len(iox.outs)
+}
+
+// Remove inputs and outputs added after the given lengths (see Len), e.g., of a
+// transaction which is not indexed
+func (iox *InputOutputIndexer) Truncate(insLen int, outsLen int) {
+	iox.ins = iox.ins[:insLen]
+	iox.outs = iox.outs[:outsLen]
+}
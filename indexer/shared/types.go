@@ -9,14 +9,17 @@ type Output interface {
 	Tx() string    // transaction id of this output
 	Index() uint32 // output index
 	Addr() string  // address
+	Amt() uint64   // amount
 }
 
 type Input interface {
 	OutTx() string    // output transaction id of the input
 	OutIndex() uint32 // index of output transaction
 	Addr() string     // address
+	Amt() uint64      // amount, 0 if not known
 
 	UpdateAddr(string)
+	UpdateAmount(uint64)
 }
 
 // Create chain specific database object from generic TxOutput (TxInput) type, e.g.,
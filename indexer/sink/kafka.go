@@ -0,0 +1,403 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/logger"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+)
+
+const (
+	PChainSinkName = "p_chain_kafka_sink"
+	XChainSinkName = "x_chain_kafka_sink"
+
+	// Header with the database id of the transaction
+	idHeader = "id"
+
+	defaultBatchSize = 100
+	kafkaTimeout     = 30 * time.Second
+)
+
+// Streams every indexed transaction, with its inputs and outputs, to a Kafka
+// topic in the order of database ids. All messages are written to partition 0
+// of the topic, which must have no other writers. P-chain transactions are
+// streamed once they are decided (see pChainSource), so their status does not
+// change after they are streamed.
+//
+// The id of the next transaction to stream and the end offset of the partition
+// after its messages are stored in a checkpoint, which is updated in one
+// statement after each written batch. If the process stops after writing
+// messages but before updating the checkpoint, the number of messages written
+// since is the difference of the end offset of the partition and the stored
+// offset, so the checkpoint is moved past them and no transaction is written
+// twice.
+type kafkaSink struct {
+	name      string
+	enabled   bool
+	timeout   time.Duration
+	batchSize int
+
+	db     sinkDB
+	source txSource
+	topic  topicWriter
+
+	// Set after the checkpoint is reconciled with the end offset of the topic
+	recovered bool
+}
+
+type sinkDB interface {
+	// State of the sink used before checkpoints were stored
+	FetchState(ctx context.Context, name string) (database.State, error)
+	// Returns nil if the sink has no checkpoint yet
+	FetchCheckpoint(ctx context.Context, name string) (*database.KafkaSinkCheckpoint, error)
+	SaveCheckpoint(ctx context.Context, checkpoint *database.KafkaSinkCheckpoint) error
+}
+
+// Transaction to stream, value is serialized as JSON
+type txRecord struct {
+	ID    uint64
+	TxID  string
+	Value interface{}
+}
+
+type txSource interface {
+	// Returns the records of at most limit rows with database id >= fromID and
+	// the id of the last fetched row (0 if there are none). Rows without
+	// transactions are not returned. The records of later calls with the same
+	// fromID start with the records returned before.
+	FetchTxs(ctx context.Context, fromID uint64, limit int) ([]txRecord, uint64, error)
+}
+
+type topicWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	// Offset of the next message written to partition 0
+	EndOffset(ctx context.Context) (int64, error)
+	// Returns the last message of the topic, nil if the topic is empty
+	LastMessage(ctx context.Context) (*kafka.Message, error)
+}
+
+// Returns the P-chain and X-chain sinks of the indexer, sinks of chains
+// without a topic are disabled
+func NewKafkaSinks(ctx indexerctx.IndexerContext) []*kafkaSink {
+	cfg := &ctx.Config().KafkaSink
+	db := sinkDBGorm{db: ctx.DB()}
+	network := ctx.Config().Network
+	return []*kafkaSink{
+		newKafkaSink(PChainSinkName, cfg, cfg.PChainTopic, db, pChainSource{db: ctx.DB(), network: network}),
+		newKafkaSink(XChainSinkName, cfg, cfg.XChainTopic, db, xChainSource{db: ctx.DB(), network: network}),
+	}
+}
+
+func newKafkaSink(name string, cfg *config.KafkaSinkConfig, topic string, db sinkDB, source txSource) *kafkaSink {
+	s := &kafkaSink{
+		name:      name,
+		enabled:   cfg.Enabled && len(topic) > 0,
+		timeout:   cfg.Timeout,
+		batchSize: cfg.BatchSize,
+		db:        db,
+		source:    source,
+	}
+	if s.enabled {
+		s.topic = newKafkaTopicWriter(cfg.Brokers, topic)
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultBatchSize
+	}
+	return s
+}
+
+func (s *kafkaSink) Name() string {
+	return s.name
+}
+
+func (s *kafkaSink) Enabled() bool {
+	return s.enabled
+}
+
+func (s *kafkaSink) Schedule() cronjob.Schedule {
+	return cronjob.NewIntervalSchedule(s.timeout)
+}
+
+func (s *kafkaSink) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func (s *kafkaSink) Call(ctx context.Context) error {
+	checkpoint, err := s.checkpoint(ctx)
+	if err != nil {
+		return err
+	}
+	if !s.recovered {
+		if err := s.recover(ctx, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	records, lastID, err := s.source.FetchTxs(ctx, checkpoint.NextID, s.batchSize)
+	if err != nil {
+		return err
+	}
+	if lastID == 0 {
+		// No new rows
+		return nil
+	}
+
+	if len(records) > 0 {
+		msgs, err := toMessages(records)
+		if err != nil {
+			return err
+		}
+		writeCtx, cancel := context.WithTimeout(ctx, kafkaTimeout)
+		defer cancel()
+		if err := s.topic.WriteMessages(writeCtx, msgs...); err != nil {
+			// Some messages may have been written, compare the offsets again
+			s.recovered = false
+			return err
+		}
+	}
+
+	checkpoint.NextID = lastID + 1
+	checkpoint.Offset += int64(len(records))
+	checkpoint.Updated = time.Now()
+	if err := s.db.SaveCheckpoint(ctx, checkpoint); err != nil {
+		s.recovered = false
+		return err
+	}
+	cronjob.ReportItems(ctx, len(records))
+	logger.Debug("%s streamed %d transactions up to id %d", s.name, len(records), lastID)
+	return nil
+}
+
+// Checkpoint of the sink, a sink without one continues at the position of its
+// state with an unknown offset
+func (s *kafkaSink) checkpoint(ctx context.Context) (*database.KafkaSinkCheckpoint, error) {
+	checkpoint, err := s.db.FetchCheckpoint(ctx, s.name)
+	if err != nil || checkpoint != nil {
+		return checkpoint, err
+	}
+	state, err := s.db.FetchState(ctx, s.name)
+	if err != nil {
+		return nil, err
+	}
+	return &database.KafkaSinkCheckpoint{Name: s.name, NextID: state.NextDBIndex, Offset: -1}, nil
+}
+
+// Moves the checkpoint past the messages written after it was saved, which are
+// the messages between the stored offset and the end offset of the partition
+func (s *kafkaSink) recover(ctx context.Context, checkpoint *database.KafkaSinkCheckpoint) error {
+	ctx, cancel := context.WithTimeout(ctx, kafkaTimeout)
+	defer cancel()
+
+	endOffset, err := s.topic.EndOffset(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case checkpoint.Offset < 0:
+		if err := s.recoverFromLastMessage(ctx, checkpoint); err != nil {
+			return err
+		}
+	case endOffset < checkpoint.Offset:
+		return fmt.Errorf("%s: topic ends at offset %d before the checkpoint at offset %d", s.name, endOffset, checkpoint.Offset)
+	case endOffset > checkpoint.Offset:
+		if err := s.skipWritten(ctx, checkpoint, endOffset-checkpoint.Offset); err != nil {
+			return err
+		}
+		logger.Info("%s recovered %d messages written after the checkpoint, next id %d", s.name, endOffset-checkpoint.Offset, checkpoint.NextID)
+	default:
+		s.recovered = true
+		return nil
+	}
+
+	checkpoint.Offset = endOffset
+	checkpoint.Updated = time.Now()
+	if err := s.db.SaveCheckpoint(ctx, checkpoint); err != nil {
+		return err
+	}
+	s.recovered = true
+	return nil
+}
+
+// Moves the checkpoint past the first written records from its id
+func (s *kafkaSink) skipWritten(ctx context.Context, checkpoint *database.KafkaSinkCheckpoint, written int64) error {
+	nextID := checkpoint.NextID
+	for written > 0 {
+		records, lastID, err := s.source.FetchTxs(ctx, nextID, s.batchSize)
+		if err != nil {
+			return err
+		}
+		if lastID == 0 {
+			return fmt.Errorf("%s: topic has %d more messages than the transactions after id %d", s.name, written, checkpoint.NextID)
+		}
+		if int64(len(records)) >= written {
+			nextID = records[written-1].ID + 1
+			break
+		}
+		written -= int64(len(records))
+		nextID = lastID + 1
+	}
+	checkpoint.NextID = nextID
+	return nil
+}
+
+// The offset of a sink without a checkpoint is not known, its state is moved
+// after the last message of the topic if it was written after the state was
+// updated
+func (s *kafkaSink) recoverFromLastMessage(ctx context.Context, checkpoint *database.KafkaSinkCheckpoint) error {
+	msg, err := s.topic.LastMessage(ctx)
+	if err != nil || msg == nil {
+		return err
+	}
+	lastID, err := messageID(msg)
+	if err != nil {
+		return err
+	}
+	if lastID+1 > checkpoint.NextID {
+		logger.Info("%s recovered state from the last message, next id %d", s.name, lastID+1)
+		checkpoint.NextID = lastID + 1
+	}
+	return nil
+}
+
+func toMessages(records []txRecord) ([]kafka.Message, error) {
+	msgs := make([]kafka.Message, len(records))
+	for i, r := range records {
+		value, err := json.Marshal(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		msgs[i] = kafka.Message{
+			Key:   []byte(r.TxID),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: idHeader, Value: []byte(strconv.FormatUint(r.ID, 10))},
+			},
+		}
+	}
+	return msgs, nil
+}
+
+func messageID(msg *kafka.Message) (uint64, error) {
+	for _, h := range msg.Headers {
+		if h.Key == idHeader {
+			return strconv.ParseUint(string(h.Value), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("message at offset %d has no %s header", msg.Offset, idHeader)
+}
+
+type sinkDBGorm struct {
+	db *gorm.DB
+}
+
+func (g sinkDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, g.db, name)
+}
+
+func (g sinkDBGorm) FetchCheckpoint(ctx context.Context, name string) (*database.KafkaSinkCheckpoint, error) {
+	return database.FetchKafkaSinkCheckpoint(ctx, g.db, name)
+}
+
+func (g sinkDBGorm) SaveCheckpoint(ctx context.Context, checkpoint *database.KafkaSinkCheckpoint) error {
+	return database.SaveKafkaSinkCheckpoint(ctx, g.db, checkpoint)
+}
+
+// Writes messages to partition 0 of a topic
+type kafkaTopicWriter struct {
+	brokers []string
+	topic   string
+	writer  *kafka.Writer
+}
+
+func newKafkaTopicWriter(brokers []string, topic string) *kafkaTopicWriter {
+	return &kafkaTopicWriter{
+		brokers: brokers,
+		topic:   topic,
+		writer: kafka.NewWriter(kafka.WriterConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			Balancer: kafka.BalancerFunc(func(msg kafka.Message, partitions ...int) int {
+				return 0
+			}),
+			// Wait for all in-sync replicas, retries are done by the sink
+			RequiredAcks: -1,
+			MaxAttempts:  1,
+		}),
+	}
+}
+
+func (w *kafkaTopicWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return w.writer.WriteMessages(ctx, msgs...)
+}
+
+func (w *kafkaTopicWriter) EndOffset(ctx context.Context) (int64, error) {
+	var err error
+	for _, broker := range w.brokers {
+		var offset int64
+		offset, err = w.endOffset(ctx, broker)
+		if err == nil {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot read the end offset of topic %s: %w", w.topic, err)
+}
+
+func (w *kafkaTopicWriter) endOffset(ctx context.Context, broker string) (int64, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", broker, w.topic, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn.ReadLastOffset()
+}
+
+func (w *kafkaTopicWriter) LastMessage(ctx context.Context) (*kafka.Message, error) {
+	var err error
+	for _, broker := range w.brokers {
+		var msg *kafka.Message
+		msg, err = w.lastMessage(ctx, broker)
+		if err == nil {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot read the last message of topic %s: %w", w.topic, err)
+}
+
+func (w *kafkaTopicWriter) lastMessage(ctx context.Context, broker string) (*kafka.Message, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", broker, w.topic, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return nil, err
+	}
+	if last <= first {
+		return nil, nil
+	}
+	if _, err := conn.Seek(last-1, kafka.SeekAbsolute); err != nil {
+		return nil, err
+	}
+	msg, err := conn.ReadMessage(10e6)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,185 @@
+//go:build !integration
+// +build !integration
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type testSinkDB struct {
+	state      database.State
+	checkpoint *database.KafkaSinkCheckpoint
+}
+
+func (db *testSinkDB) FetchState(ctx context.Context, name string) (database.State, error) {
+	return db.state, nil
+}
+
+func (db *testSinkDB) FetchCheckpoint(ctx context.Context, name string) (*database.KafkaSinkCheckpoint, error) {
+	if db.checkpoint == nil {
+		return nil, nil
+	}
+	checkpoint := *db.checkpoint
+	return &checkpoint, nil
+}
+
+func (db *testSinkDB) SaveCheckpoint(ctx context.Context, checkpoint *database.KafkaSinkCheckpoint) error {
+	saved := *checkpoint
+	db.checkpoint = &saved
+	return nil
+}
+
+// Rows with ids 1..n, rows with ids in noTx have no transaction
+type testSource struct {
+	n    uint64
+	noTx map[uint64]bool
+}
+
+func (s *testSource) FetchTxs(ctx context.Context, fromID uint64, limit int) ([]txRecord, uint64, error) {
+	if fromID == 0 {
+		fromID = 1
+	}
+	var records []txRecord
+	lastID := uint64(0)
+	for id := fromID; id <= s.n && id < fromID+uint64(limit); id++ {
+		lastID = id
+		if !s.noTx[id] {
+			records = append(records, txRecord{ID: id, TxID: "tx", Value: id})
+		}
+	}
+	return records, lastID, nil
+}
+
+type testTopic struct {
+	msgs []kafka.Message
+	fail bool
+}
+
+func (t *testTopic) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if t.fail {
+		// Partial write
+		t.msgs = append(t.msgs, msgs[0])
+		return errors.New("write failed")
+	}
+	t.msgs = append(t.msgs, msgs...)
+	return nil
+}
+
+func (t *testTopic) EndOffset(ctx context.Context) (int64, error) {
+	return int64(len(t.msgs)), nil
+}
+
+func (t *testTopic) LastMessage(ctx context.Context) (*kafka.Message, error) {
+	if len(t.msgs) == 0 {
+		return nil, nil
+	}
+	return &t.msgs[len(t.msgs)-1], nil
+}
+
+func (t *testTopic) ids(tst *testing.T) []uint64 {
+	ids := make([]uint64, len(t.msgs))
+	for i := range t.msgs {
+		id, err := messageID(&t.msgs[i])
+		require.NoError(tst, err)
+		ids[i] = id
+	}
+	return ids
+}
+
+func newTestSink(db sinkDB, source txSource, topic topicWriter) *kafkaSink {
+	s := newKafkaSink(PChainSinkName, &config.KafkaSinkConfig{BatchSize: 2}, "", db, source)
+	s.topic = topic
+	return s
+}
+
+func TestKafkaSinkStreamsAllTxs(t *testing.T) {
+	db := &testSinkDB{}
+	source := &testSource{n: 5, noTx: map[uint64]bool{3: true}}
+	topic := &testTopic{}
+	s := newTestSink(db, source, topic)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, s.Call(context.Background()))
+	}
+	require.Equal(t, []uint64{1, 2, 4, 5}, topic.ids(t))
+	require.Equal(t, uint64(6), db.checkpoint.NextID)
+	require.Equal(t, int64(4), db.checkpoint.Offset)
+	require.Equal(t, []byte("1"), topic.msgs[0].Value)
+}
+
+func TestKafkaSinkRecoversFromOffsets(t *testing.T) {
+	db := &testSinkDB{}
+	source := &testSource{n: 7, noTx: map[uint64]bool{2: true}}
+	topic := &testTopic{}
+	s := newTestSink(db, source, topic)
+
+	// Rows 1 and 2
+	require.NoError(t, s.Call(context.Background()))
+	require.Equal(t, int64(1), db.checkpoint.Offset)
+
+	// Message 3 is written but the checkpoint is not updated
+	topic.fail = true
+	require.Error(t, s.Call(context.Background()))
+	require.Equal(t, uint64(3), db.checkpoint.NextID)
+
+	topic.fail = false
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Call(context.Background()))
+	}
+	require.Equal(t, []uint64{1, 3, 4, 5, 6, 7}, topic.ids(t))
+	require.Equal(t, uint64(8), db.checkpoint.NextID)
+	require.Equal(t, int64(6), db.checkpoint.Offset)
+
+	// Messages written by a previous process after its last checkpoint
+	source.n = 10
+	topic.msgs = append(topic.msgs, kafkaMessage(8), kafkaMessage(9))
+	s = newTestSink(db, source, topic)
+	require.NoError(t, s.Call(context.Background()))
+	require.Equal(t, []uint64{1, 3, 4, 5, 6, 7, 8, 9, 10}, topic.ids(t))
+	require.Equal(t, int64(9), db.checkpoint.Offset)
+}
+
+func TestKafkaSinkRecoversWithoutCheckpoint(t *testing.T) {
+	// State of a sink before checkpoints were stored, message 4 was written
+	// after the state was updated
+	db := &testSinkDB{state: database.State{NextDBIndex: 4}}
+	source := &testSource{n: 5}
+	topic := &testTopic{msgs: []kafka.Message{kafkaMessage(1), kafkaMessage(2), kafkaMessage(3), kafkaMessage(4)}}
+	s := newTestSink(db, source, topic)
+
+	require.NoError(t, s.Call(context.Background()))
+	require.Equal(t, []uint64{1, 2, 3, 4, 5}, topic.ids(t))
+	require.Equal(t, uint64(6), db.checkpoint.NextID)
+	require.Equal(t, int64(5), db.checkpoint.Offset)
+}
+
+func TestKafkaSinkTopicBehindCheckpoint(t *testing.T) {
+	db := &testSinkDB{checkpoint: &database.KafkaSinkCheckpoint{NextID: 3, Offset: 2}}
+	s := newTestSink(db, &testSource{n: 5}, &testTopic{})
+	require.Error(t, s.Call(context.Background()))
+}
+
+func TestDecidedPChainTxs(t *testing.T) {
+	txs := []database.PChainTx{
+		{Status: database.PChainTxAccepted},
+		{Status: database.PChainTxAborted},
+		{Status: database.PChainTxProposed},
+		{Status: database.PChainTxAccepted},
+	}
+	require.Len(t, decidedPChainTxs(txs), 2)
+	require.Empty(t, decidedPChainTxs(txs[2:]))
+	require.Len(t, decidedPChainTxs(txs[:2]), 2)
+}
+
+func kafkaMessage(id uint64) kafka.Message {
+	msgs, _ := toMessages([]txRecord{{ID: id, TxID: "tx", Value: id}})
+	return msgs[0]
+}
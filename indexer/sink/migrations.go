@@ -0,0 +1,29 @@
+package sink
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/migrations"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	migrations.Container.Add("2023-10-20-00-00", "Create initial state for Kafka sinks", createKafkaSinkStates)
+}
+
+func createKafkaSinkStates(ctx context.Context, db *gorm.DB) error {
+	for _, name := range []string{PChainSinkName, XChainSinkName} {
+		err := database.CreateState(ctx, db, &database.State{
+			Name:           name,
+			NextDBIndex:    0,
+			LastChainIndex: 0,
+			Updated:        time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
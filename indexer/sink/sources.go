@@ -0,0 +1,140 @@
+package sink
+
+import (
+	"context"
+	"flare-indexer/database"
+
+	"gorm.io/gorm"
+)
+
+// Message value of a P-chain transaction
+type pChainTxValue struct {
+	Network string                    `json:"network,omitempty"`
+	Tx      database.PChainTx         `json:"tx"`
+	Inputs  []database.PChainTxInput  `json:"inputs"`
+	Outputs []database.PChainTxOutput `json:"outputs"`
+}
+
+// Message value of an X-chain transaction
+type xChainTxValue struct {
+	Network string                    `json:"network,omitempty"`
+	Tx      database.XChainTx         `json:"tx"`
+	Inputs  []database.XChainTxInput  `json:"inputs"`
+	Outputs []database.XChainTxOutput `json:"outputs"`
+}
+
+type pChainSource struct {
+	db      *gorm.DB
+	network string
+}
+
+// Transactions of a proposal block are fetched once the proposal is decided,
+// i.e. the rows stop before the first transaction with status PROPOSED, so
+// that the status of a streamed transaction does not change. The option block
+// follows the proposal block, so the delay is short.
+func (s pChainSource) FetchTxs(ctx context.Context, fromID uint64, limit int) ([]txRecord, uint64, error) {
+	txs, err := database.FetchPChainTxsFromID(ctx, s.db, fromID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	txs = decidedPChainTxs(txs)
+	if len(txs) == 0 {
+		return nil, 0, nil
+	}
+
+	values := make(map[string]*pChainTxValue, len(txs))
+	txIDs := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		// Commit and abort blocks have no transaction
+		if tx.TxID == nil {
+			continue
+		}
+		values[*tx.TxID] = &pChainTxValue{
+			Network: s.network,
+			Tx:      tx,
+			Inputs:  []database.PChainTxInput{},
+			Outputs: []database.PChainTxOutput{},
+		}
+		txIDs = append(txIDs, *tx.TxID)
+	}
+	lastID := txs[len(txs)-1].ID
+	if len(txIDs) == 0 {
+		return nil, lastID, nil
+	}
+
+	ins, err := database.FetchPChainTxInputs(ctx, s.db, txIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, in := range ins {
+		values[in.TxID].Inputs = append(values[in.TxID].Inputs, in)
+	}
+	outs, err := database.FetchPChainTxOutputs(ctx, s.db, txIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, out := range outs {
+		values[out.TxID].Outputs = append(values[out.TxID].Outputs, out)
+	}
+
+	records := make([]txRecord, len(txIDs))
+	for i, txID := range txIDs {
+		records[i] = txRecord{ID: values[txID].Tx.ID, TxID: txID, Value: values[txID]}
+	}
+	return records, lastID, nil
+}
+
+// Leading transactions which are not proposed
+func decidedPChainTxs(txs []database.PChainTx) []database.PChainTx {
+	for i := range txs {
+		if txs[i].Status == database.PChainTxProposed {
+			return txs[:i]
+		}
+	}
+	return txs
+}
+
+type xChainSource struct {
+	db      *gorm.DB
+	network string
+}
+
+func (s xChainSource) FetchTxs(ctx context.Context, fromID uint64, limit int) ([]txRecord, uint64, error) {
+	txs, err := database.FetchXChainTxsFromID(ctx, s.db, fromID, limit)
+	if err != nil || len(txs) == 0 {
+		return nil, 0, err
+	}
+
+	values := make(map[string]*xChainTxValue, len(txs))
+	txIDs := make([]string, len(txs))
+	for i, tx := range txs {
+		values[tx.TxID] = &xChainTxValue{
+			Network: s.network,
+			Tx:      tx,
+			Inputs:  []database.XChainTxInput{},
+			Outputs: []database.XChainTxOutput{},
+		}
+		txIDs[i] = tx.TxID
+	}
+
+	ins, err := database.FetchXChainTxInputs(ctx, s.db, txIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, in := range ins {
+		values[in.TxID].Inputs = append(values[in.TxID].Inputs, in)
+	}
+	outs, err := database.FetchXChainTxOutputs(ctx, s.db, txIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, out := range outs {
+		values[out.TxID].Outputs = append(values[out.TxID].Outputs, out)
+	}
+
+	records := make([]txRecord, len(txIDs))
+	for i, txID := range txIDs {
+		records[i] = txRecord{ID: values[txID].Tx.ID, TxID: txID, Value: values[txID]}
+	}
+	return records, txs[len(txs)-1].ID, nil
+}
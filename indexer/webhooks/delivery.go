@@ -0,0 +1,162 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"flare-indexer/database"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	deliveryCronjobName = "webhook_delivery"
+
+	// Headers of webhook calls
+	SignatureHeader = "X-Signature-256"
+	TimestampHeader = "X-Webhook-Timestamp"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	maxErrorLength = 256
+)
+
+// Calls the webhooks of pending deliveries, failed calls are retried with
+// exponential backoff
+type deliveryCronjob struct {
+	enabled       bool
+	timeout       time.Duration
+	batchSize     int
+	maxAttempts   int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+	hooks         *hookSource
+	db            deliveryDB
+	client        *http.Client
+	time          utils.ShiftedTime
+}
+
+type deliveryDB interface {
+	FetchDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]database.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error
+}
+
+func (c *deliveryCronjob) Name() string {
+	return deliveryCronjobName
+}
+
+func (c *deliveryCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *deliveryCronjob) Schedule() cronjob.Schedule {
+	return cronjob.NewIntervalSchedule(c.timeout)
+}
+
+func (c *deliveryCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func (c *deliveryCronjob) Call(ctx context.Context) error {
+	deliveries, err := c.db.FetchDueWebhookDeliveries(ctx, c.time.Now(), c.batchSize)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+	hooks, err := c.hooks.load(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		if err := c.deliver(ctx, hooks, &deliveries[i]); err != nil {
+			return err
+		}
+	}
+	cronjob.ReportItems(ctx, len(deliveries))
+	return nil
+}
+
+func (c *deliveryCronjob) deliver(ctx context.Context, hooks map[string]*hook, d *database.WebhookDelivery) error {
+	h, ok := hooks[d.Webhook]
+	var status int
+	var err error
+	if ok {
+		status, err = c.send(ctx, h, d)
+	} else {
+		err = fmt.Errorf("webhook is not configured")
+	}
+
+	now := c.time.Now()
+	d.Attempts++
+	d.Updated = now
+	d.ResponseStatus = status
+	switch {
+	case err == nil:
+		d.Status = database.WebhookDeliveryDelivered
+		d.LastError = ""
+	case !ok || d.Attempts >= c.maxAttempts:
+		d.Status = database.WebhookDeliveryFailed
+		d.LastError = utils.TruncateString(err.Error(), maxErrorLength)
+		logger.Warn("Webhook %s delivery %d failed: %v", d.Webhook, d.ID, err)
+	default:
+		d.NextAttempt = now.Add(c.backoff(d.Attempts))
+		d.LastError = utils.TruncateString(err.Error(), maxErrorLength)
+		logger.Debug("Webhook %s delivery %d attempt %d failed: %v", d.Webhook, d.ID, d.Attempts, err)
+	}
+	return c.db.UpdateWebhookDelivery(ctx, d)
+}
+
+// Delay after the given number of failed attempts
+func (c *deliveryCronjob) backoff(attempts int) time.Duration {
+	delay := c.retryDelay
+	for i := 1; i < attempts && delay < c.maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.maxRetryDelay {
+		delay = c.maxRetryDelay
+	}
+	return delay
+}
+
+// Posts the payload and returns the response status
+func (c *deliveryCronjob) send(ctx context.Context, h *hook, d *database.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(c.time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(d.ID, 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Signature(h.Secret, timestamp, []byte(d.Payload)))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Signature of a webhook call, "sha256=" followed by the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret as the key.
+// Receivers should compare it with the signature header and reject old
+// timestamps to prevent replays.
+func Signature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
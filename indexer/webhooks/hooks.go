@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/logger"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Transactions with inputs or outputs of one of the addresses
+	AddressActivityEvent = "address_activity"
+	// New delegations to one of the node IDs
+	DelegationEvent = "delegation"
+)
+
+type hook struct {
+	config.WebhookConfig
+	addresses map[string]bool
+	nodeIDs   map[string]bool
+}
+
+// JSON body of webhook calls
+type Payload struct {
+	Webhook     string    `json:"webhook"`
+	Event       string    `json:"event"`
+	Network     string    `json:"network,omitempty"`
+	TxID        string    `json:"txId"`
+	TxType      string    `json:"txType"`
+	BlockHeight uint64    `json:"blockHeight"`
+	Timestamp   time.Time `json:"timestamp"`
+
+	// Address activity: sum of the inputs from and the outputs to the address
+	Address  string `json:"address,omitempty"`
+	Sent     uint64 `json:"sent,omitempty"`
+	Received uint64 `json:"received,omitempty"`
+
+	// Delegation
+	NodeID    string     `json:"nodeId,omitempty"`
+	Weight    uint64     `json:"weight,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   *time.Time `json:"endTime,omitempty"`
+}
+
+// Webhooks of the config and the subscriptions registered with the admin routes
+// of the services, loaded at each call of the cronjobs
+type hookSource struct {
+	cfgs []config.WebhookConfig
+	db   subscriptionDB
+}
+
+type subscriptionDB interface {
+	FetchWebhookSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error)
+}
+
+// Subscriptions with the name of a webhook of the config are ignored
+func (s *hookSource) load(ctx context.Context) (map[string]*hook, error) {
+	subscriptions, err := s.db.FetchWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfgs := append([]config.WebhookConfig{}, s.cfgs...)
+	configured := make(map[string]bool, len(s.cfgs))
+	for _, cfg := range s.cfgs {
+		configured[cfg.Name] = true
+	}
+	for _, sub := range subscriptions {
+		if configured[sub.Name] {
+			logger.Warn("Webhook subscription %s is ignored, a webhook of the config has the same name", sub.Name)
+			continue
+		}
+		cfgs = append(cfgs, config.WebhookConfig{
+			Name:      sub.Name,
+			URL:       sub.URL,
+			Secret:    sub.Secret,
+			Event:     sub.Event,
+			Addresses: splitList(sub.Addresses),
+			NodeIDs:   splitList(sub.NodeIDs),
+		})
+	}
+	return newHooks(cfgs)
+}
+
+func splitList(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func newHooks(cfgs []config.WebhookConfig) (map[string]*hook, error) {
+	hooks := make(map[string]*hook, len(cfgs))
+	for _, cfg := range cfgs {
+		if len(cfg.Name) == 0 || len(cfg.URL) == 0 {
+			return nil, fmt.Errorf("webhooks must have a name and url")
+		}
+		if _, ok := hooks[cfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate webhook name '%s'", cfg.Name)
+		}
+		h := &hook{
+			WebhookConfig: cfg,
+			addresses:     make(map[string]bool),
+			nodeIDs:       make(map[string]bool),
+		}
+		switch cfg.Event {
+		case AddressActivityEvent:
+			for _, a := range cfg.Addresses {
+				h.addresses[strings.TrimPrefix(a, "P-")] = true
+			}
+		case DelegationEvent:
+			for _, n := range cfg.NodeIDs {
+				h.nodeIDs[n] = true
+			}
+		default:
+			return nil, fmt.Errorf("webhook '%s' has unknown event '%s', expected '%s' or '%s'",
+				cfg.Name, cfg.Event, AddressActivityEvent, DelegationEvent)
+		}
+		hooks[cfg.Name] = h
+	}
+	return hooks, nil
+}
+
+// Indexed transaction with its inputs and outputs
+type indexedTx struct {
+	tx   *database.PChainTx
+	ins  []database.PChainTxInput
+	outs []database.PChainTxOutput
+}
+
+// Returns the payloads of the events of tx matching the hook
+func (h *hook) match(tx *indexedTx) []Payload {
+	base := Payload{
+		Webhook:     h.Name,
+		Event:       h.Event,
+		TxID:        *tx.tx.TxID,
+		TxType:      string(tx.tx.Type),
+		BlockHeight: tx.tx.BlockHeight,
+		Timestamp:   tx.tx.Timestamp.UTC(),
+	}
+
+	var payloads []Payload
+	switch h.Event {
+	case AddressActivityEvent:
+		sent := make(map[string]uint64)
+		received := make(map[string]uint64)
+		seen := make(map[string]bool)
+		var addresses []string
+		add := func(address string, amounts map[string]uint64, amount uint64) {
+			if !h.addresses[address] {
+				return
+			}
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+			amounts[address] += amount
+		}
+		for _, in := range tx.ins {
+			add(in.Address, sent, in.Amount)
+		}
+		for _, out := range tx.outs {
+			add(out.Address, received, out.Amount)
+		}
+		for _, address := range addresses {
+			p := base
+			p.Address = address
+			p.Sent = sent[address]
+			p.Received = received[address]
+			payloads = append(payloads, p)
+		}
+	case DelegationEvent:
+		if tx.tx.Type.IsDelegatorTx() && h.nodeIDs[tx.tx.NodeID] {
+			p := base
+			p.NodeID = tx.tx.NodeID
+			p.Weight = tx.tx.Weight
+			p.StartTime = utcTime(tx.tx.StartTime)
+			p.EndTime = utcTime(tx.tx.EndTime)
+			payloads = append(payloads, p)
+		}
+	}
+	return payloads
+}
+
+func newDelivery(p *Payload, now time.Time) (*database.WebhookDelivery, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return &database.WebhookDelivery{
+		Webhook:     p.Webhook,
+		Event:       p.Event,
+		TxID:        p.TxID,
+		Payload:     string(body),
+		Status:      database.WebhookDeliveryPending,
+		NextAttempt: now,
+		Created:     now,
+		Updated:     now,
+	}, nil
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
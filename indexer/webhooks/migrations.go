@@ -0,0 +1,23 @@
+package webhooks
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/migrations"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	migrations.Container.Add("2023-10-25-00-00", "Create initial state for webhook queue", createQueueState)
+}
+
+func createQueueState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
+		Name:           queueStateName,
+		NextDBIndex:    0,
+		LastChainIndex: 0,
+		Updated:        time.Now(),
+	})
+}
@@ -0,0 +1,149 @@
+package webhooks
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/cronjob"
+	"flare-indexer/logger"
+	"flare-indexer/utils"
+	"time"
+)
+
+const (
+	queueCronjobName = "webhook_queue"
+	queueStateName   = "webhook_queue"
+)
+
+// Matches the indexed P-chain transactions against the webhooks and queues
+// a delivery for each event. The deliveries and the id of the next
+// transaction are stored in one database transaction, so each event is queued
+// exactly once. Only accepted transactions are matched: the queue waits for
+// the decision of a proposal block and skips aborted transactions.
+type queueCronjob struct {
+	enabled   bool
+	timeout   time.Duration
+	batchSize int
+	network   string
+	hooks     *hookSource
+	db        queueDB
+	time      utils.ShiftedTime
+}
+
+type queueDB interface {
+	FetchState(ctx context.Context, name string) (database.State, error)
+	FetchPChainTxsFromID(ctx context.Context, fromID uint64, limit int) ([]database.PChainTx, error)
+	FetchPChainTxInputs(ctx context.Context, txIDs []string) ([]database.PChainTxInput, error)
+	FetchPChainTxOutputs(ctx context.Context, txIDs []string) ([]database.PChainTxOutput, error)
+	QueueWebhookDeliveries(ctx context.Context, deliveries []*database.WebhookDelivery, state *database.State) error
+}
+
+func (c *queueCronjob) Name() string {
+	return queueCronjobName
+}
+
+func (c *queueCronjob) Enabled() bool {
+	return c.enabled
+}
+
+func (c *queueCronjob) Schedule() cronjob.Schedule {
+	return cronjob.NewIntervalSchedule(c.timeout)
+}
+
+func (c *queueCronjob) OnStart(ctx context.Context) error {
+	return nil
+}
+
+func (c *queueCronjob) Call(ctx context.Context) error {
+	state, err := c.db.FetchState(ctx, queueStateName)
+	if err != nil {
+		return err
+	}
+	txs, err := c.db.FetchPChainTxsFromID(ctx, state.NextDBIndex, c.batchSize)
+	if err != nil {
+		return err
+	}
+	txs = decidedTxs(txs)
+	if len(txs) == 0 {
+		return nil
+	}
+
+	hooks, err := c.hooks.load(ctx)
+	if err != nil {
+		return err
+	}
+	indexed, err := c.fetchInputsOutputs(ctx, txs)
+	if err != nil {
+		return err
+	}
+
+	now := c.time.Now()
+	var deliveries []*database.WebhookDelivery
+	for _, tx := range indexed {
+		for _, h := range hooks {
+			for _, p := range h.match(tx) {
+				p.Network = c.network
+				d, err := newDelivery(&p, now)
+				if err != nil {
+					return err
+				}
+				deliveries = append(deliveries, d)
+			}
+		}
+	}
+
+	lastID := txs[len(txs)-1].ID
+	state.Update(lastID+1, lastID)
+	if err := c.db.QueueWebhookDeliveries(ctx, deliveries, &state); err != nil {
+		return err
+	}
+	cronjob.ReportItems(ctx, len(deliveries))
+	if len(deliveries) > 0 {
+		logger.Info("Queued %d webhook deliveries", len(deliveries))
+	}
+	return nil
+}
+
+// The transactions before the first transaction of an undecided proposal block
+func decidedTxs(txs []database.PChainTx) []database.PChainTx {
+	for i := range txs {
+		if txs[i].Status == database.PChainTxProposed {
+			return txs[:i]
+		}
+	}
+	return txs
+}
+
+func (c *queueCronjob) fetchInputsOutputs(ctx context.Context, txs []database.PChainTx) ([]*indexedTx, error) {
+	byID := make(map[string]*indexedTx, len(txs))
+	indexed := make([]*indexedTx, 0, len(txs))
+	txIDs := make([]string, 0, len(txs))
+	for i := range txs {
+		// Commit and abort blocks have no transaction
+		if txs[i].TxID == nil || txs[i].Status == database.PChainTxAborted {
+			continue
+		}
+		tx := &indexedTx{tx: &txs[i]}
+		byID[*txs[i].TxID] = tx
+		indexed = append(indexed, tx)
+		txIDs = append(txIDs, *txs[i].TxID)
+	}
+	if len(txIDs) == 0 {
+		return nil, nil
+	}
+
+	ins, err := c.db.FetchPChainTxInputs(ctx, txIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, in := range ins {
+		byID[in.TxID].ins = append(byID[in.TxID].ins, in)
+	}
+	outs, err := c.db.FetchPChainTxOutputs(ctx, txIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, out := range outs {
+		byID[out.TxID].outs = append(byID[out.TxID].outs, out)
+	}
+	return indexed, nil
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/indexer/cronjob"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Returns the cronjobs queueing and delivering the events of the webhooks in
+// the config and of the subscriptions of the services
+func NewCronjobs(ctx indexerctx.IndexerContext) ([]cronjob.Cronjob, error) {
+	cfg := &ctx.Config().Webhooks
+	if _, err := newHooks(cfg.Hooks); err != nil {
+		return nil, err
+	}
+	db := webhooksDBGorm{db: ctx.DB()}
+	hooks := &hookSource{cfgs: cfg.Hooks, db: db}
+	return []cronjob.Cronjob{
+		newQueueCronjob(cfg, ctx.Config().Network, hooks, db, cfg.Enabled),
+		newDeliveryCronjob(cfg, hooks, db, cfg.Enabled),
+	}, nil
+}
+
+func newQueueCronjob(cfg *config.WebhooksConfig, network string, hooks *hookSource, db queueDB, enabled bool) *queueCronjob {
+	return &queueCronjob{
+		enabled:   enabled,
+		timeout:   cfg.Timeout,
+		batchSize: cfg.BatchSize,
+		network:   network,
+		hooks:     hooks,
+		db:        db,
+	}
+}
+
+func newDeliveryCronjob(cfg *config.WebhooksConfig, hooks *hookSource, db deliveryDB, enabled bool) *deliveryCronjob {
+	return &deliveryCronjob{
+		enabled:       enabled,
+		timeout:       cfg.Timeout,
+		batchSize:     cfg.BatchSize,
+		maxAttempts:   cfg.MaxAttempts,
+		retryDelay:    cfg.RetryDelay,
+		maxRetryDelay: cfg.MaxRetryDelay,
+		hooks:         hooks,
+		db:            db,
+		client:        &http.Client{Timeout: cfg.RequestTimeout},
+	}
+}
+
+type webhooksDBGorm struct {
+	db *gorm.DB
+}
+
+func (g webhooksDBGorm) FetchState(ctx context.Context, name string) (database.State, error) {
+	return database.FetchState(ctx, g.db, name)
+}
+
+func (g webhooksDBGorm) FetchPChainTxsFromID(ctx context.Context, fromID uint64, limit int) ([]database.PChainTx, error) {
+	return database.FetchPChainTxsFromID(ctx, g.db, fromID, limit)
+}
+
+func (g webhooksDBGorm) FetchPChainTxInputs(ctx context.Context, txIDs []string) ([]database.PChainTxInput, error) {
+	return database.FetchPChainTxInputs(ctx, g.db, txIDs)
+}
+
+func (g webhooksDBGorm) FetchPChainTxOutputs(ctx context.Context, txIDs []string) ([]database.PChainTxOutput, error) {
+	return database.FetchPChainTxOutputs(ctx, g.db, txIDs)
+}
+
+func (g webhooksDBGorm) QueueWebhookDeliveries(ctx context.Context, deliveries []*database.WebhookDelivery, state *database.State) error {
+	return database.DoInTransaction(ctx, g.db,
+		func(db *gorm.DB) error { return database.CreateWebhookDeliveries(ctx, db, deliveries) },
+		func(db *gorm.DB) error { return database.UpdateState(ctx, db, state) },
+	)
+}
+
+func (g webhooksDBGorm) FetchDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]database.WebhookDelivery, error) {
+	return database.FetchDueWebhookDeliveries(ctx, g.db, now, limit)
+}
+
+func (g webhooksDBGorm) FetchWebhookSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error) {
+	return database.FetchWebhookSubscriptions(ctx, g.db)
+}
+
+func (g webhooksDBGorm) UpdateWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	return database.UpdateWebhookDelivery(ctx, g.db, delivery)
+}
+
+// Checks the webhooks of the config without calling them, used by the config
+// check command
+func CheckConfig(cfg *config.WebhooksConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	_, err := newHooks(cfg.Hooks)
+	return err
+}
@@ -0,0 +1,264 @@
+//go:build !integration
+// +build !integration
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
+)
+
+var testNow = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type testDB struct {
+	state      database.State
+	txs        []database.PChainTx
+	ins        []database.PChainTxInput
+	outs       []database.PChainTxOutput
+	deliveries []*database.WebhookDelivery
+	// Subscriptions of the admin routes
+	subscriptions []database.WebhookSubscription
+}
+
+func (db *testDB) FetchWebhookSubscriptions(ctx context.Context) ([]database.WebhookSubscription, error) {
+	return db.subscriptions, nil
+}
+
+func (db *testDB) FetchState(ctx context.Context, name string) (database.State, error) {
+	return db.state, nil
+}
+
+func (db *testDB) FetchPChainTxsFromID(ctx context.Context, fromID uint64, limit int) ([]database.PChainTx, error) {
+	var txs []database.PChainTx
+	for _, tx := range db.txs {
+		if tx.ID >= fromID && len(txs) < limit {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+func (db *testDB) FetchPChainTxInputs(ctx context.Context, txIDs []string) ([]database.PChainTxInput, error) {
+	var ins []database.PChainTxInput
+	for _, in := range db.ins {
+		if slices.Contains(txIDs, in.TxID) {
+			ins = append(ins, in)
+		}
+	}
+	return ins, nil
+}
+
+func (db *testDB) FetchPChainTxOutputs(ctx context.Context, txIDs []string) ([]database.PChainTxOutput, error) {
+	var outs []database.PChainTxOutput
+	for _, out := range db.outs {
+		if slices.Contains(txIDs, out.TxID) {
+			outs = append(outs, out)
+		}
+	}
+	return outs, nil
+}
+
+func (db *testDB) QueueWebhookDeliveries(ctx context.Context, deliveries []*database.WebhookDelivery, state *database.State) error {
+	for _, d := range deliveries {
+		d.ID = uint64(len(db.deliveries) + 1)
+		db.deliveries = append(db.deliveries, d)
+	}
+	db.state = *state
+	return nil
+}
+
+func (db *testDB) FetchDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]database.WebhookDelivery, error) {
+	var due []database.WebhookDelivery
+	for _, d := range db.deliveries {
+		if d.Status == database.WebhookDeliveryPending && !d.NextAttempt.After(now) {
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (db *testDB) UpdateWebhookDelivery(ctx context.Context, delivery *database.WebhookDelivery) error {
+	*db.deliveries[delivery.ID-1] = *delivery
+	return nil
+}
+
+func pString(s string) *string {
+	return &s
+}
+
+func testConfig(url string) *config.WebhooksConfig {
+	return &config.WebhooksConfig{
+		BatchSize:     10,
+		MaxAttempts:   3,
+		RetryDelay:    time.Minute,
+		MaxRetryDelay: time.Hour,
+		Hooks: []config.WebhookConfig{
+			{
+				Name:      "deposits",
+				URL:       url,
+				Secret:    "secret",
+				Event:     AddressActivityEvent,
+				Addresses: []string{"P-flare1a"},
+			},
+			{
+				Name:    "delegations",
+				URL:     url,
+				Secret:  "secret",
+				Event:   DelegationEvent,
+				NodeIDs: []string{"NodeID-1"},
+			},
+		},
+	}
+}
+
+func newTestDB() *testDB {
+	return &testDB{
+		txs: []database.PChainTx{
+			{BaseEntity: database.BaseEntity{ID: 1}, TxID: pString("tx1"), Type: database.PChainImportTx},
+			{BaseEntity: database.BaseEntity{ID: 2}},
+			{BaseEntity: database.BaseEntity{ID: 3}, TxID: pString("tx3"), Type: database.PChainAddDelegatorTx,
+				NodeID: "NodeID-1", Weight: 100},
+			{BaseEntity: database.BaseEntity{ID: 4}, TxID: pString("tx4"), Type: database.PChainAddDelegatorTx,
+				NodeID: "NodeID-2", Weight: 100},
+		},
+		ins: []database.PChainTxInput{
+			{TxInput: database.TxInput{TxID: "tx3", Address: "flare1a", Amount: 100}},
+		},
+		outs: []database.PChainTxOutput{
+			{TxOutput: database.TxOutput{TxID: "tx1", Address: "flare1a", Amount: 10}},
+			{TxOutput: database.TxOutput{TxID: "tx1", Address: "flare1a", Amount: 5}},
+			{TxOutput: database.TxOutput{TxID: "tx1", Address: "flare1b", Amount: 7}},
+		},
+	}
+}
+
+func TestQueue(t *testing.T) {
+	cfg := testConfig("http://localhost")
+	db := newTestDB()
+	c := newQueueCronjob(cfg, "flare", &hookSource{cfgs: cfg.Hooks, db: db}, db, true)
+	c.time.SetNow(testNow)
+
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, uint64(5), db.state.NextDBIndex)
+
+	payloads := make(map[string]Payload)
+	for _, d := range db.deliveries {
+		var p Payload
+		require.NoError(t, json.Unmarshal([]byte(d.Payload), &p))
+		payloads[p.Webhook+"/"+p.TxID] = p
+	}
+	require.Len(t, payloads, 3)
+	require.Equal(t, uint64(15), payloads["deposits/tx1"].Received)
+	require.Equal(t, uint64(100), payloads["deposits/tx3"].Sent)
+	require.Equal(t, "flare", payloads["deposits/tx3"].Network)
+	require.Equal(t, uint64(100), payloads["delegations/tx3"].Weight)
+
+	// No new transactions
+	require.NoError(t, c.Call(context.Background()))
+	require.Len(t, db.deliveries, 3)
+}
+
+func TestQueueDecidedTxs(t *testing.T) {
+	cfg := testConfig("http://localhost")
+	db := newTestDB()
+	db.txs[0].Status = database.PChainTxAborted
+	db.txs[2].Status = database.PChainTxProposed
+	c := newQueueCronjob(cfg, "flare", &hookSource{cfgs: cfg.Hooks, db: db}, db, true)
+	c.time.SetNow(testNow)
+
+	// The aborted tx1 is skipped and the queue waits for the decision of tx3
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, uint64(3), db.state.NextDBIndex)
+	require.Empty(t, db.deliveries)
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, uint64(3), db.state.NextDBIndex)
+
+	db.txs[2].Status = database.PChainTxAccepted
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, uint64(5), db.state.NextDBIndex)
+	require.Len(t, db.deliveries, 2)
+}
+
+func TestQueueSubscriptions(t *testing.T) {
+	cfg := testConfig("http://localhost")
+	db := newTestDB()
+	db.subscriptions = []database.WebhookSubscription{
+		{Name: "node2", URL: "http://localhost", Event: DelegationEvent, NodeIDs: "NodeID-2,NodeID-3"},
+		// Shadowed by the webhook of the config
+		{Name: "deposits", URL: "http://localhost", Event: AddressActivityEvent, Addresses: "flare1b"},
+	}
+	c := newQueueCronjob(cfg, "flare", &hookSource{cfgs: cfg.Hooks, db: db}, db, true)
+	c.time.SetNow(testNow)
+
+	require.NoError(t, c.Call(context.Background()))
+	webhooks := make(map[string]int)
+	for _, d := range db.deliveries {
+		webhooks[d.Webhook+"/"+d.TxID]++
+	}
+	require.Equal(t, map[string]int{"deposits/tx1": 1, "deposits/tx3": 1, "delegations/tx3": 1, "node2/tx4": 1}, webhooks)
+}
+
+func TestDelivery(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		require.Equal(t, Signature("secret", r.Header.Get(TimestampHeader), body), r.Header.Get(SignatureHeader))
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	db := &testDB{}
+	d, err := newDelivery(&Payload{Webhook: "deposits", TxID: "tx1"}, testNow)
+	require.NoError(t, err)
+	db.QueueWebhookDeliveries(context.Background(), []*database.WebhookDelivery{d}, &database.State{})
+
+	c := newDeliveryCronjob(cfg, &hookSource{cfgs: cfg.Hooks, db: db}, db, true)
+	c.time.SetNow(testNow)
+
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, database.WebhookDeliveryPending, db.deliveries[0].Status)
+	require.Equal(t, http.StatusInternalServerError, db.deliveries[0].ResponseStatus)
+	require.WithinDuration(t, testNow.Add(time.Minute), db.deliveries[0].NextAttempt, time.Second)
+
+	// Not due yet
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, 1, calls)
+
+	c.time.SetNow(testNow.Add(time.Minute + time.Second))
+	require.NoError(t, c.Call(context.Background()))
+	require.Equal(t, database.WebhookDeliveryDelivered, db.deliveries[0].Status)
+	require.Equal(t, 2, db.deliveries[0].Attempts)
+}
+
+func TestBackoff(t *testing.T) {
+	c := newDeliveryCronjob(&config.WebhooksConfig{RetryDelay: time.Minute, MaxRetryDelay: 5 * time.Minute}, nil, nil, true)
+	require.Equal(t, time.Minute, c.backoff(1))
+	require.Equal(t, 2*time.Minute, c.backoff(2))
+	require.Equal(t, 4*time.Minute, c.backoff(3))
+	require.Equal(t, 5*time.Minute, c.backoff(4))
+	require.Equal(t, 5*time.Minute, c.backoff(40))
+}
+
+func TestNewHooksErrors(t *testing.T) {
+	_, err := newHooks([]config.WebhookConfig{{Name: "a", URL: "http://localhost", Event: "transfer"}})
+	require.Error(t, err)
+	_, err = newHooks([]config.WebhookConfig{
+		{Name: "a", URL: "http://localhost", Event: DelegationEvent},
+		{Name: "a", URL: "http://localhost", Event: DelegationEvent},
+	})
+	require.Error(t, err)
+}
@@ -1,8 +1,9 @@
 package xchain
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/logger"
 	"flare-indexer/utils"
@@ -10,10 +11,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/indexer"
-	"github.com/ava-labs/avalanchego/snow/engine/avalanche/vertex"
 	"github.com/ava-labs/avalanchego/vms/avm/txs"
-	"github.com/ava-labs/avalanchego/wallet/chain/x"
 	"gorm.io/gorm"
 )
 
@@ -21,23 +21,40 @@ import (
 type txBatchIndexer struct {
 	db     *gorm.DB
 	client chain.IndexerClient
+	parser *chain.Parser
 
 	inOutIndexer *shared.InputOutputIndexer
 	newTxs       []*database.XChainTx
 	newVertices  []*database.XChainVtx
+
+	// Filter of indexed transaction types, nil to index all types
+	filter *shared.TxTypeFilter
+	// Asset of the fees, ids.Empty to count all assets
+	feeAssetID ids.ID
 }
 
 func NewXChainBatchIndexer(
-	ctx context.IndexerContext,
+	ctx indexerctx.IndexerContext,
 	client chain.IndexerClient,
 	txClient chain.IndexerClient,
 ) *txBatchIndexer {
 	updater := newXChainInputUpdater(ctx, txClient)
+	return newTxBatchIndexer(ctx.DB(), client, ctx.Parser(), updater, ctx.Config().Chain.ChainAddressHRP)
+}
+
+func newTxBatchIndexer(
+	db *gorm.DB,
+	client chain.IndexerClient,
+	parser *chain.Parser,
+	updater shared.InputUpdater,
+	hrp string,
+) *txBatchIndexer {
 	return &txBatchIndexer{
-		db:     ctx.DB(),
+		db:     db,
 		client: client,
+		parser: parser,
 
-		inOutIndexer: shared.NewInputOutputIndexer(updater),
+		inOutIndexer: shared.NewInputOutputIndexer(updater, hrp),
 		newTxs:       make([]*database.XChainTx, 0),
 	}
 }
@@ -48,8 +65,13 @@ func (xi *txBatchIndexer) Reset(containerLen int) {
 	xi.inOutIndexer.Reset(containerLen)
 }
 
-func (xi *txBatchIndexer) AddContainer(index uint64, container indexer.Container) error {
-	vtx, err := vertex.Parse(container.Bytes)
+func (xi *txBatchIndexer) Rows() int {
+	ins, outs := xi.inOutIndexer.Len()
+	return len(xi.newVertices) + len(xi.newTxs) + ins + outs
+}
+
+func (xi *txBatchIndexer) AddContainer(ctx context.Context, index uint64, container indexer.Container) error {
+	vtx, err := xi.parser.ParseXChainVertex(container.Bytes)
 	if err != nil {
 		return err
 	}
@@ -57,37 +79,38 @@ func (xi *txBatchIndexer) AddContainer(index uint64, container indexer.Container
 		return fmt.Errorf("only one vertex parent is expected, got %d for id %s at height %d",
 			len(vtx.ParentIDs()), vtx.ID().String(), vtx.Height())
 	}
-	for _, txBytes := range vtx.Txs() {
-		err = xi.addTransaction(vtx.Height(), txBytes)
-		if err != nil {
-			return err
-		}
-	}
-
-	xi.newVertices = append(xi.newVertices, &database.XChainVtx{
+	dbVtx := &database.XChainVtx{
 		VtxID:     vtx.ID().String(),
 		ParentID:  vtx.ParentIDs()[0].String(),
 		VtxIndex:  index,
 		Height:    vtx.Height(),
 		Timestamp: time.Unix(0, container.Timestamp),
-	})
+	}
+	for i, txBytes := range vtx.Txs() {
+		err = xi.addTransaction(dbVtx, uint32(i), txBytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	xi.newVertices = append(xi.newVertices, dbVtx)
 	return nil
 }
 
-func (xi *txBatchIndexer) addTransaction(vtxHeight uint64, txBytes []byte) error {
-	tx, err := x.Parser.ParseGenesisTx(txBytes)
+func (xi *txBatchIndexer) addTransaction(vtx *database.XChainVtx, vtxTxIndex uint32, txBytes []byte) error {
+	tx, err := xi.parser.ParseXChainTx(txBytes)
 	if err != nil {
 		return err
 	}
 
 	switch unsignedTx := tx.Unsigned.(type) {
 	case *txs.BaseTx:
-		err := xi.addBaseTx(tx.ID().String(), vtxHeight, unsignedTx, database.XChainBaseTx, txBytes)
+		err := xi.addBaseTx(tx.ID().String(), vtx, vtxTxIndex, unsignedTx, database.XChainBaseTx, txFee(unsignedTx, xi.feeAssetID), txBytes)
 		if err != nil {
 			return err
 		}
 	case *txs.ImportTx:
-		err := xi.addBaseTx(tx.ID().String(), vtxHeight, &unsignedTx.BaseTx, database.XChainImportTx, txBytes)
+		err := xi.addBaseTx(tx.ID().String(), vtx, vtxTxIndex, &unsignedTx.BaseTx, database.XChainImportTx, txFee(unsignedTx, xi.feeAssetID), txBytes)
 		if err != nil {
 			return err
 		}
@@ -97,22 +120,32 @@ func (xi *txBatchIndexer) addTransaction(vtxHeight uint64, txBytes []byte) error
 	return nil
 }
 
-func (xi *txBatchIndexer) ProcessBatch() error {
-	return xi.inOutIndexer.ProcessBatch()
+func (xi *txBatchIndexer) ProcessBatch(ctx context.Context) error {
+	return xi.inOutIndexer.ProcessBatch(ctx)
 }
 
 func (xi *txBatchIndexer) addBaseTx(
 	txID string,
-	VtxHeight uint64,
+	vtx *database.XChainVtx,
+	vtxTxIndex uint32,
 	baseTx *txs.BaseTx,
 	txType database.XChainTxType,
+	fee uint64,
 	bytes []byte,
 ) error {
+	if !xi.filter.Indexed(string(txType)) {
+		return nil
+	}
 	tx := &database.XChainTx{}
 	tx.TxID = txID
-	tx.VtxHeight = VtxHeight
+	tx.VtxHeight = vtx.Height
+	tx.VtxID = vtx.VtxID
+	tx.VtxIndex = vtx.VtxIndex
+	tx.VtxTxIndex = vtxTxIndex
+	tx.Timestamp = vtx.Timestamp
 	tx.Type = txType
-	tx.Memo = string(baseTx.Memo)
+	tx.Fee = fee
+	tx.Memo = chain.EncodeMemo(baseTx.Memo)
 	tx.Bytes = bytes
 
 	xi.newTxs = append(xi.newTxs, tx)
@@ -120,7 +153,7 @@ func (xi *txBatchIndexer) addBaseTx(
 }
 
 // Persist all entities
-func (i *txBatchIndexer) PersistEntities(db *gorm.DB) error {
+func (i *txBatchIndexer) PersistEntities(ctx context.Context, db *gorm.DB) error {
 	ins, err := utils.CastArray[*database.XChainTxInput](i.inOutIndexer.GetIns())
 	if err != nil {
 		return err
@@ -129,5 +162,5 @@ func (i *txBatchIndexer) PersistEntities(db *gorm.DB) error {
 	if err != nil {
 		return err
 	}
-	return database.CreateXChainEntities(db, i.newVertices, i.newTxs, ins, outs)
+	return database.CreateXChainEntities(ctx, db, i.newVertices, i.newTxs, ins, outs)
 }
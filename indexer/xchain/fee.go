@@ -0,0 +1,23 @@
+package xchain
+
+import (
+	"flare-indexer/indexer/shared"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+)
+
+// Fee (burned amount) of an indexed X-chain transaction, imported inputs count as
+// consumed. Only the amounts of the fee asset are counted, all assets if it is
+// ids.Empty. Zero for transaction types which are not indexed.
+func txFee(unsignedTx txs.UnsignedTx, feeAssetID ids.ID) uint64 {
+	switch tx := unsignedTx.(type) {
+	case *txs.BaseTx:
+		return shared.TxAssetFee(feeAssetID, [][]*avax.TransferableInput{tx.Ins}, [][]*avax.TransferableOutput{tx.Outs})
+	case *txs.ImportTx:
+		return shared.TxAssetFee(feeAssetID, [][]*avax.TransferableInput{tx.Ins, tx.ImportedIns}, [][]*avax.TransferableOutput{tx.Outs})
+	default:
+		return 0
+	}
+}
@@ -1,92 +1,93 @@
 package xchain
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/indexer/context"
+	indexerctx "flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils/chain"
 	"fmt"
 
 	"github.com/ava-labs/avalanchego/vms/avm/txs"
-	"github.com/ava-labs/avalanchego/wallet/chain/x"
-	mapset "github.com/deckarep/golang-set/v2"
 	"gorm.io/gorm"
 )
 
-type xChainInputUpdater struct {
-	shared.BaseInputUpdater
-
-	db     *gorm.DB
+// Outputs of X-chain transactions, from the database and from the indexer of
+// the node
+type xChainOutputFetcher struct {
+	db     xChainOutputsDB
 	client chain.IndexerClient
+	parser *chain.Parser
+	hrp    string
+}
+
+type xChainOutputsDB interface {
+	FetchXChainTxOutputs(ctx context.Context, txIDs []string) ([]database.XChainTxOutput, error)
 }
 
-func newXChainInputUpdater(ctx context.IndexerContext, client chain.IndexerClient) *xChainInputUpdater {
-	ioUpdater := xChainInputUpdater{
-		db:     ctx.DB(),
+func newXChainInputUpdater(ctx indexerctx.IndexerContext, client chain.IndexerClient) *shared.ChainInputUpdater {
+	return newXChainInputUpdaterWithDB(xChainOutputsDBGorm{db: ctx.DB()}, client, ctx.Parser(), ctx.Config().Chain.ChainAddressHRP)
+}
+
+func newXChainInputUpdaterWithDB(db xChainOutputsDB, client chain.IndexerClient, parser *chain.Parser, hrp string) *shared.ChainInputUpdater {
+	return shared.NewChainInputUpdater(&xChainOutputFetcher{
+		db:     db,
 		client: client,
-	}
-	ioUpdater.InitCache()
-	return &ioUpdater
+		parser: parser,
+		hrp:    hrp,
+	})
 }
 
-func (iu *xChainInputUpdater) UpdateInputs(inputs shared.InputList) (mapset.Set[string], error) {
-	missingTxIds := iu.UpdateInputsFromCache(inputs)
-	missingTxIds, err := iu.updateFromDB(inputs, missingTxIds)
-	if err != nil {
-		return nil, err
-	}
-	return iu.updateFromChain(inputs, missingTxIds)
+type xChainOutputsDBGorm struct {
+	db *gorm.DB
+}
+
+func (m xChainOutputsDBGorm) FetchXChainTxOutputs(ctx context.Context, txIDs []string) ([]database.XChainTxOutput, error) {
+	return database.FetchXChainTxOutputs(ctx, m.db, txIDs)
 }
 
-func (iu *xChainInputUpdater) updateFromDB(
-	inputs shared.InputList,
-	missingTxIds mapset.Set[string],
-) (mapset.Set[string], error) {
-	outs, err := database.FetchXChainTxOutputs(iu.db, missingTxIds.ToSlice())
+func (f *xChainOutputFetcher) FetchStoredOutputs(ctx context.Context, txIDs []string) ([]shared.Output, error) {
+	outs, err := f.db.FetchXChainTxOutputs(ctx, txIDs)
 	if err != nil {
 		return nil, err
 	}
-	baseOuts := shared.NewOutputMap()
-	for _, out := range outs {
-		baseOuts.Add(shared.NewIdIndexKey(out.TxID, out.Index()), &out.TxOutput)
+	storedOuts := make([]shared.Output, len(outs))
+	for i := range outs {
+		// Outputs are kept by pointers, each must refer to its own output
+		storedOuts[i] = &outs[i].TxOutput
 	}
-	return inputs.UpdateWithOutputs(baseOuts), nil
+	return storedOuts, nil
 }
 
-func (iu *xChainInputUpdater) updateFromChain(
-	inputs shared.InputList,
-	missingTxIds mapset.Set[string],
-) (mapset.Set[string], error) {
-	fetchedOuts := shared.NewOutputMap()
-	for txId := range missingTxIds.Iterator().C {
-		container, err := chain.FetchContainerFromIndexer(iu.client, txId)
-		if err != nil {
-			return nil, err
-		}
-		if container == nil {
-			continue
-		}
+// Transactions not found in the indexer stay missing
+func (f *xChainOutputFetcher) AddChainOutputs(ctx context.Context, txId string, fetchedOuts shared.OutputMap) error {
+	container, err := chain.FetchContainerFromIndexer(ctx, f.client, txId)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return nil
+	}
 
-		tx, err := x.Parser.ParseGenesisTx(container.Bytes)
-		if err != nil {
-			return nil, err
-		}
+	tx, err := f.parser.ParseXChainTx(container.Bytes)
+	if err != nil {
+		return err
+	}
 
-		var outs []shared.Output
-		switch unsignedTx := tx.Unsigned.(type) {
-		case *txs.BaseTx:
-			outs, err = shared.OutputsFromTxOuts(txId, unsignedTx.Outs, 0, XChainInputOutputCreator /* TODO could be identity, it is not persisted */)
-		case *txs.ImportTx:
-			outs, err = shared.OutputsFromTxOuts(txId, unsignedTx.BaseTx.Outs, 0, XChainInputOutputCreator /* TODO could be identity it is not persisted */)
-		default:
-			return nil, fmt.Errorf("transaction with id %s has unsupported type %T", container.ID.String(), unsignedTx)
-		}
-		if err != nil {
-			return nil, err
-		}
-		for _, out := range outs {
-			fetchedOuts.Add(shared.NewIdIndexKey(out.Tx(), out.Index()), out)
-		}
+	var outs []shared.Output
+	switch unsignedTx := tx.Unsigned.(type) {
+	case *txs.BaseTx:
+		outs, err = shared.OutputsFromTxOuts(f.hrp, txId, unsignedTx.Outs, 0, XChainInputOutputCreator /* TODO could be identity, it is not persisted */)
+	case *txs.ImportTx:
+		outs, err = shared.OutputsFromTxOuts(f.hrp, txId, unsignedTx.BaseTx.Outs, 0, XChainInputOutputCreator /* TODO could be identity it is not persisted */)
+	default:
+		return fmt.Errorf("transaction with id %s has unsupported type %T", container.ID.String(), unsignedTx)
+	}
+	if err != nil {
+		return err
+	}
+	for _, out := range outs {
+		fetchedOuts.Add(shared.NewIdIndexKeyFromOutput(out), out)
 	}
-	return inputs.UpdateWithOutputs(fetchedOuts), nil
+	return nil
 }
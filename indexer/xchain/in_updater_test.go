@@ -0,0 +1,42 @@
+//go:build !integration
+// +build !integration
+
+package xchain
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/indexer/shared"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/chain/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// Regression test: the stored outputs were mapped by pointers to the loop
+// variable, so all inputs got the address of the last output
+func TestUpdateInputsFromDBOwnOutputs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockIndexerClient(ctrl) // no calls expected
+
+	const outTxID = "2Q6Lhbu5bZkqNZvdDFTqaFZyxJJ6hsZhgDL2iz3YSuaVp2Y4Ry"
+	db := &testOutputsDB{
+		outputs: []database.XChainTxOutput{
+			{TxOutput: database.TxOutput{TxID: outTxID, Idx: 0, Address: "localflare1a", Amount: 100}},
+			{TxOutput: database.TxOutput{TxID: outTxID, Idx: 1, Address: "localflare1b", Amount: 200}},
+		},
+	}
+	updater := newXChainInputUpdaterWithDB(db, client, chain.NewParser(), "localflare")
+
+	in0 := &database.XChainTxInput{TxInput: database.TxInput{OutTxID: outTxID, OutIdx: 0}}
+	in1 := &database.XChainTxInput{TxInput: database.TxInput{OutTxID: outTxID, OutIdx: 1}}
+	missing, err := updater.UpdateInputs(context.Background(), shared.NewInputList([]shared.Input{in0, in1}))
+	require.NoError(t, err)
+	require.Equal(t, 0, missing.Cardinality())
+	require.Equal(t, "localflare1a", in0.Address)
+	require.Equal(t, uint64(100), in0.Amount)
+	require.Equal(t, "localflare1b", in1.Address)
+	require.Equal(t, uint64(200), in1.Amount)
+}
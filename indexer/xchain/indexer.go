@@ -2,10 +2,12 @@ package xchain
 
 import (
 	"flare-indexer/config"
+	"flare-indexer/database"
 	"flare-indexer/indexer/context"
 	"flare-indexer/indexer/shared"
 	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
+	"fmt"
 )
 
 const (
@@ -16,7 +18,7 @@ type xChainTxIndexer struct {
 	shared.ChainIndexerBase
 }
 
-func CreateXChainTxIndexer(ctx context.IndexerContext) *xChainTxIndexer {
+func CreateXChainTxIndexer(ctx context.IndexerContext) (*xChainTxIndexer, error) {
 	config := ctx.Config().XChainIndexer
 	client := newClient(&ctx.Config().Chain)
 	txClient := newTxClient(&ctx.Config().Chain)
@@ -27,11 +29,25 @@ func CreateXChainTxIndexer(ctx context.IndexerContext) *xChainTxIndexer {
 	idxr.Client = client
 	idxr.DB = ctx.DB()
 	idxr.Config = config
+	idxr.FeatureFlags = ctx.FeatureFlags()
+	idxr.FeatureFlagName = "x_chain_indexer"
+	idxr.Network = ctx.Config().Network
 	idxr.InitMetrics(StateName)
 
-	idxr.BatchIndexer = NewXChainBatchIndexer(ctx, client, txClient)
+	batchIndexer := NewXChainBatchIndexer(ctx, client, txClient)
+	txTypes := utils.Map(database.XChainTxTypes, func(t database.XChainTxType) string { return string(t) })
+	filter, err := shared.NewTxTypeFilter(&config, txTypes)
+	if err != nil {
+		return nil, fmt.Errorf("x_chain_indexer: %w", err)
+	}
+	batchIndexer.filter = filter
+	batchIndexer.feeAssetID, err = chain.FeeAssetID(&ctx.Config().Chain)
+	if err != nil {
+		return nil, err
+	}
+	idxr.BatchIndexer = batchIndexer
 
-	return &idxr
+	return &idxr, nil
 }
 
 func (xi *xChainTxIndexer) Run() {
@@ -39,11 +55,13 @@ func (xi *xChainTxIndexer) Run() {
 }
 
 func newClient(cfg *config.ChainConfig) chain.IndexerClient {
-	return chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/X/vtx"),
+	client := chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/X/vtx"),
 		chain.ClientOptions(cfg.ApiKey)...)
+	return chain.NewTimeoutIndexerClient(client, cfg.GetIndexerTimeout())
 }
 
 func newTxClient(cfg *config.ChainConfig) chain.IndexerClient {
-	return chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/X/tx"),
+	client := chain.NewAvalancheIndexerClient(utils.JoinPaths(cfg.NodeURL, "ext/index/X/tx"),
 		chain.ClientOptions(cfg.ApiKey)...)
+	return chain.NewTimeoutIndexerClient(client, cfg.GetIndexerTimeout())
 }
@@ -0,0 +1,86 @@
+//go:build integration
+// +build integration
+
+package xchain
+
+import (
+	"context"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	indexerctx "flare-indexer/indexer/context"
+	"flare-indexer/utils/chain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createXChainTestIndexer(t *testing.T, batchSize int) *xChainTxIndexer {
+	ctx, err := indexerctx.BuildTestContext(&config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+		XChainIndexer: config.IndexerConfig{
+			Enabled:   true,
+			Timeout:   3000 * time.Millisecond,
+			BatchSize: batchSize,
+		},
+		DB: globalConfig.DBConfig{
+			Username: database.MysqlTestUser,
+			Password: database.MysqlTestPassword,
+			Host:     database.MysqlTestHost,
+			Port:     database.MysqlTestPort,
+			Database: "flare_indexer_indexer",
+		},
+	})
+	require.NoError(t, err)
+	client, err := chain.XChainTestClient()
+	require.NoError(t, err)
+	txClient, err := chain.XChainTestTxClient()
+	require.NoError(t, err)
+
+	idxr := xChainTxIndexer{}
+	idxr.StateName = StateName
+	idxr.IndexerName = "X-chain Vertices Test"
+	idxr.Client = client
+	idxr.DB = ctx.DB()
+	idxr.Config = ctx.Config().XChainIndexer
+	idxr.BatchIndexer = NewXChainBatchIndexer(ctx, client, txClient)
+	return &idxr
+}
+
+// Indexing a range again, e.g. after a crash or during a reindex, keeps the
+// rows of the first run
+func TestXChainReindexSameRange(t *testing.T) {
+	ctx := context.Background()
+	idxr := createXChainTestIndexer(t, 10)
+
+	require.NoError(t, idxr.IndexBatch(ctx))
+	counts := xChainRowCounts(t, idxr.DB)
+	require.Equal(t, int64(2), counts["XChainVtx"])
+	require.Equal(t, int64(3), counts["XChainTx"])
+
+	state, err := database.FetchState(ctx, idxr.DB, StateName)
+	require.NoError(t, err)
+	state.NextDBIndex = 0
+	require.NoError(t, database.UpdateState(ctx, idxr.DB, &state))
+
+	require.NoError(t, idxr.IndexBatch(ctx))
+	require.Equal(t, counts, xChainRowCounts(t, idxr.DB))
+}
+
+func xChainRowCounts(t *testing.T, db *gorm.DB) map[string]int64 {
+	counts := make(map[string]int64)
+	for name, model := range map[string]interface{}{
+		"XChainVtx":       &database.XChainVtx{},
+		"XChainTx":        &database.XChainTx{},
+		"XChainTxInput":   &database.XChainTxInput{},
+		"XChainTxOutput":  &database.XChainTxOutput{},
+		"ChangeFeedEntry": &database.ChangeFeedEntry{},
+	} {
+		var count int64
+		require.NoError(t, db.Model(model).Count(&count).Error)
+		counts[name] = count
+	}
+	return counts
+}
@@ -0,0 +1,18 @@
+package xchain
+
+import (
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+)
+
+// Memo of an indexed X-chain transaction, nil for transaction types which are not
+// indexed
+func txMemo(unsignedTx txs.UnsignedTx) []byte {
+	switch tx := unsignedTx.(type) {
+	case *txs.BaseTx:
+		return tx.Memo
+	case *txs.ImportTx:
+		return tx.Memo
+	default:
+		return nil
+	}
+}
@@ -1,22 +1,149 @@
 package xchain
 
 import (
+	"context"
 	"flare-indexer/database"
 	"flare-indexer/indexer/migrations"
+	"flare-indexer/utils/chain"
+	"math"
 	"time"
 
+	"github.com/ava-labs/avalanchego/ids"
 	"gorm.io/gorm"
 )
 
+// Number of transactions processed at once when computing fees or memos of already
+// indexed transactions
+const feeMigrationBatchSize = 1000
+
 func init() {
 	migrations.Container.Add("2023-01-27-00-00", "Create initial state for X-Chain transactions", createXChainTxState)
+	migrations.Container.Add("2023-10-30-01-00", "Compute fees of indexed X-Chain transactions", computeXChainTxFees)
+	migrations.Container.Add("2023-11-16-01-00", "Hex encode memos of indexed X-Chain transactions", encodeXChainMemos)
+	migrations.Container.Add("2023-11-24-00-00", "Store vertices of indexed X-Chain transactions", setXChainTxVertices)
+	migrations.Container.Add("2023-12-04-01-00", "Delete duplicate X-Chain inputs and outputs and create unique indexes", createXChainUniqueIndexes)
 }
 
-func createXChainTxState(db *gorm.DB) error {
-	return database.CreateState(db, &database.State{
+func createXChainTxState(ctx context.Context, db *gorm.DB) error {
+	return database.CreateState(ctx, db, &database.State{
 		Name:           StateName,
 		NextDBIndex:    0,
 		LastChainIndex: 0,
 		Updated:        time.Now(),
 	})
 }
+
+// Inputs and outputs persisted more than once before persisting was idempotent
+// are deleted, keeping the first row of each
+func createXChainUniqueIndexes(ctx context.Context, db *gorm.DB) error {
+	if err := database.CreateIndexes(ctx, db, "XChainTxInput", database.XChainTxInputIndexes); err != nil {
+		return err
+	}
+	return database.CreateIndexes(ctx, db, "XChainTxOutput", database.XChainTxOutputIndexes)
+}
+
+// Transactions indexed before fees were tracked have zero fee, the fee is computed
+// from the stored transaction bytes
+func computeXChainTxFees(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchXChainTxsFromID(ctx, db, fromID, feeMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			tx, err := parser.ParseXChainTx(dbTx.Bytes)
+			if err != nil {
+				return err
+			}
+			// Transactions indexed before fees were tracked are of Flare
+			// networks, whose X-chain has a single asset
+			fee := txFee(tx.Unsigned, ids.Empty)
+			if fee == 0 {
+				continue
+			}
+			if err := database.UpdateXChainTxFee(ctx, db, dbTx.ID, fee); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Memos of transactions indexed before memos were hex encoded are stored as raw
+// strings, they are encoded from the stored transaction bytes
+func encodeXChainMemos(ctx context.Context, db *gorm.DB) error {
+	parser := chain.ParserFromContext(ctx)
+	var fromID uint64
+	for {
+		dbTxs, err := database.FetchXChainTxsFromID(ctx, db, fromID, feeMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		for _, dbTx := range dbTxs {
+			if dbTx.Memo == "" {
+				continue
+			}
+			tx, err := parser.ParseXChainTx(dbTx.Bytes)
+			if err != nil {
+				return err
+			}
+			memo := chain.EncodeMemo(txMemo(tx.Unsigned))
+			if err := database.UpdateXChainTxMemo(ctx, db, dbTx.ID, memo); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
+
+// Transactions indexed before the vertices of transactions were stored only have
+// the vertex height. The vertex is the one with the lowest index at the height,
+// the position in the vertex follows the order in which the transactions were
+// stored.
+func setXChainTxVertices(ctx context.Context, db *gorm.DB) error {
+	var fromID uint64
+	lastHeight := uint64(math.MaxUint64)
+	var vtxTxIndex uint32
+	for {
+		dbTxs, err := database.FetchXChainTxsFromID(ctx, db, fromID, feeMigrationBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(dbTxs) == 0 {
+			return nil
+		}
+		heights := make([]uint64, len(dbTxs))
+		for i := range dbTxs {
+			heights[i] = dbTxs[i].VtxHeight
+		}
+		vertices, err := database.FetchXChainVerticesByHeight(ctx, db, heights)
+		if err != nil {
+			return err
+		}
+		for i := range dbTxs {
+			dbTx := &dbTxs[i]
+			if dbTx.VtxHeight == lastHeight {
+				vtxTxIndex++
+			} else {
+				lastHeight = dbTx.VtxHeight
+				vtxTxIndex = 0
+			}
+			vtx, ok := vertices[dbTx.VtxHeight]
+			if len(dbTx.VtxID) > 0 || !ok {
+				continue
+			}
+			if err := database.UpdateXChainTxVertex(ctx, db, dbTx.ID, &vtx, vtxTxIndex); err != nil {
+				return err
+			}
+		}
+		fromID = dbTxs[len(dbTxs)-1].ID + 1
+	}
+}
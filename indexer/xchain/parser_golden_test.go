@@ -0,0 +1,341 @@
+//go:build !integration
+// +build !integration
+
+package xchain
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	globalConfig "flare-indexer/config"
+	"flare-indexer/database"
+	"flare-indexer/indexer/config"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/snow/engine/avalanche/vertex"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/components/verify"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/chain/x"
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/stretchr/testify/require"
+)
+
+// The X-chain corpus in resources/test is synthesized with the avalanchego
+// codec, there are no recorded X-chain containers yet. Regenerate it with
+//
+//	go test ./indexer/xchain -run TestGenerateFixtures -update-fixtures
+//
+// or replace it with containers recorded from a node with the index API
+// enabled with
+//
+//	go test ./indexer/xchain -run TestRecordFixtures -record-node-url <url> -record-from <vertex index>
+//
+// and update the snapshot. The recorder also records the transactions whose
+// outputs are spent by the recorded vertices from the tx index.
+var (
+	updateFixtures = flag.Bool("update-fixtures", false, "regenerate the X-chain container fixtures")
+	recordNodeURL  = flag.String("record-node-url", "", "URL of the node the X-chain container fixtures are recorded from")
+	recordFrom     = flag.Uint64("record-from", 0, "index of the first recorded X-chain vertex")
+	recordCount    = flag.Int("record-count", 2, "number of recorded X-chain vertices")
+)
+
+var (
+	fixtureChainID   = ids.ID{'X'}
+	fixtureAssetID   = ids.ID{'F', 'L', 'R'}
+	fixtureAddress1  = ids.ShortID{1}
+	fixtureAddress2  = ids.ShortID{2}
+	fixtureTimestamp = time.Date(2023, 2, 2, 14, 0, 0, 0, time.UTC)
+)
+
+// Outputs DB which keeps the outputs of all processed batches, as the
+// database would
+type testOutputsDB struct {
+	outputs []database.XChainTxOutput
+}
+
+func (db *testOutputsDB) FetchXChainTxOutputs(ctx context.Context, txIDs []string) ([]database.XChainTxOutput, error) {
+	wanted := make(map[string]bool, len(txIDs))
+	for _, id := range txIDs {
+		wanted[id] = true
+	}
+	var result []database.XChainTxOutput
+	for _, out := range db.outputs {
+		if wanted[out.TxID] {
+			result = append(result, out)
+		}
+	}
+	return result, nil
+}
+
+// Rows produced by the parser for the fixture containers
+type goldenRows struct {
+	Vertices []database.XChainVtx
+	Txs      []database.XChainTx
+	Inputs   []database.XChainTxInput
+	Outputs  []database.XChainTxOutput
+}
+
+// Parses the X-chain fixture vertices one batch at a time and compares the
+// produced rows with the golden snapshot in .snapshots. Inputs are resolved
+// from the outputs of previous batches and from the recorded tx index. Only
+// base and import transactions are indexed, the transactions of other types
+// (third vertex of the synthesized corpus) are skipped.
+func TestParserGolden(t *testing.T) {
+	globalConfig.GlobalConfigCallback.Call(config.Config{
+		Chain: globalConfig.ChainConfig{ChainAddressHRP: "localflare"},
+	})
+
+	client, err := chain.XChainTestClient()
+	require.NoError(t, err)
+	txClient, err := chain.XChainTestTxClient()
+	require.NoError(t, err)
+
+	_, last, err := client.GetLastAccepted(context.Background())
+	require.NoError(t, err)
+
+	db := &testOutputsDB{}
+	xi := newTxBatchIndexer(nil, client, chain.NewParser(), newXChainInputUpdaterWithDB(db, txClient, chain.NewParser(), "localflare"), "localflare")
+
+	first := last
+	for first > 0 {
+		if _, err := client.GetContainerByIndex(context.Background(), first-1); err != nil {
+			break
+		}
+		first--
+	}
+
+	rows := goldenRows{}
+	for i := first; i <= last; i++ {
+		container, err := client.GetContainerByIndex(context.Background(), i)
+		require.NoError(t, err)
+
+		xi.Reset(1)
+		require.NoError(t, xi.AddContainer(context.Background(), i, container))
+		require.NoError(t, xi.ProcessBatch(context.Background()))
+
+		ins, err := utils.CastArray[*database.XChainTxInput](xi.inOutIndexer.GetIns())
+		require.NoError(t, err)
+		outs, err := utils.CastArray[*database.XChainTxOutput](xi.inOutIndexer.GetNewOuts())
+		require.NoError(t, err)
+
+		for _, vtx := range xi.newVertices {
+			vtx.Timestamp = vtx.Timestamp.UTC()
+			rows.Vertices = append(rows.Vertices, *vtx)
+		}
+		for _, tx := range xi.newTxs {
+			tx.Bytes = nil
+			rows.Txs = append(rows.Txs, *tx)
+		}
+		for _, in := range ins {
+			rows.Inputs = append(rows.Inputs, *in)
+		}
+		for _, out := range outs {
+			rows.Outputs = append(rows.Outputs, *out)
+			db.outputs = append(db.outputs, *out)
+		}
+	}
+	cupaloy.SnapshotT(t, rows)
+}
+
+func TestGenerateFixtures(t *testing.T) {
+	if !*updateFixtures {
+		t.Skip("run with -update-fixtures to regenerate the fixtures")
+	}
+
+	// Funding tx, only available from the tx index
+	fundingTx := fixtureTx(t, &txs.BaseTx{BaseTx: avax.BaseTx{
+		BlockchainID: fixtureChainID,
+		Outs:         []*avax.TransferableOutput{fixtureOut(1000, fixtureAddress1)},
+	}})
+	transferTx := fixtureTx(t, &txs.BaseTx{BaseTx: avax.BaseTx{
+		BlockchainID: fixtureChainID,
+		Outs: []*avax.TransferableOutput{
+			fixtureOut(600, fixtureAddress2),
+			fixtureOut(399, fixtureAddress1),
+		},
+		Ins:  []*avax.TransferableInput{fixtureIn(fundingTx.ID(), 0, 1000)},
+		Memo: []byte("transfer"),
+	}})
+	importTx := fixtureTx(t, &txs.ImportTx{
+		BaseTx: txs.BaseTx{BaseTx: avax.BaseTx{
+			BlockchainID: fixtureChainID,
+			Outs:         []*avax.TransferableOutput{fixtureOut(500, fixtureAddress1)},
+		}},
+		SourceChain: ids.ID{'C'},
+		ImportedIns: []*avax.TransferableInput{fixtureIn(ids.ID{'C', 1}, 0, 501)},
+	})
+	spendTx := fixtureTx(t, &txs.BaseTx{BaseTx: avax.BaseTx{
+		BlockchainID: fixtureChainID,
+		Outs:         []*avax.TransferableOutput{fixtureOut(599, fixtureAddress1)},
+		Ins:          []*avax.TransferableInput{fixtureIn(transferTx.ID(), 0, 600)},
+		Memo:         []byte("spend"),
+	}})
+
+	// Transactions which are not indexed
+	owners := secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{fixtureAddress1}}
+	exportTx := fixtureTx(t, &txs.ExportTx{
+		BaseTx: txs.BaseTx{BaseTx: avax.BaseTx{
+			BlockchainID: fixtureChainID,
+			Ins:          []*avax.TransferableInput{fixtureIn(transferTx.ID(), 1, 399)},
+		}},
+		DestinationChain: ids.ID{'C'},
+		ExportedOuts:     []*avax.TransferableOutput{fixtureOut(398, fixtureAddress1)},
+	})
+	createAssetTx := fixtureTx(t, &txs.CreateAssetTx{
+		BaseTx:       txs.BaseTx{BaseTx: avax.BaseTx{BlockchainID: fixtureChainID}},
+		Name:         "Fixture",
+		Symbol:       "FIX",
+		Denomination: 9,
+		States: []*txs.InitialState{{
+			FxIndex: 0,
+			Outs:    []verify.State{&secp256k1fx.MintOutput{OutputOwners: owners}},
+		}},
+	})
+	operationTx := fixtureTx(t, &txs.OperationTx{
+		BaseTx: txs.BaseTx{BaseTx: avax.BaseTx{BlockchainID: fixtureChainID}},
+		Ops: []*txs.Operation{{
+			Asset:   avax.Asset{ID: createAssetTx.ID()},
+			UTXOIDs: []*avax.UTXOID{{TxID: createAssetTx.ID(), OutputIndex: 0}},
+			Op: &secp256k1fx.MintOperation{
+				MintInput:      secp256k1fx.Input{SigIndices: []uint32{0}},
+				MintOutput:     secp256k1fx.MintOutput{OutputOwners: owners},
+				TransferOutput: secp256k1fx.TransferOutput{Amt: 100, OutputOwners: owners},
+			},
+		}},
+	})
+
+	vtx0, err := vertex.Build(fixtureChainID, 0, []ids.ID{ids.Empty}, [][]byte{transferTx.Bytes(), importTx.Bytes()})
+	require.NoError(t, err)
+	vtx1, err := vertex.Build(fixtureChainID, 1, []ids.ID{vtx0.ID()}, [][]byte{spendTx.Bytes()})
+	require.NoError(t, err)
+	vtx2, err := vertex.Build(fixtureChainID, 2, []ids.ID{vtx1.ID()}, [][]byte{exportTx.Bytes(), createAssetTx.Bytes(), operationTx.Bytes()})
+	require.NoError(t, err)
+
+	writeFixture(t, "x_chain_indexer_txs.json", []chain.ContainerRecording{
+		fixtureRecording(t, indexer.Container{ID: fundingTx.ID(), Bytes: fundingTx.Bytes(), Timestamp: fixtureTimestamp.UnixNano()}, 0),
+	})
+	var vertices []chain.ContainerRecording
+	for i, vtx := range []vertex.StatelessVertex{vtx0, vtx1, vtx2} {
+		container := indexer.Container{
+			ID:        vtx.ID(),
+			Bytes:     vtx.Bytes(),
+			Timestamp: fixtureTimestamp.Add(time.Duration(i) * time.Second).UnixNano(),
+		}
+		vertices = append(vertices, fixtureRecording(t, container, uint64(i)))
+	}
+	writeFixture(t, "x_chain_indexer_vertices.json", vertices)
+}
+
+func TestRecordFixtures(t *testing.T) {
+	if *recordNodeURL == "" {
+		t.Skip("run with -record-node-url to record the fixtures")
+	}
+	ctx := context.Background()
+	parser := chain.NewParser()
+	vtxClient := chain.NewAvalancheIndexerClient(utils.JoinPaths(*recordNodeURL, "ext/index/X/vtx"))
+	txClient := chain.NewAvalancheIndexerClient(utils.JoinPaths(*recordNodeURL, "ext/index/X/tx"))
+
+	containers, err := vtxClient.GetContainerRange(ctx, *recordFrom, *recordCount)
+	require.NoError(t, err)
+
+	var vertices []chain.ContainerRecording
+	recorded := make(map[ids.ID]bool)
+	var spent []ids.ID
+	for i, container := range containers {
+		vertices = append(vertices, fixtureRecording(t, container, *recordFrom+uint64(i)))
+
+		vtx, err := parser.ParseXChainVertex(container.Bytes)
+		require.NoError(t, err)
+		for _, txBytes := range vtx.Txs() {
+			tx, err := parser.ParseXChainTx(txBytes)
+			require.NoError(t, err)
+			recorded[tx.ID()] = true
+			for _, utxo := range tx.Unsigned.InputUTXOs() {
+				spent = append(spent, utxo.TxID)
+			}
+		}
+	}
+
+	var txRecordings []chain.ContainerRecording
+	for _, txID := range spent {
+		if recorded[txID] {
+			continue
+		}
+		recorded[txID] = true
+		// Inputs imported from other chains are not in the tx index
+		index, err := txClient.GetIndex(ctx, txID)
+		if err != nil {
+			t.Logf("tx %s not recorded: %v", txID, err)
+			continue
+		}
+		container, err := txClient.GetContainerByIndex(ctx, index)
+		require.NoError(t, err)
+		txRecordings = append(txRecordings, fixtureRecording(t, container, index))
+	}
+
+	writeFixture(t, "x_chain_indexer_txs.json", txRecordings)
+	writeFixture(t, "x_chain_indexer_vertices.json", vertices)
+}
+
+func fixtureTx(t *testing.T, unsigned txs.UnsignedTx) *txs.Tx {
+	tx := &txs.Tx{Unsigned: unsigned}
+	require.NoError(t, tx.Initialize(x.Parser.Codec()))
+	return tx
+}
+
+func fixtureOut(amount uint64, address ids.ShortID) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: fixtureAssetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: amount,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Threshold: 1,
+				Addrs:     []ids.ShortID{address},
+			},
+		},
+	}
+}
+
+func fixtureIn(txID ids.ID, index uint32, amount uint64) *avax.TransferableInput {
+	return &avax.TransferableInput{
+		UTXOID: avax.UTXOID{TxID: txID, OutputIndex: index},
+		Asset:  avax.Asset{ID: fixtureAssetID},
+		In: &secp256k1fx.TransferInput{
+			Amt:   amount,
+			Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+		},
+	}
+}
+
+func fixtureRecording(t *testing.T, container indexer.Container, index uint64) chain.ContainerRecording {
+	encoded, err := formatting.Encode(formatting.Hex, container.Bytes)
+	require.NoError(t, err)
+	return chain.ContainerRecording{
+		Id:        container.ID.String(),
+		Bytes:     encoded,
+		Timestamp: time.Unix(0, container.Timestamp).UTC(),
+		Index:     strconv.FormatUint(index, 10),
+	}
+}
+
+func writeFixture(t *testing.T, name string, recordings []chain.ContainerRecording) {
+	_, filename, _, _ := runtime.Caller(0)
+	dir, _ := path.Split(filename)
+
+	data, err := json.MarshalIndent(recordings, "", "  ")
+	require.NoError(t, err)
+	err = os.WriteFile(path.Join(dir, "../../resources/test", name), append(data, '\n'), 0644)
+	require.NoError(t, err)
+}
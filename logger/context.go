@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type requestIDContextKey struct{}
+
+// Returns a context carrying the ID of the request (e.g. an API call) it is
+// used for, messages logged with the context include the ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// ID of the request of the context, empty if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+func contextSugar(ctx context.Context) *zap.SugaredLogger {
+	if id := RequestID(ctx); len(id) > 0 {
+		return sugar.With("request_id", id)
+	}
+	return sugar
+}
+
+func WarnCtx(ctx context.Context, msg string, args ...interface{}) {
+	contextSugar(ctx).Warnf(msg, args...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, args ...interface{}) {
+	contextSugar(ctx).Errorf(msg, args...)
+}
+
+func InfoCtx(ctx context.Context, msg string, args ...interface{}) {
+	contextSugar(ctx).Infof(msg, args...)
+}
+
+func DebugCtx(ctx context.Context, msg string, args ...interface{}) {
+	contextSugar(ctx).Debugf(msg, args...)
+}
@@ -12,6 +12,8 @@ import (
 
 var (
 	sugar *zap.SugaredLogger
+	// Config of sugar, the global config is applied once per indexed network
+	sugarConfig config.LoggerConfig
 )
 
 const (
@@ -19,12 +21,17 @@ const (
 )
 
 func init() {
-	sugar = createSugaredLogger(DefaultLoggerConfig())
+	sugarConfig = DefaultLoggerConfig()
+	sugar = createSugaredLogger(sugarConfig)
 
 	// zap.NewDevelopment(
 
 	config.GlobalConfigCallback.AddCallback(func(config config.GlobalConfig) {
-		sugar = createSugaredLogger(config.LoggerConfig())
+		if config.LoggerConfig() == sugarConfig {
+			return
+		}
+		sugarConfig = config.LoggerConfig()
+		sugar = createSugaredLogger(sugarConfig)
 	})
 }
 
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/mia-platform/jsonschema"
+)
+
+// Formats of the amounts of the responses, set by amount_format of the
+// services config
+const (
+	// Integer number of nanoFLR, the smallest unit (as wei on the C-chain)
+	AmountFormatInteger = "integer"
+	// Decimal string of FLR with AmountDecimals decimals, e.g. "1.500000000"
+	AmountFormatDecimal = "decimal"
+)
+
+// Decimals of the denomination of P-chain and X-chain amounts, 1 FLR is 10^9
+// nanoFLR
+const AmountDecimals = 9
+
+var (
+	amountFormat = AmountFormatInteger
+
+	amountUnit = new(big.Int).Exp(big.NewInt(10), big.NewInt(AmountDecimals), nil)
+)
+
+// Sets the format of the amounts of all responses, integer if empty. Called on
+// startup, before the routes are added.
+func SetAmountFormat(format string) error {
+	switch format {
+	case "":
+		amountFormat = AmountFormatInteger
+	case AmountFormatInteger, AmountFormatDecimal:
+		amountFormat = format
+	default:
+		return fmt.Errorf("unknown amount format '%s', expected '%s' or '%s'",
+			format, AmountFormatInteger, AmountFormatDecimal)
+	}
+	return nil
+}
+
+// Amount in nanoFLR of a response, marshalled in the configured format. The
+// value is a big integer, so that sums of amounts do not overflow.
+type Amount struct {
+	value *big.Int
+}
+
+func NewAmount(x uint64) Amount {
+	return Amount{value: new(big.Int).SetUint64(x)}
+}
+
+func NewBigAmount(x *big.Int) Amount {
+	return Amount{value: x}
+}
+
+func (a Amount) Big() *big.Int {
+	if a.value == nil {
+		return new(big.Int)
+	}
+	return a.value
+}
+
+// Decimal string of FLR, e.g. "1.500000000" for 1500000000 nanoFLR
+func (a Amount) Decimal() string {
+	q, r := new(big.Int).QuoRem(a.Big(), amountUnit, new(big.Int))
+	return fmt.Sprintf("%s.%0*d", q.String(), AmountDecimals, r.Int64())
+}
+
+// Amount in the configured format, e.g. for CSV exports
+func (a Amount) String() string {
+	if amountFormat == AmountFormatDecimal {
+		return a.Decimal()
+	}
+	return a.Big().String()
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if amountFormat == AmountFormatDecimal {
+		return json.Marshal(a.Decimal())
+	}
+	return []byte(a.Big().String()), nil
+}
+
+// Accepts amounts in both formats, numbers of nanoFLR and strings of FLR
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted := strings.Trim(s, `"`); unquoted != s {
+		whole, frac, _ := strings.Cut(unquoted, ".")
+		if len(frac) > AmountDecimals {
+			return fmt.Errorf("invalid amount %s, at most %d decimals", s, AmountDecimals)
+		}
+		s = whole + frac + strings.Repeat("0", AmountDecimals-len(frac))
+	}
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok || value.Sign() < 0 {
+		return fmt.Errorf("invalid amount %s", string(data))
+	}
+	a.value = value
+	return nil
+}
+
+// Schema of the configured format in the API docs
+func (Amount) JSONSchema() *jsonschema.Schema {
+	if amountFormat == AmountFormatDecimal {
+		return &jsonschema.Schema{
+			Type:        "string",
+			Description: fmt.Sprintf("Amount in FLR with %d decimals", AmountDecimals),
+			Examples:    []interface{}{"1.500000000"},
+		}
+	}
+	return &jsonschema.Schema{
+		Type:        "integer",
+		Description: "Amount in nanoFLR",
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !integration
+// +build !integration
+
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountFormats(t *testing.T) {
+	defer func() { require.NoError(t, SetAmountFormat("")) }()
+	large := new(big.Int).Add(new(big.Int).SetUint64(math.MaxUint64), big.NewInt(1))
+
+	require.NoError(t, SetAmountFormat(AmountFormatInteger))
+	data, err := json.Marshal([]Amount{NewAmount(1500000000), NewBigAmount(large), {}})
+	require.NoError(t, err)
+	require.Equal(t, `[1500000000,18446744073709551616,0]`, string(data))
+
+	require.NoError(t, SetAmountFormat(AmountFormatDecimal))
+	data, err = json.Marshal([]Amount{NewAmount(1500000000), NewAmount(1), NewBigAmount(large), {}})
+	require.NoError(t, err)
+	require.Equal(t, `["1.500000000","0.000000001","18446744073.709551616","0.000000000"]`, string(data))
+	require.Equal(t, "1.500000000", NewAmount(1500000000).String())
+
+	require.Error(t, SetAmountFormat("hex"))
+}
+
+func TestAmountUnmarshal(t *testing.T) {
+	var amounts []Amount
+	require.NoError(t, json.Unmarshal([]byte(`[1500000000,"1.5","2","0.000000001"]`), &amounts))
+	require.Equal(t, []string{"1500000000", "1500000000", "2000000000", "1"},
+		[]string{amounts[0].Big().String(), amounts[1].Big().String(), amounts[2].Big().String(), amounts[3].Big().String()})
+
+	for _, data := range []string{`"0.0000000001"`, `-1`, `"x"`, `1.5`} {
+		var a Amount
+		require.Error(t, json.Unmarshal([]byte(data), &a), data)
+	}
+}
@@ -2,46 +2,65 @@ package api
 
 import (
 	"flare-indexer/database"
+	"flare-indexer/utils/staking"
 	"time"
 )
 
 type ApiPChainTx struct {
-	Type        database.PChainTxType `json:"type"`
-	TxID        *string               `json:"txID"`
-	BlockHeight uint64                `json:"blockHeight"`
-	ChainID     string                `json:"chainID"`
-	NodeID      string                `json:"nodeID"`
-	StartTime   *time.Time            `json:"startTime"`
-	EndTime     *time.Time            `json:"endTime"`
-	Weight      uint64                `json:"weight"`
+	Type         database.PChainTxType `json:"type"`
+	TxID         *string               `json:"txID"`
+	BlockHeight  uint64                `json:"blockHeight"`
+	BlockTxIndex uint32                `json:"blockTxIndex"`
+	ChainID      string                `json:"chainID"`
+	NodeID       string                `json:"nodeID"`
+	StartTime    *time.Time            `json:"startTime"`
+	EndTime      *time.Time            `json:"endTime"`
+	// Reward epochs containing the start and end time
+	StartEpoch *int64                  `json:"startEpoch,omitempty"`
+	EndEpoch   *int64                  `json:"endEpoch,omitempty"`
+	Weight     Amount                  `json:"weight"`
+	Fee        Amount                  `json:"fee"`
+	Memo       string                  `json:"memo"`
+	Status     database.PChainTxStatus `json:"status"`
 
 	Inputs  []ApiPChainTxInput  `json:"inputs"`
 	Outputs []ApiPChainTxOutput `json:"outputs"`
 }
 
 type ApiPChainTxInput struct {
-	Amount  uint64 `json:"amount"`
+	Amount  Amount `json:"amount"`
 	Address string `json:"address"`
 }
 
 type ApiPChainTxOutput struct {
-	Amount  uint64 `json:"amount"`
+	Amount  Amount `json:"amount"`
 	Address string `json:"address"`
 	Idx     uint32 `json:"index"`
 }
 
-func NewApiPChainTx(tx *database.PChainTx, inputs []database.PChainTxInput, outputs []database.PChainTxOutput) *ApiPChainTx {
+func NewApiPChainTx(
+	tx *database.PChainTx,
+	inputs []database.PChainTxInput,
+	outputs []database.PChainTxOutput,
+	epochs staking.EpochInfo,
+) *ApiPChainTx {
 	return &ApiPChainTx{
-		Type:        tx.Type,
-		TxID:        tx.TxID,
-		BlockHeight: tx.BlockHeight,
-		ChainID:     tx.ChainID,
-		NodeID:      tx.NodeID,
-		StartTime:   tx.StartTime,
-		EndTime:     tx.EndTime,
-		Weight:      tx.Weight,
-		Inputs:      newApiPChainInputs(inputs),
-		Outputs:     newApiPChainOutputs(outputs),
+		Type:         tx.Type,
+		TxID:         tx.TxID,
+		BlockHeight:  tx.BlockHeight,
+		BlockTxIndex: tx.BlockTxIndex,
+		ChainID:      tx.ChainID,
+		NodeID:       tx.NodeID,
+		StartTime:    tx.StartTime,
+		EndTime:      tx.EndTime,
+		StartEpoch:   epochs.EpochOf(tx.StartTime),
+		EndEpoch:     epochs.EpochOf(tx.EndTime),
+		Weight:       NewAmount(tx.Weight),
+		Fee:          NewAmount(tx.Fee),
+		Memo:         tx.Memo,
+		Status:       tx.Status,
+		Inputs:       newApiPChainInputs(inputs),
+		Outputs:      newApiPChainOutputs(outputs),
 	}
 }
 
@@ -49,7 +68,7 @@ func newApiPChainInputs(inputs []database.PChainTxInput) []ApiPChainTxInput {
 	result := make([]ApiPChainTxInput, len(inputs))
 	for i, in := range inputs {
 		result[i] = ApiPChainTxInput{
-			Amount:  in.Amount,
+			Amount:  NewAmount(in.Amount),
 			Address: in.Address,
 		}
 	}
@@ -60,7 +79,7 @@ func newApiPChainOutputs(inputs []database.PChainTxOutput) []ApiPChainTxOutput {
 	result := make([]ApiPChainTxOutput, len(inputs))
 	for i, out := range inputs {
 		result[i] = ApiPChainTxOutput{
-			Amount:  out.Amount,
+			Amount:  NewAmount(out.Amount),
 			Address: out.Address,
 			Idx:     out.Idx,
 		}
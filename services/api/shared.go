@@ -1,19 +1,22 @@
 package api
 
+import "flare-indexer/utils/errcode"
+
 type ApiResStatusEnum string
 
 const (
-	ApiResStatusOk                ApiResStatusEnum = "OK"
-	ApiResStatusError             ApiResStatusEnum = "ERROR"
-	ApiResStatusRequestBodyError  ApiResStatusEnum = "REQUEST_BODY_ERROR"
-	ApiResStatusValidationError   ApiResStatusEnum = "VALIDATION_ERROR"
-	ApiResStatusTooManyRequests   ApiResStatusEnum = "TOO_MANY_REQUESTS"
-	ApiResStatusUnauthorized      ApiResStatusEnum = "UNAUTHORIZED"
-	ApiResStatusAuthError         ApiResStatusEnum = "AUTH_ERROR"
-	ApiResStatusUpstreamHttpError ApiResStatusEnum = "UPSTREAM_HTTP_ERROR"
-	ApiResStatusInvalidRequest    ApiResStatusEnum = "INVALID_REQUEST"
-	ApiResStatusNotImplemented    ApiResStatusEnum = "NOT_IMPLEMENTED"
-	ApiResStatusPending           ApiResStatusEnum = "PENDING"
+	ApiResStatusOk                 ApiResStatusEnum = "OK"
+	ApiResStatusError              ApiResStatusEnum = "ERROR"
+	ApiResStatusRequestBodyError   ApiResStatusEnum = "REQUEST_BODY_ERROR"
+	ApiResStatusValidationError    ApiResStatusEnum = "VALIDATION_ERROR"
+	ApiResStatusTooManyRequests    ApiResStatusEnum = "TOO_MANY_REQUESTS"
+	ApiResStatusUnauthorized       ApiResStatusEnum = "UNAUTHORIZED"
+	ApiResStatusAuthError          ApiResStatusEnum = "AUTH_ERROR"
+	ApiResStatusUpstreamHttpError  ApiResStatusEnum = "UPSTREAM_HTTP_ERROR"
+	ApiResStatusInvalidRequest     ApiResStatusEnum = "INVALID_REQUEST"
+	ApiResStatusNotImplemented     ApiResStatusEnum = "NOT_IMPLEMENTED"
+	ApiResStatusPending            ApiResStatusEnum = "PENDING"
+	ApiResStatusQueryLimitExceeded ApiResStatusEnum = "QUERY_LIMIT_EXCEEDED"
 )
 
 type ApiResponseWrapper[T any] struct {
@@ -28,6 +31,10 @@ type ApiResponseWrapper[T any] struct {
 	// Response status. OK for successful responses.
 	Status ApiResStatusEnum `json:"status"`
 
+	// Machine-readable code of error responses (NOT_FOUND, CHAIN_LAG, VALIDATION
+	// or INTERNAL), omitted for successful responses.
+	ErrorCode errcode.Code `json:"errorCode,omitempty"`
+
 	ValidationErrorDetails *ApiValidationErrorDetails `json:"validationErrorDetails"`
 }
 
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"flare-indexer/services/config"
+	"flare-indexer/utils/staking"
+	"time"
+)
+
+// Cache of serialized API responses. A nil Cache disables caching.
+type Cache interface {
+	// Returns the value stored for key if it did not expire yet
+	Get(key string) ([]byte, bool)
+
+	// Stores the value for key until expiration
+	Set(key string, value []byte, expiration time.Time)
+}
+
+// Returns the cache configured in cfg, an in-memory cache or a cache in
+// Redis shared by all services instances, or nil if caching is disabled
+func New(cfg *config.CacheConfig) (Cache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if len(cfg.RedisURL) > 0 {
+		return newRedisCache(cfg.RedisURL)
+	}
+	return newMemoryCache(cfg.MaxEntries), nil
+}
+
+// Returns the expiration of a response cached at time now, after ttl or at
+// the end of the current epoch, whichever comes first. Responses depending on
+// the indexed transactions of an epoch are thus refreshed after a new epoch
+// starts.
+func EpochExpiration(ttl time.Duration, epochs staking.EpochInfo) func(now time.Time) time.Time {
+	return func(now time.Time) time.Time {
+		expiration := now.Add(ttl)
+		if epochs.Period <= 0 || now.Before(epochs.Start) {
+			return expiration
+		}
+		epochEnd := epochs.GetEndTime(epochs.GetEpochIndex(now))
+		if epochEnd.Before(expiration) {
+			return epochEnd
+		}
+		return expiration
+	}
+}
@@ -0,0 +1,70 @@
+//go:build !integration
+// +build !integration
+
+package cache
+
+import (
+	"flare-indexer/utils/staking"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	c := newMemoryCache(10)
+	c.now = func() time.Time { return testNow }
+
+	c.Set("a", []byte("1"), testNow.Add(time.Minute))
+	c.Set("b", []byte("2"), testNow)
+
+	value, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), value)
+	_, ok = c.Get("b")
+	require.False(t, ok)
+
+	c.now = func() time.Time { return testNow.Add(time.Minute) }
+	_, ok = c.Get("a")
+	require.False(t, ok)
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := newMemoryCache(2)
+	c.now = func() time.Time { return testNow }
+
+	c.Set("a", []byte("1"), testNow.Add(time.Second))
+	c.Set("b", []byte("2"), testNow.Add(time.Minute))
+
+	// Expired entries are evicted first
+	c.now = func() time.Time { return testNow.Add(2 * time.Second) }
+	c.Set("c", []byte("3"), testNow.Add(time.Minute))
+	require.Len(t, c.entries, 2)
+	_, ok := c.Get("b")
+	require.True(t, ok)
+	_, ok = c.Get("c")
+	require.True(t, ok)
+
+	// Otherwise an arbitrary entry
+	c.Set("d", []byte("4"), testNow.Add(time.Minute))
+	require.Len(t, c.entries, 2)
+	_, ok = c.Get("d")
+	require.True(t, ok)
+}
+
+func TestEpochExpiration(t *testing.T) {
+	epochs := staking.EpochInfo{
+		Start:  testNow,
+		Period: 10 * time.Minute,
+	}
+	expiration := EpochExpiration(5*time.Minute, epochs)
+
+	// The ttl ends before the epoch
+	require.Equal(t, testNow.Add(6*time.Minute), expiration(testNow.Add(time.Minute)))
+	// The epoch ends before the ttl
+	require.Equal(t, testNow.Add(20*time.Minute), expiration(testNow.Add(17*time.Minute)))
+	// Unknown epochs
+	require.Equal(t, testNow.Add(5*time.Minute), EpochExpiration(5*time.Minute, staking.EpochInfo{})(testNow))
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value      []byte
+	expiration time.Time
+}
+
+// Cache in the memory of the services process, holding at most maxEntries
+// values. If the cache is full, expired values are removed first, then
+// arbitrary ones.
+type memoryCache struct {
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	maxEntries int
+
+	// For testing
+	now func() time.Time
+}
+
+func newMemoryCache(maxEntries int) *memoryCache {
+	return &memoryCache{
+		entries:    make(map[string]memoryEntry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiration) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(key string, value []byte, expiration time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.now().Before(expiration) || c.maxEntries <= 0 {
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = memoryEntry{value: value, expiration: expiration}
+}
+
+// Removes expired entries or, if there are none, one arbitrary entry
+func (c *memoryCache) evict() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiration) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/logger"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix = "flare_indexer:cache:"
+	redisTimeout   = time.Second
+)
+
+// Cache shared by all services instances using the same Redis server. Redis
+// errors are logged and treated as cache misses, so the services keep
+// working without Redis.
+type redisCache struct {
+	client *redis.Client
+}
+
+func newRedisCache(url string) (*redisCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache redis_url: %w", err)
+	}
+	return &redisCache{client: redis.NewClient(opts)}, nil
+}
+
+func (c *redisCache) Get(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	value, err := c.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Warn("Cannot read from cache: %v", err)
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *redisCache) Set(key string, value []byte, expiration time.Time) {
+	ttl := time.Until(expiration)
+	if ttl <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	err := c.client.Set(ctx, redisKeyPrefix+key, value, ttl).Err()
+	if err != nil {
+		logger.Warn("Cannot write to cache: %v", err)
+	}
+}
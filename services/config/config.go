@@ -2,27 +2,108 @@ package config
 
 import (
 	"flare-indexer/config"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
 type Config struct {
-	DB                config.DBConfig          `toml:"db"`
-	Logger            config.LoggerConfig      `toml:"logger"`
-	Chain             config.ChainConfig       `toml:"chain"`
-	Services          ServicesConfig           `toml:"services"`
-	ContractAddresses config.ContractAddresses `toml:"contract_addresses"`
+	DB                config.DBConfig            `toml:"db"`
+	Logger            config.LoggerConfig        `toml:"logger"`
+	Chain             config.ChainConfig         `toml:"chain"`
+	Services          ServicesConfig             `toml:"services"`
+	ContractAddresses config.ContractAddresses   `toml:"contract_addresses"`
+	StakingFilter     config.StakingFilterConfig `toml:"staking_filter"`
+	StakingRules      config.StakingRulesConfig  `toml:"staking_rules"`
+	Cache             CacheConfig                `toml:"cache"`
 }
 
 type ServicesConfig struct {
 	Address        string         `toml:"address"`
 	VotingContract common.Address `toml:"votingContract"`
+	// Bearer token of the routes changing data (e.g. the watchlist), these
+	// routes are disabled if empty
+	AdminToken string `toml:"admin_token" envconfig:"SERVICES_ADMIN_TOKEN"`
+	// Prefix of all routes (e.g. "/pchain"), if the API is served under a path of a gateway
+	BasePath string `toml:"base_path" envconfig:"SERVICES_BASE_PATH"`
+	// Addresses or CIDR ranges of reverse proxies whose X-Forwarded-For header is trusted
+	TrustedProxies []string   `toml:"trusted_proxies" envconfig:"SERVICES_TRUSTED_PROXIES"`
+	CORS           CORSConfig `toml:"cors"`
+	// Address of the Prometheus metrics server (e.g. "localhost:2112"), disabled if empty
+	PrometheusAddress string `toml:"prometheus_address" envconfig:"SERVICES_PROMETHEUS_ADDRESS"`
+	// Requests taking at least this long are logged with their SQL statements, disabled if zero
+	SlowRequestThreshold time.Duration     `toml:"slow_request_threshold" envconfig:"SERVICES_SLOW_REQUEST_THRESHOLD"`
+	QueryLimits          QueryLimitsConfig `toml:"query_limits"`
+	// Serve the operator dashboard at /admin/dashboard, its data requires the admin token
+	Dashboard bool `toml:"dashboard" envconfig:"SERVICES_DASHBOARD"`
+	// Render addresses in responses as P-chain addresses ("P-costwo1...")
+	// instead of the stored bech32 addresses without the chain prefix
+	PChainAddresses bool `toml:"p_chain_addresses" envconfig:"SERVICES_P_CHAIN_ADDRESSES"`
+	// Format of the amounts in responses, "integer" (nanoFLR, the default) or
+	// "decimal" (string of FLR with 9 decimals)
+	AmountFormat string `toml:"amount_format" envconfig:"SERVICES_AMOUNT_FORMAT"`
+}
+
+// Guard rails of the cost of a single request, zero values disable a limit
+type QueryLimitsConfig struct {
+	// Maximal page size of paginated routes
+	MaxLimit int `toml:"max_limit" envconfig:"SERVICES_QUERY_LIMITS_MAX_LIMIT"`
+	// Maximal offset of paginated routes
+	MaxOffset int `toml:"max_offset" envconfig:"SERVICES_QUERY_LIMITS_MAX_OFFSET"`
+	// Maximal time range of routes aggregating over a range (e.g. burned fees)
+	MaxTimeRange time.Duration `toml:"max_time_range" envconfig:"SERVICES_QUERY_LIMITS_MAX_TIME_RANGE"`
+	// Limits of clients sending one of the keys in the X-API-Key header
+	Overrides []QueryLimitsOverride `toml:"overrides" ignored:"true"`
+}
+
+// Limits replacing all default limits for requests with the key
+type QueryLimitsOverride struct {
+	Key          string        `toml:"key"`
+	MaxLimit     int           `toml:"max_limit"`
+	MaxOffset    int           `toml:"max_offset"`
+	MaxTimeRange time.Duration `toml:"max_time_range"`
+}
+
+// Cross-origin requests from browsers, disabled if no origin is allowed
+type CORSConfig struct {
+	// Allowed origins, e.g. "https://explorer.flare.network", or "*" for all origins
+	AllowedOrigins []string `toml:"allowed_origins" envconfig:"SERVICES_CORS_ALLOWED_ORIGINS"`
+	// Request headers allowed in addition to Content-Type, Authorization and the
+	// headers of conditional requests
+	AllowedHeaders []string `toml:"allowed_headers" envconfig:"SERVICES_CORS_ALLOWED_HEADERS"`
+	// Time browsers may cache the response to a preflight request
+	MaxAge time.Duration `toml:"max_age" envconfig:"SERVICES_CORS_MAX_AGE"`
+}
+
+// Cache of the responses of the staking and mirroring routes
+type CacheConfig struct {
+	Enabled bool `toml:"enabled" envconfig:"CACHE_ENABLED"`
+	// Maximal time a response is cached, responses also expire at the end of each epoch
+	TTL time.Duration `toml:"ttl" envconfig:"CACHE_TTL"`
+	// Maximal number of responses in the in-memory cache
+	MaxEntries int `toml:"max_entries" envconfig:"CACHE_MAX_ENTRIES"`
+	// If set (e.g. "redis://localhost:6379/0"), the responses are cached in Redis instead of memory
+	RedisURL string `toml:"redis_url" envconfig:"CACHE_REDIS_URL"`
 }
 
 func newConfig() *Config {
 	return &Config{
+		Chain: config.ChainConfig{
+			EthRPCHealthCheckPeriod: 30 * time.Second,
+		},
 		Services: ServicesConfig{
 			Address: "localhost:8000",
+			CORS: CORSConfig{
+				MaxAge: 10 * time.Minute,
+			},
+			QueryLimits: QueryLimitsConfig{
+				MaxLimit:     100,
+				MaxTimeRange: 90 * 24 * time.Hour,
+			},
+		},
+		Cache: CacheConfig{
+			TTL:        5 * time.Minute,
+			MaxEntries: 10000,
 		},
 	}
 }
@@ -1,12 +1,16 @@
 package context
 
 import (
+	sysContext "context"
 	"flag"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
+	"flare-indexer/services/api"
+	"flare-indexer/services/cache"
 	"flare-indexer/services/config"
-
-	"github.com/ethereum/go-ethereum/ethclient"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/contracts/voting"
+	"flare-indexer/utils/staking"
 
 	"gorm.io/gorm"
 )
@@ -14,7 +18,9 @@ import (
 type ServicesContext interface {
 	Config() *config.Config
 	DB() *gorm.DB
-	EthRPCClient() *ethclient.Client
+	EthRPCClient() *chain.ManagedEthClient
+	Cache() cache.Cache
+	Epochs() staking.EpochInfo
 }
 
 type ServicesFlags struct {
@@ -24,7 +30,9 @@ type ServicesFlags struct {
 type servicesContext struct {
 	config       *config.Config
 	db           *gorm.DB
-	ethRPCClient *ethclient.Client
+	ethRPCClient *chain.ManagedEthClient
+	cache        cache.Cache
+	epochs       staking.EpochInfo
 }
 
 func BuildContext() (ServicesContext, error) {
@@ -35,13 +43,36 @@ func BuildContext() (ServicesContext, error) {
 		return nil, err
 	}
 	globalConfig.GlobalConfigCallback.Call(cfg)
+	if err := api.SetAmountFormat(cfg.Services.AmountFormat); err != nil {
+		return nil, err
+	}
+
+	db, err := database.ConnectWithReadReplicas(&cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.UseRequestIDTags(db); err != nil {
+		return nil, err
+	}
+	if cfg.Services.SlowRequestThreshold > 0 {
+		if err := database.UseQueryLog(db); err != nil {
+			return nil, err
+		}
+	}
 
-	db, err := database.Connect(&cfg.DB)
+	ethRPCClient := chain.NewManagedEthClient(cfg.Chain.EthRPCURL, cfg.Chain.GetEthRPCTimeout())
+	ethRPCClient.StartHealthCheck(sysContext.Background(), cfg.Chain.EthRPCHealthCheckPeriod)
+
+	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, ethRPCClient)
+	if err != nil {
+		return nil, err
+	}
+	start, period, err := staking.GetEpochConfig(votingContract)
 	if err != nil {
 		return nil, err
 	}
 
-	ethRPCClient, err := ethclient.Dial(cfg.Chain.EthRPCURL)
+	cache, err := cache.New(&cfg.Cache)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +81,8 @@ func BuildContext() (ServicesContext, error) {
 		config:       cfg,
 		db:           db,
 		ethRPCClient: ethRPCClient,
+		cache:        cache,
+		epochs:       staking.NewEpochInfo(&globalConfig.EpochConfig{}, start, period),
 	}, nil
 }
 
@@ -57,7 +90,11 @@ func (c *servicesContext) Config() *config.Config { return c.config }
 
 func (c *servicesContext) DB() *gorm.DB { return c.db }
 
-func (c *servicesContext) EthRPCClient() *ethclient.Client { return c.ethRPCClient }
+func (c *servicesContext) EthRPCClient() *chain.ManagedEthClient { return c.ethRPCClient }
+
+func (c *servicesContext) Cache() cache.Cache { return c.cache }
+
+func (c *servicesContext) Epochs() staking.EpochInfo { return c.epochs }
 
 func parseServicesFlags() *ServicesFlags {
 	cfgFlag := flag.String("config", globalConfig.CONFIG_FILE, "Configuration file (toml format)")
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flare-indexer/logger"
+	"flare-indexer/services/config"
 	"flare-indexer/services/context"
 	"flare-indexer/services/routes"
 	"flare-indexer/services/utils"
@@ -21,22 +22,45 @@ func main() {
 	}
 
 	muxRouter := mux.NewRouter()
-	router := utils.NewSwaggerRouter(muxRouter, "Flare P-Chain Indexer", "0.1.0")
+	servicesCfg := ctx.Config().Services
+	muxRouter.Use(utils.MetricsMiddleware(servicesCfg.SlowRequestThreshold))
+	muxRouter.Use(queryLimitsMiddleware(servicesCfg.QueryLimits))
+	utils.InitMetricsServer(servicesCfg.PrometheusAddress)
+	router := utils.NewSwaggerRouter(muxRouter, "Flare P-Chain Indexer", "0.1.0", servicesCfg.BasePath)
 	routes.AddTransferRoutes(router, ctx)
+	routes.AddAddressRoutes(router, ctx)
 	routes.AddStakerRoutes(router, ctx)
 	routes.AddTransactionRoutes(router, ctx)
+	routes.AddMirroringRoutes(router, ctx)
+	routes.AddWatchlistRoutes(router, ctx)
+	routes.AddWebhookRoutes(router, ctx)
+	routes.AddLabelRoutes(router, ctx)
+	routes.AddFeeRoutes(router, ctx)
+	routes.AddStatsRoutes(router, ctx)
+	routes.AddBlockRoutes(router, ctx)
+	routes.AddExportRoutes(router, ctx)
+	routes.AddCronjobRunRoutes(router, ctx)
+	routes.AddStatusRoutes(router, ctx)
+	routes.AddTombstoneRoutes(router, ctx)
+	routes.AddChangeRoutes(router, ctx)
+	routes.AddMerkleRootRoutes(router, ctx)
+	routes.AddGasRoutes(router, ctx)
 	// Disabled -- state connector routes are currently not used
 	// routes.AddQueryRoutes(router, ctx)
 
-	if err := routes.AddMirroringRoutes(router, ctx); err != nil {
-		logger.Fatal("Failed to add mirroring routes: %v", err)
-	}
-
 	router.Finalize()
 
-	address := ctx.Config().Services.Address
+	handler := utils.CORSHandler(utils.NegotiationHandler(muxRouter),
+		servicesCfg.CORS.AllowedOrigins, servicesCfg.CORS.AllowedHeaders, servicesCfg.CORS.MaxAge)
+	handler = utils.RequestIDHandler(handler)
+	handler, err = utils.ProxyHandler(handler, servicesCfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	address := servicesCfg.Address
 	srv := &http.Server{
-		Handler: muxRouter,
+		Handler: handler,
 		Addr:    address,
 		// Good practice: enforce timeouts for servers you create -- config?
 		// WriteTimeout: 15 * time.Second,
@@ -57,3 +81,20 @@ func main() {
 	<-cancelChan
 	logger.Info("Shutting down server")
 }
+
+func queryLimitsMiddleware(cfg config.QueryLimitsConfig) mux.MiddlewareFunc {
+	defaults := utils.QueryLimits{
+		MaxLimit:     cfg.MaxLimit,
+		MaxOffset:    cfg.MaxOffset,
+		MaxTimeRange: cfg.MaxTimeRange,
+	}
+	overrides := make(map[string]utils.QueryLimits)
+	for _, o := range cfg.Overrides {
+		overrides[o.Key] = utils.QueryLimits{
+			MaxLimit:     o.MaxLimit,
+			MaxOffset:    o.MaxOffset,
+			MaxTimeRange: o.MaxTimeRange,
+		}
+	}
+	return utils.QueryLimitsMiddleware(defaults, overrides)
+}
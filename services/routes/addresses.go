@@ -0,0 +1,328 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/services/api"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Page size of the activity of an address if the limit is not given
+const defaultActivityLimit = 100
+
+type AddressActivityResponse struct {
+	Type      database.AddressActivityType `json:"type"`
+	Chain     string                       `json:"chain"`
+	TxID      string                       `json:"txID"`
+	TxType    string                       `json:"txType"`
+	Amount    api.Amount                   `json:"amount"`
+	NodeID    string                       `json:"nodeID,omitempty"`
+	Timestamp time.Time                    `json:"timestamp"`
+	// Reward epoch containing the timestamp
+	Epoch *int64 `json:"epoch,omitempty"`
+}
+
+type GetAddressActivityResponse struct {
+	Address  string                    `json:"address"`
+	Activity []AddressActivityResponse `json:"activity"`
+}
+
+type UTXOResponse struct {
+	TxID        string                    `json:"txID"`
+	Idx         uint32                    `json:"idx"`
+	Amount      api.Amount                `json:"amount"`
+	Type        database.PChainOutputType `json:"type"`
+	BlockHeight uint64                    `json:"blockHeight"`
+	// Stake output of a stake which has not ended at the time of the request
+	Locked bool `json:"locked"`
+}
+
+type GetAddressUTXOsResponse struct {
+	Address string `json:"address"`
+	// Last block included and the time of the unspent set, with its reward epoch
+	Height    uint64    `json:"height"`
+	Timestamp time.Time `json:"timestamp"`
+	Epoch     *int64    `json:"epoch,omitempty"`
+	// Number of unspent outputs and their unlocked and locked (staked) amounts,
+	// of all pages
+	Count    int64          `json:"count"`
+	Unlocked api.Amount     `json:"unlocked"`
+	Locked   api.Amount     `json:"locked"`
+	UTXOs    []UTXOResponse `json:"utxos"`
+}
+
+type GetAddressSignedStakesResponse struct {
+	Address string   `json:"address"`
+	TxIDs   []string `json:"txIds"`
+}
+
+type addressRouteHandlers struct {
+	db            *gorm.DB
+	epochs        staking.EpochInfo
+	hrp           string
+	renderAddress func(string) string
+}
+
+func newAddressRouteHandlers(ctx servicesctx.ServicesContext) *addressRouteHandlers {
+	return &addressRouteHandlers{
+		db:            ctx.DB(),
+		epochs:        ctx.Epochs(),
+		hrp:           ctx.Config().Chain.ChainAddressHRP,
+		renderAddress: addressRenderer(ctx),
+	}
+}
+
+// Timeline of the received outputs, spent inputs, stakes and rewards of the
+// address on the P-chain and the X-chain, the newest first
+func (rh *addressRouteHandlers) listAddressActivity() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetAddressActivityResponse, *utils.ErrorHandler) {
+		address, errHandler := parseRequestAddress(rh.hrp, params["address"])
+		if errHandler != nil {
+			return GetAddressActivityResponse{}, errHandler
+		}
+		offset, limit, errHandler := parsePageParams(params, defaultActivityLimit)
+		if errHandler != nil {
+			return GetAddressActivityResponse{}, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckPage(offset, limit); errHandler != nil {
+			return GetAddressActivityResponse{}, errHandler
+		}
+		activity, err := database.FetchAddressActivity(ctx, rh.db, address, time.Now(), offset, limit)
+		if err != nil {
+			return GetAddressActivityResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetAddressActivityResponse{
+			Address:  rh.renderAddress(address),
+			Activity: make([]AddressActivityResponse, len(activity)),
+		}
+		for i, a := range activity {
+			response.Activity[i] = AddressActivityResponse{
+				Type:      a.Type,
+				Chain:     a.Chain,
+				TxID:      a.TxID,
+				TxType:    a.TxType,
+				Amount:    api.NewAmount(a.Amount),
+				NodeID:    a.NodeID,
+				Timestamp: a.Timestamp.UTC(),
+				Epoch:     rh.epochs.EpochOf(&a.Timestamp),
+			}
+		}
+		return response, nil
+	}
+	return utils.NewParamQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{"address:[0-9a-zA-Z-]+": "Bech32 address, with or without the chain prefix (P- or X-), or the hex encoded address bytes (0x)"},
+		map[string]string{
+			"offset": "Number of skipped events",
+			"limit":  "Number of events, 100 if not given",
+		},
+		GetAddressActivityResponse{})
+}
+
+// Converts an address of a request in any accepted format (see
+// chain.NormalizeAddress) to the stored format
+func parseRequestAddress(hrp string, address string) (string, *utils.ErrorHandler) {
+	normalized, err := chain.NormalizeAddress(hrp, address)
+	if err != nil {
+		return "", utils.HttpErrorHandler(http.StatusBadRequest, "invalid address: "+err.Error())
+	}
+	return normalized, nil
+}
+
+// Renders stored addresses in the responses, as P-chain addresses if
+// p_chain_addresses of the services config is set
+func addressRenderer(ctx servicesctx.ServicesContext) func(string) string {
+	if ctx.Config().Services.PChainAddresses {
+		return chain.FormatPChainAddress
+	}
+	return func(address string) string { return address }
+}
+
+// Unspent P-chain outputs of the address and its balance at the time of the
+// asof parameter or after the block of the height parameter, now if neither is
+// given
+func (rh *addressRouteHandlers) listAddressUTXOs() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetAddressUTXOsResponse, *utils.ErrorHandler) {
+		address, errHandler := parseRequestAddress(rh.hrp, params["address"])
+		if errHandler != nil {
+			return GetAddressUTXOsResponse{}, errHandler
+		}
+		asOf, height, errHandler := parseAsOfParams(params)
+		if errHandler != nil {
+			return GetAddressUTXOsResponse{}, errHandler
+		}
+		offset, limit, errHandler := parsePageParams(params, defaultActivityLimit)
+		if errHandler != nil {
+			return GetAddressUTXOsResponse{}, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckPage(offset, limit); errHandler != nil {
+			return GetAddressUTXOsResponse{}, errHandler
+		}
+		h, t, errHandler := rh.resolveAsOf(ctx, asOf, height)
+		if errHandler != nil {
+			return GetAddressUTXOsResponse{}, errHandler
+		}
+
+		totals, err := database.FetchPChainUTXOTotals(ctx, rh.db, address, h, t)
+		if err != nil {
+			return GetAddressUTXOsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		utxos, err := database.FetchPChainUTXOs(ctx, rh.db, address, h, t, offset, limit)
+		if err != nil {
+			return GetAddressUTXOsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetAddressUTXOsResponse{
+			Address:   rh.renderAddress(address),
+			Height:    h,
+			Timestamp: t.UTC(),
+			Epoch:     rh.epochs.EpochOf(&t),
+			Count:     totals.Count,
+			Unlocked:  api.NewBigAmount(totals.Unlocked.Big()),
+			Locked:    api.NewBigAmount(totals.Locked.Big()),
+			UTXOs:     make([]UTXOResponse, len(utxos)),
+		}
+		for i, u := range utxos {
+			response.UTXOs[i] = UTXOResponse{
+				TxID:        u.TxID,
+				Idx:         u.Idx,
+				Amount:      api.NewAmount(u.Amount),
+				Type:        u.Type,
+				BlockHeight: u.BlockHeight,
+				Locked:      u.Locked,
+			}
+		}
+		return response, nil
+	}
+	return utils.NewParamQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{"address:[0-9a-zA-Z-]+": "Bech32 address, with or without the chain prefix (P- or X-), or the hex encoded address bytes (0x)"},
+		map[string]string{
+			"asof":   "Time of the unspent set (RFC 3339), now if neither asof nor height is given",
+			"height": "Height of the last block included in the unspent set",
+			"offset": "Number of skipped outputs",
+			"limit":  "Number of outputs, 100 if not given",
+		},
+		GetAddressUTXOsResponse{})
+}
+
+// Staking transactions signed by the key of the address, in the order of their
+// blocks
+func (rh *addressRouteHandlers) listAddressSignedStakes() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetAddressSignedStakesResponse, *utils.ErrorHandler) {
+		address, errHandler := parseRequestAddress(rh.hrp, params["address"])
+		if errHandler != nil {
+			return GetAddressSignedStakesResponse{}, errHandler
+		}
+		offset, limit, errHandler := parsePageParams(params, defaultActivityLimit)
+		if errHandler != nil {
+			return GetAddressSignedStakesResponse{}, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckPage(offset, limit); errHandler != nil {
+			return GetAddressSignedStakesResponse{}, errHandler
+		}
+		txIDs, err := database.FetchPChainStakingTxIDsBySigner(ctx, rh.db, address, offset, limit)
+		if err != nil {
+			return GetAddressSignedStakesResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return GetAddressSignedStakesResponse{Address: rh.renderAddress(address), TxIDs: txIDs}, nil
+	}
+	return utils.NewParamQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{"address:[0-9a-zA-Z-]+": "Bech32 address, with or without the chain prefix (P- or X-), or the hex encoded address bytes (0x)"},
+		map[string]string{
+			"offset": "Number of skipped transactions",
+			"limit":  "Number of transactions, 100 if not given",
+		},
+		GetAddressSignedStakesResponse{})
+}
+
+// Returns the height of the last block and the time of the unspent set: the
+// last block accepted at or before asOf, the block with the given height and
+// its time, or the last indexed block and the current time
+func (rh *addressRouteHandlers) resolveAsOf(ctx context.Context, asOf *time.Time, height *uint64) (uint64, time.Time, *utils.ErrorHandler) {
+	switch {
+	case asOf != nil:
+		block, err := database.FetchPChainBlockAtTime(ctx, rh.db, *asOf)
+		if err != nil {
+			return 0, time.Time{}, utils.ErrorResponseHandler(err)
+		}
+		return block.BlockHeight, *asOf, nil
+	case height != nil:
+		block, err := database.FetchPChainBlock(ctx, rh.db, *height)
+		if err != nil {
+			return 0, time.Time{}, utils.ErrorResponseHandler(err)
+		}
+		return block.Height, block.Timestamp, nil
+	default:
+		_, maxHeight, err := database.FetchPChainBlockHeightRange(ctx, rh.db)
+		if err != nil {
+			return 0, time.Time{}, utils.ErrorResponseHandler(err)
+		}
+		return maxHeight, time.Now(), nil
+	}
+}
+
+// Parses the asof (RFC 3339 time) and height query parameters, at most one of
+// them may be given
+func parseAsOfParams(params map[string]string) (*time.Time, *uint64, *utils.ErrorHandler) {
+	asOfParam, hasAsOf := params["asof"]
+	heightParam, hasHeight := params["height"]
+	if hasAsOf && hasHeight {
+		return nil, nil, utils.HttpErrorHandler(http.StatusBadRequest, "asof and height cannot both be given")
+	}
+	if hasAsOf {
+		asOf, err := time.Parse(time.RFC3339, asOfParam)
+		if err != nil {
+			return nil, nil, utils.HttpErrorHandler(http.StatusBadRequest, "invalid asof, expected an RFC 3339 time")
+		}
+		return &asOf, nil, nil
+	}
+	if hasHeight {
+		height, err := strconv.ParseUint(heightParam, 10, 64)
+		if err != nil {
+			return nil, nil, utils.HttpErrorHandler(http.StatusBadRequest, "invalid height")
+		}
+		return nil, &height, nil
+	}
+	return nil, nil, nil
+}
+
+// Parses the offset and limit query parameters, the limit is defaultLimit if
+// not given
+func parsePageParams(params map[string]string, defaultLimit int) (int, int, *utils.ErrorHandler) {
+	offset, limit := 0, defaultLimit
+	if offsetParam, ok := params["offset"]; ok {
+		var err error
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return 0, 0, utils.HttpErrorHandler(http.StatusBadRequest, "invalid offset")
+		}
+	}
+	if limitParam, ok := params["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			return 0, 0, utils.HttpErrorHandler(http.StatusBadRequest, "invalid limit")
+		}
+	}
+	return offset, limit, nil
+}
+
+func AddAddressRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newAddressRouteHandlers(ctx)
+
+	subrouter := router.WithPrefix("/addresses", "Addresses")
+	// The activity includes the starts and ends of stakes up to now and is not cached
+	subrouter.AddRoute("/{address:[0-9a-zA-Z-]+}/activity", rh.listAddressActivity(),
+		"Activity of an address on the P-chain and the X-chain, the newest first")
+	// Unspent sets of past times do not change, but the current one does
+	subrouter.AddRoute("/{address:[0-9a-zA-Z-]+}/utxos", rh.listAddressUTXOs(),
+		"Unspent P-chain outputs and balance of an address at a time or block height")
+	subrouter.AddRoute("/{address:[0-9a-zA-Z-]+}/signed_stakes", rh.listAddressSignedStakes(),
+		"Ids of the staking transactions signed by the key of an address")
+}
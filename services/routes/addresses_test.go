@@ -0,0 +1,69 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestAddress(t *testing.T) {
+	stored := "flare1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnplamjk"
+	for _, address := range []string{stored, "P-" + stored, "X-" + stored, "0x000102030405060708090a0b0c0d0e0f10111213"} {
+		normalized, errHandler := parseRequestAddress("flare", address)
+		require.Nil(t, errHandler, address)
+		require.Equal(t, stored, normalized, address)
+	}
+
+	_, errHandler := parseRequestAddress("costwo", stored)
+	require.NotNil(t, errHandler)
+	_, errHandler = parseRequestAddress("flare", "flare1abc")
+	require.NotNil(t, errHandler)
+}
+
+func TestParsePageParams(t *testing.T) {
+	offset, limit, err := parsePageParams(map[string]string{}, 100)
+	require.Nil(t, err)
+	require.Equal(t, 0, offset)
+	require.Equal(t, 100, limit)
+
+	offset, limit, err = parsePageParams(map[string]string{"offset": "20", "limit": "10"}, 100)
+	require.Nil(t, err)
+	require.Equal(t, 20, offset)
+	require.Equal(t, 10, limit)
+
+	for _, params := range []map[string]string{{"offset": "-1"}, {"offset": "x"}, {"limit": "0"}, {"limit": "x"}} {
+		_, _, err = parsePageParams(params, 100)
+		require.NotNil(t, err, params)
+	}
+}
+
+func TestParseAsOfParams(t *testing.T) {
+	asOf, height, err := parseAsOfParams(map[string]string{})
+	require.Nil(t, err)
+	require.Nil(t, asOf)
+	require.Nil(t, height)
+
+	asOf, height, err = parseAsOfParams(map[string]string{"asof": "2023-07-01T12:00:00+02:00"})
+	require.Nil(t, err)
+	require.True(t, asOf.Equal(time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)))
+	require.Nil(t, height)
+
+	asOf, height, err = parseAsOfParams(map[string]string{"height": "1200"})
+	require.Nil(t, err)
+	require.Nil(t, asOf)
+	require.Equal(t, uint64(1200), *height)
+
+	for _, params := range []map[string]string{
+		{"asof": "2023-07-01"},
+		{"height": "-1"},
+		{"height": "x"},
+		{"asof": "2023-07-01T12:00:00Z", "height": "1200"},
+	} {
+		_, _, err = parseAsOfParams(params)
+		require.NotNil(t, err, params)
+	}
+}
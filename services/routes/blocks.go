@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GetBlockAtTimeRequest struct {
+	Time time.Time `json:"time" validate:"required"`
+}
+
+type BlockResponse struct {
+	Height         uint64    `json:"height"`
+	ContainerIndex uint64    `json:"containerIndex"`
+	BlockID        string    `json:"blockID"`
+	Timestamp      time.Time `json:"timestamp"`
+	// Reward epoch containing the timestamp
+	Epoch *int64 `json:"epoch,omitempty"`
+}
+
+type GetBlocksRequest struct {
+	PaginatedRequest
+}
+
+type PChainBlockResponse struct {
+	BlockResponse
+	ParentID string   `json:"parentID"`
+	Proposer string   `json:"proposer"`
+	TxCount  int      `json:"txCount"`
+	TxIDs    []string `json:"txIDs,omitempty"`
+}
+
+type blockRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+}
+
+func newBlockRouteHandlers(ctx servicesctx.ServicesContext) *blockRouteHandlers {
+	return &blockRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+	}
+}
+
+func (rh *blockRouteHandlers) getBlockAtTime() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetBlockAtTimeRequest) (BlockResponse, *utils.ErrorHandler) {
+		block, err := database.FetchPChainBlockAtTime(ctx, rh.db, request.Time)
+		if err != nil {
+			return BlockResponse{}, utils.ErrorResponseHandler(err)
+		}
+		containerIndex := chain.PChainContainerIndex(block.BlockHeight)
+		stored, err := database.FetchPChainBlock(ctx, rh.db, block.BlockHeight)
+		if err == nil {
+			containerIndex = blockContainerIndex(stored)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return BlockResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return BlockResponse{
+			Height:         block.BlockHeight,
+			ContainerIndex: containerIndex,
+			BlockID:        block.BlockID,
+			Timestamp:      block.Timestamp,
+			Epoch:          rh.epochs.EpochOf(&block.Timestamp),
+		}, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetBlockAtTimeRequest{}, BlockResponse{})
+}
+
+func (rh *blockRouteHandlers) getBlock() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (*PChainBlockResponse, *utils.ErrorHandler) {
+		height, err := strconv.ParseUint(params["height"], 10, 64)
+		if err != nil {
+			return nil, utils.HttpErrorHandler(http.StatusBadRequest, "invalid block height")
+		}
+		block, err := database.FetchPChainBlock(ctx, rh.db, height)
+		return rh.blockWithTxIDs(ctx, block, err)
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet,
+		map[string]string{"height:[0-9]+": "Block height"},
+		&PChainBlockResponse{})
+}
+
+func (rh *blockRouteHandlers) getBlockByContainerIndex() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (*PChainBlockResponse, *utils.ErrorHandler) {
+		index, err := strconv.ParseUint(params["index"], 10, 64)
+		if err != nil {
+			return nil, utils.HttpErrorHandler(http.StatusBadRequest, "invalid container index")
+		}
+		block, err := database.FetchPChainBlockByContainerIndex(ctx, rh.db, index)
+		return rh.blockWithTxIDs(ctx, block, err)
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet,
+		map[string]string{"index:[0-9]+": "Container index of the block in the avalanchego indexer"},
+		&PChainBlockResponse{})
+}
+
+// Response with the transaction ids of block, fetched with error err
+func (rh *blockRouteHandlers) blockWithTxIDs(ctx context.Context, block *database.PChainBlock, err error) (*PChainBlockResponse, *utils.ErrorHandler) {
+	if err != nil {
+		return nil, utils.ErrorResponseHandler(err)
+	}
+	txIDs, err := database.FetchPChainBlockTxIDs(ctx, rh.db, block.Height)
+	if err != nil {
+		return nil, utils.InternalServerErrorHandler(err)
+	}
+	response := newPChainBlockResponse(block, rh.epochs)
+	response.TxIDs = txIDs
+	return &response, nil
+}
+
+func (rh *blockRouteHandlers) listBlocks() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetBlocksRequest) ([]PChainBlockResponse, *utils.ErrorHandler) {
+		blocks, err := database.FetchPChainBlocks(ctx, rh.db, request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]PChainBlockResponse, len(blocks))
+		for i := range blocks {
+			response[i] = newPChainBlockResponse(&blocks[i], rh.epochs)
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetBlocksRequest{}, []PChainBlockResponse{})
+}
+
+func newPChainBlockResponse(block *database.PChainBlock, epochs staking.EpochInfo) PChainBlockResponse {
+	return PChainBlockResponse{
+		BlockResponse: BlockResponse{
+			Height:         block.Height,
+			ContainerIndex: blockContainerIndex(block),
+			BlockID:        block.BlockID,
+			Timestamp:      block.Timestamp,
+			Epoch:          epochs.EpochOf(&block.Timestamp),
+		},
+		ParentID: block.ParentID,
+		Proposer: block.Proposer,
+		TxCount:  block.TxCount,
+	}
+}
+
+// Stored container index of the block, or derived from the height if it is not stored
+func blockContainerIndex(block *database.PChainBlock) uint64 {
+	if block.ContainerIndex != nil {
+		return *block.ContainerIndex
+	}
+	return chain.PChainContainerIndex(block.Height)
+}
+
+func AddBlockRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newBlockRouteHandlers(ctx)
+	version := indexedDataVersion(ctx)
+	subrouter := router.WithPrefix("/blocks", "Blocks")
+	subrouter.AddRoute("/at-time", utils.ConditionalRouteHandler(rh.getBlockAtTime(), version),
+		"Last P-chain block accepted at or before the given time")
+	subrouter.AddRoute("/get/{height:[0-9]+}", utils.ConditionalRouteHandler(rh.getBlock(), version),
+		"P-chain block with the ids of its transactions")
+	subrouter.AddRoute("/container/{index:[0-9]+}", utils.ConditionalRouteHandler(rh.getBlockByContainerIndex(), version),
+		"P-chain block with the given container index of the avalanchego indexer, with the ids of its transactions")
+	subrouter.AddRoute("/list", utils.ConditionalRouteHandler(rh.listBlocks(), version), "P-chain blocks, the newest first")
+}
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Maximal length of the entity (table name) of the change feed
+const maxChangeFeedEntityLength = 64
+
+type ChangeResponse struct {
+	ID       uint64            `json:"id"`
+	Entity   string            `json:"entity"`
+	EntityID uint64            `json:"entityId"`
+	Op       database.ChangeOp `json:"op"`
+	// Block (vertex) height of the changed row
+	Height    uint64    `json:"height"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type GetChangesResponse struct {
+	Changes []ChangeResponse `json:"changes"`
+	// Value of the after parameter of the next request, the id of the last
+	// change or the after parameter of this request if there are no changes
+	Next uint64 `json:"next"`
+}
+
+type changeRouteHandlers struct {
+	db *gorm.DB
+}
+
+func newChangeRouteHandlers(ctx servicesctx.ServicesContext) *changeRouteHandlers {
+	return &changeRouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+func (rh *changeRouteHandlers) listChanges() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetChangesResponse, *utils.ErrorHandler) {
+		after, limit, entity, errHandler := parseChangeFeedParams(params)
+		if errHandler != nil {
+			return GetChangesResponse{}, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckPage(0, limit); errHandler != nil {
+			return GetChangesResponse{}, errHandler
+		}
+		entries, err := database.FetchChangeFeed(ctx, rh.db, entity, after, limit)
+		if err != nil {
+			return GetChangesResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetChangesResponse{
+			Changes: make([]ChangeResponse, len(entries)),
+			Next:    after,
+		}
+		for i, e := range entries {
+			response.Changes[i] = ChangeResponse{
+				ID:        e.ID,
+				Entity:    e.Entity,
+				EntityID:  e.EntityID,
+				Op:        e.Op,
+				Height:    e.Height,
+				Timestamp: e.Timestamp.UTC(),
+			}
+			response.Next = e.ID
+		}
+		return response, nil
+	}
+	return utils.NewQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{
+			"after":  "Id of the last change already read, 0 if not given",
+			"limit":  "Number of changes, 100 if not given",
+			"entity": "Table of the changed rows (including the table prefix), all tables if not given",
+		},
+		GetChangesResponse{})
+}
+
+// Parses the after, limit and entity query parameters of the change feed
+func parseChangeFeedParams(params map[string]string) (uint64, int, string, *utils.ErrorHandler) {
+	var after uint64
+	if afterParam, ok := params["after"]; ok {
+		var err error
+		after, err = strconv.ParseUint(afterParam, 10, 64)
+		if err != nil {
+			return 0, 0, "", utils.HttpErrorHandler(http.StatusBadRequest, "invalid after")
+		}
+	}
+	_, limit, errHandler := parsePageParams(params, 100)
+	if errHandler != nil {
+		return 0, 0, "", errHandler
+	}
+	entity := params["entity"]
+	if len(entity) > maxChangeFeedEntityLength {
+		return 0, 0, "", utils.HttpErrorHandler(http.StatusBadRequest, "invalid entity")
+	}
+	return after, limit, entity, nil
+}
+
+func AddChangeRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newChangeRouteHandlers(ctx)
+
+	subrouter := router.WithPrefix("/changes", "Changes")
+	subrouter.AddRoute("", rh.listChanges(),
+		"Created, updated and deleted rows of the indexed chain data, in the order of the changes")
+}
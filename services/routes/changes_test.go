@@ -0,0 +1,32 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChangeFeedParams(t *testing.T) {
+	after, limit, entity, err := parseChangeFeedParams(map[string]string{})
+	require.Nil(t, err)
+	require.Zero(t, after)
+	require.Equal(t, 100, limit)
+	require.Empty(t, entity)
+
+	after, limit, entity, err = parseChangeFeedParams(map[string]string{"after": "1234", "limit": "50", "entity": "p_chain_txes"})
+	require.Nil(t, err)
+	require.EqualValues(t, 1234, after)
+	require.Equal(t, 50, limit)
+	require.Equal(t, "p_chain_txes", entity)
+
+	for _, params := range []map[string]string{
+		{"after": "-1"}, {"after": "x"}, {"limit": "0"}, {"entity": strings.Repeat("a", 65)},
+	} {
+		_, _, _, err = parseChangeFeedParams(params)
+		require.NotNil(t, err, params)
+	}
+}
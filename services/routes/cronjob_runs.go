@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GetCronjobRunsRequest struct {
+	PaginatedRequest
+	// Name of the cronjob, runs of all cronjobs if empty
+	Name string `json:"name" validate:"max=50"`
+	// Only runs which processed the epoch
+	Epoch *int64 `json:"epoch" validate:"omitempty,min=0"`
+}
+
+type CronjobRunResponse struct {
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+	// Reward epoch containing the start of the run
+	StartedEpoch *int64                    `json:"startedEpoch,omitempty"`
+	Duration     int64                     `json:"duration"`
+	Status       database.CronjobRunStatus `json:"status"`
+	Error        string                    `json:"error,omitempty"`
+	Items        int                       `json:"items"`
+	FirstEpoch   *int64                    `json:"firstEpoch"`
+	LastEpoch    *int64                    `json:"lastEpoch"`
+}
+
+type cronjobRunRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+}
+
+func newCronjobRunRouteHandlers(ctx servicesctx.ServicesContext) *cronjobRunRouteHandlers {
+	return &cronjobRunRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+	}
+}
+
+func (rh *cronjobRunRouteHandlers) listRuns() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetCronjobRunsRequest) ([]CronjobRunResponse, *utils.ErrorHandler) {
+		runs, err := database.FetchCronjobRuns(ctx, rh.db, request.Name, request.Epoch, request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		return newCronjobRunResponses(runs, rh.epochs), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetCronjobRunsRequest{}, []CronjobRunResponse{})
+}
+
+func newCronjobRunResponses(runs []database.CronjobRun, epochs staking.EpochInfo) []CronjobRunResponse {
+	response := make([]CronjobRunResponse, len(runs))
+	for i, r := range runs {
+		response[i] = CronjobRunResponse{
+			Name:         r.Name,
+			Started:      r.Started.UTC(),
+			StartedEpoch: epochs.EpochOf(&r.Started),
+			Duration:     r.Duration,
+			Status:       r.Status,
+			Error:        r.Error,
+			Items:        r.Items,
+			FirstEpoch:   r.FirstEpoch,
+			LastEpoch:    r.LastEpoch,
+		}
+	}
+	return response
+}
+
+func AddCronjobRunRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newCronjobRunRouteHandlers(ctx)
+	adminToken := ctx.Config().Services.AdminToken
+
+	subrouter := router.WithPrefix("/admin", "Admin")
+	subrouter.AddRoute("/cronjob_runs", utils.AdminRouteHandler(rh.listRuns(), adminToken),
+		"Runs of the indexer cronjobs, the newest first", "Requires the admin token")
+}
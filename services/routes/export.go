@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var stakerCSVHeader = []string{
+	"txID", "nodeID", "startTime", "endTime", "weight", "feePercentage", "inputAddresses", "startEpoch", "endEpoch",
+}
+
+type ExportStakersRequest struct {
+	utils.ExportRequest
+	Epoch int64 `json:"epoch" validate:"min=0"`
+}
+
+// Input addresses are separated by spaces in the CSV export
+func (s GetStakerResponse) CSVRecord() []string {
+	return []string{
+		s.TxID,
+		s.NodeID,
+		s.StartTime.UTC().Format(time.RFC3339),
+		s.EndTime.UTC().Format(time.RFC3339),
+		s.Weight.String(),
+		strconv.FormatUint(uint64(s.FeePercentage), 10),
+		strings.Join(s.InputAddresses, " "),
+		formatEpoch(s.StartEpoch),
+		formatEpoch(s.EndEpoch),
+	}
+}
+
+// Empty if the epoch is not known
+func formatEpoch(epoch *int64) string {
+	if epoch == nil {
+		return ""
+	}
+	return strconv.FormatInt(*epoch, 10)
+}
+
+type exportRouteHandlers struct {
+	db            *gorm.DB
+	epochs        staking.EpochInfo
+	renderAddress func(string) string
+}
+
+func newExportRouteHandlers(ctx servicesctx.ServicesContext) *exportRouteHandlers {
+	return &exportRouteHandlers{
+		db:            ctx.DB(),
+		epochs:        ctx.Epochs(),
+		renderAddress: addressRenderer(ctx),
+	}
+}
+
+// Exports the stakers of type txType (permissionless ones included) starting
+// in the requested epoch, i.e., the transactions the stakes voted on and
+// mirrored for the epoch are selected from. The staking filter is not applied.
+func (rh *exportRouteHandlers) exportStakers(txType database.PChainTxType, name string) utils.RouteHandler {
+	handler := func(ctx context.Context, request ExportStakersRequest, w *utils.ExportWriter) error {
+		from, to := rh.epochs.GetTimeRange(request.Epoch)
+		// The stream is bound by the request, not by the statement timeout
+		db := database.WithoutStatementTimeout(rh.db)
+		return database.StreamPChainStakingData(ctx, db, txType, from, to, func(tx *database.PChainTxData) error {
+			return w.Write(newGetStakerResponse(tx, rh.epochs, rh.renderAddress))
+		})
+	}
+	return utils.NewExportRouteHandler(handler, http.MethodPost, ExportStakersRequest{},
+		name, stakerCSVHeader, GetStakerResponse{})
+}
+
+func AddExportRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newExportRouteHandlers(ctx)
+
+	subrouter := router.WithPrefix("/export", "Export")
+	subrouter.AddRoute("/validators", rh.exportStakers(database.PChainAddValidatorTx, "validators"),
+		"Validators starting in an epoch as JSON Lines or CSV")
+	subrouter.AddRoute("/delegators", rh.exportStakers(database.PChainAddDelegatorTx, "delegators"),
+		"Delegations starting in an epoch as JSON Lines or CSV")
+}
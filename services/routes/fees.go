@@ -0,0 +1,185 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/services/api"
+	"flare-indexer/services/cache"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	day = 24 * time.Hour
+
+	// Maximal number of days or epochs in a single burn statistics request
+	maxBurnStatsBuckets = 1000
+)
+
+type GetDailyBurnStatsRequest struct {
+	// Days (UTC) of both times are included
+	From time.Time `json:"from" validate:"required"`
+	To   time.Time `json:"to" validate:"required"`
+}
+
+type GetEpochBurnStatsRequest struct {
+	// Both epochs are included
+	From int64 `json:"from" validate:"min=0"`
+	To   int64 `json:"to" validate:"gtefield=From"`
+}
+
+type BurnStatsResponse struct {
+	Epoch *int64    `json:"epoch,omitempty"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Reward epochs containing the start and end of a day
+	StartEpoch *int64 `json:"startEpoch,omitempty"`
+	EndEpoch   *int64 `json:"endEpoch,omitempty"`
+	// Burned amount and number of transactions of both chains
+	Burned  api.Amount `json:"burned"`
+	TxCount uint64     `json:"txCount"`
+	// Burned amounts of the P-chain and X-chain transactions
+	PChainBurned api.Amount `json:"pChainBurned"`
+	XChainBurned api.Amount `json:"xChainBurned"`
+}
+
+type feeRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+}
+
+func newFeeRouteHandlers(ctx servicesctx.ServicesContext) *feeRouteHandlers {
+	return &feeRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+	}
+}
+
+func (rh *feeRouteHandlers) dailyBurnStats() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetDailyBurnStatsRequest) ([]BurnStatsResponse, *utils.ErrorHandler) {
+		from := request.From.UTC().Truncate(day)
+		to := request.To.UTC().Truncate(day).Add(day)
+		if errHandler := checkBurnStatsRange(from, to, day); errHandler != nil {
+			return nil, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckTimeRange(from, to); errHandler != nil {
+			return nil, errHandler
+		}
+		start := time.Unix(0, 0).UTC()
+		pStats, xStats, err := rh.fetchBurnStats(ctx, start, day, from, to)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := newBurnStatsResponses(pStats, xStats, start, day, false)
+		for i := range response {
+			response[i].StartEpoch = rh.epochs.EpochOf(&response[i].Start)
+			response[i].EndEpoch = rh.epochs.EpochOf(&response[i].End)
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetDailyBurnStatsRequest{}, []BurnStatsResponse{})
+}
+
+func (rh *feeRouteHandlers) epochBurnStats() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetEpochBurnStatsRequest) ([]BurnStatsResponse, *utils.ErrorHandler) {
+		from := rh.epochs.GetStartTime(request.From)
+		to := rh.epochs.GetEndTime(request.To)
+		if errHandler := checkBurnStatsRange(from, to, rh.epochs.Period); errHandler != nil {
+			return nil, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckTimeRange(from, to); errHandler != nil {
+			return nil, errHandler
+		}
+		pStats, xStats, err := rh.fetchBurnStats(ctx, rh.epochs.Start, rh.epochs.Period, from, to)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		return newBurnStatsResponses(pStats, xStats, rh.epochs.Start, rh.epochs.Period, true), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetEpochBurnStatsRequest{}, []BurnStatsResponse{})
+}
+
+func checkBurnStatsRange(from time.Time, to time.Time, bucket time.Duration) *utils.ErrorHandler {
+	if !to.After(from) {
+		return utils.HttpErrorHandler(http.StatusBadRequest, "empty time range")
+	}
+	if to.Sub(from)/bucket > maxBurnStatsBuckets {
+		return utils.HttpErrorHandler(http.StatusBadRequest,
+			fmt.Sprintf("at most %d days or epochs can be requested", maxBurnStatsBuckets))
+	}
+	return nil
+}
+
+// Burn statistics of the P-chain and of the X-chain transactions
+func (rh *feeRouteHandlers) fetchBurnStats(ctx context.Context, start time.Time, bucket time.Duration, from time.Time, to time.Time) (
+	[]database.BurnStats, []database.BurnStats, error,
+) {
+	pStats, err := database.FetchPChainBurnStats(ctx, rh.db, start, bucket, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	xStats, err := database.FetchXChainBurnStats(ctx, rh.db, start, bucket, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pStats, xStats, nil
+}
+
+// Buckets of both chains are merged, buckets without transactions on both
+// chains are omitted
+func newBurnStatsResponses(pStats []database.BurnStats, xStats []database.BurnStats, start time.Time, bucket time.Duration, withEpoch bool) []BurnStatsResponse {
+	pBurned := make(map[int64]database.BurnStats, len(pStats))
+	xBurned := make(map[int64]database.BurnStats, len(xStats))
+	var buckets []int64
+	for _, s := range pStats {
+		pBurned[s.Bucket] = s
+		buckets = append(buckets, s.Bucket)
+	}
+	for _, s := range xStats {
+		if _, ok := pBurned[s.Bucket]; !ok {
+			buckets = append(buckets, s.Bucket)
+		}
+		xBurned[s.Bucket] = s
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	response := make([]BurnStatsResponse, len(buckets))
+	for i, b := range buckets {
+		p, x := pBurned[b], xBurned[b]
+		bucketStart := start.Add(time.Duration(b) * bucket)
+		response[i] = BurnStatsResponse{
+			Start:        bucketStart,
+			End:          bucketStart.Add(bucket),
+			Burned:       api.NewBigAmount(new(big.Int).Add(p.Burned.Big(), x.Burned.Big())),
+			TxCount:      p.TxCount + x.TxCount,
+			PChainBurned: api.NewBigAmount(p.Burned.Big()),
+			XChainBurned: api.NewBigAmount(x.Burned.Big()),
+		}
+		if withEpoch {
+			epoch := b
+			response[i].Epoch = &epoch
+		}
+	}
+	return response
+}
+
+func AddFeeRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newFeeRouteHandlers(ctx)
+	expiration := cache.EpochExpiration(ctx.Config().Cache.TTL, ctx.Epochs())
+	version := indexedDataVersion(ctx)
+	cached := func(handler utils.RouteHandler) utils.RouteHandler {
+		return utils.ConditionalRouteHandler(utils.CachedRouteHandler(handler, ctx.Cache(), expiration), version)
+	}
+
+	subrouter := router.WithPrefix("/fees", "Fees")
+	subrouter.AddRoute("/daily", cached(rh.dailyBurnStats()), "Burned fees of P-chain and X-chain transactions per day (UTC)")
+	subrouter.AddRoute("/epochs", cached(rh.epochBurnStats()), "Burned fees of P-chain and X-chain transactions per reward epoch")
+}
@@ -0,0 +1,63 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/services/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBurnStatsResponses(t *testing.T) {
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	pStats := []database.BurnStats{
+		{Bucket: 2, Burned: database.NewBigInt(1000), TxCount: 3},
+		{Bucket: 5, Burned: database.NewBigInt(10), TxCount: 1},
+	}
+	xStats := []database.BurnStats{
+		{Bucket: 1, Burned: database.NewBigInt(7), TxCount: 1},
+		{Bucket: 2, Burned: database.NewBigInt(20), TxCount: 2},
+	}
+
+	response := newBurnStatsResponses(pStats, xStats, start, time.Hour, true)
+	require.Len(t, response, 3)
+	require.Equal(t, int64(1), *response[0].Epoch)
+	require.Equal(t, "7", response[0].Burned.String())
+	require.Equal(t, "0", response[0].PChainBurned.String())
+	require.Equal(t, "7", response[0].XChainBurned.String())
+	require.Equal(t, int64(2), *response[1].Epoch)
+	require.Equal(t, start.Add(2*time.Hour), response[1].Start)
+	require.Equal(t, start.Add(3*time.Hour), response[1].End)
+	require.Equal(t, "1020", response[1].Burned.String())
+	require.Equal(t, "1000", response[1].PChainBurned.String())
+	require.Equal(t, "20", response[1].XChainBurned.String())
+	require.Equal(t, uint64(5), response[1].TxCount)
+	require.Equal(t, start.Add(5*time.Hour), response[2].Start)
+	require.Equal(t, "0", response[2].XChainBurned.String())
+
+	response = newBurnStatsResponses(pStats, nil, start, time.Hour, false)
+	require.Len(t, response, 2)
+	require.Nil(t, response[0].Epoch)
+}
+
+func TestCheckBurnStatsRange(t *testing.T) {
+	from := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	require.Nil(t, checkBurnStatsRange(from, from.Add(maxBurnStatsBuckets*day), day))
+	requireBadRequest(t, checkBurnStatsRange(from, from, day))
+	requireBadRequest(t, checkBurnStatsRange(from, from.Add((maxBurnStatsBuckets+1)*day), day))
+}
+
+func requireBadRequest(t *testing.T, errHandler *utils.ErrorHandler) {
+	t.Helper()
+	require.NotNil(t, errHandler)
+	w := httptest.NewRecorder()
+	errHandler.Handler(w)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
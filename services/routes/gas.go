@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+type GetGasSpendRequest struct {
+	// Name of the cronjob, e.g. "mirror" or "voting", all cronjobs if empty
+	Cronjob string `json:"cronjob" validate:"max=50"`
+	// Both epochs are included, submissions not bound to an epoch (e.g. of the
+	// address binder) are excluded if any of them is set
+	FirstEpoch *int64 `json:"firstEpoch" validate:"omitempty,min=0"`
+	LastEpoch  *int64 `json:"lastEpoch" validate:"omitempty,min=0"`
+}
+
+type GasSpendEntry struct {
+	Cronjob     string `json:"cronjob"`
+	Epoch       *int64 `json:"epoch,omitempty"`
+	Submissions int64  `json:"submissions"`
+	Reverted    int64  `json:"reverted"`
+	GasUsed     uint64 `json:"gasUsed"`
+	// Fees in FLR
+	Fee float64 `json:"fee"`
+}
+
+type GasSpendResponse struct {
+	// Gas spend per epoch and cronjob, ordered by epoch
+	Epochs []GasSpendEntry `json:"epochs"`
+	// Gas spend of all requested epochs per cronjob
+	Totals []GasSpendEntry `json:"totals"`
+}
+
+type gasRouteHandlers struct {
+	db *gorm.DB
+}
+
+func newGasRouteHandlers(ctx servicesctx.ServicesContext) *gasRouteHandlers {
+	return &gasRouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+func (rh *gasRouteHandlers) gasSpend() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetGasSpendRequest) (GasSpendResponse, *utils.ErrorHandler) {
+		if request.FirstEpoch != nil && request.LastEpoch != nil && *request.LastEpoch < *request.FirstEpoch {
+			return GasSpendResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, "lastEpoch must not be before firstEpoch")
+		}
+		filter := &database.GasSpendFilter{
+			Cronjob:    request.Cronjob,
+			FirstEpoch: request.FirstEpoch,
+			LastEpoch:  request.LastEpoch,
+		}
+		epochs, err := database.FetchGasSpendByEpoch(ctx, rh.db, filter)
+		if err != nil {
+			return GasSpendResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		totals, err := database.FetchGasSpendTotals(ctx, rh.db, filter)
+		if err != nil {
+			return GasSpendResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return GasSpendResponse{
+			Epochs: newGasSpendEntries(epochs),
+			Totals: newGasSpendEntries(totals),
+		}, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetGasSpendRequest{}, GasSpendResponse{})
+}
+
+func newGasSpendEntries(spend []database.GasSpend) []GasSpendEntry {
+	entries := make([]GasSpendEntry, len(spend))
+	for i, s := range spend {
+		entries[i] = GasSpendEntry{
+			Cronjob:     s.Cronjob,
+			Epoch:       s.Epoch,
+			Submissions: s.Submissions,
+			Reverted:    s.Reverted,
+			GasUsed:     s.GasUsed,
+			Fee:         s.Fee,
+		}
+	}
+	return entries
+}
+
+func AddGasRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newGasRouteHandlers(ctx)
+	adminToken := ctx.Config().Services.AdminToken
+
+	subrouter := router.WithPrefix("/admin", "Admin")
+	subrouter.AddRoute("/gas_spend", utils.AdminRouteHandler(rh.gasSpend(), adminToken),
+		"Gas used and fees paid by the contract transactions of the indexer cronjobs", "Requires the admin token")
+}
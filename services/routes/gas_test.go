@@ -0,0 +1,21 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasSpendInvalidRange(t *testing.T) {
+	rh := &gasRouteHandlers{}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/admin/gas_spend", strings.NewReader(`{"firstEpoch": 10, "lastEpoch": 9}`))
+	rh.gasSpend().Handler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ValidatorLabelResponse struct {
+	NodeID       string `json:"nodeId"`
+	Name         string `json:"name"`
+	Website      string `json:"website,omitempty"`
+	FtsoProvider string `json:"ftsoProvider,omitempty"`
+}
+
+type SetValidatorLabelRequest struct {
+	NodeID       string `json:"nodeId" validate:"node-id"`
+	Name         string `json:"name" validate:"required,max=100"`
+	Website      string `json:"website" validate:"omitempty,url,max=200"`
+	FtsoProvider string `json:"ftsoProvider" validate:"omitempty,eth_addr"`
+}
+
+type RemoveValidatorLabelRequest struct {
+	NodeID string `json:"nodeId" validate:"required"`
+}
+
+type labelRouteHandlers struct {
+	db *gorm.DB
+}
+
+func newLabelRouteHandlers(ctx servicesctx.ServicesContext) *labelRouteHandlers {
+	return &labelRouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+func (rh *labelRouteHandlers) listLabels() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) ([]ValidatorLabelResponse, *utils.ErrorHandler) {
+		labels, err := database.FetchValidatorLabels(ctx, rh.db, nil)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]ValidatorLabelResponse, len(labels))
+		for i := range labels {
+			response[i] = newValidatorLabelResponse(&labels[i])
+		}
+		return response, nil
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet, map[string]string{}, []ValidatorLabelResponse{})
+}
+
+func (rh *labelRouteHandlers) setLabel() utils.RouteHandler {
+	handler := func(ctx context.Context, request SetValidatorLabelRequest) (ValidatorLabelResponse, *utils.ErrorHandler) {
+		label := database.ValidatorLabel{
+			NodeID:       request.NodeID,
+			Name:         request.Name,
+			Website:      request.Website,
+			FtsoProvider: request.FtsoProvider,
+			Updated:      time.Now(),
+		}
+		if err := database.UpsertValidatorLabels(ctx, rh.db, []*database.ValidatorLabel{&label}); err != nil {
+			return ValidatorLabelResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return newValidatorLabelResponse(&label), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, SetValidatorLabelRequest{}, ValidatorLabelResponse{})
+}
+
+func (rh *labelRouteHandlers) removeLabel() utils.RouteHandler {
+	handler := func(ctx context.Context, request RemoveValidatorLabelRequest) (bool, *utils.ErrorHandler) {
+		err := database.DeleteValidatorLabel(ctx, rh.db, request.NodeID)
+		if err != nil {
+			return false, utils.ErrorResponseHandler(err)
+		}
+		return true, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, RemoveValidatorLabelRequest{}, true)
+}
+
+func newValidatorLabelResponse(l *database.ValidatorLabel) ValidatorLabelResponse {
+	return ValidatorLabelResponse{
+		NodeID:       l.NodeID,
+		Name:         l.Name,
+		Website:      l.Website,
+		FtsoProvider: l.FtsoProvider,
+	}
+}
+
+// Returns the labels of the node IDs by node ID
+func fetchLabelsByNodeID(ctx context.Context, db *gorm.DB, nodeIDs []string) (map[string]*ValidatorLabelResponse, error) {
+	labels, err := database.FetchValidatorLabels(ctx, db, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+	byNodeID := make(map[string]*ValidatorLabelResponse, len(labels))
+	for i := range labels {
+		response := newValidatorLabelResponse(&labels[i])
+		byNodeID[response.NodeID] = &response
+	}
+	return byNodeID, nil
+}
+
+func AddLabelRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newLabelRouteHandlers(ctx)
+	adminToken := ctx.Config().Services.AdminToken
+
+	subrouter := router.WithPrefix("/labels", "Labels")
+	subrouter.AddRoute("/list", rh.listLabels(), "Operators of validator nodes")
+	subrouter.AddRoute("/set", utils.AdminRouteHandler(rh.setLabel(), adminToken),
+		"Set the operator of a validator node", "Requires the admin token")
+	subrouter.AddRoute("/remove", utils.AdminRouteHandler(rh.removeLabel(), adminToken),
+		"Remove the operator of a validator node", "Requires the admin token")
+}
@@ -0,0 +1,33 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"flare-indexer/services/api"
+	"flare-indexer/services/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetValidatorLabelValidation(t *testing.T) {
+	rh := &labelRouteHandlers{}
+	for _, body := range []string{
+		`{"nodeId": "CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6", "name": "Operator"}`,
+		`{"nodeId": "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6", "name": ""}`,
+		`{"nodeId": "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6", "name": "Operator", "website": "example"}`,
+		`{"nodeId": "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6", "name": "Operator", "ftsoProvider": "0x1234"}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/labels/set", strings.NewReader(body))
+		rh.setLabel().Handler(w, r)
+
+		var response api.ApiResponseWrapper[ValidatorLabelResponse]
+		utils.DecodeStruct(t, w.Result().Body, &response)
+		require.Equal(t, api.ApiResStatusRequestBodyError, response.Status, body)
+	}
+}
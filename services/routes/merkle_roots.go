@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MerkleRootResponse struct {
+	Epoch      int64  `json:"epoch"`
+	MerkleRoot string `json:"merkleRoot"`
+	TxCount    int    `json:"txCount"`
+	// Address of the data provider and its signature of the root, omitted if
+	// the root is not signed
+	Signer    string    `json:"signer,omitempty"`
+	Signature string    `json:"signature,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type merkleRootRouteHandlers struct {
+	db *gorm.DB
+}
+
+func newMerkleRootRouteHandlers(ctx servicesctx.ServicesContext) *merkleRootRouteHandlers {
+	return &merkleRootRouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+func (rh *merkleRootRouteHandlers) listMerkleRoots() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) ([]MerkleRootResponse, *utils.ErrorHandler) {
+		firstEpoch, lastEpoch, errHandler := parseEpochRangeParams(params)
+		if errHandler != nil {
+			return nil, errHandler
+		}
+		offset, limit, errHandler := parsePageParams(params, 100)
+		if errHandler != nil {
+			return nil, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckPage(offset, limit); errHandler != nil {
+			return nil, errHandler
+		}
+		roots, err := database.FetchEpochMerkleRoots(ctx, rh.db, firstEpoch, lastEpoch, offset, limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]MerkleRootResponse, len(roots))
+		for i, r := range roots {
+			response[i] = MerkleRootResponse{
+				Epoch:      r.Epoch,
+				MerkleRoot: r.MerkleRoot,
+				TxCount:    r.TxCount,
+				Signer:     r.Signer,
+				Signature:  r.Signature,
+				Timestamp:  r.Timestamp.UTC(),
+			}
+		}
+		return response, nil
+	}
+	return utils.NewQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{
+			"first":  "First epoch, no bound if not given",
+			"last":   "Last epoch, no bound if not given",
+			"offset": "Number of skipped roots",
+			"limit":  "Number of roots, 100 if not given",
+		},
+		[]MerkleRootResponse{})
+}
+
+// Parses the optional first and last epoch query parameters
+func parseEpochRangeParams(params map[string]string) (*int64, *int64, *utils.ErrorHandler) {
+	var epochs [2]*int64
+	for i, name := range []string{"first", "last"} {
+		param, ok := params[name]
+		if !ok {
+			continue
+		}
+		epoch, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || epoch < 0 {
+			return nil, nil, utils.HttpErrorHandler(http.StatusBadRequest, "invalid "+name+" epoch")
+		}
+		epochs[i] = &epoch
+	}
+	if epochs[0] != nil && epochs[1] != nil && *epochs[0] > *epochs[1] {
+		return nil, nil, utils.HttpErrorHandler(http.StatusBadRequest, "first epoch is after last epoch")
+	}
+	return epochs[0], epochs[1], nil
+}
+
+func AddMerkleRootRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newMerkleRootRouteHandlers(ctx)
+
+	subrouter := router.WithPrefix("/merkle_roots", "Merkle roots")
+	subrouter.AddRoute("", rh.listMerkleRoots(),
+		"History of the merkle roots of the epochs computed for voting, the newest epoch first")
+}
@@ -0,0 +1,32 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEpochRangeParams(t *testing.T) {
+	first, last, err := parseEpochRangeParams(map[string]string{})
+	require.Nil(t, err)
+	require.Nil(t, first)
+	require.Nil(t, last)
+
+	first, last, err = parseEpochRangeParams(map[string]string{"first": "10", "last": "20"})
+	require.Nil(t, err)
+	require.EqualValues(t, 10, *first)
+	require.EqualValues(t, 20, *last)
+
+	first, last, err = parseEpochRangeParams(map[string]string{"last": "20"})
+	require.Nil(t, err)
+	require.Nil(t, first)
+	require.EqualValues(t, 20, *last)
+
+	for _, params := range []map[string]string{{"first": "-1"}, {"last": "x"}, {"first": "21", "last": "20"}} {
+		_, _, err = parseEpochRangeParams(params)
+		require.NotNil(t, err, params)
+	}
+}
@@ -1,21 +1,23 @@
 package routes
 
 import (
+	"context"
 	"errors"
-	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
-	"flare-indexer/services/config"
-	"flare-indexer/services/context"
+	"flare-indexer/services/cache"
+	servicesctx "flare-indexer/services/context"
 	"flare-indexer/services/utils"
 	"flare-indexer/utils/contracts/mirroring"
-	"flare-indexer/utils/contracts/voting"
+	"flare-indexer/utils/errcode"
+	"flare-indexer/utils/staketree"
 	"flare-indexer/utils/staking"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"gorm.io/gorm"
 )
 
@@ -41,56 +43,93 @@ type MirroringResponse struct {
 
 type GetMirroringResponse []MirroringResponse
 
+var mirroringCSVHeader = []string{
+	"txId", "stakingType", "inputAddress", "nodeId", "startTime", "endTime", "weight", "merkleProof", "txInput",
+}
+
+// Proof hashes are separated by spaces in the CSV export
+func (r MirroringResponse) CSVRecord() []string {
+	return []string{
+		r.StakeData.TxID,
+		strconv.FormatUint(uint64(r.StakeData.StakingType), 10),
+		r.StakeData.InputAddress,
+		r.StakeData.NodeId,
+		strconv.FormatUint(r.StakeData.StartTime, 10),
+		strconv.FormatUint(r.StakeData.EndTime, 10),
+		strconv.FormatUint(r.StakeData.Weight, 10),
+		strings.Join(r.MerkleProof, " "),
+		r.TxInput,
+	}
+}
+
+type MirroringAttemptResponse struct {
+	InputAddress string                          `json:"inputAddress"`
+	Epoch        int64                           `json:"epoch"`
+	EthTxHash    string                          `json:"ethTxHash"`
+	Sender       string                          `json:"sender"`
+	Contract     string                          `json:"contract"`
+	GasUsed      uint64                          `json:"gasUsed"`
+	Status       database.MirroringAttemptStatus `json:"status"`
+	RevertReason string                          `json:"revertReason"`
+	Timestamp    time.Time                       `json:"timestamp"`
+	// Reward epoch containing the time of the attempt
+	TimestampEpoch *int64 `json:"timestampEpoch,omitempty"`
+	Reconciled     bool   `json:"reconciled"`
+}
+
+type GetMirroringAttemptsResponse []MirroringAttemptResponse
+
+type MirroringEpochStatus string
+
+const (
+	MirroringEpochNotProcessed MirroringEpochStatus = "NOT_PROCESSED" // Not processed (yet) by the mirroring cronjob
+	MirroringEpochEmpty        MirroringEpochStatus = "EMPTY"         // Processed, no stakes to mirror
+	MirroringEpochMirrored     MirroringEpochStatus = "MIRRORED"      // Processed, stakes were mirrored
+)
+
+type MirroringEpochResponse struct {
+	Epoch  int64                `json:"epoch"`
+	Status MirroringEpochStatus `json:"status"`
+	// Number of stakes of the epoch eligible for mirroring
+	TxCount   int        `json:"txCount"`
+	Processed *time.Time `json:"processed,omitempty"`
+	// Reward epoch containing the processing time
+	ProcessedEpoch *int64 `json:"processedEpoch,omitempty"`
+}
+
 type mirrorDB interface {
-	GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error)
-	GetPChainTx(txID string) (*database.PChainTx, error)
+	GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error)
+	GetPChainTx(ctx context.Context, txID string) (*database.PChainTx, error)
+	GetMirroringAttempts(ctx context.Context, txID string) ([]database.MirroringAttempt, error)
+	GetMirroringEpoch(ctx context.Context, epoch int64) (*database.MirroringEpoch, error)
 }
 
 type mirroringRouteHandlers struct {
 	db     mirrorDB
 	epochs staking.EpochInfo
+	filter *staking.TxFilter
 }
 
-func newMirroringRouteHandlers(ctx context.ServicesContext) (*mirroringRouteHandlers, error) {
-	cfg := ctx.Config()
-
-	start, period, err := getEpochStartAndPeriod(cfg)
-	if err != nil {
-		return nil, err
-	}
-
+func newMirroringRouteHandlers(ctx servicesctx.ServicesContext) *mirroringRouteHandlers {
 	return &mirroringRouteHandlers{
 		db:     NewMirrorDBGorm(ctx.DB()),
-		epochs: staking.NewEpochInfo(&globalConfig.EpochConfig{}, start, period),
-	}, nil
-}
-
-func getEpochStartAndPeriod(cfg *config.Config) (time.Time, time.Duration, error) {
-	eth, err := ethclient.Dial(cfg.Chain.EthRPCURL)
-	if err != nil {
-		return time.Time{}, 0, err
+		epochs: ctx.Epochs(),
+		filter: staking.NewTxFilter(&ctx.Config().StakingFilter, &ctx.Config().StakingRules),
 	}
-
-	votingContract, err := voting.NewVoting(cfg.ContractAddresses.Voting, eth)
-	if err != nil {
-		return time.Time{}, 0, err
-	}
-
-	return staking.GetEpochConfig(votingContract)
 }
 
 func (rh *mirroringRouteHandlers) listMirroringTransactions() utils.RouteHandler {
-	handler := func(params map[string]string) (GetMirroringResponse, *utils.ErrorHandler) {
+	handler := func(ctx context.Context, params map[string]string) (GetMirroringResponse, *utils.ErrorHandler) {
 		txID := params["tx_id"]
-		tx, err := rh.db.GetPChainTx(txID)
+		tx, err := rh.db.GetPChainTx(ctx, txID)
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return GetMirroringResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, "tx not found")
+				return GetMirroringResponse{}, utils.HttpErrorHandler(http.StatusNotFound, "tx not found")
 			} else {
 				return GetMirroringResponse{}, utils.InternalServerErrorHandler(err)
 			}
 		}
-		response, err := rh.createMirroringData(tx)
+		response, err := rh.createMirroringData(ctx, tx)
 		if err != nil {
 			return GetMirroringResponse{}, utils.InternalServerErrorHandler(err)
 		}
@@ -102,70 +141,169 @@ func (rh *mirroringRouteHandlers) listMirroringTransactions() utils.RouteHandler
 		GetMirroringResponse{})
 }
 
-func AddMirroringRoutes(router utils.Router, ctx context.ServicesContext) error {
-	rh, err := newMirroringRouteHandlers(ctx)
-	if err != nil {
-		return err
+func (rh *mirroringRouteHandlers) listMirroringAttempts() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetMirroringAttemptsResponse, *utils.ErrorHandler) {
+		attempts, err := rh.db.GetMirroringAttempts(ctx, params["tx_id"])
+		if err != nil {
+			return GetMirroringAttemptsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := make(GetMirroringAttemptsResponse, len(attempts))
+		for i, a := range attempts {
+			response[i] = MirroringAttemptResponse{
+				InputAddress:   a.InputAddress,
+				Epoch:          a.Epoch,
+				EthTxHash:      a.EthTxHash,
+				Sender:         a.Sender,
+				Contract:       a.Contract,
+				GasUsed:        a.GasUsed,
+				Status:         a.Status,
+				RevertReason:   a.RevertReason,
+				Timestamp:      a.Timestamp.UTC(),
+				TimestampEpoch: rh.epochs.EpochOf(&a.Timestamp),
+				Reconciled:     a.Reconciled,
+			}
+		}
+		return response, nil
 	}
 
-	mirroringSubrouter := router.WithPrefix("/mirroring", "Mirroring")
-	mirroringSubrouter.AddRoute("/tx_data/{tx_id:[0-9a-zA-Z]+}", rh.listMirroringTransactions())
+	return utils.NewParamRouteHandler(handler, http.MethodGet,
+		map[string]string{"tx_id:[0-9a-zA-Z]+": "Transaction ID"},
+		GetMirroringAttemptsResponse{})
+}
+
+func (rh *mirroringRouteHandlers) getMirroringEpoch() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (MirroringEpochResponse, *utils.ErrorHandler) {
+		epoch, err := strconv.ParseInt(params["epoch"], 10, 64)
+		if err != nil {
+			return MirroringEpochResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, "invalid epoch")
+		}
+		processed, err := rh.db.GetMirroringEpoch(ctx, epoch)
+		if err != nil {
+			return MirroringEpochResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := MirroringEpochResponse{Epoch: epoch, Status: MirroringEpochNotProcessed}
+		if processed == nil {
+			return response, nil
+		}
+		response.Status = MirroringEpochMirrored
+		if processed.TxCount == 0 {
+			response.Status = MirroringEpochEmpty
+		}
+		response.TxCount = processed.TxCount
+		processedTime := processed.Timestamp.UTC()
+		response.Processed = &processedTime
+		response.ProcessedEpoch = rh.epochs.EpochOf(&processedTime)
+		return response, nil
+	}
 
-	return nil
+	return utils.NewParamRouteHandler(handler, http.MethodGet,
+		map[string]string{"epoch:[0-9]+": "Reward epoch"},
+		MirroringEpochResponse{})
 }
 
-func (rh *mirroringRouteHandlers) createMirroringData(tx *database.PChainTx) ([]MirroringResponse, error) {
+func AddMirroringRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newMirroringRouteHandlers(ctx)
+	expiration := cache.EpochExpiration(ctx.Config().Cache.TTL, ctx.Epochs())
+
+	mirroringSubrouter := router.WithPrefix("/mirroring", "Mirroring")
+	mirroringSubrouter.AddRoute("/tx_data/{tx_id:[0-9a-zA-Z]+}",
+		utils.ConditionalRouteHandler(utils.CachedRouteHandler(rh.listMirroringTransactions(), ctx.Cache(), expiration),
+			indexedDataVersion(ctx)))
+	// Attempts change with every mirroring cronjob run and are not cached
+	mirroringSubrouter.AddRoute("/attempts/{tx_id:[0-9a-zA-Z]+}", rh.listMirroringAttempts())
+	mirroringSubrouter.AddRoute("/epochs/{epoch:[0-9]+}", rh.getMirroringEpoch())
+	mirroringSubrouter.AddRoute("/epochs/{epoch:[0-9]+}/proofs", rh.exportEpochProofs(),
+		"Stake data, merkle proofs and mirroring transaction inputs of all stakes of an epoch as JSON Lines or CSV")
+}
+
+func (rh *mirroringRouteHandlers) createMirroringData(ctx context.Context, tx *database.PChainTx) ([]MirroringResponse, error) {
 	epoch := rh.epochs.GetEpochIndex(*tx.StartTime)
-	startTimestamp, endTimestamp := rh.epochs.GetTimeRange(epoch)
-	txs, err := rh.db.GetPChainTxsForEpoch(startTimestamp, endTimestamp)
-	if err != nil {
-		return nil, err
-	}
-	txs = staking.DedupeTxs(txs)
-	merkleTree, err := staking.BuildTree(txs)
+	set, err := rh.epochStakeSet(ctx, epoch)
 	if err != nil {
 		return nil, err
 	}
 
 	var mirroringData []MirroringResponse
-	for _, txData := range txs {
-		if txData.ID != tx.ID {
+	stakes := set.Stakes()
+	for i := range stakes {
+		if stakes[i].TxID.String() != *tx.TxID {
 			continue
 		}
-		stakeData, err := staking.ToStakeData(&txData)
+		response, err := newMirroringResponse(set, &stakes[i])
 		if err != nil {
 			return nil, err
 		}
-		merkleProof, err := staking.GetMerkleProof(merkleTree, &txData)
+		mirroringData = append(mirroringData, response)
+	}
+	if len(mirroringData) == 0 {
+		return nil, fmt.Errorf("no mirroring data found for tx %s", *tx.TxID)
+	}
+	return mirroringData, nil
+}
+
+// Streams the mirroring data of all stakes starting in the epoch, with the
+// proofs of the merkle root voted for the epoch
+func (rh *mirroringRouteHandlers) exportEpochProofs() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string, w *utils.ExportWriter) error {
+		epoch, err := strconv.ParseInt(params["epoch"], 10, 64)
 		if err != nil {
-			return nil, err
+			return errcode.New(errcode.Validation, "invalid epoch")
 		}
-		txDataBytes, err := createMirrorTransactionBytes(stakeData, merkleProof)
+		set, err := rh.epochStakeSet(ctx, epoch)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		merkleProofStrings := make([]string, len(merkleProof))
-		for i, proof := range merkleProof {
-			merkleProofStrings[i] = hexutil.Encode(proof[:])
+		stakes := set.Stakes()
+		for i := range stakes {
+			response, err := newMirroringResponse(set, &stakes[i])
+			if err != nil {
+				return err
+			}
+			if err := w.Write(response); err != nil {
+				return err
+			}
 		}
-		mirroringData = append(mirroringData, MirroringResponse{
-			StakeData: MirroringStakeData{
-				TxID:         hexutil.Encode(stakeData.TxId[:]),
-				StakingType:  stakeData.StakingType,
-				InputAddress: hexutil.Encode(stakeData.InputAddress[:]),
-				NodeId:       hexutil.Encode(stakeData.NodeId[:]),
-				StartTime:    stakeData.StartTime,
-				EndTime:      stakeData.EndTime,
-				Weight:       stakeData.Weight,
-			},
-			MerkleProof: merkleProofStrings,
-			TxInput:     hexutil.Encode(txDataBytes),
-		})
+		return nil
 	}
-	if len(mirroringData) == 0 {
-		return nil, fmt.Errorf("no mirroring data found for tx %s", *tx.TxID)
+	return utils.NewParamExportRouteHandler(handler, http.MethodGet,
+		map[string]string{"epoch:[0-9]+": "Reward epoch"},
+		"epoch_proofs", mirroringCSVHeader, MirroringResponse{})
+}
+
+// Stakes of the epoch as mirrored by the mirroring cronjob and their merkle tree
+func (rh *mirroringRouteHandlers) epochStakeSet(ctx context.Context, epoch int64) (*staketree.Set, error) {
+	startTimestamp, endTimestamp := rh.epochs.GetTimeRange(epoch)
+	source := staking.NewTxSource(rh.db.GetPChainTxsForEpoch, rh.filter)
+	return staketree.BuildEpochSet(ctx, source, startTimestamp, endTimestamp)
+}
+
+func newMirroringResponse(set *staketree.Set, stake *staketree.Stake) (MirroringResponse, error) {
+	stakeData := stake.StakeData()
+	merkleProof, err := set.Proof(stake.TxID)
+	if err != nil {
+		return MirroringResponse{}, err
 	}
-	return mirroringData, nil
+	txDataBytes, err := createMirrorTransactionBytes(stakeData, merkleProof)
+	if err != nil {
+		return MirroringResponse{}, err
+	}
+	merkleProofStrings := make([]string, len(merkleProof))
+	for i, proof := range merkleProof {
+		merkleProofStrings[i] = hexutil.Encode(proof[:])
+	}
+	return MirroringResponse{
+		StakeData: MirroringStakeData{
+			TxID:         hexutil.Encode(stakeData.TxId[:]),
+			StakingType:  stakeData.StakingType,
+			InputAddress: hexutil.Encode(stakeData.InputAddress[:]),
+			NodeId:       hexutil.Encode(stakeData.NodeId[:]),
+			StartTime:    stakeData.StartTime,
+			EndTime:      stakeData.EndTime,
+			Weight:       stakeData.Weight,
+		},
+		MerkleProof: merkleProofStrings,
+		TxInput:     hexutil.Encode(txDataBytes),
+	}, nil
 }
 
 func createMirrorTransactionBytes(stakeData *mirroring.IPChainStakeMirrorVerifierPChainStake, merkleProof [][32]byte) ([]byte, error) {
@@ -188,14 +326,22 @@ func NewMirrorDBGorm(db *gorm.DB) mirrorDBGorm {
 	return mirrorDBGorm{db: db}
 }
 
-func (m mirrorDBGorm) GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error) {
-	return database.GetPChainTxsForEpoch(&database.GetPChainTxsForEpochInput{
+func (m mirrorDBGorm) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+	return database.GetPChainTxsForEpoch(ctx, &database.GetPChainTxsForEpochInput{
 		DB:             m.db,
 		StartTimestamp: start,
 		EndTimestamp:   end,
 	})
 }
 
-func (m mirrorDBGorm) GetPChainTx(txID string) (*database.PChainTx, error) {
-	return database.FetchPChainTx(m.db, txID)
+func (m mirrorDBGorm) GetPChainTx(ctx context.Context, txID string) (*database.PChainTx, error) {
+	return database.FetchPChainTx(ctx, m.db, txID)
+}
+
+func (m mirrorDBGorm) GetMirroringAttempts(ctx context.Context, txID string) ([]database.MirroringAttempt, error) {
+	return database.FetchMirroringAttempts(ctx, m.db, txID)
+}
+
+func (m mirrorDBGorm) GetMirroringEpoch(ctx context.Context, epoch int64) (*database.MirroringEpoch, error) {
+	return database.FetchMirroringEpoch(ctx, m.db, epoch)
 }
@@ -1,6 +1,11 @@
+//go:build !integration
+// +build !integration
+
 package routes
 
 import (
+	"context"
+	"encoding/json"
 	globalConfig "flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/services/api"
@@ -9,6 +14,7 @@ import (
 	"flare-indexer/utils/staking"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,6 +67,100 @@ func TestGetMirroringData(t *testing.T) {
 	cupaloy.SnapshotT(t, wResponse)
 }
 
+func TestExportEpochProofs(t *testing.T) {
+	mh := newMirroringTestRouteHandlers(testMirroringData)
+	router := mux.NewRouter()
+	router.HandleFunc("/tx_data/{tx_id}", mh.listMirroringTransactions().Handler)
+	router.HandleFunc("/epochs/{epoch}/proofs", mh.exportEpochProofs().Handler)
+	get := func(url string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// The proofs of the epoch are the proofs of its transactions
+	var txData api.ApiResponseWrapper[GetMirroringResponse]
+	serviceUtils.DecodeStruct(t, get("/tx_data/2NuEmDJopBVunGZym7pcYjfuWTPaoWuHSnSvxiqdFdvDY7TGqQ").Body, &txData)
+	require.Len(t, txData.Data, 1)
+
+	w := get("/epochs/0/proofs")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	require.Len(t, lines, 1)
+	var proof MirroringResponse
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &proof))
+	require.Equal(t, txData.Data[0], proof)
+
+	w = get("/epochs/0/proofs?format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	rows := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	require.Len(t, rows, 2)
+	require.Equal(t, strings.Join(mirroringCSVHeader, ","), rows[0])
+	require.Equal(t, strings.Join(proof.CSVRecord(), ","), rows[1])
+
+	// Epochs without stakes are empty
+	w = get("/epochs/5/proofs")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Body.String())
+
+	w = get("/epochs/0/proofs?format=xml")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetMirroringAttempts(t *testing.T) {
+	mh := newMirroringTestRouteHandlers(testMirroringData)
+
+	r, err := http.NewRequest(http.MethodGet, "/attempts/2NuEmDJopBVunGZym7pcYjfuWTPaoWuHSnSvxiqdFdvDY7TGqQ", nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/attempts/{tx_id}", mh.listMirroringAttempts().Handler)
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var wResponse api.ApiResponseWrapper[GetMirroringAttemptsResponse]
+	serviceUtils.DecodeStruct(t, w.Result().Body, &wResponse)
+
+	cupaloy.SnapshotT(t, wResponse)
+}
+
+func TestGetMirroringEpoch(t *testing.T) {
+	mh := newMirroringTestRouteHandlers(testMirroringData)
+	router := mux.NewRouter()
+	router.HandleFunc("/epochs/{epoch}", mh.getMirroringEpoch().Handler)
+
+	for _, c := range []struct {
+		epoch   string
+		status  MirroringEpochStatus
+		txCount int
+	}{
+		{"1", MirroringEpochMirrored, 1},
+		{"2", MirroringEpochEmpty, 0},
+		{"3", MirroringEpochNotProcessed, 0},
+	} {
+		r, err := http.NewRequest(http.MethodGet, "/epochs/"+c.epoch, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		var wResponse api.ApiResponseWrapper[MirroringEpochResponse]
+		serviceUtils.DecodeStruct(t, w.Result().Body, &wResponse)
+		require.Equal(t, c.status, wResponse.Data.Status, c.epoch)
+		require.Equal(t, c.txCount, wResponse.Data.TxCount, c.epoch)
+		require.Equal(t, c.status != MirroringEpochNotProcessed, wResponse.Data.Processed != nil, c.epoch)
+		if c.status != MirroringEpochNotProcessed {
+			// Processed 10 minutes after the start of epoch 0
+			require.Equal(t, int64(3), *wResponse.Data.ProcessedEpoch, c.epoch)
+		}
+	}
+}
+
 func newMirroringTestRouteHandlers(txs map[string]database.PChainTxData) *mirroringRouteHandlers {
 	return &mirroringRouteHandlers{
 		db: newTestDB(txs),
@@ -79,7 +179,7 @@ func newTestDB(txs map[string]database.PChainTxData) mirrorDB {
 	return &testDB{txs}
 }
 
-func (db testDB) GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTxData, error) {
+func (db testDB) GetPChainTxsForEpoch(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
 	var txs []database.PChainTxData
 	for _, tx := range db.txs {
 		if !tx.StartTime.Before(start) && tx.StartTime.Before(end) {
@@ -89,13 +189,53 @@ func (db testDB) GetPChainTxsForEpoch(start, end time.Time) ([]database.PChainTx
 	return txs, nil
 }
 
-func (db testDB) GetPChainTx(txID string) (*database.PChainTx, error) {
+func (db testDB) GetPChainTx(ctx context.Context, txID string) (*database.PChainTx, error) {
 	if tx, ok := db.txs[txID]; ok {
 		return &tx.PChainTx, nil
 	}
 	return nil, gorm.ErrRecordNotFound
 }
 
+func (db testDB) GetMirroringAttempts(ctx context.Context, txID string) ([]database.MirroringAttempt, error) {
+	if _, ok := db.txs[txID]; !ok {
+		return nil, nil
+	}
+	return []database.MirroringAttempt{
+		{
+			TxID:         txID,
+			InputAddress: db.txs[txID].InputAddress,
+			Epoch:        1,
+			Sender:       "0x4f3D6fDeB7f8f6C0b1F5e2D8a8F6f4C2B1E6A7d3",
+			Status:       database.MirroringAttemptRejected,
+			RevertReason: "execution reverted: staking data invalid",
+			Timestamp:    time.Date(2023, time.January, 1, 0, 5, 0, 0, time.UTC),
+		},
+		{
+			TxID:         txID,
+			InputAddress: db.txs[txID].InputAddress,
+			Epoch:        1,
+			EthTxHash:    "0x9b0a3c2f6e1d4b5a7c8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c",
+			Sender:       "0x4f3D6fDeB7f8f6C0b1F5e2D8a8F6f4C2B1E6A7d3",
+			Contract:     "0xE64Df6a7e4f4c277C5299f0FE12D7BbB8A207175",
+			GasUsed:      186243,
+			Status:       database.MirroringAttemptSucceeded,
+			Timestamp:    time.Date(2023, time.January, 1, 0, 8, 0, 0, time.UTC),
+		},
+	}, nil
+}
+
+// Epoch 1 was mirrored, epoch 2 had no stakes
+func (db testDB) GetMirroringEpoch(ctx context.Context, epoch int64) (*database.MirroringEpoch, error) {
+	processed := time.Date(2023, time.January, 1, 0, 10, 0, 0, time.UTC)
+	switch epoch {
+	case 1:
+		return &database.MirroringEpoch{Epoch: 1, TxCount: len(db.txs), Timestamp: processed}, nil
+	case 2:
+		return &database.MirroringEpoch{Epoch: 2, Timestamp: processed}, nil
+	}
+	return nil, nil
+}
+
 func pString(s string) *string { return &s }
 
 func pTime(year int, month time.Month, day, hour, min, sec, nsec int, loc *time.Location) *time.Time {
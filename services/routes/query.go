@@ -1,11 +1,12 @@
 package routes
 
 import (
+	"context"
 	"errors"
 	"flare-indexer/config"
 	"flare-indexer/database"
 	"flare-indexer/services/api"
-	"flare-indexer/services/context"
+	servicesctx "flare-indexer/services/context"
 	"flare-indexer/services/utils"
 	globalUtils "flare-indexer/utils"
 	"net/http"
@@ -14,7 +15,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func AddQueryRoutes(router utils.Router, ctx context.ServicesContext) {
+func AddQueryRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
 	qr := newQueryRouteHandlers(ctx)
 	subrouter := router.WithPrefix("/query", "Query")
 
@@ -33,7 +34,7 @@ type queryRouteHandlers struct {
 	cfg config.ChainConfig
 }
 
-func newQueryRouteHandlers(ctx context.ServicesContext) *queryRouteHandlers {
+func newQueryRouteHandlers(ctx servicesctx.ServicesContext) *queryRouteHandlers {
 	return &queryRouteHandlers{
 		db:  ctx.DB(),
 		cfg: ctx.Config().ChainConfig(),
@@ -45,12 +46,12 @@ func newQueryRouteHandlers(ctx context.ServicesContext) *queryRouteHandlers {
 // Request type: api.APIAttestationRequest
 // Response type: api.ApiResponseWrapper[api.APIVerification[api.ARPChainStaking, api.DHPChainStaking]]
 func (qr *queryRouteHandlers) processAttestationRequest() utils.RouteHandler {
-	handler := func(request api.APIAttestationRequest) (*api.APIVerification[api.ARPChainStaking, api.DHPChainStaking], *utils.ErrorHandler) {
+	handler := func(ctx context.Context, request api.APIAttestationRequest) (*api.APIVerification[api.ARPChainStaking, api.DHPChainStaking], *utils.ErrorHandler) {
 		unpackedReq, err := utils.UnpackPChainStakingRequest(request.Request)
 		if err != nil {
 			return nil, utils.ApiResponseErrorHandler(api.ApiResStatusInvalidRequest, "invalid request", err.Error())
 		}
-		return qr.processPChainStakingRequest(unpackedReq)
+		return qr.processPChainStakingRequest(ctx, unpackedReq)
 	}
 	return utils.NewRouteHandler(handler, http.MethodPost, api.APIAttestationRequest{}, &api.APIVerification[api.ARPChainStaking, api.DHPChainStaking]{})
 }
@@ -60,8 +61,8 @@ func (qr *queryRouteHandlers) processAttestationRequest() utils.RouteHandler {
 // Request type: api.ARPChainStaking
 // Response type: api.ApiResponseWrapper[api.APIVerification[api.ARPChainStaking, api.DHPChainStaking]]
 func (qr *queryRouteHandlers) prepareRequest() utils.RouteHandler {
-	handler := func(request api.ARPChainStaking) (*api.APIVerification[api.ARPChainStaking, api.DHPChainStaking], *utils.ErrorHandler) {
-		return qr.processPChainStakingRequest(&request)
+	handler := func(ctx context.Context, request api.ARPChainStaking) (*api.APIVerification[api.ARPChainStaking, api.DHPChainStaking], *utils.ErrorHandler) {
+		return qr.processPChainStakingRequest(ctx, &request)
 	}
 	return utils.NewRouteHandler(handler, http.MethodPost, api.ARPChainStaking{}, &api.APIVerification[api.ARPChainStaking, api.DHPChainStaking]{})
 }
@@ -72,8 +73,8 @@ func (qr *queryRouteHandlers) prepareRequest() utils.RouteHandler {
 // Request type: api.ARPChainStaking
 // Response type: api.ApiResponseWrapper[string]
 func (qr *queryRouteHandlers) integrityRequest() utils.RouteHandler {
-	handler := func(request api.ARPChainStaking) (string, *utils.ErrorHandler) {
-		response, errHandler := qr.processPChainStakingRequest(&request)
+	handler := func(ctx context.Context, request api.ARPChainStaking) (string, *utils.ErrorHandler) {
+		response, errHandler := qr.processPChainStakingRequest(ctx, &request)
 		if errHandler != nil {
 			return "", errHandler
 		}
@@ -93,8 +94,8 @@ func (qr *queryRouteHandlers) integrityRequest() utils.RouteHandler {
 // Request type: api.ARPChainStaking
 // Response type: api.ApiResponseWrapper[string]
 func (qr *queryRouteHandlers) prepareAttestationRequest() utils.RouteHandler {
-	handler := func(request api.ARPChainStaking) (string, *utils.ErrorHandler) {
-		response, errHandler := qr.processPChainStakingRequest(&request)
+	handler := func(ctx context.Context, request api.ARPChainStaking) (string, *utils.ErrorHandler) {
+		response, errHandler := qr.processPChainStakingRequest(ctx, &request)
 		if errHandler != nil {
 			return "", errHandler
 		}
@@ -114,9 +115,10 @@ func (qr *queryRouteHandlers) prepareAttestationRequest() utils.RouteHandler {
 
 // Process attestation request. Write errors into w, if any, otherwise return the response.
 func (qr *queryRouteHandlers) processPChainStakingRequest(
+	ctx context.Context,
 	request *api.ARPChainStaking,
 ) (*api.APIVerification[api.ARPChainStaking, api.DHPChainStaking], *utils.ErrorHandler) {
-	response, err1, err2 := qr.executePChainStakingRequest(request)
+	response, err1, err2 := qr.executePChainStakingRequest(ctx, request)
 	if err1 != nil {
 		return nil, utils.ApiResponseErrorHandler(api.ApiResStatusInvalidRequest, "invalid request", err1.Error())
 	}
@@ -130,6 +132,7 @@ func (qr *queryRouteHandlers) processPChainStakingRequest(
 // Returns an error if the request is invalid (1st error),
 // or if there is an error querying the database (2nd error)
 func (qr *queryRouteHandlers) executePChainStakingRequest(
+	ctx context.Context,
 	request *api.ARPChainStaking,
 ) (*api.APIVerification[api.ARPChainStaking, api.DHPChainStaking], error, error) {
 	if request.AttestationType != api.AttestationTypePChainStaking {
@@ -144,7 +147,7 @@ func (qr *queryRouteHandlers) executePChainStakingRequest(
 	id, _ := ids.ToID(bytes)
 	txID := id.String()
 
-	tx, blockExists, err := database.FindPChainTxInBlockHeight(qr.db, txID, request.BlockNumber)
+	tx, blockExists, err := database.FindPChainTxInBlockHeight(ctx, qr.db, txID, request.BlockNumber)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -155,11 +158,11 @@ func (qr *queryRouteHandlers) executePChainStakingRequest(
 		response.Status = api.VerificationStatusNonExistentBlock
 	case tx == nil:
 		response.Status = api.VerificationStatusNonExistentTransaction
-	case tx.Type != database.PChainAddValidatorTx && tx.Type != database.PChainAddDelegatorTx:
+	case !tx.Type.IsValidatorTx() && !tx.Type.IsDelegatorTx():
 		response.Status = api.VerificationStatusNonExistentTransaction
 	default:
 		var txType byte
-		if tx.Type == database.PChainAddValidatorTx {
+		if tx.Type.IsValidatorTx() {
 			txType = 0
 		} else {
 			txType = 1
@@ -1,10 +1,17 @@
 package routes
 
 import (
+	"context"
+	"errors"
 	"flare-indexer/database"
-	"flare-indexer/services/context"
+	"flare-indexer/services/api"
+	"flare-indexer/services/cache"
+	servicesctx "flare-indexer/services/context"
 	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,29 +31,106 @@ type GetStakerRequest struct {
 }
 
 type GetStakerResponse struct {
-	TxID           string    `json:"txID"`
-	NodeID         string    `json:"nodeID"`
-	StartTime      time.Time `json:"startTime"`
-	EndTime        time.Time `json:"endTime"`
-	Weight         uint64    `json:"weight"`
-	FeePercentage  uint32    `json:"feePercentage"`
-	InputAddresses []string  `json:"inputAddresses"`
+	TxID      string    `json:"txID"`
+	NodeID    string    `json:"nodeID"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	// Reward epochs containing the start and end time
+	StartEpoch     *int64     `json:"startEpoch,omitempty"`
+	EndEpoch       *int64     `json:"endEpoch,omitempty"`
+	Weight         api.Amount `json:"weight"`
+	FeePercentage  uint32     `json:"feePercentage"`
+	InputAddresses []string   `json:"inputAddresses"`
+	// Operator of the validator node, if labeled
+	Label *ValidatorLabelResponse `json:"label,omitempty"`
+}
+
+type GetValidatorCapacityRequest struct {
+	Time time.Time `json:"time"`
+}
+
+type ValidatorCapacityResponse struct {
+	NodeID         string     `json:"nodeID"`
+	ValidatorStake api.Amount `json:"validatorStake"`
+	DelegatedStake api.Amount `json:"delegatedStake"`
+	// Maximal total stake and stake which can still be delegated, omitted if
+	// the stake of the validator is not limited
+	MaxStake          *api.Amount                `json:"maxStake,omitempty"`
+	RemainingCapacity *api.Amount                `json:"remainingCapacity,omitempty"`
+	Violations        []StakingViolationResponse `json:"violations"`
+}
+
+type StakingViolationResponse struct {
+	TxID string       `json:"txID"`
+	Rule staking.Rule `json:"rule"`
+}
+
+// Validators and delegators active at a point in time
+type GetStakersAtResponse struct {
+	Time       time.Time           `json:"time"`
+	Epoch      int64               `json:"epoch"` // Reward epoch containing time
+	Validators []GetStakerResponse `json:"validators"`
+	Delegators []GetStakerResponse `json:"delegators"`
+}
+
+// BLS key registered by a permissionless validator transaction of the node
+type ValidatorBLSKeyResponse struct {
+	TxID              string    `json:"txID"`
+	StartTime         time.Time `json:"startTime"`
+	EndTime           time.Time `json:"endTime"`
+	StartEpoch        *int64    `json:"startEpoch,omitempty"`
+	EndEpoch          *int64    `json:"endEpoch,omitempty"`
+	PublicKey         string    `json:"publicKey"`
+	ProofOfPossession string    `json:"proofOfPossession"`
+}
+
+// Weight of a validator node at the start of a reward epoch
+type ValidatorWeightResponse struct {
+	NodeID          string     `json:"nodeID"`
+	OwnWeight       api.Amount `json:"ownWeight"`
+	DelegatedWeight api.Amount `json:"delegatedWeight"`
+	Weight          api.Amount `json:"weight"`
+	Delegators      int        `json:"delegators"`
+	// Operator of the validator node, if labeled
+	Label *ValidatorLabelResponse `json:"label,omitempty"`
+}
+
+type GetValidatorWeightsResponse struct {
+	Epoch int64 `json:"epoch"`
+	// Sum of the weights of all validators of the epoch
+	TotalWeight api.Amount                `json:"totalWeight"`
+	Validators  []ValidatorWeightResponse `json:"validators"`
 }
 
 type stakerRouteHandlers struct {
-	db *gorm.DB
+	db            *gorm.DB
+	rules         *staking.Rules
+	epochs        staking.EpochInfo
+	hrp           string
+	renderAddress func(string) string
 }
 
-func newStakerRouteHandlers(ctx context.ServicesContext) *stakerRouteHandlers {
+func newStakerRouteHandlers(ctx servicesctx.ServicesContext) *stakerRouteHandlers {
 	return &stakerRouteHandlers{
-		db: ctx.DB(),
+		db:            ctx.DB(),
+		rules:         staking.NewRules(&ctx.Config().StakingRules),
+		epochs:        ctx.Epochs(),
+		hrp:           ctx.Config().Chain.ChainAddressHRP,
+		renderAddress: addressRenderer(ctx),
 	}
 }
 
 func (rh *stakerRouteHandlers) listStakingTransactions(txType database.PChainTxType) utils.RouteHandler {
-	handler := func(request GetStakerTxRequest) (TxIDsResponse, *utils.ErrorHandler) {
-		txIDs, err := database.FetchPChainStakingTransactions(rh.db, txType, request.NodeID,
-			request.Address, request.Time, request.Offset, request.Limit)
+	handler := func(ctx context.Context, request GetStakerTxRequest) (TxIDsResponse, *utils.ErrorHandler) {
+		address := request.Address
+		if len(address) > 0 {
+			var errHandler *utils.ErrorHandler
+			if address, errHandler = parseRequestAddress(rh.hrp, address); errHandler != nil {
+				return TxIDsResponse{}, errHandler
+			}
+		}
+		txIDs, err := database.FetchPChainStakingTransactions(ctx, rh.db, txType, request.NodeID,
+			address, request.Time, request.Offset, request.Limit)
 		if err != nil {
 			return TxIDsResponse{}, utils.InternalServerErrorHandler(err)
 		}
@@ -56,36 +140,259 @@ func (rh *stakerRouteHandlers) listStakingTransactions(txType database.PChainTxT
 }
 
 func (rh *stakerRouteHandlers) listStakers(txType database.PChainTxType) utils.RouteHandler {
-	handler := func(request GetStakerRequest) ([]GetStakerResponse, *utils.ErrorHandler) {
-		stakerTxData, err := database.FetchPChainStakingData(rh.db, request.Time, txType, request.Offset, request.Limit)
+	handler := func(ctx context.Context, request GetStakerRequest) ([]GetStakerResponse, *utils.ErrorHandler) {
+		stakerTxData, err := database.FetchPChainStakingData(ctx, rh.db, request.Time, txType, request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		nodeIDs := make([]string, len(stakerTxData))
+		for i := range stakerTxData {
+			nodeIDs[i] = stakerTxData[i].NodeID
+		}
+		labels, err := fetchLabelsByNodeID(ctx, rh.db, nodeIDs)
 		if err != nil {
 			return nil, utils.InternalServerErrorHandler(err)
 		}
 		stakers := make([]GetStakerResponse, len(stakerTxData))
-		for i, tx := range stakerTxData {
-			stakers[i] = GetStakerResponse{
-				TxID:           *tx.TxID,
-				NodeID:         tx.NodeID,
-				StartTime:      *tx.StartTime,
-				EndTime:        *tx.EndTime,
-				Weight:         tx.Weight,
-				FeePercentage:  tx.FeePercentage,
-				InputAddresses: strings.Split(tx.InputAddress, ","),
-			}
+		for i := range stakerTxData {
+			stakers[i] = newGetStakerResponse(&stakerTxData[i], rh.epochs, rh.renderAddress)
+			stakers[i].Label = labels[stakerTxData[i].NodeID]
 		}
 		return stakers, nil
 	}
 	return utils.NewRouteHandler(handler, http.MethodPost, GetStakerRequest{}, []GetStakerResponse{})
 }
 
-func AddStakerRoutes(router utils.Router, ctx context.ServicesContext) {
+// All validators and delegators active at the time given by the query
+// parameter time (RFC 3339) or epoch (the start of the reward epoch)
+func (rh *stakerRouteHandlers) listStakersAt() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetStakersAtResponse, *utils.ErrorHandler) {
+		t, err := parseStakersAtTime(params, rh.epochs)
+		if err != nil {
+			return GetStakersAtResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, err.Error())
+		}
+		stakerTxData, err := database.FetchPChainStakersAt(ctx, rh.db, t)
+		if err != nil {
+			return GetStakersAtResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetStakersAtResponse{
+			Time:       t.UTC(),
+			Epoch:      rh.epochs.GetEpochIndex(t),
+			Validators: []GetStakerResponse{},
+			Delegators: []GetStakerResponse{},
+		}
+		for i := range stakerTxData {
+			staker := newGetStakerResponse(&stakerTxData[i], rh.epochs, rh.renderAddress)
+			if stakerTxData[i].Type.IsValidatorTx() {
+				response.Validators = append(response.Validators, staker)
+			} else {
+				response.Delegators = append(response.Delegators, staker)
+			}
+		}
+		return response, nil
+	}
+	return utils.NewQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{
+			"time":  "Point in time (RFC 3339), e.g. 2023-10-01T12:00:00Z",
+			"epoch": "Reward epoch, the stakers active at its start are returned",
+		},
+		GetStakersAtResponse{})
+}
+
+// Exactly one of the parameters time and epoch must be set
+func parseStakersAtTime(params map[string]string, epochs staking.EpochInfo) (time.Time, error) {
+	timeParam, hasTime := params["time"]
+	epochParam, hasEpoch := params["epoch"]
+	if hasTime == hasEpoch {
+		return time.Time{}, errors.New("exactly one of time and epoch must be given")
+	}
+	if hasTime {
+		t, err := time.Parse(time.RFC3339, timeParam)
+		if err != nil {
+			return time.Time{}, errors.New("invalid time, expected RFC 3339 format")
+		}
+		return t, nil
+	}
+	epoch, err := strconv.ParseInt(epochParam, 10, 64)
+	if err != nil || epoch < 0 {
+		return time.Time{}, errors.New("invalid epoch")
+	}
+	return epochs.GetStartTime(epoch), nil
+}
+
+// Weights of the validators at the start of the reward epoch given by the
+// query parameter epoch, the highest weight first. The weights are recorded by
+// the P-chain indexer once the indexed chain passes the start of the epoch.
+func (rh *stakerRouteHandlers) listValidatorWeights() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetValidatorWeightsResponse, *utils.ErrorHandler) {
+		epoch, err := parseWeightsEpoch(params)
+		if err != nil {
+			return GetValidatorWeightsResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, err.Error())
+		}
+		state, err := database.FetchState(ctx, rh.db, database.ValidatorWeightsStateName)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return GetValidatorWeightsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		if err != nil || state.NextDBIndex <= uint64(epoch) {
+			return GetValidatorWeightsResponse{}, utils.HttpErrorHandler(http.StatusNotFound, "validator weights of the epoch are not recorded")
+		}
+		weights, err := database.FetchValidatorWeights(ctx, rh.db, epoch)
+		if err != nil {
+			return GetValidatorWeightsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		nodeIDs := make([]string, len(weights))
+		for i := range weights {
+			nodeIDs[i] = weights[i].NodeID
+		}
+		labels, err := fetchLabelsByNodeID(ctx, rh.db, nodeIDs)
+		if err != nil {
+			return GetValidatorWeightsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response := GetValidatorWeightsResponse{
+			Epoch:       epoch,
+			TotalWeight: api.NewBigAmount(totalValidatorWeight(weights)),
+			Validators:  make([]ValidatorWeightResponse, len(weights)),
+		}
+		for i, w := range weights {
+			response.Validators[i] = ValidatorWeightResponse{
+				NodeID:          w.NodeID,
+				OwnWeight:       api.NewBigAmount(w.OwnWeight.Big()),
+				DelegatedWeight: api.NewBigAmount(w.DelegatedWeight.Big()),
+				Weight:          api.NewBigAmount(w.Weight.Big()),
+				Delegators:      w.Delegators,
+				Label:           labels[w.NodeID],
+			}
+		}
+		return response, nil
+	}
+	return utils.NewQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{"epoch": "Reward epoch, the weights at its start are returned"},
+		GetValidatorWeightsResponse{})
+}
+
+// Sum of the weights, which may overflow uint64
+func totalValidatorWeight(weights []database.ValidatorWeight) *big.Int {
+	total := new(big.Int)
+	for i := range weights {
+		total.Add(total, weights[i].Weight.Big())
+	}
+	return total
+}
+
+func parseWeightsEpoch(params map[string]string) (int64, error) {
+	epochParam, ok := params["epoch"]
+	if !ok {
+		return 0, errors.New("epoch must be given")
+	}
+	epoch, err := strconv.ParseInt(epochParam, 10, 64)
+	if err != nil || epoch < 0 {
+		return 0, errors.New("invalid epoch")
+	}
+	return epoch, nil
+}
+
+// BLS keys of the validator node, the latest first
+func (rh *stakerRouteHandlers) listValidatorBLSKeys() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) ([]ValidatorBLSKeyResponse, *utils.ErrorHandler) {
+		txs, err := database.FetchNodeBLSKeys(ctx, rh.db, params["node_id"])
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]ValidatorBLSKeyResponse, len(txs))
+		for i, tx := range txs {
+			response[i] = ValidatorBLSKeyResponse{
+				TxID:              *tx.TxID,
+				StartTime:         *tx.StartTime,
+				EndTime:           *tx.EndTime,
+				StartEpoch:        rh.epochs.EpochOf(tx.StartTime),
+				EndEpoch:          rh.epochs.EpochOf(tx.EndTime),
+				PublicKey:         tx.BlsPublicKey,
+				ProofOfPossession: tx.BlsProofOfPossession,
+			}
+		}
+		return response, nil
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet,
+		map[string]string{"node_id:NodeID-[0-9a-zA-Z]+": "Node ID"},
+		[]ValidatorBLSKeyResponse{})
+}
+
+// Stake and remaining delegation capacity of the validator nodes at the given
+// time, with the active stakes violating the staking rules
+func (rh *stakerRouteHandlers) listValidatorCapacities() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetValidatorCapacityRequest) ([]ValidatorCapacityResponse, *utils.ErrorHandler) {
+		validators, err := database.FetchNodeStakingIntervals(ctx, rh.db, database.PChainAddValidatorTx, request.Time, request.Time)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		delegators, err := database.FetchNodeStakingIntervals(ctx, rh.db, database.PChainAddDelegatorTx, request.Time, request.Time)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		capacities := rh.rules.Capacities(validators, delegators)
+		response := make([]ValidatorCapacityResponse, len(capacities))
+		for i := range capacities {
+			response[i] = newValidatorCapacityResponse(&capacities[i])
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetValidatorCapacityRequest{}, []ValidatorCapacityResponse{})
+}
+
+func newValidatorCapacityResponse(c *staking.ValidatorCapacity) ValidatorCapacityResponse {
+	response := ValidatorCapacityResponse{
+		NodeID:         c.NodeID,
+		ValidatorStake: api.NewBigAmount(c.ValidatorStake),
+		DelegatedStake: api.NewBigAmount(c.DelegatedStake),
+		Violations:     make([]StakingViolationResponse, len(c.Violations)),
+	}
+	if remaining, limited := c.Remaining(); limited {
+		maxStake, remainingCapacity := api.NewBigAmount(c.MaxStake), api.NewBigAmount(remaining)
+		response.MaxStake = &maxStake
+		response.RemainingCapacity = &remainingCapacity
+	}
+	for i, v := range c.Violations {
+		response.Violations[i] = StakingViolationResponse{TxID: v.TxID, Rule: v.Rule}
+	}
+	return response
+}
+
+func newGetStakerResponse(tx *database.PChainTxData, epochs staking.EpochInfo, renderAddress func(string) string) GetStakerResponse {
+	inputAddresses := strings.Split(tx.InputAddress, ",")
+	for i, address := range inputAddresses {
+		inputAddresses[i] = renderAddress(address)
+	}
+	return GetStakerResponse{
+		TxID:           *tx.TxID,
+		NodeID:         tx.NodeID,
+		StartTime:      *tx.StartTime,
+		EndTime:        *tx.EndTime,
+		StartEpoch:     epochs.EpochOf(tx.StartTime),
+		EndEpoch:       epochs.EpochOf(tx.EndTime),
+		Weight:         api.NewAmount(tx.Weight),
+		FeePercentage:  tx.FeePercentage,
+		InputAddresses: inputAddresses,
+	}
+}
+
+func AddStakerRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
 	vr := newStakerRouteHandlers(ctx)
+	expiration := cache.EpochExpiration(ctx.Config().Cache.TTL, ctx.Epochs())
+	version := labeledDataVersion(ctx)
+	cached := func(handler utils.RouteHandler) utils.RouteHandler {
+		return utils.ConditionalRouteHandler(utils.CachedRouteHandler(handler, ctx.Cache(), expiration), version)
+	}
 
 	validatorSubrouter := router.WithPrefix("/validators", "Staking")
-	validatorSubrouter.AddRoute("/transactions", vr.listStakingTransactions(database.PChainAddValidatorTx))
-	validatorSubrouter.AddRoute("/list", vr.listStakers(database.PChainAddValidatorTx))
+	validatorSubrouter.AddRoute("/transactions", cached(vr.listStakingTransactions(database.PChainAddValidatorTx)))
+	validatorSubrouter.AddRoute("/list", cached(vr.listStakers(database.PChainAddValidatorTx)))
+	validatorSubrouter.AddRoute("/capacity", cached(vr.listValidatorCapacities()))
+	validatorSubrouter.AddRoute("/at", cached(vr.listStakersAt()))
+	validatorSubrouter.AddRoute("/weights", cached(vr.listValidatorWeights()))
+	validatorSubrouter.AddRoute("/top", cached(newTopValidatorsRouteHandlers(ctx).listTopValidators()))
+	validatorSubrouter.AddRoute("/bls/{node_id:NodeID-[0-9a-zA-Z]+}", cached(vr.listValidatorBLSKeys()))
 
 	delegatorSubrouter := router.WithPrefix("/delegators", "Staking")
-	delegatorSubrouter.AddRoute("/transactions", vr.listStakingTransactions(database.PChainAddDelegatorTx))
-	delegatorSubrouter.AddRoute("/list", vr.listStakers(database.PChainAddDelegatorTx))
+	delegatorSubrouter.AddRoute("/transactions", cached(vr.listStakingTransactions(database.PChainAddDelegatorTx)))
+	delegatorSubrouter.AddRoute("/list", cached(vr.listStakers(database.PChainAddDelegatorTx)))
 }
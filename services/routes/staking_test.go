@@ -0,0 +1,60 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/staking"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStakersAtTime(t *testing.T) {
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	epochs := staking.EpochInfo{Start: start, Period: 24 * time.Hour}
+
+	at, err := parseStakersAtTime(map[string]string{"time": "2023-10-05T12:00:00Z"}, epochs)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, 10, 5, 12, 0, 0, 0, time.UTC), at)
+
+	at, err = parseStakersAtTime(map[string]string{"epoch": "3"}, epochs)
+	require.NoError(t, err)
+	require.Equal(t, start.Add(72*time.Hour), at)
+
+	for _, params := range []map[string]string{
+		{},
+		{"time": "2023-10-05T12:00:00Z", "epoch": "3"},
+		{"time": "2023-10-05"},
+		{"epoch": "-1"},
+		{"epoch": "x"},
+	} {
+		_, err = parseStakersAtTime(params, epochs)
+		require.Error(t, err, params)
+	}
+}
+
+func TestParseWeightsEpoch(t *testing.T) {
+	epoch, err := parseWeightsEpoch(map[string]string{"epoch": "12"})
+	require.NoError(t, err)
+	require.Equal(t, int64(12), epoch)
+
+	for _, params := range []map[string]string{{}, {"epoch": "-1"}, {"epoch": "x"}} {
+		_, err = parseWeightsEpoch(params)
+		require.Error(t, err, params)
+	}
+}
+
+func TestTotalValidatorWeight(t *testing.T) {
+	require.Equal(t, "0", totalValidatorWeight(nil).String())
+
+	weights := []database.ValidatorWeight{
+		{NodeID: "NodeID-1", Weight: database.NewBigInt(math.MaxUint64)},
+		{NodeID: "NodeID-2", Weight: database.NewBigInt(math.MaxUint64)},
+		{NodeID: "NodeID-3", Weight: database.NewBigInt(2)},
+	}
+	require.Equal(t, "36893488147419103232", totalValidatorWeight(weights).String())
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/services/api"
+	"flare-indexer/services/cache"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GetDailyStatsRequest struct {
+	// Days (UTC) of both times are included
+	From time.Time `json:"from" validate:"required"`
+	To   time.Time `json:"to" validate:"required"`
+}
+
+type DailyStatsResponse struct {
+	Day             time.Time                        `json:"day"`
+	TxCount         uint64                           `json:"txCount"`
+	TxCounts        map[database.PChainTxType]uint64 `json:"txCounts"` // Number of transactions per type
+	TotalStaked     api.Amount                       `json:"totalStaked"`
+	UniqueAddresses uint64                           `json:"uniqueAddresses"`
+	NewValidators   uint64                           `json:"newValidators"`
+}
+
+type statsRouteHandlers struct {
+	db *gorm.DB
+}
+
+func newStatsRouteHandlers(ctx servicesctx.ServicesContext) *statsRouteHandlers {
+	return &statsRouteHandlers{
+		db: ctx.DB(),
+	}
+}
+
+func (rh *statsRouteHandlers) dailyStats() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetDailyStatsRequest) ([]DailyStatsResponse, *utils.ErrorHandler) {
+		from := request.From.UTC().Truncate(day)
+		to := request.To.UTC().Truncate(day).Add(day)
+		if errHandler := checkBurnStatsRange(from, to, day); errHandler != nil {
+			return nil, errHandler
+		}
+		stats, counts, err := database.FetchDailyStats(ctx, rh.db, from, to)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		return newDailyStatsResponses(stats, counts), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetDailyStatsRequest{}, []DailyStatsResponse{})
+}
+
+func newDailyStatsResponses(stats []database.DailyStats, counts []database.DailyTxCount) []DailyStatsResponse {
+	countsByDay := make(map[time.Time]map[database.PChainTxType]uint64)
+	for _, c := range counts {
+		day := c.Day.UTC()
+		if countsByDay[day] == nil {
+			countsByDay[day] = make(map[database.PChainTxType]uint64)
+		}
+		countsByDay[day][c.Type] = c.TxCount
+	}
+	response := make([]DailyStatsResponse, len(stats))
+	for i, s := range stats {
+		txCounts := countsByDay[s.Day.UTC()]
+		if txCounts == nil {
+			txCounts = map[database.PChainTxType]uint64{}
+		}
+		response[i] = DailyStatsResponse{
+			Day:             s.Day.UTC(),
+			TxCount:         s.TxCount,
+			TxCounts:        txCounts,
+			TotalStaked:     api.NewBigAmount(s.TotalStaked.Big()),
+			UniqueAddresses: s.UniqueAddresses,
+			NewValidators:   s.NewValidators,
+		}
+	}
+	return response
+}
+
+func AddStatsRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newStatsRouteHandlers(ctx)
+	expiration := cache.EpochExpiration(ctx.Config().Cache.TTL, ctx.Epochs())
+	version := indexedDataVersion(ctx)
+	cached := func(handler utils.RouteHandler) utils.RouteHandler {
+		return utils.ConditionalRouteHandler(utils.CachedRouteHandler(handler, ctx.Cache(), expiration), version)
+	}
+
+	subrouter := router.WithPrefix("/stats", "Statistics")
+	subrouter.AddRoute("/daily", cached(rh.dailyStats()), "Daily aggregates of P-chain transactions (UTC), maintained by the stats cronjob")
+}
@@ -0,0 +1,42 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/services/api"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDailyStatsResponses(t *testing.T) {
+	day1 := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(day)
+	stats := []database.DailyStats{
+		{Day: day1, TxCount: 3, TotalStaked: database.NewBigInt(1000), UniqueAddresses: 2, NewValidators: 1},
+		{Day: day2, TotalStaked: database.NewBigInt(1000)},
+	}
+	counts := []database.DailyTxCount{
+		{Day: day1, Type: database.PChainAddValidatorTx, TxCount: 1},
+		{Day: day1, Type: database.PChainImportTx, TxCount: 2},
+	}
+
+	response := newDailyStatsResponses(stats, counts)
+	require.Equal(t, []DailyStatsResponse{
+		{
+			Day:     day1,
+			TxCount: 3,
+			TxCounts: map[database.PChainTxType]uint64{
+				database.PChainAddValidatorTx: 1,
+				database.PChainImportTx:       2,
+			},
+			TotalStaked:     api.NewAmount(1000),
+			UniqueAddresses: 2,
+			NewValidators:   1,
+		},
+		{Day: day2, TxCounts: map[database.PChainTxType]uint64{}, TotalStaked: api.NewAmount(1000)},
+	}, response)
+}
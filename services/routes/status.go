@@ -0,0 +1,160 @@
+package routes
+
+import (
+	"context"
+	_ "embed"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"time"
+
+	swagger "github.com/davidebianchi/gswagger"
+	"gorm.io/gorm"
+)
+
+// Number of the recent runs and of the recent failed runs in the status
+const statusRecentRuns = 20
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+type StateResponse struct {
+	Name string `json:"name"`
+	// Next item to process, the next block or vertex index of the indexers and
+	// e.g. the next epoch of the voting and mirroring cronjobs
+	Next           uint64    `json:"next"`
+	LastChainIndex uint64    `json:"lastChainIndex"`
+	Updated        time.Time `json:"updated"`
+	// Number of items on the chain which are not processed yet, only set if
+	// the last chain index is known
+	Behind *uint64 `json:"behind,omitempty"`
+}
+
+type StatusResponse struct {
+	Time time.Time `json:"time"`
+	// Current reward epoch
+	Epoch *int64 `json:"epoch,omitempty"`
+	// Last indexed P-chain block, omitted if no block is indexed
+	LastBlock *BlockResponse `json:"lastBlock,omitempty"`
+	// Time since the last indexed block in seconds
+	LastBlockAge *int64               `json:"lastBlockAge,omitempty"`
+	States       []StateResponse      `json:"states"`
+	RecentRuns   []CronjobRunResponse `json:"recentRuns"`
+	RecentErrors []CronjobRunResponse `json:"recentErrors"`
+}
+
+type statusRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+}
+
+func newStatusRouteHandlers(ctx servicesctx.ServicesContext) *statusRouteHandlers {
+	return &statusRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+	}
+}
+
+func (rh *statusRouteHandlers) getStatus() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (StatusResponse, *utils.ErrorHandler) {
+		now := time.Now()
+		response := StatusResponse{
+			Time:  now.UTC(),
+			Epoch: rh.epochs.EpochOf(&now),
+		}
+
+		blocks, err := database.FetchPChainBlocks(ctx, rh.db, 0, 1)
+		if err != nil {
+			return StatusResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		if len(blocks) > 0 {
+			b := blocks[0]
+			age := int64(now.Sub(b.Timestamp) / time.Second)
+			response.LastBlock = &BlockResponse{
+				Height:         b.Height,
+				ContainerIndex: blockContainerIndex(&b),
+				BlockID:        b.BlockID,
+				Timestamp:      b.Timestamp.UTC(),
+				Epoch:          rh.epochs.EpochOf(&b.Timestamp),
+			}
+			response.LastBlockAge = &age
+		}
+
+		states, err := database.FetchStates(ctx, rh.db)
+		if err != nil {
+			return StatusResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response.States = make([]StateResponse, len(states))
+		for i, s := range states {
+			response.States[i] = newStateResponse(s)
+		}
+
+		runs, err := database.FetchCronjobRuns(ctx, rh.db, "", nil, 0, statusRecentRuns)
+		if err != nil {
+			return StatusResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response.RecentRuns = newCronjobRunResponses(runs, rh.epochs)
+		failed, err := database.FetchFailedCronjobRuns(ctx, rh.db, statusRecentRuns)
+		if err != nil {
+			return StatusResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		response.RecentErrors = newCronjobRunResponses(failed, rh.epochs)
+		return response, nil
+	}
+	return utils.NewQueryRouteHandler(handler, http.MethodGet, map[string]string{}, StatusResponse{})
+}
+
+func newStateResponse(s database.State) StateResponse {
+	response := StateResponse{
+		Name:           s.Name,
+		Next:           s.NextDBIndex,
+		LastChainIndex: s.LastChainIndex,
+		Updated:        s.Updated.UTC(),
+	}
+	if s.LastChainIndex > 0 {
+		var behind uint64
+		if s.LastChainIndex >= s.NextDBIndex {
+			behind = s.LastChainIndex - s.NextDBIndex + 1
+		}
+		response.Behind = &behind
+	}
+	return response
+}
+
+// Static page of the dashboard, it reads the status route with the admin token
+// entered by the operator
+func dashboardHandler() utils.RouteHandler {
+	return utils.RouteHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Write(dashboardPage)
+		},
+		SwaggerDefinitions: swagger.Definitions{
+			Responses: map[int]swagger.ContentValue{
+				200: {
+					Content: swagger.Content{
+						"text/html": {Value: ""},
+					},
+				},
+			},
+		},
+		Method: http.MethodGet,
+	}
+}
+
+func AddStatusRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newStatusRouteHandlers(ctx)
+	servicesCfg := ctx.Config().Services
+
+	subrouter := router.WithPrefix("/admin", "Admin")
+	subrouter.AddRoute("/status", utils.AdminRouteHandler(rh.getStatus(), servicesCfg.AdminToken),
+		"Sync status of the indexers, states of the cronjobs and their recent runs and errors",
+		"Requires the admin token")
+	if servicesCfg.Dashboard {
+		subrouter.AddRoute("/dashboard", dashboardHandler(),
+			"Operator dashboard showing the status", "The data of the page requires the admin token")
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"flare-indexer/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStateResponse(t *testing.T) {
+	response := newStateResponse(database.State{Name: "p_chain_block", NextDBIndex: 101, LastChainIndex: 120})
+	require.Equal(t, uint64(20), *response.Behind)
+
+	response = newStateResponse(database.State{Name: "p_chain_block", NextDBIndex: 121, LastChainIndex: 120})
+	require.Equal(t, uint64(0), *response.Behind)
+
+	// Cronjob states do not know the last chain index
+	response = newStateResponse(database.State{Name: "voting_cronjob", NextDBIndex: 100})
+	require.Nil(t, response.Behind)
+	require.Equal(t, uint64(100), response.Next)
+}
+
+func TestDashboardHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	dashboardHandler().Handler(w, httptest.NewRequest(http.MethodGet, "/admin/dashboard", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+	require.Contains(t, w.Body.String(), `fetch("status"`)
+}
@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GetTombstonesRequest struct {
+	PaginatedRequest
+	// Table of the deleted rows (including the table prefix), all tables if empty
+	Table string `json:"table" validate:"max=64"`
+}
+
+type TombstoneResponse struct {
+	Table  string          `json:"table"`
+	Reason string          `json:"reason"`
+	Data   json.RawMessage `json:"data"`
+	// Time of the deletion and the reward epoch containing it
+	Timestamp time.Time `json:"timestamp"`
+	Epoch     *int64    `json:"epoch,omitempty"`
+}
+
+type tombstoneRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+}
+
+func newTombstoneRouteHandlers(ctx servicesctx.ServicesContext) *tombstoneRouteHandlers {
+	return &tombstoneRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+	}
+}
+
+func (rh *tombstoneRouteHandlers) listTombstones() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetTombstonesRequest) ([]TombstoneResponse, *utils.ErrorHandler) {
+		tombstones, err := database.FetchTombstones(ctx, rh.db, request.Table, request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]TombstoneResponse, len(tombstones))
+		for i, t := range tombstones {
+			response[i] = TombstoneResponse{
+				Table:     t.SourceTable,
+				Reason:    t.Reason,
+				Data:      json.RawMessage(t.Data),
+				Timestamp: t.Timestamp.UTC(),
+				Epoch:     rh.epochs.EpochOf(&t.Timestamp),
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetTombstonesRequest{}, []TombstoneResponse{})
+}
+
+func AddTombstoneRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newTombstoneRouteHandlers(ctx)
+	adminToken := ctx.Config().Services.AdminToken
+
+	subrouter := router.WithPrefix("/admin", "Admin")
+	subrouter.AddRoute("/tombstones", utils.AdminRouteHandler(rh.listTombstones(), adminToken),
+		"Copies of the rows deleted by reindex, the newest first", "Requires the admin token")
+}
@@ -0,0 +1,223 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	"flare-indexer/services/api"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Rankings of /validators/top
+const (
+	topByWeight     = "weight"
+	topByDelegators = "delegators"
+	topByUptime     = "uptime"
+)
+
+// Number of validators returned if the limit is not given
+const defaultTopValidatorsLimit = 10
+
+// Validator of a leaderboard, with the weight and delegators for rankings by
+// weight and delegators and with the uptime for rankings by uptime
+type TopValidatorResponse struct {
+	Rank            int         `json:"rank"`
+	NodeID          string      `json:"nodeID"`
+	Weight          *api.Amount `json:"weight,omitempty"`
+	DelegatedWeight *api.Amount `json:"delegatedWeight,omitempty"`
+	Delegators      *int        `json:"delegators,omitempty"`
+	// Ratio of the uptime to the staking duration in the uptime epoch
+	Uptime *float64 `json:"uptime,omitempty"`
+	// Operator of the validator node, if labeled
+	Label *ValidatorLabelResponse `json:"label,omitempty"`
+}
+
+type GetTopValidatorsResponse struct {
+	By         string                 `json:"by"`
+	Epoch      int64                  `json:"epoch"`
+	Validators []TopValidatorResponse `json:"validators"`
+}
+
+type topValidatorsDB interface {
+	// Validator weights are recorded for the epochs before next (from the first
+	// epoch of the indexer config)
+	NextValidatorWeightsEpoch(ctx context.Context) (int64, error)
+	FetchTopValidatorWeights(ctx context.Context, epoch int64, order database.ValidatorWeightOrder, limit int) ([]database.ValidatorWeight, error)
+	// Last aggregated uptime epoch, -1 if there is none
+	LastUptimeEpoch(ctx context.Context) (int64, error)
+	FetchTopUptimeAggregations(ctx context.Context, epoch int64, limit int) ([]database.UptimeAggregation, error)
+	FetchLabels(ctx context.Context, nodeIDs []string) (map[string]*ValidatorLabelResponse, error)
+}
+
+type topValidatorsRouteHandlers struct {
+	db topValidatorsDB
+}
+
+func newTopValidatorsRouteHandlers(ctx servicesctx.ServicesContext) *topValidatorsRouteHandlers {
+	return &topValidatorsRouteHandlers{db: topValidatorsDBGorm{db: ctx.DB()}}
+}
+
+// Leaderboard of the validators by weight or delegators at the start of a
+// reward epoch (from the validator weights recorded by the indexer), or by
+// uptime in an uptime epoch (from the uptime aggregations), the last recorded
+// epoch if the epoch is not given
+func (rh *topValidatorsRouteHandlers) listTopValidators() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (GetTopValidatorsResponse, *utils.ErrorHandler) {
+		by, epoch, limit, errHandler := parseTopValidatorsParams(params)
+		if errHandler != nil {
+			return GetTopValidatorsResponse{}, errHandler
+		}
+		if errHandler := utils.QueryLimitsFromContext(ctx).CheckPage(0, limit); errHandler != nil {
+			return GetTopValidatorsResponse{}, errHandler
+		}
+		var validators []TopValidatorResponse
+		if by == topByUptime {
+			epoch, validators, errHandler = rh.topByUptime(ctx, epoch, limit)
+		} else {
+			epoch, validators, errHandler = rh.topByWeight(ctx, database.ValidatorWeightOrder(by), epoch, limit)
+		}
+		if errHandler != nil {
+			return GetTopValidatorsResponse{}, errHandler
+		}
+
+		nodeIDs := make([]string, len(validators))
+		for i := range validators {
+			nodeIDs[i] = validators[i].NodeID
+		}
+		labels, err := rh.db.FetchLabels(ctx, nodeIDs)
+		if err != nil {
+			return GetTopValidatorsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		for i := range validators {
+			validators[i].Rank = i + 1
+			validators[i].Label = labels[validators[i].NodeID]
+		}
+		return GetTopValidatorsResponse{By: by, Epoch: epoch, Validators: validators}, nil
+	}
+	return utils.NewQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{
+			"by":    "Ranking, weight (default), delegators or uptime",
+			"epoch": "Reward epoch (uptime epoch for uptime), the last recorded epoch if not given",
+			"limit": "Number of validators, 10 if not given",
+		},
+		GetTopValidatorsResponse{})
+}
+
+// Epoch is -1 if not given
+func parseTopValidatorsParams(params map[string]string) (string, int64, int, *utils.ErrorHandler) {
+	by, ok := params["by"]
+	if !ok {
+		by = topByWeight
+	}
+	if by != topByWeight && by != topByDelegators && by != topByUptime {
+		return "", 0, 0, utils.HttpErrorHandler(http.StatusBadRequest, "by must be weight, delegators or uptime")
+	}
+	epoch := int64(-1)
+	if epochParam, ok := params["epoch"]; ok {
+		var err error
+		epoch, err = strconv.ParseInt(epochParam, 10, 64)
+		if err != nil || epoch < 0 {
+			return "", 0, 0, utils.HttpErrorHandler(http.StatusBadRequest, "invalid epoch")
+		}
+	}
+	limit := defaultTopValidatorsLimit
+	if limitParam, ok := params["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			return "", 0, 0, utils.HttpErrorHandler(http.StatusBadRequest, "invalid limit")
+		}
+	}
+	return by, epoch, limit, nil
+}
+
+func (rh *topValidatorsRouteHandlers) topByWeight(
+	ctx context.Context, order database.ValidatorWeightOrder, epoch int64, limit int,
+) (int64, []TopValidatorResponse, *utils.ErrorHandler) {
+	next, err := rh.db.NextValidatorWeightsEpoch(ctx)
+	if err != nil {
+		return 0, nil, utils.InternalServerErrorHandler(err)
+	}
+	if epoch < 0 {
+		epoch = next - 1
+	}
+	if epoch < 0 || epoch >= next {
+		return 0, nil, utils.HttpErrorHandler(http.StatusNotFound, "validator weights of the epoch are not recorded")
+	}
+	weights, err := rh.db.FetchTopValidatorWeights(ctx, epoch, order, limit)
+	if err != nil {
+		return 0, nil, utils.InternalServerErrorHandler(err)
+	}
+	validators := make([]TopValidatorResponse, len(weights))
+	for i := range weights {
+		w := &weights[i]
+		weight, delegatedWeight := api.NewBigAmount(w.Weight.Big()), api.NewBigAmount(w.DelegatedWeight.Big())
+		validators[i] = TopValidatorResponse{
+			NodeID:          w.NodeID,
+			Weight:          &weight,
+			DelegatedWeight: &delegatedWeight,
+			Delegators:      &w.Delegators,
+		}
+	}
+	return epoch, validators, nil
+}
+
+func (rh *topValidatorsRouteHandlers) topByUptime(ctx context.Context, epoch int64, limit int) (int64, []TopValidatorResponse, *utils.ErrorHandler) {
+	last, err := rh.db.LastUptimeEpoch(ctx)
+	if err != nil {
+		return 0, nil, utils.InternalServerErrorHandler(err)
+	}
+	if epoch < 0 {
+		epoch = last
+	}
+	if epoch < 0 || epoch > last {
+		return 0, nil, utils.HttpErrorHandler(http.StatusNotFound, "uptimes of the epoch are not aggregated")
+	}
+	aggregations, err := rh.db.FetchTopUptimeAggregations(ctx, epoch, limit)
+	if err != nil {
+		return 0, nil, utils.InternalServerErrorHandler(err)
+	}
+	validators := make([]TopValidatorResponse, len(aggregations))
+	for i, a := range aggregations {
+		uptime := float64(a.Value) / float64(a.StakingDuration)
+		validators[i] = TopValidatorResponse{NodeID: a.NodeID, Uptime: &uptime}
+	}
+	return epoch, validators, nil
+}
+
+type topValidatorsDBGorm struct {
+	db *gorm.DB
+}
+
+func (t topValidatorsDBGorm) NextValidatorWeightsEpoch(ctx context.Context) (int64, error) {
+	state, err := database.FetchState(ctx, t.db, database.ValidatorWeightsStateName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return int64(state.NextDBIndex), err
+}
+
+func (t topValidatorsDBGorm) FetchTopValidatorWeights(ctx context.Context, epoch int64, order database.ValidatorWeightOrder, limit int) ([]database.ValidatorWeight, error) {
+	return database.FetchTopValidatorWeights(ctx, t.db, epoch, order, limit)
+}
+
+func (t topValidatorsDBGorm) LastUptimeEpoch(ctx context.Context) (int64, error) {
+	last, err := database.FetchLastUptimeAggregation(ctx, t.db)
+	if err != nil || last == nil {
+		return -1, err
+	}
+	return int64(last.Epoch), nil
+}
+
+func (t topValidatorsDBGorm) FetchTopUptimeAggregations(ctx context.Context, epoch int64, limit int) ([]database.UptimeAggregation, error) {
+	return database.FetchTopUptimeAggregations(ctx, t.db, int(epoch), limit)
+}
+
+func (t topValidatorsDBGorm) FetchLabels(ctx context.Context, nodeIDs []string) (map[string]*ValidatorLabelResponse, error) {
+	return fetchLabelsByNodeID(ctx, t.db, nodeIDs)
+}
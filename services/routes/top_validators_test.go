@@ -0,0 +1,142 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/services/api"
+	serviceUtils "flare-indexer/services/utils"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopValidators(t *testing.T) {
+	db := &topValidatorsTestDB{
+		weights: []database.ValidatorWeight{
+			{Epoch: 4, NodeID: "NodeID-1", Weight: database.NewBigInt(100), Delegators: 1},
+			{Epoch: 4, NodeID: "NodeID-2", Weight: database.NewBigInt(300), Delegators: 0},
+			{Epoch: 4, NodeID: "NodeID-3", Weight: database.NewBigInt(200), Delegators: 5},
+			{Epoch: 3, NodeID: "NodeID-1", Weight: database.NewBigInt(50), Delegators: 0},
+		},
+		uptimes: []database.UptimeAggregation{
+			{Epoch: 7, NodeID: "NodeID-1", Value: 90, StakingDuration: 100},
+			{Epoch: 7, NodeID: "NodeID-2", Value: 100, StakingDuration: 100},
+		},
+		nextWeightsEpoch: 5,
+		lastUptimeEpoch:  7,
+	}
+	rh := &topValidatorsRouteHandlers{db: db}
+
+	response := getTopValidators(t, rh, "/validators/top", http.StatusOK)
+	require.Equal(t, "weight", response.By)
+	require.Equal(t, int64(4), response.Epoch)
+	require.Equal(t, []string{"NodeID-2", "NodeID-3", "NodeID-1"}, topNodeIDs(response))
+	require.Equal(t, 1, response.Validators[0].Rank)
+	require.Equal(t, "300", response.Validators[0].Weight.String())
+	require.NotNil(t, response.Validators[0].Label)
+
+	response = getTopValidators(t, rh, "/validators/top?by=delegators&limit=2", http.StatusOK)
+	require.Equal(t, []string{"NodeID-3", "NodeID-1"}, topNodeIDs(response))
+
+	response = getTopValidators(t, rh, "/validators/top?epoch=3", http.StatusOK)
+	require.Equal(t, []string{"NodeID-1"}, topNodeIDs(response))
+
+	response = getTopValidators(t, rh, "/validators/top?by=uptime", http.StatusOK)
+	require.Equal(t, int64(7), response.Epoch)
+	require.Equal(t, []string{"NodeID-2", "NodeID-1"}, topNodeIDs(response))
+	require.Equal(t, 0.9, *response.Validators[1].Uptime)
+	require.Nil(t, response.Validators[1].Weight)
+
+	getTopValidators(t, rh, "/validators/top?epoch=5", http.StatusNotFound)
+	getTopValidators(t, rh, "/validators/top?by=uptime&epoch=8", http.StatusNotFound)
+	getTopValidators(t, rh, "/validators/top?by=stake", http.StatusBadRequest)
+	getTopValidators(t, rh, "/validators/top?epoch=-1", http.StatusBadRequest)
+	getTopValidators(t, rh, "/validators/top?limit=0", http.StatusBadRequest)
+
+	w := httptest.NewRecorder()
+	rh.listTopValidators().Handler(w, httptest.NewRequest(http.MethodGet, "/validators/top?limit=1000", nil))
+	var wResponse api.ApiResponseWrapper[GetTopValidatorsResponse]
+	serviceUtils.DecodeStruct(t, w.Result().Body, &wResponse)
+	require.Equal(t, api.ApiResStatusQueryLimitExceeded, wResponse.Status)
+
+	rh.db = &topValidatorsTestDB{lastUptimeEpoch: -1}
+	getTopValidators(t, rh, "/validators/top", http.StatusNotFound)
+	getTopValidators(t, rh, "/validators/top?by=uptime", http.StatusNotFound)
+}
+
+func getTopValidators(t *testing.T, rh *topValidatorsRouteHandlers, url string, status int) GetTopValidatorsResponse {
+	w := httptest.NewRecorder()
+	rh.listTopValidators().Handler(w, httptest.NewRequest(http.MethodGet, url, nil))
+	require.Equal(t, status, w.Code, url)
+	if status != http.StatusOK {
+		return GetTopValidatorsResponse{}
+	}
+	var wResponse api.ApiResponseWrapper[GetTopValidatorsResponse]
+	serviceUtils.DecodeStruct(t, w.Result().Body, &wResponse)
+	return wResponse.Data
+}
+
+func topNodeIDs(response GetTopValidatorsResponse) []string {
+	nodeIDs := make([]string, len(response.Validators))
+	for i, v := range response.Validators {
+		nodeIDs[i] = v.NodeID
+	}
+	return nodeIDs
+}
+
+type topValidatorsTestDB struct {
+	weights          []database.ValidatorWeight
+	uptimes          []database.UptimeAggregation
+	nextWeightsEpoch int64
+	lastUptimeEpoch  int64
+}
+
+func (db *topValidatorsTestDB) NextValidatorWeightsEpoch(ctx context.Context) (int64, error) {
+	return db.nextWeightsEpoch, nil
+}
+
+func (db *topValidatorsTestDB) FetchTopValidatorWeights(ctx context.Context, epoch int64, order database.ValidatorWeightOrder, limit int) ([]database.ValidatorWeight, error) {
+	var weights []database.ValidatorWeight
+	for _, w := range db.weights {
+		if w.Epoch == epoch {
+			weights = append(weights, w)
+		}
+	}
+	sort.Slice(weights, func(i, j int) bool {
+		if order == database.ValidatorWeightOrderDelegators {
+			return weights[i].Delegators > weights[j].Delegators
+		}
+		return weights[i].Weight.Cmp(weights[j].Weight) > 0
+	})
+	if len(weights) > limit {
+		weights = weights[:limit]
+	}
+	return weights, nil
+}
+
+func (db *topValidatorsTestDB) LastUptimeEpoch(ctx context.Context) (int64, error) {
+	return db.lastUptimeEpoch, nil
+}
+
+func (db *topValidatorsTestDB) FetchTopUptimeAggregations(ctx context.Context, epoch int64, limit int) ([]database.UptimeAggregation, error) {
+	var aggregations []database.UptimeAggregation
+	for _, a := range db.uptimes {
+		if int64(a.Epoch) == epoch {
+			aggregations = append(aggregations, a)
+		}
+	}
+	sort.Slice(aggregations, func(i, j int) bool {
+		return aggregations[i].Value*aggregations[j].StakingDuration > aggregations[j].Value*aggregations[i].StakingDuration
+	})
+	return aggregations, nil
+}
+
+func (db *topValidatorsTestDB) FetchLabels(ctx context.Context, nodeIDs []string) (map[string]*ValidatorLabelResponse, error) {
+	return map[string]*ValidatorLabelResponse{"NodeID-2": {NodeID: "NodeID-2", Name: "Operator"}}, nil
+}
@@ -1,33 +1,96 @@
 package routes
 
 import (
+	"context"
+	"encoding/json"
 	"flare-indexer/database"
 	"flare-indexer/services/api"
-	"flare-indexer/services/context"
+	servicesctx "flare-indexer/services/context"
 	"flare-indexer/services/utils"
+	globalUtils "flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/errcode"
+	"flare-indexer/utils/staking"
 	"net/http"
+	"strings"
 
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
 	"gorm.io/gorm"
 )
 
+// Sources of the raw transactions
+const (
+	// Stored bytes of the block of the transaction
+	RawTxSourceDB = "db"
+	// Transaction fetched from the node (platform.getTx)
+	RawTxSourceNode = "node"
+)
+
+type GetTransactionsBatchRequest struct {
+	// At most 100 ids
+	TxIDs []string `json:"txIds" validate:"required,min=1,max=100,dive,required"`
+}
+
+type GetTransactionsByMemoRequest struct {
+	PaginatedRequest
+	// Hex encoded prefix of the memo, e.g. "0x6465706f736974"
+	Prefix string `json:"prefix" validate:"required_without=Text,omitempty,hexadecimal,max=514"`
+	// Prefix of the memo as text, used if Prefix is empty
+	Text string `json:"text" validate:"max=256"`
+}
+
+type TransactionsBatchResponse struct {
+	// Transactions in the order of the request
+	Transactions []*api.ApiPChainTx `json:"transactions"`
+	// Requested ids of transactions which are not indexed
+	NotFound []string `json:"notFound"`
+}
+
+type RawTransactionResponse struct {
+	TxID string `json:"txId"`
+	// Source of the transaction, "db" or "node"
+	Source string `json:"source"`
+	// Decoded transaction with all fields of the unsigned transaction and the
+	// credentials, as returned by platform.getTx with the json encoding
+	Tx json.RawMessage `json:"tx"`
+}
+
 type transactionRouteHandlers struct {
-	db *gorm.DB
+	db     *gorm.DB
+	epochs staking.EpochInfo
+	// Client of the node, nil if the node url is not configured
+	client chain.RPCClient
+	hrp    string
+	// The services do not check the node version, raw transactions are parsed
+	// with the newest parser
+	parser *chain.Parser
 }
 
-func newTransactionRouteHandlers(ctx context.ServicesContext) *transactionRouteHandlers {
-	return &transactionRouteHandlers{
-		db: ctx.DB(),
+func newTransactionRouteHandlers(ctx servicesctx.ServicesContext) *transactionRouteHandlers {
+	chainCfg := ctx.Config().Chain
+	rh := &transactionRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+		hrp:    chainCfg.ChainAddressHRP,
+		parser: chain.NewParser(),
 	}
+	if len(chainCfg.NodeURL) > 0 {
+		rh.client = chain.NewAvalancheRPCClient(
+			globalUtils.JoinPaths(chainCfg.NodeURL, "ext/bc/P"+chain.RPCClientOptions(chainCfg.ApiKey)),
+			chainCfg.GetPlatformTimeout())
+	}
+	return rh
 }
 
 func (rh *transactionRouteHandlers) getTransaction() utils.RouteHandler {
-	handler := func(params map[string]string) (*api.ApiPChainTx, *utils.ErrorHandler) {
+	handler := func(ctx context.Context, params map[string]string) (*api.ApiPChainTx, *utils.ErrorHandler) {
 		txID := params["tx_id"]
 		var resp *api.ApiPChainTx = nil
-		err := database.DoInTransaction(rh.db, func(dbTx *gorm.DB) error {
-			tx, inputs, outputs, err := database.FetchPChainTxFull(rh.db, txID)
+		err := database.DoInTransaction(ctx, rh.db, func(dbTx *gorm.DB) error {
+			tx, inputs, outputs, err := database.FetchPChainTxFull(ctx, rh.db, txID)
 			if err == nil {
-				resp = api.NewApiPChainTx(tx, inputs, outputs)
+				resp = api.NewApiPChainTx(tx, inputs, outputs, rh.epochs)
 			}
 			return err
 		})
@@ -41,8 +104,156 @@ func (rh *transactionRouteHandlers) getTransaction() utils.RouteHandler {
 		&api.ApiPChainTx{})
 }
 
-func AddTransactionRoutes(router utils.Router, ctx context.ServicesContext) {
+func (rh *transactionRouteHandlers) getRawTransaction() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) (RawTransactionResponse, *utils.ErrorHandler) {
+		txID, err := ids.FromString(params["tx_id"])
+		if err != nil {
+			return RawTransactionResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, "invalid tx_id")
+		}
+		source := params["source"]
+		switch source {
+		case "", RawTxSourceDB:
+		case RawTxSourceNode:
+			if rh.client == nil {
+				return RawTransactionResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, "node url is not configured")
+			}
+		default:
+			return RawTransactionResponse{}, utils.HttpErrorHandler(http.StatusBadRequest, "invalid source, expected db or node")
+		}
+
+		tx, source, err := rh.fetchRawTransaction(ctx, txID, source)
+		if err != nil {
+			return RawTransactionResponse{}, utils.ErrorResponseHandler(err)
+		}
+		data, err := chain.PChainTxJSON(tx, rh.hrp)
+		if err != nil {
+			return RawTransactionResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return RawTransactionResponse{TxID: txID.String(), Source: source, Tx: data}, nil
+	}
+	return utils.NewParamQueryRouteHandler(handler, http.MethodGet,
+		map[string]string{"tx_id:[0-9a-zA-Z]+": "Transaction ID"},
+		map[string]string{
+			"source": "db to decode the transaction from the stored block, node to fetch it from the node; " +
+				"if not given, the node is used only if the stored block is not available",
+		},
+		RawTransactionResponse{})
+}
+
+// Returns the transaction and its source: decoded from the stored bytes of its
+// block unless the source is node, otherwise (or if the block is not stored and
+// the source is not db) fetched from the node
+func (rh *transactionRouteHandlers) fetchRawTransaction(ctx context.Context, txID ids.ID, source string) (*txs.Tx, string, error) {
+	if source != RawTxSourceNode {
+		tx, err := rh.fetchStoredTransaction(ctx, txID)
+		if err == nil {
+			return tx, RawTxSourceDB, nil
+		}
+		if source == RawTxSourceDB || rh.client == nil || errcode.Of(err) != errcode.NotFound {
+			return nil, "", err
+		}
+	}
+	tx, err := chain.FetchPChainTx(ctx, rh.client, rh.parser, txID)
+	if err != nil {
+		return nil, "", err
+	}
+	return tx, RawTxSourceNode, nil
+}
+
+// Decodes the transaction from the stored bytes of its block, the error has the
+// code errcode.NotFound if the transaction or the bytes are not stored
+func (rh *transactionRouteHandlers) fetchStoredTransaction(ctx context.Context, txID ids.ID) (*txs.Tx, error) {
+	blockBytes, err := database.FetchPChainTxBlockBytes(ctx, rh.db, txID.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(blockBytes) == 0 {
+		return nil, errcode.New(errcode.NotFound, "block of tx %s is not stored", txID)
+	}
+	tx, err := chain.PChainBlockTx(rh.parser, blockBytes, txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, errcode.New(errcode.NotFound, "tx %s is not in its stored block", txID)
+	}
+	return tx, nil
+}
+
+func (rh *transactionRouteHandlers) getTransactionsBatch() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetTransactionsBatchRequest) (TransactionsBatchResponse, *utils.ErrorHandler) {
+		txs, inputs, outputs, err := database.FetchPChainTxsFull(ctx, rh.db, request.TxIDs)
+		if err != nil {
+			return TransactionsBatchResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return newTransactionsBatchResponse(request.TxIDs, txs, inputs, outputs, rh.epochs), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetTransactionsBatchRequest{}, TransactionsBatchResponse{})
+}
+
+func (rh *transactionRouteHandlers) listTransactionsByMemo() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetTransactionsByMemoRequest) (TxIDsResponse, *utils.ErrorHandler) {
+		txIDs, err := database.FetchPChainTxIDsByMemoPrefix(ctx, rh.db, memoPrefix(&request), request.Offset, request.Limit)
+		if err != nil {
+			return TxIDsResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return TxIDsResponse{TxIDs: txIDs}, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetTransactionsByMemoRequest{}, TxIDsResponse{})
+}
+
+// Prefix of the stored memos (hex encoded, lower case, "0x" prefix) of the request
+func memoPrefix(request *GetTransactionsByMemoRequest) string {
+	if request.Prefix == "" {
+		return chain.EncodeMemo([]byte(request.Text))
+	}
+	prefix := strings.ToLower(request.Prefix)
+	return "0x" + strings.TrimPrefix(prefix, "0x")
+}
+
+func newTransactionsBatchResponse(
+	txIDs []string,
+	txs []database.PChainTx,
+	inputs []database.PChainTxInput,
+	outputs []database.PChainTxOutput,
+	epochs staking.EpochInfo,
+) TransactionsBatchResponse {
+	txMap := make(map[string]*database.PChainTx, len(txs))
+	for i := range txs {
+		txMap[*txs[i].TxID] = &txs[i]
+	}
+	inputMap := make(map[string][]database.PChainTxInput)
+	for _, in := range inputs {
+		inputMap[in.TxID] = append(inputMap[in.TxID], in)
+	}
+	outputMap := make(map[string][]database.PChainTxOutput)
+	for _, out := range outputs {
+		outputMap[out.TxID] = append(outputMap[out.TxID], out)
+	}
+
+	response := TransactionsBatchResponse{
+		Transactions: make([]*api.ApiPChainTx, 0, len(txIDs)),
+		NotFound:     []string{},
+	}
+	for _, txID := range txIDs {
+		if tx, ok := txMap[txID]; ok {
+			response.Transactions = append(response.Transactions, api.NewApiPChainTx(tx, inputMap[txID], outputMap[txID], epochs))
+		} else {
+			response.NotFound = append(response.NotFound, txID)
+		}
+	}
+	return response
+}
+
+func AddTransactionRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
 	vr := newTransactionRouteHandlers(ctx)
+	version := indexedDataVersion(ctx)
 	subrouter := router.WithPrefix("/transactions", "Transactions")
-	subrouter.AddRoute("/get/{tx_id:[0-9a-zA-Z]+}", vr.getTransaction())
+	subrouter.AddRoute("/get/{tx_id:[0-9a-zA-Z]+}", utils.ConditionalRouteHandler(vr.getTransaction(), version))
+	subrouter.AddRoute("/raw/{tx_id:[0-9a-zA-Z]+}", utils.ConditionalRouteHandler(vr.getRawTransaction(), version),
+		"Fully decoded transaction with its credentials, from the stored block or from the node")
+	subrouter.AddRoute("/batch", utils.ConditionalRouteHandler(vr.getTransactionsBatch(), version),
+		"Transactions with the given ids (at most 100) with inputs and outputs")
+	subrouter.AddRoute("/memo", utils.ConditionalRouteHandler(vr.listTransactionsByMemo(), version),
+		"Ids of the transactions whose memo starts with the given prefix")
 }
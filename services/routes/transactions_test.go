@@ -0,0 +1,95 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"encoding/json"
+	"flare-indexer/database"
+	"flare-indexer/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransactionsBatchResponse(t *testing.T) {
+	txA, txB := "txA", "txB"
+	start, end := utils.ParseTime("2023-10-01T12:00:00Z"), utils.ParseTime("2023-10-15T12:00:00Z")
+	txs := []database.PChainTx{
+		{TxID: &txB, Type: database.PChainExportTx},
+		{TxID: &txA, Type: database.PChainAddDelegatorTx, StartTime: &start, EndTime: &end},
+	}
+	inputs := []database.PChainTxInput{
+		{TxInput: database.TxInput{TxID: txA, Amount: 10, Address: "in1"}},
+	}
+	outputs := []database.PChainTxOutput{
+		{TxOutput: database.TxOutput{TxID: txA, Amount: 9, Idx: 0, Address: "out1"}},
+		{TxOutput: database.TxOutput{TxID: txB, Amount: 5, Idx: 0, Address: "out2"}},
+		{TxOutput: database.TxOutput{TxID: txB, Amount: 4, Idx: 1, Address: "out3"}},
+	}
+
+	epochs := staking.EpochInfo{Start: utils.ParseTime("2023-09-30T00:00:00Z"), Period: 7 * 24 * time.Hour}
+	response := newTransactionsBatchResponse([]string{"txA", "missing", "txB"}, txs, inputs, outputs, epochs)
+
+	require.Equal(t, []string{"missing"}, response.NotFound)
+	require.Len(t, response.Transactions, 2)
+
+	a := response.Transactions[0]
+	require.Equal(t, txA, *a.TxID)
+	require.Len(t, a.Inputs, 1)
+	require.Equal(t, "in1", a.Inputs[0].Address)
+	require.Len(t, a.Outputs, 1)
+	require.Equal(t, int64(0), *a.StartEpoch)
+	require.Equal(t, int64(2), *a.EndEpoch)
+
+	b := response.Transactions[1]
+	require.Equal(t, txB, *b.TxID)
+	require.Empty(t, b.Inputs)
+	require.Len(t, b.Outputs, 2)
+	require.Equal(t, uint32(1), b.Outputs[1].Idx)
+	require.Nil(t, b.StartEpoch)
+}
+
+func TestMemoPrefix(t *testing.T) {
+	require.Equal(t, "0x6465706f736974", memoPrefix(&GetTransactionsByMemoRequest{Text: "deposit"}))
+	require.Equal(t, "0x6465ab", memoPrefix(&GetTransactionsByMemoRequest{Prefix: "0x6465AB", Text: "ignored"}))
+	require.Equal(t, "0x646", memoPrefix(&GetTransactionsByMemoRequest{Prefix: "646"}))
+}
+
+func TestGetRawTransaction(t *testing.T) {
+	client, err := chain.PChainTestRPCClient()
+	require.NoError(t, err)
+	txID := "274PsK9Wn5GgfihqK8iWHfCYxEDDTgvKWvfmmzgV7kvaK7vNCv"
+
+	call := func(rh *transactionRouteHandlers, url string) *httptest.ResponseRecorder {
+		router := mux.NewRouter()
+		router.HandleFunc("/transactions/raw/{tx_id:[0-9a-zA-Z]+}", rh.getRawTransaction().Handler)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	rh := &transactionRouteHandlers{client: client, hrp: "localflare", parser: chain.NewParser()}
+	w := call(rh, "/transactions/raw/"+txID+"?source=node")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data RawTransactionResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Equal(t, txID, response.Data.TxID)
+	require.Equal(t, RawTxSourceNode, response.Data.Source)
+	var tx map[string]interface{}
+	require.NoError(t, json.Unmarshal(response.Data.Tx, &tx))
+	require.Contains(t, tx, "unsignedTx")
+	require.Contains(t, tx, "credentials")
+
+	require.Equal(t, http.StatusBadRequest, call(rh, "/transactions/raw/"+txID+"?source=other").Code)
+	require.Equal(t, http.StatusBadRequest, call(rh, "/transactions/raw/invalid").Code)
+	require.Equal(t, http.StatusBadRequest, call(&transactionRouteHandlers{}, "/transactions/raw/"+txID+"?source=node").Code)
+}
@@ -1,8 +1,9 @@
 package routes
 
 import (
+	"context"
 	"flare-indexer/database"
-	"flare-indexer/services/context"
+	servicesctx "flare-indexer/services/context"
 	"flare-indexer/services/utils"
 	"net/http"
 
@@ -15,19 +16,28 @@ type GetTransferRequest struct {
 }
 
 type transferRouteHandlers struct {
-	db *gorm.DB
+	db  *gorm.DB
+	hrp string
 }
 
-func newTransferRouteHandlers(ctx context.ServicesContext) *transferRouteHandlers {
+func newTransferRouteHandlers(ctx servicesctx.ServicesContext) *transferRouteHandlers {
 	return &transferRouteHandlers{
-		db: ctx.DB(),
+		db:  ctx.DB(),
+		hrp: ctx.Config().Chain.ChainAddressHRP,
 	}
 }
 
 func (rh *transferRouteHandlers) listTransferTransactions(txType database.PChainTxType) utils.RouteHandler {
-	handler := func(request GetTransferRequest) (TxIDsResponse, *utils.ErrorHandler) {
-		txIDs, err := database.FetchPChainTransferTransactions(rh.db, txType,
-			request.Address, request.Offset, request.Limit)
+	handler := func(ctx context.Context, request GetTransferRequest) (TxIDsResponse, *utils.ErrorHandler) {
+		address := request.Address
+		if len(address) > 0 {
+			var errHandler *utils.ErrorHandler
+			if address, errHandler = parseRequestAddress(rh.hrp, address); errHandler != nil {
+				return TxIDsResponse{}, errHandler
+			}
+		}
+		txIDs, err := database.FetchPChainTransferTransactions(ctx, rh.db, txType,
+			address, request.Offset, request.Limit)
 		if err != nil {
 			return TxIDsResponse{}, utils.InternalServerErrorHandler(err)
 		}
@@ -36,7 +46,7 @@ func (rh *transferRouteHandlers) listTransferTransactions(txType database.PChain
 	return utils.NewRouteHandler(handler, http.MethodPost, GetTransferRequest{}, TxIDsResponse{})
 }
 
-func AddTransferRoutes(router utils.Router, ctx context.ServicesContext) {
+func AddTransferRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
 	vr := newTransferRouteHandlers(ctx)
 
 	importSubrouter := router.WithPrefix("/imports", "Transfers")
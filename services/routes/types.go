@@ -1,8 +1,15 @@
 package routes
 
+import "flare-indexer/services/utils"
+
+// The page size is at most the max limit of the query limits (100 by default)
 type PaginatedRequest struct {
 	Offset int `json:"offset" validate:"gte=0"`
-	Limit  int `json:"limit" validate:"gte=0,lte=100"`
+	Limit  int `json:"limit" validate:"gte=0"`
+}
+
+func (r PaginatedRequest) CheckLimits(limits utils.QueryLimits) *utils.ErrorHandler {
+	return limits.CheckPage(r.Offset, r.Limit)
 }
 
 type TxIDsResponse struct {
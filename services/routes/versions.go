@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Version of the indexed P-chain data: the state of the P-chain indexer and the
+// current reward epoch, since responses may depend on the epoch (e.g. the
+// mirroring data of the stakes of the epoch). The data was last modified when
+// the indexer state was updated, blocks are indexed well after their timestamp.
+func indexedDataVersion(ctx servicesctx.ServicesContext) func(ctx context.Context) (utils.DataVersion, error) {
+	db := ctx.DB()
+	epochs := ctx.Epochs()
+	return func(ctx context.Context) (utils.DataVersion, error) {
+		var version utils.DataVersion
+		state, err := database.FetchState(ctx, db, database.PChainIndexerStateName)
+		switch {
+		case err == nil:
+			version.Tag = fmt.Sprintf("index=%d", state.NextDBIndex)
+			version.Modified = state.Updated
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return utils.DataVersion{}, err
+		}
+		if epochs.Period > 0 {
+			epoch := epochs.GetEpochIndex(time.Now())
+			version.Tag += fmt.Sprintf(";epoch=%d", epoch)
+			version.Modified = latest(version.Modified, epochs.GetStartTime(epoch))
+		}
+		return version, nil
+	}
+}
+
+// Version of the indexed data and the validator labels, for responses which
+// include labels
+func labeledDataVersion(ctx servicesctx.ServicesContext) func(ctx context.Context) (utils.DataVersion, error) {
+	db := ctx.DB()
+	indexed := indexedDataVersion(ctx)
+	return func(ctx context.Context) (utils.DataVersion, error) {
+		version, err := indexed(ctx)
+		if err != nil {
+			return utils.DataVersion{}, err
+		}
+		count, updated, err := database.FetchValidatorLabelsVersion(ctx, db)
+		if err != nil {
+			return utils.DataVersion{}, err
+		}
+		version.Tag += fmt.Sprintf(";labels=%d-%d", count, updated.UnixNano())
+		version.Modified = latest(version.Modified, updated)
+		return version, nil
+	}
+}
+
+func latest(t1, t2 time.Time) time.Time {
+	if t1.After(t2) {
+		return t1
+	}
+	return t2
+}
@@ -0,0 +1,167 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staking"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WatchlistEntryResponse struct {
+	ID      uint64                 `json:"id"`
+	Kind    database.WatchlistKind `json:"kind"`
+	Value   string                 `json:"value"`
+	Label   string                 `json:"label"`
+	Created time.Time              `json:"created"`
+	// Reward epoch containing the creation time
+	CreatedEpoch *int64 `json:"createdEpoch,omitempty"`
+}
+
+type AddWatchlistEntryRequest struct {
+	Kind  database.WatchlistKind `json:"kind" validate:"oneof=ADDRESS NODE_ID"`
+	Value string                 `json:"value" validate:"required,max=60"`
+	Label string                 `json:"label" validate:"max=100"`
+}
+
+type RemoveWatchlistEntryRequest struct {
+	ID uint64 `json:"id" validate:"required"`
+}
+
+type GetWatchlistMatchesRequest struct {
+	PaginatedRequest
+	// Address or node ID, all matches if empty
+	Value string `json:"value"`
+}
+
+type WatchlistMatchResponse struct {
+	EntryID   uint64                 `json:"entryId"`
+	Kind      database.WatchlistKind `json:"kind"`
+	Value     string                 `json:"value"`
+	TxID      string                 `json:"txId"`
+	TxType    database.PChainTxType  `json:"txType"`
+	Timestamp time.Time              `json:"timestamp"`
+	// Reward epoch containing the timestamp
+	Epoch *int64 `json:"epoch,omitempty"`
+}
+
+type watchlistRouteHandlers struct {
+	db     *gorm.DB
+	epochs staking.EpochInfo
+	hrp    string
+}
+
+func newWatchlistRouteHandlers(ctx servicesctx.ServicesContext) *watchlistRouteHandlers {
+	return &watchlistRouteHandlers{
+		db:     ctx.DB(),
+		epochs: ctx.Epochs(),
+		hrp:    ctx.Config().Chain.ChainAddressHRP,
+	}
+}
+
+func (rh *watchlistRouteHandlers) listEntries() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) ([]WatchlistEntryResponse, *utils.ErrorHandler) {
+		entries, err := database.FetchWatchlist(ctx, rh.db)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]WatchlistEntryResponse, len(entries))
+		for i, e := range entries {
+			response[i] = newWatchlistEntryResponse(&e, rh.epochs)
+		}
+		return response, nil
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet, map[string]string{}, []WatchlistEntryResponse{})
+}
+
+func (rh *watchlistRouteHandlers) addEntry() utils.RouteHandler {
+	handler := func(ctx context.Context, request AddWatchlistEntryRequest) (WatchlistEntryResponse, *utils.ErrorHandler) {
+		entry := database.WatchlistEntry{
+			Kind:    request.Kind,
+			Value:   request.Value,
+			Label:   request.Label,
+			Created: time.Now(),
+		}
+		if entry.Kind == database.WatchlistAddress {
+			var errHandler *utils.ErrorHandler
+			if entry.Value, errHandler = parseRequestAddress(rh.hrp, entry.Value); errHandler != nil {
+				return WatchlistEntryResponse{}, errHandler
+			}
+		}
+		if err := database.CreateWatchlistEntry(ctx, rh.db, &entry); err != nil {
+			return WatchlistEntryResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return newWatchlistEntryResponse(&entry, rh.epochs), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, AddWatchlistEntryRequest{}, WatchlistEntryResponse{})
+}
+
+func (rh *watchlistRouteHandlers) removeEntry() utils.RouteHandler {
+	handler := func(ctx context.Context, request RemoveWatchlistEntryRequest) (bool, *utils.ErrorHandler) {
+		err := database.DeleteWatchlistEntry(ctx, rh.db, request.ID)
+		if err != nil {
+			return false, utils.ErrorResponseHandler(err)
+		}
+		return true, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, RemoveWatchlistEntryRequest{}, true)
+}
+
+func (rh *watchlistRouteHandlers) listMatches() utils.RouteHandler {
+	handler := func(ctx context.Context, request GetWatchlistMatchesRequest) ([]WatchlistMatchResponse, *utils.ErrorHandler) {
+		// Values other than addresses (node IDs) are matched as given
+		value := request.Value
+		if address, err := chain.NormalizeAddress(rh.hrp, value); err == nil {
+			value = address
+		}
+		matches, err := database.FetchWatchlistMatches(ctx, rh.db, value, request.Offset, request.Limit)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]WatchlistMatchResponse, len(matches))
+		for i, m := range matches {
+			response[i] = WatchlistMatchResponse{
+				EntryID:   m.EntryID,
+				Kind:      m.Kind,
+				Value:     m.Value,
+				TxID:      m.TxID,
+				TxType:    m.TxType,
+				Timestamp: m.Timestamp.UTC(),
+				Epoch:     rh.epochs.EpochOf(&m.Timestamp),
+			}
+		}
+		return response, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, GetWatchlistMatchesRequest{}, []WatchlistMatchResponse{})
+}
+
+func newWatchlistEntryResponse(e *database.WatchlistEntry, epochs staking.EpochInfo) WatchlistEntryResponse {
+	return WatchlistEntryResponse{
+		ID:           e.ID,
+		Kind:         e.Kind,
+		Value:        e.Value,
+		Label:        e.Label,
+		Created:      e.Created.UTC(),
+		CreatedEpoch: epochs.EpochOf(&e.Created),
+	}
+}
+
+func AddWatchlistRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newWatchlistRouteHandlers(ctx)
+	adminToken := ctx.Config().Services.AdminToken
+
+	subrouter := router.WithPrefix("/watchlist", "Watchlist")
+	subrouter.AddRoute("/list", utils.AdminRouteHandler(rh.listEntries(), adminToken),
+		"Watched addresses and node IDs", "Requires the admin token")
+	subrouter.AddRoute("/add", utils.AdminRouteHandler(rh.addEntry(), adminToken),
+		"Watch an address or node ID", "Requires the admin token")
+	subrouter.AddRoute("/remove", utils.AdminRouteHandler(rh.removeEntry(), adminToken),
+		"Stop watching an address or node ID", "Requires the admin token")
+	subrouter.AddRoute("/matches", utils.AdminRouteHandler(rh.listMatches(), adminToken),
+		"Transactions of watched addresses and node IDs", "Requires the admin token")
+}
@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"context"
+	"flare-indexer/database"
+	servicesctx "flare-indexer/services/context"
+	"flare-indexer/services/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Events of the webhooks, see the webhooks of the indexer
+const (
+	webhookAddressActivityEvent = "address_activity"
+	webhookDelegationEvent      = "delegation"
+)
+
+// Subscription without its secret
+type WebhookSubscriptionResponse struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Event     string    `json:"event"`
+	Addresses []string  `json:"addresses,omitempty"`
+	NodeIDs   []string  `json:"nodeIds,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+type SetWebhookSubscriptionRequest struct {
+	Name   string `json:"name" validate:"required,max=100"`
+	URL    string `json:"url" validate:"required,url,max=500"`
+	Secret string `json:"secret" validate:"required,max=200"`
+	Event  string `json:"event" validate:"oneof=address_activity delegation"`
+	// Addresses of address_activity webhooks
+	Addresses []string `json:"addresses" validate:"max=100,dive,required"`
+	// Node IDs of delegation webhooks
+	NodeIDs []string `json:"nodeIds" validate:"max=100,dive,node-id"`
+}
+
+type RemoveWebhookSubscriptionRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type webhookRouteHandlers struct {
+	db  *gorm.DB
+	hrp string
+}
+
+func newWebhookRouteHandlers(ctx servicesctx.ServicesContext) *webhookRouteHandlers {
+	return &webhookRouteHandlers{
+		db:  ctx.DB(),
+		hrp: ctx.Config().Chain.ChainAddressHRP,
+	}
+}
+
+func (rh *webhookRouteHandlers) listSubscriptions() utils.RouteHandler {
+	handler := func(ctx context.Context, params map[string]string) ([]WebhookSubscriptionResponse, *utils.ErrorHandler) {
+		subscriptions, err := database.FetchWebhookSubscriptions(ctx, rh.db)
+		if err != nil {
+			return nil, utils.InternalServerErrorHandler(err)
+		}
+		response := make([]WebhookSubscriptionResponse, len(subscriptions))
+		for i := range subscriptions {
+			response[i] = newWebhookSubscriptionResponse(&subscriptions[i])
+		}
+		return response, nil
+	}
+	return utils.NewParamRouteHandler(handler, http.MethodGet, map[string]string{}, []WebhookSubscriptionResponse{})
+}
+
+func (rh *webhookRouteHandlers) setSubscription() utils.RouteHandler {
+	handler := func(ctx context.Context, request SetWebhookSubscriptionRequest) (WebhookSubscriptionResponse, *utils.ErrorHandler) {
+		subscription := database.WebhookSubscription{
+			Name:    request.Name,
+			URL:     request.URL,
+			Secret:  request.Secret,
+			Event:   request.Event,
+			Updated: time.Now(),
+		}
+		switch request.Event {
+		case webhookAddressActivityEvent:
+			if len(request.Addresses) == 0 || len(request.NodeIDs) > 0 {
+				return WebhookSubscriptionResponse{}, utils.HttpErrorHandler(http.StatusBadRequest,
+					"address_activity webhooks need addresses and no node IDs")
+			}
+			addresses := make([]string, len(request.Addresses))
+			for i, a := range request.Addresses {
+				var errHandler *utils.ErrorHandler
+				if addresses[i], errHandler = parseRequestAddress(rh.hrp, a); errHandler != nil {
+					return WebhookSubscriptionResponse{}, errHandler
+				}
+			}
+			subscription.Addresses = strings.Join(addresses, ",")
+		case webhookDelegationEvent:
+			if len(request.NodeIDs) == 0 || len(request.Addresses) > 0 {
+				return WebhookSubscriptionResponse{}, utils.HttpErrorHandler(http.StatusBadRequest,
+					"delegation webhooks need node IDs and no addresses")
+			}
+			subscription.NodeIDs = strings.Join(request.NodeIDs, ",")
+		}
+		if err := database.UpsertWebhookSubscription(ctx, rh.db, &subscription); err != nil {
+			return WebhookSubscriptionResponse{}, utils.InternalServerErrorHandler(err)
+		}
+		return newWebhookSubscriptionResponse(&subscription), nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, SetWebhookSubscriptionRequest{}, WebhookSubscriptionResponse{})
+}
+
+func (rh *webhookRouteHandlers) removeSubscription() utils.RouteHandler {
+	handler := func(ctx context.Context, request RemoveWebhookSubscriptionRequest) (bool, *utils.ErrorHandler) {
+		err := database.DeleteWebhookSubscription(ctx, rh.db, request.Name)
+		if err != nil {
+			return false, utils.ErrorResponseHandler(err)
+		}
+		return true, nil
+	}
+	return utils.NewRouteHandler(handler, http.MethodPost, RemoveWebhookSubscriptionRequest{}, true)
+}
+
+func newWebhookSubscriptionResponse(s *database.WebhookSubscription) WebhookSubscriptionResponse {
+	response := WebhookSubscriptionResponse{
+		Name:    s.Name,
+		URL:     s.URL,
+		Event:   s.Event,
+		Updated: s.Updated.UTC(),
+	}
+	if len(s.Addresses) > 0 {
+		response.Addresses = strings.Split(s.Addresses, ",")
+	}
+	if len(s.NodeIDs) > 0 {
+		response.NodeIDs = strings.Split(s.NodeIDs, ",")
+	}
+	return response
+}
+
+func AddWebhookRoutes(router utils.Router, ctx servicesctx.ServicesContext) {
+	rh := newWebhookRouteHandlers(ctx)
+	adminToken := ctx.Config().Services.AdminToken
+
+	subrouter := router.WithPrefix("/webhooks", "Webhooks")
+	subrouter.AddRoute("/list", utils.AdminRouteHandler(rh.listSubscriptions(), adminToken),
+		"Webhooks registered with the admin routes, without their secrets", "Requires the admin token")
+	subrouter.AddRoute("/set", utils.AdminRouteHandler(rh.setSubscription(), adminToken),
+		"Register a webhook or replace the webhook of the same name", "Requires the admin token")
+	subrouter.AddRoute("/remove", utils.AdminRouteHandler(rh.removeSubscription(), adminToken),
+		"Remove a registered webhook, its pending deliveries fail", "Requires the admin token")
+}
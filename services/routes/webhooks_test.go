@@ -0,0 +1,45 @@
+//go:build !integration
+// +build !integration
+
+package routes
+
+import (
+	"flare-indexer/services/api"
+	"flare-indexer/services/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWebhookSubscriptionValidation(t *testing.T) {
+	rh := &webhookRouteHandlers{hrp: "flare"}
+	for _, body := range []string{
+		`{"name": "", "url": "https://example.com", "secret": "s", "event": "delegation", "nodeIds": ["NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"]}`,
+		`{"name": "hook", "url": "example", "secret": "s", "event": "delegation", "nodeIds": ["NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"]}`,
+		`{"name": "hook", "url": "https://example.com", "secret": "s", "event": "transfer"}`,
+		`{"name": "hook", "url": "https://example.com", "secret": "s", "event": "delegation", "nodeIds": ["CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"]}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/set", strings.NewReader(body))
+		rh.setSubscription().Handler(w, r)
+
+		var response api.ApiResponseWrapper[WebhookSubscriptionResponse]
+		utils.DecodeStruct(t, w.Result().Body, &response)
+		require.Equal(t, api.ApiResStatusRequestBodyError, response.Status, body)
+	}
+
+	// Events without their addresses or node IDs
+	for _, body := range []string{
+		`{"name": "hook", "url": "https://example.com", "secret": "s", "event": "delegation"}`,
+		`{"name": "hook", "url": "https://example.com", "secret": "s", "event": "address_activity", "nodeIds": ["NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6"]}`,
+		`{"name": "hook", "url": "https://example.com", "secret": "s", "event": "address_activity", "addresses": ["invalid"]}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/set", strings.NewReader(body))
+		rh.setSubscription().Handler(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
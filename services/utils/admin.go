@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"flare-indexer/logger"
+	"net/http"
+	"strings"
+
+	swagger "github.com/davidebianchi/gswagger"
+)
+
+// Wraps handler so that it requires the header "Authorization: Bearer <token>".
+// If token is empty, the handler is disabled.
+func AdminRouteHandler(handler RouteHandler, token string) RouteHandler {
+	next := handler.Handler
+	handler.Handler = func(w http.ResponseWriter, r *http.Request) {
+		if len(token) == 0 {
+			http.Error(w, "admin routes are disabled", http.StatusForbidden)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			logger.WarnCtx(r.Context(), "Unauthorized admin request from %s to %s", r.RemoteAddr, r.URL.Path)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+	handler.SwaggerDefinitions.Headers = map[string]swagger.Parameter{
+		"Authorization": {
+			Schema:      &swagger.Schema{Value: ""},
+			Description: "Bearer admin token",
+		},
+	}
+	return handler
+}
@@ -0,0 +1,33 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRouteHandler(t *testing.T) {
+	handler := RouteHandler{
+		Handler: func(w http.ResponseWriter, r *http.Request) {},
+		Method:  http.MethodPost,
+	}
+	call := func(token string, auth string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/test", nil)
+		if len(auth) > 0 {
+			r.Header.Set("Authorization", auth)
+		}
+		AdminRouteHandler(handler, token).Handler(w, r)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, call("secret", "Bearer secret"))
+	require.Equal(t, http.StatusUnauthorized, call("secret", "Bearer other"))
+	require.Equal(t, http.StatusUnauthorized, call("secret", ""))
+	require.Equal(t, http.StatusForbidden, call("", "Bearer "))
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flare-indexer/services/cache"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Wraps handler so that its successful responses are stored in c until
+// expiration(now) and served from c for identical requests (same method, URL
+// and body) with the same data version, if the handler is wrapped with
+// ConditionalRouteHandler. If c is nil, handler is returned unchanged.
+func CachedRouteHandler(handler RouteHandler, c cache.Cache, expiration func(now time.Time) time.Time) RouteHandler {
+	if c == nil {
+		return handler
+	}
+
+	next := handler.Handler
+	handler.Handler = func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if HandleInternalServerError(w, err) {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := versionedCacheKey(r, body)
+		if value, ok := c.Get(key); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Cache", "HIT")
+			w.Write(value)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		w.Header().Set("X-Cache", "MISS")
+		next(recorder, r)
+		if recorder.status == http.StatusOK {
+			c.Set(key, recorder.body.Bytes(), expiration(time.Now()))
+		}
+	}
+	return handler
+}
+
+func cacheKey(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.RawQuery))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Responses computed from other data versions must not be served, they would not
+// match the ETag of the version
+func versionedCacheKey(r *http.Request, body []byte) string {
+	tag := dataVersionTag(r.Context())
+	if tag == "" {
+		return cacheKey(r, body)
+	}
+	hash := sha256.New()
+	hash.Write([]byte(cacheKey(r, body)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(tag))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Writes the response to the wrapped writer and keeps a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,82 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"context"
+	"flare-indexer/services/api"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCache map[string][]byte
+
+func (c testCache) Get(key string) ([]byte, bool) {
+	value, ok := c[key]
+	return value, ok
+}
+
+func (c testCache) Set(key string, value []byte, expiration time.Time) {
+	c[key] = value
+}
+
+type testCacheRequest struct {
+	Value string `json:"value"`
+}
+
+func TestCachedRouteHandler(t *testing.T) {
+	calls := 0
+	handler := NewRouteHandler(func(ctx context.Context, request testCacheRequest) (string, *ErrorHandler) {
+		calls++
+		if request.Value == "error" {
+			return "", HttpErrorHandler(http.StatusBadRequest, "error")
+		}
+		return request.Value, nil
+	}, http.MethodPost, testCacheRequest{}, "")
+
+	c := testCache{}
+	cached := CachedRouteHandler(handler, c, func(now time.Time) time.Time { return now.Add(time.Minute) })
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		cached.Handler(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body)))
+		return w
+	}
+
+	w := call(`{"value": "a"}`)
+	require.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	w = call(`{"value": "a"}`)
+	require.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	require.Equal(t, 1, calls)
+
+	var response api.ApiResponseWrapper[string]
+	DecodeStruct(t, w.Result().Body, &response)
+	require.Equal(t, "a", response.Data)
+
+	call(`{"value": "b"}`)
+	require.Equal(t, 2, calls)
+
+	// Errors are not cached
+	call(`{"value": "error"}`)
+	w = call(`{"value": "error"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, 4, calls)
+	require.Len(t, c, 2)
+}
+
+func TestCachedRouteHandlerDisabled(t *testing.T) {
+	handler := NewRouteHandler(func(ctx context.Context, request testCacheRequest) (string, *ErrorHandler) {
+		return request.Value, nil
+	}, http.MethodPost, testCacheRequest{}, "")
+
+	cached := CachedRouteHandler(handler, nil, nil)
+	w := httptest.NewRecorder()
+	cached.Handler(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{}`)))
+	require.Empty(t, w.Header().Get("X-Cache"))
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request headers allowed in cross-origin requests in addition to the configured ones
+var corsDefaultHeaders = []string{"Content-Type", "Authorization", "If-None-Match", "If-Modified-Since", APIKeyHeader, RequestIDHeader}
+
+// Response headers readable by cross-origin clients
+var corsExposedHeaders = []string{"ETag", "Last-Modified", "X-Cache", RequestIDHeader}
+
+// Wraps handler so that cross-origin requests from the allowed origins ("*" for
+// all origins) are allowed. Preflight requests of allowed origins are answered
+// directly, preflight requests of other origins get a response without CORS
+// headers, which makes the browser block the request. If no origin is allowed,
+// handler is returned unchanged.
+func CORSHandler(handler http.Handler, allowedOrigins []string, allowedHeaders []string, maxAge time.Duration) http.Handler {
+	if len(allowedOrigins) == 0 {
+		return handler
+	}
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[strings.TrimSuffix(strings.TrimSpace(origin), "/")] = true
+	}
+	allowAll := origins["*"]
+	headers := strings.Join(append(append([]string{}, corsDefaultHeaders...), allowedHeaders...), ", ")
+	exposed := strings.Join(corsExposedHeaders, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if origin == "" || !(allowAll || origins[origin]) {
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", "GET, POST")
+			header.Set("Access-Control-Allow-Headers", headers)
+			if maxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		header.Set("Access-Control-Expose-Headers", exposed)
+		handler.ServeHTTP(w, r)
+	})
+}
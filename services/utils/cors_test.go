@@ -0,0 +1,58 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORSHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	call := func(handler http.Handler, method string, origin string, preflight bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/validators/list", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if preflight {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	handler := CORSHandler(ok, []string{"https://explorer.example.com/"}, []string{"X-Api-Key"}, 10*time.Minute)
+
+	w := call(handler, http.MethodOptions, "https://explorer.example.com", true)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "https://explorer.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-Api-Key")
+	require.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = call(handler, http.MethodPost, "https://explorer.example.com", false)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "https://explorer.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "ETag")
+
+	// Other origins
+	w = call(handler, http.MethodOptions, "https://other.example.com", true)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	w = call(handler, http.MethodPost, "https://other.example.com", false)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = call(CORSHandler(ok, []string{"*"}, nil, 0), http.MethodPost, "https://other.example.com", false)
+	require.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = call(CORSHandler(ok, nil, nil, 0), http.MethodPost, "https://explorer.example.com", false)
+	require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
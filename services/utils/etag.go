@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Version of the data a response is computed from, e.g. the height of the last
+// indexed block
+type DataVersion struct {
+	// Changes whenever responses computed from the data may change
+	Tag string
+	// Time of the last change of the data, zero if unknown
+	Modified time.Time
+}
+
+// Wraps handler with HTTP conditional request support. The ETag of a response is
+// derived from the request (method, URL and body) and the current data version, so
+// it changes when new data is indexed. The version is passed on to the handler in
+// the request context, so that a wrapped CachedRouteHandler only serves responses
+// computed from the same version. Requests with a matching If-None-Match
+// header, or without If-None-Match and with an If-Modified-Since header not before
+// the last modification, are answered with 304 Not Modified. Clients polling POST
+// routes can use the headers in the same way as with GET routes. If version fails,
+// the response is served without caching headers.
+func ConditionalRouteHandler(handler RouteHandler, version func(ctx context.Context) (DataVersion, error)) RouteHandler {
+	next := handler.Handler
+	handler.Handler = func(w http.ResponseWriter, r *http.Request) {
+		v, err := version(r.Context())
+		if err != nil {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if HandleInternalServerError(w, err) {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		etag := responseETag(r, body, v.Tag)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if !v.Modified.IsZero() {
+			w.Header().Set("Last-Modified", v.Modified.UTC().Format(http.TimeFormat))
+		}
+		if notModified(r, etag, v.Modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), dataVersionKey{}, v.Tag)))
+	}
+	return handler
+}
+
+type dataVersionKey struct{}
+
+// Returns the tag of the data version set by ConditionalRouteHandler, empty if
+// the request is not conditional
+func dataVersionTag(ctx context.Context) string {
+	tag, _ := ctx.Value(dataVersionKey{}).(string)
+	return tag
+}
+
+func responseETag(r *http.Request, body []byte, versionTag string) string {
+	hash := sha256.New()
+	hash.Write([]byte(cacheKey(r, body)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(versionTag))
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// Header times have a resolution of one second
+	return !modified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,109 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalRouteHandler(t *testing.T) {
+	calls := 0
+	handler := NewRouteHandler(func(ctx context.Context, request testCacheRequest) (string, *ErrorHandler) {
+		calls++
+		return request.Value, nil
+	}, http.MethodPost, testCacheRequest{}, "")
+
+	modified := time.Date(2023, 10, 1, 12, 0, 0, 500, time.UTC)
+	version := DataVersion{Tag: "height=1", Modified: modified}
+	var versionErr error
+	conditional := ConditionalRouteHandler(handler, func(ctx context.Context) (DataVersion, error) {
+		return version, versionErr
+	})
+
+	call := func(body string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body))
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		conditional.Handler(w, r)
+		return w
+	}
+
+	w := call(`{"value": "a"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "Sun, 01 Oct 2023 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	require.Equal(t, 1, calls)
+
+	// Same request and data
+	w = call(`{"value": "a"}`, map[string]string{"If-None-Match": `"x", W/` + etag})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+	w = call(`{"value": "a"}`, map[string]string{"If-Modified-Since": "Sun, 01 Oct 2023 12:00:00 GMT"})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Equal(t, 1, calls)
+
+	// Other request
+	w = call(`{"value": "b"}`, map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// New data
+	version = DataVersion{Tag: "height=2", Modified: modified.Add(time.Minute)}
+	w = call(`{"value": "a"}`, map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = call(`{"value": "a"}`, map[string]string{"If-Modified-Since": "Sun, 01 Oct 2023 12:00:00 GMT"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, 4, calls)
+
+	// Served without caching headers if the version is not available
+	versionErr = errors.New("db error")
+	w = call(`{"value": "a"}`, map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("ETag"))
+	require.Equal(t, 5, calls)
+}
+
+func TestConditionalCachedRouteHandler(t *testing.T) {
+	calls := 0
+	height := 1
+	handler := NewRouteHandler(func(ctx context.Context, request testCacheRequest) (int, *ErrorHandler) {
+		calls++
+		return height, nil
+	}, http.MethodPost, testCacheRequest{}, 0)
+
+	tag := "height=1"
+	conditional := ConditionalRouteHandler(
+		CachedRouteHandler(handler, testCache{}, func(now time.Time) time.Time { return now.Add(time.Minute) }),
+		func(ctx context.Context) (DataVersion, error) { return DataVersion{Tag: tag}, nil },
+	)
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		conditional.Handler(w, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{}`)))
+		return w
+	}
+
+	w := call()
+	require.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	w = call()
+	require.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	require.Equal(t, 1, calls)
+
+	// The entry computed at the old height is not served with the ETag of the new one
+	height, tag = 2, "height=2"
+	w = call()
+	require.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	require.Contains(t, w.Body.String(), `"data":2`)
+	require.Equal(t, 2, calls)
+}
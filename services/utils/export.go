@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flare-indexer/logger"
+	"fmt"
+	"net/http"
+
+	swagger "github.com/davidebianchi/gswagger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+
+	// Number of records written between two flushes of the response
+	exportFlushInterval = 100
+)
+
+// Record of an export. JSON Lines contain the json encoding of the record, CSV
+// rows the fields returned by CSVRecord.
+type ExportRecord interface {
+	CSVRecord() []string
+}
+
+// Embedded in the requests of export routes
+type ExportRequest struct {
+	// Defaults to jsonl
+	Format string `json:"format" validate:"omitempty,oneof=csv jsonl"`
+}
+
+func (r ExportRequest) ExportFormat() string {
+	if r.Format == "" {
+		return ExportFormatJSONL
+	}
+	return r.Format
+}
+
+// Writes records to the response as they are produced. The response is
+// flushed every exportFlushInterval records, so the client receives the data
+// in chunks, and a slow client blocks the writes, and thereby the producer of
+// the records, instead of the records piling up in memory.
+type ExportWriter struct {
+	w         http.ResponseWriter
+	format    string
+	name      string
+	csvHeader []string
+	csv       *csv.Writer
+	started   bool
+	count     int
+}
+
+// Writer of the export name (used as the file name) in the given format. The
+// CSV output starts with the csvHeader row.
+func NewExportWriter(w http.ResponseWriter, format string, name string, csvHeader []string) *ExportWriter {
+	ew := &ExportWriter{
+		w:         w,
+		format:    format,
+		name:      name,
+		csvHeader: csvHeader,
+	}
+	if format == ExportFormatCSV {
+		ew.csv = csv.NewWriter(w)
+	}
+	return ew
+}
+
+// Returns true if the response has been started, i.e., the status and the
+// headers have been sent
+func (ew *ExportWriter) Started() bool {
+	return ew.started
+}
+
+func (ew *ExportWriter) Write(record ExportRecord) error {
+	if err := ew.start(); err != nil {
+		return err
+	}
+	if ew.csv != nil {
+		if err := ew.csv.Write(record.CSVRecord()); err != nil {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err := ew.w.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	ew.count++
+	if ew.count%exportFlushInterval == 0 {
+		return ew.flush()
+	}
+	return nil
+}
+
+// Writes the remaining records. An export without records consists of the
+// CSV header only.
+func (ew *ExportWriter) Close() error {
+	if err := ew.start(); err != nil {
+		return err
+	}
+	return ew.flush()
+}
+
+func (ew *ExportWriter) start() error {
+	if ew.started {
+		return nil
+	}
+	ew.started = true
+
+	contentType := "application/x-ndjson"
+	if ew.csv != nil {
+		contentType = "text/csv"
+	}
+	ew.w.Header().Set("Content-Type", contentType)
+	ew.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", ew.name, ew.format))
+	ew.w.WriteHeader(http.StatusOK)
+	if ew.csv != nil {
+		return ew.csv.Write(ew.csvHeader)
+	}
+	return nil
+}
+
+func (ew *ExportWriter) flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := ew.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Route handler factory for exports
+// Request passed to export is the request body parsed to a struct of type R,
+// export writes the records to the given writer. The context passed to export
+// is the context of the http request, it is canceled when the client
+// disconnects. If export fails before the
+// first record is written, the client gets an internal server error,
+// otherwise the error is logged and the response is aborted, so that the
+// client does not take a truncated export for a complete one.
+func NewExportRouteHandler[R interface{ ExportFormat() string }](
+	export func(ctx context.Context, request R, w *ExportWriter) error,
+	method string,
+	requestObject R,
+	name string,
+	csvHeader []string,
+	recordObject ExportRecord,
+) RouteHandler {
+	routeHandler := func(w http.ResponseWriter, r *http.Request) {
+		var request R
+		if !DecodeBody(w, r, &request) {
+			return
+		}
+		ew := NewExportWriter(w, request.ExportFormat(), name, csvHeader)
+		runExport(w, r, ew, name, func() error {
+			return export(r.Context(), request, ew)
+		})
+	}
+	swaggerDefinitions := swagger.Definitions{
+		RequestBody: &swagger.ContentValue{
+			Content: swagger.Content{
+				"application/json": {Value: requestObject},
+			},
+		},
+		Responses: exportResponses(recordObject),
+	}
+	return RouteHandler{
+		Handler:            routeHandler,
+		SwaggerDefinitions: swaggerDefinitions,
+		Method:             method,
+	}
+}
+
+// Route handler factory for exports of GET routes
+// The values passed to export are the path parameters and the query parameters
+// of the request as in NewParamQueryRouteHandler, the format is given by the
+// query parameter format (jsonl if not given). Errors are handled as in
+// NewExportRouteHandler, errors with a code (e.g. an invalid parameter) are
+// answered with their code.
+func NewParamExportRouteHandler(
+	export func(ctx context.Context, params map[string]string, w *ExportWriter) error,
+	method string,
+	pathParamDescriptions map[string]string,
+	name string,
+	csvHeader []string,
+	recordObject ExportRecord,
+) RouteHandler {
+	routeHandler := func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]string)
+		for name, values := range r.URL.Query() {
+			if len(values) > 0 {
+				params[name] = values[0]
+			}
+		}
+		for name, value := range mux.Vars(r) {
+			params[name] = value
+		}
+		format := ExportRequest{Format: params["format"]}.ExportFormat()
+		if format != ExportFormatJSONL && format != ExportFormatCSV {
+			HttpErrorHandler(http.StatusBadRequest, "invalid format, expected csv or jsonl").Handler(w)
+			return
+		}
+		ew := NewExportWriter(w, format, name, csvHeader)
+		runExport(w, r, ew, name, func() error {
+			return export(r.Context(), params, ew)
+		})
+	}
+	pathParams := make(map[string]swagger.Parameter)
+	for name, description := range pathParamDescriptions {
+		pathParams[name] = swagger.Parameter{
+			Schema:      &swagger.Schema{Value: ""},
+			Description: description,
+		}
+	}
+	swaggerDefinitions := swagger.Definitions{
+		PathParams: pathParams,
+		Querystring: map[string]swagger.Parameter{
+			"format": {Schema: &swagger.Schema{Value: ""}, Description: "csv or jsonl, jsonl if not given"},
+		},
+		Responses: exportResponses(recordObject),
+	}
+	return RouteHandler{
+		Handler:            routeHandler,
+		SwaggerDefinitions: swaggerDefinitions,
+		Method:             method,
+	}
+}
+
+func runExport(w http.ResponseWriter, r *http.Request, ew *ExportWriter, name string, export func() error) {
+	err := export()
+	if err == nil {
+		err = ew.Close()
+	}
+	if err == nil {
+		return
+	}
+	if !ew.Started() {
+		InternalServerErrorHandler(err).Handler(w)
+		return
+	}
+	logger.ErrorCtx(r.Context(), "Export %s aborted: %v", name, err)
+	panic(http.ErrAbortHandler)
+}
+
+func exportResponses(recordObject ExportRecord) map[int]swagger.ContentValue {
+	return map[int]swagger.ContentValue{
+		200: {
+			Content: swagger.Content{
+				"application/x-ndjson": {Value: recordObject},
+				"text/csv":             {Value: ""},
+			},
+		},
+	}
+}
@@ -0,0 +1,103 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testExportRecord struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+func (r testExportRecord) CSVRecord() []string {
+	return []string{r.Name, strconv.Itoa(r.Value)}
+}
+
+type testExportRequest struct {
+	ExportRequest
+	Count int `json:"count"`
+	Fail  int `json:"fail"`
+}
+
+func testExportHandler() RouteHandler {
+	export := func(ctx context.Context, request testExportRequest, w *ExportWriter) error {
+		for i := 0; i < request.Count; i++ {
+			if i == request.Fail {
+				return errors.New("export failed")
+			}
+			if err := w.Write(testExportRecord{Name: "a,b", Value: i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return NewExportRouteHandler(export, http.MethodPost, testExportRequest{},
+		"test", []string{"name", "value"}, testExportRecord{})
+}
+
+func TestExportRouteHandler(t *testing.T) {
+	handler := testExportHandler()
+
+	w := httptest.NewRecorder()
+	handler.Handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"count": 2, "fail": -1}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="test.jsonl"`, w.Header().Get("Content-Disposition"))
+	require.Equal(t, "{\"name\":\"a,b\",\"value\":0}\n{\"name\":\"a,b\",\"value\":1}\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.Handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"format": "csv", "count": 2, "fail": -1}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	require.Equal(t, "name,value\n\"a,b\",0\n\"a,b\",1\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.Handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"format": "csv", "count": 0}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "name,value\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.Handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"format": "xml"}`)))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), "error validating request body")
+}
+
+func TestExportRouteHandlerError(t *testing.T) {
+	handler := testExportHandler()
+
+	// Nothing has been written, the client gets an error status
+	w := httptest.NewRecorder()
+	handler.Handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"count": 2, "fail": 0}`)))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// The response has been started, it is aborted
+	w = httptest.NewRecorder()
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.Handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"count": 2, "fail": 1}`)))
+	})
+}
+
+func TestExportWriterFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	ew := NewExportWriter(w, ExportFormatCSV, "test", []string{"name", "value"})
+	for i := 0; i < exportFlushInterval-1; i++ {
+		require.NoError(t, ew.Write(testExportRecord{Name: "a", Value: i}))
+	}
+	require.False(t, w.Flushed)
+	require.Empty(t, w.Body.String())
+
+	require.NoError(t, ew.Write(testExportRecord{Name: "a", Value: exportFlushInterval}))
+	require.True(t, w.Flushed)
+	require.Equal(t, exportFlushInterval+1, strings.Count(w.Body.String(), "\n"))
+}
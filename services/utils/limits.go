@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"context"
+	"crypto/subtle"
+	"flare-indexer/services/api"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Header with the key selecting the query limits of a client
+const APIKeyHeader = "X-API-Key"
+
+// Guard rails of the cost of a single request, zero values disable a limit
+type QueryLimits struct {
+	// Maximal page size of paginated routes
+	MaxLimit int
+	// Maximal offset of paginated routes
+	MaxOffset int
+	// Maximal time range of routes aggregating over a range (e.g. burned fees)
+	MaxTimeRange time.Duration
+}
+
+// Limits of requests served without QueryLimitsMiddleware, e.g. in tests
+var DefaultQueryLimits = QueryLimits{
+	MaxLimit:     100,
+	MaxTimeRange: 90 * 24 * time.Hour,
+}
+
+// Request whose cost is bounded by the query limits. Route handlers created
+// with NewRouteHandler check the limits before calling the handler.
+type LimitedRequest interface {
+	CheckLimits(limits QueryLimits) *ErrorHandler
+}
+
+type queryLimitsKey struct{}
+
+// Middleware setting the query limits of a request, the limits of the override
+// with the key in the X-API-Key header or the default limits
+func QueryLimitsMiddleware(defaults QueryLimits, overrides map[string]QueryLimits) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limits := defaults
+			if key := r.Header.Get(APIKeyHeader); key != "" {
+				for overrideKey, override := range overrides {
+					if subtle.ConstantTimeCompare([]byte(key), []byte(overrideKey)) == 1 {
+						limits = override
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), queryLimitsKey{}, limits)))
+		})
+	}
+}
+
+// Query limits of the request with context ctx
+func QueryLimitsFromContext(ctx context.Context) QueryLimits {
+	if limits, ok := ctx.Value(queryLimitsKey{}).(QueryLimits); ok {
+		return limits
+	}
+	return DefaultQueryLimits
+}
+
+// Checks the offset and the page size of a paginated request
+func (l QueryLimits) CheckPage(offset int, limit int) *ErrorHandler {
+	if l.MaxLimit > 0 && limit > l.MaxLimit {
+		return queryLimitExceeded(fmt.Sprintf("limit %d exceeds the maximum of %d", limit, l.MaxLimit))
+	}
+	if l.MaxOffset > 0 && offset > l.MaxOffset {
+		return queryLimitExceeded(fmt.Sprintf("offset %d exceeds the maximum of %d", offset, l.MaxOffset))
+	}
+	return nil
+}
+
+// Checks the time range [from, to) of a request
+func (l QueryLimits) CheckTimeRange(from time.Time, to time.Time) *ErrorHandler {
+	if l.MaxTimeRange > 0 && to.Sub(from) > l.MaxTimeRange {
+		return queryLimitExceeded(fmt.Sprintf("time range %s exceeds the maximum of %s", to.Sub(from), l.MaxTimeRange))
+	}
+	return nil
+}
+
+func queryLimitExceeded(details string) *ErrorHandler {
+	return ApiResponseErrorHandler(api.ApiResStatusQueryLimitExceeded, "query limit exceeded", details)
+}
@@ -0,0 +1,76 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"flare-indexer/services/api"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+type testPageRequest struct {
+	Limit int `json:"limit"`
+}
+
+func (r testPageRequest) CheckLimits(limits QueryLimits) *ErrorHandler {
+	return limits.CheckPage(0, r.Limit)
+}
+
+func TestQueryLimitsMiddleware(t *testing.T) {
+	handler := func(ctx context.Context, request testPageRequest) (int, *ErrorHandler) {
+		return request.Limit, nil
+	}
+	router := mux.NewRouter()
+	router.Use(QueryLimitsMiddleware(
+		QueryLimits{MaxLimit: 100},
+		map[string]QueryLimits{"secret": {MaxLimit: 10000}},
+	))
+	router.HandleFunc("/test", NewRouteHandler(handler, http.MethodPost, testPageRequest{}, 0).Handler)
+
+	request := func(limit string, key string) api.ApiResponseWrapper[int] {
+		r := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(`{"limit": `+limit+`}`))
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		var response api.ApiResponseWrapper[int]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	require.Equal(t, api.ApiResStatusOk, request("100", "").Status)
+	response := request("5000", "")
+	require.Equal(t, api.ApiResStatusQueryLimitExceeded, response.Status)
+	require.Equal(t, "limit 5000 exceeds the maximum of 100", response.ErrorDetails)
+	require.Equal(t, api.ApiResStatusQueryLimitExceeded, request("5000", "unknown").Status)
+
+	response = request("5000", "secret")
+	require.Equal(t, api.ApiResStatusOk, response.Status)
+	require.Equal(t, 5000, response.Data)
+}
+
+func TestQueryLimitsCheck(t *testing.T) {
+	from := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	limits := QueryLimits{MaxLimit: 100, MaxOffset: 1000, MaxTimeRange: 90 * 24 * time.Hour}
+
+	require.Nil(t, limits.CheckPage(1000, 100))
+	require.NotNil(t, limits.CheckPage(1001, 100))
+	require.NotNil(t, limits.CheckPage(0, 101))
+	require.Nil(t, limits.CheckTimeRange(from, from.Add(90*24*time.Hour)))
+	require.NotNil(t, limits.CheckTimeRange(from, from.Add(91*24*time.Hour)))
+
+	// Zero values disable the limits
+	require.Nil(t, QueryLimits{}.CheckPage(1<<30, 1<<30))
+	require.Nil(t, QueryLimits{}.CheckTimeRange(from, from.Add(10000*24*time.Hour)))
+	require.Equal(t, DefaultQueryLimits, QueryLimitsFromContext(context.Background()))
+}
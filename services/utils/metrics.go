@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/logger"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics of the API requests, labeled by the route (the path template, e.g.
+// "/transactions/get/{tx_id:[0-9a-zA-Z]+}") and the method
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "services_requests_total",
+		Help: "Number of API requests by route, method and response status",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "services_request_duration_seconds",
+		Help:    "Duration of API requests by route and method",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// Serves the metrics at /metrics on the given address, if it is not empty
+func InitMetricsServer(address string) {
+	if len(address) == 0 {
+		return
+	}
+
+	r := mux.NewRouter()
+	r.Path("/metrics").Handler(promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:    address,
+		Handler: r,
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		log.Fatal(err)
+	}()
+}
+
+// Middleware of the API router recording the request metrics. Requests taking
+// at least slowThreshold (if positive) are logged with the SQL statements they
+// executed, the statements are collected only if the query log plugin is
+// registered on the database (see database.UseQueryLog).
+func MetricsMiddleware(slowThreshold time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+			var queryLog *database.QueryLog
+			if slowThreshold > 0 {
+				var ctx context.Context
+				ctx, queryLog = database.WithQueryLog(r.Context())
+				r = r.WithContext(ctx)
+			}
+
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+			duration := time.Since(start)
+
+			requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+			requestDuration.WithLabelValues(route, r.Method).Observe(duration.Seconds())
+			if queryLog != nil && duration >= slowThreshold {
+				logger.WarnCtx(r.Context(), "Slow request %s %s (route %s, status %d) took %v%s",
+					r.Method, r.URL.RequestURI(), route, recorder.status, duration, formatQueryLog(queryLog))
+			}
+		})
+	}
+}
+
+func formatQueryLog(queryLog *database.QueryLog) string {
+	queries, dropped := queryLog.Queries()
+	var b strings.Builder
+	fmt.Fprintf(&b, ", %d SQL statements", len(queries)+dropped)
+	for _, q := range queries {
+		fmt.Fprintf(&b, "\n  [%v, %d rows] %s", q.Duration, q.Rows, q.SQL)
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&b, "\n  ... %d more statements", dropped)
+	}
+	return b.String()
+}
+
+// Records the status of the response, keeps streamed responses streamed
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware(time.Nanosecond))
+	router.HandleFunc("/test/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}).Methods(http.MethodGet)
+
+	route := "/test/{id:[0-9]+}"
+	before := testutil.ToFloat64(requestsTotal.WithLabelValues(route, http.MethodGet, "404"))
+	for _, path := range []string{"/test/1", "/test/2"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	}
+	require.Equal(t, before+2, testutil.ToFloat64(requestsTotal.WithLabelValues(route, http.MethodGet, "404")))
+}
+
+func TestStatusRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	recorder.Write([]byte("data"))
+	recorder.WriteHeader(http.StatusInternalServerError)
+	require.Equal(t, http.StatusOK, recorder.status)
+
+	recorder.Flush()
+	require.True(t, w.Flushed)
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// Content type of protobuf responses, a google.protobuf.Value message
+	// (google/protobuf/struct.proto) with the same content as the json response,
+	// integers are encoded as strings
+	ProtobufContentType = "application/x-protobuf; messageType=google.protobuf.Value"
+
+	encodingGzip = "gzip"
+	// HTTP deflate is the zlib format (RFC 1950), not a raw deflate stream
+	encodingDeflate = "deflate"
+)
+
+// Wraps handler with content negotiation. JSON responses are converted to
+// protobuf if the Accept header prefers application/x-protobuf (or
+// application/protobuf) over application/json, and responses are compressed
+// with gzip or deflate if the Accept-Encoding header allows it. Other responses,
+// e.g. error messages and exports, are only compressed. Streamed responses stay
+// streamed, flushing the response also flushes the compressor.
+func NegotiationHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nw := &negotiatingWriter{
+			ResponseWriter: w,
+			protobuf:       prefersProtobuf(r.Header.Get("Accept")),
+			encoding:       acceptedEncoding(r.Header.Get("Accept-Encoding")),
+		}
+		w.Header().Add("Vary", "Accept, Accept-Encoding")
+		handler.ServeHTTP(nw, r)
+		// Not reached if the handler aborts the response with a panic, so that
+		// a truncated response is not completed
+		nw.finish()
+	})
+}
+
+// Response writer converting and compressing the response body. The
+// conversions are selected when the handler writes the header, depending on
+// the status and the content type of the response.
+type negotiatingWriter struct {
+	http.ResponseWriter
+	protobuf bool
+	encoding string
+
+	wroteHeader bool
+	status      int
+	// Body of a JSON response converted to protobuf, nil otherwise
+	buffer *bytes.Buffer
+	// Compressor, nil if the response is not compressed
+	compressor io.WriteCloser
+}
+
+func (w *negotiatingWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+
+	header := w.Header()
+	hasBody := status != http.StatusNotModified && status != http.StatusNoContent
+	if hasBody && w.protobuf && status == http.StatusOK && isJSON(header.Get("Content-Type")) {
+		w.buffer = &bytes.Buffer{}
+		header.Set("Content-Type", ProtobufContentType)
+		weakenETag(header)
+		// The header is written with the converted body
+		return
+	}
+	if hasBody && w.encoding != "" && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		weakenETag(header)
+		w.compressor = newCompressor(w.ResponseWriter, w.encoding)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *negotiatingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffer != nil {
+		return w.buffer.Write(b)
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flushes the compressor and the response, buffered responses are written by finish
+func (w *negotiatingWriter) Flush() {
+	if w.buffer != nil {
+		return
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Writes the converted body and completes the compressed stream
+func (w *negotiatingWriter) finish() {
+	if w.buffer != nil {
+		body, err := jsonToProtobuf(w.buffer.Bytes())
+		if err != nil {
+			w.Header().Del("ETag")
+			http.Error(w.ResponseWriter, "error converting response to protobuf", http.StatusInternalServerError)
+			return
+		}
+		w.buffer = nil
+		if w.encoding != "" {
+			w.Header().Set("Content-Encoding", w.encoding)
+			w.compressor = newCompressor(w.ResponseWriter, w.encoding)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		w.Write(body)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+	}
+}
+
+func jsonToProtobuf(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var content interface{}
+	if err := decoder.Decode(&content); err != nil {
+		return nil, err
+	}
+	value, err := protobufValue(content)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(value)
+}
+
+// Converts decoded json to a protobuf value. Doubles cannot represent integers
+// above 2^53 exactly (e.g. amounts in nanoFLR), so integers are encoded as strings
+// with their decimal digits, as in the JSON mapping of 64-bit protobuf integers.
+func protobufValue(content interface{}) (*structpb.Value, error) {
+	switch v := content.(type) {
+	case json.Number:
+		if isInteger(string(v)) {
+			return structpb.NewStringValue(string(v)), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewNumberValue(f), nil
+	case []interface{}:
+		values := make([]*structpb.Value, len(v))
+		for i, item := range v {
+			value, err := protobufValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return structpb.NewListValue(&structpb.ListValue{Values: values}), nil
+	case map[string]interface{}:
+		fields := make(map[string]*structpb.Value, len(v))
+		for key, item := range v {
+			value, err := protobufValue(item)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = value
+		}
+		return structpb.NewStructValue(&structpb.Struct{Fields: fields}), nil
+	case string, bool, nil:
+		return structpb.NewValue(v)
+	default:
+		return nil, fmt.Errorf("unexpected json value of type %T", v)
+	}
+}
+
+func isInteger(number string) bool {
+	return !strings.ContainsAny(number, ".eE")
+}
+
+func newCompressor(w io.Writer, encoding string) io.WriteCloser {
+	if encoding == encodingGzip {
+		return gzip.NewWriter(w)
+	}
+	return zlib.NewWriter(w)
+}
+
+// Representations with different content encodings have the same ETag, which
+// is allowed for weak ETags only
+func weakenETag(header http.Header) {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+}
+
+func isJSON(contentType string) bool {
+	return strings.HasPrefix(strings.TrimSpace(contentType), "application/json")
+}
+
+// Parses a header with comma separated values with optional quality factors
+// (e.g. "gzip;q=0.5, deflate") into the quality of each value
+func parseQualities(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(fields[0]))
+		if value == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[value] = quality
+	}
+	return qualities
+}
+
+// True if protobuf has a higher quality than json, json is the default
+func prefersProtobuf(accept string) bool {
+	qualities := parseQualities(accept)
+	protobuf := qualities["application/x-protobuf"]
+	if q := qualities["application/protobuf"]; q > protobuf {
+		protobuf = q
+	}
+	if protobuf == 0 {
+		return false
+	}
+	json, ok := qualities["application/json"]
+	if !ok {
+		json = qualities["*/*"]
+	}
+	return protobuf > json
+}
+
+// The accepted encoding with the highest quality, gzip if both are equal, or
+// an empty string if none is accepted
+func acceptedEncoding(acceptEncoding string) string {
+	qualities := parseQualities(acceptEncoding)
+	quality := func(encoding string) float64 {
+		if q, ok := qualities[encoding]; ok {
+			return q
+		}
+		return qualities["*"]
+	}
+	gzipQuality, deflateQuality := quality(encodingGzip), quality(encodingDeflate)
+	if gzipQuality > 0 && gzipQuality >= deflateQuality {
+		return encodingGzip
+	}
+	if deflateQuality > 0 {
+		return encodingDeflate
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestNegotiationHandler(t *testing.T) {
+	jsonHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		WriteApiResponseOk(w, []string{"a", "b"})
+	})
+	call := func(handler http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/test", nil)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		NegotiationHandler(handler).ServeHTTP(w, r)
+		return w
+	}
+
+	// Plain json
+	w := call(jsonHandler, nil)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	plain := w.Body.String()
+	require.Contains(t, plain, `"data":["a","b"]`)
+
+	// Compressed json
+	w = call(jsonHandler, map[string]string{"Accept-Encoding": "deflate, gzip"})
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	require.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Equal(t, plain, string(body))
+
+	w = call(jsonHandler, map[string]string{"Accept-Encoding": "gzip;q=0, deflate"})
+	require.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	zr, err := zlib.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, plain, string(body))
+
+	// Protobuf
+	w = call(jsonHandler, map[string]string{"Accept": "application/x-protobuf, application/json;q=0.5"})
+	require.Equal(t, ProtobufContentType, w.Header().Get("Content-Type"))
+	var value structpb.Value
+	require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &value))
+	require.Equal(t, []interface{}{"a", "b"}, value.GetStructValue().AsMap()["data"])
+
+	// Protobuf, compressed
+	w = call(jsonHandler, map[string]string{"Accept": "application/protobuf", "Accept-Encoding": "gzip"})
+	require.Equal(t, ProtobufContentType, w.Header().Get("Content-Type"))
+	gz, err = gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err = io.ReadAll(gz)
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(body, &value))
+
+	// Errors are not converted, responses without body are not compressed
+	w = call(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}), map[string]string{"Accept": "application/x-protobuf"})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "bad request\n", w.Body.String())
+	w = call(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}), map[string]string{"Accept-Encoding": "gzip"})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestNegotiationHandlerStreaming(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{\"a\":1}\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("{\"a\":2}\n"))
+	})
+	r := httptest.NewRequest(http.MethodPost, "/export", nil)
+	r.Header.Set("Accept", "application/x-protobuf")
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	NegotiationHandler(handler).ServeHTTP(w, r)
+
+	require.True(t, w.Flushed)
+	require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	gz, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Equal(t, "{\"a\":1}\n{\"a\":2}\n", string(body))
+}
+
+func TestAcceptedEncoding(t *testing.T) {
+	require.Equal(t, "", acceptedEncoding(""))
+	require.Equal(t, "", acceptedEncoding("br"))
+	require.Equal(t, "gzip", acceptedEncoding("gzip, deflate, br"))
+	require.Equal(t, "deflate", acceptedEncoding("gzip;q=0.5, deflate"))
+	require.Equal(t, "gzip", acceptedEncoding("*"))
+	require.Equal(t, "deflate", acceptedEncoding("*, gzip;q=0"))
+}
+
+func TestJSONToProtobuf(t *testing.T) {
+	body, err := jsonToProtobuf([]byte(`{"amount":18446744073709551615,"height":7,"fee":0.5,"tags":["a",true,null]}`))
+	require.NoError(t, err)
+	var value structpb.Value
+	require.NoError(t, proto.Unmarshal(body, &value))
+	require.Equal(t, map[string]interface{}{
+		"amount": "18446744073709551615",
+		"height": "7",
+		"fee":    0.5,
+		"tags":   []interface{}{"a", true, nil},
+	}, value.GetStructValue().AsMap())
+
+	_, err = jsonToProtobuf([]byte(`{`))
+	require.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Wraps handler so that the remote address of a request forwarded by a trusted
+// proxy (an address or a CIDR range in trustedProxies) is the client address
+// from the X-Forwarded-For header. The header is read from the right, skipping
+// the trusted proxies, since clients can send the header with arbitrary
+// addresses. Requests from other addresses are not changed. If there are no
+// trusted proxies, handler is returned unchanged.
+func ProxyHandler(handler http.Handler, trustedProxies []string) (http.Handler, error) {
+	if len(trustedProxies) == 0 {
+		return handler, nil
+	}
+	networks, err := parseNetworks(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	trusted := func(address string) bool {
+		ip := net.ParseIP(strings.TrimSpace(address))
+		if ip == nil {
+			return false
+		}
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err == nil && trusted(host) {
+			if client := forwardedClient(r.Header.Values("X-Forwarded-For"), trusted); client != "" {
+				r.RemoteAddr = net.JoinHostPort(client, "0")
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}), nil
+}
+
+// The rightmost untrusted address of the X-Forwarded-For headers, or the
+// leftmost address if all are trusted
+func forwardedClient(headers []string, trusted func(address string) bool) string {
+	var addresses []string
+	for _, header := range headers {
+		for _, address := range strings.Split(header, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	for i := len(addresses) - 1; i >= 0; i-- {
+		if !trusted(addresses[i]) {
+			if net.ParseIP(addresses[i]) == nil {
+				return ""
+			}
+			return addresses[i]
+		}
+	}
+	if len(addresses) > 0 {
+		return addresses[0]
+	}
+	return ""
+}
+
+func parseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", value)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range %q: %w", value, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,46 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler(t *testing.T) {
+	var remoteAddr string
+	handler, err := ProxyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	}), []string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	call := func(remote string, forwardedFor ...string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remote
+		for _, header := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return remoteAddr
+	}
+
+	require.Equal(t, "203.0.113.7:0", call("10.1.2.3:5000", "203.0.113.7"))
+	// Addresses set by the client are ignored
+	require.Equal(t, "203.0.113.7:0", call("10.1.2.3:5000", "1.1.1.1, 203.0.113.7", "192.168.1.1"))
+	// All proxies are trusted
+	require.Equal(t, "10.0.0.1:0", call("10.1.2.3:5000", "10.0.0.1, 10.0.0.2"))
+	// Untrusted peer
+	require.Equal(t, "198.51.100.1:5000", call("198.51.100.1:5000", "203.0.113.7"))
+	// Invalid or missing header
+	require.Equal(t, "10.1.2.3:5000", call("10.1.2.3:5000", "unknown"))
+	require.Equal(t, "10.1.2.3:5000", call("10.1.2.3:5000"))
+
+	_, err = ProxyHandler(http.NotFoundHandler(), []string{"10.0.0.0/33"})
+	require.Error(t, err)
+	_, err = ProxyHandler(http.NotFoundHandler(), []string{"proxy"})
+	require.Error(t, err)
+}
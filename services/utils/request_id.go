@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flare-indexer/logger"
+	"net/http"
+	"regexp"
+)
+
+// Header with the ID of a request, accepted from the client (e.g. the explorer
+// backend) or generated, and returned in the response
+const RequestIDHeader = "X-Request-ID"
+
+// Accepted request IDs, the ID is written to logs and SQL comments
+var requestIDPattern = regexp.MustCompile(`^[0-9A-Za-z._-]{1,64}$`)
+
+// Wraps handler so that each request has an ID: the valid X-Request-ID header
+// of the request or a new random ID. The ID is set as the X-Request-ID header of
+// the response before handler is called and attached to the context of the
+// request, so that the log messages and the SQL statements (see
+// database.UseRequestIDTags) of the request include it.
+func RequestIDHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		handler.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Context with the ID of the request of the response, for logging in error
+// handlers, which get the response only
+func responseContext(w http.ResponseWriter) context.Context {
+	return logger.WithRequestID(context.Background(), w.Header().Get(RequestIDHeader))
+}
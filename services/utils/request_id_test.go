@@ -0,0 +1,49 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"flare-indexer/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	var contextID string
+	handler := RequestIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = logger.RequestID(r.Context())
+	}))
+	call := func(id string) string {
+		r := httptest.NewRequest(http.MethodGet, "/validators/list", nil)
+		if len(id) > 0 {
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, contextID, w.Header().Get(RequestIDHeader))
+		return contextID
+	}
+
+	require.Equal(t, "explorer-7f3a.1", call("explorer-7f3a.1"))
+
+	generated := call("")
+	require.Len(t, generated, 32)
+	require.NotEqual(t, generated, call(""))
+
+	// IDs which could escape the SQL comment or the log line are replaced
+	for _, id := range []string{"a */ DROP TABLE states; /*", "a\nb", string(make([]byte, 65))} {
+		replaced := call(id)
+		require.NotEqual(t, id, replaced)
+		require.Len(t, replaced, 32)
+	}
+}
+
+func TestResponseContext(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIDHeader, "abc")
+	require.Equal(t, "abc", logger.RequestID(responseContext(w)))
+}
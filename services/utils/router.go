@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"context"
 	"flare-indexer/logger"
 	"flare-indexer/services/api"
+	"flare-indexer/utils/errcode"
 	"log"
 	"net/http"
+	"strings"
 
 	swagger "github.com/davidebianchi/gswagger"
 	"github.com/davidebianchi/gswagger/support/gorilla"
@@ -55,25 +58,44 @@ func NewDefaultRouter(mRouter *mux.Router) Router {
 
 // Router implementation with swagger support
 type swaggerRouter struct {
-	mRouter *mux.Router
-	router  *swagger.Router[gorilla.HandlerFunc, *mux.Route]
-	tag     string
+	mRouter  *mux.Router
+	router   *swagger.Router[gorilla.HandlerFunc, *mux.Route]
+	tag      string
+	basePath string
 }
 
-func NewSwaggerRouter(mRouter *mux.Router, title string, version string) Router {
-	router, _ := swagger.NewRouter(gorilla.NewRouter(mRouter), swagger.Options{
-		Openapi: &openapi3.T{
-			Info: &openapi3.Info{
-				Title:   title,
-				Version: version,
-			},
+// Router with the routes (including the swagger UI and the openapi definitions)
+// under basePath, e.g. "/pchain", or at the root if basePath is empty
+func NewSwaggerRouter(mRouter *mux.Router, title string, version string, basePath string) Router {
+	basePath = NormalizeBasePath(basePath)
+	openapi := &openapi3.T{
+		Info: &openapi3.Info{
+			Title:   title,
+			Version: version,
 		},
+	}
+	if basePath != "" {
+		mRouter = mRouter.PathPrefix(basePath).Subrouter()
+		openapi.Servers = openapi3.Servers{{URL: basePath}}
+	}
+	router, _ := swagger.NewRouter(gorilla.NewRouter(mRouter), swagger.Options{
+		Openapi: openapi,
 	})
 	return &swaggerRouter{
-		mRouter: mRouter,
-		router:  router,
-		tag:     "",
+		mRouter:  mRouter,
+		router:   router,
+		tag:      "",
+		basePath: basePath,
+	}
+}
+
+// Base path with a leading and without a trailing slash, empty for the root
+func NormalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
 	}
+	return "/" + basePath
 }
 
 // Add a route to the router and generate openapi definitions from the handler
@@ -101,9 +123,10 @@ func (r *swaggerRouter) WithPrefix(prefix string, tag string) Router {
 		PathPrefix: prefix,
 	})
 	return &swaggerRouter{
-		mRouter: mSubRouter,
-		router:  subRouter,
-		tag:     tag,
+		mRouter:  mSubRouter,
+		router:   subRouter,
+		tag:      tag,
+		basePath: r.basePath,
 	}
 }
 
@@ -112,15 +135,18 @@ func (r *swaggerRouter) Finalize() {
 		log.Fatal(err)
 	}
 
-	handler := v3.NewHandler("Flare P-chain indexer API", "/documentation/json", "/swagger")
+	handler := v3.NewHandler("Flare P-chain indexer API",
+		r.basePath+swagger.DefaultJSONDocumentationPath, r.basePath+"/swagger")
 	r.mRouter.PathPrefix("/swagger").HandlerFunc(handler.ServeHTTP)
 }
 
 // Route handler factory
 // Request passed to handler is the request body parsed to a struct of type R. The response of handler is wrapped to
-// an ApiResponseWrapper object and returned as json
+// an ApiResponseWrapper object and returned as json. The context passed to handler is the context of the http request,
+// it is canceled when the client disconnects. Requests implementing LimitedRequest exceeding the query limits are
+// rejected.
 // Openapi definitions are generated from the request and response objects
-func NewRouteHandler[R interface{}, T interface{}](handler func(request R) (T, *ErrorHandler), method string, requestObject R, respObject T) RouteHandler {
+func NewRouteHandler[R interface{}, T interface{}](handler func(ctx context.Context, request R) (T, *ErrorHandler), method string, requestObject R, respObject T) RouteHandler {
 	wrappedRespObject := api.ApiResponseWrapper[T]{Data: respObject}
 	return wrappedRouteHandler(handler, func(w http.ResponseWriter, resp T) {
 		WriteApiResponseOk(w, resp)
@@ -129,16 +155,16 @@ func NewRouteHandler[R interface{}, T interface{}](handler func(request R) (T, *
 
 // Route handler factory
 // Request passed to handler is the request body parsed to a struct of type R.
-// The response of handler is an object of type T
+// The response of handler is an object of type T, the context is the one of the http request
 // Openapi definitions are generated from the request and response objects
-func NewClassicRouteHandler[R interface{}, T interface{}](handler func(request R) (T, *ErrorHandler), method string, requestObject R, respObject T) RouteHandler {
+func NewClassicRouteHandler[R interface{}, T interface{}](handler func(ctx context.Context, request R) (T, *ErrorHandler), method string, requestObject R, respObject T) RouteHandler {
 	return wrappedRouteHandler(handler, func(w http.ResponseWriter, resp T) {
 		WriteResponse(w, resp)
 	}, method, requestObject, respObject)
 }
 
 func wrappedRouteHandler[R interface{}, T interface{}, S interface{}](
-	handler func(R) (T, *ErrorHandler),
+	handler func(context.Context, R) (T, *ErrorHandler),
 	responseWriter func(http.ResponseWriter, T),
 	method string,
 	requestObject R,
@@ -149,7 +175,13 @@ func wrappedRouteHandler[R interface{}, T interface{}, S interface{}](
 		if !DecodeBody(w, r, &request) {
 			return
 		}
-		resp, err := handler(request)
+		if limited, ok := any(request).(LimitedRequest); ok {
+			if err := limited.CheckLimits(QueryLimitsFromContext(r.Context())); err != nil {
+				err.Handler(w)
+				return
+			}
+		}
+		resp, err := handler(r.Context(), request)
 		if err != nil {
 			err.Handler(w)
 			return
@@ -180,16 +212,17 @@ func wrappedRouteHandler[R interface{}, T interface{}, S interface{}](
 // Route handler factory
 // The value passed to handler are the path parameters parsed to a map of string. The response of handler is wrapped to
 // an ApiResponseWrapper object and returned as json. Openapi definitionas for the path parameters are generated from the
-// paramDescriptions map, definitions for the response object are generated from the response object.
+// paramDescriptions map, definitions for the response object are generated from the response object. The context passed
+// to handler is the context of the http request.
 func NewParamRouteHandler[T interface{}](
-	handler func(params map[string]string) (T, *ErrorHandler),
+	handler func(ctx context.Context, params map[string]string) (T, *ErrorHandler),
 	method string,
 	paramDescriptions map[string]string,
 	respObject T,
 ) RouteHandler {
 	routeHandler := func(w http.ResponseWriter, r *http.Request) {
 		params := mux.Vars(r)
-		resp, err := handler(params)
+		resp, err := handler(r.Context(), params)
 		if err != nil {
 			err.Handler(w)
 			return
@@ -221,19 +254,151 @@ func NewParamRouteHandler[T interface{}](
 	}
 }
 
+// Route handler factory
+// The values passed to handler are the query parameters of the request (the first value of each parameter). The
+// response of handler is wrapped to an ApiResponseWrapper object and returned as json. Openapi definitions for the
+// query parameters are generated from the paramDescriptions map, definitions for the response object are generated
+// from the response object. The context passed to handler is the context of the http request.
+func NewQueryRouteHandler[T interface{}](
+	handler func(ctx context.Context, params map[string]string) (T, *ErrorHandler),
+	method string,
+	paramDescriptions map[string]string,
+	respObject T,
+) RouteHandler {
+	routeHandler := func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]string)
+		for name, values := range r.URL.Query() {
+			if len(values) > 0 {
+				params[name] = values[0]
+			}
+		}
+		resp, err := handler(r.Context(), params)
+		if err != nil {
+			err.Handler(w)
+			return
+		}
+		WriteApiResponseOk(w, resp)
+	}
+	queryParams := make(map[string]swagger.Parameter)
+	for name, description := range paramDescriptions {
+		queryParams[name] = swagger.Parameter{
+			Schema:      &swagger.Schema{Value: ""},
+			Description: description,
+		}
+	}
+	wrappedRespObject := api.ApiResponseWrapper[T]{Data: respObject}
+	swaggerDefinitions := swagger.Definitions{
+		Querystring: queryParams,
+		Responses: map[int]swagger.ContentValue{
+			200: {
+				Content: swagger.Content{
+					"application/json": {Value: wrappedRespObject},
+				},
+			},
+		},
+	}
+	return RouteHandler{
+		Handler:            routeHandler,
+		SwaggerDefinitions: swaggerDefinitions,
+		Method:             method,
+	}
+}
+
+// Route handler factory
+// The values passed to handler are the path parameters and the query parameters of the request (the first value of
+// each parameter), path parameters take precedence. Openapi definitions for the parameters are generated from the
+// pathParamDescriptions and queryParamDescriptions maps, the response is handled as in NewQueryRouteHandler.
+func NewParamQueryRouteHandler[T interface{}](
+	handler func(ctx context.Context, params map[string]string) (T, *ErrorHandler),
+	method string,
+	pathParamDescriptions map[string]string,
+	queryParamDescriptions map[string]string,
+	respObject T,
+) RouteHandler {
+	routeHandler := func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]string)
+		for name, values := range r.URL.Query() {
+			if len(values) > 0 {
+				params[name] = values[0]
+			}
+		}
+		for name, value := range mux.Vars(r) {
+			params[name] = value
+		}
+		resp, err := handler(r.Context(), params)
+		if err != nil {
+			err.Handler(w)
+			return
+		}
+		WriteApiResponseOk(w, resp)
+	}
+	pathParams := make(map[string]swagger.Parameter)
+	for name, description := range pathParamDescriptions {
+		pathParams[name] = swagger.Parameter{
+			Schema:      &swagger.Schema{Value: ""},
+			Description: description,
+		}
+	}
+	queryParams := make(map[string]swagger.Parameter)
+	for name, description := range queryParamDescriptions {
+		queryParams[name] = swagger.Parameter{
+			Schema:      &swagger.Schema{Value: ""},
+			Description: description,
+		}
+	}
+	wrappedRespObject := api.ApiResponseWrapper[T]{Data: respObject}
+	swaggerDefinitions := swagger.Definitions{
+		PathParams:  pathParams,
+		Querystring: queryParams,
+		Responses: map[int]swagger.ContentValue{
+			200: {
+				Content: swagger.Content{
+					"application/json": {Value: wrappedRespObject},
+				},
+			},
+		},
+	}
+	return RouteHandler{
+		Handler:            routeHandler,
+		SwaggerDefinitions: swaggerDefinitions,
+		Method:             method,
+	}
+}
+
+// Error response of err. Errors with a code other than errcode.Internal (e.g.
+// not found errors of the database helpers) are answered with their code and
+// message, other errors are logged and answered with a generic message.
 func InternalServerErrorHandler(err error) *ErrorHandler {
+	if code := errcode.Of(err); code != errcode.Internal {
+		return ErrorResponseHandler(err)
+	}
+	return &ErrorHandler{
+		Handler: func(w http.ResponseWriter) {
+			logger.ErrorCtx(responseContext(w), "Internal error: %v", err)
+			WriteErrorResponse(w, errcode.Internal, "Internal server error")
+		},
+	}
+}
+
+// Error response with the code and the message of err, see errcode.Of
+func ErrorResponseHandler(err error) *ErrorHandler {
+	code := errcode.Of(err)
+	if code == errcode.Internal {
+		return InternalServerErrorHandler(err)
+	}
 	return &ErrorHandler{
 		Handler: func(w http.ResponseWriter) {
-			logger.Error("Internal error: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			WriteErrorResponse(w, code, errcode.MessageOf(err))
 		},
 	}
 }
 
+// Error response with the HTTP status and the error code of the status, e.g.
+// errcode.Validation for http.StatusBadRequest
 func HttpErrorHandler(code int, err string) *ErrorHandler {
 	return &ErrorHandler{
 		Handler: func(w http.ResponseWriter) {
-			http.Error(w, err, code)
+			writeErrorResponse(w, code, httpStatusErrorCode(code), err)
 		},
 	}
 }
@@ -0,0 +1,77 @@
+//go:build !integration
+// +build !integration
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flare-indexer/services/api"
+	"flare-indexer/utils/errcode"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamQueryRouteHandler(t *testing.T) {
+	handler := NewParamQueryRouteHandler(func(ctx context.Context, params map[string]string) (map[string]string, *ErrorHandler) {
+		return params, nil
+	}, http.MethodGet, map[string]string{"id": "ID"}, map[string]string{"limit": "Limit"}, map[string]string{})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/items/{id}", handler.Handler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items/a?limit=5&id=b", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	// Path parameters take precedence over query parameters
+	require.Equal(t, map[string]string{"id": "a", "limit": "5"}, response.Data)
+}
+
+func TestErrorHandlers(t *testing.T) {
+	call := func(errHandler *ErrorHandler) (*httptest.ResponseRecorder, api.ApiResponseWrapper[any]) {
+		w := httptest.NewRecorder()
+		errHandler.Handler(w)
+		var response api.ApiResponseWrapper[any]
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return w, response
+	}
+
+	w, response := call(HttpErrorHandler(http.StatusBadRequest, "invalid epoch"))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Equal(t, errcode.Validation, response.ErrorCode)
+	require.Equal(t, api.ApiResStatusValidationError, response.Status)
+	require.Equal(t, "invalid epoch", response.ErrorMessage)
+
+	notFound := errcode.Wrap(errors.New("record not found"), errcode.NotFound, "block 5 not found")
+	w, response = call(ErrorResponseHandler(notFound))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, errcode.NotFound, response.ErrorCode)
+	require.Equal(t, "block 5 not found", response.ErrorMessage)
+
+	// Errors with a code are not internal errors
+	w, response = call(InternalServerErrorHandler(fmt.Errorf("fetch: %w", errcode.New(errcode.ChainLag, "block 9 is not indexed yet"))))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, errcode.ChainLag, response.ErrorCode)
+
+	// Messages of other errors are not revealed
+	w, response = call(ErrorResponseHandler(errors.New("dial tcp 10.0.0.1:3306: connection refused")))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, errcode.Internal, response.ErrorCode)
+	require.Equal(t, "Internal server error", response.ErrorMessage)
+
+	// Error responses with the status OK have a code as well
+	w, response = call(ApiResponseErrorHandler(api.ApiResStatusQueryLimitExceeded, "query limit exceeded", ""))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, errcode.Validation, response.ErrorCode)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"flare-indexer/services/api"
+	"flare-indexer/utils/errcode"
 	"fmt"
 	"net/http"
 
@@ -62,12 +63,72 @@ func WriteApiResponseError(
 ) {
 	response := api.ApiResponseWrapper[any]{
 		Status:       status,
+		ErrorCode:    apiStatusErrorCode(status),
 		ErrorDetails: errorDetails,
 		ErrorMessage: errorMessage,
 	}
 	WriteResponse(w, response)
 }
 
+// Writes an error response with the HTTP status of the error code
+func WriteErrorResponse(w http.ResponseWriter, code errcode.Code, errorMessage string) {
+	writeErrorResponse(w, errorCodeHttpStatus(code), code, errorMessage)
+}
+
+func writeErrorResponse(w http.ResponseWriter, httpStatus int, code errcode.Code, errorMessage string) {
+	status := api.ApiResStatusError
+	if code == errcode.Validation {
+		status = api.ApiResStatusValidationError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpStatus)
+	WriteResponse(w, api.ApiResponseWrapper[any]{
+		Status:       status,
+		ErrorCode:    code,
+		ErrorMessage: errorMessage,
+	})
+}
+
+func errorCodeHttpStatus(code errcode.Code) int {
+	switch code {
+	case errcode.NotFound:
+		return http.StatusNotFound
+	case errcode.ChainLag:
+		return http.StatusServiceUnavailable
+	case errcode.Validation:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func httpStatusErrorCode(status int) errcode.Code {
+	switch {
+	case status == http.StatusNotFound:
+		return errcode.NotFound
+	case status == http.StatusServiceUnavailable:
+		return errcode.ChainLag
+	case status >= 400 && status < 500:
+		return errcode.Validation
+	default:
+		return errcode.Internal
+	}
+}
+
+// Error responses with these statuses are sent with the HTTP status OK
+func apiStatusErrorCode(status api.ApiResStatusEnum) errcode.Code {
+	switch status {
+	case api.ApiResStatusOk:
+		return ""
+	case api.ApiResStatusRequestBodyError, api.ApiResStatusValidationError,
+		api.ApiResStatusInvalidRequest, api.ApiResStatusQueryLimitExceeded:
+		return errcode.Validation
+	default:
+		return errcode.Internal
+	}
+}
+
 // Set InternalServerError to output if err is not nil. Return true if err is not nil
 func HandleInternalServerError(w http.ResponseWriter, err error) bool {
 	if err != nil {
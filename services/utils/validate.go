@@ -17,6 +17,9 @@ func init() {
 	if err := validate.RegisterValidation("tx-id", ValidateTxID); err != nil {
 		log.Fatal(err)
 	}
+	if err := validate.RegisterValidation("node-id", ValidateNodeID); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func ValidateTxID(fl validator.FieldLevel) bool {
@@ -28,3 +31,9 @@ func ValidateTxID(fl validator.FieldLevel) bool {
 	_, err = ids.ToID(bytes)
 	return err == nil
 }
+
+// Node ID with the "NodeID-" prefix
+func ValidateNodeID(fl validator.FieldLevel) bool {
+	_, err := ids.NodeIDFromString(fl.Field().String())
+	return err == nil
+}
@@ -1,8 +1,11 @@
 package chain
 
 import (
+	"encoding/hex"
 	"flare-indexer/config"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/ava-labs/avalanchego/utils/crypto"
 	"github.com/ava-labs/avalanchego/utils/formatting/address"
@@ -11,23 +14,46 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Chain prefixes of human-readable addresses, e.g. "P-costwo1..."
+const (
+	PChainAddressPrefix = "P-"
+	XChainAddressPrefix = "X-"
+)
+
 var (
-	AddressHRP string
+	// Address prefixes accepted by ParseAddress, all indexed networks are
+	// registered when indexing several networks in one process
+	addressHRPs     = make(map[string]bool)
+	addressHRPsLock sync.RWMutex
 
 	ErrInvalidPublicKeyType = errors.New("invalid public key type")
 )
 
 func init() {
 	config.GlobalConfigCallback.AddCallback(func(config config.GlobalConfig) {
-		AddressHRP = config.ChainConfig().ChainAddressHRP
-		if len(AddressHRP) == 0 {
+		hrp := config.ChainConfig().ChainAddressHRP
+		if len(hrp) == 0 {
 			panic(fmt.Errorf("AddressHRP must be specified"))
 		}
+		RegisterAddressHRP(hrp)
 	})
 }
 
-func FormatAddressBytes(addr []byte) (string, error) {
-	return address.FormatBech32(AddressHRP, addr)
+// Accept addresses with prefix hrp in ParseAddress
+func RegisterAddressHRP(hrp string) {
+	addressHRPsLock.Lock()
+	defer addressHRPsLock.Unlock()
+	addressHRPs[hrp] = true
+}
+
+func isRegisteredAddressHRP(hrp string) bool {
+	addressHRPsLock.RLock()
+	defer addressHRPsLock.RUnlock()
+	return addressHRPs[hrp]
+}
+
+func FormatAddressBytes(hrp string, addr []byte) (string, error) {
+	return address.FormatBech32(hrp, addr)
 }
 
 func ParseAddress(addr string) ([20]byte, error) {
@@ -36,13 +62,50 @@ func ParseAddress(addr string) ([20]byte, error) {
 	if err != nil {
 		return address20, err
 	}
-	if hrp != AddressHRP {
+	if !isRegisteredAddressHRP(hrp) {
 		return address20, fmt.Errorf("invalid address prefix: %s", hrp)
 	}
 	copy(address20[:], address)
 	return address20, nil
 }
 
+// Formats a stored address (bech32 without the chain prefix) as a
+// human-readable P-chain address, e.g. "P-costwo1..."
+func FormatPChainAddress(addr string) string {
+	if len(addr) == 0 || strings.HasPrefix(addr, PChainAddressPrefix) {
+		return addr
+	}
+	return PChainAddressPrefix + addr
+}
+
+// Converts an address given as a P-chain or X-chain address ("P-costwo1..."),
+// as a bech32 address without the chain prefix ("costwo1...") or as the hex
+// encoded 20 address bytes ("0x...") to the stored format, a lowercase bech32
+// address with prefix hrp
+func NormalizeAddress(hrp string, addr string) (string, error) {
+	if strings.HasPrefix(addr, "0x") || strings.HasPrefix(addr, "0X") {
+		bytes, err := hex.DecodeString(addr[2:])
+		if err != nil || len(bytes) != 20 {
+			return "", fmt.Errorf("invalid hex address %s, expected 20 bytes", addr)
+		}
+		return FormatAddressBytes(hrp, bytes)
+	}
+	for _, prefix := range []string{PChainAddressPrefix, XChainAddressPrefix} {
+		if strings.HasPrefix(addr, prefix) {
+			addr = addr[len(prefix):]
+			break
+		}
+	}
+	addrHRP, bytes, err := address.ParseBech32(addr)
+	if err != nil {
+		return "", err
+	}
+	if addrHRP != hrp {
+		return "", fmt.Errorf("invalid address prefix %s, expected %s", addrHRP, hrp)
+	}
+	return FormatAddressBytes(hrp, bytes)
+}
+
 func PublicKeyToEthAddress(publicKey crypto.PublicKey) (common.Address, error) {
 	if pk, ok := publicKey.(*crypto.PublicKeySECP256K1R); ok {
 		return ethCrypto.PubkeyToAddress(*pk.ToECDSA()), nil
@@ -0,0 +1,45 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	bytes := make([]byte, 20)
+	for i := range bytes {
+		bytes[i] = byte(i)
+	}
+	stored, err := FormatAddressBytes("costwo", bytes)
+	require.NoError(t, err)
+
+	for _, addr := range []string{
+		stored,
+		"P-" + stored,
+		"X-" + stored,
+		strings.ToUpper(stored),
+		"0x000102030405060708090a0b0c0d0e0f10111213",
+	} {
+		normalized, err := NormalizeAddress("costwo", addr)
+		require.NoError(t, err, addr)
+		require.Equal(t, stored, normalized, addr)
+	}
+
+	flare, err := FormatAddressBytes("flare", bytes)
+	require.NoError(t, err)
+	for _, addr := range []string{flare, "P-" + flare, "costwo1abc", "0x0102", "0xzz", ""} {
+		_, err := NormalizeAddress("costwo", addr)
+		require.Error(t, err, addr)
+	}
+}
+
+func TestFormatPChainAddress(t *testing.T) {
+	require.Equal(t, "P-costwo1abc", FormatPChainAddress("costwo1abc"))
+	require.Equal(t, "P-costwo1abc", FormatPChainAddress("P-costwo1abc"))
+	require.Equal(t, "", FormatPChainAddress(""))
+}
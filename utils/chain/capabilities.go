@@ -0,0 +1,128 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"flare-indexer/utils"
+	"fmt"
+	"strings"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Code of the JSON-RPC error returned for unknown methods, avalanchego (gorilla
+// rpc) returns a server error with a "can't find method" message instead
+const jsonRPCMethodNotFound = -32601
+
+// APIs of the node used by the indexer, see ProbeNodeCapabilities
+type NodeCapabilities struct {
+	// info API (ext/info), used by the startup checks
+	Info Capability
+	// platform API (ext/bc/P), used by the uptime cronjob and for the outputs
+	// spent by P-chain transactions which are not indexed
+	Platform Capability
+	// platform.getBlockByHeight, used by the P-chain indexer with source "rpc",
+	// it is not available on older nodes
+	PlatformBlockByHeight Capability
+	// avm API (ext/bc/X)
+	AVM Capability
+	// index API of the P-chain blocks (ext/index/P/block), used by the P-chain
+	// indexer with source "indexer"
+	PChainIndex Capability
+	// index API of the X-chain transactions and vertices (ext/index/X/tx and
+	// ext/index/X/vtx), used by the X-chain indexer
+	XChainIndex Capability
+}
+
+type Capability struct {
+	Available bool
+	// Reason why the API is not available, e.g. the error of the node
+	Reason string
+}
+
+func (c Capability) String() string {
+	if c.Available {
+		return "available"
+	}
+	return "not available (" + c.Reason + ")"
+}
+
+// Report of the capabilities, one line per API
+func (c NodeCapabilities) String() string {
+	var sb strings.Builder
+	for _, line := range []struct {
+		name       string
+		capability Capability
+	}{
+		{"info API", c.Info},
+		{"platform API", c.Platform},
+		{"platform.getBlockByHeight", c.PlatformBlockByHeight},
+		{"avm API", c.AVM},
+		{"P-chain index", c.PChainIndex},
+		{"X-chain index", c.XChainIndex},
+	} {
+		fmt.Fprintf(&sb, "\n  %-26s %s", line.name+":", line.capability)
+	}
+	return sb.String()
+}
+
+// Probes the APIs of the node by calling a method of each API. An API is
+// available if the node returns a result or an error of the method, e.g. the
+// index API of a chain without accepted containers is available. A route that
+// is not found (an API or index which is not enabled) or an unknown method
+// makes it unavailable.
+func ProbeNodeCapabilities(cfg *config.ChainConfig) NodeCapabilities {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), NodeInfoTimeout)
+	defer cancelCtx()
+
+	p := capabilityProber{nodeURL: cfg.NodeURL, apiKey: cfg.ApiKey}
+	return NodeCapabilities{
+		Info:     p.probeMethod(ctx, "ext/info", "info.getNodeVersion", struct{}{}),
+		Platform: p.probeMethod(ctx, "ext/bc/P", "platform.getHeight", struct{}{}),
+		PlatformBlockByHeight: p.probeMethod(ctx, "ext/bc/P", "platform.getBlockByHeight",
+			map[string]string{"height": "1", "encoding": "hex"}),
+		AVM: p.probeMethod(ctx, "ext/bc/X", "avm.getAssetDescription",
+			map[string]string{"assetID": "AVAX"}),
+		PChainIndex: p.probeIndex(ctx, "ext/index/P/block"),
+		XChainIndex: p.probeIndex(ctx, "ext/index/X/tx", "ext/index/X/vtx"),
+	}
+}
+
+type capabilityProber struct {
+	nodeURL string
+	apiKey  string
+}
+
+// An index with several routes is available if all of them are
+func (p capabilityProber) probeIndex(ctx context.Context, paths ...string) Capability {
+	for _, path := range paths {
+		c := p.probeMethod(ctx, path, "index.getLastAccepted", map[string]string{"encoding": "hex"})
+		if !c.Available {
+			c.Reason = path + ": " + c.Reason
+			return c
+		}
+	}
+	return Capability{Available: true}
+}
+
+func (p capabilityProber) probeMethod(ctx context.Context, path string, method string, params interface{}) Capability {
+	endpoint := utils.JoinPaths(p.nodeURL, path+RPCClientOptions(p.apiKey))
+	response, err := jsonrpc.NewClient(endpoint).Call(ctx, method, params)
+	var httpErr *jsonrpc.HTTPError
+	switch {
+	case errors.As(err, &httpErr) && response == nil:
+		return Capability{Reason: fmt.Sprintf("status code %d", httpErr.Code)}
+	case response == nil:
+		// The error of the client contains the URL with the API key
+		return Capability{Reason: redactAPIKey(err.Error(), p.apiKey)}
+	case response.Error != nil && isMethodNotFound(response.Error):
+		return Capability{Reason: "unknown method " + method}
+	}
+	return Capability{Available: true}
+}
+
+func isMethodNotFound(err *jsonrpc.RPCError) bool {
+	return err.Code == jsonRPCMethodNotFound || strings.Contains(err.Message, "can't find method") ||
+		strings.Contains(err.Message, "can't find service")
+}
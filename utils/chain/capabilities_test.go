@@ -0,0 +1,56 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"encoding/json"
+	"flare-indexer/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeNodeCapabilities(t *testing.T) {
+	// A node with the P-chain index only, without platform.getBlockByHeight and
+	// accepted X-chain transactions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.URL.Query().Get("x-apikey"))
+		var request struct {
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/ext/index/X/vtx" || r.URL.Path == "/ext/index/X/tx":
+			http.NotFound(w, r)
+		case request.Method == "platform.getBlockByHeight":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":0,"error":{"code":-32000,"message":"rpc: can't find method \"platform.getBlockByHeight\""}}`))
+		case request.Method == "avm.getAssetDescription":
+			w.Write([]byte(`{"jsonrpc":"2.0","id":0,"error":{"code":-32000,"message":"couldn't find asset"}}`))
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","id":0,"result":{}}`))
+		}
+	}))
+	defer server.Close()
+
+	caps := ProbeNodeCapabilities(&config.ChainConfig{NodeURL: server.URL, ApiKey: "secret"})
+	require.True(t, caps.Info.Available)
+	require.True(t, caps.Platform.Available)
+	require.True(t, caps.PChainIndex.Available)
+	// Errors of the method do not make the API unavailable
+	require.True(t, caps.AVM.Available)
+	require.False(t, caps.PlatformBlockByHeight.Available)
+	require.Equal(t, "unknown method platform.getBlockByHeight", caps.PlatformBlockByHeight.Reason)
+	require.False(t, caps.XChainIndex.Available)
+	require.Equal(t, "ext/index/X/tx: status code 404", caps.XChainIndex.Reason)
+
+	server.Close()
+	caps = ProbeNodeCapabilities(&config.ChainConfig{NodeURL: server.URL, ApiKey: "secret"})
+	require.False(t, caps.Info.Available)
+	require.NotContains(t, caps.Info.Reason, "secret")
+	require.Contains(t, caps.String(), "info API:")
+}
@@ -3,6 +3,7 @@ package chain
 import (
 	"context"
 	"flare-indexer/logger"
+	"strings"
 	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
@@ -10,32 +11,49 @@ import (
 	"github.com/ava-labs/avalanchego/utils/rpc"
 )
 
-const (
-	IndexerTimeout time.Duration = 3 * time.Minute
-)
+// Calls of the functions below are limited by ctx only, the duration of a single
+// call is limited by the client (see NewTimeoutIndexerClient)
 
 // Get range of indexed objects by calling "index.getContainerRange"
-func FetchContainerRangeFromIndexer(client IndexerClient, from uint64, numToFetch int) ([]indexer.Container, error) {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), IndexerTimeout)
-	defer cancelCtx()
-
+func FetchContainerRangeFromIndexer(ctx context.Context, client IndexerClient, from uint64, numToFetch int) ([]indexer.Container, error) {
 	return client.GetContainerRange(ctx, from, numToFetch)
 }
 
 // Get last accepted container by calling "index.getLastAccepted"
-func FetchLastAcceptedContainer(client IndexerClient) (indexer.Container, uint64, error) {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), IndexerTimeout)
-	defer cancelCtx()
-
+func FetchLastAcceptedContainer(ctx context.Context, client IndexerClient) (indexer.Container, uint64, error) {
 	return client.GetLastAccepted(ctx)
 }
 
+// Range of container indexes available on the node, i.e. from the first
+// retained to the last accepted container. The first index is found by a
+// binary search with "index.getContainerByIndex", assuming that the node
+// retains a contiguous range of containers up to the last accepted one.
+func FetchAvailableIndexRange(ctx context.Context, client IndexerClient) (uint64, uint64, error) {
+	_, last, err := FetchLastAcceptedContainer(ctx, client)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The container at index last is available, search the first available
+	// index in [low, high]
+	low, high := uint64(0), last
+	for low < high {
+		mid := low + (high-low)/2
+		_, err := client.GetContainerByIndex(ctx, mid)
+		if err == nil {
+			high = mid
+		} else if ctx.Err() != nil {
+			return 0, 0, ctx.Err()
+		} else {
+			low = mid + 1
+		}
+	}
+	return low, last, nil
+}
+
 // Get object by its id by calling "index.getIndex" and "index.getContainerByIndex" successively.
 // Returns nil, nil if getIndex failed with an error.
-func FetchContainerFromIndexer(client IndexerClient, id string) (*indexer.Container, error) {
-	ctx, cancelCtx := context.WithTimeout(context.Background(), IndexerTimeout)
-	defer cancelCtx()
-
+func FetchContainerFromIndexer(ctx context.Context, client IndexerClient, id string) (*indexer.Container, error) {
 	txID, _ := ids.FromString(id)
 	index, err := client.GetIndex(ctx, txID)
 	if err != nil {
@@ -68,6 +86,14 @@ func RPCClientOptions(apiKey string) string {
 	}
 }
 
+// Replaces the API key in s, e.g. in an error containing the URL of a request
+func redactAPIKey(s string, apiKey string) string {
+	if len(apiKey) == 0 {
+		return s
+	}
+	return strings.ReplaceAll(s, apiKey, "***")
+}
+
 // Convert timestamp in nanoseconds or seconds to time.Time
 // Timestamps less than 253370764800 (9999-01-01T00:00:00Z) are considered to be in seconds
 func TimestampToTime(timestamp int64) time.Time {
@@ -0,0 +1,56 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/stretchr/testify/require"
+)
+
+// Node retaining the containers at indexes first to last, only the methods used
+// in the tests are implemented
+type testRangeIndexerClient struct {
+	IndexerClient
+	first, last uint64
+	calls       int
+}
+
+func (c *testRangeIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	return indexer.Container{}, c.last, nil
+}
+
+func (c *testRangeIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	c.calls++
+	if err := ctx.Err(); err != nil {
+		return indexer.Container{}, err
+	}
+	if index < c.first || index > c.last {
+		return indexer.Container{}, errors.New("no container at index")
+	}
+	return indexer.Container{}, nil
+}
+
+func TestFetchAvailableIndexRange(t *testing.T) {
+	for _, first := range []uint64{0, 1, 500, 999_999, 1_000_000} {
+		client := &testRangeIndexerClient{first: first, last: 1_000_000}
+		from, to, err := FetchAvailableIndexRange(context.Background(), client)
+		require.NoError(t, err)
+		require.Equal(t, first, from)
+		require.Equal(t, uint64(1_000_000), to)
+		require.LessOrEqual(t, client.calls, 20)
+	}
+}
+
+func TestFetchAvailableIndexRangeCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := &testRangeIndexerClient{first: 500, last: 1_000_000}
+	_, _, err := FetchAvailableIndexRange(ctx, client)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, client.calls)
+}
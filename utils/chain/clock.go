@@ -0,0 +1,54 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"flare-indexer/config"
+	"flare-indexer/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Resolution of the Date header of HTTP responses
+const httpDateResolution = time.Second
+
+// Estimates the difference between the local clock and the clock of the node,
+// positive if the local clock is ahead. The time of the node is the Date header
+// of a response of the info API, see clockDrift.
+func FetchNodeClockDrift(ctx context.Context, cfg *config.ChainConfig) (time.Duration, error) {
+	endpoint := utils.JoinPaths(cfg.NodeURL, "ext/info"+RPCClientOptions(cfg.ApiKey))
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"info.getNodeVersion","params":{}}`)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	sent := time.Now()
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		// The error contains the URL with the API key
+		return 0, fmt.Errorf("cannot fetch the time of the node: %s", redactAPIKey(err.Error(), cfg.ApiKey))
+	}
+	received := time.Now()
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	date, err := http.ParseTime(response.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("node response has no valid Date header: %w", err)
+	}
+	return clockDrift(sent, received, date), nil
+}
+
+// The node sets the date of the response between sent and received, it is
+// assumed to be in the middle. The date is truncated to a second, the middle of
+// the second is taken as the time of the node. The error of the estimate is at
+// most half of the round trip time plus half a second.
+func clockDrift(sent time.Time, received time.Time, date time.Time) time.Duration {
+	local := sent.Add(received.Sub(sent) / 2)
+	node := date.Add(httpDateResolution / 2)
+	return local.Sub(node)
+}
@@ -0,0 +1,48 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"flare-indexer/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockDrift(t *testing.T) {
+	sent := time.Date(2023, 11, 24, 12, 0, 10, 0, time.UTC)
+	received := sent.Add(400 * time.Millisecond)
+
+	// The node time is 12:00:10.5, the local time 12:00:10.2
+	require.Equal(t, -300*time.Millisecond, clockDrift(sent, received, sent))
+	// The local clock is ahead by a minute
+	require.Equal(t, 59700*time.Millisecond, clockDrift(sent, received, sent.Add(-time.Minute)))
+}
+
+func TestFetchNodeClockDrift(t *testing.T) {
+	// A node with the clock 10 seconds behind
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ext/info", r.URL.Path)
+		require.Equal(t, "secret", r.URL.Query().Get("x-apikey"))
+		w.Header().Set("Date", time.Now().Add(-10*time.Second).UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.ChainConfig{NodeURL: server.URL, ApiKey: "secret"}
+	drift, err := FetchNodeClockDrift(context.Background(), cfg)
+	require.NoError(t, err)
+	require.True(t, drift > 9*time.Second && drift <= 11*time.Second, "drift %v", drift)
+
+	// The API key is not included in the error
+	server.Close()
+	_, err = FetchNodeClockDrift(context.Background(), cfg)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret")
+}
@@ -0,0 +1,22 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//go:generate mockgen -destination=mocks/mocks.go -package=mocks flare-indexer/utils/chain IndexerClient,RPCClient,EthClient
+
+// Subset of ethclient.Client methods needed by the contract bindings and for
+// waiting on transaction receipts
+type EthClient interface {
+	bind.ContractBackend
+	bind.DeployBackend
+}
+
+// Sends several JSON-RPC calls in one request (implemented by ManagedEthClient)
+type BatchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
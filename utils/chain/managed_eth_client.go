@@ -0,0 +1,322 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/logger"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const ethHealthCheckTimeout = 10 * time.Second
+
+// Methods of ethclient.Client used by ManagedEthClient
+type ethConn interface {
+	EthClient
+	ChainIDClient
+	BlockNumber(ctx context.Context) (uint64, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+	Close()
+}
+
+// ethclient.Client with the batch calls of its RPC client
+type ethClientConn struct {
+	*ethclient.Client
+	rpc *rpc.Client
+}
+
+func (c ethClientConn) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return c.rpc.BatchCallContext(ctx, b)
+}
+
+// Eth client shared by all contract bindings of a network. The connection is
+// dialed on the first call and dialed again after a call or a health check
+// failing with a connection error, e.g. when the RPC node restarts. A replaced
+// connection is closed once the calls still using it have returned.
+// Implements EthClient, ChainIDClient and BatchCaller.
+type ManagedEthClient struct {
+	url  string
+	dial func(url string) (ethConn, error)
+	// Maximal duration of a call, no limit if zero. Subscriptions are not limited.
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn *managedConn
+}
+
+// Connection with the number of calls using it, guarded by the mutex of the
+// client
+type managedConn struct {
+	ethConn
+	refs    int
+	dropped bool
+}
+
+func NewManagedEthClient(url string, timeout time.Duration) *ManagedEthClient {
+	return &ManagedEthClient{
+		url:     url,
+		timeout: timeout,
+		dial: func(url string) (ethConn, error) {
+			client, err := rpc.Dial(url)
+			if err != nil {
+				return nil, err
+			}
+			return ethClientConn{Client: ethclient.NewClient(client), rpc: client}, nil
+		},
+	}
+}
+
+// Checks the connection every period (if positive) by fetching the latest
+// block number until ctx is done. The connection is dialed again on the next
+// call if the check fails with a connection error.
+func (c *ManagedEthClient) StartHealthCheck(ctx context.Context, period time.Duration) {
+	if period <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.healthCheck(ctx); err != nil {
+					logger.Warn("Eth RPC health check failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Fetches the latest block number and drops the connection if it fails with a
+// connection error. The client is not dialed if it is not connected, so a
+// process that does not use the eth RPC does not connect to it.
+func (c *ManagedEthClient) healthCheck(ctx context.Context) error {
+	c.mu.Lock()
+	conn := c.conn
+	if conn != nil {
+		conn.refs++
+	}
+	c.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	defer c.release(conn)
+
+	ctx, cancel := context.WithTimeout(ctx, ethHealthCheckTimeout)
+	defer cancel()
+	_, err := conn.BlockNumber(ctx)
+	c.check(conn, err)
+	return err
+}
+
+// Returns the connection, dialed if needed, with a reference that must be
+// released
+func (c *ManagedEthClient) acquire() (*managedConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		conn, err := c.dial(c.url)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = &managedConn{ethConn: conn}
+	}
+	c.conn.refs++
+	return c.conn, nil
+}
+
+// Releases a reference to the connection, a dropped connection is closed with
+// its last reference
+func (c *ManagedEthClient) release(conn *managedConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn.refs--
+	if conn.dropped && conn.refs == 0 {
+		conn.Close()
+	}
+}
+
+// Replaces the connection on the next call unless it has already been replaced.
+// The connection is closed when no call uses it.
+func (c *ManagedEthClient) drop(conn *managedConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != conn {
+		return
+	}
+	c.conn = nil
+	conn.dropped = true
+	if conn.refs == 0 {
+		conn.Close()
+	}
+}
+
+// Drops the connection if err is a connection error
+func (c *ManagedEthClient) check(conn *managedConn, err error) {
+	if isConnectionError(err) {
+		logger.Warn("Eth RPC connection error, reconnecting: %v", err)
+		c.drop(conn)
+	}
+}
+
+// Errors of a broken connection, as opposed to errors returned by the node.
+// Timeouts of the caller's context are not connection errors.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, rpc.ErrClientQuit) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.As(err, &netErr)
+}
+
+// Calls f with the connection, dialed if needed, and a context limited by the
+// timeout of the client. The connection is dropped if f fails with a connection
+// error and is not closed while f uses it.
+func managedCall[T any](ctx context.Context, c *ManagedEthClient, f func(ctx context.Context, conn ethConn) (T, error)) (T, error) {
+	conn, err := c.acquire()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer c.release(conn)
+
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
+	result, err := f(ctx, conn)
+	c.check(conn, err)
+	return result, err
+}
+
+func (c *ManagedEthClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) ([]byte, error) {
+		return conn.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (c *ManagedEthClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) ([]byte, error) {
+		return conn.CallContract(ctx, call, blockNumber)
+	})
+}
+
+func (c *ManagedEthClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (*types.Header, error) {
+		return conn.HeaderByNumber(ctx, number)
+	})
+}
+
+func (c *ManagedEthClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) ([]byte, error) {
+		return conn.PendingCodeAt(ctx, account)
+	})
+}
+
+func (c *ManagedEthClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (uint64, error) {
+		return conn.PendingNonceAt(ctx, account)
+	})
+}
+
+func (c *ManagedEthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (*big.Int, error) {
+		return conn.SuggestGasPrice(ctx)
+	})
+}
+
+func (c *ManagedEthClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (*big.Int, error) {
+		return conn.SuggestGasTipCap(ctx)
+	})
+}
+
+func (c *ManagedEthClient) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (uint64, error) {
+		return conn.EstimateGas(ctx, call)
+	})
+}
+
+func (c *ManagedEthClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := managedCall(ctx, c, func(ctx context.Context, conn ethConn) (struct{}, error) {
+		return struct{}{}, conn.SendTransaction(ctx, tx)
+	})
+	return err
+}
+
+func (c *ManagedEthClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) ([]types.Log, error) {
+		return conn.FilterLogs(ctx, query)
+	})
+}
+
+// The subscription keeps its connection until it is unsubscribed, it is not
+// renewed when the connection is dialed again
+func (c *ManagedEthClient) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	conn, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := conn.SubscribeFilterLogs(ctx, query, ch)
+	c.check(conn, err)
+	if err != nil {
+		c.release(conn)
+		return nil, err
+	}
+	return &managedSubscription{Subscription: sub, release: func() { c.release(conn) }}, nil
+}
+
+// Releases the connection of the subscription when it is unsubscribed
+type managedSubscription struct {
+	ethereum.Subscription
+	release func()
+	once    sync.Once
+}
+
+func (s *managedSubscription) Unsubscribe() {
+	s.Subscription.Unsubscribe()
+	s.once.Do(s.release)
+}
+
+func (c *ManagedEthClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (*types.Receipt, error) {
+		return conn.TransactionReceipt(ctx, txHash)
+	})
+}
+
+func (c *ManagedEthClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (*big.Int, error) {
+		return conn.ChainID(ctx)
+	})
+}
+
+func (c *ManagedEthClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return managedCall(ctx, c, func(ctx context.Context, conn ethConn) (*big.Int, error) {
+		return conn.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+// The batch is limited by the timeout of a single call
+func (c *ManagedEthClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	_, err := managedCall(ctx, c, func(ctx context.Context, conn ethConn) (struct{}, error) {
+		return struct{}{}, conn.BatchCallContext(ctx, b)
+	})
+	return err
+}
@@ -0,0 +1,189 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// Only the methods used in the tests are implemented
+type testEthConn struct {
+	EthClient
+	err    error
+	closed bool
+	// CodeAt waits for the end of its context
+	slow bool
+}
+
+func (c *testEthConn) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if c.slow {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return []byte{1}, c.err
+}
+
+func (c *testEthConn) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(114), c.err
+}
+
+func (c *testEthConn) BlockNumber(ctx context.Context) (uint64, error) {
+	return 1, c.err
+}
+
+func (c *testEthConn) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return big.NewInt(1), c.err
+}
+
+func (c *testEthConn) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return c.err
+}
+
+func (c *testEthConn) Close() {
+	c.closed = true
+}
+
+func newTestManagedEthClient(conns ...*testEthConn) (*ManagedEthClient, *int) {
+	dials := 0
+	return &ManagedEthClient{
+		url: "ws://localhost:9650",
+		dial: func(url string) (ethConn, error) {
+			if dials >= len(conns) {
+				return nil, errors.New("connection refused")
+			}
+			dials++
+			return conns[dials-1], nil
+		},
+	}, &dials
+}
+
+func TestManagedEthClientReconnect(t *testing.T) {
+	conn1 := &testEthConn{}
+	conn2 := &testEthConn{}
+	client, dials := newTestManagedEthClient(conn1, conn2)
+	require.Equal(t, 0, *dials)
+
+	_, err := client.CodeAt(context.Background(), common.Address{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, *dials)
+
+	// Errors returned by the node keep the connection
+	conn1.err = errors.New("execution reverted")
+	_, err = client.CodeAt(context.Background(), common.Address{}, nil)
+	require.Error(t, err)
+	_, err = client.ChainID(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, *dials)
+	require.False(t, conn1.closed)
+
+	// The connection is dialed again after a connection error
+	conn1.err = io.EOF
+	_, err = client.CodeAt(context.Background(), common.Address{}, nil)
+	require.ErrorIs(t, err, io.EOF)
+	require.True(t, conn1.closed)
+	id, err := client.ChainID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(114), id.Int64())
+	require.Equal(t, 2, *dials)
+
+	// Dial errors are returned
+	conn2.err = io.EOF
+	_, err = client.ChainID(context.Background())
+	require.Error(t, err)
+	_, err = client.ChainID(context.Background())
+	require.EqualError(t, err, "connection refused")
+}
+
+func TestManagedEthClientHealthCheck(t *testing.T) {
+	conn := &testEthConn{}
+	client, dials := newTestManagedEthClient(conn)
+	ctx := context.Background()
+
+	// Not connected yet, nothing to check
+	require.NoError(t, client.healthCheck(ctx))
+	require.Equal(t, 0, *dials)
+
+	_, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	require.NoError(t, client.healthCheck(ctx))
+
+	// Errors returned by the node keep the connection
+	conn.err = errors.New("header not found")
+	require.Error(t, client.healthCheck(ctx))
+	require.False(t, conn.closed)
+	require.NotNil(t, client.conn)
+
+	conn.err = io.EOF
+	require.Error(t, client.healthCheck(ctx))
+	require.True(t, conn.closed)
+	require.Nil(t, client.conn)
+}
+
+func TestManagedEthClientDropInFlight(t *testing.T) {
+	conn1 := &testEthConn{}
+	conn2 := &testEthConn{}
+	client, dials := newTestManagedEthClient(conn1, conn2)
+
+	// A connection dropped by the health check is closed after the calls
+	// using it return, the next call uses a new connection
+	_, err := managedCall(context.Background(), client, func(ctx context.Context, conn ethConn) (struct{}, error) {
+		conn1.err = io.EOF
+		require.Error(t, client.healthCheck(ctx))
+		require.False(t, conn1.closed)
+
+		_, err := client.ChainID(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, *dials)
+		return struct{}{}, nil
+	})
+	require.NoError(t, err)
+	require.True(t, conn1.closed)
+	require.False(t, conn2.closed)
+}
+
+func TestManagedEthClientStopHealthCheck(t *testing.T) {
+	conn := &testEthConn{}
+	client, _ := newTestManagedEthClient(conn)
+	_, err := client.ChainID(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.StartHealthCheck(ctx, time.Millisecond)
+	cancel()
+	// Let a running check finish, no check runs after the cancellation
+	time.Sleep(10 * time.Millisecond)
+	conn.err = io.EOF
+	time.Sleep(10 * time.Millisecond)
+	require.False(t, conn.closed)
+}
+
+func TestIsConnectionError(t *testing.T) {
+	require.False(t, isConnectionError(nil))
+	require.False(t, isConnectionError(errors.New("execution reverted")))
+	require.False(t, isConnectionError(context.DeadlineExceeded))
+	require.True(t, isConnectionError(io.ErrUnexpectedEOF))
+}
+
+func TestManagedEthClientTimeout(t *testing.T) {
+	conn := &testEthConn{slow: true}
+	client, dials := newTestManagedEthClient(conn)
+	client.timeout = 10 * time.Millisecond
+
+	// A slow call ends after the timeout and keeps the connection
+	_, err := client.CodeAt(context.Background(), common.Address{}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, conn.closed)
+	_, err = client.ChainID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, *dials)
+}
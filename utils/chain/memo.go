@@ -0,0 +1,23 @@
+package chain
+
+import (
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Length of the longest encoded memo, the size of the memo columns
+const MaxEncodedMemoLength = 2 + 2*avax.MaxMemoSize
+
+// Memo of a transaction as stored in the database, hex encoded with the "0x"
+// prefix and at most avax.MaxMemoSize bytes long, or an empty string if the
+// memo is empty. Valid transactions have no longer memos, longer ones are
+// truncated.
+func EncodeMemo(memo []byte) string {
+	if len(memo) == 0 {
+		return ""
+	}
+	if len(memo) > avax.MaxMemoSize {
+		memo = memo[:avax.MaxMemoSize]
+	}
+	return hexutil.Encode(memo)
+}
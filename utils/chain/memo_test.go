@@ -0,0 +1,20 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeMemo(t *testing.T) {
+	require.Equal(t, "", EncodeMemo(nil))
+	require.Equal(t, "0x00ff", EncodeMemo([]byte{0, 255}))
+
+	memo := EncodeMemo(bytes.Repeat([]byte{1}, avax.MaxMemoSize+10))
+	require.Len(t, memo, MaxEncodedMemoLength)
+}
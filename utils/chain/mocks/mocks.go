@@ -0,0 +1,359 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: flare-indexer/utils/chain (interfaces: IndexerClient,RPCClient,EthClient)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	chain "flare-indexer/utils/chain"
+	big "math/big"
+	reflect "reflect"
+
+	api "github.com/ava-labs/avalanchego/api"
+	ids "github.com/ava-labs/avalanchego/ids"
+	indexer "github.com/ava-labs/avalanchego/indexer"
+	ethereum "github.com/ethereum/go-ethereum"
+	common "github.com/ethereum/go-ethereum/common"
+	types "github.com/ethereum/go-ethereum/core/types"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIndexerClient is a mock of IndexerClient interface.
+type MockIndexerClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockIndexerClientMockRecorder
+}
+
+// MockIndexerClientMockRecorder is the mock recorder for MockIndexerClient.
+type MockIndexerClientMockRecorder struct {
+	mock *MockIndexerClient
+}
+
+// NewMockIndexerClient creates a new mock instance.
+func NewMockIndexerClient(ctrl *gomock.Controller) *MockIndexerClient {
+	mock := &MockIndexerClient{ctrl: ctrl}
+	mock.recorder = &MockIndexerClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIndexerClient) EXPECT() *MockIndexerClientMockRecorder {
+	return m.recorder
+}
+
+// GetContainerByIndex mocks base method.
+func (m *MockIndexerClient) GetContainerByIndex(arg0 context.Context, arg1 uint64) (indexer.Container, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContainerByIndex", arg0, arg1)
+	ret0, _ := ret[0].(indexer.Container)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContainerByIndex indicates an expected call of GetContainerByIndex.
+func (mr *MockIndexerClientMockRecorder) GetContainerByIndex(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerByIndex", reflect.TypeOf((*MockIndexerClient)(nil).GetContainerByIndex), arg0, arg1)
+}
+
+// GetContainerRange mocks base method.
+func (m *MockIndexerClient) GetContainerRange(arg0 context.Context, arg1 uint64, arg2 int) ([]indexer.Container, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContainerRange", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]indexer.Container)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContainerRange indicates an expected call of GetContainerRange.
+func (mr *MockIndexerClientMockRecorder) GetContainerRange(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerRange", reflect.TypeOf((*MockIndexerClient)(nil).GetContainerRange), arg0, arg1, arg2)
+}
+
+// GetIndex mocks base method.
+func (m *MockIndexerClient) GetIndex(arg0 context.Context, arg1 ids.ID) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIndex", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIndex indicates an expected call of GetIndex.
+func (mr *MockIndexerClientMockRecorder) GetIndex(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIndex", reflect.TypeOf((*MockIndexerClient)(nil).GetIndex), arg0, arg1)
+}
+
+// GetLastAccepted mocks base method.
+func (m *MockIndexerClient) GetLastAccepted(arg0 context.Context) (indexer.Container, uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastAccepted", arg0)
+	ret0, _ := ret[0].(indexer.Container)
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetLastAccepted indicates an expected call of GetLastAccepted.
+func (mr *MockIndexerClientMockRecorder) GetLastAccepted(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastAccepted", reflect.TypeOf((*MockIndexerClient)(nil).GetLastAccepted), arg0)
+}
+
+// MockRPCClient is a mock of RPCClient interface.
+type MockRPCClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockRPCClientMockRecorder
+}
+
+// MockRPCClientMockRecorder is the mock recorder for MockRPCClient.
+type MockRPCClientMockRecorder struct {
+	mock *MockRPCClient
+}
+
+// NewMockRPCClient creates a new mock instance.
+func NewMockRPCClient(ctrl *gomock.Controller) *MockRPCClient {
+	mock := &MockRPCClient{ctrl: ctrl}
+	mock.recorder = &MockRPCClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRPCClient) EXPECT() *MockRPCClientMockRecorder {
+	return m.recorder
+}
+
+// GetRewardUTXOs mocks base method.
+func (m *MockRPCClient) GetRewardUTXOs(arg0 context.Context, arg1 ids.ID) (*chain.GetRewardUTXOsReply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRewardUTXOs", arg0, arg1)
+	ret0, _ := ret[0].(*chain.GetRewardUTXOsReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRewardUTXOs indicates an expected call of GetRewardUTXOs.
+func (mr *MockRPCClientMockRecorder) GetRewardUTXOs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRewardUTXOs", reflect.TypeOf((*MockRPCClient)(nil).GetRewardUTXOs), arg0, arg1)
+}
+
+// GetTx mocks base method.
+func (m *MockRPCClient) GetTx(arg0 context.Context, arg1 ids.ID) (*api.GetTxReply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTx", arg0, arg1)
+	ret0, _ := ret[0].(*api.GetTxReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTx indicates an expected call of GetTx.
+func (mr *MockRPCClientMockRecorder) GetTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTx", reflect.TypeOf((*MockRPCClient)(nil).GetTx), arg0, arg1)
+}
+
+// MockEthClient is a mock of EthClient interface.
+type MockEthClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockEthClientMockRecorder
+}
+
+// MockEthClientMockRecorder is the mock recorder for MockEthClient.
+type MockEthClientMockRecorder struct {
+	mock *MockEthClient
+}
+
+// NewMockEthClient creates a new mock instance.
+func NewMockEthClient(ctrl *gomock.Controller) *MockEthClient {
+	mock := &MockEthClient{ctrl: ctrl}
+	mock.recorder = &MockEthClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEthClient) EXPECT() *MockEthClientMockRecorder {
+	return m.recorder
+}
+
+// CallContract mocks base method.
+func (m *MockEthClient) CallContract(arg0 context.Context, arg1 ethereum.CallMsg, arg2 *big.Int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CallContract", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CallContract indicates an expected call of CallContract.
+func (mr *MockEthClientMockRecorder) CallContract(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CallContract", reflect.TypeOf((*MockEthClient)(nil).CallContract), arg0, arg1, arg2)
+}
+
+// CodeAt mocks base method.
+func (m *MockEthClient) CodeAt(arg0 context.Context, arg1 common.Address, arg2 *big.Int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CodeAt", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CodeAt indicates an expected call of CodeAt.
+func (mr *MockEthClientMockRecorder) CodeAt(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CodeAt", reflect.TypeOf((*MockEthClient)(nil).CodeAt), arg0, arg1, arg2)
+}
+
+// EstimateGas mocks base method.
+func (m *MockEthClient) EstimateGas(arg0 context.Context, arg1 ethereum.CallMsg) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EstimateGas", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EstimateGas indicates an expected call of EstimateGas.
+func (mr *MockEthClientMockRecorder) EstimateGas(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimateGas", reflect.TypeOf((*MockEthClient)(nil).EstimateGas), arg0, arg1)
+}
+
+// FilterLogs mocks base method.
+func (m *MockEthClient) FilterLogs(arg0 context.Context, arg1 ethereum.FilterQuery) ([]types.Log, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterLogs", arg0, arg1)
+	ret0, _ := ret[0].([]types.Log)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterLogs indicates an expected call of FilterLogs.
+func (mr *MockEthClientMockRecorder) FilterLogs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterLogs", reflect.TypeOf((*MockEthClient)(nil).FilterLogs), arg0, arg1)
+}
+
+// HeaderByNumber mocks base method.
+func (m *MockEthClient) HeaderByNumber(arg0 context.Context, arg1 *big.Int) (*types.Header, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HeaderByNumber", arg0, arg1)
+	ret0, _ := ret[0].(*types.Header)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HeaderByNumber indicates an expected call of HeaderByNumber.
+func (mr *MockEthClientMockRecorder) HeaderByNumber(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeaderByNumber", reflect.TypeOf((*MockEthClient)(nil).HeaderByNumber), arg0, arg1)
+}
+
+// PendingCodeAt mocks base method.
+func (m *MockEthClient) PendingCodeAt(arg0 context.Context, arg1 common.Address) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingCodeAt", arg0, arg1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingCodeAt indicates an expected call of PendingCodeAt.
+func (mr *MockEthClientMockRecorder) PendingCodeAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingCodeAt", reflect.TypeOf((*MockEthClient)(nil).PendingCodeAt), arg0, arg1)
+}
+
+// PendingNonceAt mocks base method.
+func (m *MockEthClient) PendingNonceAt(arg0 context.Context, arg1 common.Address) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingNonceAt", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingNonceAt indicates an expected call of PendingNonceAt.
+func (mr *MockEthClientMockRecorder) PendingNonceAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingNonceAt", reflect.TypeOf((*MockEthClient)(nil).PendingNonceAt), arg0, arg1)
+}
+
+// SendTransaction mocks base method.
+func (m *MockEthClient) SendTransaction(arg0 context.Context, arg1 *types.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendTransaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendTransaction indicates an expected call of SendTransaction.
+func (mr *MockEthClientMockRecorder) SendTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendTransaction", reflect.TypeOf((*MockEthClient)(nil).SendTransaction), arg0, arg1)
+}
+
+// SubscribeFilterLogs mocks base method.
+func (m *MockEthClient) SubscribeFilterLogs(arg0 context.Context, arg1 ethereum.FilterQuery, arg2 chan<- types.Log) (ethereum.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeFilterLogs", arg0, arg1, arg2)
+	ret0, _ := ret[0].(ethereum.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeFilterLogs indicates an expected call of SubscribeFilterLogs.
+func (mr *MockEthClientMockRecorder) SubscribeFilterLogs(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeFilterLogs", reflect.TypeOf((*MockEthClient)(nil).SubscribeFilterLogs), arg0, arg1, arg2)
+}
+
+// SuggestGasPrice mocks base method.
+func (m *MockEthClient) SuggestGasPrice(arg0 context.Context) (*big.Int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuggestGasPrice", arg0)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SuggestGasPrice indicates an expected call of SuggestGasPrice.
+func (mr *MockEthClientMockRecorder) SuggestGasPrice(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestGasPrice", reflect.TypeOf((*MockEthClient)(nil).SuggestGasPrice), arg0)
+}
+
+// SuggestGasTipCap mocks base method.
+func (m *MockEthClient) SuggestGasTipCap(arg0 context.Context) (*big.Int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuggestGasTipCap", arg0)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SuggestGasTipCap indicates an expected call of SuggestGasTipCap.
+func (mr *MockEthClientMockRecorder) SuggestGasTipCap(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestGasTipCap", reflect.TypeOf((*MockEthClient)(nil).SuggestGasTipCap), arg0)
+}
+
+// TransactionReceipt mocks base method.
+func (m *MockEthClient) TransactionReceipt(arg0 context.Context, arg1 common.Hash) (*types.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransactionReceipt", arg0, arg1)
+	ret0, _ := ret[0].(*types.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransactionReceipt indicates an expected call of TransactionReceipt.
+func (mr *MockEthClientMockRecorder) TransactionReceipt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionReceipt", reflect.TypeOf((*MockEthClient)(nil).TransactionReceipt), arg0, arg1)
+}
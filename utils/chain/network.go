@@ -0,0 +1,112 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/rpc"
+)
+
+var (
+	ErrNetworkMismatch = errors.New("network mismatch")
+)
+
+// Implemented by info.Client
+type NetworkIDClient interface {
+	GetNetworkID(ctx context.Context, options ...rpc.Option) (uint32, error)
+}
+
+// Implemented by ethclient.Client
+type ChainIDClient interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Address prefixes of the Avalanche networks the indexer can be pointed at
+// for testing by the chain ID of their C-chain. Their network IDs are not
+// unique, e.g. 5 is the network ID of Fuji and of songbird.
+var avalancheAddressHRPs = map[int]string{
+	43114: constants.MainnetHRP,
+	43113: constants.FujiHRP,
+}
+
+// Checks that the node is connected to the network with the network ID of the
+// config (see config.ChainConfig.GetNetworkID). The eth RPC is checked with CheckEthChainID by the
+// cronjobs using it, so that the indexer starts while the eth RPC is
+// unavailable.
+func CheckNetwork(cfg *config.ChainConfig) error {
+	networkID, err := cfg.GetNetworkID()
+	if err != nil {
+		return err
+	}
+	if err := checkAddressHRP(cfg.ChainID, cfg.ChainAddressHRP); err != nil {
+		return err
+	}
+	if _, err := FeeAssetID(cfg); err != nil {
+		return err
+	}
+	var infoClient NetworkIDClient
+	if len(cfg.NodeURL) > 0 {
+		infoClient = info.NewClient(cfg.NodeURL)
+	}
+	return checkNetworkIDs(infoClient, nil, int(networkID), ClientOptions(cfg.ApiKey)...)
+}
+
+// Avalanche networks have fixed address prefixes, e.g. "avax" for the mainnet
+func checkAddressHRP(chainID int, hrp string) error {
+	if expected, ok := avalancheAddressHRPs[chainID]; ok && hrp != expected {
+		return fmt.Errorf("%w: address_hrp of chain id %d is %q, configured %q", ErrNetworkMismatch, chainID, expected, hrp)
+	}
+	return nil
+}
+
+// ID of the asset paying the fees (asset_id), ids.Empty if all assets are
+// counted in the fees
+func FeeAssetID(cfg *config.ChainConfig) (ids.ID, error) {
+	if len(cfg.AssetID) == 0 {
+		return ids.Empty, nil
+	}
+	assetID, err := ids.FromString(cfg.AssetID)
+	if err != nil {
+		return ids.Empty, fmt.Errorf("invalid asset_id %q: %w", cfg.AssetID, err)
+	}
+	return assetID, nil
+}
+
+// Checks that the eth RPC is connected to the network configured with chain_id
+func CheckEthChainID(ethClient ChainIDClient, expected int) error {
+	return checkNetworkIDs(nil, ethClient, expected)
+}
+
+// Checks the network ID of the node or the chain ID of the eth RPC against the
+// expected value, clients that are nil are not checked. The two IDs are not
+// compared with each other since they differ for some networks.
+func checkNetworkIDs(infoClient NetworkIDClient, ethClient ChainIDClient, expected int, opts ...rpc.Option) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), NodeInfoTimeout)
+	defer cancelCtx()
+
+	if infoClient != nil {
+		networkID, err := infoClient.GetNetworkID(ctx, opts...)
+		if err != nil {
+			return fmt.Errorf("cannot fetch network id of the node: %w", err)
+		}
+		if int64(networkID) != int64(expected) {
+			return fmt.Errorf("%w: node network id is %d, configured network id is %d", ErrNetworkMismatch, networkID, expected)
+		}
+	}
+	if ethClient != nil {
+		chainID, err := ethClient.ChainID(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot fetch chain id of the eth RPC: %w", err)
+		}
+		if !chainID.IsInt64() || chainID.Int64() != int64(expected) {
+			return fmt.Errorf("%w: eth RPC chain id is %s, configured chain id is %d", ErrNetworkMismatch, chainID, expected)
+		}
+	}
+	return nil
+}
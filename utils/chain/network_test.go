@@ -0,0 +1,91 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+type testNetworkIDClient struct {
+	networkID uint32
+	err       error
+}
+
+func (c testNetworkIDClient) GetNetworkID(ctx context.Context, options ...rpc.Option) (uint32, error) {
+	return c.networkID, c.err
+}
+
+type testChainIDClient struct {
+	chainID int64
+}
+
+func (c testChainIDClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(c.chainID), nil
+}
+
+func TestCheckNetworkIDs(t *testing.T) {
+	require.NoError(t, checkNetworkIDs(testNetworkIDClient{networkID: 114}, testChainIDClient{chainID: 114}, 114))
+	require.NoError(t, checkNetworkIDs(nil, nil, 114))
+
+	err := checkNetworkIDs(testNetworkIDClient{networkID: 16}, testChainIDClient{chainID: 114}, 114)
+	require.ErrorIs(t, err, ErrNetworkMismatch)
+	require.Contains(t, err.Error(), "node network id is 16")
+
+	err = checkNetworkIDs(testNetworkIDClient{networkID: 114}, testChainIDClient{chainID: 19}, 114)
+	require.ErrorIs(t, err, ErrNetworkMismatch)
+	require.Contains(t, err.Error(), "eth RPC chain id is 19")
+
+	err = checkNetworkIDs(testNetworkIDClient{err: errors.New("connection refused")}, nil, 114)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrNetworkMismatch))
+}
+
+func TestCheckAddressHRP(t *testing.T) {
+	require.NoError(t, checkAddressHRP(43114, "avax"))
+	require.NoError(t, checkAddressHRP(43113, "fuji"))
+	require.NoError(t, checkAddressHRP(114, "costwo"))
+	// songbird has the network ID of Fuji
+	require.NoError(t, checkAddressHRP(19, "songbird"))
+
+	err := checkAddressHRP(43114, "flare")
+	require.ErrorIs(t, err, ErrNetworkMismatch)
+	require.Contains(t, err.Error(), `address_hrp of chain id 43114 is "avax"`)
+}
+
+func TestFeeAssetID(t *testing.T) {
+	assetID, err := FeeAssetID(&config.ChainConfig{})
+	require.NoError(t, err)
+	require.Equal(t, ids.Empty, assetID)
+
+	// AVAX of the Avalanche mainnet
+	assetID, err = FeeAssetID(&config.ChainConfig{AssetID: "FvwEAhmxKfeiG8SnEvq42hc6whRyY3EFYAvebMqDNDGCgxN5Z"})
+	require.NoError(t, err)
+	require.NotEqual(t, ids.Empty, assetID)
+
+	_, err = FeeAssetID(&config.ChainConfig{AssetID: "AVAX"})
+	require.Error(t, err)
+}
+
+func TestGetNetworkID(t *testing.T) {
+	for chainID, expected := range map[int]uint32{14: 14, 19: 5, 16: 7, 114: 114} {
+		networkID, err := config.ChainConfig{ChainID: chainID}.GetNetworkID()
+		require.NoError(t, err)
+		require.Equal(t, expected, networkID)
+	}
+
+	networkID, err := config.ChainConfig{ChainID: 43114, NetworkID: 1}.GetNetworkID()
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), networkID)
+
+	_, err = config.ChainConfig{ChainID: 43114}.GetNetworkID()
+	require.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package chain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// Normalizes a node ID with or without the "NodeID-" prefix to the stored
+// format, the prefixed CB58 encoding parsed by ids.NodeIDFromString. The
+// checksum of the encoding is verified.
+func NormalizeNodeID(nodeID string) (string, error) {
+	encoded := strings.TrimPrefix(strings.TrimSpace(nodeID), ids.NodeIDPrefix)
+	id, err := ids.ShortFromString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid node ID '%s': %w", nodeID, err)
+	}
+	return ids.NodeID(id).String(), nil
+}
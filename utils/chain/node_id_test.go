@@ -0,0 +1,31 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeNodeID(t *testing.T) {
+	const nodeID = "NodeID-MFrZFVCXPv5iCn6M9K6XduxGTYp891xXZ"
+
+	for _, valid := range []string{nodeID, "MFrZFVCXPv5iCn6M9K6XduxGTYp891xXZ", " " + nodeID + "\n"} {
+		normalized, err := NormalizeNodeID(valid)
+		require.NoError(t, err, valid)
+		require.Equal(t, nodeID, normalized)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"NodeID-",
+		"NodeID-MFrZFVCXPv5iCn6M9K6XduxGTYp891xXY", // checksum
+		"NodeID-MFrZFVCXPv5iCn6M9K6XduxGTYp891x",   // length
+		"nodeid-MFrZFVCXPv5iCn6M9K6XduxGTYp891xXZ",
+	} {
+		_, err := NormalizeNodeID(invalid)
+		require.Error(t, err, invalid)
+	}
+}
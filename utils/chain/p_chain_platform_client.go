@@ -0,0 +1,154 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	avaJson "github.com/ava-labs/avalanchego/utils/json"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/pkg/errors"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Copy-paste from
+//
+//	"github.com/ava-labs/avalanchego/vms/platformvm/service"
+//
+// of newer avalanchego versions, the route is not in v1.9.x
+type GetBlockByHeightArgs struct {
+	Height   avaJson.Uint64      `json:"height"`
+	Encoding formatting.Encoding `json:"encoding"`
+}
+
+type GetHeightResponse struct {
+	Height avaJson.Uint64 `json:"height"`
+}
+
+// Implements IndexerClient for the P-chain with the platform API of the node
+// (platform.getHeight and platform.getBlockByHeight), for nodes without the
+// index API. The container at index i is the block with height i+1, see
+// PChainContainerIndex. Containers are P-chain blocks without the proposervm
+// blocks wrapping them, so their IDs and parents differ from the containers of
+// the index API. The timestamp of a container is the time of the block, which
+// only Banff blocks have, so earlier blocks cannot be fetched.
+type PChainPlatformClient struct {
+	client jsonrpc.RPCClient
+	parser *Parser
+}
+
+func NewPChainPlatformClient(endpoint string, parser *Parser) *PChainPlatformClient {
+	return &PChainPlatformClient{
+		client: jsonrpc.NewClient(endpoint),
+		parser: parser,
+	}
+}
+
+func (c *PChainPlatformClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	height, err := c.getHeight(ctx)
+	if err != nil {
+		return indexer.Container{}, 0, err
+	}
+	if height == 0 {
+		return indexer.Container{}, 0, errors.New("no block is accepted after the genesis")
+	}
+	index := PChainContainerIndex(height)
+	container, err := c.GetContainerByIndex(ctx, index)
+	return container, index, err
+}
+
+func (c *PChainPlatformClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	height := index + 1
+	var reply api.GetBlockResponse
+	err := c.call(ctx, "platform.getBlockByHeight", GetBlockByHeightArgs{
+		Height:   avaJson.Uint64(height),
+		Encoding: formatting.Hex,
+	}, &reply)
+	if err != nil {
+		return indexer.Container{}, err
+	}
+	return c.newContainer(&reply)
+}
+
+// Containers from index from up to the last accepted one, at most numToFetch
+func (c *PChainPlatformClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	height, err := c.getHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var containers []indexer.Container
+	for index := from; index < from+uint64(numToFetch) && index+1 <= height; index++ {
+		container, err := c.GetContainerByIndex(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// Index of the container with the given block ID (platform.getBlock)
+func (c *PChainPlatformClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	var reply api.GetBlockResponse
+	err := c.call(ctx, "platform.getBlock", api.GetBlockArgs{BlockID: id, Encoding: formatting.Hex}, &reply)
+	if err != nil {
+		return 0, err
+	}
+	blkBytes, err := decodeHexBlock(&reply)
+	if err != nil {
+		return 0, err
+	}
+	blk, err := c.parser.ParsePChainBlock(blkBytes)
+	if err != nil {
+		return 0, err
+	}
+	return PChainContainerIndex(blk.Height()), nil
+}
+
+func (c *PChainPlatformClient) getHeight(ctx context.Context) (uint64, error) {
+	var reply GetHeightResponse
+	if err := c.call(ctx, "platform.getHeight", struct{}{}, &reply); err != nil {
+		return 0, err
+	}
+	return uint64(reply.Height), nil
+}
+
+func (c *PChainPlatformClient) call(ctx context.Context, method string, params interface{}, reply interface{}) error {
+	if err := c.client.CallFor(ctx, reply, method, params); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+func decodeHexBlock(reply *api.GetBlockResponse) ([]byte, error) {
+	blkHex, ok := reply.Block.(string)
+	if !ok {
+		return nil, errors.Errorf("unexpected block encoding %T", reply.Block)
+	}
+	return formatting.Decode(formatting.Hex, blkHex)
+}
+
+func (c *PChainPlatformClient) newContainer(reply *api.GetBlockResponse) (indexer.Container, error) {
+	blkBytes, err := decodeHexBlock(reply)
+	if err != nil {
+		return indexer.Container{}, err
+	}
+	blk, err := c.parser.ParsePChainBlock(blkBytes)
+	if err != nil {
+		return indexer.Container{}, err
+	}
+	banffBlk, ok := blk.(blocks.BanffBlock)
+	if !ok {
+		return indexer.Container{}, fmt.Errorf(
+			"block %d (%T) has no timestamp, blocks before the Banff upgrade cannot be indexed with the platform API",
+			blk.Height(), blk)
+	}
+	return indexer.Container{
+		ID:        blk.ID(),
+		Bytes:     blkBytes,
+		Timestamp: banffBlk.Timestamp().UnixNano(),
+	}, nil
+}
@@ -0,0 +1,104 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPChainPlatformClient(t *testing.T) {
+	blockTime := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	apricotBlk, err := blocks.NewApricotStandardBlock(ids.Empty, 1, nil)
+	require.NoError(t, err)
+	banffBlk, err := blocks.NewBanffStandardBlock(blockTime, apricotBlk.ID(), 2, nil)
+	require.NoError(t, err)
+	nextBlk, err := blocks.NewBanffStandardBlock(blockTime.Add(time.Second), banffBlk.ID(), 3, nil)
+	require.NoError(t, err)
+	chainBlocks := []blocks.Block{apricotBlk, banffBlk, nextBlk}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+			Params struct {
+				Height  string `json:"height"`
+				BlockID string `json:"blockID"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		var result interface{}
+		blockResult := func(blk blocks.Block) interface{} {
+			blkHex, err := formatting.Encode(formatting.Hex, blk.Bytes())
+			require.NoError(t, err)
+			return map[string]string{"block": blkHex, "encoding": "hex"}
+		}
+		switch request.Method {
+		case "platform.getHeight":
+			result = map[string]string{"height": fmt.Sprint(len(chainBlocks))}
+		case "platform.getBlockByHeight":
+			for _, blk := range chainBlocks {
+				if fmt.Sprint(blk.Height()) == request.Params.Height {
+					result = blockResult(blk)
+				}
+			}
+		case "platform.getBlock":
+			for _, blk := range chainBlocks {
+				if blk.ID().String() == request.Params.BlockID {
+					result = blockResult(blk)
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if result == nil {
+			w.Write([]byte(`{"jsonrpc": "2.0", "id": 0, "error": {"code": -32000, "message": "not found"}}`))
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 0, "result": result}))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewPChainPlatformClient(server.URL, NewParser())
+	last, index, err := client.GetLastAccepted(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), index)
+	require.Equal(t, nextBlk.ID(), last.ID)
+	require.Equal(t, blockTime.Add(time.Second).UnixNano(), last.Timestamp)
+
+	// The range ends at the last accepted container
+	containers, err := client.GetContainerRange(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, containers, 2)
+	require.Equal(t, banffBlk.ID(), containers[0].ID)
+	require.Equal(t, banffBlk.Bytes(), containers[0].Bytes)
+
+	// Containers are parsed without proposervm blocks
+	parser := NewParser()
+	blk, err := parser.ParsePChainBlock(containers[0].Bytes)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), blk.Height())
+	header, err := parser.ParsePChainBlockHeader(containers[0].Bytes)
+	require.NoError(t, err)
+	require.Equal(t, apricotBlk.ID(), header.ParentID)
+
+	index, err = client.GetIndex(ctx, nextBlk.ID())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), index)
+
+	// Apricot blocks have no timestamp
+	_, err = client.GetContainerByIndex(ctx, 0)
+	require.ErrorContains(t, err, "Banff")
+	_, err = client.GetContainerByIndex(ctx, 5)
+	require.ErrorContains(t, err, "not found")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ava-labs/avalanchego/api"
 	"github.com/ava-labs/avalanchego/ids"
@@ -25,27 +26,32 @@ type GetRewardUTXOsReply struct {
 }
 
 type RPCClient interface {
-	GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, error)
-	GetTx(id ids.ID) (*api.GetTxReply, error)
+	GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error)
+	GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error)
 }
 
+// Client of the platform API, each call is limited by timeout (no limit if zero)
+// or by the deadline of its ctx if it is earlier
 type AvalancheRPCClient struct {
-	client jsonrpc.RPCClient
+	client  jsonrpc.RPCClient
+	timeout time.Duration
 }
 
-func NewAvalancheRPCClient(endpoint string) *AvalancheRPCClient {
+func NewAvalancheRPCClient(endpoint string, timeout time.Duration) *AvalancheRPCClient {
 	return &AvalancheRPCClient{
-		client: jsonrpc.NewClient(endpoint),
+		client:  jsonrpc.NewClient(endpoint),
+		timeout: timeout,
 	}
 }
 
-func (c *AvalancheRPCClient) GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, error) {
+func (c *AvalancheRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error) {
 	params := api.GetTxArgs{
 		TxID:     id,
 		Encoding: formatting.Hex,
 	}
 	reply := &GetRewardUTXOsReply{}
-	ctx := context.Background()
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
 	response, err := c.client.Call(ctx, "platform.getRewardUTXOs", params)
 	if err != nil {
 		return nil, err
@@ -57,13 +63,14 @@ func (c *AvalancheRPCClient) GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, er
 	return reply, nil
 }
 
-func (c *AvalancheRPCClient) GetTx(id ids.ID) (*api.GetTxReply, error) {
+func (c *AvalancheRPCClient) GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
 	params := api.GetTxArgs{
 		TxID:     id,
 		Encoding: formatting.Hex,
 	}
 	reply := &api.GetTxReply{}
-	ctx := context.Background()
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
 	response, err := c.client.Call(ctx, "platform.getTx", params)
 	if err != nil {
 		return nil, err
@@ -116,14 +123,14 @@ func NewRecordedRPCClient(fileName string) (*RecordedRPCClient, error) {
 	return &RecordedRPCClient{txIDToRecording: txIDToRecording}, nil
 }
 
-func (c *RecordedRPCClient) GetRewardUTXOs(id ids.ID) (*GetRewardUTXOsReply, error) {
+func (c *RecordedRPCClient) GetRewardUTXOs(ctx context.Context, id ids.ID) (*GetRewardUTXOsReply, error) {
 	if reply, ok := c.txIDToRecording[id.String()]; ok {
 		return reply.toGetRewardUTXOsReply(), nil
 	}
 	return nil, fmt.Errorf("no recording for tx %v", id)
 }
 
-func (c *RecordedRPCClient) GetTx(id ids.ID) (*api.GetTxReply, error) {
+func (c *RecordedRPCClient) GetTx(ctx context.Context, id ids.ID) (*api.GetTxReply, error) {
 	if reply, ok := c.txIDToRecording[id.String()]; ok {
 		return reply.toGetTxReply(), nil
 	}
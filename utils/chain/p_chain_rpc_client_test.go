@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ava-labs/avalanchego/ids"
@@ -20,22 +21,22 @@ func TestRPCClient(t *testing.T) {
 		t.Fatal("Wrong ID")
 	}
 
-	_, err = client.GetTx(id1)
+	_, err = client.GetTx(context.Background(), id1)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = client.GetTx(id2)
+	_, err = client.GetTx(context.Background(), id2)
 	if err == nil {
 		t.Fatal("Expected error")
 	}
 
-	_, err = client.GetTx(id3)
+	_, err = client.GetTx(context.Background(), id3)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	utxos1, err := client.GetRewardUTXOs(id1)
+	utxos1, err := client.GetRewardUTXOs(context.Background(), id1)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,7 +44,7 @@ func TestRPCClient(t *testing.T) {
 		t.Fatal("Expected 0 utxos")
 	}
 
-	utxos3, err := client.GetRewardUTXOs(id3)
+	utxos3, err := client.GetRewardUTXOs(context.Background(), id3)
 	if err != nil {
 		t.Fatal(err)
 	}
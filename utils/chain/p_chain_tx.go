@@ -6,7 +6,6 @@ import (
 	"github.com/ava-labs/avalanchego/utils/crypto"
 	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
 	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
-	"github.com/ava-labs/avalanchego/vms/proposervm/block"
 	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
 	"github.com/pkg/errors"
 )
@@ -19,14 +18,10 @@ var (
 // For a given block (byte array) return a list of public keys for
 // signatures of inputs of the transaction in this block
 // Block must be of type "ApricotProposalBlock"
-func PublicKeysFromPChainBlock(blockBytes []byte) ([][]crypto.PublicKey, error) {
-	blk, err := block.Parse(blockBytes)
+func PublicKeysFromPChainBlock(parser *Parser, blockBytes []byte) ([][]crypto.PublicKey, error) {
+	innerBlk, err := parser.ParsePChainBlock(blockBytes)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse block")
-	}
-	innerBlk, err := blocks.Parse(blocks.GenesisCodec, blk.Block())
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse inner block")
+		return nil, err
 	}
 	if propBlk, ok := innerBlk.(*blocks.ApricotProposalBlock); ok {
 		return PublicKeysFromPChainTx(propBlk.Tx)
@@ -58,3 +53,37 @@ func PublicKeysFromPChainTx(tx *txs.Tx) ([][]crypto.PublicKey, error) {
 	}
 	return response, nil
 }
+
+// Addresses (formatted with the prefix hrp) of the keys which signed the
+// transaction, by credential and signature, recovered from the signatures of the
+// secp256k1 credentials. Other credentials have no addresses.
+func PChainTxSignerAddresses(tx *txs.Tx, hrp string) ([][]string, error) {
+	factory := crypto.FactorySECP256K1R{}
+	unsignedBytes := tx.Unsigned.Bytes()
+	response := make([][]string, len(tx.Creds))
+	for ci, cred := range tx.Creds {
+		secpCred, ok := cred.(*secp256k1fx.Credential)
+		if !ok {
+			continue
+		}
+		response[ci] = make([]string, len(secpCred.Sigs))
+		for si, sig := range secpCred.Sigs {
+			pubKey, err := factory.RecoverPublicKey(unsignedBytes, sig[:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to recover public key from cred %d sig %d: %w", ci, si, err)
+			}
+			response[ci][si], err = FormatAddressBytes(hrp, pubKey.Address().Bytes())
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return response, nil
+}
+
+// Index of the P-chain block with the given height in the avalanchego indexer.
+// The genesis block (height 0) is not indexed, the first indexed container is
+// the block with height 1.
+func PChainContainerIndex(height uint64) uint64 {
+	return height - 1
+}
@@ -0,0 +1,116 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/formatting"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/pkg/errors"
+)
+
+// Network ID without an address prefix, avalanchego formats its addresses
+// with the fallback prefix, which is replaced with the prefix of the indexed
+// network
+const pChainTxJSONNetworkID = 0
+
+// Transaction with the given ID of the P-chain block, nil if the block does not
+// contain it. The transactions of Banff proposal blocks preceding the proposal
+// transaction are included.
+func PChainBlockTx(parser *Parser, blockBytes []byte, txID ids.ID) (*txs.Tx, error) {
+	blk, err := parser.ParsePChainBlock(blockBytes)
+	if err != nil {
+		return nil, err
+	}
+	blkTxs := blk.Txs()
+	if proposalBlk, ok := blk.(*blocks.BanffProposalBlock); ok {
+		blkTxs = append(append([]*txs.Tx{}, proposalBlk.Transactions...), blkTxs...)
+	}
+	for _, tx := range blkTxs {
+		if tx.ID() == txID {
+			return tx, nil
+		}
+	}
+	return nil, nil
+}
+
+// Fetches the transaction with the given ID from the node (platform.getTx)
+func FetchPChainTx(ctx context.Context, client RPCClient, parser *Parser, txID ids.ID) (*txs.Tx, error) {
+	reply, err := client.GetTx(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	txHex, ok := reply.Tx.(string)
+	if !ok {
+		return nil, errors.Errorf("unexpected transaction encoding %T", reply.Tx)
+	}
+	txData, err := formatting.Decode(formatting.Hex, txHex)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParsePChainTx(txData)
+}
+
+// JSON of the transaction with all fields of the unsigned transaction and the
+// credentials, as returned by platform.getTx with the json encoding, with the
+// addresses formatted with the prefix hrp. The transaction is initialized for
+// formatting, it should not be used otherwise afterwards.
+func PChainTxJSON(tx *txs.Tx, hrp string) (json.RawMessage, error) {
+	aliaser := ids.NewAliaser()
+	if err := aliaser.Alias(constants.PlatformChainID, "P"); err != nil {
+		return nil, err
+	}
+	tx.Unsigned.InitCtx(&snow.Context{
+		NetworkID: pChainTxJSONNetworkID,
+		ChainID:   constants.PlatformChainID,
+		BCLookup:  aliaser,
+	})
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return nil, errors.Wrap(err, "json.Marshal")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	value, err = reformatAddresses(value, constants.GetHRP(pChainTxJSONNetworkID), hrp)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// Replaces the prefix from of the addresses in the JSON value with to
+func reformatAddresses(value interface{}, from string, to string) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if v[key], err = reformatAddresses(item, from, to); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if v[i], err = reformatAddresses(item, from, to); err != nil {
+				return nil, err
+			}
+		}
+	case string:
+		chainAlias, addrHRP, addr, parseErr := address.Parse(v)
+		if parseErr != nil || addrHRP != from {
+			return v, nil
+		}
+		return address.Format(chainAlias, to, addr)
+	}
+	return value, nil
+}
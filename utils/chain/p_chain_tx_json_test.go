@@ -0,0 +1,57 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPChainTxJSON(t *testing.T) {
+	client, err := PChainTestRPCClient()
+	require.NoError(t, err)
+	txID, err := ids.FromString("274PsK9Wn5GgfihqK8iWHfCYxEDDTgvKWvfmmzgV7kvaK7vNCv")
+	require.NoError(t, err)
+
+	tx, err := FetchPChainTx(context.Background(), client, NewParser(), txID)
+	require.NoError(t, err)
+	require.Equal(t, txID, tx.ID())
+
+	data, err := PChainTxJSON(tx, "localflare")
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Contains(t, decoded, "unsignedTx")
+	require.Contains(t, decoded, "credentials")
+	require.Contains(t, string(data), `"P-localflare1`)
+	require.NotContains(t, string(data), "custom1")
+}
+
+func TestPChainBlockTx(t *testing.T) {
+	client, err := PChainTestClient()
+	require.NoError(t, err)
+	containers, err := client.GetContainerRange(context.Background(), 0, 20)
+	require.NoError(t, err)
+
+	parser := NewParser()
+	found := 0
+	for _, c := range containers {
+		blk, err := parser.ParsePChainBlock(c.Bytes)
+		require.NoError(t, err)
+		for _, blkTx := range blk.Txs() {
+			tx, err := PChainBlockTx(parser, c.Bytes, blkTx.ID())
+			require.NoError(t, err)
+			require.Equal(t, blkTx.ID(), tx.ID())
+			_, err = PChainTxJSON(tx, "localflare")
+			require.NoError(t, err)
+			found++
+		}
+	}
+	require.Positive(t, found)
+
+	tx, err := PChainBlockTx(parser, containers[0].Bytes, ids.GenerateTestID())
+	require.NoError(t, err)
+	require.Nil(t, tx)
+}
@@ -0,0 +1,27 @@
+package chain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPChainTxSignerAddresses(t *testing.T) {
+	client, err := PChainTestRPCClient()
+	require.NoError(t, err)
+	txID, err := ids.FromString("274PsK9Wn5GgfihqK8iWHfCYxEDDTgvKWvfmmzgV7kvaK7vNCv")
+	require.NoError(t, err)
+	tx, err := FetchPChainTx(context.Background(), client, NewParser(), txID)
+	require.NoError(t, err)
+
+	signers, err := PChainTxSignerAddresses(tx, "localflare")
+	require.NoError(t, err)
+	// One credential with one signature for each of the inputs, all signed by
+	// the owner of the stake
+	require.Len(t, signers, 6)
+	for _, credSigners := range signers {
+		require.Equal(t, []string{"localflare18jma8ppw3nhx5r4ap8clazz0dps7rv5uj3gy4v"}, credSigners)
+	}
+}
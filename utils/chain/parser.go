@@ -0,0 +1,179 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/engine/avalanche/vertex"
+	avmTxs "github.com/ava-labs/avalanchego/vms/avm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks"
+	"github.com/ava-labs/avalanchego/vms/platformvm/genesis"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/proposervm/block"
+	"github.com/ava-labs/avalanchego/wallet/chain/x"
+)
+
+// Parser of serialized P-chain and X-chain containers. The serialization format
+// depends on the avalanchego version of the node, so each supported version range
+// has its own implementation (see containerParsers). All indexer code should parse
+// containers with the Parser of the network, which uses the implementation
+// selected for the version of its node at startup (see CheckNodeVersion).
+type ContainerParser interface {
+	ParsePChainBlock(bytes []byte) (blocks.Block, error)
+	ParsePChainBlockHeader(bytes []byte) (PChainBlockHeader, error)
+	ParsePChainTx(bytes []byte) (*txs.Tx, error)
+	ParseXChainVertex(bytes []byte) (vertex.StatelessVertex, error)
+	ParseXChainTx(bytes []byte) (*avmTxs.Tx, error)
+}
+
+// Data of the proposervm block wrapping a P-chain block
+type PChainBlockHeader struct {
+	// ID of the parent container
+	ParentID ids.ID
+	// Empty for blocks built without a proposer
+	Proposer ids.NodeID
+}
+
+// Error returned when a container cannot be deserialized. The message lists the
+// node versions supported by the parser, since the most common cause of such
+// errors is a node running an unsupported avalanchego version.
+type ParseError struct {
+	Object string
+	Err    error
+	// Node versions supported by the parser and the detected node version
+	Versions    string
+	NodeVersion string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cannot parse %s (supported node versions are %s, detected %s): %v",
+		e.Object, e.Versions, e.NodeVersion, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parser for avalanchego 1.9.x nodes (Apricot and Banff block formats)
+type containerParserV1_9 struct{}
+
+// Containers of the index API are proposervm blocks wrapping the P-chain blocks,
+// containers of the platform API (see PChainPlatformClient) are P-chain blocks
+func (containerParserV1_9) ParsePChainBlock(bytes []byte) (blocks.Block, error) {
+	blk, err := block.Parse(bytes)
+	if err != nil {
+		if innerBlk, innerErr := blocks.Parse(blocks.GenesisCodec, bytes); innerErr == nil {
+			return innerBlk, nil
+		}
+		return nil, err
+	}
+	return blocks.Parse(blocks.GenesisCodec, blk.Block())
+}
+
+// P-chain blocks without a proposervm block have their own parent and no proposer
+func (containerParserV1_9) ParsePChainBlockHeader(bytes []byte) (PChainBlockHeader, error) {
+	blk, err := block.Parse(bytes)
+	if err != nil {
+		if innerBlk, innerErr := blocks.Parse(blocks.GenesisCodec, bytes); innerErr == nil {
+			return PChainBlockHeader{ParentID: innerBlk.Parent()}, nil
+		}
+		return PChainBlockHeader{}, err
+	}
+	header := PChainBlockHeader{ParentID: blk.ParentID()}
+	if signedBlk, ok := blk.(block.SignedBlock); ok {
+		header.Proposer = signedBlk.Proposer()
+	}
+	return header, nil
+}
+
+func (containerParserV1_9) ParsePChainTx(bytes []byte) (*txs.Tx, error) {
+	return txs.Parse(genesis.Codec, bytes)
+}
+
+func (containerParserV1_9) ParseXChainVertex(bytes []byte) (vertex.StatelessVertex, error) {
+	return vertex.Parse(bytes)
+}
+
+func (containerParserV1_9) ParseXChainTx(bytes []byte) (*avmTxs.Tx, error) {
+	return x.Parser.ParseGenesisTx(bytes)
+}
+
+// Container parser of a network. Each indexed network has its own parser, as
+// the nodes of the networks may run different avalanchego versions. The newest
+// implementation is used until the version of the node is detected (see
+// CheckNodeVersion).
+type Parser struct {
+	parser      versionedParser
+	nodeVersion string
+}
+
+// Parser with the newest implementation, for containers of nodes whose version
+// is not checked
+func NewParser() *Parser {
+	return &Parser{
+		parser:      containerParsers[len(containerParsers)-1],
+		nodeVersion: "unknown",
+	}
+}
+
+type parserContextKey struct{}
+
+// Context carrying the parser of the network, for code which only gets a
+// context, e.g. the migrations
+func ContextWithParser(ctx context.Context, parser *Parser) context.Context {
+	return context.WithValue(ctx, parserContextKey{}, parser)
+}
+
+// Parser of the network of the context, the newest parser if the context has
+// none
+func ParserFromContext(ctx context.Context) *Parser {
+	if parser, ok := ctx.Value(parserContextKey{}).(*Parser); ok {
+		return parser
+	}
+	return NewParser()
+}
+
+func (p *Parser) parseError(object string, err error) error {
+	return &ParseError{Object: object, Err: err, Versions: p.parser.versions(), NodeVersion: p.nodeVersion}
+}
+
+func (p *Parser) ParsePChainBlock(bytes []byte) (blocks.Block, error) {
+	blk, err := p.parser.parser.ParsePChainBlock(bytes)
+	if err != nil {
+		return nil, p.parseError("P-chain block", err)
+	}
+	return blk, nil
+}
+
+func (p *Parser) ParsePChainBlockHeader(bytes []byte) (PChainBlockHeader, error) {
+	header, err := p.parser.parser.ParsePChainBlockHeader(bytes)
+	if err != nil {
+		return PChainBlockHeader{}, p.parseError("P-chain block header", err)
+	}
+	return header, nil
+}
+
+func (p *Parser) ParsePChainTx(bytes []byte) (*txs.Tx, error) {
+	tx, err := p.parser.parser.ParsePChainTx(bytes)
+	if err != nil {
+		return nil, p.parseError("P-chain transaction", err)
+	}
+	return tx, nil
+}
+
+func (p *Parser) ParseXChainVertex(bytes []byte) (vertex.StatelessVertex, error) {
+	vtx, err := p.parser.parser.ParseXChainVertex(bytes)
+	if err != nil {
+		return nil, p.parseError("X-chain vertex", err)
+	}
+	return vtx, nil
+}
+
+func (p *Parser) ParseXChainTx(bytes []byte) (*avmTxs.Tx, error) {
+	tx, err := p.parser.parser.ParseXChainTx(bytes)
+	if err != nil {
+		return nil, p.parseError("X-chain transaction", err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,49 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectContainerParser(t *testing.T) {
+	p := NewParser()
+	require.NoError(t, p.Select("avalanche/1.9.0"))
+	require.NoError(t, p.Select("avalanche/1.9.11"))
+	require.Equal(t, "avalanche/1.9.11", p.nodeVersion)
+
+	for _, v := range []string{"avalanche/1.8.6", "avalanche/1.10.0", "avalanche/2.0.0"} {
+		err := p.Select(v)
+		require.ErrorIs(t, err, ErrUnsupportedNodeVersion, v)
+		require.Contains(t, err.Error(), SupportedNodeVersions())
+	}
+	require.Equal(t, "avalanche/1.9.11", p.nodeVersion)
+
+	err := p.Select("go-flare")
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrUnsupportedNodeVersion))
+
+	// Parsers of other networks are not changed
+	require.Equal(t, "unknown", NewParser().nodeVersion)
+}
+
+func TestParseErrorListsSupportedVersions(t *testing.T) {
+	p := NewParser()
+	require.NoError(t, p.Select("avalanche/1.9.7"))
+	_, err := p.ParsePChainBlock([]byte{0, 1, 2})
+	require.Error(t, err)
+
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, "P-chain block", parseErr.Object)
+	require.Contains(t, err.Error(), "[avalanche/1.9.0, avalanche/1.10.0)")
+	require.Contains(t, err.Error(), "detected avalanche/1.9.7")
+
+	_, err = p.ParseXChainVertex([]byte{})
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, "X-chain vertex", parseErr.Object)
+}
@@ -0,0 +1,73 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	avaJson "github.com/ava-labs/avalanchego/utils/json"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Client of the current stakers of the primary network
+type StakersClient interface {
+	// Weights of the current validators and delegators by transaction ID
+	GetCurrentStakerWeights(ctx context.Context) (map[string]uint64, error)
+}
+
+// Each call is limited by timeout, no limit if zero
+type AvalancheStakersClient struct {
+	client  jsonrpc.RPCClient
+	timeout time.Duration
+}
+
+func NewAvalancheStakersClient(endpoint string, timeout time.Duration) *AvalancheStakersClient {
+	return &AvalancheStakersClient{
+		client:  jsonrpc.NewClient(endpoint),
+		timeout: timeout,
+	}
+}
+
+// Validator or delegator in the platform.getCurrentValidators reply. Older node
+// versions report the weight of permissionless stakers as stakeAmount.
+type currentStaker struct {
+	TxID        ids.ID          `json:"txID"`
+	Weight      *avaJson.Uint64 `json:"weight"`
+	StakeAmount *avaJson.Uint64 `json:"stakeAmount"`
+	Delegators  []currentStaker `json:"delegators"`
+}
+
+type currentStakers struct {
+	Validators []currentStaker `json:"validators"`
+}
+
+func (c *AvalancheStakersClient) GetCurrentStakerWeights(ctx context.Context) (map[string]uint64, error) {
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
+	response, err := c.client.Call(ctx, "platform.getCurrentValidators")
+	if err != nil {
+		return nil, err
+	}
+	reply := currentStakers{}
+	if err := response.GetObject(&reply); err != nil {
+		return nil, err
+	}
+	weights := make(map[string]uint64)
+	for _, v := range reply.Validators {
+		weights[v.TxID.String()] = v.weight()
+		for _, d := range v.Delegators {
+			weights[d.TxID.String()] = d.weight()
+		}
+	}
+	return weights, nil
+}
+
+func (s *currentStaker) weight() uint64 {
+	if s.Weight != nil {
+		return uint64(*s.Weight)
+	}
+	if s.StakeAmount != nil {
+		return uint64(*s.StakeAmount)
+	}
+	return 0
+}
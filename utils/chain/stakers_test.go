@@ -0,0 +1,33 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCurrentStakerWeights(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": {"validators": [{
+			"txID": "22ewQXuJw8PKQPiqJxwDezQszrNT2GbLyh4oCpCyVCSjAaDp2o",
+			"weight": "2000",
+			"delegators": [{"txID": "2VhbseqzJLTZ1wxBWzWqvgshmAqx8LshT2p8HJP7P6zwz4iZTg", "stakeAmount": "50"}]
+		}]}}`))
+	}))
+	defer server.Close()
+
+	weights, err := NewAvalancheStakersClient(server.URL, time.Second).GetCurrentStakerWeights(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{
+		"22ewQXuJw8PKQPiqJxwDezQszrNT2GbLyh4oCpCyVCSjAaDp2o": 2000,
+		"2VhbseqzJLTZ1wxBWzWqvgshmAqx8LshT2p8HJP7P6zwz4iZTg": 50,
+	}, weights)
+}
@@ -38,3 +38,24 @@ func UptimeTestClient() (*RecordedUptimeClient, error) {
 	}
 	return client, nil
 }
+
+func XChainTestClient() (*RecordedIndexerClient, error) {
+	_, filename, _, _ := runtime.Caller(0)
+	dir, _ := path.Split(filename)
+	verticesFile := path.Join(dir, "../../resources/test/x_chain_indexer_vertices.json")
+	return NewRecordedIndexerClient(verticesFile)
+}
+
+func XChainTestTxClient() (*RecordedIndexerClient, error) {
+	_, filename, _, _ := runtime.Caller(0)
+	dir, _ := path.Split(filename)
+	txsFile := path.Join(dir, "../../resources/test/x_chain_indexer_txs.json")
+	return NewRecordedIndexerClient(txsFile)
+}
+
+func PChainBanffTestClient() (*RecordedIndexerClient, error) {
+	_, filename, _, _ := runtime.Caller(0)
+	dir, _ := path.Split(filename)
+	blocksFile := path.Join(dir, "../../resources/test/p_chain_indexer_banff_blocks.json")
+	return NewRecordedIndexerClient(blocksFile)
+}
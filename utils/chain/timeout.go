@@ -0,0 +1,52 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+)
+
+// Context of a single call limited by timeout, or by the deadline of ctx if it
+// is earlier. No limit is added if timeout is not positive.
+func callContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// IndexerClient limiting the duration of each call of the wrapped client
+type timeoutIndexerClient struct {
+	client  IndexerClient
+	timeout time.Duration
+}
+
+func NewTimeoutIndexerClient(client IndexerClient, timeout time.Duration) IndexerClient {
+	return &timeoutIndexerClient{client: client, timeout: timeout}
+}
+
+func (c *timeoutIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
+	return c.client.GetContainerRange(ctx, from, numToFetch)
+}
+
+func (c *timeoutIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
+	return c.client.GetLastAccepted(ctx)
+}
+
+func (c *timeoutIndexerClient) GetContainerByIndex(ctx context.Context, index uint64) (indexer.Container, error) {
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
+	return c.client.GetContainerByIndex(ctx, index)
+}
+
+func (c *timeoutIndexerClient) GetIndex(ctx context.Context, id ids.ID) (uint64, error) {
+	ctx, cancel := callContext(ctx, c.timeout)
+	defer cancel()
+	return c.client.GetIndex(ctx, id)
+}
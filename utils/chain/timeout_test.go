@@ -0,0 +1,44 @@
+//go:build !integration
+// +build !integration
+
+package chain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/indexer"
+	"github.com/stretchr/testify/require"
+)
+
+// Waits for the end of the context of each call, only GetLastAccepted and
+// GetContainerRange are implemented
+type slowIndexerClient struct {
+	IndexerClient
+}
+
+func (c slowIndexerClient) GetLastAccepted(ctx context.Context) (indexer.Container, uint64, error) {
+	<-ctx.Done()
+	return indexer.Container{}, 0, ctx.Err()
+}
+
+func (c slowIndexerClient) GetContainerRange(ctx context.Context, from uint64, numToFetch int) ([]indexer.Container, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTimeoutIndexerClient(t *testing.T) {
+	client := NewTimeoutIndexerClient(slowIndexerClient{}, 10*time.Millisecond)
+
+	start := time.Now()
+	_, _, err := client.GetLastAccepted(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	// An earlier deadline of the caller applies
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewTimeoutIndexerClient(slowIndexerClient{}, time.Hour).GetContainerRange(ctx, 0, 1)
+	require.ErrorIs(t, err, context.Canceled)
+}
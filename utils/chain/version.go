@@ -0,0 +1,86 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"flare-indexer/config"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/version"
+)
+
+const (
+	// Timeout of startup checks calling the node info API and the eth RPC
+	NodeInfoTimeout time.Duration = 30 * time.Second
+)
+
+var (
+	ErrUnsupportedNodeVersion = errors.New("unsupported node version")
+
+	// Container parsers by node version, ordered from the oldest to the newest
+	containerParsers = []versionedParser{
+		{
+			min:    &version.Application{Major: 1, Minor: 9, Patch: 0},
+			max:    &version.Application{Major: 1, Minor: 10, Patch: 0},
+			parser: containerParserV1_9{},
+		},
+	}
+)
+
+// Container parser for node versions in [min, max)
+type versionedParser struct {
+	min    *version.Application
+	max    *version.Application
+	parser ContainerParser
+}
+
+func (vp versionedParser) supports(v *version.Application) bool {
+	return v.Compare(vp.min) >= 0 && v.Compare(vp.max) < 0
+}
+
+func (vp versionedParser) versions() string {
+	return fmt.Sprintf("[%s, %s)", vp.min, vp.max)
+}
+
+// Returns the range of node versions supported by the indexer, e.g.
+// "[avalanche/1.9.0, avalanche/1.10.0)"
+func SupportedNodeVersions() string {
+	min := containerParsers[0].min
+	max := containerParsers[len(containerParsers)-1].max
+	return fmt.Sprintf("[%s, %s)", min, max)
+}
+
+// Selects the container parser of p for the given node version (as returned by
+// info.getNodeVersion, e.g. "avalanche/1.9.7"). Returns an error wrapping
+// ErrUnsupportedNodeVersion if no parser supports the version, p is not changed
+// then.
+func (p *Parser) Select(nodeVersion string) error {
+	v, err := version.ParseApplication(nodeVersion)
+	if err != nil {
+		return fmt.Errorf("cannot parse node version %q: %w", nodeVersion, err)
+	}
+	for _, vp := range containerParsers {
+		if vp.supports(v) {
+			p.parser = vp
+			p.nodeVersion = v.String()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %s, supported versions are %s", ErrUnsupportedNodeVersion, v, SupportedNodeVersions())
+}
+
+// Fetches the version of the node by calling "info.getNodeVersion" and selects
+// the container parser of the network for it
+func CheckNodeVersion(cfg *config.ChainConfig, parser *Parser) error {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), NodeInfoTimeout)
+	defer cancelCtx()
+
+	client := info.NewClient(cfg.NodeURL)
+	reply, err := client.GetNodeVersion(ctx, ClientOptions(cfg.ApiKey)...)
+	if err != nil {
+		return fmt.Errorf("cannot fetch node version: %w", err)
+	}
+	return parser.Select(reply.Version)
+}
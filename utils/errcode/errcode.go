@@ -0,0 +1,73 @@
+// Package errcode defines errors with machine-readable codes, returned by the
+// database helpers and the route handlers, so that clients of the services can
+// branch on the code of an error response instead of its message.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Code string
+
+const (
+	// The requested item does not exist
+	NotFound Code = "NOT_FOUND"
+	// The requested item is not indexed yet, the request can be retried later
+	ChainLag Code = "CHAIN_LAG"
+	// The request is invalid
+	Validation Code = "VALIDATION"
+	// Any other error, e.g. a failed database query
+	Internal Code = "INTERNAL"
+)
+
+// Error with a code, the message is meant for the clients and may wrap a cause
+// (e.g. gorm.ErrRecordNotFound, so that errors.Is keeps working)
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error with the code and the message wrapping err
+func Wrap(err error, code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Returns the code of the first Error in the chain of err, Internal if there is
+// none and an empty code if err is nil
+func Of(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Internal
+}
+
+// Returns the message of the first Error in the chain of err, an empty string
+// if there is none, since other errors may reveal internals
+func MessageOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return ""
+}
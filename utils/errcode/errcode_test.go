@@ -0,0 +1,23 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodes(t *testing.T) {
+	cause := errors.New("record not found")
+	err := fmt.Errorf("fetch block: %w", Wrap(cause, NotFound, "block %d not found", 5))
+	require.Equal(t, NotFound, Of(err))
+	require.Equal(t, "block 5 not found", MessageOf(err))
+	require.ErrorIs(t, err, cause)
+	require.Equal(t, "fetch block: block 5 not found: record not found", err.Error())
+
+	require.Equal(t, ChainLag, Of(New(ChainLag, "no block is indexed yet")))
+	require.Equal(t, Internal, Of(cause))
+	require.Empty(t, MessageOf(cause))
+	require.Equal(t, Code(""), Of(nil))
+}
@@ -1,6 +1,7 @@
 package merkle
 
 import (
+	"bytes"
 	"errors"
 	"sort"
 
@@ -9,14 +10,46 @@ import (
 )
 
 var (
-	ErrEmptyTree    = errors.New("empty tree")
-	ErrInvalidIndex = errors.New("invalid index")
-	ErrHashNotFound = errors.New("hash not found")
+	ErrEmptyTree     = errors.New("empty tree")
+	ErrInvalidIndex  = errors.New("invalid index")
+	ErrHashNotFound  = errors.New("hash not found")
+	ErrDuplicateLeaf = errors.New("duplicated leaf")
 )
 
 // Tree implementation with helper functions.
 type Tree struct {
 	tree []common.Hash
+	// Set if the leaves are in descending order, see ModeOpenZeppelin
+	descending bool
+}
+
+// Mode selects how a tree is built from the values, see BuildWithMode. The
+// pairs of nodes are hashed sorted in all modes, so the proofs of all trees
+// are verified by MerkleProof.verify of OpenZeppelin (see VerifyProof).
+type Mode int
+
+const (
+	// The values are the leaves, as in Build(values, false). The trees of the
+	// stakes voted on and mirrored (see staketree) are built in this mode.
+	ModeRaw Mode = iota
+	// The leaves are the keccak256 hashes of the values, as in
+	// Build(values, true).
+	ModeHashed
+	// The tree of StandardMerkleTree of @openzeppelin/merkle-tree, for values
+	// keccak256(abi.encode(...)) of its entries. The leaves are the keccak256
+	// hashes of the values as in ModeHashed (the double hashed leaves of the
+	// library), but they are placed in the tree in descending order, so the
+	// roots and proofs are the ones of the library.
+	ModeOpenZeppelin
+)
+
+// Leaf returns the leaf of the value in the trees built in the mode, e.g. the
+// hash of GetProofFromHash.
+func (m Mode) Leaf(value common.Hash) common.Hash {
+	if m == ModeRaw {
+		return value
+	}
+	return crypto.Keccak256Hash(value.Bytes())
 }
 
 // New creates a new Merkle tree from the given hash values as bytes. It is
@@ -37,26 +70,42 @@ func NewFromHex(hexValues []string) Tree {
 	return New(values)
 }
 
-// Given an array of leaf hashes, builds the Merkle tree.
+// Given an array of leaf hashes, builds the Merkle tree. The leaves are the
+// hashes of the values if initialHash is set, see ModeRaw and ModeHashed.
 func Build(hashes []common.Hash, initialHash bool) Tree {
 	if initialHash {
-		hashes = mapSingleHash(hashes)
+		return BuildWithMode(hashes, ModeHashed)
 	}
+	return BuildWithMode(hashes, ModeRaw)
+}
+
+// BuildWithMode builds the Merkle tree of the values in the mode. The values
+// are reordered in ModeRaw.
+func BuildWithMode(values []common.Hash, mode Mode) Tree {
+	hashes := values
+	if mode != ModeRaw {
+		hashes = mapSingleHash(values)
+	}
+	descending := mode == ModeOpenZeppelin
 
 	// Hashes must be sorted to enable binary search.
 	sort.Slice(hashes, func(i, j int) bool {
-		return hashes[i].Hex() < hashes[j].Hex()
+		if descending {
+			return lessHash(hashes[j], hashes[i])
+		}
+		return lessHash(hashes[i], hashes[j])
 	})
 
 	n := len(hashes)
 	tree := make([]common.Hash, n-1, (2*n)-1)
 	tree = append(tree, hashes...)
 
+	hasher := newPairHasher()
 	for i := n - 2; i >= 0; i-- {
-		tree[i] = SortedHashPair(tree[2*i+1], tree[2*i+2])
+		tree[i] = hasher.sortedHashPair(tree[2*i+1], tree[2*i+2])
 	}
 
-	return New(tree)
+	return Tree{tree: tree, descending: descending}
 }
 
 // Given an array of hex-encoded leaf hashes, builds the Merkle tree.
@@ -81,15 +130,46 @@ func mapSingleHash(hashes []common.Hash) []common.Hash {
 	return output
 }
 
+// lessHash orders the hashes by their hex representation. The representations
+// are lowercase and of equal length, so this is the order of the bytes, which
+// is compared without allocating the strings.
+func lessHash(x, y common.Hash) bool {
+	return bytes.Compare(x[:], y[:]) < 0
+}
+
 // SortedHashPair returns a sorted hash of two hashes.
 func SortedHashPair(x, y common.Hash) common.Hash {
-	if x.Hex() <= y.Hex() {
+	if !lessHash(y, x) {
 		return crypto.Keccak256Hash(x.Bytes(), y.Bytes())
 	}
 
 	return crypto.Keccak256Hash(y.Bytes(), x.Bytes())
 }
 
+// pairHasher computes SortedHashPair reusing the hash state, which avoids the
+// allocations of a new state for each pair when hashing many pairs.
+type pairHasher struct {
+	state crypto.KeccakState
+	pair  [2 * common.HashLength]byte
+	hash  common.Hash
+}
+
+func newPairHasher() *pairHasher {
+	return &pairHasher{state: crypto.NewKeccakState()}
+}
+
+func (h *pairHasher) sortedHashPair(x, y common.Hash) common.Hash {
+	if lessHash(y, x) {
+		x, y = y, x
+	}
+	copy(h.pair[:common.HashLength], x[:])
+	copy(h.pair[common.HashLength:], y[:])
+	h.state.Reset()
+	h.state.Write(h.pair[:])
+	h.state.Read(h.hash[:])
+	return h.hash
+}
+
 // Root returns the Merkle root of the tree.
 func (t Tree) Root() (common.Hash, error) {
 	if len(t.tree) == 0 {
@@ -113,7 +193,8 @@ func (t Tree) HashCount() int {
 	return (len(t.tree) + 1) / 2
 }
 
-// SortedHashes returns all leaves in a slice.
+// SortedHashes returns all leaves in a slice, in ascending order except for the
+// trees built in ModeOpenZeppelin.
 func (t Tree) SortedHashes() []common.Hash {
 	numLeaves := t.HashCount()
 	if numLeaves == 0 {
@@ -134,7 +215,8 @@ func (t Tree) GetHash(i int) (common.Hash, error) {
 	return t.tree[pos], nil
 }
 
-// GetProof returns the Merkle proof for the `i`th leaf.
+// GetProof returns the Merkle proof for the `i`th leaf, see GetProofFromHash
+// for the proof of a leaf hash.
 func (t Tree) GetProof(i int) ([]common.Hash, error) {
 	numLeaves := t.HashCount()
 	if numLeaves == 0 || i < 0 || i >= numLeaves {
@@ -168,7 +250,10 @@ func (t Tree) GetProofFromHash(hash common.Hash) ([]common.Hash, error) {
 func (t Tree) binarySearch(hash common.Hash) (int, error) {
 	leaves := t.SortedHashes()
 	i := sort.Search(len(leaves), func(i int) bool {
-		return leaves[i].Hex() >= hash.Hex()
+		if t.descending {
+			return !lessHash(hash, leaves[i])
+		}
+		return !lessHash(leaves[i], hash)
 	})
 
 	if i < len(leaves) && leaves[i] == hash {
@@ -178,7 +263,135 @@ func (t Tree) binarySearch(hash common.Hash) (int, error) {
 	return 0, ErrHashNotFound
 }
 
-// VerifyProof verifies a Merkle proof for a given leaf.
+// MultiProof proves several leaves of a tree at once, in the format of
+// MerkleProof.multiProofVerify of OpenZeppelin. It is shorter than the proofs
+// of the leaves, since the nodes computed from the leaves are not included.
+type MultiProof struct {
+	// The proven leaves in the order expected by the verification, which is
+	// the order of their positions in the tree from the last one
+	Leaves     []common.Hash
+	Proof      []common.Hash
+	ProofFlags []bool
+}
+
+// GetMultiProof returns the multi proof of the leaves with the indexes, as
+// getMultiProof of @openzeppelin/merkle-tree.
+func (t Tree) GetMultiProof(indexes []int) (*MultiProof, error) {
+	numLeaves := t.HashCount()
+	positions := make([]int, len(indexes))
+	for k, i := range indexes {
+		if numLeaves == 0 || i < 0 || i >= numLeaves {
+			return nil, ErrInvalidIndex
+		}
+		positions[k] = len(t.tree) - numLeaves + i
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(positions)))
+
+	p := &MultiProof{Leaves: make([]common.Hash, len(positions))}
+	for k, pos := range positions {
+		if k > 0 && pos == positions[k-1] {
+			return nil, ErrDuplicateLeaf
+		}
+		p.Leaves[k] = t.tree[pos]
+	}
+
+	// The nodes to be computed, in the order of the computation: the children
+	// of a node are taken from the queue and the node is appended to it. A
+	// sibling which is not in the queue is taken from the proof.
+	queue := append([]int(nil), positions...)
+	for len(queue) > 0 && queue[0] > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		sibling := pos + ((2 * (pos % 2)) - 1)
+		if len(queue) > 0 && queue[0] == sibling {
+			p.ProofFlags = append(p.ProofFlags, true)
+			queue = queue[1:]
+		} else {
+			p.ProofFlags = append(p.ProofFlags, false)
+			p.Proof = append(p.Proof, t.tree[sibling])
+		}
+		queue = append(queue, parent(pos))
+	}
+	if len(positions) == 0 {
+		if len(t.tree) == 0 {
+			return nil, ErrEmptyTree
+		}
+		p.Proof = append(p.Proof, t.tree[0])
+	}
+
+	return p, nil
+}
+
+// GetMultiProofFromHashes returns the multi proof of the leaves with the
+// hashes.
+func (t Tree) GetMultiProofFromHashes(hashes []common.Hash) (*MultiProof, error) {
+	indexes := make([]int, len(hashes))
+	for k, hash := range hashes {
+		i, err := t.binarySearch(hash)
+		if err != nil {
+			return nil, err
+		}
+		indexes[k] = i
+	}
+
+	return t.GetMultiProof(indexes)
+}
+
+// VerifyMultiProof verifies a multi proof. It is equivalent to
+// MerkleProof.multiProofVerify of OpenZeppelin.
+func VerifyMultiProof(p *MultiProof, root common.Hash) bool {
+	totalHashes := len(p.ProofFlags)
+	if len(p.Leaves)+len(p.Proof) != totalHashes+1 {
+		return false
+	}
+
+	hashes := make([]common.Hash, totalHashes)
+	leafPos, hashPos, proofPos := 0, 0, 0
+	// Takes the next leaf or, after the leaves, the next computed hash, which
+	// must have been computed before the ith hash
+	next := func(i int) (common.Hash, bool) {
+		if leafPos < len(p.Leaves) {
+			leafPos++
+			return p.Leaves[leafPos-1], true
+		}
+		if hashPos >= i {
+			return common.Hash{}, false
+		}
+		hashPos++
+		return hashes[hashPos-1], true
+	}
+	for i := 0; i < totalHashes; i++ {
+		a, ok := next(i)
+		if !ok {
+			return false
+		}
+		var b common.Hash
+		if p.ProofFlags[i] {
+			if b, ok = next(i); !ok {
+				return false
+			}
+		} else {
+			if proofPos >= len(p.Proof) {
+				return false
+			}
+			b = p.Proof[proofPos]
+			proofPos++
+		}
+		hashes[i] = SortedHashPair(a, b)
+	}
+
+	switch {
+	case totalHashes > 0:
+		return proofPos == len(p.Proof) && hashes[totalHashes-1] == root
+	case len(p.Leaves) > 0:
+		return p.Leaves[0] == root
+	default:
+		return p.Proof[0] == root
+	}
+}
+
+// VerifyProof verifies a Merkle proof for a given leaf. It is equivalent to
+// MerkleProof.verify of OpenZeppelin, which hashes the pairs sorted as well.
 func VerifyProof(leaf common.Hash, proof []common.Hash, root common.Hash) bool {
 	hash := leaf
 	for _, pair := range proof {
@@ -187,3 +400,57 @@ func VerifyProof(leaf common.Hash, proof []common.Hash, root common.Hash) bool {
 
 	return hash == root
 }
+
+// RootBuilder computes the root of the tree built by Build from the added
+// leaves without building the tree. Only the leaves are kept, which is half of
+// the memory of the tree, so the root of an epoch with many stakes can be
+// computed while reading them in batches.
+type RootBuilder struct {
+	leaves []common.Hash
+}
+
+// NewRootBuilder creates a builder with capacity for sizeHint leaves.
+func NewRootBuilder(sizeHint int) *RootBuilder {
+	return &RootBuilder{leaves: make([]common.Hash, 0, sizeHint)}
+}
+
+// Add adds a leaf hash, the leaves can be added in any order.
+func (b *RootBuilder) Add(hash common.Hash) {
+	b.leaves = append(b.leaves, hash)
+}
+
+// Len returns the number of added leaves.
+func (b *RootBuilder) Len() int {
+	return len(b.leaves)
+}
+
+// Root returns the root of Build(leaves, false). The leaves are overwritten,
+// so the builder is empty afterwards.
+func (b *RootBuilder) Root() (common.Hash, error) {
+	queue := b.leaves
+	b.leaves = nil
+
+	n := len(queue)
+	if n == 0 {
+		return common.Hash{}, ErrEmptyTree
+	}
+
+	// Build computes the nodes of the tree from the last one to the first one,
+	// each from the two nodes following its children. The children are thus
+	// consumed in the reverse order of their positions, which is the order in
+	// which the nodes are computed, so a FIFO queue starting with the leaves in
+	// descending order yields the same root. The queue is a ring buffer in the
+	// slice of the leaves, each step takes two nodes and adds one.
+	sort.Slice(queue, func(i, j int) bool {
+		return lessHash(queue[j], queue[i])
+	})
+	hasher := newPairHasher()
+	head := 0
+	for count := n; count > 1; count-- {
+		right, left := queue[head], queue[(head+1)%n]
+		queue[(head+count)%n] = hasher.sortedHashPair(left, right)
+		head = (head + 2) % n
+	}
+
+	return queue[head], nil
+}
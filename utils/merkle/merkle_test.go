@@ -1,12 +1,18 @@
 package merkle_test
 
 import (
+	"bytes"
 	"flare-indexer/utils/merkle"
 	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -125,3 +131,267 @@ func TestMultiLeafTree(t *testing.T) {
 		})
 	}
 }
+
+func TestRootBuilder(t *testing.T) {
+	_, err := merkle.NewRootBuilder(0).Root()
+	assert.Equal(t, err, merkle.ErrEmptyTree)
+
+	for n := 1; n <= 70; n++ {
+		hashes := randomHashes(n)
+		// Duplicated leaves are kept by Build as well
+		if n > 3 {
+			hashes[n-1] = hashes[0]
+		}
+
+		builder := merkle.NewRootBuilder(0)
+		for _, hash := range hashes {
+			builder.Add(hash)
+		}
+		require.Equal(t, n, builder.Len())
+		root, err := builder.Root()
+		require.NoError(t, err)
+
+		expected, err := merkle.Build(append([]common.Hash(nil), hashes...), false).Root()
+		require.NoError(t, err)
+		require.Equal(t, expected, root, "%d leaves", n)
+		require.Zero(t, builder.Len())
+	}
+}
+
+const benchmarkLeaves = 100000
+
+func BenchmarkBuild(b *testing.B) {
+	hashes := randomHashes(benchmarkLeaves)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		leaves := append([]common.Hash(nil), hashes...)
+		if _, err := merkle.Build(leaves, false).Root(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRootBuilder(b *testing.B) {
+	hashes := randomHashes(benchmarkLeaves)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder := merkle.NewRootBuilder(len(hashes))
+		for _, hash := range hashes {
+			builder.Add(hash)
+		}
+		if _, err := builder.Root(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func randomHashes(n int) []common.Hash {
+	r := rand.New(rand.NewSource(int64(n)))
+	hashes := make([]common.Hash, n)
+	for i := range hashes {
+		r.Read(hashes[i][:])
+	}
+	return hashes
+}
+
+func TestBuildWithMode(t *testing.T) {
+	values := randomHashes(11)
+
+	raw := merkle.BuildWithMode(append([]common.Hash(nil), values...), merkle.ModeRaw)
+	require.Equal(t, merkle.Build(append([]common.Hash(nil), values...), false).Tree(), raw.Tree())
+
+	hashed := merkle.BuildWithMode(values, merkle.ModeHashed)
+	require.Equal(t, merkle.Build(append([]common.Hash(nil), values...), true).Tree(), hashed.Tree())
+
+	for _, mode := range []merkle.Mode{merkle.ModeRaw, merkle.ModeHashed, merkle.ModeOpenZeppelin} {
+		tree := merkle.BuildWithMode(values, mode)
+		root, err := tree.Root()
+		require.NoError(t, err)
+		for _, value := range values {
+			leaf := mode.Leaf(value)
+			proof, err := tree.GetProofFromHash(leaf)
+			require.NoError(t, err)
+			require.True(t, solidityVerify(proof, root, leaf), "mode %d", mode)
+		}
+	}
+}
+
+// The example of the README of @openzeppelin/merkle-tree:
+// StandardMerkleTree.of(values, ["address", "uint256"])
+func TestOpenZeppelinStandardTree(t *testing.T) {
+	addressType, err := abi.NewType("address", "", nil)
+	require.NoError(t, err)
+	uint256Type, err := abi.NewType("uint256", "", nil)
+	require.NoError(t, err)
+	arguments := abi.Arguments{{Type: addressType}, {Type: uint256Type}}
+
+	entries := []struct {
+		address string
+		amount  string
+	}{
+		{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+		{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+	}
+	values := make([]common.Hash, len(entries))
+	for i, e := range entries {
+		amount, ok := new(big.Int).SetString(e.amount, 10)
+		require.True(t, ok)
+		encoded, err := arguments.Pack(common.HexToAddress(e.address), amount)
+		require.NoError(t, err)
+		values[i] = crypto.Keccak256Hash(encoded)
+	}
+
+	tree := merkle.BuildWithMode(values, merkle.ModeOpenZeppelin)
+	root, err := tree.Root()
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"), root)
+
+	for _, value := range values {
+		leaf := merkle.ModeOpenZeppelin.Leaf(value)
+		proof, err := tree.GetProofFromHash(leaf)
+		require.NoError(t, err)
+		require.True(t, solidityVerify(proof, root, leaf))
+	}
+}
+
+// The layout of the trees differs from Build for numbers of leaves which are
+// not powers of two
+func TestOpenZeppelinLayout(t *testing.T) {
+	for n := 1; n <= 20; n++ {
+		values := randomHashes(n)
+		leaves := make([]common.Hash, n)
+		for i := range values {
+			leaves[i] = merkle.ModeOpenZeppelin.Leaf(values[i])
+		}
+		expected := openZeppelinTree(leaves)
+
+		tree := merkle.BuildWithMode(values, merkle.ModeOpenZeppelin)
+		require.Equal(t, expected, tree.Tree(), "%d leaves", n)
+		for i := range leaves {
+			proof, err := tree.GetProofFromHash(leaves[i])
+			require.NoError(t, err)
+			require.True(t, solidityVerify(proof, expected[0], leaves[i]))
+		}
+	}
+}
+
+// Port of makeMerkleTree of @openzeppelin/merkle-tree for leaves sorted as by
+// StandardMerkleTree
+func openZeppelinTree(leaves []common.Hash) []common.Hash {
+	leaves = append([]common.Hash(nil), leaves...)
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i][:], leaves[j][:]) < 0
+	})
+	tree := make([]common.Hash, 2*len(leaves)-1)
+	for i, leaf := range leaves {
+		tree[len(tree)-1-i] = leaf
+	}
+	for i := len(tree) - 1 - len(leaves); i >= 0; i-- {
+		tree[i] = solidityHashPair(tree[2*i+1], tree[2*i+2])
+	}
+	return tree
+}
+
+// Port of MerkleProof.verify of OpenZeppelin
+func solidityVerify(proof []common.Hash, root common.Hash, leaf common.Hash) bool {
+	computedHash := leaf
+	for _, p := range proof {
+		computedHash = solidityHashPair(computedHash, p)
+	}
+	return computedHash == root
+}
+
+func solidityHashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) < 0 {
+		return crypto.Keccak256Hash(a[:], b[:])
+	}
+	return crypto.Keccak256Hash(b[:], a[:])
+}
+
+func TestMultiProof(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, mode := range []merkle.Mode{merkle.ModeRaw, merkle.ModeOpenZeppelin} {
+		for n := 1; n <= 20; n++ {
+			tree := merkle.BuildWithMode(randomHashes(n), mode)
+			root, err := tree.Root()
+			require.NoError(t, err)
+
+			subsets := [][]int{nil, {0}, {n - 1}, r.Perm(n), r.Perm(n)[:r.Intn(n)+1]}
+			for _, indexes := range subsets {
+				p, err := tree.GetMultiProof(indexes)
+				require.NoError(t, err)
+				require.Len(t, p.Leaves, len(indexes))
+				require.True(t, merkle.VerifyMultiProof(p, root), "%d leaves, indexes %v", n, indexes)
+				require.Equal(t, root, solidityProcessMultiProof(p))
+
+				hashes := make([]common.Hash, len(indexes))
+				for k, i := range indexes {
+					hashes[k], err = tree.GetHash(i)
+					require.NoError(t, err)
+				}
+				fromHashes, err := tree.GetMultiProofFromHashes(hashes)
+				require.NoError(t, err)
+				require.Equal(t, p, fromHashes)
+
+				if len(p.Leaves) > 0 && n > 1 {
+					p.Leaves[0] = common.HexToHash("0x01")
+					require.False(t, merkle.VerifyMultiProof(p, root))
+				}
+			}
+		}
+	}
+
+	tree := merkle.Build(randomHashes(5), false)
+	_, err := tree.GetMultiProof([]int{1, 3, 1})
+	require.ErrorIs(t, err, merkle.ErrDuplicateLeaf)
+	_, err = tree.GetMultiProof([]int{5})
+	require.ErrorIs(t, err, merkle.ErrInvalidIndex)
+	_, err = tree.GetMultiProofFromHashes([]common.Hash{common.HexToHash("0x01")})
+	require.ErrorIs(t, err, merkle.ErrHashNotFound)
+
+	// Proofs not matching the flags are rejected
+	p, err := tree.GetMultiProof([]int{0, 4})
+	require.NoError(t, err)
+	root, err := tree.Root()
+	require.NoError(t, err)
+	p.ProofFlags = append(p.ProofFlags, true)
+	require.False(t, merkle.VerifyMultiProof(p, root))
+}
+
+// Port of MerkleProof.processMultiProof of OpenZeppelin
+func solidityProcessMultiProof(p *merkle.MultiProof) common.Hash {
+	leavesLen, totalHashes := len(p.Leaves), len(p.ProofFlags)
+	hashes := make([]common.Hash, totalHashes)
+	leafPos, hashPos, proofPos := 0, 0, 0
+	for i := 0; i < totalHashes; i++ {
+		var a, b common.Hash
+		if leafPos < leavesLen {
+			a = p.Leaves[leafPos]
+			leafPos++
+		} else {
+			a = hashes[hashPos]
+			hashPos++
+		}
+		if p.ProofFlags[i] {
+			if leafPos < leavesLen {
+				b = p.Leaves[leafPos]
+				leafPos++
+			} else {
+				b = hashes[hashPos]
+				hashPos++
+			}
+		} else {
+			b = p.Proof[proofPos]
+			proofPos++
+		}
+		hashes[i] = solidityHashPair(a, b)
+	}
+	if totalHashes > 0 {
+		return hashes[totalHashes-1]
+	} else if leavesLen > 0 {
+		return p.Leaves[0]
+	}
+	return p.Proof[0]
+}
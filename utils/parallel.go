@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Returns f(0), ..., f(n-1) computed by a pool of workers goroutines, one per
+// CPU if workers is not positive. The results are in the order of the indexes
+// regardless of the scheduling. If f fails for some indexes, the error of the
+// lowest of them is returned, so that the error does not depend on the
+// scheduling either.
+func ParallelMap[V any](n int, workers int, f func(i int) (V, error)) ([]V, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	results := make([]V, n)
+	errs := make([]error, n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
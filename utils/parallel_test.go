@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelMap(t *testing.T) {
+	square := func(i int) (int, error) {
+		return i * i, nil
+	}
+	for _, workers := range []int{0, 1, 4, 100} {
+		results, err := ParallelMap(50, workers, square)
+		require.NoError(t, err)
+		require.Len(t, results, 50)
+		for i, r := range results {
+			require.Equal(t, i*i, r)
+		}
+	}
+
+	results, err := ParallelMap(0, 4, square)
+	require.NoError(t, err)
+	require.Empty(t, results)
+
+	// The error of the lowest failing index is returned
+	_, err = ParallelMap(50, 8, func(i int) (int, error) {
+		if i%10 == 7 {
+			return 0, fmt.Errorf("failed at %d", i)
+		}
+		return i, nil
+	})
+	require.EqualError(t, err, "failed at 7")
+}
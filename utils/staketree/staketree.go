@@ -0,0 +1,250 @@
+// Package staketree builds the canonical set of P-chain stakes of an epoch and
+// its merkle tree. The merkle root of the set is what the voting clients submit
+// to the voting contract and the proofs are what the mirroring contract verifies,
+// so any data provider building the set with this package from its own data
+// obtains identical roots.
+package staketree
+
+import (
+	"context"
+	"flare-indexer/utils"
+	"flare-indexer/utils/contracts/mirroring"
+	"flare-indexer/utils/merkle"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+type StakeType uint8
+
+const (
+	ValidatorStake StakeType = 0
+	DelegatorStake StakeType = 1
+)
+
+var (
+	// Merkle root of an epoch without stakes
+	EmptyRoot common.Hash = crypto.Keccak256Hash(make([]byte, 32))
+
+	ErrStakeNotFound = errors.New("stake not found")
+
+	stakeABIArguments abi.Arguments
+)
+
+func init() {
+	bytes32Ty, err1 := abi.NewType("bytes32", "", nil)
+	uint8Ty, err2 := abi.NewType("uint8", "", nil)
+	bytes20Ty, err3 := abi.NewType("bytes20", "", nil)
+	uint64Ty, err4 := abi.NewType("uint64", "", nil)
+	err := utils.Join(err1, err2, err3, err4)
+	if err != nil {
+		panic(err)
+	}
+	stakeABIArguments = abi.Arguments{
+		{Name: "txId", Type: bytes32Ty},
+		{Name: "stakingType", Type: uint8Ty},
+		{Name: "inputAddress", Type: bytes20Ty},
+		{Name: "nodeId", Type: bytes20Ty},
+		{Name: "startTime", Type: uint64Ty},
+		{Name: "endTime", Type: uint64Ty},
+		{Name: "weight", Type: uint64Ty},
+	}
+}
+
+// Stake is an add validator or add delegator transaction. InputAddress is the
+// address of the first input (input index 0) of the transaction.
+type Stake struct {
+	TxID         ids.ID
+	Type         StakeType
+	InputAddress [20]byte
+	NodeID       ids.NodeID
+	StartTime    time.Time
+	EndTime      time.Time
+	Weight       uint64
+}
+
+// Source of stakes, e.g., a database of indexed P-chain transactions. The voted
+// set has one stake per transaction, taken from its first input (input index 0),
+// and only the stakes passing the staking filter of the voters (see
+// staking.TxSource, which does both for the indexed transactions).
+type Source interface {
+	// Return all stakes with start time in [start, end)
+	FetchStakes(ctx context.Context, start, end time.Time) ([]Stake, error)
+}
+
+// StakeData returns the stake in the form expected by the mirroring contract
+func (s *Stake) StakeData() *mirroring.IPChainStakeMirrorVerifierPChainStake {
+	return &mirroring.IPChainStakeMirrorVerifierPChainStake{
+		TxId:         s.TxID,
+		StakingType:  uint8(s.Type),
+		InputAddress: s.InputAddress,
+		NodeId:       s.NodeID,
+		StartTime:    uint64(s.StartTime.Unix()),
+		EndTime:      uint64(s.EndTime.Unix()),
+		Weight:       s.Weight,
+	}
+}
+
+// Hash returns the merkle tree leaf of the stake, i.e., the keccak256 hash of the
+// ABI encoded stake data
+func (s *Stake) Hash() (common.Hash, error) {
+	data := s.StakeData()
+	encoded, err := stakeABIArguments.Pack(
+		data.TxId,
+		data.StakingType,
+		data.InputAddress,
+		data.NodeId,
+		data.StartTime,
+		data.EndTime,
+		data.Weight,
+	)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "abi.Pack")
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// Canonicalize removes duplicated stakes (keeping the first one) and sorts
+// the stakes by transaction ID
+func Canonicalize(stakes []Stake) []Stake {
+	seen := make(map[ids.ID]bool, len(stakes))
+	result := make([]Stake, 0, len(stakes))
+	for _, s := range stakes {
+		if seen[s.TxID] {
+			continue
+		}
+		seen[s.TxID] = true
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TxID.String() < result[j].TxID.String()
+	})
+	return result
+}
+
+// Set is a canonical set of stakes with its merkle tree
+type Set struct {
+	stakes []Stake
+	hashes map[ids.ID]common.Hash
+	tree   merkle.Tree
+}
+
+// NewSet canonicalizes the stakes and builds the merkle tree
+func NewSet(stakes []Stake) (*Set, error) {
+	stakes = Canonicalize(stakes)
+	set := &Set{
+		stakes: stakes,
+		hashes: make(map[ids.ID]common.Hash, len(stakes)),
+	}
+	if len(stakes) == 0 {
+		return set, nil
+	}
+
+	leaves := make([]common.Hash, len(stakes))
+	for i := range stakes {
+		hash, err := stakes[i].Hash()
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = hash
+		set.hashes[stakes[i].TxID] = hash
+	}
+	set.tree = merkle.BuildWithMode(leaves, merkle.ModeRaw)
+	return set, nil
+}
+
+// RootBuilder computes the merkle root of stakes added one by one, e.g. while
+// reading them in batches, keeping only their transaction IDs and hashes
+// instead of the stakes and the full tree. The root is the root of the Set of
+// the added stakes.
+type RootBuilder struct {
+	seen   map[ids.ID]bool
+	leaves *merkle.RootBuilder
+}
+
+// NewRootBuilder creates a builder with capacity for sizeHint stakes
+func NewRootBuilder(sizeHint int) *RootBuilder {
+	return &RootBuilder{
+		seen:   make(map[ids.ID]bool, sizeHint),
+		leaves: merkle.NewRootBuilder(sizeHint),
+	}
+}
+
+// Add adds the stake, unless a stake with its transaction ID was added before
+// (the first one is kept as by Canonicalize)
+func (b *RootBuilder) Add(stake *Stake) error {
+	if b.seen[stake.TxID] {
+		return nil
+	}
+	hash, err := stake.Hash()
+	if err != nil {
+		return err
+	}
+	b.seen[stake.TxID] = true
+	b.leaves.Add(hash)
+	return nil
+}
+
+// Len returns the number of added stakes with distinct transaction IDs, it
+// must be called before Root
+func (b *RootBuilder) Len() int {
+	return b.leaves.Len()
+}
+
+// Root returns the merkle root of the added stakes, EmptyRoot if there are
+// none. It can be called only once.
+func (b *RootBuilder) Root() (common.Hash, error) {
+	if b.leaves.Len() == 0 {
+		return EmptyRoot, nil
+	}
+	return b.leaves.Root()
+}
+
+// BuildEpochSet fetches the stakes starting in [start, end) and builds their set
+func BuildEpochSet(ctx context.Context, source Source, start, end time.Time) (*Set, error) {
+	stakes, err := source.FetchStakes(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return NewSet(stakes)
+}
+
+// Stakes returns the canonical list of stakes in the set
+func (s *Set) Stakes() []Stake {
+	return s.stakes
+}
+
+// Tree returns the merkle tree of the set (empty for an empty set)
+func (s *Set) Tree() merkle.Tree {
+	return s.tree
+}
+
+// Root returns the merkle root of the set, EmptyRoot if the set is empty
+func (s *Set) Root() (common.Hash, error) {
+	if len(s.stakes) == 0 {
+		return EmptyRoot, nil
+	}
+	return s.tree.Root()
+}
+
+// Proof returns the merkle proof of the stake with the given transaction ID
+func (s *Set) Proof(txID ids.ID) ([][32]byte, error) {
+	hash, ok := s.hashes[txID]
+	if !ok {
+		return nil, ErrStakeNotFound
+	}
+	proof, err := s.tree.GetProofFromHash(hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "merkleTree.GetProofFromHash")
+	}
+	result := make([][32]byte, len(proof))
+	for i := range proof {
+		result[i] = proof[i]
+	}
+	return result, nil
+}
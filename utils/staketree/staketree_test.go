@@ -0,0 +1,125 @@
+package staketree
+
+import (
+	"context"
+	"flare-indexer/utils/merkle"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var testTxIDs = []string{
+	"XnfV79XVMyuXbTw8iNreQ9FrUgy9csYBJp1xRscay3oDzhyq8",
+	"nsPmyQbm4oo77jyykxbjf7s4Zp4urNptkyAouxVWZ2EB2kw1z",
+	"2p32tpqNrfzP3SStbP9bQGHZtJkCxjV3iHNssVnkcpUWxHMSuj",
+}
+
+func testStakes(t *testing.T) []Stake {
+	_, addr, err := address.ParseBech32("costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u")
+	require.NoError(t, err)
+	nodeID, err := ids.NodeIDFromString("NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6")
+	require.NoError(t, err)
+
+	start := time.Date(2023, 1, 1, 0, 9, 0, 0, time.UTC)
+	end := time.Date(2023, 1, 3, 2, 0, 0, 0, time.UTC)
+
+	stakes := make([]Stake, len(testTxIDs))
+	for i, txID := range testTxIDs {
+		id, err := ids.FromString(txID)
+		require.NoError(t, err)
+		stakes[i] = Stake{
+			TxID:      id,
+			Type:      DelegatorStake,
+			NodeID:    nodeID,
+			StartTime: start,
+			EndTime:   end,
+		}
+		copy(stakes[i].InputAddress[:], addr)
+	}
+	return stakes
+}
+
+func TestSetRoot(t *testing.T) {
+	stakes := testStakes(t)
+
+	// Duplicates do not change the set
+	set, err := NewSet(append(stakes, stakes[0]))
+	require.NoError(t, err)
+	require.Len(t, set.Stakes(), len(testTxIDs))
+
+	root, err := set.Root()
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("b3ec965b802c71f9058d2ed4d80bdf5af902a3741a75221992c5eb2f879a116c"), root)
+
+	for i := range stakes {
+		proof, err := set.Proof(stakes[i].TxID)
+		require.NoError(t, err)
+
+		leaf, err := stakes[i].Hash()
+		require.NoError(t, err)
+
+		proofHashes := make([]common.Hash, len(proof))
+		for j := range proof {
+			proofHashes[j] = proof[j]
+		}
+		require.True(t, merkle.VerifyProof(leaf, proofHashes, root))
+	}
+}
+
+func TestEmptySet(t *testing.T) {
+	set, err := NewSet(nil)
+	require.NoError(t, err)
+
+	root, err := set.Root()
+	require.NoError(t, err)
+	require.Equal(t, EmptyRoot, root)
+
+	_, err = set.Proof(ids.Empty)
+	require.ErrorIs(t, err, ErrStakeNotFound)
+}
+
+func TestRootBuilder(t *testing.T) {
+	stakes := testStakes(t)
+
+	builder := NewRootBuilder(0)
+	for i := range stakes {
+		require.NoError(t, builder.Add(&stakes[i]))
+	}
+	// A later stake with the transaction ID of an added one is ignored
+	duplicate := stakes[0]
+	duplicate.Weight = 1000
+	require.NoError(t, builder.Add(&duplicate))
+
+	root, err := builder.Root()
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("b3ec965b802c71f9058d2ed4d80bdf5af902a3741a75221992c5eb2f879a116c"), root)
+
+	root, err = NewRootBuilder(0).Root()
+	require.NoError(t, err)
+	require.Equal(t, EmptyRoot, root)
+}
+
+type testSource []Stake
+
+func (s testSource) FetchStakes(ctx context.Context, start, end time.Time) ([]Stake, error) {
+	var result []Stake
+	for _, stake := range s {
+		if !stake.StartTime.Before(start) && stake.StartTime.Before(end) {
+			result = append(result, stake)
+		}
+	}
+	return result, nil
+}
+
+func TestBuildEpochSet(t *testing.T) {
+	stakes := testStakes(t)
+	stakes[2].StartTime = stakes[2].StartTime.Add(time.Hour)
+
+	set, err := BuildEpochSet(context.Background(), testSource(stakes), stakes[0].StartTime, stakes[0].StartTime.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, set.Stakes(), 2)
+}
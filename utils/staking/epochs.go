@@ -2,7 +2,7 @@ package staking
 
 import (
 	"flare-indexer/config"
-	"flare-indexer/utils/contracts/voting"
+	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -34,17 +34,51 @@ func (e EpochInfo) GetTimeRange(epoch int64) (time.Time, time.Time) {
 	return e.GetStartTime(epoch), e.GetEndTime(epoch)
 }
 
+// Epoch containing the time, negative for times before the start of the first
+// epoch (the division is rounded down, not towards zero)
 func (e EpochInfo) GetEpochIndex(t time.Time) int64 {
-	return int64(t.Sub(e.Start) / e.Period)
+	elapsed := t.Sub(e.Start)
+	epoch := int64(elapsed / e.Period)
+	if elapsed%e.Period < 0 {
+		epoch--
+	}
+	return epoch
+}
+
+// Epoch containing the time, nil if the time is nil or the epochs are not known
+// (zero period)
+func (e EpochInfo) EpochOf(t *time.Time) *int64 {
+	if t == nil || e.Period <= 0 {
+		return nil
+	}
+	epoch := e.GetEpochIndex(*t)
+	return &epoch
+}
+
+// Epochs of the voting contract, numbered from cfg.First
+func NewVotingEpochInfo(cfg *config.EpochConfig, votingContract EpochConfigCaller) (EpochInfo, error) {
+	start, period, err := GetEpochConfig(votingContract)
+	if err != nil {
+		return EpochInfo{}, err
+	}
+	return NewEpochInfo(cfg, start, period), nil
+}
+
+// Implemented by the voting contract binding
+type EpochConfigCaller interface {
+	GetEpochConfiguration(opts *bind.CallOpts) (struct {
+		FirstEpochStartTs    *big.Int
+		EpochDurationSeconds *big.Int
+	}, error)
 }
 
-func GetEpochConfig(votingContract *voting.Voting) (time.Time, time.Duration, error) {
+func GetEpochConfig(votingContract EpochConfigCaller) (time.Time, time.Duration, error) {
 	chainCfg, err := votingContract.GetEpochConfiguration(&bind.CallOpts{})
 	if err != nil {
 		return time.Time{}, 0, err
 	}
 
-	start := time.Unix(chainCfg.FirstEpochStartTs.Int64(), 0)
+	start := time.Unix(chainCfg.FirstEpochStartTs.Int64(), 0).UTC()
 	period := time.Duration(chainCfg.EpochDurationSeconds.Int64()) * time.Second
 
 	return start, period, nil
@@ -0,0 +1,58 @@
+package staking
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Time zone of the DST tests, if not installed
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEpochIndex(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	epochs := EpochInfo{Start: start, Period: time.Hour}
+
+	require.Equal(t, int64(0), epochs.GetEpochIndex(start))
+	require.Equal(t, int64(0), epochs.GetEpochIndex(start.Add(time.Hour-time.Nanosecond)))
+	require.Equal(t, int64(1), epochs.GetEpochIndex(start.Add(time.Hour)))
+	// Rounded down before the start
+	require.Equal(t, int64(-1), epochs.GetEpochIndex(start.Add(-time.Nanosecond)))
+	require.Equal(t, int64(-1), epochs.GetEpochIndex(start.Add(-time.Hour)))
+	require.Equal(t, int64(-2), epochs.GetEpochIndex(start.Add(-time.Hour-time.Nanosecond)))
+}
+
+// Epochs of local times do not depend on the time zone, also when the clocks
+// are changed for daylight saving time
+func TestGetEpochIndexAcrossDST(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Ljubljana")
+	require.NoError(t, err)
+	// Hourly epochs starting at midnight UTC of the day the clocks go forward
+	epochs := EpochInfo{Start: time.Date(2023, 3, 26, 0, 0, 0, 0, time.UTC), Period: time.Hour}
+
+	for _, c := range []struct {
+		local time.Time
+		epoch int64
+	}{
+		// Clocks go forward from 2:00 CET to 3:00 CEST on March 26
+		{time.Date(2023, 3, 26, 1, 59, 59, 0, location), 0},
+		{time.Date(2023, 3, 26, 3, 0, 0, 0, location), 1},
+		{time.Date(2023, 3, 26, 3, 59, 59, 0, location), 1},
+		// Clocks go back from 3:00 CEST to 2:00 CET on October 29, 217 days
+		// (5208 epochs) later
+		{time.Date(2023, 10, 29, 1, 59, 59, 0, location), 5207},
+		{time.Date(2023, 10, 29, 3, 0, 0, 0, location), 5210},
+	} {
+		require.Equal(t, c.epoch, epochs.GetEpochIndex(c.local), c.local.String())
+		require.Equal(t, c.epoch, epochs.GetEpochIndex(c.local.UTC()), c.local.String())
+	}
+}
+
+func TestEpochOf(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	epochs := EpochInfo{Start: start, Period: time.Hour}
+
+	t1 := start.Add(90 * time.Minute)
+	require.Equal(t, int64(1), *epochs.EpochOf(&t1))
+	require.Nil(t, epochs.EpochOf(nil))
+	require.Nil(t, EpochInfo{}.EpochOf(&t1))
+}
@@ -0,0 +1,34 @@
+package staking
+
+import (
+	"flare-indexer/database"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/reward"
+)
+
+// Fees of validators are their shares of the delegation rewards in units of
+// 1/10000 % (1000000 is 100%), as the DelegationShares of the P-chain
+// transactions and the fee_percentage column
+const FeePercentageDenominator = reward.PercentDenominator
+
+// Validated fee percentage of a staking transaction. Delegator transactions
+// have no fee, a stored fee of a delegator transaction is an error, as is a
+// validator fee above 100%.
+func FeePercentage(tx *database.PChainTx) (uint32, error) {
+	switch tx.Type {
+	case database.PChainAddValidatorTx, database.PChainAddPermissionlessValidatorTx:
+		if tx.FeePercentage > FeePercentageDenominator {
+			return 0, fmt.Errorf("fee percentage %d of validator transaction %s exceeds %d",
+				tx.FeePercentage, txID(tx), FeePercentageDenominator)
+		}
+		return tx.FeePercentage, nil
+	case database.PChainAddDelegatorTx, database.PChainAddPermissionlessDelegatorTx:
+		if tx.FeePercentage != 0 {
+			return 0, fmt.Errorf("delegator transaction %s has fee percentage %d", txID(tx), tx.FeePercentage)
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("transaction %s of type %s is not a staking transaction", txID(tx), tx.Type)
+	}
+}
@@ -0,0 +1,61 @@
+package staking
+
+import (
+	"flare-indexer/config"
+	"flare-indexer/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeePercentage(t *testing.T) {
+	tests := []struct {
+		txType   database.PChainTxType
+		fee      uint32
+		expected uint32
+		valid    bool
+	}{
+		{database.PChainAddValidatorTx, 100000, 100000, true},
+		{database.PChainAddPermissionlessValidatorTx, FeePercentageDenominator, FeePercentageDenominator, true},
+		{database.PChainAddValidatorTx, FeePercentageDenominator + 1, 0, false},
+		{database.PChainAddPermissionlessValidatorTx, 2 * FeePercentageDenominator, 0, false},
+		{database.PChainAddDelegatorTx, 0, 0, true},
+		{database.PChainAddPermissionlessDelegatorTx, 0, 0, true},
+		{database.PChainAddDelegatorTx, 20000, 0, false},
+		{database.PChainImportTx, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		tx := database.PChainTx{Type: test.txType, FeePercentage: test.fee}
+		fee, err := FeePercentage(&tx)
+		if test.valid {
+			require.NoError(t, err, "%s %d", test.txType, test.fee)
+		} else {
+			require.Error(t, err, "%s %d", test.txType, test.fee)
+		}
+		require.Equal(t, test.expected, fee)
+	}
+}
+
+func TestTxFilterMaxFee(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newTx := func(txType database.PChainTxType, nodeID string, fee uint32) database.PChainTxData {
+		tx := newFilterTestTx(nodeID, "costwo1a", 1000, fee, start, start.Add(24*time.Hour))
+		tx.Type = txType
+		return tx
+	}
+	txs := []database.PChainTxData{
+		newTx(database.PChainAddValidatorTx, "NodeID-A", 100000),
+		newTx(database.PChainAddPermissionlessValidatorTx, "NodeID-B", 500000),
+		newTx(database.PChainAddPermissionlessDelegatorTx, "NodeID-C", 0),
+		newTx(database.PChainAddDelegatorTx, "NodeID-D", 500000),
+	}
+
+	filtered := NewTxFilter(&config.StakingFilterConfig{MaxFeePercentage: 200000}, &config.StakingRulesConfig{}).Apply(txs)
+	nodeIDs := make([]string, len(filtered))
+	for i := range filtered {
+		nodeIDs[i] = filtered[i].NodeID
+	}
+	require.Equal(t, []string{"NodeID-A", "NodeID-C"}, nodeIDs)
+}
@@ -0,0 +1,120 @@
+package staking
+
+import (
+	"flare-indexer/config"
+	"flare-indexer/database"
+	"strings"
+	"time"
+)
+
+// Filter of staking transactions by node ID, input address, weight, duration
+// and fee, built from the staking filter config, and by the staking rules if
+// the rules filter is enabled. A nil filter accepts all transactions.
+type TxFilter struct {
+	includeNodeIDs   map[string]bool
+	excludeNodeIDs   map[string]bool
+	includeAddresses map[string]bool
+	excludeAddresses map[string]bool
+
+	minWeight        uint64
+	maxDuration      time.Duration
+	maxFeePercentage uint32
+
+	// Nil if stakes violating the rules are not excluded
+	rules *Rules
+}
+
+func NewTxFilter(cfg *config.StakingFilterConfig, rulesCfg *config.StakingRulesConfig) *TxFilter {
+	if len(cfg.IncludeNodeIDs) == 0 && len(cfg.ExcludeNodeIDs) == 0 &&
+		len(cfg.IncludeAddresses) == 0 && len(cfg.ExcludeAddresses) == 0 &&
+		cfg.MinWeight == 0 && cfg.MaxDuration == 0 &&
+		cfg.MaxFeePercentage == 0 && !rulesCfg.Filter {
+		return nil
+	}
+	var rules *Rules
+	if rulesCfg.Filter {
+		rules = NewRules(rulesCfg)
+	}
+	return &TxFilter{
+		includeNodeIDs:   toSet(cfg.IncludeNodeIDs),
+		excludeNodeIDs:   toSet(cfg.ExcludeNodeIDs),
+		includeAddresses: toSet(cfg.IncludeAddresses),
+		excludeAddresses: toSet(cfg.ExcludeAddresses),
+		minWeight:        cfg.MinWeight,
+		maxDuration:      cfg.MaxDuration,
+		maxFeePercentage: cfg.MaxFeePercentage,
+		rules:            rules,
+	}
+}
+
+// Accept returns true if the transaction passes the filter
+func (f *TxFilter) Accept(tx *database.PChainTxData) bool {
+	if f == nil {
+		return true
+	}
+	if !acceptValue(tx.NodeID, f.includeNodeIDs, f.excludeNodeIDs) {
+		return false
+	}
+	if !acceptValue(normalizeAddress(tx.InputAddress), f.includeAddresses, f.excludeAddresses) {
+		return false
+	}
+	if tx.Weight < f.minWeight {
+		return false
+	}
+	if f.maxFeePercentage > 0 {
+		// Applies to the permissionless validators as well, delegations have no fee
+		if fee, err := FeePercentage(&tx.PChainTx); err != nil || fee > f.maxFeePercentage {
+			return false
+		}
+	}
+	if f.rules != nil && len(f.rules.Check(&tx.PChainTx)) > 0 {
+		return false
+	}
+	return f.acceptTimeWindow(tx)
+}
+
+// Staking interval must be non-empty and not longer than the configured limit.
+// The minimal durations are checked by the staking rules.
+func (f *TxFilter) acceptTimeWindow(tx *database.PChainTxData) bool {
+	if f.maxDuration == 0 {
+		return true
+	}
+	if tx.StartTime == nil || tx.EndTime == nil || !tx.StartTime.Before(*tx.EndTime) {
+		return false
+	}
+	return tx.EndTime.Sub(*tx.StartTime) <= f.maxDuration
+}
+
+// Apply returns the transactions passing the filter, keeping their order
+func (f *TxFilter) Apply(txs []database.PChainTxData) []database.PChainTxData {
+	if f == nil {
+		return txs
+	}
+	result := make([]database.PChainTxData, 0, len(txs))
+	for i := range txs {
+		if f.Accept(&txs[i]) {
+			result = append(result, txs[i])
+		}
+	}
+	return result
+}
+
+func acceptValue(value string, include, exclude map[string]bool) bool {
+	if exclude[value] {
+		return false
+	}
+	return len(include) == 0 || include[value]
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[normalizeAddress(strings.TrimSpace(v))] = true
+	}
+	return set
+}
+
+// Addresses may be given with the P-chain prefix, e.g. "P-costwo1..."
+func normalizeAddress(address string) string {
+	return strings.TrimPrefix(address, "P-")
+}
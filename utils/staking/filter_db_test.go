@@ -0,0 +1,81 @@
+//go:build integration
+// +build integration
+
+package staking
+
+import (
+	"context"
+	"flare-indexer/config"
+	"flare-indexer/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The staking transactions of all types are read from the database and filtered
+func TestTxFilterEpochQuery(t *testing.T) {
+	db, err := database.ConnectAndInitializeTestDB(&config.DBConfig{
+		Username: database.MysqlTestUser,
+		Password: database.MysqlTestPassword,
+		Host:     database.MysqlTestHost,
+		Port:     database.MysqlTestPort,
+		Database: "flare_indexer_indexer",
+	}, false)
+	require.NoError(t, err)
+
+	// The transactions are rolled back after the test
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	start := time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(30 * 24 * time.Hour)
+	stakes := []struct {
+		txID   string
+		txType database.PChainTxType
+		fee    uint32
+	}{
+		{"filterValidator", database.PChainAddValidatorTx, 100000},
+		{"filterDelegator", database.PChainAddDelegatorTx, 0},
+		{"filterPermissionlessValidator", database.PChainAddPermissionlessValidatorTx, 100000},
+		{"filterPermissionlessHighFee", database.PChainAddPermissionlessValidatorTx, 500000},
+		{"filterPermissionlessDelegator", database.PChainAddPermissionlessDelegatorTx, 0},
+	}
+	for i, stake := range stakes {
+		txID := stake.txID
+		require.NoError(t, tx.Create(&database.PChainTx{
+			Type:          stake.txType,
+			TxID:          &txID,
+			BlockID:       "filterBlock",
+			NodeID:        "NodeID-Filter",
+			StartTime:     &start,
+			EndTime:       &end,
+			Weight:        uint64(1000 + i),
+			FeePercentage: stake.fee,
+			Status:        database.PChainTxAccepted,
+		}).Error)
+		require.NoError(t, tx.Create(&database.PChainTxInput{TxInput: database.TxInput{
+			TxID:    txID,
+			Address: "costwo1filter",
+		}}).Error)
+	}
+
+	txs, err := database.GetPChainTxsForEpoch(context.Background(), &database.GetPChainTxsForEpochInput{
+		DB:             tx,
+		StartTimestamp: start,
+		EndTimestamp:   start.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, txs, len(stakes))
+
+	filter := NewTxFilter(&config.StakingFilterConfig{MaxFeePercentage: 200000}, &config.StakingRulesConfig{})
+	var accepted []string
+	for i := range txs {
+		if filter.Accept(&txs[i]) {
+			accepted = append(accepted, *txs[i].TxID)
+		}
+	}
+	require.ElementsMatch(t, []string{
+		"filterValidator", "filterDelegator", "filterPermissionlessValidator", "filterPermissionlessDelegator",
+	}, accepted)
+}
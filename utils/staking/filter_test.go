@@ -0,0 +1,113 @@
+package staking
+
+import (
+	"flare-indexer/config"
+	"flare-indexer/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxFilter(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	txs := []database.PChainTxData{
+		newFilterTestTx("NodeID-A", "costwo1a", 1000, 0, start, start.Add(24*time.Hour)),
+		newFilterTestTx("NodeID-B", "costwo1b", 2000, 100000, start, start.Add(72*time.Hour)),
+		newFilterTestTx("NodeID-C", "costwo1c", 3000, 500000, start, start.Add(720*time.Hour)),
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.StakingFilterConfig
+		rules    config.StakingRulesConfig
+		expected []string
+	}{
+		{
+			name:     "empty",
+			cfg:      config.StakingFilterConfig{},
+			expected: []string{"NodeID-A", "NodeID-B", "NodeID-C"},
+		},
+		{
+			name:     "exclude node",
+			cfg:      config.StakingFilterConfig{ExcludeNodeIDs: []string{"NodeID-B"}},
+			expected: []string{"NodeID-A", "NodeID-C"},
+		},
+		{
+			name:     "include node",
+			cfg:      config.StakingFilterConfig{IncludeNodeIDs: []string{"NodeID-B", "NodeID-C"}},
+			expected: []string{"NodeID-B", "NodeID-C"},
+		},
+		{
+			name:     "exclude address with prefix",
+			cfg:      config.StakingFilterConfig{ExcludeAddresses: []string{"P-costwo1a"}},
+			expected: []string{"NodeID-B", "NodeID-C"},
+		},
+		{
+			name:     "min weight",
+			cfg:      config.StakingFilterConfig{MinWeight: 2000},
+			expected: []string{"NodeID-B", "NodeID-C"},
+		},
+		{
+			name:     "duration",
+			cfg:      config.StakingFilterConfig{MaxDuration: 240 * time.Hour},
+			expected: []string{"NodeID-A", "NodeID-B"},
+		},
+		{
+			name:     "max fee",
+			cfg:      config.StakingFilterConfig{MaxFeePercentage: 200000},
+			expected: []string{"NodeID-A", "NodeID-B"},
+		},
+		{
+			name: "include and exclude",
+			cfg: config.StakingFilterConfig{
+				IncludeAddresses: []string{"costwo1a", "costwo1c"},
+				ExcludeNodeIDs:   []string{"NodeID-C"},
+			},
+			expected: []string{"NodeID-A"},
+		},
+		{
+			name:     "rules not filtered",
+			rules:    config.StakingRulesConfig{MaxValidatorStake: 2000},
+			expected: []string{"NodeID-A", "NodeID-B", "NodeID-C"},
+		},
+		{
+			name:     "rules",
+			rules:    config.StakingRulesConfig{MaxValidatorStake: 2000, MinValidatorDuration: 48 * time.Hour, Filter: true},
+			expected: []string{"NodeID-B"},
+		},
+		{
+			name:     "max duration and rules",
+			cfg:      config.StakingFilterConfig{MaxDuration: 240 * time.Hour},
+			rules:    config.StakingRulesConfig{MinValidatorDuration: 48 * time.Hour, Filter: true},
+			expected: []string{"NodeID-B"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filtered := NewTxFilter(&test.cfg, &test.rules).Apply(txs)
+			nodeIDs := make([]string, len(filtered))
+			for i := range filtered {
+				nodeIDs[i] = filtered[i].NodeID
+			}
+			require.Equal(t, test.expected, nodeIDs)
+		})
+	}
+}
+
+func newFilterTestTx(
+	nodeID, address string, weight uint64, fee uint32, start, end time.Time,
+) database.PChainTxData {
+	return database.PChainTxData{
+		PChainTx: database.PChainTx{
+			Type:          database.PChainAddValidatorTx,
+			NodeID:        nodeID,
+			Weight:        weight,
+			FeePercentage: fee,
+			StartTime:     &start,
+			EndTime:       &end,
+		},
+		InputAddress: address,
+	}
+}
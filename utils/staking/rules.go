@@ -0,0 +1,183 @@
+package staking
+
+import (
+	"flare-indexer/config"
+	"flare-indexer/database"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Staking rule violated by a stake
+type Rule string
+
+const (
+	// Stake of a validator (own or delegated) above the max validator stake
+	RuleMaxValidatorStake Rule = "MAX_VALIDATOR_STAKE"
+	// Delegation exceeding the remaining capacity of the validator when it started
+	RuleMaxDelegation Rule = "MAX_DELEGATION"
+	// Staking interval shorter than the minimal duration
+	RuleMinDuration Rule = "MIN_DURATION"
+	// Delegation to a node without an active validator
+	RuleNoValidator Rule = "NO_VALIDATOR"
+	// Validator fee above 100% or a fee of a delegation, see FeePercentage
+	RuleInvalidFee Rule = "INVALID_FEE"
+)
+
+// Violation of a staking rule by a transaction
+type Violation struct {
+	TxID string
+	Rule Rule
+}
+
+// Staking rules of the network, built from the staking rules config
+type Rules struct {
+	maxValidatorStake    uint64
+	maxDelegationFactor  uint64
+	minValidatorDuration time.Duration
+	minDelegatorDuration time.Duration
+}
+
+func NewRules(cfg *config.StakingRulesConfig) *Rules {
+	return &Rules{
+		maxValidatorStake:    cfg.MaxValidatorStake,
+		maxDelegationFactor:  cfg.MaxDelegationFactor,
+		minValidatorDuration: cfg.MinValidatorDuration,
+		minDelegatorDuration: cfg.MinDelegatorDuration,
+	}
+}
+
+// Rules violated by the transaction alone, i.e. not depending on other stakes
+func (r *Rules) Check(tx *database.PChainTx) []Rule {
+	var rules []Rule
+	if r.maxValidatorStake > 0 && tx.Weight > r.maxValidatorStake {
+		rules = append(rules, RuleMaxValidatorStake)
+	}
+	minDuration := r.minDelegatorDuration
+	if tx.Type.IsValidatorTx() {
+		minDuration = r.minValidatorDuration
+	}
+	if minDuration > 0 && (tx.StartTime == nil || tx.EndTime == nil || tx.EndTime.Sub(*tx.StartTime) < minDuration) {
+		rules = append(rules, RuleMinDuration)
+	}
+	if _, err := FeePercentage(tx); err != nil {
+		rules = append(rules, RuleInvalidFee)
+	}
+	return rules
+}
+
+// Maximal total stake of a validator with the given own stake, nil if it is
+// not limited. The stakes are big integers, since the sums of the stakes of a
+// node (and their product with the delegation factor) may overflow uint64.
+func (r *Rules) MaxStake(validatorStake *big.Int) *big.Int {
+	var maxStake *big.Int
+	if r.maxValidatorStake > 0 {
+		maxStake = new(big.Int).SetUint64(r.maxValidatorStake)
+	}
+	if r.maxDelegationFactor > 0 {
+		factorStake := new(big.Int).Mul(validatorStake, new(big.Int).SetUint64(r.maxDelegationFactor))
+		if maxStake == nil || factorStake.Cmp(maxStake) < 0 {
+			maxStake = factorStake
+		}
+	}
+	return maxStake
+}
+
+// Stake of a validator node and the violations of the rules by its stakes
+type ValidatorCapacity struct {
+	NodeID         string
+	ValidatorStake *big.Int
+	DelegatedStake *big.Int
+	// Maximal total stake, nil if it is not limited
+	MaxStake   *big.Int
+	Violations []Violation
+}
+
+// Stake which can still be delegated to the validator and false if it is not limited
+func (c *ValidatorCapacity) Remaining() (*big.Int, bool) {
+	if c.MaxStake == nil {
+		return new(big.Int), false
+	}
+	remaining := new(big.Int).Sub(c.MaxStake, c.ValidatorStake)
+	remaining.Sub(remaining, c.DelegatedStake)
+	if remaining.Sign() < 0 {
+		return new(big.Int), true
+	}
+	return remaining, true
+}
+
+// Capacities of the validator nodes of the given validator and delegator
+// transactions, which should be the stakes active at some time. Delegations are
+// added in the order of their start times and a delegation exceeding the
+// remaining capacity of its validator violates RuleMaxDelegation. Nodes with
+// delegations but no validator are included with zero validator stake. The
+// result is sorted by node ID.
+func (r *Rules) Capacities(validators []database.PChainTx, delegators []database.PChainTx) []ValidatorCapacity {
+	capacities := make(map[string]*ValidatorCapacity)
+	get := func(nodeID string) *ValidatorCapacity {
+		c, ok := capacities[nodeID]
+		if !ok {
+			c = &ValidatorCapacity{NodeID: nodeID, ValidatorStake: new(big.Int), DelegatedStake: new(big.Int)}
+			capacities[nodeID] = c
+		}
+		return c
+	}
+	for i := range validators {
+		tx := &validators[i]
+		c := get(tx.NodeID)
+		c.ValidatorStake.Add(c.ValidatorStake, new(big.Int).SetUint64(tx.Weight))
+		c.Violations = append(c.Violations, r.violations(tx)...)
+	}
+	for _, c := range capacities {
+		c.MaxStake = r.MaxStake(c.ValidatorStake)
+	}
+
+	delegators = sortedByStartTime(delegators)
+	for i := range delegators {
+		tx := &delegators[i]
+		c := get(tx.NodeID)
+		c.Violations = append(c.Violations, r.violations(tx)...)
+		weight := new(big.Int).SetUint64(tx.Weight)
+		if c.ValidatorStake.Sign() == 0 {
+			c.Violations = append(c.Violations, Violation{TxID: txID(tx), Rule: RuleNoValidator})
+		} else if remaining, limited := c.Remaining(); limited && weight.Cmp(remaining) > 0 {
+			c.Violations = append(c.Violations, Violation{TxID: txID(tx), Rule: RuleMaxDelegation})
+		}
+		c.DelegatedStake.Add(c.DelegatedStake, weight)
+	}
+
+	result := make([]ValidatorCapacity, 0, len(capacities))
+	for _, c := range capacities {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NodeID < result[j].NodeID })
+	return result
+}
+
+func (r *Rules) violations(tx *database.PChainTx) []Violation {
+	var violations []Violation
+	for _, rule := range r.Check(tx) {
+		violations = append(violations, Violation{TxID: txID(tx), Rule: rule})
+	}
+	return violations
+}
+
+// Sorted copy of txs, transactions without a start time go last
+func sortedByStartTime(txs []database.PChainTx) []database.PChainTx {
+	sorted := make([]database.PChainTx, len(txs))
+	copy(sorted, txs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].StartTime == nil || sorted[j].StartTime == nil {
+			return sorted[j].StartTime == nil && sorted[i].StartTime != nil
+		}
+		return sorted[i].StartTime.Before(*sorted[j].StartTime)
+	})
+	return sorted
+}
+
+func txID(tx *database.PChainTx) string {
+	if tx.TxID == nil {
+		return ""
+	}
+	return *tx.TxID
+}
@@ -0,0 +1,132 @@
+package staking
+
+import (
+	"flare-indexer/config"
+	"flare-indexer/database"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesCheck(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rules := NewRules(&config.StakingRulesConfig{
+		MaxValidatorStake:    1000,
+		MinValidatorDuration: 60 * 24 * time.Hour,
+		MinDelegatorDuration: 14 * 24 * time.Hour,
+	})
+
+	validator := newRulesTestTx(database.PChainAddValidatorTx, "A", "NodeID-A", 1000, start, start.Add(60*24*time.Hour))
+	require.Empty(t, rules.Check(&validator))
+
+	validator.Weight = 1001
+	validator.EndTime = &start
+	require.Equal(t, []Rule{RuleMaxValidatorStake, RuleMinDuration}, rules.Check(&validator))
+
+	delegator := newRulesTestTx(database.PChainAddDelegatorTx, "B", "NodeID-A", 10, start, start.Add(14*24*time.Hour))
+	require.Empty(t, rules.Check(&delegator))
+
+	delegator.FeePercentage = 100000
+	require.Equal(t, []Rule{RuleInvalidFee}, rules.Check(&delegator))
+
+	require.Empty(t, NewRules(&config.StakingRulesConfig{}).Check(&validator))
+}
+
+func TestRulesMaxStake(t *testing.T) {
+	require.Nil(t, NewRules(&config.StakingRulesConfig{}).MaxStake(big.NewInt(100)))
+	require.Equal(t, big.NewInt(1500), NewRules(&config.StakingRulesConfig{MaxDelegationFactor: 15}).MaxStake(big.NewInt(100)))
+
+	rules := NewRules(&config.StakingRulesConfig{MaxValidatorStake: 1000, MaxDelegationFactor: 15})
+	require.Equal(t, big.NewInt(150), rules.MaxStake(big.NewInt(10)))
+	require.Equal(t, big.NewInt(1000), rules.MaxStake(big.NewInt(100)))
+	require.Equal(t, big.NewInt(1000), rules.MaxStake(big.NewInt(1<<62)))
+
+	// The product of the stake and the factor exceeds uint64
+	maxUint64 := new(big.Int).SetUint64(math.MaxUint64)
+	rules = NewRules(&config.StakingRulesConfig{MaxDelegationFactor: 15})
+	require.Equal(t, new(big.Int).Mul(maxUint64, big.NewInt(15)), rules.MaxStake(maxUint64))
+}
+
+func TestRulesCapacities(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(100 * 24 * time.Hour)
+	rules := NewRules(&config.StakingRulesConfig{MaxValidatorStake: 1000, MaxDelegationFactor: 4})
+
+	validators := []database.PChainTx{
+		newRulesTestTx(database.PChainAddValidatorTx, "V1", "NodeID-B", 100, start, end),
+		newRulesTestTx(database.PChainAddValidatorTx, "V2", "NodeID-A", 500, start, end),
+	}
+	delegators := []database.PChainTx{
+		// Added after D2, exceeds the capacity of NodeID-B
+		newRulesTestTx(database.PChainAddDelegatorTx, "D1", "NodeID-B", 200, start.Add(2*time.Hour), end),
+		newRulesTestTx(database.PChainAddDelegatorTx, "D2", "NodeID-B", 200, start.Add(time.Hour), end),
+		newRulesTestTx(database.PChainAddDelegatorTx, "D3", "NodeID-A", 300, start, end),
+		newRulesTestTx(database.PChainAddDelegatorTx, "D4", "NodeID-C", 50, start, end),
+	}
+
+	capacities := rules.Capacities(validators, delegators)
+	require.Equal(t, []ValidatorCapacity{
+		{NodeID: "NodeID-A", ValidatorStake: big.NewInt(500), DelegatedStake: big.NewInt(300), MaxStake: big.NewInt(1000)},
+		{
+			NodeID: "NodeID-B", ValidatorStake: big.NewInt(100), DelegatedStake: big.NewInt(400), MaxStake: big.NewInt(400),
+			Violations: []Violation{{TxID: "D1", Rule: RuleMaxDelegation}},
+		},
+		{
+			NodeID: "NodeID-C", ValidatorStake: new(big.Int), DelegatedStake: big.NewInt(50),
+			Violations: []Violation{{TxID: "D4", Rule: RuleNoValidator}},
+		},
+	}, capacities)
+
+	remaining, limited := capacities[0].Remaining()
+	require.True(t, limited)
+	require.Equal(t, "200", remaining.String())
+	remaining, limited = capacities[1].Remaining()
+	require.True(t, limited)
+	require.Equal(t, "0", remaining.String())
+	_, limited = capacities[2].Remaining()
+	require.False(t, limited)
+}
+
+func TestRulesCapacitiesOverflow(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(100 * 24 * time.Hour)
+	rules := NewRules(&config.StakingRulesConfig{MaxDelegationFactor: 2})
+
+	// Stakes of the node sum to more than uint64, the delegations are within
+	// the capacity of twice the validator stake
+	validators := []database.PChainTx{
+		newRulesTestTx(database.PChainAddValidatorTx, "V1", "NodeID-A", math.MaxUint64, start, end),
+		newRulesTestTx(database.PChainAddValidatorTx, "V2", "NodeID-A", 1, start, end),
+	}
+	delegators := []database.PChainTx{
+		newRulesTestTx(database.PChainAddDelegatorTx, "D1", "NodeID-A", math.MaxUint64, start, end),
+		newRulesTestTx(database.PChainAddDelegatorTx, "D2", "NodeID-A", 1, start.Add(time.Hour), end),
+		newRulesTestTx(database.PChainAddDelegatorTx, "D3", "NodeID-A", 1, start.Add(2*time.Hour), end),
+	}
+
+	capacities := rules.Capacities(validators, delegators)
+	require.Len(t, capacities, 1)
+	c := capacities[0]
+	require.Equal(t, "18446744073709551616", c.ValidatorStake.String())
+	require.Equal(t, "18446744073709551617", c.DelegatedStake.String())
+	require.Equal(t, "36893488147419103232", c.MaxStake.String())
+	// D3 exceeds the capacity by one
+	require.Equal(t, []Violation{{TxID: "D3", Rule: RuleMaxDelegation}}, c.Violations)
+	remaining, limited := c.Remaining()
+	require.True(t, limited)
+	require.Equal(t, "0", remaining.String())
+}
+
+func newRulesTestTx(txType database.PChainTxType, txID, nodeID string, weight uint64, start, end time.Time) database.PChainTx {
+	return database.PChainTx{
+		TxID:      &txID,
+		Type:      txType,
+		NodeID:    nodeID,
+		Weight:    weight,
+		StartTime: &start,
+		EndTime:   &end,
+	}
+}
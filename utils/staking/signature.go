@@ -0,0 +1,55 @@
+package staking
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// Length of a signature of a merkle root, r, s and v (27 or 28)
+const merkleRootSignatureLength = 65
+
+// Hash signed by the data provider attesting the merkle root of the epoch,
+// keccak256(abi.encodePacked(voting, uint256(epoch), merkleRoot)) with the
+// "\x19Ethereum Signed Message:\n32" prefix (eth_sign, EIP-191), so that it can
+// be verified with the usual tools and with ecrecover
+func MerkleRootSignatureHash(voting common.Address, epoch int64, merkleRoot common.Hash) common.Hash {
+	message := crypto.Keccak256(
+		voting.Bytes(),
+		math.U256Bytes(big.NewInt(epoch)),
+		merkleRoot.Bytes(),
+	)
+	return common.BytesToHash(accounts.TextHash(message))
+}
+
+// Signs the merkle root of the epoch of the voting contract
+func SignMerkleRoot(key *ecdsa.PrivateKey, voting common.Address, epoch int64, merkleRoot common.Hash) ([]byte, error) {
+	signature, err := crypto.Sign(MerkleRootSignatureHash(voting, epoch, merkleRoot).Bytes(), key)
+	if err != nil {
+		return nil, errors.Wrap(err, "crypto.Sign")
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// Returns the address of the key which signed the merkle root of the epoch
+func RecoverMerkleRootSigner(signature []byte, voting common.Address, epoch int64, merkleRoot common.Hash) (common.Address, error) {
+	if len(signature) != merkleRootSignatureLength {
+		return common.Address{}, errors.Errorf("invalid signature length %d", len(signature))
+	}
+	sig := make([]byte, merkleRootSignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	publicKey, err := crypto.SigToPub(MerkleRootSignatureHash(voting, epoch, merkleRoot).Bytes(), sig)
+	if err != nil {
+		return common.Address{}, errors.Wrap(err, "crypto.SigToPub")
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
@@ -0,0 +1,40 @@
+package staking
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignMerkleRoot(t *testing.T) {
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	require.NoError(t, err)
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	voting := common.HexToAddress("0xf956df3800379fdFA31D0A45FDD5001D02F4109c")
+	root := common.HexToHash("0x8d1e3b5d5bd2c6f1a4d1b5aa5e7e2d35bd9bb4a8bbed3a9d3fbb2d4ed5b4c1d2")
+
+	signature, err := SignMerkleRoot(key, voting, 100, root)
+	require.NoError(t, err)
+	require.Len(t, signature, 65)
+	require.Contains(t, []byte{27, 28}, signature[64])
+
+	recovered, err := RecoverMerkleRootSigner(signature, voting, 100, root)
+	require.NoError(t, err)
+	require.Equal(t, signer, recovered)
+
+	// The signature does not attest another epoch, root or contract
+	recovered, err = RecoverMerkleRootSigner(signature, voting, 101, root)
+	require.NoError(t, err)
+	require.NotEqual(t, signer, recovered)
+	recovered, err = RecoverMerkleRootSigner(signature, voting, 100, common.Hash{})
+	require.NoError(t, err)
+	require.NotEqual(t, signer, recovered)
+	recovered, err = RecoverMerkleRootSigner(signature, common.Address{}, 100, root)
+	require.NoError(t, err)
+	require.NotEqual(t, signer, recovered)
+
+	_, err = RecoverMerkleRootSigner(signature[:64], voting, 100, root)
+	require.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package staking
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/staketree"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Staking transactions of all types starting in [start, end), with one row per
+// input address, see database.GetPChainTxsForEpoch
+type EpochTxsFetcher func(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error)
+
+// Source of the stakes of an epoch as voted on and mirrored, implements
+// staketree.Source. The transactions are deduplicated by DedupeTxs and
+// filtered by the staking filter.
+type TxSource struct {
+	fetch  EpochTxsFetcher
+	filter *TxFilter
+}
+
+func NewTxSource(fetch EpochTxsFetcher, filter *TxFilter) *TxSource {
+	return &TxSource{fetch: fetch, filter: filter}
+}
+
+// Source of the stakes indexed in the database
+func NewDBSource(db *gorm.DB, filter *TxFilter) *TxSource {
+	return NewTxSource(func(ctx context.Context, start, end time.Time) ([]database.PChainTxData, error) {
+		return database.GetPChainTxsForEpoch(ctx, &database.GetPChainTxsForEpochInput{
+			DB:             db,
+			StartTimestamp: start,
+			EndTimestamp:   end,
+		})
+	}, filter)
+}
+
+func (s *TxSource) FetchStakes(ctx context.Context, start, end time.Time) ([]staketree.Stake, error) {
+	txs, err := s.fetch(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return EpochStakes(txs, s.filter)
+}
+
+// Transactions of the epoch which are voted on and mirrored: the first input
+// of each transaction, passing the filter
+func EpochTxs(txs []database.PChainTxData, filter *TxFilter) []database.PChainTxData {
+	return filter.Apply(DedupeTxs(txs))
+}
+
+// Stakes of the transactions returned by EpochTxs
+func EpochStakes(txs []database.PChainTxData, filter *TxFilter) ([]staketree.Stake, error) {
+	txs = EpochTxs(txs, filter)
+	stakes := make([]staketree.Stake, len(txs))
+	for i := range txs {
+		stake, err := ToStake(&txs[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "toStake")
+		}
+		stakes[i] = *stake
+	}
+	return stakes, nil
+}
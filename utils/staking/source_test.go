@@ -0,0 +1,59 @@
+package staking
+
+import (
+	"context"
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/staketree"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/stretchr/testify/require"
+)
+
+// The set built from the source is the set mirrored from the same transactions
+func TestTxSource(t *testing.T) {
+	chain.RegisterAddressHRP("costwo")
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	var txs []database.PChainTxData
+	for i := 0; i < 4; i++ {
+		txID := ids.GenerateTestID().String()
+		tx := database.PChainTxData{
+			PChainTx: database.PChainTx{
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &start,
+				EndTime:   &end,
+				TxID:      &txID,
+				Type:      database.PChainAddDelegatorTx,
+				Weight:    uint64(1000 * (i + 1)),
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+		// Rows of other inputs are not stakes, the address cannot be parsed
+		other := tx
+		other.InputAddress = "costwo1other"
+		other.InputIndex = 1
+		txs = append(txs, tx, other)
+	}
+	filter := &TxFilter{minWeight: 2000}
+
+	var fetched [2]time.Time
+	source := NewTxSource(func(ctx context.Context, from, to time.Time) ([]database.PChainTxData, error) {
+		fetched = [2]time.Time{from, to}
+		return txs, nil
+	}, filter)
+	set, err := staketree.BuildEpochSet(context.Background(), source, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, [2]time.Time{start, start.Add(time.Hour)}, fetched)
+	require.Len(t, set.Stakes(), 3)
+
+	tree, err := BuildTree(EpochTxs(txs, filter))
+	require.NoError(t, err)
+	expected, err := tree.Root()
+	require.NoError(t, err)
+	root, err := set.Root()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
@@ -2,64 +2,17 @@ package staking
 
 import (
 	"flare-indexer/database"
-	"flare-indexer/utils"
 	"flare-indexer/utils/chain"
 	"flare-indexer/utils/contracts/mirroring"
 	"flare-indexer/utils/merkle"
+	"flare-indexer/utils/staketree"
 	"sort"
 
 	"github.com/ava-labs/avalanchego/ids"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 )
 
-var (
-	merkleTreeItemABIObjectArguments abi.Arguments
-)
-
-func init() {
-	bytes32Ty, err1 := abi.NewType("bytes32", "", nil)
-	uint8Ty, err2 := abi.NewType("uint8", "", nil)
-	bytes20Ty, err3 := abi.NewType("bytes20", "", nil)
-	uint64Ty, err4 := abi.NewType("uint64", "", nil)
-	err := utils.Join(err1, err2, err3, err4)
-	if err != nil {
-		panic(err)
-	}
-	merkleTreeItemABIObjectArguments = abi.Arguments{
-		{
-			Name: "txId",
-			Type: bytes32Ty,
-		},
-		{
-			Name: "stakingType",
-			Type: uint8Ty,
-		},
-		{
-			Name: "inputAddress",
-			Type: bytes20Ty,
-		},
-		{
-			Name: "nodeId",
-			Type: bytes20Ty,
-		},
-		{
-			Name: "startTime",
-			Type: uint64Ty,
-		},
-		{
-			Name: "endTime",
-			Type: uint64Ty,
-		},
-		{
-			Name: "weight",
-			Type: uint64Ty,
-		},
-	}
-}
-
 func GetMerkleProof(merkleTree merkle.Tree, tx *database.PChainTxData) ([][32]byte, error) {
 	hash, err := HashTransaction(tx)
 	if err != nil {
@@ -79,22 +32,75 @@ func GetMerkleProof(merkleTree merkle.Tree, tx *database.PChainTxData) ([][32]by
 	return proofBytes, nil
 }
 
-func HashTransaction(tx *database.PChainTxData) (common.Hash, error) {
-	if tx.TxID == nil {
-		return common.Hash{}, errors.New("tx.TxID is nil")
+// Arguments of a batch verification of stakes (multiProofVerify), the stake
+// data is in the order of the leaves of the proof
+type MerkleMultiProof struct {
+	StakeData  []*mirroring.IPChainStakeMirrorVerifierPChainStake
+	Proof      [][32]byte
+	ProofFlags []bool
+}
+
+// Multi proof of the transactions, which must be in the tree
+func GetMerkleMultiProof(merkleTree merkle.Tree, txs []database.PChainTxData) (*MerkleMultiProof, error) {
+	hashes := make([]common.Hash, len(txs))
+	stakeData := make(map[common.Hash]*mirroring.IPChainStakeMirrorVerifierPChainStake, len(txs))
+	for i := range txs {
+		stake, err := ToStake(&txs[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "toStake")
+		}
+		if hashes[i], err = stake.Hash(); err != nil {
+			return nil, err
+		}
+		stakeData[hashes[i]] = stake.StakeData()
 	}
 
-	encodedBytes, err := encodeTreeItem(tx)
+	proof, err := merkleTree.GetMultiProofFromHashes(hashes)
 	if err != nil {
-		return common.Hash{}, errors.Wrap(err, "encodeTreeItem")
+		return nil, errors.Wrap(err, "merkleTree.GetMultiProof")
 	}
 
-	return crypto.Keccak256Hash(encodedBytes), nil
+	result := &MerkleMultiProof{
+		StakeData:  make([]*mirroring.IPChainStakeMirrorVerifierPChainStake, len(proof.Leaves)),
+		Proof:      make([][32]byte, len(proof.Proof)),
+		ProofFlags: proof.ProofFlags,
+	}
+	for i, leaf := range proof.Leaves {
+		result.StakeData[i] = stakeData[leaf]
+	}
+	for i := range proof.Proof {
+		result.Proof[i] = proof.Proof[i]
+	}
+
+	return result, nil
+}
+
+func HashTransaction(tx *database.PChainTxData) (common.Hash, error) {
+	stake, err := ToStake(tx)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "toStake")
+	}
+
+	return stake.Hash()
 }
 
 func ToStakeData(
 	tx *database.PChainTxData,
 ) (*mirroring.IPChainStakeMirrorVerifierPChainStake, error) {
+	stake, err := ToStake(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return stake.StakeData(), nil
+}
+
+// Convert the indexed transaction to the stake tree representation
+func ToStake(tx *database.PChainTxData) (*staketree.Stake, error) {
+	if tx.TxID == nil {
+		return nil, errors.New("tx.TxID is nil")
+	}
+
 	txHash, err := ids.FromString(*tx.TxID)
 	if err != nil {
 		return nil, errors.Wrap(err, "ids.FromString")
@@ -114,55 +120,33 @@ func ToStakeData(
 		return nil, errors.New("tx.StartTime is nil")
 	}
 
-	startTime := uint64(tx.StartTime.Unix())
-
 	if tx.EndTime == nil {
 		return nil, errors.New("tx.EndTime is nil")
 	}
 
-	endTime := uint64(tx.EndTime.Unix())
-
 	address, err := chain.ParseAddress(tx.InputAddress)
 	if err != nil {
 		return nil, errors.Wrap(err, "utils.ParseAddress")
 	}
 
-	return &mirroring.IPChainStakeMirrorVerifierPChainStake{
-		TxId:         txHash,
-		StakingType:  txType,
+	return &staketree.Stake{
+		TxID:         txHash,
+		Type:         staketree.StakeType(txType),
 		InputAddress: address,
-		NodeId:       nodeID,
-		StartTime:    startTime,
-		EndTime:      endTime,
+		NodeID:       nodeID,
+		StartTime:    *tx.StartTime,
+		EndTime:      *tx.EndTime,
 		Weight:       tx.Weight,
 	}, nil
 }
 
-func encodeTreeItem(tx *database.PChainTxData) ([]byte, error) {
-	// ABI Encode mirroring.IPChainStakeMirrorVerifierPChainStake
-
-	stakeData, err := ToStakeData(tx)
-	if err != nil {
-		return nil, errors.Wrap(err, "toStakeData")
-	}
-	return merkleTreeItemABIObjectArguments.Pack(
-		stakeData.TxId,
-		stakeData.StakingType,
-		stakeData.InputAddress,
-		stakeData.NodeId,
-		stakeData.StartTime,
-		stakeData.EndTime,
-		stakeData.Weight,
-	)
-}
-
 func GetTxType(txType database.PChainTxType) (uint8, error) {
-	switch txType {
-	case database.PChainAddValidatorTx:
-		return 0, nil
+	switch {
+	case txType.IsValidatorTx():
+		return uint8(staketree.ValidatorStake), nil
 
-	case database.PChainAddDelegatorTx:
-		return 1, nil
+	case txType.IsDelegatorTx():
+		return uint8(staketree.DelegatorStake), nil
 
 	default:
 		return 0, errors.New("invalid tx type")
@@ -170,26 +154,80 @@ func GetTxType(txType database.PChainTxType) (uint8, error) {
 }
 
 func BuildTree(txs []database.PChainTxData) (merkle.Tree, error) {
-	hashes := make([]common.Hash, len(txs))
+	set, err := buildStakeSet(txs)
+	if err != nil {
+		return merkle.Tree{}, err
+	}
 
-	for i := range txs {
-		hash, err := HashTransaction(&txs[i])
+	return set.Tree(), nil
+}
+
+// Returns staketree.EmptyRoot if there are no transactions. Unlike BuildTree,
+// it does not build the tree, see staketree.RootBuilder.
+func GetMerkleRoot(votingData []database.PChainTxData) (common.Hash, error) {
+	builder := staketree.NewRootBuilder(len(votingData))
+	for i := range votingData {
+		stake, err := ToStake(&votingData[i])
 		if err != nil {
-			return merkle.Tree{}, errors.Wrap(err, "getTxHash")
+			return common.Hash{}, errors.Wrap(err, "toStake")
+		}
+		if err := builder.Add(stake); err != nil {
+			return common.Hash{}, err
 		}
-
-		hashes[i] = hash
 	}
+	return builder.Root()
+}
 
-	return merkle.Build(hashes, false), nil
+// Page of the voting data of an epoch with ids greater than afterID, see
+// database.FetchPChainVotingDataPage
+type VotingDataPageFetcher func(afterID uint64, limit int) ([]database.PChainTxData, error)
+
+// Returns the merkle root and the number of stakes of the voting data read page
+// by page, so the data of an epoch is never held in memory. The root is the
+// root of GetMerkleRoot of the data deduplicated by DedupeTxs and passing the
+// filter.
+func GetMerkleRootFromPages(fetch VotingDataPageFetcher, filter *TxFilter, pageSize int) (common.Hash, int, error) {
+	builder := staketree.NewRootBuilder(pageSize)
+	var afterID uint64
+	for {
+		page, err := fetch(afterID, pageSize)
+		if err != nil {
+			return common.Hash{}, 0, err
+		}
+		for i := range page {
+			tx := &page[i]
+			if tx.TxID == nil || tx.InputIndex != 0 || !filter.Accept(tx) {
+				continue
+			}
+			stake, err := ToStake(tx)
+			if err != nil {
+				return common.Hash{}, 0, errors.Wrap(err, "toStake")
+			}
+			if err := builder.Add(stake); err != nil {
+				return common.Hash{}, 0, err
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+	count := builder.Len()
+	root, err := builder.Root()
+	return root, count, err
 }
 
-func GetMerkleRoot(votingData []database.PChainTxData) (common.Hash, error) {
-	tree, err := BuildTree(votingData)
-	if err != nil {
-		return [32]byte{}, err
+func buildStakeSet(txs []database.PChainTxData) (*staketree.Set, error) {
+	stakes := make([]staketree.Stake, len(txs))
+	for i := range txs {
+		stake, err := ToStake(&txs[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "toStake")
+		}
+		stakes[i] = *stake
 	}
-	return tree.Root()
+
+	return staketree.NewSet(stakes)
 }
 
 // Keep only transactions with unique address and input index 0
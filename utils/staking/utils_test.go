@@ -0,0 +1,125 @@
+package staking
+
+import (
+	"flare-indexer/database"
+	"flare-indexer/utils/chain"
+	"flare-indexer/utils/merkle"
+	"flare-indexer/utils/staketree"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMerkleMultiProof(t *testing.T) {
+	chain.RegisterAddressHRP("costwo")
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	txs := make([]database.PChainTxData, 5)
+	for i := range txs {
+		txID := ids.GenerateTestID().String()
+		txs[i] = database.PChainTxData{
+			PChainTx: database.PChainTx{
+				NodeID:    "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+				StartTime: &start,
+				EndTime:   &end,
+				TxID:      &txID,
+				Type:      database.PChainAddDelegatorTx,
+				Weight:    uint64(1000 * (i + 1)),
+			},
+			InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+		}
+	}
+	tree, err := BuildTree(txs)
+	require.NoError(t, err)
+	root, err := tree.Root()
+	require.NoError(t, err)
+
+	proven := []database.PChainTxData{txs[3], txs[0], txs[4]}
+	proof, err := GetMerkleMultiProof(tree, proven)
+	require.NoError(t, err)
+	require.Len(t, proof.StakeData, len(proven))
+
+	// The leaves are the hashes of the stake data in the order of the proof
+	multiProof := &merkle.MultiProof{ProofFlags: proof.ProofFlags}
+	for _, data := range proof.StakeData {
+		stake := staketree.Stake{
+			TxID:         data.TxId,
+			Type:         staketree.StakeType(data.StakingType),
+			InputAddress: data.InputAddress,
+			NodeID:       data.NodeId,
+			StartTime:    time.Unix(int64(data.StartTime), 0),
+			EndTime:      time.Unix(int64(data.EndTime), 0),
+			Weight:       data.Weight,
+		}
+		leaf, err := stake.Hash()
+		require.NoError(t, err)
+		multiProof.Leaves = append(multiProof.Leaves, leaf)
+	}
+	for _, hash := range proof.Proof {
+		multiProof.Proof = append(multiProof.Proof, common.Hash(hash))
+	}
+	require.True(t, merkle.VerifyMultiProof(multiProof, root))
+
+	other := txs[0]
+	otherID := ids.GenerateTestID().String()
+	other.TxID = &otherID
+	_, err = GetMerkleMultiProof(tree, []database.PChainTxData{other})
+	require.Error(t, err)
+}
+
+func TestGetMerkleRootFromPages(t *testing.T) {
+	chain.RegisterAddressHRP("costwo")
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	var txs []database.PChainTxData
+	for i := 0; i < 7; i++ {
+		txID := ids.GenerateTestID().String()
+		for inputIndex := uint32(0); inputIndex < 2; inputIndex++ {
+			txs = append(txs, database.PChainTxData{
+				PChainTx: database.PChainTx{
+					BaseEntity: database.BaseEntity{ID: uint64(i + 1)},
+					NodeID:     "NodeID-CZYx3on11wwYXFoHwZtAQZT5unZ9JHMf6",
+					StartTime:  &start,
+					EndTime:    &end,
+					TxID:       &txID,
+					Type:       database.PChainAddDelegatorTx,
+					Weight:     uint64(1000 * (i + 1)),
+				},
+				InputAddress: "costwo18atl0e95w5ym6t8u5yrjpz35vqqzxfzrrsnq8u",
+				InputIndex:   inputIndex,
+			})
+		}
+	}
+	filter := &TxFilter{minWeight: 2000}
+
+	// Pages of the rows with input index 0 as read from the database
+	var fetched []uint64
+	fetch := func(afterID uint64, limit int) ([]database.PChainTxData, error) {
+		fetched = append(fetched, afterID)
+		var page []database.PChainTxData
+		for _, tx := range txs {
+			if tx.InputIndex == 0 && tx.ID > afterID && len(page) < limit {
+				page = append(page, tx)
+			}
+		}
+		return page, nil
+	}
+	root, count, err := GetMerkleRootFromPages(fetch, filter, 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 3, 6}, fetched)
+
+	expected, err := GetMerkleRoot(filter.Apply(DedupeTxs(txs)))
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+	require.Equal(t, 6, count)
+
+	root, count, err = GetMerkleRootFromPages(func(uint64, int) ([]database.PChainTxData, error) {
+		return nil, nil
+	}, nil, 3)
+	require.NoError(t, err)
+	require.Equal(t, staketree.EmptyRoot, root)
+	require.Zero(t, count)
+}
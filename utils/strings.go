@@ -0,0 +1,14 @@
+package utils
+
+import "unicode/utf8"
+
+// Truncates s to at most maxLength bytes without splitting a UTF-8 sequence
+func TruncateString(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	for maxLength > 0 && !utf8.RuneStart(s[maxLength]) {
+		maxLength--
+	}
+	return s[:maxLength]
+}
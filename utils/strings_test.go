@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateString(t *testing.T) {
+	require.Equal(t, "abc", TruncateString("abc", 3))
+	require.Equal(t, "ab", TruncateString("abc", 2))
+	// "é" is two bytes and is not split
+	require.Equal(t, "a", TruncateString("aé", 2))
+	require.Equal(t, "aé", TruncateString("aé", 3))
+}
@@ -1,10 +1,57 @@
 package utils
 
 import (
-	"math/rand"
+	"sync"
 	"time"
 )
 
+// Source of the current time. Code comparing the current time with epoch
+// boundaries takes a clock, so that tests can run it at a given time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// The clock of the system
+var SystemClock Clock = systemClock{}
+
+// Clock which only moves when it is set or advanced, e.g. to run code exactly
+// at an epoch boundary. It may also be set back, as a stepped system clock is.
+type FixedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFixedClock(now time.Time) *FixedClock {
+	return &FixedClock{now: now}
+}
+
+func (c *FixedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FixedClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Moves the clock by d, back if d is negative
+func (c *FixedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Clock running at the rate of the system clock, shifted by Shift (a skewed
+// clock)
 type ShiftedTime struct {
 	Shift time.Duration
 }
@@ -30,6 +77,18 @@ func (s *ShiftedTime) AdvanceNow(duration time.Duration) {
 	s.Shift += duration
 }
 
+// Converts t to UTC and truncates it to the given number of fractional second
+// digits, as stored in a datetime column of that precision. Truncating (instead
+// of rounding) never moves a time into the next second, and thus into the next
+// epoch.
+func TruncateUTC(t time.Time, precision int) time.Time {
+	unit := time.Second
+	for i := 0; i < precision && unit > time.Nanosecond; i++ {
+		unit /= 10
+	}
+	return t.UTC().Truncate(unit)
+}
+
 // Use when s is the correct RFC3339 time (e.g. in tests, error results in panic)
 func ParseTime(s string) time.Time {
 	time, err := time.Parse(time.RFC3339, s)
@@ -38,24 +97,3 @@ func ParseTime(s string) time.Time {
 	}
 	return time
 }
-
-func NewRandomizedTicker(interval time.Duration, randomDelta time.Duration) <-chan time.Time {
-	deltaIntervalMs := int(randomDelta.Milliseconds())
-	ch := make(chan time.Time)
-	go func() {
-		for {
-			d := interval + randomDuration(deltaIntervalMs)
-			time.Sleep(d)
-			ch <- time.Now()
-		}
-	}()
-	return ch
-}
-
-func randomDuration(deltaMs int) time.Duration {
-	delta := int64(0)
-	if deltaMs > 0 {
-		delta = int64(rand.Intn(deltaMs))
-	}
-	return time.Duration(delta * int64(time.Millisecond))
-}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Time zone of the DST tests, if not installed
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateUTC(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Ljubljana")
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		local     time.Time
+		precision int
+		utc       time.Time
+	}{
+		// Before and after the clocks go forward on March 26
+		{time.Date(2023, 3, 26, 1, 59, 59, 999999999, location), 3, time.Date(2023, 3, 26, 0, 59, 59, 999000000, time.UTC)},
+		{time.Date(2023, 3, 26, 3, 0, 0, 123456789, location), 6, time.Date(2023, 3, 26, 1, 0, 0, 123456000, time.UTC)},
+		// Before and after the clocks go back on October 29
+		{time.Date(2023, 10, 29, 1, 59, 59, 999999999, location), 0, time.Date(2023, 10, 28, 23, 59, 59, 0, time.UTC)},
+		{time.Date(2023, 10, 29, 3, 0, 0, 500000000, location), 0, time.Date(2023, 10, 29, 2, 0, 0, 0, time.UTC)},
+	} {
+		truncated := TruncateUTC(c.local, c.precision)
+		require.Equal(t, c.utc, truncated, c.local.String())
+		require.Equal(t, time.UTC, truncated.Location())
+	}
+}
+
+func TestTimestampUTC(t *testing.T) {
+	var ts Timestamp
+	require.NoError(t, ts.UnmarshalText([]byte("2023-10-29T02:30:00+01:00")))
+	require.Equal(t, time.Date(2023, 10, 29, 1, 30, 0, 0, time.UTC), ts.Time)
+	require.Equal(t, time.UTC, ts.Location())
+
+	require.NoError(t, ts.UnmarshalText([]byte("1698543000")))
+	require.Equal(t, time.Date(2023, 10, 29, 1, 30, 0, 0, time.UTC), ts.Time)
+	require.Equal(t, time.UTC, ts.Location())
+}
+
+func TestFixedClock(t *testing.T) {
+	start := time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFixedClock(start)
+	require.Equal(t, start, clock.Now())
+	time.Sleep(time.Millisecond)
+	require.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Second)
+	require.Equal(t, start.Add(time.Second), clock.Now())
+	// Stepped back, e.g. by a time synchronization
+	clock.Advance(-2 * time.Second)
+	require.Equal(t, start.Add(-time.Second), clock.Now())
+
+	clock.Set(start)
+	require.Equal(t, start, clock.Now())
+}